@@ -0,0 +1,228 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+// Package external delegates the signatures of a node to an external signer
+// process, so the private keys of the validator accounts never live in the node.
+//
+// The signer is reached over IPC (or any other endpoint the rpc package can dial)
+// and serves the account_ namespace:
+//
+//	account_list            returns the accounts managed by the signer
+//	account_signHash        signs a hash (consensus votes, block signatures)
+//	account_signTransaction signs a transaction (regular and broadcast transactions)
+//	account_signVrf         computes a VRF output with an account key
+//
+// Every request carries its kind, for the ruleset of the signer to approve or
+// reject it without operator interaction.
+package external
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// Kinds of the signing requests.
+const (
+	KindConsensus   = "consensus"   // Consensus votes and block signatures
+	KindTransaction = "transaction" // Regular transactions
+	KindBroadcast   = "broadcast"   // Broadcast transactions (heartbeats, public keys, roll calls)
+	KindVrf         = "vrf"         // VRF outputs of the block producers
+)
+
+const (
+	// requestTimeout is the time allowed to the signer to answer a request.
+	requestTimeout = 10 * time.Second
+
+	// accountsRefreshCycle is the time the list of signer accounts is cached.
+	accountsRefreshCycle = time.Minute
+)
+
+var (
+	ErrNoSignerAccount = errors.New("no account managed by the external signer")
+	ErrInvalidResponse = errors.New("invalid external signer response")
+)
+
+// HashRequest is the request to sign a hash.
+type HashRequest struct {
+	Account  common.Address `json:"account"`
+	Kind     string         `json:"kind"`
+	Hash     hexutil.Bytes  `json:"hash"`
+	Validate bool           `json:"validate"`
+}
+
+// TxRequest is the request to sign a transaction. Tx is the RLP encoding of the
+// transaction for the rules to inspect, Hash the hash to sign.
+type TxRequest struct {
+	Account common.Address `json:"account"`
+	Kind    string         `json:"kind"`
+	Tx      hexutil.Bytes  `json:"tx"`
+	ChainID *hexutil.Big   `json:"chainId"`
+	Hash    common.Hash    `json:"hash"`
+}
+
+// VrfRequest is the request to compute a VRF output.
+type VrfRequest struct {
+	Account common.Address `json:"account"`
+	Kind    string         `json:"kind"`
+	Msg     hexutil.Bytes  `json:"msg"`
+}
+
+// VrfResult is the VRF output computed by the signer.
+type VrfResult struct {
+	PublicKey hexutil.Bytes `json:"publicKey"`
+	Value     hexutil.Bytes `json:"value"`
+	Proof     hexutil.Bytes `json:"proof"`
+}
+
+// Signer is a client of an external signer.
+type Signer struct {
+	endpoint string
+	client   *rpc.Client
+
+	lock     sync.Mutex
+	accounts map[common.Address]bool // Accounts managed by the signer
+	listed   time.Time               // Time the accounts were last listed
+}
+
+// NewSigner connects to the external signer listening on endpoint.
+func NewSigner(endpoint string) (*Signer, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSigner(endpoint, client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func newSigner(endpoint string, client *rpc.Client) (*Signer, error) {
+	s := &Signer{endpoint: endpoint, client: client}
+	accounts, err := s.Accounts()
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Connected to external signer", "endpoint", endpoint, "accounts", len(accounts))
+	return s, nil
+}
+
+// Endpoint returns the endpoint of the signer.
+func (s *Signer) Endpoint() string {
+	return s.endpoint
+}
+
+// Close disconnects from the signer.
+func (s *Signer) Close() {
+	s.client.Close()
+}
+
+func (s *Signer) call(result interface{}, method string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return s.client.CallContext(ctx, result, method, args...)
+}
+
+// Accounts lists the accounts managed by the signer.
+func (s *Signer) Accounts() ([]common.Address, error) {
+	var accounts []common.Address
+	if err := s.call(&accounts, "account_list"); err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	s.accounts = make(map[common.Address]bool, len(accounts))
+	for _, account := range accounts {
+		s.accounts[account] = true
+	}
+	s.listed = time.Now()
+	s.lock.Unlock()
+	return accounts, nil
+}
+
+// Pick returns the first of the candidate accounts managed by the signer.
+func (s *Signer) Pick(candidates []common.Address) (common.Address, error) {
+	s.lock.Lock()
+	stale := time.Since(s.listed) > accountsRefreshCycle
+	s.lock.Unlock()
+	if stale {
+		if _, err := s.Accounts(); err != nil {
+			return common.Address{}, err
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, account := range candidates {
+		if s.accounts[account] {
+			return account, nil
+		}
+	}
+	return common.Address{}, ErrNoSignerAccount
+}
+
+// SignHash requests the signature of a hash by account.
+func (s *Signer) SignHash(account common.Address, kind string, hash []byte, validate bool) ([]byte, error) {
+	var sig hexutil.Bytes
+	req := &HashRequest{Account: account, Kind: kind, Hash: hash, Validate: validate}
+	if err := s.call(&sig, "account_signHash", req); err != nil {
+		return nil, err
+	}
+	if len(sig) != 65 {
+		return nil, ErrInvalidResponse
+	}
+	return sig, nil
+}
+
+// SignTx requests the signature of a transaction by account and returns the
+// signed transaction.
+func (s *Signer) SignTx(account common.Address, tx types.SelfTransaction, chainID *big.Int) (types.SelfTransaction, error) {
+	kind := KindTransaction
+	if tx.GetMatrixType() == common.ExtraBroadTxType {
+		kind = KindBroadcast
+	}
+	raw, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	signer := types.NewEIP155Signer(chainID)
+	req := &TxRequest{
+		Account: account,
+		Kind:    kind,
+		Tx:      raw,
+		ChainID: (*hexutil.Big)(chainID),
+		Hash:    signer.Hash(tx),
+	}
+	var sig hexutil.Bytes
+	if err := s.call(&sig, "account_signTransaction", req); err != nil {
+		return nil, err
+	}
+	if len(sig) != 65 {
+		return nil, ErrInvalidResponse
+	}
+	return tx.WithSignature(signer, sig)
+}
+
+// SignVrf requests the VRF output of msg computed with the key of account,
+// returning the compressed public key, the VRF value and its proof.
+func (s *Signer) SignVrf(account common.Address, msg []byte) ([]byte, []byte, []byte, error) {
+	var res VrfResult
+	if err := s.call(&res, "account_signVrf", &VrfRequest{Account: account, Kind: KindVrf, Msg: msg}); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(res.PublicKey) == 0 || len(res.Proof) == 0 {
+		return nil, nil, nil, ErrInvalidResponse
+	}
+	return res.PublicKey, res.Value, res.Proof, nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package external
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// testRules is an external signer approving the consensus signatures only.
+type testRules struct {
+	accounts []common.Address
+}
+
+func (r *testRules) List() []common.Address {
+	return r.accounts
+}
+
+func (r *testRules) SignHash(req HashRequest) (hexutil.Bytes, error) {
+	if req.Kind != KindConsensus {
+		return nil, errors.New("request rejected")
+	}
+	return make([]byte, 65), nil
+}
+
+func newTestSigner(t *testing.T, accounts ...common.Address) *Signer {
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", &testRules{accounts: accounts}); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+	s, err := newSigner("inproc", rpc.DialInProc(server))
+	if err != nil {
+		t.Fatalf("failed to connect signer: %v", err)
+	}
+	return s
+}
+
+func TestSignerPick(t *testing.T) {
+	a, b := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	s := newTestSigner(t, b)
+	defer s.Close()
+
+	if account, err := s.Pick([]common.Address{a, b}); err != nil || account != b {
+		t.Errorf("picked account mismatch: have %x (%v), want %x", account, err, b)
+	}
+	if _, err := s.Pick([]common.Address{a}); err != ErrNoSignerAccount {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrNoSignerAccount)
+	}
+}
+
+func TestSignerRules(t *testing.T) {
+	account := common.HexToAddress("0x01")
+	s := newTestSigner(t, account)
+	defer s.Close()
+
+	if sig, err := s.SignHash(account, KindConsensus, make([]byte, 32), true); err != nil || len(sig) != 65 {
+		t.Errorf("consensus signature failed: %v", err)
+	}
+	if _, err := s.SignHash(account, KindVrf, make([]byte, 32), true); err == nil {
+		t.Errorf("rejected request signed")
+	}
+}
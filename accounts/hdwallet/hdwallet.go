@@ -0,0 +1,181 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+// Package hdwallet implements the hierarchical deterministic derivation of the
+// account keys (BIP-32) from mnemonic seed phrases (BIP-39), along the BIP-44
+// paths of the Matrix accounts.
+package hdwallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts"
+	"github.com/MatrixAINetwork/go-matrix/common/math"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const (
+	// DefaultCoinType is the BIP-44 coin type of the Matrix accounts, the one the
+	// default derivation paths of the hardware wallets use.
+	DefaultCoinType uint32 = 60
+
+	// MnemonicEntropy is the entropy of the generated mnemonics, 24 words.
+	MnemonicEntropy = 256
+
+	// hardenedKeyStart is the index of the first hardened child key.
+	hardenedKeyStart uint32 = 0x80000000
+)
+
+// The BIP-44 accounts the keys are derived from.
+const (
+	walletAccount  uint32 = 0 // Accounts holding funds and deposits
+	signingAccount uint32 = 1 // Validator signing accounts
+)
+
+var (
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+	ErrInvalidKey      = errors.New("derived key is invalid")
+	ErrDerivedDepth    = errors.New("derivation path too deep")
+
+	masterKeySeed = []byte("Bitcoin seed")
+)
+
+// NewMnemonic generates a new random mnemonic of MnemonicEntropy bits.
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(MnemonicEntropy)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// NewSeed returns the seed of a mnemonic, checking its checksum. The passphrase
+// is the optional BIP-39 passphrase, not the one encrypting the keystore.
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, ErrInvalidMnemonic
+	}
+	return seed, nil
+}
+
+// AccountPath returns the derivation path of the index-th wallet account,
+// m/44'/coin'/0'/0/index.
+func AccountPath(coinType uint32, index uint32) accounts.DerivationPath {
+	return bip44Path(coinType, walletAccount, index)
+}
+
+// SigningPath returns the derivation path of the index-th validator signing
+// account, m/44'/coin'/1'/0/index. Signing keys live in their own BIP-44 account
+// for the wallet accounts not to be exposed by the signing key files of a node.
+func SigningPath(coinType uint32, index uint32) accounts.DerivationPath {
+	return bip44Path(coinType, signingAccount, index)
+}
+
+func bip44Path(coinType uint32, account uint32, index uint32) accounts.DerivationPath {
+	return accounts.DerivationPath{
+		hardenedKeyStart + 44,
+		hardenedKeyStart + coinType,
+		hardenedKeyStart + account,
+		0,
+		index,
+	}
+}
+
+// ExtendedKey is a BIP-32 extended private key.
+type ExtendedKey struct {
+	key       *big.Int
+	chainCode []byte
+	depth     uint8
+}
+
+// NewMaster returns the master key of a seed.
+func NewMaster(seed []byte) (*ExtendedKey, error) {
+	mac := hmac.New(sha512.New, masterKeySeed)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key := new(big.Int).SetBytes(sum[:32])
+	if key.Sign() == 0 || key.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, ErrInvalidKey
+	}
+	return &ExtendedKey{key: key, chainCode: sum[32:]}, nil
+}
+
+// Child returns the i-th child key, hardened if i is above hardenedKeyStart.
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	if k.depth == 255 {
+		return nil, ErrDerivedDepth
+	}
+	var data []byte
+	if i >= hardenedKeyStart {
+		data = append([]byte{0x00}, math.PaddedBigBytes(k.key, 32)...)
+	} else {
+		priv, err := crypto.ToECDSA(math.PaddedBigBytes(k.key, 32))
+		if err != nil {
+			return nil, err
+		}
+		data = crypto.CompressPubkey(&priv.PublicKey)
+	}
+	var index [4]byte
+	binary.BigEndian.PutUint32(index[:], i)
+	data = append(data, index[:]...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(n) >= 0 {
+		return nil, ErrInvalidKey
+	}
+	key := tweak.Add(tweak, k.key)
+	key.Mod(key, n)
+	if key.Sign() == 0 {
+		return nil, ErrInvalidKey
+	}
+	return &ExtendedKey{key: key, chainCode: sum[32:], depth: k.depth + 1}, nil
+}
+
+// Derive returns the key at path, relative to k.
+func (k *ExtendedKey) Derive(path accounts.DerivationPath) (*ExtendedKey, error) {
+	key := k
+	for _, i := range path {
+		child, err := key.Child(i)
+		if err != nil {
+			return nil, err
+		}
+		key = child
+	}
+	return key, nil
+}
+
+// PrivateKey returns the private key of the extended key.
+func (k *ExtendedKey) PrivateKey() (*ecdsa.PrivateKey, error) {
+	return crypto.ToECDSA(math.PaddedBigBytes(k.key, 32))
+}
+
+// DeriveKey returns the private key at path of the key tree of a mnemonic.
+func DeriveKey(mnemonic, passphrase string, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	seed, err := NewSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	master, err := NewMaster(seed)
+	if err != nil {
+		return nil, err
+	}
+	key, err := master.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey()
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package hdwallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts"
+	"github.com/MatrixAINetwork/go-matrix/common/math"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+)
+
+// Tests the derivation against the first BIP-32 test vector.
+func TestDeriveVector(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMaster(seed)
+	if err != nil {
+		t.Fatalf("failed to create master key: %v", err)
+	}
+	tests := []struct {
+		path accounts.DerivationPath
+		key  string
+	}{
+		{nil, "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{accounts.DerivationPath{hardenedKeyStart}, "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{accounts.DerivationPath{hardenedKeyStart, 1}, "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+	}
+	for i, tt := range tests {
+		key, err := master.Derive(tt.path)
+		if err != nil {
+			t.Fatalf("test %d: failed to derive key: %v", i, err)
+		}
+		if have := hex.EncodeToString(math.PaddedBigBytes(key.key, 32)); have != tt.key {
+			t.Errorf("test %d: key mismatch: have %s, want %s", i, have, tt.key)
+		}
+	}
+}
+
+func TestMnemonicRecovery(t *testing.T) {
+	mnemonic, err := NewMnemonic()
+	if err != nil {
+		t.Fatalf("failed to create mnemonic: %v", err)
+	}
+	account, err := DeriveKey(mnemonic, "", AccountPath(DefaultCoinType, 0))
+	if err != nil {
+		t.Fatalf("failed to derive account key: %v", err)
+	}
+	signing, err := DeriveKey(mnemonic, "", SigningPath(DefaultCoinType, 0))
+	if err != nil {
+		t.Fatalf("failed to derive signing key: %v", err)
+	}
+	if crypto.PubkeyToAddress(account.PublicKey) == crypto.PubkeyToAddress(signing.PublicKey) {
+		t.Errorf("signing key equals account key")
+	}
+	// Recovering from the same phrase yields the same signing key
+	recovered, _ := DeriveKey(mnemonic, "", SigningPath(DefaultCoinType, 0))
+	if recovered.D.Cmp(signing.D) != 0 {
+		t.Errorf("recovered signing key mismatch")
+	}
+	if _, err := DeriveKey(mnemonic+" abandon", "", AccountPath(DefaultCoinType, 0)); err != ErrInvalidMnemonic {
+		t.Errorf("invalid mnemonic error mismatch: have %v, want %v", err, ErrInvalidMnemonic)
+	}
+}
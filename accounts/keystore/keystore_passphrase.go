@@ -160,6 +160,9 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 		keyBytes, keyId []byte
 		err             error
 	)
+	if version, ok := m["version"].(float64); ok && version == versionV4 {
+		return decryptKeyV4(keyjson, auth)
+	}
 	if version, ok := m["version"].(string); ok && version == "1" {
 		k := new(encryptedKeyJSONV1)
 		if err := json.Unmarshal(keyjson, k); err != nil {
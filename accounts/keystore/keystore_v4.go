@@ -0,0 +1,239 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts"
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common/math"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/crypto/randentropy"
+	"github.com/pborman/uuid"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Version 4 key files encrypt the whole 256 bit derived key with AES-256-GCM,
+// authenticating the address of the key along with it, and may bind the key to
+// a hardware token: the scrypt output is then mixed with a secret the token
+// derives from the salt, so the key file can't be decrypted without the token.
+
+const (
+	versionV4 = 4
+
+	cipherV4 = "aes-256-gcm"
+
+	// StrongScryptN is the N parameter of Scrypt encryption algorithm the key
+	// files are upgraded with, using 1GB memory and taking approximately 4s CPU
+	// time on a modern processor.
+	StrongScryptN = 1 << 20
+
+	// StrongScryptP is the P parameter of Scrypt encryption algorithm the key
+	// files are upgraded with.
+	StrongScryptP = 1
+)
+
+var (
+	ErrNoHardwareSecret = errors.New("key bound to a hardware token, but no token configured")
+	ErrHardwareMismatch = errors.New("key bound to another hardware token")
+)
+
+// HardwareSecret derives secrets from a key held by a hardware token, which
+// never leaves it.
+type HardwareSecret interface {
+	// Label identifies the token key the secrets are derived from.
+	Label() string
+
+	// Derive returns the secret bound to salt.
+	Derive(salt []byte) ([]byte, error)
+}
+
+var (
+	hardwareSecret HardwareSecret
+	hardwareLock   sync.RWMutex
+)
+
+// SetHardwareSecret sets the hardware token new key files are bound to and the
+// bound key files are decrypted with.
+func SetHardwareSecret(secret HardwareSecret) {
+	hardwareLock.Lock()
+	defer hardwareLock.Unlock()
+
+	hardwareSecret = secret
+}
+
+func getHardwareSecret() HardwareSecret {
+	hardwareLock.RLock()
+	defer hardwareLock.RUnlock()
+
+	return hardwareSecret
+}
+
+type encryptedKeyJSONV4 struct {
+	Address  string        `json:"address"`
+	Crypto   cryptoJSON    `json:"crypto"`
+	Hardware *hardwareJSON `json:"hardware,omitempty"`
+	Id       string        `json:"id"`
+	Version  int           `json:"version"`
+}
+
+type hardwareJSON struct {
+	Label string `json:"label"`
+}
+
+// EncryptKeyV4 encrypts a key into a version 4 json blob, bound to the hardware
+// token if one is set.
+func EncryptKeyV4(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
+	salt := randentropy.GetEntropyCSPRNG(32)
+	secret := getHardwareSecret()
+
+	derivedKey, err := deriveKeyV4(auth, salt, scryptN, scryptR, scryptP, secret)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := randentropy.GetEntropyCSPRNG(gcm.NonceSize())
+	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
+	cipherText := gcm.Seal(nil, nonce, keyBytes, key.Address[:])
+
+	keyJSON := encryptedKeyJSONV4{
+		Address: key.ManAddress(),
+		Crypto: cryptoJSON{
+			Cipher:       cipherV4,
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherparamsJSON{IV: hex.EncodeToString(nonce)},
+			KDF:          keyHeaderKDF,
+			KDFParams: map[string]interface{}{
+				"n":     scryptN,
+				"r":     scryptR,
+				"p":     scryptP,
+				"dklen": scryptDKLen,
+				"salt":  hex.EncodeToString(salt),
+			},
+		},
+		Id:      key.Id.String(),
+		Version: versionV4,
+	}
+	if secret != nil {
+		keyJSON.Hardware = &hardwareJSON{Label: secret.Label()}
+	}
+	return json.Marshal(keyJSON)
+}
+
+func decryptKeyV4(keyjson []byte, auth string) (*Key, error) {
+	k := new(encryptedKeyJSONV4)
+	if err := json.Unmarshal(keyjson, k); err != nil {
+		return nil, err
+	}
+	if k.Crypto.Cipher != cipherV4 {
+		return nil, fmt.Errorf("Cipher not supported: %v", k.Crypto.Cipher)
+	}
+	if k.Crypto.KDF != keyHeaderKDF {
+		return nil, fmt.Errorf("Unsupported KDF: %s", k.Crypto.KDF)
+	}
+	var secret HardwareSecret
+	if k.Hardware != nil {
+		if secret = getHardwareSecret(); secret == nil {
+			return nil, ErrNoHardwareSecret
+		}
+		if secret.Label() != k.Hardware.Label {
+			return nil, ErrHardwareMismatch
+		}
+	}
+	salt, err := hex.DecodeString(k.Crypto.KDFParams["salt"].(string))
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(k.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	params := k.Crypto.KDFParams
+	derivedKey, err := deriveKeyV4(auth, salt, ensureInt(params["n"]), ensureInt(params["r"]), ensureInt(params["p"]), secret)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	// The address is authenticated along with the key
+	address, err := base58.Base58DecodeToAddress(k.Address)
+	if err != nil {
+		return nil, err
+	}
+	keyBytes, err := gcm.Open(nil, nonce, cipherText, address[:])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	privateKey := crypto.ToECDSAUnsafe(keyBytes)
+	return &Key{
+		Id:         uuid.Parse(k.Id),
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}, nil
+}
+
+// deriveKeyV4 derives the encryption key from the passphrase, mixing the secret
+// of the hardware token in if any.
+func deriveKeyV4(auth string, salt []byte, n, r, p int, secret HardwareSecret) ([]byte, error) {
+	derivedKey, err := scrypt.Key([]byte(auth), salt, n, r, p, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return derivedKey, nil
+	}
+	tokenSecret, err := secret.Derive(salt)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, derivedKey)
+	mac.Write(tokenSecret)
+	return mac.Sum(nil), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Upgrade re-encrypts the key file of an account in place in the version 4
+// format with the given scrypt parameters.
+func (ks *KeyStore) Upgrade(a accounts.Account, passphrase string, scryptN, scryptP int) error {
+	a, key, err := ks.getDecryptedKey(a, passphrase)
+	if err != nil {
+		return err
+	}
+	defer zeroKey(key.PrivateKey)
+
+	keyjson, err := EncryptKeyV4(key, passphrase, scryptN, scryptP)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(a.URL.Path, keyjson)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package keystore
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+)
+
+// testSecret is a hardware secret derived from a key held in memory.
+type testSecret struct {
+	label string
+	key   []byte
+}
+
+func (s *testSecret) Label() string { return s.label }
+
+func (s *testSecret) Derive(salt []byte) ([]byte, error) {
+	sum := sha256.Sum256(append(append([]byte{}, s.key...), salt...))
+	return sum[:], nil
+}
+
+// Tests that version 4 key files can be decrypted with and only with the right
+// password and hardware token.
+func TestKeyEncryptDecryptV4(t *testing.T) {
+	defer SetHardwareSecret(nil)
+
+	key, err := newKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenA := &testSecret{label: "a", key: []byte("token a")}
+	tokenB := &testSecret{label: "a", key: []byte("token b")}
+
+	tests := []struct {
+		encrypt HardwareSecret
+		decrypt HardwareSecret
+		err     error
+	}{
+		{encrypt: nil, decrypt: nil},
+		{encrypt: nil, decrypt: tokenA},
+		{encrypt: tokenA, decrypt: tokenA},
+		{encrypt: tokenA, decrypt: nil, err: ErrNoHardwareSecret},
+		{encrypt: tokenA, decrypt: &testSecret{label: "b"}, err: ErrHardwareMismatch},
+		{encrypt: tokenA, decrypt: tokenB, err: ErrDecrypt},
+	}
+	for i, tt := range tests {
+		SetHardwareSecret(tt.encrypt)
+		keyjson, err := EncryptKeyV4(key, "pass", veryLightScryptN, veryLightScryptP)
+		if err != nil {
+			t.Fatalf("test %d: failed to encrypt key: %v", i, err)
+		}
+		SetHardwareSecret(tt.decrypt)
+		if _, err := DecryptKey(keyjson, "bad"); err == nil {
+			t.Errorf("test %d: key decrypted with bad password", i)
+		}
+		decrypted, err := DecryptKey(keyjson, "pass")
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if err == nil && decrypted.Address != key.Address {
+			t.Errorf("test %d: address mismatch: have %x, want %x", i, decrypted.Address, key.Address)
+		}
+	}
+}
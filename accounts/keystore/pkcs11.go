@@ -0,0 +1,95 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+// +build pkcs11
+
+package keystore
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+)
+
+// pkcs11Secret derives the secrets of hardware bound key files with a HMAC key
+// held by a PKCS#11 token.
+type pkcs11Secret struct {
+	ctx   *pkcs11.Ctx
+	label string
+	pin   string
+	lock  sync.Mutex
+}
+
+// NewPKCS11Secret loads a PKCS#11 module and returns the hardware secret derived
+// with the HMAC-SHA256 key labelled label on the first token it reports.
+func NewPKCS11Secret(module, label, pin string) (HardwareSecret, error) {
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %s", module)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, err
+	}
+	secret := &pkcs11Secret{ctx: ctx, label: label, pin: pin}
+
+	// Make sure the key is there now instead of failing on the first unlock
+	if _, err := secret.Derive(make([]byte, 32)); err != nil {
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, err
+	}
+	return secret, nil
+}
+
+// Label implements HardwareSecret, returning the label of the token key.
+func (s *pkcs11Secret) Label() string {
+	return s.label
+}
+
+// Derive implements HardwareSecret, computing the HMAC of the salt on the token.
+func (s *pkcs11Secret) Derive(salt []byte) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	slots, err := s.ctx.GetSlotList(true)
+	if err != nil {
+		return nil, err
+	}
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("no PKCS#11 token present")
+	}
+	session, err := s.ctx.OpenSession(slots[0], pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return nil, err
+	}
+	defer s.ctx.CloseSession(session)
+
+	if err := s.ctx.Login(session, pkcs11.CKU_USER, s.pin); err != nil {
+		return nil, err
+	}
+	defer s.ctx.Logout(session)
+
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, s.label),
+	}
+	if err := s.ctx.FindObjectsInit(session, template); err != nil {
+		return nil, err
+	}
+	objects, _, err := s.ctx.FindObjects(session, 1)
+	s.ctx.FindObjectsFinal(session)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no PKCS#11 key labelled %q", s.label)
+	}
+	mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_SHA256_HMAC, nil)}
+	if err := s.ctx.SignInit(session, mechanism, objects[0]); err != nil {
+		return nil, err
+	}
+	return s.ctx.Sign(session, salt)
+}
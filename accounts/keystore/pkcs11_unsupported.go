@@ -0,0 +1,14 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+// +build !pkcs11
+
+package keystore
+
+import "errors"
+
+// NewPKCS11Secret is not supported without the pkcs11 build tag.
+func NewPKCS11Secret(module, label, pin string) (HardwareSecret, error) {
+	return nil, errors.New("PKCS#11 support not compiled in, build with the pkcs11 tag")
+}
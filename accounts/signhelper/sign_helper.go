@@ -7,10 +7,12 @@ import (
 	"math/big"
 
 	"github.com/MatrixAINetwork/go-matrix/accounts"
+	"github.com/MatrixAINetwork/go-matrix/accounts/external"
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/crypto/bls"
 	"github.com/pkg/errors"
 
 	"sync"
@@ -42,11 +44,14 @@ var (
 	ErrIllegalSignAccount    = errors.New("sign account is illegal")
 	ErrReader                = errors.New("auth reader is nil")
 	ErrGetAccountAndPassword = errors.New("get account and password  error")
+	ErrNoBLSKey              = errors.New("bls key is not set")
 )
 
 type SignHelper struct {
 	mu         sync.RWMutex
 	keyStore   *keystore.KeyStore
+	signer     *external.Signer // External signer taking over the keystore, if any
+	blsKey     *bls.SecretKey   // BLS key the block votes are signed with, if any
 	authReader AuthReader
 }
 
@@ -86,6 +91,61 @@ func (sh *SignHelper) SetAccountManager(am *accounts.Manager) error {
 	return nil
 }
 
+// SetExternalSigner delegates all the signatures to an external signer, the
+// keystore and the entrust passwords are not used anymore.
+func (sh *SignHelper) SetExternalSigner(signer *external.Signer) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.signer = signer
+}
+
+// SetBLSKey sets the BLS key the block votes are additionally signed with, to
+// be aggregated in the block headers.
+func (sh *SignHelper) SetBLSKey(key *bls.SecretKey) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.blsKey = key
+}
+
+// HasBLSKey reports whether a BLS key is set.
+func (sh *SignHelper) HasBLSKey() bool {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	return sh.blsKey != nil
+}
+
+// SignBLSHash signs a hash with the BLS key.
+func (sh *SignHelper) SignBLSHash(hash []byte) ([]byte, error) {
+	sh.mu.RLock()
+	key := sh.blsKey
+	sh.mu.RUnlock()
+
+	if key == nil {
+		return nil, ErrNoBLSKey
+	}
+	return key.Sign(hash).Bytes(), nil
+}
+
+// signAccountPassword selects the account signing among the candidates, the one
+// managed by the external signer if any, otherwise the one with an entrust password.
+func (sh *SignHelper) signAccountPassword(reader AuthReader, candidates []common.Address) (common.Address, string, error) {
+	sh.mu.RLock()
+	signer := sh.signer
+	sh.mu.RUnlock()
+
+	if signer != nil {
+		account, err := signer.Pick(candidates)
+		return account, "", err
+	}
+	if reader == nil {
+		return common.Address{}, "", ErrReader
+	}
+	return reader.GetSignAccountPassword(candidates)
+}
+
 func (sh *SignHelper) SignHashWithValidateByReader(reader AuthReader, hash []byte, validate bool, blkHash common.Hash) (common.Signature, error) {
 	signAccount, signPassword, err := sh.getSignAccountAndPassword(reader, blkHash)
 	if err != nil {
@@ -97,6 +157,13 @@ func (sh *SignHelper) SignHashWithValidateByReader(reader AuthReader, hash []byt
 
 	sh.mu.RLock()
 	defer sh.mu.RUnlock()
+	if sh.signer != nil {
+		sign, err := sh.signer.SignHash(signAccount.Address, external.KindConsensus, hash, validate)
+		if err != nil {
+			return common.Signature{}, err
+		}
+		return common.BytesToSignature(sign), nil
+	}
 	if nil == sh.keyStore {
 		return common.Signature{}, ErrNilKeyStore
 	}
@@ -112,7 +179,7 @@ func (sh *SignHelper) SignHashWithValidate(hash []byte, validate bool, blkHash c
 }
 
 func (sh *SignHelper) SignHashWithValidateByAccount(hash []byte, validate bool, account common.Address) (common.Signature, error) {
-	signAccount, password, err := sh.authReader.GetSignAccountPassword([]common.Address{account})
+	signAccount, password, err := sh.signAccountPassword(sh.authReader, []common.Address{account})
 	if err != nil {
 		log.Error(ModeLog, "account", account.Hex(), "签名失败", err)
 		return common.Signature{}, errors.New("get sign account password err!")
@@ -120,6 +187,13 @@ func (sh *SignHelper) SignHashWithValidateByAccount(hash []byte, validate bool,
 
 	sh.mu.RLock()
 	defer sh.mu.RUnlock()
+	if sh.signer != nil {
+		sign, err := sh.signer.SignHash(signAccount, external.KindConsensus, hash, validate)
+		if err != nil {
+			return common.Signature{}, err
+		}
+		return common.BytesToSignature(sign), nil
+	}
 	if nil == sh.keyStore {
 		return common.Signature{}, ErrNilKeyStore
 	}
@@ -144,6 +218,9 @@ func (sh *SignHelper) SignTx(tx types.SelfTransaction, chainID *big.Int, blkHash
 	}
 	sh.mu.RLock()
 	defer sh.mu.RUnlock()
+	if sh.signer != nil {
+		return sh.signer.SignTx(signAccount.Address, tx, chainID)
+	}
 	if nil == sh.keyStore {
 		return nil, ErrNilKeyStore
 	}
@@ -151,7 +228,7 @@ func (sh *SignHelper) SignTx(tx types.SelfTransaction, chainID *big.Int, blkHash
 }
 
 func (sh *SignHelper) SignVrfByAccount(msg []byte, account common.Address) ([]byte, []byte, []byte, error) {
-	signAccount, password, err := sh.authReader.GetSignAccountPassword([]common.Address{account})
+	signAccount, password, err := sh.signAccountPassword(sh.authReader, []common.Address{account})
 	if err != nil {
 		log.Error(ModeLog, "VRFaccount", account.Hex(), "签名失败", err)
 		return nil, nil, nil, errors.New("get sign account password err!")
@@ -159,6 +236,9 @@ func (sh *SignHelper) SignVrfByAccount(msg []byte, account common.Address) ([]by
 
 	sh.mu.RLock()
 	defer sh.mu.RUnlock()
+	if sh.signer != nil {
+		return sh.signer.SignVrf(signAccount, msg)
+	}
 	if nil == sh.keyStore {
 		return nil, nil, nil, ErrNilKeyStore
 	}
@@ -178,6 +258,9 @@ func (sh *SignHelper) SignVrf(msg []byte, blkHash common.Hash) ([]byte, []byte,
 
 	sh.mu.RLock()
 	defer sh.mu.RUnlock()
+	if sh.signer != nil {
+		return sh.signer.SignVrf(signAccount.Address, msg)
+	}
 	if nil == sh.keyStore {
 		return []byte{}, []byte{}, []byte{}, ErrNilKeyStore
 	}
@@ -198,7 +281,7 @@ func (sh *SignHelper) getSignAccountAndPasswordAtSignHeight(reader AuthReader, b
 		addrs = []common.Address{ca.GetSignAddress()}
 	}
 
-	addr, password, err := reader.GetSignAccountPassword(addrs)
+	addr, password, err := sh.signAccountPassword(reader, addrs)
 	account.Address = addr
 	return account, password, err
 }
@@ -210,7 +293,7 @@ func (sh *SignHelper) getSignAccountAndPassword(reader AuthReader, blkHash commo
 		return account, "", err
 	}
 
-	addr, password, err := reader.GetSignAccountPassword(addrs)
+	addr, password, err := sh.signAccountPassword(reader, addrs)
 	account.Address = addr
 	return account, password, err
 }
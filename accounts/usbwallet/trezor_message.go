@@ -0,0 +1,173 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package usbwallet
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// Types of the Trezor messages exchanged by the driver.
+const (
+	trezorInitialize         uint16 = 0
+	trezorPing               uint16 = 1
+	trezorSuccess            uint16 = 2
+	trezorFailure            uint16 = 3
+	trezorFeatures           uint16 = 17
+	trezorPinMatrixRequest   uint16 = 18
+	trezorPinMatrixAck       uint16 = 19
+	trezorButtonRequest      uint16 = 26
+	trezorButtonAck          uint16 = 27
+	trezorEthereumGetAddress uint16 = 56
+	trezorEthereumAddress    uint16 = 57
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTrezorMessageInvalid = errors.New("trezor: invalid message encoding")
+
+// trezorMessage is a protobuf message of the Trezor wire protocol. The messages
+// exchanged by the driver only hold varint and length delimited fields, so they
+// are encoded by field number instead of through generated bindings.
+type trezorMessage struct {
+	kind  uint16
+	ints  map[int][]uint64 // Varint fields by field number
+	blobs map[int][][]byte // Length delimited fields by field number
+}
+
+func newTrezorMessage(kind uint16) *trezorMessage {
+	return &trezorMessage{
+		kind:  kind,
+		ints:  make(map[int][]uint64),
+		blobs: make(map[int][][]byte),
+	}
+}
+
+// setUint appends a varint value to a field.
+func (m *trezorMessage) setUint(num int, v uint64) *trezorMessage {
+	m.ints[num] = append(m.ints[num], v)
+	return m
+}
+
+// setBool appends a bool value to a field.
+func (m *trezorMessage) setBool(num int, v bool) *trezorMessage {
+	if v {
+		return m.setUint(num, 1)
+	}
+	return m.setUint(num, 0)
+}
+
+// setString appends a string value to a field.
+func (m *trezorMessage) setString(num int, s string) *trezorMessage {
+	m.blobs[num] = append(m.blobs[num], []byte(s))
+	return m
+}
+
+// uint returns the first varint value of a field, zero if missing.
+func (m *trezorMessage) uint(num int) uint64 {
+	if vals := m.ints[num]; len(vals) > 0 {
+		return vals[0]
+	}
+	return 0
+}
+
+// bytes returns the first length delimited value of a field, nil if missing.
+func (m *trezorMessage) bytes(num int) []byte {
+	if vals := m.blobs[num]; len(vals) > 0 {
+		return vals[0]
+	}
+	return nil
+}
+
+// string returns the first length delimited value of a field as a string.
+func (m *trezorMessage) string(num int) string {
+	return string(m.bytes(num))
+}
+
+// marshal encodes the message fields in field number order.
+func (m *trezorMessage) marshal() []byte {
+	nums := make([]int, 0, len(m.ints)+len(m.blobs))
+	for num := range m.ints {
+		nums = append(nums, num)
+	}
+	for num := range m.blobs {
+		if _, ok := m.ints[num]; !ok {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+
+	var (
+		data []byte
+		buf  [binary.MaxVarintLen64]byte
+	)
+	for _, num := range nums {
+		for _, v := range m.ints[num] {
+			data = append(data, buf[:binary.PutUvarint(buf[:], uint64(num)<<3|wireVarint)]...)
+			data = append(data, buf[:binary.PutUvarint(buf[:], v)]...)
+		}
+		for _, blob := range m.blobs[num] {
+			data = append(data, buf[:binary.PutUvarint(buf[:], uint64(num)<<3|wireBytes)]...)
+			data = append(data, buf[:binary.PutUvarint(buf[:], uint64(len(blob)))]...)
+			data = append(data, blob...)
+		}
+	}
+	return data
+}
+
+// unmarshalTrezorMessage decodes the fields of a message, skipping the fixed
+// size ones.
+func unmarshalTrezorMessage(kind uint16, data []byte) (*trezorMessage, error) {
+	m := newTrezorMessage(kind)
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errTrezorMessageInvalid
+		}
+		data = data[n:]
+		num := int(key >> 3)
+
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errTrezorMessageInvalid
+			}
+			m.ints[num] = append(m.ints[num], v)
+			data = data[n:]
+
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return nil, errTrezorMessageInvalid
+			}
+			m.blobs[num] = append(m.blobs[num], data[n:n+int(size)])
+			data = data[n+int(size):]
+
+		case wireFixed64:
+			if len(data) < 8 {
+				return nil, errTrezorMessageInvalid
+			}
+			data = data[8:]
+
+		case wireFixed32:
+			if len(data) < 4 {
+				return nil, errTrezorMessageInvalid
+			}
+			data = data[4:]
+
+		default:
+			return nil, errTrezorMessageInvalid
+		}
+	}
+	return m, nil
+}
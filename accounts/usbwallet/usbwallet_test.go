@@ -0,0 +1,95 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package usbwallet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/log"
+)
+
+// testLedger is a USB device replaying a canned Ledger reply.
+type testLedger struct {
+	sent  bytes.Buffer
+	reply bytes.Buffer
+}
+
+func (d *testLedger) Write(p []byte) (int, error) { return d.sent.Write(p) }
+func (d *testLedger) Read(p []byte) (int, error)  { return d.reply.Read(p) }
+
+// queue frames a reply payload into 64 byte Ledger chunks.
+func (d *testLedger) queue(payload []byte) {
+	data := make([]byte, 2, 2+len(payload))
+	binary.BigEndian.PutUint16(data, uint16(len(payload)))
+	data = append(data, payload...)
+
+	for seq := 0; len(data) > 0; seq++ {
+		chunk := make([]byte, 64)
+		copy(chunk, []byte{0x01, 0x01, 0x05})
+		binary.BigEndian.PutUint16(chunk[3:], uint16(seq))
+		n := copy(chunk[5:], data)
+		data = data[n:]
+		d.reply.Write(chunk)
+	}
+}
+
+func TestLedgerDerive(t *testing.T) {
+	address := common.HexToAddress("0x8605cdbbdb6d264aa742e77020dcbc58fcdce182")
+
+	var reply []byte
+	reply = append(reply, 65)
+	reply = append(reply, make([]byte, 65)...)
+	reply = append(reply, 40)
+	reply = append(reply, []byte(hex.EncodeToString(address[:]))...)
+	reply = append(reply, 0x90, 0x00)
+
+	device := new(testLedger)
+	device.queue(reply)
+
+	driver := &ledgerDriver{device: device, log: log.New()}
+	derived, err := driver.Derive(accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		t.Fatalf("failed to derive address: %v", err)
+	}
+	if derived != address {
+		t.Errorf("address mismatch: have %x, want %x", derived, address)
+	}
+	// The request must carry the derivation path in a single APDU chunk
+	sent := device.sent.Bytes()
+	if len(sent) != 64 {
+		t.Fatalf("request size mismatch: have %d, want 64", len(sent))
+	}
+	if sent[8] != byte(ledgerOpRetrieveAddress) || int(sent[12]) != len(accounts.DefaultBaseDerivationPath) {
+		t.Errorf("invalid request: %x", sent)
+	}
+}
+
+func TestTrezorMessageRoundTrip(t *testing.T) {
+	msg := newTrezorMessage(trezorEthereumGetAddress)
+	for _, component := range accounts.DefaultBaseDerivationPath {
+		msg.setUint(1, uint64(component))
+	}
+	msg.setBool(2, true).setString(3, "label")
+
+	decoded, err := unmarshalTrezorMessage(msg.kind, msg.marshal())
+	if err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.ints, msg.ints) {
+		t.Errorf("varint fields mismatch: have %v, want %v", decoded.ints, msg.ints)
+	}
+	if decoded.string(3) != "label" {
+		t.Errorf("string field mismatch: have %q, want %q", decoded.string(3), "label")
+	}
+	if _, err := unmarshalTrezorMessage(trezorFeatures, []byte{0x0a, 0x05, 'a'}); err != errTrezorMessageInvalid {
+		t.Errorf("truncated message error mismatch: have %v, want %v", err, errTrezorMessageInvalid)
+	}
+}
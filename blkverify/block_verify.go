@@ -222,7 +222,7 @@ func (self *BlockVerify) handleVoteMsg(voteMsg *mc.HD_ConsensusVote) {
 		return
 	}
 
-	process.HandleVote(voteMsg.SignHash, voteMsg.Sign, voteMsg.From)
+	process.HandleVote(voteMsg.SignHash, voteMsg.Sign, voteMsg.BLSSign, voteMsg.From)
 }
 
 func (self *BlockVerify) handleRecoveryMsg(msg *mc.RecoveryStateMsg) {
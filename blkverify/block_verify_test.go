@@ -239,7 +239,7 @@ func newMan(t *testing.T, confOverride func(*man.Config), isBroadcastNode bool)
 	}
 
 	manConf := &man.Config{
-		Genesis:   core.DeveloperGenesisBlock(15, common.Address{}),
+		Genesis:   core.DeveloperGenesisBlock(15, common.Address{}, nil),
 		Manerbase: common.HexToAddress(testAddress),
 		Manash: manash.Config{
 			PowMode: manash.ModeTest,
@@ -19,6 +19,7 @@ type voteInfo struct {
 	sign     common.Signature
 	signHash common.Hash
 	from     common.Address
+	blsSign  []byte // BLS投票签名, 可为空
 }
 
 type unverifiedVotePool struct {
@@ -39,7 +40,7 @@ func newUnverifiedVotePool(logInfo string) *unverifiedVotePool {
 	}
 }
 
-func (vp *unverifiedVotePool) AddVote(signHash common.Hash, sign common.Signature, from common.Address) error {
+func (vp *unverifiedVotePool) AddVote(signHash common.Hash, sign common.Signature, blsSign []byte, from common.Address) error {
 	if (signHash == common.Hash{}) || (sign == common.Signature{}) || (from == common.Address{}) {
 		return ErrParamIsNil
 	}
@@ -49,6 +50,7 @@ func (vp *unverifiedVotePool) AddVote(signHash common.Hash, sign common.Signatur
 		sign:     sign,
 		signHash: signHash,
 		from:     from,
+		blsSign:  blsSign,
 	}
 
 	if err := vp.addVoteToMap(vote); err != nil {
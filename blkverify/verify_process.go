@@ -11,6 +11,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/ca"
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/consensus/blkmanage"
+	"github.com/MatrixAINetwork/go-matrix/consensus/mtxdpos"
 	"github.com/MatrixAINetwork/go-matrix/core"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/event"
@@ -230,12 +231,12 @@ func (p *Process) ProcessRecoveryMsg(msg *mc.RecoveryStateMsg) {
 	parentHash := reqData.req.Header.ParentHash
 	//添加投票
 	for _, sign := range msg.Header.Signatures {
-		verifiedVote, err := p.verifyVote(reqData.hash, sign, common.Address{}, parentHash, false)
+		verifiedVote, err := p.verifyVote(reqData.hash, sign, nil, common.Address{}, parentHash, false)
 		if err != nil {
 			log.Info(p.logExtraInfo(), "处理状态恢复消息", "签名验证失败", "err", err)
 			continue
 		}
-		reqData.addVote(verifiedVote)
+		p.addVote(reqData, verifiedVote)
 	}
 
 	if p.role == common.RoleBroadcast {
@@ -303,12 +304,12 @@ func (p *Process) AddLocalReq(localReq *mc.LocalBlockVerifyConsensusReq) {
 			log.Debug(p.logExtraInfo(), "区块共识请求(本地)处理", "添加早的投票, signHash不匹配")
 			continue
 		}
-		verifiedVote, err := p.verifyVote(reqData.hash, vote.sign, vote.from, parentHash, true)
+		verifiedVote, err := p.verifyVote(reqData.hash, vote.sign, vote.blsSign, vote.from, parentHash, true)
 		if err != nil {
 			log.Debug(p.logExtraInfo(), "区块共识请求(本地)处理", "添加早的投票, 签名验证失败", "err", err, "from", vote.from.Hex(), "reqHash", reqData.hash.TerminalString())
 			continue
 		}
-		reqData.addVote(verifiedVote)
+		p.addVote(reqData, verifiedVote)
 	}
 
 	if p.role == common.RoleBroadcast {
@@ -318,7 +319,7 @@ func (p *Process) AddLocalReq(localReq *mc.LocalBlockVerifyConsensusReq) {
 	}
 }
 
-func (p *Process) HandleVote(signHash common.Hash, vote common.Signature, from common.Address) {
+func (p *Process) HandleVote(signHash common.Hash, vote common.Signature, blsSign []byte, from common.Address) {
 	if (signHash == common.Hash{}) || (vote == common.Signature{}) || (from == common.Address{}) {
 		return
 	}
@@ -329,7 +330,7 @@ func (p *Process) HandleVote(signHash common.Hash, vote common.Signature, from c
 	// 签名不是当前处理的请求
 	if p.curProcessReq == nil || p.curProcessReq.hash != signHash {
 		// 将投票存入未验证票池中
-		p.unverifiedVotes.AddVote(signHash, vote, from)
+		p.unverifiedVotes.AddVote(signHash, vote, blsSign, from)
 		return
 	}
 
@@ -338,13 +339,13 @@ func (p *Process) HandleVote(signHash common.Hash, vote common.Signature, from c
 		return
 	}
 
-	verifiedVote, err := p.verifyVote(signHash, vote, from, p.curProcessReq.req.Header.ParentHash, true)
+	verifiedVote, err := p.verifyVote(signHash, vote, blsSign, from, p.curProcessReq.req.Header.ParentHash, true)
 	if err != nil {
 		log.Info(p.logExtraInfo(), "处理投票消息", "签名验证失败", "err", err)
 		return
 	}
 
-	p.curProcessReq.addVote(verifiedVote)
+	p.addVote(p.curProcessReq, verifiedVote)
 	p.processDPOSOnce()
 }
 
@@ -539,7 +540,9 @@ func (p *Process) sendVote(validate bool) {
 		return
 	}
 
-	p.startVoteMsgSender(&mc.HD_ConsensusVote{SignHash: signHash, Sign: sign, Number: p.number})
+	blsSign := p.signBLSVote(signHash)
+
+	p.startVoteMsgSender(&mc.HD_ConsensusVote{SignHash: signHash, Sign: sign, Number: p.number, BLSSign: blsSign})
 
 	//将自己的投票加入票池
 	p.curProcessReq.addVote(&common.VerifiedSign{
@@ -547,9 +550,27 @@ func (p *Process) sendVote(validate bool) {
 		Account:  ca.GetDepositAddress(),
 		Validate: true,
 		Stock:    0,
+		BLSSign:  blsSign,
 	})
 }
 
+// signBLSVote 启用BLS聚合投票后, 配置了BLS密钥的验证者对投票附加BLS签名
+func (p *Process) signBLSVote(signHash common.Hash) []byte {
+	if !p.signHelper().HasBLSKey() {
+		return nil
+	}
+	cfg, err := p.blockChain().GetBLSVoteCfg(p.curProcessReq.req.Header.ParentHash)
+	if err != nil || !cfg.Active(p.number) {
+		return nil
+	}
+	blsSign, err := p.signHelper().SignBLSHash(signHash.Bytes())
+	if err != nil {
+		log.Error(p.logExtraInfo(), "BLS投票签名失败", err, "高度", p.number)
+		return nil
+	}
+	return blsSign
+}
+
 func (p *Process) notifyVerifiedBlock() {
 	// notify block genor server the result
 	result := mc.BlockLocalVerifyOK{
@@ -597,12 +618,12 @@ func (p *Process) startDPOSVerify(lvResult verifyResult) {
 			log.Debug(p.logExtraInfo(), "开始POS阶段", "添加早的投票, signHash不匹配")
 			continue
 		}
-		verifiedVote, err := p.verifyVote(p.curProcessReq.hash, vote.sign, vote.from, parentHash, true)
+		verifiedVote, err := p.verifyVote(p.curProcessReq.hash, vote.sign, vote.blsSign, vote.from, parentHash, true)
 		if err != nil {
 			log.Debug(p.logExtraInfo(), "开始POS阶段", "添加早的投票, 签名验证失败", "err", err, "from", vote.from.Hex(), "reqHash", p.curProcessReq.hash.TerminalString())
 			continue
 		}
-		p.curProcessReq.addVote(verifiedVote)
+		p.addVote(p.curProcessReq, verifiedVote)
 	}
 
 	p.state = StateDPOSVerify
@@ -633,10 +654,32 @@ func (p *Process) processDPOSOnce() {
 	log.Info(p.logExtraInfo(), "POS验证处理", "POS通过", "正确签名数量", len(rightSigns), "高度", p.number)
 	p.curProcessReq.posFinished = true
 	p.curProcessReq.req.Header.Signatures = rightSigns
+	p.aggregateVotes(signs, rightSigns)
 
 	p.finishedProcess()
 }
 
+// aggregateVotes 启用BLS聚合投票后, 将带BLS签名的同意票聚合到区块头, 并从签名列表中移除
+func (p *Process) aggregateVotes(signs []*common.VerifiedSign, rightSigns []common.Signature) {
+	header := p.curProcessReq.req.Header
+	if manversion.VersionCmp(string(header.Version), manversion.VersionAIMine) < 0 {
+		return
+	}
+	cfg, err := p.blockChain().GetBLSVoteCfg(header.ParentHash)
+	if err != nil || !cfg.Active(p.number) {
+		return
+	}
+	aggregate, remainSigns, err := mtxdpos.AggregateVerifiedSigns(p.blockChain(), p.curProcessReq.hash, signs, rightSigns, header.ParentHash)
+	if err != nil {
+		log.Info(p.logExtraInfo(), "BLS聚合投票", "聚合失败, 使用全部签名", "err", err, "高度", p.number)
+		return
+	}
+	log.Debug(p.logExtraInfo(), "BLS聚合投票", "聚合成功", "聚合数量", len(rightSigns)-len(remainSigns), "剩余签名数量", len(remainSigns), "高度", p.number)
+	// 保留领导者提议的检查点投票
+	header.AggregateVotes = append([]types.AggregateVote{*aggregate}, header.AggregateVotes...)
+	header.Signatures = remainSigns
+}
+
 func (p *Process) finishedProcess() {
 	result := p.curProcessReq.localVerifyResult
 	if result == localVerifyResultProcessing {
@@ -691,7 +734,8 @@ func (p *Process) changeState(targetState State) {
 	}
 }
 
-func (p *Process) verifyVote(signHash common.Hash, vote common.Signature, from common.Address, blkHash common.Hash, verifyFrom bool) (*common.VerifiedSign, error) {
+// verifyVote 验证投票签名, BLS签名在聚合时验证
+func (p *Process) verifyVote(signHash common.Hash, vote common.Signature, blsSign []byte, from common.Address, blkHash common.Hash, verifyFrom bool) (*common.VerifiedSign, error) {
 	depositAccount, signAccount, validate, err := p.signHelper().VerifySignWithValidateDependHash(signHash.Bytes(), vote.Bytes(), blkHash)
 	if err != nil {
 		return nil, err
@@ -706,9 +750,20 @@ func (p *Process) verifyVote(signHash common.Hash, vote common.Signature, from c
 		Account:  depositAccount,
 		Validate: validate,
 		Stock:    0,
+		BLSSign:  blsSign,
 	}, nil
 }
 
+// addVote 添加验证通过的投票, 同时按共识轮次记录到证据池中用于发现双签
+func (p *Process) addVote(data *reqData, vote *common.VerifiedSign) {
+	data.addVote(vote)
+	parent := p.blockChain().GetHeaderByHash(data.req.Header.ParentHash)
+	if parent == nil {
+		return
+	}
+	p.blockChain().EvidencePool().AddVote(parent, data.req.Header, vote.Sign, data.req.ConsensusTurn.TotalTurns())
+}
+
 func (p *Process) resendMineReq() {
 	parentHeader := p.pm.bc.GetHeaderByNumber(p.number - 1)
 	if parentHeader == nil {
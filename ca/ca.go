@@ -32,6 +32,14 @@ type TopologyGraphReader interface {
 	GetSuperSeq(blockHash common.Hash) (uint64, error)
 }
 
+// RoleChangeEvent is posted when the role of the local node changes.
+type RoleChangeEvent struct {
+	OldRole   common.RoleType
+	NewRole   common.RoleType
+	BlockNum  uint64      // Number of the block the new role is taken at
+	BlockHash common.Hash // Hash of the block the new role is taken at
+}
+
 // Identity stand for node's identity.
 type Identity struct {
 	// self nodeId
@@ -66,6 +74,10 @@ type Identity struct {
 	// sub to unsubscribe block channel
 	sub event.Subscription
 
+	// feed of the role changes of the local node
+	roleFeed  event.Feed
+	roleScope event.SubscriptionScope
+
 	// logger
 	log log.Logger
 
@@ -108,6 +120,7 @@ func Start(id discover.NodeID, path string, addr common.Address) {
 
 	defer func() {
 		ide.sub.Unsubscribe()
+		ide.roleScope.Close()
 
 		close(ide.quit)
 		close(ide.blockChan)
@@ -130,6 +143,7 @@ func Start(id discover.NodeID, path string, addr common.Address) {
 		case block := <-ide.blockChan:
 			header := block.Header()
 			hash := block.Hash()
+			electedLists.newHead(header.Number.Uint64(), hash, header.ParentHash)
 			ide.currentHeight = header.Number
 			ide.hash = block.Hash()
 
@@ -137,6 +151,12 @@ func Start(id discover.NodeID, path string, addr common.Address) {
 
 			// init current height deposit
 			ide.deposit, _ = GetElectedByHeightWithdrawByHash(header.Hash())
+			// accounts with a registered consensus key sign the next block with it
+			if deposit, err := depoistInfo.SetConsensusSignersByHash(hash, header.Number.Uint64()+1, ide.deposit); err != nil {
+				ide.log.Error("set consensus signers", "error", err)
+			} else {
+				ide.deposit = deposit
+			}
 
 			// get broadcast interval
 			bcInterval, err := manparams.GetBCIntervalInfoByHash(hash)
@@ -201,8 +221,10 @@ func Start(id discover.NodeID, path string, addr common.Address) {
 			ide.prevElect = newElect
 
 			// init topology
+			prevRole := ide.currentRole
 			initCurrentTopology()
 			initNowTopologyResult()
+			ide.notifyRoleChange(prevRole, header.Number.Uint64(), hash)
 
 			// get nodes in buckets
 			nodesInBuckets := getNodesInBuckets(header.Hash())
@@ -223,6 +245,26 @@ func Start(id discover.NodeID, path string, addr common.Address) {
 	}
 }
 
+// notifyRoleChange posts a role change event if the role of the local node
+// differs from the previous one.
+func (ide *Identity) notifyRoleChange(prevRole common.RoleType, number uint64, hash common.Hash) {
+	ide.lock.RLock()
+	role := ide.currentRole
+	ide.lock.RUnlock()
+
+	if role == prevRole {
+		return
+	}
+	log.Info("CA", "local role changed", role, "old role", prevRole, "height", number)
+	ide.roleFeed.Send(RoleChangeEvent{OldRole: prevRole, NewRole: role, BlockNum: number, BlockHash: hash})
+}
+
+// SubscribeRoleChange registers a subscription of RoleChangeEvent, posted when
+// the role of the local node changes at an election or broadcast boundary.
+func SubscribeRoleChange(ch chan<- RoleChangeEvent) event.Subscription {
+	return ide.roleScope.Track(ide.roleFeed.Subscribe(ch))
+}
+
 // Stop this Identity.
 func Stop() {
 	ide.log.Info("identity stop")
@@ -366,32 +408,44 @@ func InDuration() bool {
 
 // GetElectedByHeightAndRole get elected node, miner or validator by block height and type.
 func GetElectedByHeightAndRole(height *big.Int, roleType common.RoleType) ([]vm.DepositDetail, error) {
-	return depoistInfo.GetDepositList(height, roleType)
+	return electedLists.byHeight(electedByRole, height, roleType, func() ([]vm.DepositDetail, error) {
+		return depoistInfo.GetDepositList(height, roleType)
+	})
 }
 
 // GetElectedByHeight get all elected node by height.
 func GetElectedByHeight(height *big.Int) ([]vm.DepositDetail, error) {
-	return depoistInfo.GetAllDeposit(height)
+	return electedLists.byHeight(electedAll, height, common.RoleAll, func() ([]vm.DepositDetail, error) {
+		return depoistInfo.GetAllDeposit(height)
+	})
 }
 
 // GetElectedByHeightWithdraw get all info in deposit.
 func GetElectedByHeightWithdraw(height *big.Int) ([]vm.DepositDetail, error) {
-	return depoistInfo.GetDepositAndWithDrawList(height)
+	return electedLists.byHeight(electedWithdraw, height, common.RoleAll, func() ([]vm.DepositDetail, error) {
+		return depoistInfo.GetDepositAndWithDrawList(height)
+	})
 }
 
 // GetElectedByHeightAndRole get elected node, miner or validator by block height and type.
 func GetElectedByHeightAndRoleByHash(hash common.Hash, roleType common.RoleType) ([]vm.DepositDetail, error) {
-	return depoistInfo.GetDepositListByHash(hash, roleType)
+	return electedLists.byHash(electedByRole, hash, roleType, func() ([]vm.DepositDetail, error) {
+		return depoistInfo.GetDepositListByHash(hash, roleType)
+	})
 }
 
 // GetElectedByHeight get all elected node by height.
 func GetElectedByHeightByHash(hash common.Hash) ([]vm.DepositDetail, error) {
-	return depoistInfo.GetAllDepositByHash(hash)
+	return electedLists.byHash(electedAll, hash, common.RoleAll, func() ([]vm.DepositDetail, error) {
+		return depoistInfo.GetAllDepositByHash(hash)
+	})
 }
 
 // GetElectedByHeightWithdraw get all info in deposit.
 func GetElectedByHeightWithdrawByHash(hash common.Hash) ([]vm.DepositDetail, error) {
-	return depoistInfo.GetDepositAndWithDrawListByHash(hash)
+	return electedLists.byHash(electedWithdraw, hash, common.RoleAll, func() ([]vm.DepositDetail, error) {
+		return depoistInfo.GetDepositAndWithDrawListByHash(hash)
+	})
 }
 
 // GetNodeNumber
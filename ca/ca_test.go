@@ -0,0 +1,46 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package ca
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+// Tests that role change events are only posted when the local role changes.
+func TestRoleChangeFeed(t *testing.T) {
+	ide := newIde()
+
+	ch := make(chan RoleChangeEvent, 4)
+	sub := ide.roleScope.Track(ide.roleFeed.Subscribe(ch))
+	defer sub.Unsubscribe()
+
+	ide.currentRole = common.RoleMiner
+	ide.notifyRoleChange(common.RoleNil, 1, common.Hash{0x01})
+	ide.notifyRoleChange(common.RoleMiner, 2, common.Hash{0x02})
+	ide.currentRole = common.RoleValidator
+	ide.notifyRoleChange(common.RoleMiner, 3, common.Hash{0x03})
+
+	want := []RoleChangeEvent{
+		{OldRole: common.RoleNil, NewRole: common.RoleMiner, BlockNum: 1, BlockHash: common.Hash{0x01}},
+		{OldRole: common.RoleMiner, NewRole: common.RoleValidator, BlockNum: 3, BlockHash: common.Hash{0x03}},
+	}
+	for i, w := range want {
+		select {
+		case ev := <-ch:
+			if ev != w {
+				t.Errorf("event %d: have %+v, want %+v", i, ev, w)
+			}
+		default:
+			t.Fatalf("event %d: missing", i)
+		}
+	}
+	select {
+	case ev := <-ch:
+		t.Errorf("unexpected event %+v", ev)
+	default:
+	}
+}
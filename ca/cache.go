@@ -0,0 +1,138 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package ca
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// electedCacheLimit is the number of elected lists kept in the cache.
+const electedCacheLimit = 256
+
+var (
+	electedCacheHitMeter  = metrics.NewRegisteredMeter("ca/elected/cache/hit", nil)
+	electedCacheMissMeter = metrics.NewRegisteredMeter("ca/elected/cache/miss", nil)
+)
+
+// electedKind is the deposit list an elected cache entry holds.
+type electedKind uint8
+
+const (
+	electedByRole   electedKind = iota // Deposits of a role
+	electedAll                         // All deposits
+	electedWithdraw                    // All deposits including the withdrawing ones
+)
+
+// electedKey identifies a cached deposit list. Lists are looked up either by
+// the height of a canonical block or by block hash.
+type electedKey struct {
+	kind   electedKind
+	height uint64
+	hash   common.Hash
+	role   common.RoleType
+}
+
+// electedCache is an LRU cache of the deposit lists retrieved from the state.
+// Lists retrieved by height are bound to the canonical chain and are dropped
+// when it changes, lists retrieved by hash never go stale.
+type electedCache struct {
+	cache *lru.Cache
+
+	lock sync.Mutex  // Protects head
+	head common.Hash // Hash of the last head the cache was updated with
+}
+
+func newElectedCache() *electedCache {
+	cache, _ := lru.New(electedCacheLimit)
+	return &electedCache{cache: cache}
+}
+
+var electedLists = newElectedCache()
+
+// get retrieves a deposit list from the cache, or retrieves it with the given
+// function and caches it. Errors are not cached.
+func (c *electedCache) get(key electedKey, retrieve func() ([]vm.DepositDetail, error)) ([]vm.DepositDetail, error) {
+	if cached, ok := c.cache.Get(key); ok {
+		electedCacheHitMeter.Mark(1)
+		return copyDeposits(cached.([]vm.DepositDetail)), nil
+	}
+	electedCacheMissMeter.Mark(1)
+
+	deposits, err := retrieve()
+	if err != nil {
+		return deposits, err
+	}
+	c.cache.Add(key, copyDeposits(deposits))
+	return deposits, nil
+}
+
+// byHeight retrieves a deposit list of a canonical block through the cache.
+func (c *electedCache) byHeight(kind electedKind, height *big.Int, role common.RoleType, retrieve func() ([]vm.DepositDetail, error)) ([]vm.DepositDetail, error) {
+	// Symbolic heights (latest, pending) move with the chain, never cache them
+	if height == nil || height.Sign() < 0 {
+		return retrieve()
+	}
+	return c.get(electedKey{kind: kind, height: height.Uint64(), role: role}, retrieve)
+}
+
+// byHash retrieves a deposit list of any block through the cache.
+func (c *electedCache) byHash(kind electedKind, hash common.Hash, role common.RoleType, retrieve func() ([]vm.DepositDetail, error)) ([]vm.DepositDetail, error) {
+	return c.get(electedKey{kind: kind, hash: hash, role: role}, retrieve)
+}
+
+// newHead updates the cache with a new chain head. If the head does not extend
+// the previous one the chain was reorganised and the lists retrieved by height
+// are dropped, otherwise only the ones at or above the new head are.
+func (c *electedCache) newHead(number uint64, hash, parent common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	reorg := c.head != (common.Hash{}) && c.head != parent
+	c.head = hash
+
+	for _, key := range c.cache.Keys() {
+		k := key.(electedKey)
+		if k.hash != (common.Hash{}) {
+			continue
+		}
+		if reorg || k.height >= number {
+			c.cache.Remove(key)
+		}
+	}
+}
+
+// copyDeposits deep copies a deposit list, so that callers modifying the list
+// or the amounts they got do not corrupt the cache.
+func copyDeposits(deposits []vm.DepositDetail) []vm.DepositDetail {
+	if deposits == nil {
+		return nil
+	}
+	cpy := make([]vm.DepositDetail, len(deposits))
+	for i, deposit := range deposits {
+		cpy[i] = vm.DepositDetail{
+			Address:     deposit.Address,
+			SignAddress: deposit.SignAddress,
+			Deposit:     copyBig(deposit.Deposit),
+			WithdrawH:   copyBig(deposit.WithdrawH),
+			OnlineTime:  copyBig(deposit.OnlineTime),
+			Role:        copyBig(deposit.Role),
+		}
+	}
+	return cpy
+}
+
+// copyBig copies a possibly nil big integer.
+func copyBig(x *big.Int) *big.Int {
+	if x == nil {
+		return nil
+	}
+	return new(big.Int).Set(x)
+}
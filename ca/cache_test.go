@@ -0,0 +1,84 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package ca
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+)
+
+// Tests that deposit lists retrieved by height are cached until the canonical
+// chain changes, while the ones retrieved by hash survive reorgs.
+func TestElectedCacheInvalidation(t *testing.T) {
+	cache := newElectedCache()
+
+	retrievals := 0
+	retrieve := func() ([]vm.DepositDetail, error) {
+		retrievals++
+		return []vm.DepositDetail{{Address: common.Address{0x01}}}, nil
+	}
+	byHeight := func(height int64) {
+		cache.byHeight(electedByRole, big.NewInt(height), common.RoleValidator, retrieve)
+	}
+	byHash := func(hash common.Hash) {
+		cache.byHash(electedByRole, hash, common.RoleValidator, retrieve)
+	}
+	check := func(step string, want int) {
+		if retrievals != want {
+			t.Fatalf("%s: retrievals mismatch: have %d, want %d", step, retrievals, want)
+		}
+	}
+	cache.newHead(10, common.Hash{0x0a}, common.Hash{0x09})
+
+	byHeight(5)
+	byHeight(5)
+	byHash(common.Hash{0x05})
+	byHash(common.Hash{0x05})
+	check("first lookups", 2)
+
+	byHeight(-1)
+	byHeight(-1)
+	check("latest lookups", 4)
+
+	// Extending the chain keeps the lists below the head
+	cache.newHead(11, common.Hash{0x0b}, common.Hash{0x0a})
+	byHeight(5)
+	check("chain extended", 4)
+
+	// A reorg drops the lists retrieved by height only
+	cache.newHead(11, common.Hash{0xbb}, common.Hash{0xaa})
+	byHeight(5)
+	byHash(common.Hash{0x05})
+	check("chain reorged", 5)
+}
+
+// Tests that modifying the amounts of a retrieved deposit list does not corrupt
+// the cached one.
+func TestElectedCacheCopy(t *testing.T) {
+	cache := newElectedCache()
+
+	retrieve := func() ([]vm.DepositDetail, error) {
+		return []vm.DepositDetail{{Address: common.Address{0x01}, Deposit: big.NewInt(100)}}, nil
+	}
+	deposits, _ := cache.byHash(electedAll, common.Hash{0x01}, common.RoleValidator, retrieve)
+	deposits[0].Deposit.SetInt64(1)
+
+	deposits, _ = cache.byHash(electedAll, common.Hash{0x01}, common.RoleValidator, retrieve)
+	if deposits[0].Deposit.Int64() != 100 {
+		t.Fatalf("cached deposit modified: have %v, want 100", deposits[0].Deposit)
+	}
+	deposits[0].Deposit.SetInt64(1)
+
+	deposits, _ = cache.byHash(electedAll, common.Hash{0x01}, common.RoleValidator, retrieve)
+	if deposits[0].Deposit.Int64() != 100 {
+		t.Fatalf("cached deposit modified through a cache hit: have %v, want 100", deposits[0].Deposit)
+	}
+	if deposits[0].WithdrawH != nil {
+		t.Errorf("nil amount copied as %v", deposits[0].WithdrawH)
+	}
+}
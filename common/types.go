@@ -371,6 +371,7 @@ type VerifiedSign struct {
 	Account  Address   `json:"account"`
 	Validate bool      `json:"validate"`
 	Stock    uint16    `json:"stock"`
+	BLSSign  []byte    `json:"blsSign,omitempty"`
 }
 
 type VerifiedSign1 struct {
@@ -467,6 +468,13 @@ const (
 	ExtraUnGasTxsType         byte = 12  //交易费奖励类型
 	ExtraUnGasLotteryTxType   byte = 13  //彩票奖励类型
 	ExtraSetBlackListTxType   byte = 14  //设置黑名单交易
+	ExtraEvidenceTxType       byte = 15  //作恶证据交易
+	ExtraRewardGovernanceTx   byte = 16  //奖励分配比例治理交易
+	ExtraScheduledTxType      byte = 17  //预约交易(到达指定高度或时间后才可执行)
+	ExtraConsensusKeyTxType   byte = 18  //共识密钥注册交易
+	ExtraParamUpdateTxType    byte = 19  //链参数更新治理交易
+	ExtraBridgeAttestTxType   byte = 21  //跨链证明交易
+	ExtraRevocableHeight      byte = 22  //按区块高度设置撤销期的可撤销交易
 	ExtraSuperBlockTx         byte = 120 //超级区块交易
 )
 
@@ -520,6 +528,8 @@ type EntrustType struct {
 	StartTime    uint64
 	EndTime      uint64
 	EntrustCount uint32 //委托次数
+
+	EntrustTxTypes []uint32 `json:",omitempty"` //委托gas可支付的交易类型, 为空时不限制
 }
 
 type AuthType struct {
@@ -532,6 +542,8 @@ type AuthType struct {
 	StartTime       uint64
 	EndTime         uint64
 	EntrustCount    uint32 //授权委托次数
+
+	EntrustTxTypes []uint32 `json:",omitempty"` //委托gas可支付的交易类型, 为空时不限制
 }
 
 type CoinRoot struct {
@@ -96,6 +96,8 @@ type txPool interface {
 	// The slice should be modifiable by the caller.
 	Pending() (map[string]map[common.Address]types.SelfTransactions, error)
 	GetAllSpecialTxs() (reqVal map[common.Address][]types.SelfTransaction)
+	DrainSpecialTxs() (reqVal map[common.Address][]types.SelfTransaction)
+	SelectTxs(budget core.TxBudget, withSpecials bool) ([]types.CoinSelfTransaction, error)
 }
 
 type Mux interface {
@@ -126,6 +128,7 @@ var (
 type ManBlkManage struct {
 	support        BlKSupport
 	mapManBlkPlugs map[string]MANBLKPlUGS
+	builderHooks   []BlockBuilderHook
 }
 
 func New(support BlKSupport) (*ManBlkManage, error) {
@@ -241,6 +244,10 @@ func (bd *ManBlkManage) Finalize(types string, version string, header *types.Hea
 		log.Error(LogManBlk, "获取插件失败", "")
 		return nil, nil, errors.New("获取插件失败")
 	}
+	if err := bd.runBuilderHooks(types, header, txs); err != nil {
+		log.Warn(LogManBlk, "区块生成扩展终止出块", err, "高度", header.Number.Uint64())
+		return nil, nil, err
+	}
 	return plug.Finalize(bd.support, header, state, txs, uncles, receipts, args)
 }
 
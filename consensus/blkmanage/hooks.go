@@ -0,0 +1,97 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package blkmanage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+// ErrSkipBlock 由BlockBuilderHook返回, 表示本节点放弃本轮出块
+var ErrSkipBlock = errors.New("block skipped by builder hook")
+
+// BlockBuildContext 出块节点封装区块前提供给BlockBuilderHook的区块信息
+type BlockBuildContext struct {
+	Type   string // CommonBlk 或 BroadcastBlk
+	Header *types.Header
+	Txs    []types.CoinSelfTransaction
+}
+
+// BlockBuilderHook 出块节点在区块封装前调用的扩展点, 通过man.Config.BlockBuilderHooks在节点服务创建时注册
+type BlockBuilderHook interface {
+	// BeforeSeal 可以修改区块头的属性(如extraData), 返回ErrSkipBlock时放弃本轮出块, 返回其他错误时出块失败
+	BeforeSeal(ctx *BlockBuildContext) error
+}
+
+// RegisterBuilderHook 注册区块生成扩展, 按注册顺序调用
+func (bd *ManBlkManage) RegisterBuilderHook(hook BlockBuilderHook) {
+	bd.builderHooks = append(bd.builderHooks, hook)
+}
+
+// runBuilderHooks 依次调用已注册的区块生成扩展, 并检查extraData长度
+func (bd *ManBlkManage) runBuilderHooks(blkType string, header *types.Header, txs []types.CoinSelfTransaction) error {
+	if len(bd.builderHooks) == 0 {
+		return nil
+	}
+	ctx := &BlockBuildContext{Type: blkType, Header: header, Txs: txs}
+	for _, hook := range bd.builderHooks {
+		if err := hook.BeforeSeal(ctx); err != nil {
+			return err
+		}
+	}
+	if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
+		return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra), params.MaximumExtraDataSize)
+	}
+	return nil
+}
+
+// ExtraDataHook 将普通区块的extraData设置为指定内容
+type ExtraDataHook []byte
+
+func (h ExtraDataHook) BeforeSeal(ctx *BlockBuildContext) error {
+	if ctx.Type == CommonBlk {
+		ctx.Header.Extra = common.CopyBytes(h)
+	}
+	return nil
+}
+
+// InclusionListHook 要求普通区块打包列表中仍在本地交易池里的交易, 缺少任何一笔时放弃本轮出块
+type InclusionListHook struct {
+	Hashes  []common.Hash
+	Pending func(hash common.Hash) bool // 交易是否仍在交易池中等待打包
+}
+
+func (h InclusionListHook) BeforeSeal(ctx *BlockBuildContext) error {
+	if ctx.Type != CommonBlk || h.Pending == nil {
+		return nil
+	}
+	included := make(map[common.Hash]bool)
+	for _, coinTxs := range ctx.Txs {
+		for _, tx := range coinTxs.Txser {
+			included[tx.Hash()] = true
+		}
+	}
+	for _, hash := range h.Hashes {
+		if h.Pending(hash) && !included[hash] {
+			return ErrSkipBlock
+		}
+	}
+	return nil
+}
+
+// SkipBroadcastHook 按高度跳过广播区块, Skip返回true的广播轮次本节点不出块
+type SkipBroadcastHook struct {
+	Skip func(number uint64) bool
+}
+
+func (h SkipBroadcastHook) BeforeSeal(ctx *BlockBuildContext) error {
+	if ctx.Type == BroadcastBlk && h.Skip != nil && h.Skip(ctx.Header.Number.Uint64()) {
+		return ErrSkipBlock
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package blkmanage
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+func TestManBlkManage_runBuilderHooks(t *testing.T) {
+	test, _ := New(nil)
+	header := &types.Header{Number: big.NewInt(100)}
+
+	test.RegisterBuilderHook(ExtraDataHook("matrix"))
+	if err := test.runBuilderHooks(CommonBlk, header, nil); err != nil {
+		t.Fatalf("执行区块生成扩展失败 %v", err)
+	}
+	if !bytes.Equal(header.Extra, []byte("matrix")) {
+		t.Errorf("extraData错误 %x", header.Extra)
+	}
+
+	test.RegisterBuilderHook(SkipBroadcastHook{Skip: func(number uint64) bool { return number == 100 }})
+	if err := test.runBuilderHooks(BroadcastBlk, header, nil); err != ErrSkipBlock {
+		t.Errorf("广播区块应被跳过 %v", err)
+	}
+
+	test.RegisterBuilderHook(ExtraDataHook(make([]byte, 1024)))
+	if err := test.runBuilderHooks(CommonBlk, header, nil); err == nil {
+		t.Errorf("extraData超长应返回错误")
+	}
+}
+
+func TestInclusionListHook(t *testing.T) {
+	hash := common.HexToHash("0x01")
+	hook := InclusionListHook{Hashes: []common.Hash{hash}, Pending: func(common.Hash) bool { return true }}
+	ctx := &BlockBuildContext{Type: CommonBlk, Header: &types.Header{Number: big.NewInt(1)}}
+	if err := hook.BeforeSeal(ctx); err != ErrSkipBlock {
+		t.Errorf("缺少必须打包的交易时应放弃出块 %v", err)
+	}
+	hook.Pending = func(common.Hash) bool { return false }
+	if err := hook.BeforeSeal(ctx); err != nil {
+		t.Errorf("交易已不在交易池时不应放弃出块 %v", err)
+	}
+}
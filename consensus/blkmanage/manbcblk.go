@@ -151,8 +151,13 @@ func (bd *ManBCBlkPlug) ProcessState(support BlKSupport, header *types.Header, a
 		log.Error(LogManBlk, "执行算力检测处理错误", err, "高度", header.Number)
 		return nil, nil, nil, nil, nil, nil, err
 	}
+	err = support.BlockChain().ProcessRewardGovernance(string(header.Version), work.State, header)
+	if err != nil {
+		log.Error(LogManBlk, "执行奖励治理处理错误", err, "高度", header.Number)
+		return nil, nil, nil, nil, nil, nil, err
+	}
 
-	mapTxs := support.TxPool().GetAllSpecialTxs()
+	mapTxs := support.TxPool().DrainSpecialTxs()
 	Txs := make([]types.SelfTransaction, 0)
 	for _, txs := range mapTxs {
 		for _, tx := range txs {
@@ -239,6 +244,11 @@ func (bd *ManBCBlkPlug) VerifyTxsAndState(support BlKSupport, verifyHeader *type
 		log.Error(LogManBlk, "执行算力检测处理错误", err, "高度", verifyHeader.Number)
 		return nil, nil, nil, nil, err
 	}
+	err = support.BlockChain().ProcessRewardGovernance(string(verifyHeader.Version), work.State, verifyHeader)
+	if err != nil {
+		log.Error(LogManBlk, "执行奖励治理处理错误", err, "高度", verifyHeader.Number)
+		return nil, nil, nil, nil, err
+	}
 	//执行交易
 	work.ProcessBroadcastTransactions(support.EventMux(), verifyTxs)
 
@@ -234,7 +234,7 @@ func (bd *ManBlkBasePlug) ProcessState(support BlKSupport, header *types.Header,
 		return nil, nil, nil, nil, nil, nil, err
 	}
 
-	txsCode, originalTxs := work.ProcessTransactions(support.EventMux(), support.TxPool(), upTimeMap)
+	txsCode, originalTxs := work.ProcessTransactions(support.EventMux(), newSelectedTxPool(support.TxPool(), header), upTimeMap)
 
 	//block := types.NewBlock(header, types.MakeCurencyBlock(types.GetCoinTX(finalTxs), work.Receipts, nil), nil)
 	block := types.NewBlock(header, types.MakeCurencyBlock(work.GetTxs(), work.Receipts, nil), nil)
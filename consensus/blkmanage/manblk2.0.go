@@ -105,6 +105,13 @@ func (bd *ManBlkV2Plug) setBasePower(header *types.Header) {
 	header.BasePowers = make([]types.BasePowers, 0)
 }
 
+// setCheckpointVote 携带收集到超过2/3投票的检查点投票, 使检查点终局
+func (bd *ManBlkV2Plug) setCheckpointVote(support BlKSupport, header *types.Header) {
+	if vote := support.BlockChain().PendingCheckpointVote(header); vote != nil {
+		header.AggregateVotes = []types.AggregateVote{*vote}
+	}
+}
+
 func (bd *ManBlkV2Plug) setGasLimit(header *types.Header, parent *types.Block) {
 	header.GasLimit = core.CalcGasLimit(parent)
 }
@@ -225,6 +232,7 @@ func (bd *ManBlkV2Plug) Prepare(version string, support BlKSupport, interval *mc
 	if nil != err {
 		return nil, nil, err
 	}
+	bd.setCheckpointVote(support, originHeader)
 	if err := support.BlockChain().Engine(originHeader.Version).Prepare(support.BlockChain(), originHeader); err != nil {
 		log.Error(LogManBlk, "Failed to prepare header for mining", err)
 		return nil, nil, err
@@ -267,7 +275,22 @@ func (bd *ManBlkV2Plug) ProcessState(support BlKSupport, header *types.Header, a
 		log.Error(LogManBlk, "执行算力检测处理错误", err, "高度", header.Number)
 		return nil, nil, nil, nil, nil, nil, err
 	}
-	txsCode, originalTxs := work.ProcessTransactions(support.EventMux(), support.TxPool(), upTimeMap)
+	_, err = support.BlockChain().ProcessHeartbeatSlash(string(header.Version), work.State, header)
+	if err != nil {
+		log.Error(LogManBlk, "执行心跳惩罚处理错误", err, "高度", header.Number)
+		return nil, nil, nil, nil, nil, nil, err
+	}
+	_, err = support.BlockChain().ProcessSeedCommitReveal(string(header.Version), work.State, header)
+	if err != nil {
+		log.Error(LogManBlk, "执行随机种子揭示处理错误", err, "高度", header.Number)
+		return nil, nil, nil, nil, nil, nil, err
+	}
+	err = support.BlockChain().ProcessCheckpointVote(string(header.Version), work.State, header)
+	if err != nil {
+		log.Error(LogManBlk, "执行检查点投票处理错误", err, "高度", header.Number)
+		return nil, nil, nil, nil, nil, nil, err
+	}
+	txsCode, originalTxs := work.ProcessTransactions(support.EventMux(), newSelectedTxPool(support.TxPool(), header), upTimeMap)
 
 	//block := types.NewBlock(header, types.MakeCurencyBlock(types.GetCoinTX(finalTxs), work.Receipts, nil), nil)
 	block := types.NewBlock(header, types.MakeCurencyBlock(work.GetTxs(), work.Receipts, nil), nil)
@@ -401,6 +424,21 @@ func (bd *ManBlkV2Plug) VerifyTxsAndState(support BlKSupport, verifyHeader *type
 		log.Error(LogManBlk, "执行算力检测处理错误", err, "高度", verifyHeader.Number)
 		return nil, nil, nil, nil, err
 	}
+	_, err = support.BlockChain().ProcessHeartbeatSlash(string(verifyHeader.Version), work.State, localHeader)
+	if err != nil {
+		log.Error(LogManBlk, "执行心跳惩罚处理错误", err, "高度", verifyHeader.Number)
+		return nil, nil, nil, nil, err
+	}
+	_, err = support.BlockChain().ProcessSeedCommitReveal(string(verifyHeader.Version), work.State, localHeader)
+	if err != nil {
+		log.Error(LogManBlk, "执行随机种子揭示处理错误", err, "高度", verifyHeader.Number)
+		return nil, nil, nil, nil, err
+	}
+	err = support.BlockChain().ProcessCheckpointVote(string(verifyHeader.Version), work.State, localHeader)
+	if err != nil {
+		log.Error(LogManBlk, "执行检查点投票处理错误", err, "高度", verifyHeader.Number)
+		return nil, nil, nil, nil, err
+	}
 	err = work.ConsensusTransactions(support.EventMux(), verifyTxs, uptimeMap)
 	if err != nil {
 		log.Error(LogManBlk, "交易验证，共识执行交易出错!", err, "高度", verifyHeader.Number.Uint64())
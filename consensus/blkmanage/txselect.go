@@ -0,0 +1,40 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package blkmanage
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+// selectedTxPool 将交易池的TxSelector选出的交易作为Pending提供给Work执行,
+// 使普通区块按选择策略和区块gas预算打包交易
+type selectedTxPool struct {
+	txPool
+	budget core.TxBudget
+}
+
+func newSelectedTxPool(pool txPool, header *types.Header) *selectedTxPool {
+	return &selectedTxPool{txPool: pool, budget: core.TxBudget{Gas: header.GasLimit}}
+}
+
+// Pending 返回选择策略选出的交易, 按币种和账户分组, 同一账户内保持选择顺序
+func (p *selectedTxPool) Pending() (map[string]map[common.Address]types.SelfTransactions, error) {
+	selected, err := p.txPool.SelectTxs(p.budget, false)
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[string]map[common.Address]types.SelfTransactions, len(selected))
+	for _, coinTxs := range selected {
+		if pending[coinTxs.CoinType] == nil {
+			pending[coinTxs.CoinType] = make(map[common.Address]types.SelfTransactions)
+		}
+		for _, tx := range coinTxs.Txser {
+			from := tx.From()
+			pending[coinTxs.CoinType][from] = append(pending[coinTxs.CoinType][from], tx)
+		}
+	}
+	return pending, nil
+}
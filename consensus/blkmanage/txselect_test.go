@@ -0,0 +1,47 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package blkmanage
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+type selectTestPool struct {
+	txPool
+	budget   core.TxBudget
+	selected []types.CoinSelfTransaction
+}
+
+func (p *selectTestPool) SelectTxs(budget core.TxBudget, withSpecials bool) ([]types.CoinSelfTransaction, error) {
+	p.budget = budget
+	return p.selected, nil
+}
+
+func TestSelectedTxPool_Pending(t *testing.T) {
+	from := common.HexToAddress("0x01")
+	txs := make(types.SelfTransactions, 3)
+	for i := range txs {
+		tx := types.NewTransaction(uint64(i), common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil, big.NewInt(0), big.NewInt(0), big.NewInt(0), 0, 0, params.MAN_COIN, 0)
+		tx.SetFromLoad(from)
+		txs[i] = tx
+	}
+	pool := &selectTestPool{selected: []types.CoinSelfTransaction{{CoinType: params.MAN_COIN, Txser: txs[:2]}}}
+	pending, err := newSelectedTxPool(pool, &types.Header{GasLimit: 42000}).Pending()
+	if err != nil {
+		t.Fatalf("获取选择的交易失败 %v", err)
+	}
+	if pool.budget.Gas != 42000 {
+		t.Errorf("区块gas预算错误 %d", pool.budget.Gas)
+	}
+	got := pending[params.MAN_COIN][from]
+	if len(got) != 2 || got[0] != txs[0] || got[1] != txs[1] {
+		t.Errorf("选择的交易错误 %v", got)
+	}
+}
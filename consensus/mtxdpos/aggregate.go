@@ -0,0 +1,208 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package mtxdpos
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/consensus"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto/bls"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+	"github.com/pkg/errors"
+)
+
+// 启用BLS聚合投票后, 验证者的同意票聚合为一个BLS签名, 区块头中只保留未聚合的签名.
+// 聚合投票的位图按拓扑图中验证者的顺序标记投票者
+
+// blsVoteReader 读取BLS聚合投票配置及验证者的BLS公钥
+type blsVoteReader interface {
+	GetBLSVoteCfg(blockHash common.Hash) (*mc.BLSVoteCfg, error)
+	GetConsensusBLSPubKeys(accounts []common.Address, blockHash common.Hash) ([][]byte, error)
+}
+
+var (
+	errAggregateVoteReader = errors.New("state reader can't read bls vote info")
+
+	errAggregateVoteNotActive = errors.New("aggregate votes before bls vote is active")
+
+	errAggregateVoteCount = errors.New("aggregate vote count err, not one")
+
+	errAggregateVoteBitmap = errors.New("aggregate vote bitmap mismatch validators")
+
+	errAggregateVoteKey = errors.New("aggregate voter's bls public key is invalid")
+
+	errAggregateVoteSign = errors.New("aggregate vote sign verify failed")
+
+	errAggregateVoteEmpty = errors.New("no bls vote to aggregate")
+)
+
+// ValidatorAccounts 按拓扑图顺序返回验证者账户, 即聚合投票位图的顺序
+func ValidatorAccounts(topologyInfo *mc.TopologyGraph) []common.Address {
+	accounts := make([]common.Address, 0)
+	exist := make(map[common.Address]bool)
+	for _, node := range topologyInfo.NodeList {
+		if node.Type != common.RoleValidator || exist[node.Account] {
+			continue
+		}
+		exist[node.Account] = true
+		accounts = append(accounts, node.Account)
+	}
+	return accounts
+}
+
+func getBLSVoteReader(reader consensus.StateReader) (blsVoteReader, error) {
+	blsReader, ok := reader.(blsVoteReader)
+	if !ok {
+		return nil, errAggregateVoteReader
+	}
+	return blsReader, nil
+}
+
+// AggregateVerifiedSigns 聚合DPOS通过的同意票中验证通过的BLS签名, 返回聚合投票及未聚合的签名
+func AggregateVerifiedSigns(reader consensus.StateReader, signHash common.Hash, signs []*common.VerifiedSign, rightSigns []common.Signature, blockHash common.Hash) (*types.AggregateVote, []common.Signature, error) {
+	blsReader, err := getBLSVoteReader(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	topologyInfo, _, err := reader.GetGraphByHash(blockHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	validators := ValidatorAccounts(topologyInfo)
+	pubKeys, err := blsReader.GetConsensusBLSPubKeys(validators, blockHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	right := make(map[common.Signature]bool, len(rightSigns))
+	for _, sign := range rightSigns {
+		right[sign] = true
+	}
+
+	bitmap := types.NewVoteBitmap(len(validators))
+	blsSigns := make([]*bls.Signature, 0)
+	aggregated := make(map[common.Signature]bool)
+	for i, account := range validators {
+		vote := findBLSVote(signs, account, right)
+		if vote == nil || len(pubKeys[i]) == 0 {
+			continue
+		}
+		pubKey, err := bls.PublicKeyFromBytes(pubKeys[i])
+		if err != nil {
+			log.Warn("共识引擎", "聚合投票 BLS公钥错误 node", account.Hex(), "err", err)
+			continue
+		}
+		blsSign, err := bls.SignatureFromBytes(vote.BLSSign)
+		if err != nil || !blsSign.Verify(pubKey, signHash.Bytes()) {
+			log.Warn("共识引擎", "聚合投票 BLS签名验证失败 node", account.Hex())
+			continue
+		}
+		types.SetVoter(bitmap, i)
+		blsSigns = append(blsSigns, blsSign)
+		aggregated[vote.Sign] = true
+	}
+	if len(blsSigns) == 0 {
+		return nil, nil, errAggregateVoteEmpty
+	}
+
+	aggregateSign, err := bls.AggregateSignatures(blsSigns)
+	if err != nil {
+		return nil, nil, err
+	}
+	remainSigns := make([]common.Signature, 0)
+	for _, sign := range rightSigns {
+		if !aggregated[sign] {
+			remainSigns = append(remainSigns, sign)
+		}
+	}
+	return &types.AggregateVote{Bitmap: bitmap, Sign: aggregateSign.Bytes()}, remainSigns, nil
+}
+
+// findBLSVote 查找账户带BLS签名的同意票
+func findBLSVote(signs []*common.VerifiedSign, account common.Address, right map[common.Signature]bool) *common.VerifiedSign {
+	for _, sign := range signs {
+		if sign.Account == account && sign.Validate && len(sign.BLSSign) != 0 && right[sign.Sign] {
+			return sign
+		}
+	}
+	return nil
+}
+
+// verifyAggregateVotes 验证区块头的聚合投票, 返回聚合投票的验证者
+func (md *MtxDPOS) verifyAggregateVotes(reader consensus.StateReader, header *types.Header, signHash common.Hash, stocks map[common.Address]uint16) (map[common.Address]*common.VerifiedSign, error) {
+	vote := header.BlockVote()
+	if vote == nil {
+		return nil, nil
+	}
+	if manversion.VersionCmp(string(header.Version), manversion.VersionAIMine) < 0 {
+		return nil, errAggregateVoteNotActive
+	}
+	blsReader, err := getBLSVoteReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := blsReader.GetBLSVoteCfg(header.ParentHash)
+	if err != nil {
+		return nil, errors.Errorf("get bls vote cfg err(%v)", err)
+	}
+	if !cfg.Active(header.Number.Uint64()) {
+		return nil, errAggregateVoteNotActive
+	}
+	// 区块头只能携带一个本区块的聚合投票, 检查点投票由区块链状态处理验证
+	blockVotes := 0
+	for _, v := range header.AggregateVotes {
+		if v.Number == 0 {
+			blockVotes++
+		}
+	}
+	if blockVotes != 1 {
+		return nil, errAggregateVoteCount
+	}
+
+	topologyInfo, _, err := reader.GetGraphByHash(header.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	validators := ValidatorAccounts(topologyInfo)
+	if len(vote.Bitmap) != len(types.NewVoteBitmap(len(validators))) {
+		return nil, errAggregateVoteBitmap
+	}
+	voters := vote.Voters()
+	if len(voters) == 0 {
+		return nil, errAggregateVoteBitmap
+	}
+	accounts := make([]common.Address, 0, len(voters))
+	for _, index := range voters {
+		if index >= len(validators) {
+			return nil, errAggregateVoteBitmap
+		}
+		accounts = append(accounts, validators[index])
+	}
+
+	keys, err := blsReader.GetConsensusBLSPubKeys(accounts, header.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	pubKeys := make([]*bls.PublicKey, 0, len(keys))
+	for i, key := range keys {
+		pubKey, err := bls.PublicKeyFromBytes(key)
+		if err != nil {
+			log.Error("共识引擎", "聚合投票 BLS公钥错误 node", accounts[i].Hex(), "err", err)
+			return nil, errAggregateVoteKey
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+	sign, err := bls.SignatureFromBytes(vote.Sign)
+	if err != nil || !sign.FastAggregateVerify(pubKeys, signHash.Bytes()) {
+		return nil, errAggregateVoteSign
+	}
+
+	verifiedSigns := make(map[common.Address]*common.VerifiedSign, len(accounts))
+	for _, account := range accounts {
+		verifiedSigns[account] = &common.VerifiedSign{Account: account, Validate: true, Stock: stocks[account]}
+	}
+	return verifiedSigns, nil
+}
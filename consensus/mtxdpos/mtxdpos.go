@@ -206,8 +206,13 @@ func (md *MtxDPOS) VerifyBlock(reader consensus.StateReader, header *types.Heade
 	}
 
 	hash := header.HashNoSignsAndNonce()
-	log.Trace("共识引擎", "VerifyBlock, 签名总数", len(header.Signatures), "hash", hash, "txhash:", header.Roots)
-	_, err = md.VerifyHashWithStocks(reader, hash, header.Signatures, stocks, header.ParentHash)
+	aggregated, err := md.verifyAggregateVotes(reader, header, hash, stocks)
+	if err != nil {
+		log.Error("共识引擎", "验证聚合投票失败", err)
+		return err
+	}
+	log.Trace("共识引擎", "VerifyBlock, 签名总数", len(header.Signatures), "聚合投票数", len(aggregated), "hash", hash, "txhash:", header.Roots)
+	_, err = md.verifyHashWithAggregated(reader, hash, header.Signatures, aggregated, stocks, header.ParentHash)
 	return err
 }
 
@@ -225,6 +230,11 @@ func (md *MtxDPOS) VerifyHashWithBlock(reader consensus.StateReader, signHash co
 }
 
 func (md *MtxDPOS) VerifyHashWithStocks(reader consensus.StateReader, signHash common.Hash, signs []common.Signature, stocks map[common.Address]uint16, blockHash common.Hash) ([]common.Signature, error) {
+	return md.verifyHashWithAggregated(reader, signHash, signs, nil, stocks, blockHash)
+}
+
+// verifyHashWithAggregated 验证签名, 已验证的聚合投票计入同意票
+func (md *MtxDPOS) verifyHashWithAggregated(reader consensus.StateReader, signHash common.Hash, signs []common.Signature, aggregated map[common.Address]*common.VerifiedSign, stocks map[common.Address]uint16, blockHash common.Hash) ([]common.Signature, error) {
 	if len(signHash) != 32 {
 		return nil, errSignHashLenErr
 	}
@@ -235,12 +245,15 @@ func (md *MtxDPOS) VerifyHashWithStocks(reader consensus.StateReader, signHash c
 	}
 
 	// check whether sign count is enough
-	if len(signs) < target.targetCount {
-		log.Error("共识引擎", "签名数量不足 size", len(signs), "target", target.targetCount)
+	if len(signs)+len(aggregated) < target.targetCount {
+		log.Error("共识引擎", "签名数量不足 size", len(signs), "aggregated", len(aggregated), "target", target.targetCount)
 		return nil, errSignCountErr
 	}
 
 	verifiedSigns := md.verifySigns(reader, signHash, signs, stocks, blockHash)
+	for account, sign := range aggregated {
+		verifiedSigns[account] = sign
+	}
 	if len(verifiedSigns) < target.targetCount {
 		log.Error("共识引擎", "验证后的签名数量不足 size", len(verifiedSigns), "target", target.targetCount)
 		return nil, errSignCountErr
@@ -382,7 +395,10 @@ func (md *MtxDPOS) verifyDPOS(verifiedSigns map[common.Address]*common.VerifiedS
 		if signInfo.Validate == true {
 			agreeCount++
 			agreeStock += uint64(signInfo.Stock)
-			rightSigns = append(rightSigns, signInfo.Sign)
+			// 聚合投票没有单独的签名
+			if signInfo.Sign != (common.Signature{}) {
+				rightSigns = append(rightSigns, signInfo.Sign)
+			}
 		} else {
 			disagreeCount++
 			disagreeStock += uint64(signInfo.Stock)
@@ -138,6 +138,13 @@ func (c *Console) init(preload []string) error {
 			// Enable web3.js built-in extension if available.
 			flatten += fmt.Sprintf("var %s = web3.%s; ", api, api)
 		}
+		if file, ok := matrixModules[api]; ok {
+			// Load the Matrix specific bindings on top of the extension.
+			if err = c.jsre.Compile(fmt.Sprintf("%s-matrix.js", api), file); err != nil {
+				return fmt.Errorf("%s-matrix.js: %v", api, err)
+			}
+			flatten += fmt.Sprintf("var %s = web3.%s; ", api, api)
+		}
 	}
 	if _, err = c.jsre.Run(flatten); err != nil {
 		return fmt.Errorf("namespace flattening: %v", err)
@@ -84,7 +84,7 @@ func newTester(t *testing.T, confOverride func(*man.Config)) *tester {
 		t.Fatalf("failed to create node: %v", err)
 	}
 	manConf := &man.Config{
-		Genesis:   core.DeveloperGenesisBlock(15, common.Address{}),
+		Genesis:   core.DeveloperGenesisBlock(15, common.Address{}, nil),
 		Manerbase: common.HexToAddress(testAddress),
 		Manash: manash.Config{
 			PowMode: manash.ModeTest,
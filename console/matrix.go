@@ -0,0 +1,187 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package console
+
+// matrixModules are console extensions of the Matrix specific RPC namespaces,
+// loaded on top of the web3 extension of the same namespace, if any.
+var matrixModules = map[string]string{
+	"matrix":  Matrix_JS,
+	"deposit": Deposit_JS,
+}
+
+// Matrix_JS binds the elected nodes, election simulation, broadcast interval,
+// deposit, entrust and address transaction queries to the matrix object, for
+// operators to script maintenance tasks.
+const Matrix_JS = `
+web3._extend({
+	property: 'matrix',
+	methods: [
+		new web3._extend.Method({
+			name: 'getElectedNodes',
+			call: 'man_getTopologyStatusByNumber',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorGroupInfo',
+			call: 'man_getValidatorGroupInfo',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSignAccounts',
+			call: 'man_getSignAccountsByNumber',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBroadcastIntervalInfo',
+			call: 'matrix_getBroadcastIntervalInfo',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getIndexedBroadcastInterval',
+			call: 'matrix_getIndexedBroadcastInterval',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getBroadcastHistory',
+			call: 'matrix_getBroadcastHistory',
+			params: 4,
+			inputFormatter: [null, null, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getHeartbeatHistory',
+			call: 'matrix_getHeartbeatHistory',
+			params: 3,
+			inputFormatter: [null, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getCallTheRollHistory',
+			call: 'matrix_getCallTheRollHistory',
+			params: 3,
+			inputFormatter: [null, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getDeposits',
+			call: 'man_getDeposit',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getDeposit',
+			call: 'man_getDepositByAddr',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getWithdrawalStatus',
+			call: 'matrix_withdrawalStatus',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEntrustList',
+			call: 'matrix_getEntrustList',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getAuthFrom',
+			call: 'man_getAuthFrom',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getEntrustFrom',
+			call: 'man_getEntrustFrom',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionsByAddress',
+			call: 'matrix_getTransactionsByAddress',
+			params: 4,
+			inputFormatter: [null, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal, null]
+		}),
+		new web3._extend.Method({
+			name: 'simulateElection',
+			call: 'matrix_simulateElection',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getAuthGasAddress',
+			call: 'man_getAuthGasAddress',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+	]
+});
+
+// electedRole returns the role an address is elected for at a block, or null.
+web3.matrix.electedRole = function(address, block) {
+	var status = web3.matrix.getElectedNodes(block);
+	var groups = ['validators', 'backup_validators', 'miners'];
+	for (var i = 0; i < groups.length; i++) {
+		var nodes = status[groups[i]] || [];
+		for (var j = 0; j < nodes.length; j++) {
+			if (JSON.stringify(nodes[j]).indexOf(address) >= 0) {
+				return groups[i];
+			}
+		}
+	}
+	return null;
+};
+
+// blocksToNextInterval returns the number of blocks until the next broadcast
+// and re-election blocks.
+web3.matrix.blocksToNextInterval = function() {
+	var info = web3.matrix.getBroadcastIntervalInfo('latest');
+	return {
+		broadcast: web3.toDecimal(info.nextBroadcast) - web3.toDecimal(info.number),
+		reElection: web3.toDecimal(info.nextReElection) - web3.toDecimal(info.number)
+	};
+};
+`
+
+// Deposit_JS binds the deposit queries and the deposit transaction helper to
+// the deposit object.
+const Deposit_JS = `
+web3._extend({
+	property: 'deposit',
+	methods: [
+		new web3._extend.Method({
+			name: 'getDeposit',
+			call: 'deposit_getDeposit',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getAllDeposits',
+			call: 'deposit_getAllDeposits',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getInterest',
+			call: 'deposit_getInterest',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getUnbonding',
+			call: 'deposit_getUnbonding',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'sendDeposit',
+			call: 'deposit_sendDeposit',
+			params: 1
+		}),
+	]
+});
+`
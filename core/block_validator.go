@@ -42,21 +42,36 @@ func (v *BlockValidator) ValidateHeader(header *types.Header) error {
 }
 
 func (v *BlockValidator) ValidateBody(block *types.Block) error {
-	// Check whether the block's known, and if not, that it's linkable
-	if v.bc.HasBlockAndState(block.Hash(), block.NumberU64()) {
+	if err := validateBlockLink(v.bc, block); err != nil {
+		return err
+	}
+	// Header validity is known at this point, check the uncles and transactions
+	if err := v.engine.VerifyUncles(v.bc, block); err != nil {
+		return err
+	}
+	return v.ValidateBodyContent(block)
+}
+
+// validateBlockLink checks whether the block's known, and if not, that it's
+// linkable to a block with available state.
+func validateBlockLink(bc *BlockChain, block *types.Block) error {
+	if bc.HasBlockAndState(block.Hash(), block.NumberU64()) {
 		return ErrKnownBlock
 	}
-	if !v.bc.HasBlockAndState(block.ParentHash(), block.NumberU64()-1) {
-		if !v.bc.HasBlock(block.ParentHash(), block.NumberU64()-1) {
+	if !bc.HasBlockAndState(block.ParentHash(), block.NumberU64()-1) {
+		if !bc.HasBlock(block.ParentHash(), block.NumberU64()-1) {
 			return consensus.ErrUnknownAncestor
 		}
 		return consensus.ErrPrunedAncestor
 	}
-	// Header validity is known at this point, check the uncles and transactions
+	return nil
+}
+
+// ValidateBodyContent verifies the transaction and uncle roots of the header.
+// It reads neither the chain nor the state, so it can run ahead of the import
+// of the parent blocks.
+func (v *BlockValidator) ValidateBodyContent(block *types.Block) error {
 	header := block.Header()
-	if err := v.engine.VerifyUncles(v.bc, block); err != nil {
-		return err
-	}
 	if hash := types.CalcUncleHash(block.Uncles()); hash != header.UncleHash {
 		return fmt.Errorf("uncle root hash mismatch: have %x, want %x", hash, header.UncleHash)
 	}
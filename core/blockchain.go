@@ -51,6 +51,7 @@ import (
 
 var (
 	blockInsertTimer = metrics.NewRegisteredTimer("chain/inserts", nil)
+	headBlockGauge   = metrics.NewRegisteredGauge("chain/head/block", nil)
 
 	ErrNoGenesis = errors.New("Genesis not found in chain")
 
@@ -76,9 +77,15 @@ const (
 // CacheConfig contains the configuration values for the trie caching/pruning
 // that's resident in a blockchain.
 type CacheConfig struct {
-	Disabled      bool          // Whether to disable trie write caching (archive node)
-	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
-	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	Disabled       bool          // Whether to disable trie write caching (archive node)
+	TrieCleanLimit int           // Memory allowance (MB) to use for caching trie nodes in memory
+	TrieNodeLimit  int           // Memory limit (MB) at which to flush the oldest in-memory trie nodes to disk
+	TrieTimeLimit  time.Duration // Time limit after which to flush the current in-memory trie to disk
+
+	StateRetention uint64 // Number of recent block states kept on disk by the online pruner, 0 disables pruning
+
+	AncientThreshold uint64 // Number of recent blocks kept in the key-value store, older ones are moved to the freezer, 0 disables freezing
+	AncientDir       string // Directory of the freezer flat files
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -99,9 +106,11 @@ type BlockChain struct {
 	chainConfig *params.ChainConfig // Chain & network configuration
 	cacheConfig *CacheConfig        // Cache configuration for pruning
 
-	db     mandb.Database // Low level persistent database to store final content in
-	triegc *prque.Prque   // Priority queue mapping block numbers to tries to gc
-	gcproc time.Duration  // Accumulates canonical block processing for trie dumping
+	db      mandb.Database // Low level persistent database to store final content in
+	triegc  *prque.Prque   // Priority queue mapping block numbers to tries to gc
+	gcproc  time.Duration  // Accumulates canonical block processing for trie dumping
+	pruner  *StatePruner   // Online pruner of the persisted state, nil if disabled
+	freezer *chainFreezer  // Mover of the old chain segments into the ancient store, nil if disabled
 
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
@@ -109,6 +118,8 @@ type BlockChain struct {
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
+	reorgTxsFeed  event.Feed
+	reorgFeed     event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -156,6 +167,18 @@ type BlockChain struct {
 	matrixProcessor *MatrixProcessor
 	topologyStore   *TopologyStore
 
+	//double sign evidence
+	evidencePool *EvidencePool
+
+	//checkpoint votes of the finality gadget
+	checkpointPool *CheckpointPool
+
+	//validator signs on the bridge events
+	bridgePool *BridgePool
+
+	//broadcast interval changes of the canonical chain
+	intervalCalendar *IntervalCalendar
+
 	//bad block dump history
 	badDumpHistory []common.Hash
 }
@@ -166,9 +189,24 @@ type BlockChain struct {
 func NewBlockChain(db mandb.Database, cacheConfig *CacheConfig, chainConfig *params.ChainConfig, vmConfig vm.Config, engine map[string]consensus.Engine, dposEngine map[string]consensus.DPOSEngine) (*BlockChain, error) {
 	if cacheConfig == nil {
 		cacheConfig = &CacheConfig{
-			TrieNodeLimit: 256 * 1024 * 1024,
-			TrieTimeLimit: 5 * time.Minute,
+			TrieCleanLimit: 256,
+			TrieNodeLimit:  256 * 1024 * 1024,
+			TrieTimeLimit:  5 * time.Minute,
+		}
+	}
+	// 启用冻结区时, 链数据库由AncientDatabase包装, 已冻结的数据经rawdb透明读取
+	var ancientDb *AncientDatabase
+	ancientOwned := false
+	if cacheConfig.AncientThreshold > 0 {
+		var ok bool
+		if ancientDb, ok = db.(*AncientDatabase); !ok {
+			var err error
+			if ancientDb, err = OpenAncientDatabase(db, cacheConfig.AncientDir); err != nil {
+				return nil, err
+			}
+			ancientOwned = true
 		}
+		db = ancientDb
 	}
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
@@ -181,7 +219,7 @@ func NewBlockChain(db mandb.Database, cacheConfig *CacheConfig, chainConfig *par
 		cacheConfig:     cacheConfig,
 		db:              db,
 		triegc:          prque.New(),
-		stateCache:      state.NewDatabase(db),
+		stateCache:      state.NewDatabaseWithCache(db, cacheConfig.TrieCleanLimit),
 		quit:            make(chan struct{}),
 		bodyCache:       bodyCache,
 		bodyRLPCache:    bodyRLPCache,
@@ -198,11 +236,17 @@ func NewBlockChain(db mandb.Database, cacheConfig *CacheConfig, chainConfig *par
 		badDumpHistory:  make([]common.Hash, 0),
 	}
 	bc.topologyStore = NewTopologyStore(bc)
+	bc.evidencePool = NewEvidencePool()
+	bc.checkpointPool = NewCheckpointPool()
+	bc.bridgePool = NewBridgePool()
 
 	bc.initVersionConfig(chainConfig, engine, dposEngine)
 
 	bc.RegisterMatrixStateDataProducer(mc.MSKeyTopologyGraph, bc.topologyStore.ProduceTopologyStateData)
 	bc.RegisterMatrixStateDataProducer(mc.MSKeyBroadcastInterval, ProduceBroadcastIntervalData)
+	bc.RegisterMatrixStateDataProducer(mc.MSKeyParamUpdates, ProduceParamUpdatesData)
+	bc.RegisterMatrixStateDataProducer(mc.MSKeyElectConfigInfo, ProduceElectConfigInfoData)
+	bc.RegisterMatrixStateDataProducer(mc.MSKeyElectMinerNum, ProduceElectMinerNumData)
 
 	var err error
 	bc.hc, err = NewHeaderChain(db, chainConfig, bc.getProcInterrupt)
@@ -211,6 +255,11 @@ func NewBlockChain(db mandb.Database, cacheConfig *CacheConfig, chainConfig *par
 	}
 	bc.hc.SetEngine(manversion.VersionAlpha, engine[manversion.VersionAlpha])
 	bc.hc.SetDposEngine(manversion.VersionAlpha, dposEngine[manversion.VersionAlpha])
+	if ancientDb != nil {
+		if bc.freezer, err = newChainFreezer(bc, ancientDb, cacheConfig.AncientThreshold, ancientOwned); err != nil {
+			return nil, err
+		}
+	}
 	bc.genesisBlock = bc.GetBlockByNumber(0)
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
@@ -223,6 +272,18 @@ func NewBlockChain(db mandb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	if bc.intervalCalendar, err = NewIntervalCalendar(db); err != nil {
+		return nil, err
+	}
+	if bc.intervalCalendar.Empty() {
+		// 日历为空时从当前区块开始记录, 更早的高度回退到历史状态查询
+		bc.recordBroadcastInterval(bc.CurrentBlock())
+	}
+	if cacheConfig.StateRetention > 0 && !cacheConfig.Disabled {
+		if bc.pruner, err = NewStatePruner(bc, cacheConfig.StateRetention); err != nil {
+			return nil, err
+		}
+	}
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -365,6 +426,11 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	}
 	bc.hc.SetHead(head, delFn)
 	currentHeader := bc.hc.CurrentHeader()
+	if bc.freezer != nil {
+		if err := bc.freezer.truncate(currentHeader.Number.Uint64()); err != nil {
+			return err
+		}
+	}
 
 	// Clear out any stale content from the caches
 	bc.bodyCache.Purge()
@@ -581,6 +647,8 @@ func (bc *BlockChain) insert(block *types.Block, old *types.Block) {
 	rawdb.WriteHeadBlockHash(bc.db, block.Hash())
 
 	bc.currentBlock.Store(block)
+	headBlockGauge.Update(int64(block.NumberU64()))
+	bc.recordBroadcastInterval(block)
 
 	// If the block is better than our head or is on a different chain, force update heads
 	if updateHeads {
@@ -802,6 +870,9 @@ func (bc *BlockChain) Stop() {
 			log.Error("Dangling trie nodes after full cleanup")
 		}
 	}
+	if bc.freezer != nil {
+		bc.freezer.close()
+	}
 	log.Info("Blockchain manager stopped")
 }
 
@@ -1139,33 +1210,29 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, state *state.State
 		bc.triegc.Push(roothash, -float32(block.NumberU64()))
 
 		if current := block.NumberU64(); current > triesInMemory {
-			// Find the next state trie we need to commit
-			header := bc.GetHeaderByNumber(current - triesInMemory)
-			chosen := header.Number.Uint64()
-
-			// Only write to disk if we exceeded our memory allowance *and* also have at
-			// least a given number of tries gapped.
+			// If we exceeded our memory allowance, flush matured singleton nodes to disk
 			var (
 				size  = triedb.Size()
 				limit = common.StorageSize(bc.cacheConfig.TrieNodeLimit) * 1024 * 1024
 			)
-			if size > limit || bc.gcproc > bc.cacheConfig.TrieTimeLimit {
+			if size > limit {
+				triedb.Cap(limit - mandb.IdealBatchSize)
+			}
+			// Find the next state trie we need to commit
+			header := bc.GetHeaderByNumber(current - triesInMemory)
+			chosen := header.Number.Uint64()
+
+			// If we exceeded our time allowance, flush an entire trie to disk
+			if bc.gcproc > bc.cacheConfig.TrieTimeLimit {
 				// If we're exceeding limits but haven't reached a large enough memory gap,
 				// warn the user that the system is becoming unstable.
-				if chosen < lastWrite+triesInMemory {
-					switch {
-					case size >= 2*limit:
-						log.Warn("State memory usage too high, committing", "size", size, "limit", limit, "optimum", float64(chosen-lastWrite)/triesInMemory)
-					case bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit:
-						log.Info("State in memory for too long, committing", "time", bc.gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-lastWrite)/triesInMemory)
-					}
-				}
-				// If optimum or critical limits reached, write to disk
-				if chosen >= lastWrite+triesInMemory || size >= 2*limit || bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit {
-					triedb.CommitRoots(header.Roots, true)
-					lastWrite = chosen
-					bc.gcproc = 0
+				if chosen < lastWrite+triesInMemory && bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit {
+					log.Info("State in memory for too long, committing", "time", bc.gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-lastWrite)/triesInMemory)
 				}
+				// Flush an entire trie and restart the counters
+				triedb.CommitRoots(header.Roots, true)
+				lastWrite = chosen
+				bc.gcproc = 0
 			}
 			// Garbage collect anything below our required write retention
 			for !bc.triegc.Empty() {
@@ -1177,8 +1244,17 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, state *state.State
 				triedb.Dereference(root.(common.Hash), common.Hash{})
 			}
 		}
+		if bc.pruner != nil && block.NumberU64()%statePruneInterval == 0 {
+			bc.pruner.trigger()
+		}
+		if bc.freezer != nil && block.NumberU64()%freezeInterval == 0 {
+			bc.freezer.trigger()
+		}
 	}
 	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
+	if logs := state.SystemLogs(); len(logs) > 0 {
+		WriteSystemLogs(batch, block.Hash(), block.NumberU64(), logs)
+	}
 
 	// If the total difficulty is higher than our known, add it to the canonical chain
 	// Second clause in the if statement reduces the vulnerability to selfish mining.
@@ -1207,6 +1283,10 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, state *state.State
 	}
 
 	currentBlock = bc.CurrentBlock()
+	if reorg && block.ParentHash() != currentBlock.Hash() && bc.reorgBehindFinalized(block) {
+		log.Warn("Refused reorg behind the finalized checkpoint", "number", block.NumberU64(), "hash", block.Hash())
+		reorg = false
+	}
 	if reorg {
 		// Reorganise the chain if the parent is not the head block
 		if block.ParentHash() != currentBlock.Hash() {
@@ -1361,6 +1441,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks, flg int) (int, []interface
 		coalescedLogs []types.CoinLogs
 		status        WriteStatus
 	)
+	// Verify the block bodies ahead of the state processing
+	pipeline := bc.newInsertPipeline(chain)
+	defer pipeline.stop()
 
 	// Iterate over the blocks and insert when the verifier permits
 	for i, block := range chain {
@@ -1387,13 +1470,21 @@ func (bc *BlockChain) insertChain(chain types.Blocks, flg int) (int, []interface
 
 		header := block.Header()
 
+		// The header checks read the parent state, so they run once the parent is imported
 		seal := true
-		if manparams.IsBroadcastNumberByHash(block.NumberU64(), block.ParentHash()) || block.IsSuperBlock() {
+		if bc.IsBroadcastBlock(block.NumberU64(), block.ParentHash()) || block.IsSuperBlock() {
 			seal = false
 		}
+		bodyErr := pipeline.next()
 		err := bc.Engine(header.Version).VerifyHeader(bc, header, seal, false)
 		if err == nil {
-			err = bc.Validator(header.Version).ValidateBody(block)
+			err = validateBlockLink(bc, block)
+		}
+		if err == nil {
+			err = bc.Engine(header.Version).VerifyUncles(bc, block)
+		}
+		if err == nil {
+			err = bodyErr
 		}
 
 		CleanMemFlgNum++
@@ -1529,6 +1620,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, flg int) (int, []interface
 			log.Debug(" Inserted new block", "number", block.Number(), "hash", block.Hash(), "uncles", len(block.Uncles()),
 				"txs", len(txs), "gas", block.GasUsed(), "elapsed", common.PrettyDuration(time.Since(bstart)))
 
+			logs = append(logs, bc.GetSystemLogs(block.Hash(), block.NumberU64())...)
 			coalescedLogs = append(coalescedLogs, logs...)
 			blockInsertTimer.UpdateSince(bstart)
 			events = append(events, ChainEvent{block, block.Hash(), logs})
@@ -1675,12 +1767,13 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		}
 	)
 
-	var txs []types.SelfTransaction
-	for _, currencie := range oldBlock.Currencies() {
-		tx := currencie.Transactions.GetTransactions()
-		for _, t := range tx {
-			txs = append(txs, t)
+	// blockTxs collects the transactions of all currencies of a dropped block
+	blockTxs := func(block *types.Block) types.SelfTransactions {
+		var txs types.SelfTransactions
+		for _, currencie := range block.Currencies() {
+			txs = append(txs, currencie.Transactions.GetTransactions()...)
 		}
+		return txs
 	}
 
 	// first reduce whoever is higher bound
@@ -1688,7 +1781,7 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		// reduce old chain
 		for ; oldBlock != nil && oldBlock.NumberU64() != newBlock.NumberU64(); oldBlock = bc.GetBlock(oldBlock.ParentHash(), oldBlock.NumberU64()-1) {
 			oldChain = append(oldChain, oldBlock)
-			deletedTxs = append(deletedTxs, txs...)
+			deletedTxs = append(deletedTxs, blockTxs(oldBlock)...)
 
 			collectLogs(oldBlock.Hash())
 		}
@@ -1713,7 +1806,7 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 
 		oldChain = append(oldChain, oldBlock)
 		newChain = append(newChain, newBlock)
-		deletedTxs = append(deletedTxs, txs...)
+		deletedTxs = append(deletedTxs, blockTxs(oldBlock)...)
 		collectLogs(oldBlock.Hash())
 
 		oldBlock, newBlock = bc.GetBlock(oldBlock.ParentHash(), oldBlock.NumberU64()-1), bc.GetBlock(newBlock.ParentHash(), newBlock.NumberU64()-1)
@@ -1754,9 +1847,16 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	for _, tx := range diff {
 		rawdb.DeleteTxLookupEntry(bc.db, tx.Hash())
 	}
+	// Hand the dropped transactions back to the transaction pools
+	if len(diff) > 0 {
+		go bc.reorgTxsFeed.Send(ReorgTxsEvent{Txs: diff})
+	}
 	if len(deletedLogs) > 0 {
 		go bc.rmLogsFeed.Send(RemovedLogsEvent{deletedLogs})
 	}
+	if len(oldChain) > 0 && len(newChain) > 0 {
+		go bc.reorgFeed.Send(ChainReorgEvent{OldHead: oldChain[0], NewHead: newChain[0], Common: commonBlock, Dropped: len(oldChain), Added: len(newChain)})
+	}
 	if len(oldChain) > 0 {
 		go func() {
 			for _, block := range oldChain {
@@ -2050,6 +2150,16 @@ func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Su
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
 }
 
+// SubscribeReorgTxsEvent registers a subscription of ReorgTxsEvent.
+func (bc *BlockChain) SubscribeReorgTxsEvent(ch chan<- ReorgTxsEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgTxsFeed.Subscribe(ch))
+}
+
+// SubscribeChainReorgEvent registers a subscription of ChainReorgEvent.
+func (bc *BlockChain) SubscribeChainReorgEvent(ch chan<- ChainReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgFeed.Subscribe(ch))
+}
+
 // SubscribeLogsEvent registers a subscription of []*types.Log.
 func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []types.CoinLogs) event.Subscription {
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
@@ -2079,65 +2189,13 @@ func (bc *BlockChain) GetAncestorHash(sonHash common.Hash, ancestorNumber uint64
 }
 
 func (bc *BlockChain) InsertSuperBlock(superBlockGen *Genesis, notify bool) (*types.Block, error) {
-	if nil == superBlockGen {
-		return nil, errors.New("super block is nil")
-	}
-	if superBlockGen.Number <= 0 {
-		return nil, errors.Errorf("super block`s number(%d) is too low", superBlockGen.Number)
-	}
-	parent := bc.GetBlockByHash(superBlockGen.ParentHash)
-	if nil == parent {
-		return nil, errors.Errorf("get parent block by hash(%s) err", superBlockGen.ParentHash.Hex())
-	}
-	if parent.NumberU64()+1 != superBlockGen.Number {
-		return nil, errors.Errorf("parent block number(%d) + 1 != super block number(%d)", parent.NumberU64(), superBlockGen.Number)
-	}
-
-	block := superBlockGen.GenSuperBlock(parent.Header(), bc.db, bc.stateCache, bc.chainConfig)
-	if nil == block {
-		return nil, errors.New("genesis super block failed")
-	}
-
-	if !block.IsSuperBlock() {
-		return nil, errors.New("err, genesis block is not super block!")
-	}
-	blockHash := types.RlpHash(block.Root())
-	superHash := types.RlpHash(superBlockGen.Roots)
-	if blockHash != superHash {
-		return nil, errors.Errorf("root not match, calc root(%s) != genesis root(%s)", blockHash, superHash)
-	}
-
-	for _, currencie := range block.Currencies() {
-		for _, coinRoot := range superBlockGen.Roots {
-			if currencie.CurrencyName == coinRoot.Cointyp {
-				bltxHash := types.DeriveShaHash(currencie.Transactions.TxHashs)
-				if bltxHash != coinRoot.TxHash {
-					return nil, errors.Errorf("txHash not match, calc txHash(%s) != genesis txHash(%s)", currencie.Header.TxHash.TerminalString(), coinRoot.TxHash.TerminalString())
-				}
-			}
-		}
-	}
-	sbh, err := bc.GetSuperBlockNum()
-	if nil != err {
-		return nil, errors.Errorf("get super seq error")
-	}
-	superBlock := bc.GetBlockByNumber(sbh)
-	if nil != superBlock {
-		if block.Hash() == superBlock.Hash() {
-			log.Warn(ModuleName, "has the same super block", "")
-			return block, nil
-		}
-	}
-	sbs, err := bc.GetSuperBlockSeq()
-	if nil != err {
-		return nil, errors.Errorf("get super seq error")
-	}
-	if block.Header().SuperBlockSeq() <= sbs {
-		return nil, errors.Errorf("SuperBlockSeq not match, current seq(%v) < genesis block(%v)", sbs, block.Header().SuperBlockSeq())
+	block, known, err := bc.VerifySuperBlock(superBlockGen)
+	if err != nil {
+		return nil, err
 	}
-
-	if err := bc.DPOSEngine(block.Header().Version).VerifyBlock(bc, block.Header()); err != nil {
-		return nil, errors.Errorf("verify super block err(%v)", err)
+	if known {
+		log.Warn(ModuleName, "has the same super block", "")
+		return block, nil
 	}
 	//if err := bc.SetHead(superBlockGen.Number - 1); err != nil {
 	//	return nil, errors.Errorf("rollback chain err(%v)", err)
@@ -2326,6 +2384,9 @@ func (bc *BlockChain) GetA2AccountsFromA0Account(a0Account common.Address, block
 		return nil, errors.New("获取stateDB失败")
 	}
 
+	if signer := depoistInfo.GetConsensusSigner(st, a0Account, block.NumberU64()+1); signer != (common.Address{}) {
+		return []common.Address{signer}, nil
+	}
 	a1Account, err := bc.GetA1AccountFromA0Account(a0Account, block, st)
 	if err != nil {
 		return nil, err
@@ -2351,11 +2412,16 @@ func (bc *BlockChain) GetA0AccountFromAnyAccount(account common.Address, blockHa
 		log.Error(common.SignLog, "从A0账户获取A1账户", "失败", "根据区块root获取状态树失败 err", err)
 		return common.Address{}, common.Address{}, errors.New("获取stateDB失败")
 	}
+	//签名用于下一个区块, 按下一高度判断共识密钥
+	signHeight := block.NumberU64() + 1
+	if a0Account := depoistInfo.GetConsensusAccount(st, account, signHeight); a0Account != (common.Address{}) {
+		return a0Account, account, nil
+	}
 	//假设传入的account为A1账户
 	a0Account, err := bc.GetA0AccountFromA1Account(account, block, st)
 	if err == nil {
 		//log.Debug(common.SignLog, "根据任意账户得到A0和A1账户", "输入为A1账户", "输入A1", account.Hex(), "输出A0", a0Account.Hex())
-		return a0Account, account, nil
+		return a0Account, account, checkConsensusKey(st, a0Account, account, signHeight)
 	}
 	//走到这，说明是输入账户不是A1账户
 	a1Account, err := bc.GetA1AccountFromA2Account(account, block, st)
@@ -2367,7 +2433,9 @@ func (bc *BlockChain) GetA0AccountFromAnyAccount(account common.Address, blockHa
 	a0Account, err = bc.GetA0AccountFromA1Account(a1Account, block, st)
 	if err != nil {
 		log.Error(common.SignLog, "根据任意账户得到A0和A1账户", "输入为A2账户", "输入A2", account.Hex(), "输出A1", a1Account.Hex(), "输出A0", "失败")
+		return a0Account, a1Account, err
 	}
+	err = checkConsensusKey(st, a0Account, account, signHeight)
 	//log.Info(common.SignLog, "根据任意账户得到A0和A1账户", "输入为A2账户", "输入A2", account.Hex(), "输出A1", a1Account.Hex(), "输出A0", a0Account.Hex())
 	return a0Account, a1Account, err
 }
@@ -2386,6 +2454,10 @@ func (bc *BlockChain) GetA2AccountsFromA0AccountAtSignHeight(a0Account common.Ad
 		log.Error(common.SignLog, "从A0账户获取A1账户", "失败", "根据区块root获取状态树失败 err", err)
 		return nil, errors.New("获取stateDB失败")
 	}
+	//共识密钥生效后只能使用共识签名账户签名
+	if signer := depoistInfo.GetConsensusSigner(st, a0Account, signHeight); signer != (common.Address{}) {
+		return []common.Address{signer}, nil
+	}
 	a1Account, err := bc.GetA1AccountFromA0Account(a0Account, block, st)
 	if err != nil {
 		return nil, err
@@ -2397,6 +2469,15 @@ func (bc *BlockChain) GetA2AccountsFromA0AccountAtSignHeight(a0Account common.Ad
 	return a2Accounts, nil
 }
 
+// checkConsensusKey 抵押账户注册的共识密钥生效后, 拒绝A1及A2账户的共识签名
+func checkConsensusKey(st *state.StateDBManage, a0Account common.Address, account common.Address, signHeight uint64) error {
+	if signer := depoistInfo.GetConsensusSigner(st, a0Account, signHeight); signer != (common.Address{}) && signer != account {
+		log.Error(common.SignLog, "共识密钥已生效, 拒绝签名账户", account.Hex(), "A0", a0Account.Hex(), "共识账户", signer.Hex(), "签名高度", signHeight)
+		return errors.New("账户已注册共识密钥, 签名账户不是共识账户")
+	}
+	return nil
+}
+
 func (bc *BlockChain) GetA2AccountsFromA1AccountAtSignHeight(a1Account common.Address, block *types.Block, st *state.StateDBManage, signHeight uint64) ([]common.Address, error) {
 
 	a2Accounts := []common.Address{}
@@ -2423,11 +2504,14 @@ func (bc *BlockChain) GetA0AccountFromAnyAccountAtSignHeight(account common.Addr
 		log.Error(common.SignLog, "从A1账户获取A0账户", "失败", "根据区块root获取状态树失败 err", err)
 		return common.Address{}, common.Address{}, nil
 	}
+	if a0Account := depoistInfo.GetConsensusAccount(st, account, signHeight); a0Account != (common.Address{}) {
+		return a0Account, account, nil
+	}
 	//假设传入的account为A1账户
 	a0Account, err := bc.GetA0AccountFromA1Account(account, block, st)
 	if err == nil {
 		//log.Debug(common.SignLog, "根据任意账户得到A0和A1账户", "输入为A1账户", "输入A1", account.Hex(), "输出A0", a0Account.Hex(), "签名高度", signHeight)
-		return a0Account, account, nil
+		return a0Account, account, checkConsensusKey(st, a0Account, account, signHeight)
 	}
 	//走到这，说明是输入账户不是A1账户
 	a1Account, err := bc.GetA1AccountFromA2AccountAtSignHeight(account, st, signHeight)
@@ -2439,7 +2523,9 @@ func (bc *BlockChain) GetA0AccountFromAnyAccountAtSignHeight(account common.Addr
 	a0Account, err = bc.GetA0AccountFromA1Account(a1Account, block, st)
 	if err != nil {
 		log.Error(common.SignLog, "根据任意账户得到A0和A1账户", "输入为A2账户", "输入A2", account.Hex(), "输出A1", a1Account.Hex(), "输出A0", "失败", "签名高度", signHeight)
+		return a0Account, a1Account, err
 	}
+	err = checkConsensusKey(st, a0Account, account, signHeight)
 	//log.Info(common.SignLog, "根据任意账户得到A0和A1账户", "输入为A2账户", "输入A2", account.Hex(), "输出A1", a1Account.Hex(), "输出A0", a0Account.Hex(), "签名高度", signHeight)
 	return a0Account, a1Account, err
 }
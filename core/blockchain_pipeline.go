@@ -0,0 +1,52 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+// insertPipeline 区块导入流水线. 不读取链和状态的区块体校验(交易签名恢复, 交易根, 叔块根)
+// 在后台按区块顺序提前进行, 与主循环中前面区块的状态执行重叠.
+// 区块头校验会读取父区块状态(广播周期, 出块时间等), 必须在父区块写入后于主循环中进行
+type insertPipeline struct {
+	abort  chan struct{}
+	bodies chan error // 区块体内容校验结果, 与区块顺序一致
+}
+
+// newInsertPipeline 启动区块体校验阶段
+func (bc *BlockChain) newInsertPipeline(chain types.Blocks) *insertPipeline {
+	p := &insertPipeline{
+		abort:  make(chan struct{}),
+		bodies: make(chan error, len(chain)),
+	}
+	go bc.verifyBodiesStage(chain, p)
+	return p
+}
+
+// verifyBodiesStage 提前恢复交易发送者并校验区块体内容
+func (bc *BlockChain) verifyBodiesStage(chain types.Blocks, p *insertPipeline) {
+	signer := types.NewEIP155Signer(bc.chainConfig.ChainId)
+	for _, block := range chain {
+		select {
+		case <-p.abort:
+			return
+		default:
+		}
+		for _, currencie := range block.Currencies() {
+			senderCacher.recover(signer, currencie.Transactions.GetTransactions())
+		}
+		p.bodies <- bc.Validator(block.Header().Version).ValidateBodyContent(block)
+	}
+}
+
+// next 按顺序获取下一个区块的区块体校验结果
+func (p *insertPipeline) next() error {
+	return <-p.bodies
+}
+
+// stop 终止尚未完成的校验
+func (p *insertPipeline) stop() {
+	close(p.abort)
+}
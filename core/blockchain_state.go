@@ -11,6 +11,7 @@ import (
 
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
@@ -353,6 +354,10 @@ func (bc *BlockChain) GetTopologyStore() *TopologyStore {
 	return bc.topologyStore
 }
 
+func (bc *BlockChain) EvidencePool() *EvidencePool {
+	return bc.evidencePool
+}
+
 func (bc *BlockChain) GetBroadcastInterval() (*mc.BCIntervalInfo, error) {
 	st, err := bc.State()
 	if err != nil {
@@ -377,6 +382,65 @@ func (bc *BlockChain) GetBroadcastIntervalByNumber(number uint64) (*mc.BCInterva
 	return matrixstate.GetBroadcastInterval(st)
 }
 
+// recordBroadcastInterval 将主链区块状态中的广播周期记入广播周期日历
+func (bc *BlockChain) recordBroadcastInterval(block *types.Block) {
+	if bc.intervalCalendar == nil {
+		return
+	}
+	st, err := bc.StateAt(block.Root())
+	if err != nil {
+		log.Warn(ModuleName, "广播周期日历获取状态失败", err, "高度", block.NumberU64())
+		return
+	}
+	info, err := matrixstate.GetBroadcastInterval(st)
+	if err != nil {
+		log.Warn(ModuleName, "广播周期日历获取广播周期失败", err, "高度", block.NumberU64())
+		return
+	}
+	if err := bc.intervalCalendar.Record(block.NumberU64(), info); err != nil {
+		log.Error(ModuleName, "广播周期日历记录失败", err, "高度", block.NumberU64())
+	}
+}
+
+// GetBroadcastIntervalAt 查询number高度区块生效的广播周期(即父区块状态中的广播周期),
+// 日历中没有记录时回退到父区块的历史状态
+func (bc *BlockChain) GetBroadcastIntervalAt(number uint64) (*mc.BCIntervalInfo, error) {
+	if bc.intervalCalendar != nil {
+		info, err := bc.intervalCalendar.GetBroadcastIntervalAt(number)
+		if err != ErrIntervalNotRecorded {
+			return info, err
+		}
+	}
+	if number == 0 {
+		return bc.GetBroadcastIntervalByNumber(0)
+	}
+	return bc.GetBroadcastIntervalByNumber(number - 1)
+}
+
+// IsBroadcastNumberAt 按number高度生效的广播周期判断是否为广播区块
+func (bc *BlockChain) IsBroadcastNumberAt(number uint64) (bool, error) {
+	info, err := bc.GetBroadcastIntervalAt(number)
+	if err != nil {
+		return false, err
+	}
+	return info.IsBroadcastNumber(number), nil
+}
+
+// IsBroadcastBlock 判断父区块为parentHash的number高度区块是否为广播区块.
+// 父区块在主链上时按广播周期日历查询, 不依赖(可能已裁剪的)历史状态, 否则读取父区块状态
+func (bc *BlockChain) IsBroadcastBlock(number uint64, parentHash common.Hash) bool {
+	if number > 0 && rawdb.ReadCanonicalHash(bc.db, number-1) == parentHash {
+		if ok, err := bc.IsBroadcastNumberAt(number); err == nil {
+			return ok
+		}
+	}
+	return manparams.IsBroadcastNumberByHash(number, parentHash)
+}
+
+func (bc *BlockChain) IntervalCalendar() *IntervalCalendar {
+	return bc.intervalCalendar
+}
+
 func (bc *BlockChain) GetBroadcastAccounts(blockHash common.Hash) ([]common.Address, error) {
 	st, err := bc.StateAtBlockHash(blockHash)
 	if err != nil {
@@ -401,6 +465,33 @@ func (bc *BlockChain) GetVersionSuperAccounts(blockHash common.Hash) ([]common.A
 	return matrixstate.GetVersionSuperAccounts(st)
 }
 
+func (bc *BlockChain) GetBLSVoteCfg(blockHash common.Hash) (*mc.BLSVoteCfg, error) {
+	st, err := bc.StateAtBlockHash(blockHash)
+	if err != nil {
+		return nil, errors.Errorf("get state by hash(%s) err(%v)", blockHash.Hex(), err)
+	}
+	return matrixstate.GetBLSVoteCfg(st)
+}
+
+// GetConsensusBLSPubKeys returns the BLS keys of the deposit accounts voting on
+// the child of the block, empty for the accounts without one.
+func (bc *BlockChain) GetConsensusBLSPubKeys(accounts []common.Address, blockHash common.Hash) ([][]byte, error) {
+	block := bc.GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, errors.Errorf("can't find block by hash(%s)", blockHash.Hex())
+	}
+	st, err := bc.StateAt(block.Root())
+	if err != nil {
+		return nil, errors.Errorf("get state by hash(%s) err(%v)", blockHash.Hex(), err)
+	}
+	signHeight := block.NumberU64() + 1
+	keys := make([][]byte, len(accounts))
+	for i, account := range accounts {
+		keys[i] = depoistInfo.GetConsensusBLSPubKey(st, account, signHeight)
+	}
+	return keys, nil
+}
+
 func (bc *BlockChain) GetMultiCoinSuperAccounts(blockHash common.Hash) ([]common.Address, error) {
 	st, err := bc.StateAtBlockHash(blockHash)
 	if err != nil {
@@ -568,6 +659,15 @@ func ProduceBroadcastIntervalData(block *types.Block, state *state.StateDBManage
 
 	modify := false
 	number := block.NumberU64()
+	update, err := paramUpdateAt(readFn, number)
+	if err != nil {
+		log.Error("ProduceBroadcastIntervalData", "read pre param updates err", err)
+		return nil, err
+	}
+	if update != nil && update.BCInterval != 0 {
+		// 链参数更新的广播周期在本选举区块生效
+		bcInterval.SetBackupBCInterval(update.BCInterval, number)
+	}
 	backupEnableNumber := bcInterval.GetBackupEnableNumber()
 	if number == backupEnableNumber {
 		// 备选生效时间点
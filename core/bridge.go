@@ -0,0 +1,293 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// 跨链桥: 验证者对链上锁定/销毁事件签名证明, 超过2/3的签名由跨链证明交易上链.
+// 中继者读取上链的证明及验证者签名, 在其他链上释放/铸造资产
+
+var (
+	ErrBridgeNotEnabled    = errors.New("bridge is not enabled at this height")
+	ErrBridgeEventTooOld   = errors.New("bridge event is too old")
+	ErrBridgeEventFuture   = errors.New("bridge event is beyond the current block")
+	ErrBridgeNotValidator  = errors.New("bridge event signer is not a current validator")
+	ErrBridgePoolFull      = errors.New("bridge pool is full")
+	ErrBridgeEventHash     = errors.New("bridge event block mismatch the canonical chain")
+	ErrBridgeAttested      = errors.New("bridge event already attested")
+	ErrBridgeSupermajority = errors.New("bridge attestation lacks a validator supermajority")
+)
+
+// bridgeEventMaxAge 跨链证明须在事件区块之后的bridgeEventMaxAge个区块内上链, 以便按区块哈希校验事件区块
+const bridgeEventMaxAge = 256
+
+// bridgePoolMaxEvents 跨链签名池最多保存的跨链事件数
+const bridgePoolMaxEvents = 1024
+
+// bridgeAttestationPrefix 上链的跨链证明在状态中的键前缀
+const bridgeAttestationPrefix = "bridge_attestation"
+
+// BridgeEvent 跨链桥合约的锁定/销毁事件
+type BridgeEvent struct {
+	Contract    common.Address
+	Topics      []common.Hash
+	Data        []byte
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxHash      common.Hash
+	LogIndex    uint64
+}
+
+// NewBridgeEvent 由区块中的事件日志构造跨链事件
+func NewBridgeEvent(block *types.Block, l *types.Log) *BridgeEvent {
+	return &BridgeEvent{
+		Contract:    l.Address,
+		Topics:      append([]common.Hash{}, l.Topics...),
+		Data:        common.CopyBytes(l.Data),
+		BlockNumber: block.NumberU64(),
+		BlockHash:   block.Hash(),
+		TxHash:      l.TxHash,
+		LogIndex:    uint64(l.Index),
+	}
+}
+
+// Hash 事件哈希, 即验证者签名的哈希及证明的ID
+func (ev *BridgeEvent) Hash() common.Hash {
+	return types.RlpHash(ev)
+}
+
+// BridgeAttestation 验证者对跨链事件的签名证明
+type BridgeAttestation struct {
+	Event BridgeEvent
+	Signs []common.Signature
+}
+
+// BridgeAttestationRecord 上链的跨链证明
+type BridgeAttestationRecord struct {
+	Attestation BridgeAttestation
+	Number      uint64 // 证明上链的区块高度
+}
+
+// EncodeBridgeAttestation 编码跨链证明, 作为跨链证明交易的数据
+func EncodeBridgeAttestation(att *BridgeAttestation) ([]byte, error) {
+	return rlp.EncodeToBytes(att)
+}
+
+// DecodeBridgeAttestation 解码跨链证明交易的数据
+func DecodeBridgeAttestation(data []byte) (*BridgeAttestation, error) {
+	att := new(BridgeAttestation)
+	if err := rlp.DecodeBytes(data, att); err != nil {
+		return nil, err
+	}
+	return att, nil
+}
+
+func bridgeAttestationKey(id common.Hash) common.Hash {
+	return types.RlpHash(bridgeAttestationPrefix + id.Hex())
+}
+
+// GetBridgeAttestation 读取上链的跨链证明, 未上链时返回nil
+func GetBridgeAttestation(st vm.StateDBManager, id common.Hash) (*BridgeAttestationRecord, error) {
+	data := st.GetMatrixData(bridgeAttestationKey(id))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	record := new(BridgeAttestationRecord)
+	if err := rlp.DecodeBytes(data, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// CheckBridgeAttestation 验证number高度上链的跨链证明, getHash返回主链区块哈希
+func CheckBridgeAttestation(st vm.StateDBManager, getHash func(uint64) common.Hash, att *BridgeAttestation, number uint64) error {
+	if !ForkActive(st, mc.ForkBridgeAttest, number) {
+		return ErrBridgeNotEnabled
+	}
+	ev := &att.Event
+	if ev.BlockNumber >= number || ev.BlockNumber+bridgeEventMaxAge < number {
+		return ErrBridgeEventTooOld
+	}
+	if getHash(ev.BlockNumber) != ev.BlockHash {
+		return ErrBridgeEventHash
+	}
+	record, err := GetBridgeAttestation(st, ev.Hash())
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		return ErrBridgeAttested
+	}
+	agreed, total, err := countProposalSigners(st, ev.Hash(), att.Signs)
+	if err != nil {
+		return err
+	}
+	// 需要超过2/3的当选验证者签名
+	if total == 0 || agreed*3 <= total*2 {
+		return ErrBridgeSupermajority
+	}
+	return nil
+}
+
+// applyBridgeAttestation 验证跨链证明并记录到状态中
+func applyBridgeAttestation(st vm.StateDBManager, getHash func(uint64) common.Hash, att *BridgeAttestation, number uint64) error {
+	if err := CheckBridgeAttestation(st, getHash, att, number); err != nil {
+		return err
+	}
+	data, err := rlp.EncodeToBytes(&BridgeAttestationRecord{Attestation: *att, Number: number})
+	if err != nil {
+		return err
+	}
+	id := att.Event.Hash()
+	log.Info(ModuleName, "跨链证明上链, 事件", id.TerminalString(), "事件高度", att.Event.BlockNumber, "签名数", len(att.Signs), "高度", number)
+	st.SetMatrixData(bridgeAttestationKey(id), data)
+	return nil
+}
+
+type bridgePoolEntry struct {
+	event BridgeEvent
+	signs map[common.Address]common.Signature
+}
+
+// BridgePool 收集验证者对跨链事件的签名, 直到跨链证明上链
+type BridgePool struct {
+	mu      sync.Mutex
+	entries map[common.Hash]*bridgePoolEntry
+}
+
+func NewBridgePool() *BridgePool {
+	return &BridgePool{
+		entries: make(map[common.Hash]*bridgePoolEntry),
+	}
+}
+
+// AddSign 记录验证者对跨链事件的签名, 返回签名者. st为head高度的状态, 只接受当前验证者对
+// 不晚于head且仍可上链的事件的签名, 每个验证者对每个事件只保留一个签名
+func (pool *BridgePool) AddSign(st vm.StateDBManager, head uint64, ev *BridgeEvent, sign common.Signature) (common.Address, error) {
+	if ev.BlockNumber > head {
+		return common.Address{}, ErrBridgeEventFuture
+	}
+	if ev.BlockNumber+bridgeEventMaxAge < head {
+		return common.Address{}, ErrBridgeEventTooOld
+	}
+	id := ev.Hash()
+	signer, validate, err := crypto.VerifySignWithValidate(id.Bytes(), sign.Bytes())
+	if err != nil {
+		return common.Address{}, err
+	}
+	if !validate {
+		return common.Address{}, errors.New("bridge event sign is not a validate sign")
+	}
+	validators, err := currentValidators(st)
+	if err != nil {
+		return common.Address{}, err
+	}
+	account := depoistInfo.GetDepositAccount(st, signer)
+	if !validators[account] {
+		return common.Address{}, ErrBridgeNotValidator
+	}
+	if err := pool.add(ev, account, sign); err != nil {
+		return common.Address{}, err
+	}
+	return signer, nil
+}
+
+// add 记录account账户的验证者对跨链事件的签名
+func (pool *BridgePool) add(ev *BridgeEvent, account common.Address, sign common.Signature) error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	id := ev.Hash()
+	entry, ok := pool.entries[id]
+	if !ok {
+		if len(pool.entries) >= bridgePoolMaxEvents && !pool.evictOldest(ev.BlockNumber) {
+			return ErrBridgePoolFull
+		}
+		entry = &bridgePoolEntry{event: *ev, signs: make(map[common.Address]common.Signature)}
+		pool.entries[id] = entry
+	}
+	entry.signs[account] = sign
+	return nil
+}
+
+// evictOldest 签名池已满时删除事件高度最低的跨链事件, 为number高度的事件腾出空间.
+// 没有比number更早的事件时返回false
+func (pool *BridgePool) evictOldest(number uint64) bool {
+	var (
+		oldest common.Hash
+		found  bool
+	)
+	for id, entry := range pool.entries {
+		if entry.event.BlockNumber < number {
+			oldest, number, found = id, entry.event.BlockNumber, true
+		}
+	}
+	if found {
+		delete(pool.entries, oldest)
+	}
+	return found
+}
+
+// Pending 返回收集到签名的跨链证明, 按事件高度排列
+func (pool *BridgePool) Pending() []*BridgeAttestation {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	list := make([]*BridgeAttestation, 0, len(pool.entries))
+	for _, entry := range pool.entries {
+		att := &BridgeAttestation{Event: entry.event, Signs: make([]common.Signature, 0, len(entry.signs))}
+		for _, sign := range entry.signs {
+			att.Signs = append(att.Signs, sign)
+		}
+		sort.Slice(att.Signs, func(i, j int) bool { return string(att.Signs[i].Bytes()) < string(att.Signs[j].Bytes()) })
+		list = append(list, att)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Event.BlockNumber != list[j].Event.BlockNumber {
+			return list[i].Event.BlockNumber < list[j].Event.BlockNumber
+		}
+		return list[i].Event.LogIndex < list[j].Event.LogIndex
+	})
+	return list
+}
+
+// Remove 删除已上链的跨链证明
+func (pool *BridgePool) Remove(id common.Hash) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	delete(pool.entries, id)
+}
+
+// Prune 删除number高度已无法上链的跨链事件
+func (pool *BridgePool) Prune(number uint64) {
+	if number <= bridgeEventMaxAge {
+		return
+	}
+	limit := number - bridgeEventMaxAge
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for id, entry := range pool.entries {
+		if entry.event.BlockNumber < limit {
+			delete(pool.entries, id)
+		}
+	}
+}
+
+func (bc *BlockChain) BridgePool() *BridgePool {
+	return bc.bridgePool
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+)
+
+func testBridgeEvent(number uint64) *BridgeEvent {
+	return &BridgeEvent{
+		Contract:    common.HexToAddress("0x0c"),
+		Topics:      []common.Hash{crypto.Keccak256Hash([]byte("Lock(address,uint256,uint256,bytes)"))},
+		Data:        []byte{1, 2, 3},
+		BlockNumber: number,
+		BlockHash:   common.HexToHash("0x01"),
+		TxHash:      common.HexToHash("0x02"),
+	}
+}
+
+func Test_bridgeAttestationEncode(t *testing.T) {
+	att := &BridgeAttestation{Event: *testBridgeEvent(100), Signs: []common.Signature{common.BytesToSignature([]byte{1})}}
+
+	data, err := EncodeBridgeAttestation(att)
+	if err != nil {
+		t.Fatalf("跨链证明编码错误 %v", err)
+	}
+	decoded, err := DecodeBridgeAttestation(data)
+	if err != nil {
+		t.Fatalf("跨链证明解码错误 %v", err)
+	}
+	if decoded.Event.Hash() != att.Event.Hash() || len(decoded.Signs) != 1 {
+		t.Fatalf("跨链证明解码结果错误 %+v", decoded)
+	}
+	if testBridgeEvent(100).Hash() == testBridgeEvent(101).Hash() {
+		t.Errorf("不同跨链事件的哈希相同")
+	}
+}
+
+func Test_BridgePool(t *testing.T) {
+	pool := NewBridgePool()
+	ev := testBridgeEvent(100)
+	accountA := common.HexToAddress("0x0a")
+	accountB := common.HexToAddress("0x0b")
+
+	for _, account := range []common.Address{accountA, accountB, accountA} {
+		if err := pool.add(ev, account, common.BytesToSignature(account.Bytes())); err != nil {
+			t.Fatalf("添加签名失败 %v", err)
+		}
+	}
+	// 每个验证者只记录一个签名
+	pending := pool.Pending()
+	if len(pending) != 1 || len(pending[0].Signs) != 2 {
+		t.Fatalf("待上链跨链证明错误 %v", pending)
+	}
+
+	// 未来及过期事件的签名在验签前被拒绝
+	key, _ := crypto.GenerateKey()
+	head := uint64(bridgeEventMaxAge + 100)
+	for number, want := range map[uint64]error{head + 1: ErrBridgeEventFuture, 99: ErrBridgeEventTooOld} {
+		ev := testBridgeEvent(number)
+		sign, err := crypto.SignWithValidate(ev.Hash().Bytes(), true, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.AddSign(nil, head, ev, common.BytesToSignature(sign)); err != want {
+			t.Errorf("事件高度%d: 错误 %v, 应为 %v", number, err, want)
+		}
+	}
+	if _, err := pool.AddSign(nil, 100, ev, common.BytesToSignature([]byte{1})); err == nil {
+		t.Fatalf("无效签名不应添加")
+	}
+
+	pool.Prune(100 + bridgeEventMaxAge)
+	if len(pool.Pending()) != 1 {
+		t.Fatalf("未过期的跨链事件被删除")
+	}
+	pool.Remove(ev.Hash())
+	if len(pool.Pending()) != 0 {
+		t.Fatalf("上链的跨链事件未删除")
+	}
+}
+
+func Test_BridgePoolBound(t *testing.T) {
+	pool := NewBridgePool()
+	account := common.HexToAddress("0x0a")
+	for i := uint64(0); i < bridgePoolMaxEvents; i++ {
+		if err := pool.add(testBridgeEvent(1000+i), account, common.Signature{}); err != nil {
+			t.Fatalf("添加签名失败 %v", err)
+		}
+	}
+	// 池满时拒绝更早的事件, 新的事件替换最早的事件
+	if err := pool.add(testBridgeEvent(999), account, common.Signature{}); err != ErrBridgePoolFull {
+		t.Fatalf("池满时应拒绝更早的事件 %v", err)
+	}
+	if err := pool.add(testBridgeEvent(1000+bridgePoolMaxEvents), account, common.Signature{}); err != nil {
+		t.Fatalf("池满时添加新事件失败 %v", err)
+	}
+	pending := pool.Pending()
+	if len(pending) != bridgePoolMaxEvents || pending[0].Event.BlockNumber != 1001 {
+		t.Fatalf("池满时应删除最早的事件")
+	}
+}
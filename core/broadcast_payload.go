@@ -0,0 +1,48 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"encoding/json"
+
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// decodeBroadcastTxPayload 解码number高度的广播交易内容.
+// 硬分叉前只接受JSON编码, 与旧版本节点的处理结果保持一致
+func decodeBroadcastTxPayload(st matrixstate.StateDB, number uint64, payload []byte) (map[string][]byte, error) {
+	if ForkActive(st, mc.ForkBroadcastCodec, number) {
+		return types.DecodeBroadcastPayload(payload)
+	}
+	if len(payload) == 0 {
+		return nil, types.ErrEmptyBroadcastPayload
+	}
+	data := make(map[string][]byte)
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// EncodeBroadcastTxPayload 按number高度启用的编码格式编码广播交易内容
+func EncodeBroadcastTxPayload(st matrixstate.StateDB, number uint64, data map[string][]byte) ([]byte, error) {
+	if ForkActive(st, mc.ForkBroadcastCodec, number) {
+		return types.EncodeBroadcastPayload(data)
+	}
+	return json.Marshal(data)
+}
+
+// switchBroadcastTxCodec 硬分叉生效后将广播交易状态数据的编码切换为规范编码, 之后写入的数据按新编码存储
+func switchBroadcastTxCodec(st matrixstate.StateDB, number uint64) error {
+	if !ForkActive(st, mc.ForkBroadcastCodec, number) {
+		return nil
+	}
+	version, err := matrixstate.GetCodecVersion(st, mc.MSKeyBroadcastTx)
+	if err != nil || version == mc.BroadcastTxCodecCanonical {
+		return err
+	}
+	return matrixstate.SetCodecVersion(st, mc.MSKeyBroadcastTx, mc.BroadcastTxCodecCanonical)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func Test_broadcastTxPayloadFork(t *testing.T) {
+	st := newForkTestState(mc.ForkActivation{Name: mc.ForkBroadcastCodec, ActivateNumber: 100})
+	data := map[string][]byte{mc.Publickey: {0x01, 0x02}}
+
+	legacy, err := EncodeBroadcastTxPayload(st, 99, data)
+	if err != nil || len(legacy) == 0 || legacy[0] != '{' {
+		t.Fatalf("硬分叉前应使用JSON编码, data %x, err %v", legacy, err)
+	}
+	versioned, err := EncodeBroadcastTxPayload(st, 100, data)
+	if err != nil || len(versioned) == 0 || versioned[0] == '{' {
+		t.Fatalf("硬分叉后应使用版本化编码, data %x, err %v", versioned, err)
+	}
+
+	// 硬分叉前拒绝版本化编码, 硬分叉后两种编码都可解码
+	if _, err := decodeBroadcastTxPayload(st, 99, versioned); err == nil {
+		t.Errorf("硬分叉前解码版本化编码应返回错误")
+	}
+	for _, payload := range [][]byte{legacy, versioned} {
+		decoded, err := decodeBroadcastTxPayload(st, 100, payload)
+		if err != nil || !bytes.Equal(decoded[mc.Publickey], data[mc.Publickey]) {
+			t.Errorf("硬分叉后解码错误, payload %x, decoded %v, err %v", payload, decoded, err)
+		}
+	}
+}
+
+func Test_switchBroadcastTxCodec(t *testing.T) {
+	st := newForkTestState(mc.ForkActivation{Name: mc.ForkBroadcastCodec, ActivateNumber: 100})
+	for _, tt := range []struct {
+		number uint64
+		want   uint64
+	}{
+		{99, mc.BroadcastTxCodecRLP},
+		{100, mc.BroadcastTxCodecCanonical},
+		{200, mc.BroadcastTxCodecCanonical},
+	} {
+		if err := switchBroadcastTxCodec(st, tt.number); err != nil {
+			t.Fatalf("高度%d切换编码错误 %v", tt.number, err)
+		}
+		if version, err := matrixstate.GetCodecVersion(st, mc.MSKeyBroadcastTx); err != nil || version != tt.want {
+			t.Errorf("高度%d编码版本错误: have %d, want %d, err %v", tt.number, version, tt.want, err)
+		}
+	}
+}
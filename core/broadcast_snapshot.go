@@ -0,0 +1,157 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto/threshold"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+var (
+	// ErrFutureBroadcastInterval is returned when querying the broadcast data of
+	// an interval whose broadcast block has not been produced yet.
+	ErrFutureBroadcastInterval = errors.New("broadcast interval not finished yet")
+
+	// ErrBroadcastBlockNotFound is returned when no broadcast block could be
+	// located for the queried interval.
+	ErrBroadcastBlockNotFound = errors.New("broadcast block not found")
+)
+
+// BroadcastHistoryReader is the chain access needed to look up the broadcast
+// data of past intervals.
+type BroadcastHistoryReader interface {
+	ChainReader
+	CurrentHeader() *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+	GetBroadcastIntervalAt(number uint64) (*mc.BCIntervalInfo, error)
+}
+
+// BroadcastSnapshot is the broadcast data committed to the matrix state by the
+// broadcast block of an interval.
+type BroadcastSnapshot struct {
+	Interval uint64      // Broadcast interval the data was sent in
+	Number   uint64      // Number of the broadcast block committing the data
+	Hash     common.Hash // Hash of the broadcast block committing the data
+
+	// Payloads per broadcast type state key (mc.Heartbeat, mc.Publickey,
+	// mc.Privatekey, mc.CallTheRoll and any registered type) and sender
+	Data map[string]map[common.Address][]byte
+}
+
+// Heartbeats returns the heartbeat payloads of the snapshot by sender.
+func (s BroadcastSnapshot) Heartbeats() map[common.Address][]byte { return s.get(mc.Heartbeat) }
+
+// PublicKeys returns the seed proof payloads of the snapshot by sender.
+func (s BroadcastSnapshot) PublicKeys() map[common.Address][]byte { return s.get(mc.Publickey) }
+
+// PrivateKeys returns the seed payloads of the snapshot by sender.
+func (s BroadcastSnapshot) PrivateKeys() map[common.Address][]byte { return s.get(mc.Privatekey) }
+
+// PrivateKeyCiphertexts returns the threshold encrypted seed payloads of the
+// snapshot by sender, skipping the payloads not threshold encrypted.
+func (s BroadcastSnapshot) PrivateKeyCiphertexts() map[common.Address]*threshold.Ciphertext {
+	ciphertexts := make(map[common.Address]*threshold.Ciphertext)
+	for from, data := range s.PrivateKeys() {
+		if ct, err := DecodePrivatekeyPayload(data); err == nil {
+			ciphertexts[from] = ct
+		}
+	}
+	return ciphertexts
+}
+
+// SeedShares returns the seed share payloads of the snapshot by sender.
+func (s BroadcastSnapshot) SeedShares() map[common.Address][]byte { return s.get(mc.SeedShare) }
+
+// Seeds decrypts the threshold encrypted seed payloads of the snapshot with the
+// shares their recipients released in the snapshot of the next interval.
+func (s BroadcastSnapshot) Seeds(next BroadcastSnapshot) map[common.Address][]byte {
+	return CombinePrivatekeyPayloads(s.PrivateKeys(), next.SeedShares())
+}
+
+// VrfPublicKeys returns the VRF public keys registered in the snapshot by sender.
+func (s BroadcastSnapshot) VrfPublicKeys() map[common.Address][]byte { return s.get(mc.VrfPublicKey) }
+
+// CallTheRolls returns the roll call payloads of the snapshot by sender.
+func (s BroadcastSnapshot) CallTheRolls() map[common.Address][]byte { return s.get(mc.CallTheRoll) }
+
+func (s BroadcastSnapshot) get(key string) map[common.Address][]byte {
+	if data, ok := s.Data[key]; ok {
+		return data
+	}
+	return make(map[common.Address][]byte)
+}
+
+// GetBroadcastDataByInterval retrieves the broadcast data of any finished
+// broadcast interval, by locating the broadcast block closing the interval and
+// reading the broadcast transactions out of its matrix state. The broadcast
+// period in effect at the block is used, on-chain param updates may change it.
+func GetBroadcastDataByInterval(bc BroadcastHistoryReader, interval uint64) (BroadcastSnapshot, error) {
+	if interval == 0 {
+		return BroadcastSnapshot{}, ErrBroadcastBlockNotFound
+	}
+	head := bc.CurrentHeader().Number.Uint64()
+	bcInterval, err := bc.GetBroadcastIntervalAt(head)
+	if err != nil {
+		return BroadcastSnapshot{}, err
+	}
+	// Transactions of interval k are accepted on top of the blocks (k-1)*period
+	// up to k*period-1 and are committed by the broadcast block k*period. Settle
+	// on the period in effect at the estimated block.
+	period := bcInterval.GetBroadcastInterval()
+	for i := 0; i < 4; i++ {
+		at, err := bc.GetBroadcastIntervalAt(interval * period)
+		if err != nil || at.GetBroadcastInterval() == period {
+			break
+		}
+		period = at.GetBroadcastInterval()
+	}
+	number := interval * period
+	if number > head {
+		return BroadcastSnapshot{}, ErrFutureBroadcastInterval
+	}
+	// Walk the headers back to the broadcast block in case the estimate is off
+	var header *types.Header
+	for i := uint64(0); i < period && i <= number; i++ {
+		h := bc.GetHeaderByNumber(number - i)
+		if h == nil {
+			break
+		}
+		if at, err := bc.GetBroadcastIntervalAt(h.Number.Uint64()); err == nil && at.IsBroadcastNumber(h.Number.Uint64()) {
+			header = h
+			break
+		}
+	}
+	if header == nil {
+		return BroadcastSnapshot{}, ErrBroadcastBlockNotFound
+	}
+	return GetBroadcastDataByBlock(bc, interval, header)
+}
+
+// GetBroadcastDataByBlock retrieves the broadcast data committed by a broadcast
+// block, closing the given broadcast interval.
+func GetBroadcastDataByBlock(bc ChainReader, interval uint64, header *types.Header) (BroadcastSnapshot, error) {
+	st, err := bc.StateAt(header.Roots)
+	if err != nil {
+		return BroadcastSnapshot{}, err
+	}
+	txs, err := matrixstate.GetBroadcastTxs(st)
+	if err != nil {
+		return BroadcastSnapshot{}, err
+	}
+	snapshot := BroadcastSnapshot{
+		Interval: interval,
+		Number:   header.Number.Uint64(),
+		Hash:     header.Hash(),
+		Data:     make(map[string]map[common.Address][]byte),
+	}
+	for _, bt := range BroadcastTypes() {
+		snapshot.Data[bt.StateKey] = txs.FindKey(bt.StateKey)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/crypto/threshold"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// The privatekey broadcast payloads are threshold encrypted to the public keys
+// the validators sent in the publickey round: the payload can only be decrypted
+// once a quorum of the validators released their shares of the secret, so that
+// no single broadcast node learns it beforehand.
+
+var (
+	// ErrNoPrivatekeyRecipients is returned when encrypting a privatekey payload
+	// without any valid public key from the publickey round.
+	ErrNoPrivatekeyRecipients = errors.New("no public key to encrypt the privatekey payload to")
+
+	// ErrNotPrivatekeyRecipient is returned when decrypting the share of an
+	// account which sent no public key in the publickey round.
+	ErrNotPrivatekeyRecipient = errors.New("account is not a privatekey payload recipient")
+
+	// ErrInvalidPrivatekeyPayload is returned for privatekey payloads not
+	// threshold encrypted to a quorum of recipients.
+	ErrInvalidPrivatekeyPayload = errors.New("invalid privatekey payload")
+
+	// ErrInvalidSeedSharePayload is returned for seed share payloads which do
+	// not decode to a list of shares.
+	ErrInvalidSeedSharePayload = errors.New("invalid seed share payload")
+)
+
+// PrivatekeyShare is the decrypted share of the privatekey payload of a sender,
+// released by a recipient in a seed share broadcast transaction.
+type PrivatekeyShare struct {
+	Sender common.Address
+	Share  threshold.Share
+}
+
+// PrivatekeyThreshold returns the number of shares needed to decrypt a
+// privatekey payload encrypted to n recipients, two thirds of them as for the
+// block consensus.
+func PrivatekeyThreshold(n int) int {
+	return (2*n + 2) / 3
+}
+
+// PrivatekeyRecipients returns the senders of valid public keys of the
+// publickey round along with their keys, sorted by address. The share of the
+// i-th recipient has index i+1.
+func PrivatekeyRecipients(publicKeys map[common.Address][]byte) ([]common.Address, []*ecdsa.PublicKey) {
+	accounts := make([]common.Address, 0, len(publicKeys))
+	for account := range publicKeys {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return bytes.Compare(accounts[i][:], accounts[j][:]) < 0 })
+
+	recipients := make([]common.Address, 0, len(accounts))
+	keys := make([]*ecdsa.PublicKey, 0, len(accounts))
+	for _, account := range accounts {
+		pub := crypto.ToECDSAPub(publicKeys[account])
+		if pub == nil || pub.X == nil || !crypto.S256().IsOnCurve(pub.X, pub.Y) {
+			continue
+		}
+		recipients = append(recipients, account)
+		keys = append(keys, pub)
+	}
+	return recipients, keys
+}
+
+// EncryptPrivatekeyPayload threshold encrypts a privatekey payload to the public
+// keys of the publickey round.
+func EncryptPrivatekeyPayload(payload []byte, publicKeys map[common.Address][]byte) ([]byte, error) {
+	_, keys := PrivatekeyRecipients(publicKeys)
+	if len(keys) == 0 {
+		return nil, ErrNoPrivatekeyRecipients
+	}
+	ct, err := threshold.Encrypt(payload, keys, PrivatekeyThreshold(len(keys)))
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(ct)
+}
+
+// DecodePrivatekeyPayload decodes a threshold encrypted privatekey payload.
+func DecodePrivatekeyPayload(data []byte) (*threshold.Ciphertext, error) {
+	ct := new(threshold.Ciphertext)
+	if err := rlp.DecodeBytes(data, ct); err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+// DecryptPrivatekeyShare decrypts the share of a recipient of a privatekey
+// payload with the private key matching its public key of the publickey round.
+func DecryptPrivatekeyShare(data []byte, publicKeys map[common.Address][]byte, account common.Address, prv *ecdsa.PrivateKey) (threshold.Share, error) {
+	ct, err := DecodePrivatekeyPayload(data)
+	if err != nil {
+		return threshold.Share{}, err
+	}
+	recipients, _ := PrivatekeyRecipients(publicKeys)
+	for i, recipient := range recipients {
+		if recipient == account {
+			return ct.DecryptShare(uint32(i+1), prv)
+		}
+	}
+	return threshold.Share{}, ErrNotPrivatekeyRecipient
+}
+
+// CombinePrivatekeyPayload decrypts a privatekey payload from the shares
+// released by a quorum of its recipients.
+func CombinePrivatekeyPayload(data []byte, shares []threshold.Share) ([]byte, error) {
+	ct, err := DecodePrivatekeyPayload(data)
+	if err != nil {
+		return nil, err
+	}
+	return ct.Decrypt(shares)
+}
+
+// ReleasePrivatekeyShares decrypts the shares of all the privatekey payloads,
+// by sender, which were encrypted to the public key of prv. Payloads the key is
+// no recipient of are skipped.
+func ReleasePrivatekeyShares(payloads map[common.Address][]byte, prv *ecdsa.PrivateKey) []PrivatekeyShare {
+	senders := make([]common.Address, 0, len(payloads))
+	for sender := range payloads {
+		senders = append(senders, sender)
+	}
+	sort.Slice(senders, func(i, j int) bool { return bytes.Compare(senders[i][:], senders[j][:]) < 0 })
+
+	var shares []PrivatekeyShare
+	for _, sender := range senders {
+		ct, err := DecodePrivatekeyPayload(payloads[sender])
+		if err != nil {
+			continue
+		}
+		// The recipient only knows its key, try it on every share
+		for _, sealed := range ct.Shares {
+			if share, err := ct.DecryptShare(sealed.Index, prv); err == nil {
+				shares = append(shares, PrivatekeyShare{Sender: sender, Share: share})
+				break
+			}
+		}
+	}
+	return shares
+}
+
+// EncodePrivatekeyShares encodes the payload of a seed share transaction.
+func EncodePrivatekeyShares(shares []PrivatekeyShare) ([]byte, error) {
+	return rlp.EncodeToBytes(shares)
+}
+
+// DecodePrivatekeyShares decodes the payload of a seed share transaction.
+func DecodePrivatekeyShares(data []byte) ([]PrivatekeyShare, error) {
+	var shares []PrivatekeyShare
+	if err := rlp.DecodeBytes(data, &shares); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// CombinePrivatekeyPayloads decrypts the privatekey payloads, by sender, from
+// the seed share payloads released by their recipients. Shares failing the
+// commitments of a payload are ignored, and payloads without a quorum of valid
+// shares are left out of the result.
+func CombinePrivatekeyPayloads(payloads map[common.Address][]byte, sharePayloads map[common.Address][]byte) map[common.Address][]byte {
+	released := make(map[common.Address]map[uint32]threshold.Share)
+	for _, data := range sharePayloads {
+		shares, err := DecodePrivatekeyShares(data)
+		if err != nil {
+			continue
+		}
+		for _, share := range shares {
+			if released[share.Sender] == nil {
+				released[share.Sender] = make(map[uint32]threshold.Share)
+			}
+			released[share.Sender][share.Share.Index] = share.Share
+		}
+	}
+	plain := make(map[common.Address][]byte)
+	for sender, data := range payloads {
+		ct, err := DecodePrivatekeyPayload(data)
+		if err != nil {
+			continue
+		}
+		var shares []threshold.Share
+		for _, share := range released[sender] {
+			if share.Value != nil && threshold.VerifyShare(share, ct.Commitments) {
+				shares = append(shares, share)
+			}
+		}
+		if msg, err := ct.Decrypt(shares); err == nil {
+			plain[sender] = msg
+		}
+	}
+	return plain
+}
+
+// checkSeedSharePayload only accepts seed share payloads decoding to a list of
+// shares.
+func checkSeedSharePayload(payload []byte) error {
+	if _, err := DecodePrivatekeyShares(payload); err != nil {
+		return ErrInvalidSeedSharePayload
+	}
+	return nil
+}
+
+// checkPrivatekeyPayload only accepts privatekey payloads threshold encrypted
+// to a quorum of their recipients.
+func checkPrivatekeyPayload(payload []byte) error {
+	ct, err := DecodePrivatekeyPayload(payload)
+	if err != nil {
+		return ErrInvalidPrivatekeyPayload
+	}
+	if len(ct.Shares) == 0 || int(ct.Threshold) != PrivatekeyThreshold(len(ct.Shares)) || len(ct.Commitments) != int(ct.Threshold) {
+		return ErrInvalidPrivatekeyPayload
+	}
+	for i, share := range ct.Shares {
+		if share.Index != uint32(i+1) {
+			return ErrInvalidPrivatekeyPayload
+		}
+	}
+	return nil
+}
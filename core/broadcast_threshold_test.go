@@ -0,0 +1,117 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/crypto/threshold"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// Tests that a privatekey payload can only be decrypted by a quorum of the
+// validators of the publickey round.
+func TestPrivatekeyPayloadThreshold(t *testing.T) {
+	publicKeys := make(map[common.Address][]byte)
+	keys := make(map[common.Address]*ecdsa.PrivateKey)
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateKey()
+		account := crypto.PubkeyToAddress(key.PublicKey)
+		publicKeys[account] = crypto.FromECDSAPub(&key.PublicKey)
+		keys[account] = key
+	}
+	anyKey, _ := crypto.GenerateKey()
+	// Invalid public keys are no recipients
+	publicKeys[common.HexToAddress("0x01")] = []byte{1, 2, 3}
+
+	payload := []byte("seed")
+	data, err := EncryptPrivatekeyPayload(payload, publicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkBroadcastPayload(mc.Privatekey+"12", data); err != nil {
+		t.Fatalf("threshold encrypted payload rejected: %v", err)
+	}
+	if err := checkBroadcastPayload(mc.Privatekey+"12", payload); err != ErrInvalidPrivatekeyPayload {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrInvalidPrivatekeyPayload)
+	}
+
+	var shares []threshold.Share
+	for account, key := range keys {
+		share, err := DecryptPrivatekeyShare(data, publicKeys, account, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shares = append(shares, share)
+	}
+	if _, err := DecryptPrivatekeyShare(data, publicKeys, common.HexToAddress("0x01"), anyKey); err != ErrNotPrivatekeyRecipient {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNotPrivatekeyRecipient)
+	}
+	if _, err := CombinePrivatekeyPayload(data, shares[:PrivatekeyThreshold(4)-1]); err != threshold.ErrNotEnoughShares {
+		t.Fatalf("error mismatch: have %v, want %v", err, threshold.ErrNotEnoughShares)
+	}
+	plain, err := CombinePrivatekeyPayload(data, shares[:PrivatekeyThreshold(4)])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain, payload) {
+		t.Fatalf("payload mismatch: have %q, want %q", plain, payload)
+	}
+}
+
+// Tests that the recipients release their shares of all the privatekey payloads
+// of an interval, which then decrypt the payloads of the senders.
+func TestPrivatekeyShareRelease(t *testing.T) {
+	publicKeys := make(map[common.Address][]byte)
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateKey()
+		publicKeys[crypto.PubkeyToAddress(key.PublicKey)] = crypto.FromECDSAPub(&key.PublicKey)
+		keys = append(keys, key)
+	}
+	payloads := make(map[common.Address][]byte)
+	for i := byte(1); i <= 2; i++ {
+		data, err := EncryptPrivatekeyPayload([]byte{i}, publicKeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payloads[common.BytesToAddress([]byte{i})] = data
+	}
+
+	sharePayloads := make(map[common.Address][]byte)
+	for i, key := range keys[:PrivatekeyThreshold(4)] {
+		shares := ReleasePrivatekeyShares(payloads, key)
+		if len(shares) != len(payloads) {
+			t.Fatalf("recipient %d: share count mismatch: have %d, want %d", i, len(shares), len(payloads))
+		}
+		data, err := EncodePrivatekeyShares(shares)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkBroadcastPayload(mc.SeedShare+"12", data); err != nil {
+			t.Fatalf("seed share payload rejected: %v", err)
+		}
+		sharePayloads[crypto.PubkeyToAddress(key.PublicKey)] = data
+	}
+	// A forged share must not prevent the decryption
+	forged, _ := EncodePrivatekeyShares([]PrivatekeyShare{{Sender: common.BytesToAddress([]byte{1}), Share: threshold.Share{Index: 4, Value: common.Big1}}})
+	sharePayloads[common.HexToAddress("0x05")] = forged
+
+	plain := CombinePrivatekeyPayloads(payloads, sharePayloads)
+	for sender := range payloads {
+		if !bytes.Equal(plain[sender], sender[common.AddressLength-1:]) {
+			t.Errorf("sender %x: payload mismatch: have %x", sender, plain[sender])
+		}
+	}
+	// Without a quorum nothing is decrypted
+	delete(sharePayloads, crypto.PubkeyToAddress(keys[0].PublicKey))
+	if plain := CombinePrivatekeyPayloads(payloads, sharePayloads); len(plain) != 0 {
+		t.Errorf("decrypted %d payloads without a quorum of shares", len(plain))
+	}
+}
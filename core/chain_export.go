@@ -0,0 +1,321 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// ChainExportVersion 当前区块导出格式版本
+const ChainExportVersion = 1
+
+// chainExportMagic 版本化导出文件每个分段的起始标识, 旧格式的RLP区块流不会以该字节开头
+var chainExportMagic = []byte("MANCHAIN")
+
+// 导出文件记录类型
+const (
+	exportRecordHeader uint8 = iota
+	exportRecordBlock
+	exportRecordTrailer
+)
+
+var (
+	ErrExportFormat           = errors.New("invalid chain export format")
+	ErrExportChecksum         = errors.New("chain export checksum mismatch")
+	ErrExportIncompleteBody   = errors.New("block body is sharded, export requires a node storing complete bodies")
+	ErrBroadcastStateMismatch = errors.New("imported broadcast state differs from the exported one")
+)
+
+// ChainExportHeader 导出分段头
+type ChainExportHeader struct {
+	Version uint64
+	Genesis common.Hash
+	First   uint64
+	Last    uint64
+}
+
+// exportRecord 导出文件中的一条记录
+type exportRecord struct {
+	Kind    uint8
+	Payload []byte
+}
+
+// exportBlock 一个区块的完整内容(含特殊交易的Matrix_EX字段)以及广播区块提交的广播交易状态
+type exportBlock struct {
+	Block        []byte      // 区块RLP编码
+	BroadcastTxs []byte      // 广播区块的MSKeyBroadcastTx状态RLP编码, 普通区块为空
+	Checksum     common.Hash // Keccak256(Block || BroadcastTxs)
+}
+
+// exportTrailer 导出分段尾, 校验分段的完整性
+type exportTrailer struct {
+	Count    uint64
+	Checksum common.Hash // 所有区块校验和的累积哈希
+}
+
+func exportChecksum(parts ...[]byte) common.Hash {
+	return crypto.Keccak256Hash(parts...)
+}
+
+func writeExportRecord(w io.Writer, kind uint8, payload interface{}) error {
+	data, err := rlp.EncodeToBytes(payload)
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, exportRecord{Kind: kind, Payload: data})
+}
+
+// ExportVersioned 以版本化格式导出区块[first, last], 每个区块带校验和, 广播区块同时导出其广播交易状态.
+// 非归档节点已裁剪的广播区块状态不导出, 导入时跳过这些区块的广播状态比对
+func (bc *BlockChain) ExportVersioned(w io.Writer, first uint64, last uint64) error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if first > last {
+		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
+	}
+	log.Info("Exporting batch of blocks", "count", last-first+1, "version", ChainExportVersion)
+
+	if _, err := w.Write(chainExportMagic); err != nil {
+		return err
+	}
+	header := ChainExportHeader{Version: ChainExportVersion, Genesis: bc.genesisBlock.Hash(), First: first, Last: last}
+	if err := writeExportRecord(w, exportRecordHeader, header); err != nil {
+		return err
+	}
+	trailer := exportTrailer{}
+	pruned := 0
+	for nr := first; nr <= last; nr++ {
+		block := bc.GetBlockByNumber(nr)
+		if block == nil {
+			return fmt.Errorf("export failed on #%d: not found", nr)
+		}
+		for _, currency := range block.Currencies() {
+			if len(currency.Transactions.Sharding) != 0 {
+				return fmt.Errorf("export failed on #%d: %v", nr, ErrExportIncompleteBody)
+			}
+		}
+		entry := exportBlock{}
+		var err error
+		if entry.Block, err = rlp.EncodeToBytes(block); err != nil {
+			return err
+		}
+		if nr > 0 && manparams.IsBroadcastNumberByHash(nr, block.ParentHash()) {
+			if st, err := bc.StateAt(block.Root()); err != nil {
+				log.Debug("Broadcast state unavailable, exporting block without it", "number", nr, "err", err)
+				pruned++
+			} else {
+				txs, err := matrixstate.GetBroadcastTxs(st)
+				if err != nil {
+					return fmt.Errorf("export failed on #%d: %v", nr, err)
+				}
+				if entry.BroadcastTxs, err = rlp.EncodeToBytes(txs); err != nil {
+					return err
+				}
+			}
+		}
+		entry.Checksum = exportChecksum(entry.Block, entry.BroadcastTxs)
+		if err := writeExportRecord(w, exportRecordBlock, entry); err != nil {
+			return err
+		}
+		trailer.Count++
+		trailer.Checksum = exportChecksum(trailer.Checksum[:], entry.Checksum[:])
+	}
+	if pruned > 0 {
+		log.Warn("Exported broadcast blocks without their pruned state", "count", pruned)
+	}
+	return writeExportRecord(w, exportRecordTrailer, trailer)
+}
+
+// IsVersionedExport 检查数据流是否以版本化导出格式开头
+func IsVersionedExport(r *bufio.Reader) bool {
+	magic, err := r.Peek(len(chainExportMagic))
+	return err == nil && bytes.Equal(magic, chainExportMagic)
+}
+
+// ChainExportReader 流式读取一个版本化导出分段, 逐个区块校验
+type ChainExportReader struct {
+	Header ChainExportHeader
+
+	stream   *rlp.Stream
+	count    uint64
+	checksum common.Hash
+	done     bool
+}
+
+// NewChainExportReader 读取并检查分段头
+func NewChainExportReader(r *bufio.Reader) (*ChainExportReader, error) {
+	magic := make([]byte, len(chainExportMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, chainExportMagic) {
+		return nil, ErrExportFormat
+	}
+	reader := &ChainExportReader{stream: rlp.NewStream(r, 0)}
+	var record exportRecord
+	if err := reader.stream.Decode(&record); err != nil {
+		return nil, err
+	}
+	if record.Kind != exportRecordHeader {
+		return nil, ErrExportFormat
+	}
+	if err := rlp.DecodeBytes(record.Payload, &reader.Header); err != nil {
+		return nil, err
+	}
+	if reader.Header.Version > ChainExportVersion {
+		return nil, fmt.Errorf("unsupported chain export version %d", reader.Header.Version)
+	}
+	return reader, nil
+}
+
+// Next 返回下一个区块及其导出时的广播交易状态(非广播区块为nil), 分段结束且校验通过后返回io.EOF
+func (r *ChainExportReader) Next() (*types.Block, common.BroadTxSlice, error) {
+	if r.done {
+		return nil, nil, io.EOF
+	}
+	var record exportRecord
+	if err := r.stream.Decode(&record); err != nil {
+		if err == io.EOF {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+		return nil, nil, err
+	}
+	switch record.Kind {
+	case exportRecordBlock:
+		var entry exportBlock
+		if err := rlp.DecodeBytes(record.Payload, &entry); err != nil {
+			return nil, nil, err
+		}
+		if exportChecksum(entry.Block, entry.BroadcastTxs) != entry.Checksum {
+			return nil, nil, fmt.Errorf("block %d of segment: %v", r.count, ErrExportChecksum)
+		}
+		block := new(types.Block)
+		if err := rlp.DecodeBytes(entry.Block, block); err != nil {
+			return nil, nil, err
+		}
+		var txs common.BroadTxSlice
+		if len(entry.BroadcastTxs) > 0 {
+			if err := rlp.DecodeBytes(entry.BroadcastTxs, &txs); err != nil {
+				return nil, nil, err
+			}
+		}
+		r.count++
+		r.checksum = exportChecksum(r.checksum[:], entry.Checksum[:])
+		return block, txs, nil
+
+	case exportRecordTrailer:
+		var trailer exportTrailer
+		if err := rlp.DecodeBytes(record.Payload, &trailer); err != nil {
+			return nil, nil, err
+		}
+		if trailer.Count != r.count || trailer.Checksum != r.checksum {
+			return nil, nil, ErrExportChecksum
+		}
+		r.done = true
+		return nil, nil, io.EOF
+
+	default:
+		return nil, nil, ErrExportFormat
+	}
+}
+
+// VerifyBroadcastState 检查导入的广播区块在本地重新生成的广播交易状态与导出时一致
+func (bc *BlockChain) VerifyBroadcastState(block *types.Block, want common.BroadTxSlice) error {
+	st, err := bc.StateAt(block.Root())
+	if err != nil {
+		return err
+	}
+	have, err := matrixstate.GetBroadcastTxs(st)
+	if err != nil {
+		return err
+	}
+	haveEnc, err := rlp.EncodeToBytes(have)
+	if err != nil {
+		return err
+	}
+	wantEnc, err := rlp.EncodeToBytes(want)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(haveEnc, wantEnc) {
+		return fmt.Errorf("block %d: %v", block.NumberU64(), ErrBroadcastStateMismatch)
+	}
+	return nil
+}
+
+// ImportVersioned 流式导入版本化导出文件的所有分段, 每批最多batchSize个区块.
+// 每个新写入的广播区块都会与导出时的广播交易状态比对. interrupt返回true时在下一批前停止
+func (bc *BlockChain) ImportVersioned(r *bufio.Reader, batchSize int, interrupt func() bool) (int, error) {
+	imported := 0
+	for IsVersionedExport(r) {
+		segment, err := NewChainExportReader(r)
+		if err != nil {
+			return imported, err
+		}
+		if segment.Header.Genesis != bc.genesisBlock.Hash() {
+			return imported, fmt.Errorf("export genesis %x mismatches local genesis %x", segment.Header.Genesis, bc.genesisBlock.Hash())
+		}
+		for done := false; !done; {
+			if interrupt != nil && interrupt() {
+				return imported, errors.New("interrupted")
+			}
+			blocks := make(types.Blocks, 0, batchSize)
+			broadcasts := make(map[common.Hash]common.BroadTxSlice)
+			for len(blocks) < batchSize {
+				block, txs, err := segment.Next()
+				if err == io.EOF {
+					done = true
+					break
+				}
+				if err != nil {
+					return imported, fmt.Errorf("segment %d-%d: %v", segment.Header.First, segment.Header.Last, err)
+				}
+				if block.NumberU64() == 0 {
+					continue
+				}
+				blocks = append(blocks, block)
+				if txs != nil {
+					broadcasts[block.Hash()] = txs
+				}
+			}
+			missing := make(types.Blocks, 0, len(blocks))
+			for _, block := range blocks {
+				if !bc.HasBlockAndState(block.Hash(), block.NumberU64()) {
+					missing = append(missing, block)
+				}
+			}
+			if len(missing) == 0 {
+				continue
+			}
+			if _, err := bc.InsertChain(missing, 0); err != nil {
+				return imported, fmt.Errorf("invalid block %d: %v", missing[0].NumberU64(), err)
+			}
+			for _, block := range missing {
+				if txs, ok := broadcasts[block.Hash()]; ok {
+					if err := bc.VerifyBroadcastState(block, txs); err != nil {
+						return imported, err
+					}
+				}
+			}
+			imported += len(missing)
+		}
+	}
+	if _, err := r.Peek(1); err != io.EOF {
+		return imported, ErrExportFormat
+	}
+	return imported, nil
+}
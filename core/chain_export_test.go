@@ -0,0 +1,100 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// writeTestSegment writes a versioned export segment holding the given block,
+// committing the broadcast txs.
+func writeTestSegment(t *testing.T, w io.Writer, block *types.Block, txs common.BroadTxSlice, tamper bool) {
+	w.Write(chainExportMagic)
+	header := ChainExportHeader{Version: ChainExportVersion, First: block.NumberU64(), Last: block.NumberU64()}
+	if err := writeExportRecord(w, exportRecordHeader, header); err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+	entry := exportBlock{}
+	entry.Block, _ = rlp.EncodeToBytes(block)
+	entry.BroadcastTxs, _ = rlp.EncodeToBytes(txs)
+	entry.Checksum = exportChecksum(entry.Block, entry.BroadcastTxs)
+	if tamper {
+		entry.BroadcastTxs[len(entry.BroadcastTxs)-1] ^= 0xff
+	}
+	if err := writeExportRecord(w, exportRecordBlock, entry); err != nil {
+		t.Fatalf("failed to write block: %v", err)
+	}
+	trailer := exportTrailer{Count: 1, Checksum: exportChecksum(common.Hash{}.Bytes(), entry.Checksum[:])}
+	if err := writeExportRecord(w, exportRecordTrailer, trailer); err != nil {
+		t.Fatalf("failed to write trailer: %v", err)
+	}
+}
+
+func TestChainExportRoundTrip(t *testing.T) {
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(100), Difficulty: big.NewInt(1), Time: big.NewInt(1)})
+	var txs common.BroadTxSlice
+	txs.Insert(mc.Heartbeat, common.HexToAddress("0x01"), []byte{1, 2, 3})
+
+	buf := new(bytes.Buffer)
+	writeTestSegment(t, buf, block, txs, false)
+	writeTestSegment(t, buf, block, txs, false)
+
+	r := bufio.NewReader(buf)
+	for segments := 0; IsVersionedExport(r); segments++ {
+		segment, err := NewChainExportReader(r)
+		if err != nil {
+			t.Fatalf("segment %d: failed to read header: %v", segments, err)
+		}
+		have, haveTxs, err := segment.Next()
+		if err != nil {
+			t.Fatalf("segment %d: failed to read block: %v", segments, err)
+		}
+		if have.Hash() != block.Hash() {
+			t.Errorf("segment %d: block hash mismatch: have %x, want %x", segments, have.Hash(), block.Hash())
+		}
+		if len(haveTxs) != 1 || !bytes.Equal(haveTxs[0].Value, []byte{1, 2, 3}) {
+			t.Errorf("segment %d: broadcast txs mismatch: %v", segments, haveTxs)
+		}
+		if _, _, err := segment.Next(); err != io.EOF {
+			t.Errorf("segment %d: trailer not verified: %v", segments, err)
+		}
+	}
+	if buf.Len() != 0 || r.Buffered() != 0 {
+		t.Errorf("unread export data left")
+	}
+}
+
+func TestChainExportChecksum(t *testing.T) {
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(100), Difficulty: big.NewInt(1), Time: big.NewInt(1)})
+	var txs common.BroadTxSlice
+	txs.Insert(mc.Heartbeat, common.HexToAddress("0x01"), []byte{1, 2, 3})
+
+	buf := new(bytes.Buffer)
+	writeTestSegment(t, buf, block, txs, true)
+
+	segment, err := NewChainExportReader(bufio.NewReader(buf))
+	if err != nil {
+		t.Fatalf("failed to read header: %v", err)
+	}
+	if _, _, err := segment.Next(); err == nil {
+		t.Fatalf("tampered block accepted")
+	}
+}
+
+func TestIsVersionedExport(t *testing.T) {
+	legacy, _ := rlp.EncodeToBytes(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}))
+	if IsVersionedExport(bufio.NewReader(bytes.NewReader(legacy))) {
+		t.Errorf("legacy block stream detected as versioned export")
+	}
+}
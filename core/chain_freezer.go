@@ -0,0 +1,319 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+// 冻结区数据表
+const (
+	freezerHashTable       = "hashes"
+	freezerHeaderTable     = "headers"
+	freezerBodiesTable     = "bodies"
+	freezerReceiptTable    = "receipts"
+	freezerDifficultyTable = "diffs"
+)
+
+// chainFreezerTables 冻结区数据表及是否压缩
+var chainFreezerTables = map[string]bool{
+	freezerHashTable:       false,
+	freezerHeaderTable:     true,
+	freezerBodiesTable:     true,
+	freezerReceiptTable:    true,
+	freezerDifficultyTable: false,
+}
+
+const (
+	freezeInterval   = 1024  // 两次冻结之间的区块数
+	freezeBatchLimit = 30000 // 单次冻结的最大区块数
+	freezeBatchSize  = 256   // 每批冻结的区块数, 每批落盘后删除键值库中的数据
+)
+
+// rawdb的键前缀: 区块头(及难度后缀), 区块体, 收据, 区块哈希->高度索引
+var (
+	headerPrefix        = []byte("h")
+	headerTDSuffix      = []byte("t")
+	blockBodyPrefix     = []byte("b")
+	blockReceiptsPrefix = []byte("r")
+	headerNumberPrefix  = []byte("H") // 删除区块头后需要保留该索引
+)
+
+// ErrAncientThresholdTooLow 冻结阈值小于内存中保留的状态数, 冻结的区块可能被回滚
+var ErrAncientThresholdTooLow = errors.New("ancient threshold shorter than the in-memory state window")
+
+// chainBlockKey 生成rawdb按高度和哈希索引的键
+func chainBlockKey(prefix []byte, number uint64, hash common.Hash) []byte {
+	key := make([]byte, len(prefix)+8+common.HashLength)
+	copy(key, prefix)
+	binary.BigEndian.PutUint64(key[len(prefix):], number)
+	copy(key[len(prefix)+8:], hash[:])
+	return key
+}
+
+// ancientKey 解析rawdb的键, 返回对应的冻结表, 区块高度和哈希
+func ancientKey(key []byte) (string, uint64, common.Hash, bool) {
+	var kind string
+	switch {
+	case len(key) == 41 && key[0] == headerPrefix[0]:
+		kind = freezerHeaderTable
+	case len(key) == 42 && key[0] == headerPrefix[0] && key[41] == headerTDSuffix[0]:
+		kind = freezerDifficultyTable
+	case len(key) == 41 && key[0] == blockBodyPrefix[0]:
+		kind = freezerBodiesTable
+	case len(key) == 41 && key[0] == blockReceiptsPrefix[0]:
+		kind = freezerReceiptTable
+	default:
+		return "", 0, common.Hash{}, false
+	}
+	return kind, binary.BigEndian.Uint64(key[1:9]), common.BytesToHash(key[9:41]), true
+}
+
+// AncientDatabase 在键值库之上透明回退到冻结区: 已冻结的区块头, 区块体, 收据和难度从键值库删除后,
+// 经rawdb读取时由冻结区返回原始数据, 所有使用链数据库的模块(交易查询, 日志过滤, 索引等)均可读到冻结的区块
+type AncientDatabase struct {
+	mandb.Database
+	ancients *mandb.Freezer
+}
+
+// OpenAncientDatabase 打开datadir下的冻结区, 并包装键值库db
+func OpenAncientDatabase(db mandb.Database, datadir string) (*AncientDatabase, error) {
+	ancients, err := mandb.NewFreezer(datadir, chainFreezerTables)
+	if err != nil {
+		return nil, err
+	}
+	return &AncientDatabase{Database: db, ancients: ancients}, nil
+}
+
+// ancient 读取冻结区块在表kind中的数据, 区块不在冻结区时返回nil
+func (db *AncientDatabase) ancient(kind string, hash common.Hash, number uint64) []byte {
+	if !db.frozen(hash, number) {
+		return nil
+	}
+	data, err := db.ancients.Ancient(kind, number)
+	if err != nil {
+		log.Error("Failed to read ancient data", "table", kind, "number", number, "err", err)
+		return nil
+	}
+	return data
+}
+
+// frozen 检查区块是否为冻结区中的规范链区块
+func (db *AncientDatabase) frozen(hash common.Hash, number uint64) bool {
+	data, err := db.ancients.Ancient(freezerHashTable, number)
+	return err == nil && common.BytesToHash(data) == hash
+}
+
+func (db *AncientDatabase) Get(key []byte) ([]byte, error) {
+	data, err := db.Database.Get(key)
+	if err == nil {
+		return data, nil
+	}
+	if kind, number, hash, ok := ancientKey(key); ok {
+		if data := db.ancient(kind, hash, number); len(data) > 0 {
+			return data, nil
+		}
+	}
+	return nil, err
+}
+
+func (db *AncientDatabase) Has(key []byte) (bool, error) {
+	if has, err := db.Database.Has(key); has || err != nil {
+		return has, err
+	}
+	if kind, number, hash, ok := ancientKey(key); ok {
+		return db.frozen(hash, number) && db.ancients.HasAncient(kind, number), nil
+	}
+	return false, nil
+}
+
+// NewIteratorWithPrefix 只遍历键值库中的数据
+func (db *AncientDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	if it, ok := db.Database.(mandb.Iteratee); ok {
+		return it.NewIteratorWithPrefix(prefix)
+	}
+	return iterator.NewEmptyIterator(errors.New("database does not support iteration"))
+}
+
+func (db *AncientDatabase) Stat() (string, error) {
+	if st, ok := db.Database.(mandb.Stater); ok {
+		return st.Stat()
+	}
+	return "", errors.New("database does not support stats")
+}
+
+func (db *AncientDatabase) Close() {
+	if err := db.ancients.Close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+	db.Database.Close()
+}
+
+// chainFreezer 将距链头超过threshold个区块的规范链区块头, 区块体, 收据和难度移入只追加的平面文件,
+// 然后从键值库中删除, 读取时由AncientDatabase透明回退到冻结区
+type chainFreezer struct {
+	bc        *BlockChain
+	db        *AncientDatabase
+	threshold uint64
+	owned     bool       // 冻结区由BlockChain打开, 停止时需关闭
+	lock      sync.Mutex // 冻结与回滚链头互斥
+	running   int32      // 冻结是否在进行中, 需原子访问
+}
+
+func newChainFreezer(bc *BlockChain, db *AncientDatabase, threshold uint64, owned bool) (*chainFreezer, error) {
+	if threshold < triesInMemory {
+		return nil, ErrAncientThresholdTooLow
+	}
+	return &chainFreezer{bc: bc, db: db, threshold: threshold, owned: owned}, nil
+}
+
+// trigger 后台启动一次冻结, 已在进行中则忽略
+func (f *chainFreezer) trigger() {
+	if !atomic.CompareAndSwapInt32(&f.running, 0, 1) {
+		return
+	}
+	f.bc.wg.Add(1)
+	go func() {
+		defer f.bc.wg.Done()
+		defer atomic.StoreInt32(&f.running, 0)
+
+		if _, err := f.freeze(); err != nil {
+			log.Error("Failed to freeze chain segment", "err", err)
+		}
+	}()
+}
+
+// freeze 将阈值之外的规范链区块分批移入冻结区, 返回冻结的区块数. 冻结不持有链锁,
+// 阈值之外的区块不会被回滚, 每批删除前仍确认区块仍在规范链上
+func (f *chainFreezer) freeze() (int, error) {
+	head := f.bc.CurrentBlock().NumberU64()
+	if head <= f.threshold {
+		return 0, nil
+	}
+	first, limit := f.db.ancients.Ancients(), head-f.threshold
+	if first >= limit {
+		return 0, nil
+	}
+	if limit-first > freezeBatchLimit {
+		limit = first + freezeBatchLimit
+	}
+	start := time.Now()
+
+	frozen := 0
+	for batch := first; batch < limit; batch += freezeBatchSize {
+		select {
+		case <-f.bc.quit:
+			return frozen, nil
+		default:
+		}
+		end := batch + freezeBatchSize
+		if end > limit {
+			end = limit
+		}
+		n, err := f.freezeBatch(batch, end)
+		frozen += n
+		if err != nil {
+			return frozen, err
+		}
+	}
+	log.Info("Moved chain segment into ancient store", "from", first, "count", frozen, "frozen", f.db.ancients.Ancients(), "elapsed", common.PrettyDuration(time.Since(start)))
+	return frozen, nil
+}
+
+// freezeBatch 冻结[first, end)区间的规范链区块
+func (f *chainFreezer) freezeBatch(first, end uint64) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// 回滚链头可能已截断冻结区
+	if f.db.ancients.Ancients() != first {
+		return 0, nil
+	}
+	hashes := make([]common.Hash, 0, end-first)
+	for number := first; number < end; number++ {
+		hash := rawdb.ReadCanonicalHash(f.db, number)
+		items, err := f.ancientItems(hash, number)
+		if err != nil {
+			return 0, err
+		}
+		if err := f.db.ancients.AppendAncient(number, items); err != nil {
+			return 0, err
+		}
+		hashes = append(hashes, hash)
+	}
+	// 冻结区落盘后才能删除键值库中的数据, 创世区块始终保留
+	if err := f.db.ancients.Sync(); err != nil {
+		return 0, err
+	}
+	for i, hash := range hashes {
+		number := first + uint64(i)
+		if number == 0 {
+			continue
+		}
+		if rawdb.ReadCanonicalHash(f.db, number) != hash {
+			return i, fmt.Errorf("block #%d [%x] reorged while freezing", number, hash[:4])
+		}
+		for _, prefix := range [][]byte{blockBodyPrefix, blockReceiptsPrefix, headerPrefix} {
+			if err := f.db.Database.Delete(chainBlockKey(prefix, number, hash)); err != nil {
+				return i, err
+			}
+		}
+		if err := f.db.Database.Delete(append(chainBlockKey(headerPrefix, number, hash), headerTDSuffix...)); err != nil {
+			return i, err
+		}
+	}
+	return len(hashes), nil
+}
+
+// ancientItems 读取规范链区块在各冻结表中的数据, 保存键值库中的原始编码
+func (f *chainFreezer) ancientItems(hash common.Hash, number uint64) (map[string][]byte, error) {
+	kv := f.db.Database
+	header, _ := kv.Get(chainBlockKey(headerPrefix, number, hash))
+	body, _ := kv.Get(chainBlockKey(blockBodyPrefix, number, hash))
+	td, _ := kv.Get(append(chainBlockKey(headerPrefix, number, hash), headerTDSuffix...))
+	if len(header) == 0 || len(body) == 0 || len(td) == 0 {
+		return nil, fmt.Errorf("block #%d [%x] missing from database", number, hash[:4])
+	}
+	receipts, _ := kv.Get(chainBlockKey(blockReceiptsPrefix, number, hash))
+	return map[string][]byte{
+		freezerHashTable:       hash.Bytes(),
+		freezerHeaderTable:     header,
+		freezerBodiesTable:     body,
+		freezerReceiptTable:    receipts,
+		freezerDifficultyTable: td,
+	}, nil
+}
+
+// frozen 检查区块是否为冻结区中的规范链区块
+func (f *chainFreezer) frozen(hash common.Hash, number uint64) bool {
+	return f.db.frozen(hash, number)
+}
+
+// truncate 回滚链头时丢弃head之后的冻结区块
+func (f *chainFreezer) truncate(head uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.db.ancients.TruncateAncients(head + 1)
+}
+
+func (f *chainFreezer) close() {
+	if !f.owned {
+		return
+	}
+	if err := f.db.ancients.Close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+}
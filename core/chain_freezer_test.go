@@ -0,0 +1,67 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+)
+
+func TestAncientDatabaseFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ancient-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := OpenAncientDatabase(mandb.NewMemDatabase(), dir)
+	if err != nil {
+		t.Fatalf("failed to open ancient database: %v", err)
+	}
+	defer db.Close()
+
+	hash := common.HexToHash("0x01")
+	items := map[string][]byte{
+		freezerHashTable:       hash.Bytes(),
+		freezerHeaderTable:     {0x01},
+		freezerBodiesTable:     {0x02},
+		freezerReceiptTable:    {0x03},
+		freezerDifficultyTable: {0x04},
+	}
+	if err := db.ancients.AppendAncient(0, items); err != nil {
+		t.Fatalf("failed to freeze block: %v", err)
+	}
+	// The rawdb keys of a frozen block are served from the freezer
+	keys := map[string][]byte{
+		freezerHeaderTable:     chainBlockKey(headerPrefix, 0, hash),
+		freezerBodiesTable:     chainBlockKey(blockBodyPrefix, 0, hash),
+		freezerReceiptTable:    chainBlockKey(blockReceiptsPrefix, 0, hash),
+		freezerDifficultyTable: append(chainBlockKey(headerPrefix, 0, hash), headerTDSuffix...),
+	}
+	for kind, key := range keys {
+		if data, err := db.Get(key); err != nil || !bytes.Equal(data, items[kind]) {
+			t.Errorf("%s: ancient data mismatch: have %x, want %x, err %v", kind, data, items[kind], err)
+		}
+		if has, _ := db.Has(key); !has {
+			t.Errorf("%s: ancient data missing", kind)
+		}
+	}
+	// Blocks of another hash or number are not served
+	for _, key := range [][]byte{chainBlockKey(headerPrefix, 0, common.HexToHash("0x02")), chainBlockKey(headerPrefix, 1, hash)} {
+		if _, err := db.Get(key); err == nil {
+			t.Errorf("non-frozen block %x served", key)
+		}
+	}
+	// Recent data is read from the key-value store
+	db.Put([]byte("key"), []byte("value"))
+	if data, err := db.Get([]byte("key")); err != nil || string(data) != "value" {
+		t.Errorf("key-value data mismatch: have %s, err %v", data, err)
+	}
+}
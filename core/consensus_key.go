@@ -0,0 +1,148 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/crypto/bls"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+var (
+	ErrConsensusKeyDisabled = errors.New("consensus key registration is not enabled at this height")
+	ErrConsensusKeyDeposit  = errors.New("consensus key registration sender is not a deposit account")
+	ErrConsensusKeyProof    = errors.New("consensus key registration proof is not signed by the consensus key")
+	ErrConsensusKeyInUse    = errors.New("consensus key is already used by another account")
+	ErrConsensusKeyPending  = errors.New("previous consensus key registration is not active yet")
+	ErrConsensusKeyBLS      = errors.New("consensus BLS public key is invalid")
+	ErrConsensusKeyBLSProof = errors.New("consensus BLS public key possession is not proven")
+	ErrConsensusKeyNoSigner = errors.New("consensus key registration has no signer")
+)
+
+// ConsensusKeyRegistration registers a dedicated consensus signing key for the
+// deposit account sending it, so that the keys of the deposit and authorized
+// (A1) accounts can be kept offline. The new key proves possession by signing
+// SignHash, and the BLS key, if any, by a proof of possession preventing rogue
+// keys from forging aggregated votes. It becomes active after the activation
+// delay of the matrix state, the previous key keeps signing in the meantime.
+type ConsensusKeyRegistration struct {
+	Signer    common.Address   // Account of the ECDSA consensus key
+	BLSPubKey []byte           // Optional BLS consensus public key
+	Proof     common.Signature // Signature of the consensus key on SignHash
+	BLSProof  []byte           // Proof of possession of the BLS key
+}
+
+// SignHash returns the hash signed by the consensus key of the deposit account.
+func (r *ConsensusKeyRegistration) SignHash(account common.Address) common.Hash {
+	return types.RlpHash([]interface{}{account, r.Signer, r.BLSPubKey})
+}
+
+// EncodeConsensusKeyRegistration encodes the registration as the payload of a
+// consensus key transaction.
+func EncodeConsensusKeyRegistration(r *ConsensusKeyRegistration) ([]byte, error) {
+	return rlp.EncodeToBytes(r)
+}
+
+// DecodeConsensusKeyRegistration decodes the payload of a consensus key
+// transaction.
+func DecodeConsensusKeyRegistration(data []byte) (*ConsensusKeyRegistration, error) {
+	r := new(ConsensusKeyRegistration)
+	if err := rlp.DecodeBytes(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// verifyBLSPossession 验证BLS共识公钥及其持有证明
+func verifyBLSPossession(r *ConsensusKeyRegistration) error {
+	if len(r.BLSPubKey) == 0 {
+		return nil
+	}
+	pubKey, err := bls.PublicKeyFromBytes(r.BLSPubKey)
+	if err != nil {
+		return ErrConsensusKeyBLS
+	}
+	proof, err := bls.SignatureFromBytes(r.BLSProof)
+	if err != nil || !bls.VerifyPossession(pubKey, proof) {
+		return ErrConsensusKeyBLSProof
+	}
+	return nil
+}
+
+// consensusKeyInUse 检查签名账户是否已被其他抵押账户作为A1账户或共识签名账户使用
+func consensusKeyInUse(st vm.StateDBManager, keys *mc.ConsensusKeys, account common.Address, signer common.Address) bool {
+	if owner := depoistInfo.GetDepositAccount(st, signer); owner != (common.Address{}) && owner != account {
+		return true
+	}
+	for _, key := range keys.Keys {
+		if key.Account != account && (key.Signer == signer || key.PrevSigner == signer) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyConsensusKeyRegistration 验证共识密钥注册并记录, 在注册高度加上生效延迟后生效
+func applyConsensusKeyRegistration(st vm.StateDBManager, account common.Address, r *ConsensusKeyRegistration, number uint64) error {
+	if !ForkActive(st, mc.ForkConsensusKey, number) {
+		return ErrConsensusKeyDisabled
+	}
+	if r.Signer == (common.Address{}) {
+		return ErrConsensusKeyNoSigner
+	}
+	if err := verifyBLSPossession(r); err != nil {
+		return err
+	}
+	// 只有抵押账户可以注册共识密钥
+	if depoistInfo.GetAuthAccount(st, account) == (common.Address{}) {
+		return ErrConsensusKeyDeposit
+	}
+	signer, _, err := crypto.VerifySignWithValidate(r.SignHash(account).Bytes(), r.Proof.Bytes())
+	if err != nil || signer != r.Signer {
+		return ErrConsensusKeyProof
+	}
+	cfg, err := matrixstate.GetConsensusKeyCfg(st)
+	if err != nil {
+		return err
+	}
+	keys, err := matrixstate.GetConsensusKeys(st)
+	if err != nil {
+		return err
+	}
+	if consensusKeyInUse(st, keys, account, r.Signer) {
+		return ErrConsensusKeyInUse
+	}
+
+	entry := mc.ConsensusKey{
+		Account:        account,
+		Signer:         r.Signer,
+		BLSPubKey:      r.BLSPubKey,
+		ActivateNumber: number + cfg.ActivateDelay,
+	}
+	for i, key := range keys.Keys {
+		if key.Account != account {
+			continue
+		}
+		// 上一次注册生效前不能再次轮换, 否则生效前使用的密钥无法确定
+		if number < key.ActivateNumber {
+			return ErrConsensusKeyPending
+		}
+		entry.PrevSigner, entry.PrevBLSPubKey = key.Signer, key.BLSPubKey
+		keys.Keys[i] = entry
+		log.Info(ModuleName, "共识密钥轮换, 抵押账户", account.Hex(), "共识账户", r.Signer.Hex(), "生效高度", entry.ActivateNumber)
+		return matrixstate.SetConsensusKeys(st, keys)
+	}
+	keys.Keys = append(keys.Keys, entry)
+	log.Info(ModuleName, "共识密钥注册, 抵押账户", account.Hex(), "共识账户", r.Signer.Hex(), "生效高度", entry.ActivateNumber)
+	return matrixstate.SetConsensusKeys(st, keys)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/crypto/bls"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func Test_consensusKeyRegistration(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	blsKey, _ := bls.GenerateKey()
+	account := common.HexToAddress("0x01")
+
+	reg := &ConsensusKeyRegistration{
+		Signer:    crypto.PubkeyToAddress(key.PublicKey),
+		BLSPubKey: blsKey.PublicKey().Bytes(),
+		BLSProof:  blsKey.ProvePossession().Bytes(),
+	}
+	sign, err := crypto.SignWithValidate(reg.SignHash(account).Bytes(), true, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.Proof = common.BytesToSignature(sign)
+
+	data, err := EncodeConsensusKeyRegistration(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeConsensusKeyRegistration(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, _, err := crypto.VerifySignWithValidate(decoded.SignHash(account).Bytes(), decoded.Proof.Bytes())
+	if err != nil || signer != reg.Signer {
+		t.Fatalf("共识密钥证明验证失败 signer %x err %v", signer, err)
+	}
+	// 证明与抵押账户绑定, 不能用于其他账户
+	signer, _, _ = crypto.VerifySignWithValidate(decoded.SignHash(common.HexToAddress("0x02")).Bytes(), decoded.Proof.Bytes())
+	if signer == reg.Signer {
+		t.Fatalf("共识密钥证明可用于其他抵押账户")
+	}
+}
+
+func Test_verifyBLSPossession(t *testing.T) {
+	blsKey, _ := bls.GenerateKey()
+	otherKey, _ := bls.GenerateKey()
+
+	reg := &ConsensusKeyRegistration{BLSPubKey: blsKey.PublicKey().Bytes(), BLSProof: blsKey.ProvePossession().Bytes()}
+	if err := verifyBLSPossession(reg); err != nil {
+		t.Fatalf("BLS公钥证明验证失败 %v", err)
+	}
+	// 其他密钥的证明不能用于该公钥
+	reg.BLSProof = otherKey.ProvePossession().Bytes()
+	if err := verifyBLSPossession(reg); err != ErrConsensusKeyBLSProof {
+		t.Fatalf("错误不匹配 have %v, want %v", err, ErrConsensusKeyBLSProof)
+	}
+	reg.BLSPubKey = []byte{1, 2, 3}
+	if err := verifyBLSPossession(reg); err != ErrConsensusKeyBLS {
+		t.Fatalf("错误不匹配 have %v, want %v", err, ErrConsensusKeyBLS)
+	}
+	// 未注册BLS公钥时不需要证明
+	if err := verifyBLSPossession(&ConsensusKeyRegistration{}); err != nil {
+		t.Fatalf("未注册BLS公钥验证失败 %v", err)
+	}
+}
+
+func Test_consensusKeyFork(t *testing.T) {
+	st := newForkTestState(mc.ForkActivation{Name: mc.ForkConsensusKey, ActivateNumber: 100})
+
+	blsKey, _ := bls.GenerateKey()
+	reg := &ConsensusKeyRegistration{
+		Signer:    common.HexToAddress("0x02"),
+		BLSPubKey: blsKey.PublicKey().Bytes(),
+		BLSProof:  blsKey.ProvePossession().Bytes(),
+	}
+	if err := applyConsensusKeyRegistration(st, common.HexToAddress("0x01"), reg, 99); err != ErrConsensusKeyDisabled {
+		t.Fatalf("硬分叉前注册共识密钥应返回 %v, 实际 %v", ErrConsensusKeyDisabled, err)
+	}
+	// 硬分叉后非抵押账户注册被拒绝
+	if err := applyConsensusKeyRegistration(st, common.HexToAddress("0x01"), reg, 100); err != ErrConsensusKeyDeposit {
+		t.Fatalf("硬分叉后非抵押账户注册应返回 %v, 实际 %v", ErrConsensusKeyDeposit, err)
+	}
+}
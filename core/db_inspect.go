@@ -0,0 +1,117 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// ErrInspectInterrupted 数据库统计被中断
+var ErrInspectInterrupted = errors.New("database inspection interrupted")
+
+// KeyCategory 数据库键的一个分类, Match根据键和值判断是否属于该分类
+type KeyCategory struct {
+	Name  string
+	Match func(key, value []byte) bool
+}
+
+// DatabaseStat 一个键分类的统计
+type DatabaseStat struct {
+	Category string
+	Count    uint64
+	Size     common.StorageSize // 键和值的总字节数
+}
+
+// matrixDataPrefix matrixstate数据在状态树叶子节点中的值前缀
+var matrixDataPrefix = []byte("MAN-")
+
+// chainMetadataKeys 链数据库中的单个元数据键
+var chainMetadataKeys = [][]byte{
+	[]byte("DatabaseVersion"),
+	[]byte("LastHeader"),
+	[]byte("LastBlock"),
+	[]byte("LastFast"),
+	[]byte("TrieSync"),
+	lastPrunedStateKey,
+	schemaVersionKey,
+	schemaMigrationProgressKey,
+	intervalCalendarKey,
+}
+
+// ChainKeyCategories 链数据库(chaindata)的键分类, 按rawdb的键布局匹配, 依次匹配第一个符合的分类
+var ChainKeyCategories = []KeyCategory{
+	{Name: "Headers", Match: func(key, value []byte) bool { return len(key) == 41 && key[0] == 'h' }},
+	{Name: "Total difficulties", Match: func(key, value []byte) bool { return len(key) == 42 && key[0] == 'h' && key[41] == 't' }},
+	{Name: "Canonical hashes", Match: func(key, value []byte) bool { return len(key) == 10 && key[0] == 'h' && key[9] == 'n' }},
+	{Name: "Header numbers", Match: func(key, value []byte) bool { return len(key) == 33 && bytes.HasPrefix(key, headerNumberPrefix) }},
+	{Name: "Bodies", Match: func(key, value []byte) bool { return len(key) == 41 && key[0] == 'b' }},
+	{Name: "Receipts", Match: func(key, value []byte) bool { return len(key) == 41 && key[0] == 'r' }},
+	{Name: "Tx lookups", Match: func(key, value []byte) bool { return len(key) == 33 && key[0] == 'l' }},
+	{Name: "Bloom bits", Match: func(key, value []byte) bool { return len(key) == 43 && key[0] == 'B' }},
+	{Name: "Preimages", Match: func(key, value []byte) bool { return bytes.HasPrefix(key, []byte("secure-key-")) }},
+	{Name: "System logs", Match: func(key, value []byte) bool { return bytes.HasPrefix(key, systemLogsPrefix) }},
+	{Name: "Matrix state leaves", Match: func(key, value []byte) bool { return len(key) == common.HashLength && isMatrixDataNode(value) }},
+	{Name: "State trie nodes and code", Match: func(key, value []byte) bool { return len(key) == common.HashLength }},
+	{Name: "Chain metadata", Match: func(key, value []byte) bool {
+		for _, meta := range chainMetadataKeys {
+			if bytes.Equal(key, meta) {
+				return true
+			}
+		}
+		return false
+	}},
+}
+
+// isMatrixDataNode 检查状态树节点是否为保存matrixstate数据的叶子节点
+func isMatrixDataNode(node []byte) bool {
+	elems, _, err := rlp.SplitList(node)
+	if err != nil {
+		return false
+	}
+	if count, err := rlp.CountValues(elems); err != nil || count != 2 {
+		return false
+	}
+	_, rest, err := rlp.SplitString(elems)
+	if err != nil {
+		return false
+	}
+	value, _, err := rlp.SplitString(rest)
+	return err == nil && bytes.HasPrefix(value, matrixDataPrefix)
+}
+
+// InspectDatabase 遍历数据库所有的键, 按分类统计数量和大小, 未匹配任何分类的键统计在最后的"Unaccounted"中.
+// interrupt返回true时停止遍历
+func InspectDatabase(db mandb.Iteratee, categories []KeyCategory, interrupt func() bool) ([]DatabaseStat, error) {
+	stats := make([]DatabaseStat, len(categories)+1)
+	for i, category := range categories {
+		stats[i].Category = category.Name
+	}
+	stats[len(categories)].Category = "Unaccounted"
+
+	it := db.NewIteratorWithPrefix(nil)
+	defer it.Release()
+
+	for count := 0; it.Next(); count++ {
+		if count%100000 == 0 && interrupt != nil && interrupt() {
+			return stats, ErrInspectInterrupted
+		}
+		key, value := it.Key(), it.Value()
+		index := len(categories)
+		for i, category := range categories {
+			if category.Match(key, value) {
+				index = i
+				break
+			}
+		}
+		stats[index].Count++
+		stats[index].Size += common.StorageSize(len(key) + len(value))
+	}
+	return stats, it.Error()
+}
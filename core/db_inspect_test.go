@@ -0,0 +1,64 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+func TestInspectDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inspect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := mandb.NewLDBDatabase(dir, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	hash := common.HexToHash("0x01")
+	matrixLeaf, _ := rlp.EncodeToBytes([][]byte{{0x20, 0x01}, append([]byte("MAN-"), 0x01)})
+	plainLeaf, _ := rlp.EncodeToBytes([][]byte{{0x20, 0x02}, {0x01}})
+	entries := map[string][]byte{
+		string(append(append([]byte("h"), make([]byte, 8)...), hash[:]...)): {0x01},
+		string(append(append([]byte("b"), make([]byte, 8)...), hash[:]...)): {0x02},
+		string(common.HexToHash("0x02").Bytes()):                            matrixLeaf,
+		string(common.HexToHash("0x03").Bytes()):                            plainLeaf,
+		string(schemaVersionKey):                                            {0x01},
+		"unknown":                                                           {0x01},
+	}
+	for key, value := range entries {
+		db.Put([]byte(key), value)
+	}
+	stats, err := InspectDatabase(db, ChainKeyCategories, nil)
+	if err != nil {
+		t.Fatalf("inspection failed: %v", err)
+	}
+	want := map[string]uint64{
+		"Headers":                   1,
+		"Bodies":                    1,
+		"Matrix state leaves":       1,
+		"State trie nodes and code": 1,
+		"Chain metadata":            1,
+		"Unaccounted":               1,
+	}
+	for _, stat := range stats {
+		if stat.Count != want[stat.Category] {
+			t.Errorf("%s: count mismatch: have %d, want %d", stat.Category, stat.Count, want[stat.Category])
+		}
+	}
+	if last := stats[len(stats)-1].Category; last != "Unaccounted" {
+		t.Errorf("last category mismatch: have %s", last)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+var (
+	ErrElectedOutOfRange = errors.New("height is out of the current and the next election period")
+	ErrElectedNotReady   = errors.New("elected set of the next election period is not generated yet")
+)
+
+// GetElected 返回number高度的状态中查询到的height高度role角色的当选账户.
+// 状态中只保存当前选举周期的当选列表及下一选举周期已生成的当选列表, 因此只能查询这两个选举周期内的高度
+func GetElected(st vm.StateDBManager, number uint64, height uint64, role common.RoleType) ([]common.Address, error) {
+	bcInterval, err := matrixstate.GetBroadcastInterval(st)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := matrixstate.GetElectGraph(st)
+	if err != nil {
+		return nil, err
+	}
+
+	current := bcInterval.GetNextReElectionNumber(number)
+	var list []mc.ElectNodeInfo
+	switch bcInterval.GetNextReElectionNumber(height) {
+	case current:
+		list = graph.ElectList
+	case current + bcInterval.GetReElectionInterval():
+		switch role {
+		case common.RoleValidator, common.RoleBackupValidator:
+			list = graph.NextValidatorElect
+		case common.RoleMiner, common.RoleBackupMiner:
+			list = graph.NextMinerElect
+		}
+		if len(list) == 0 {
+			return nil, ErrElectedNotReady
+		}
+	default:
+		return nil, ErrElectedOutOfRange
+	}
+
+	accounts := make([]common.Address, 0, len(list))
+	for _, node := range list {
+		if node.Type == role {
+			accounts = append(accounts, node.Account)
+		}
+	}
+	return accounts, nil
+}
+
+// HasRole 检查账户在当前拓扑中是否为role角色, 广播节点按状态中的广播账户检查
+func HasRole(st vm.StateDBManager, account common.Address, role common.RoleType) (bool, error) {
+	if role == common.RoleBroadcast {
+		accounts, err := matrixstate.GetBroadcastAccounts(st)
+		if err != nil {
+			return false, err
+		}
+		for _, broadcast := range accounts {
+			if broadcast == account {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	topology, err := matrixstate.GetTopologyGraph(st)
+	if err != nil {
+		return false, err
+	}
+	for _, node := range topology.NodeList {
+		if node.Account == account && node.Type == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// BroadcastSchedule 返回状态中的广播周期及最后广播区块高度
+func BroadcastSchedule(st vm.StateDBManager) (uint64, uint64, error) {
+	bcInterval, err := matrixstate.GetBroadcastInterval(st)
+	if err != nil {
+		return 0, 0, err
+	}
+	return bcInterval.GetBroadcastInterval(), bcInterval.GetLastBroadcastNumber(), nil
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+)
+
+var ErrEntrustTxType = errors.New("gas entrust does not allow the transaction type")
+
+// entrustAllowsTxType 判断授权数据是否允许该交易类型, 未设置交易类型的授权不做限制
+func entrustAllowsTxType(txTypes []uint32, txType byte) bool {
+	if len(txTypes) == 0 {
+		return true
+	}
+	for _, typ := range txTypes {
+		if typ == uint32(txType) {
+			return true
+		}
+	}
+	return false
+}
+
+// EntrustTxTypeAllowed 判断授权人authFrom给被委托人entrustFrom的委托gas是否允许支付该类型的交易
+func EntrustTxTypeAllowed(st vm.StateDBManager, cointyp string, entrustFrom common.Address, authFrom common.Address, txType byte) bool {
+	if manversion.VersionCmp(matrixstate.GetVersionInfo(st), manversion.VersionAIMine) < 0 {
+		return true
+	}
+	data := st.GetAuthStateByteArray(cointyp, entrustFrom)
+	if len(data) == 0 {
+		return false
+	}
+	authDataList := make([]common.AuthType, 0)
+	if err := json.Unmarshal(data, &authDataList); err != nil {
+		return false
+	}
+	for _, authData := range authDataList {
+		if authData.AuthAddres == authFrom && authData.IsEntrustGas && entrustAllowsTxType(authData.EntrustTxTypes, txType) {
+			return true
+		}
+	}
+	return false
+}
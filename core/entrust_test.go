@@ -0,0 +1,39 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+func Test_entrustAllowsTxType(t *testing.T) {
+	if !entrustAllowsTxType(nil, common.ExtraNormalTxType) {
+		t.Errorf("未设置交易类型的委托应不限制交易类型")
+	}
+	txTypes := []uint32{uint32(common.ExtraNormalTxType), uint32(common.ExtraRevocable)}
+	if !entrustAllowsTxType(txTypes, common.ExtraRevocable) {
+		t.Errorf("委托的交易类型应被允许")
+	}
+	if entrustAllowsTxType(txTypes, common.ExtraAuthTx) {
+		t.Errorf("未委托的交易类型不应被允许")
+	}
+}
+
+func Test_entrustTxTypesEncoding(t *testing.T) {
+	// 未设置交易类型的委托数据编码需与升级前保持一致
+	data, err := json.Marshal(common.AuthType{IsEntrustGas: true})
+	if err != nil {
+		t.Fatalf("编码失败 %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("解码失败 %v", err)
+	}
+	if _, ok := fields["EntrustTxTypes"]; ok {
+		t.Errorf("未设置交易类型时不应编码EntrustTxTypes %s", data)
+	}
+}
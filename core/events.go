@@ -15,6 +15,26 @@ type NewTxsEvent struct {
 	poolType byte
 }
 
+// BroadcastTxEvent is posted when a special transaction is accepted by the
+// broadcast transaction pool.
+type BroadcastTxEvent struct {
+	Tx       types.SelfTransaction
+	From     common.Address
+	Type     string // broadcast type (heartbeat, publickey, privatekey, calltheroll)
+	Interval uint64 // broadcast interval the transaction targets
+}
+
+// TxReplacedEvent is posted when a pending transaction is replaced by another
+// one of the same sender and nonce paying a higher gas price.
+type TxReplacedEvent struct {
+	Old *types.Transaction
+	New *types.Transaction
+}
+
+// ReorgTxsEvent is posted when a chain reorg drops transactions which are not
+// included in the new canonical chain.
+type ReorgTxsEvent struct{ Txs types.SelfTransactions }
+
 //type NewSNEvent struct{ SN map[*big.Int]uint32 } //by
 
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
@@ -42,3 +62,13 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ChainReorgEvent is posted when the canonical chain switches to another branch.
+// Dropped blocks of the old branch are replaced by Added blocks of the new one.
+type ChainReorgEvent struct {
+	OldHead *types.Block
+	NewHead *types.Block
+	Common  *types.Block
+	Dropped int
+	Added   int
+}
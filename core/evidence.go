@@ -0,0 +1,342 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
+	"github.com/MatrixAINetwork/go-matrix/event"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+)
+
+var (
+	ErrEvidenceDisabled     = errors.New("double sign slash is disabled")
+	ErrEvidenceTooOld       = errors.New("evidence is too old")
+	ErrEvidenceFuture       = errors.New("evidence is not below the current block")
+	ErrEvidenceNotValidator = errors.New("evidence signer is not an elected validator")
+	ErrEvidenceDuplicate    = errors.New("evidence already punished")
+	ErrEvidenceTurnMismatch = errors.New("evidence votes are not in the same consensus turn")
+)
+
+// evidencePoolMaxAge is the number of blocks votes and evidence are kept in
+// the evidence pool.
+const evidencePoolMaxAge = 1000
+
+// evidenceVoteKey identifies the votes which must not conflict: a signer votes
+// at most for one block of a leader in a consensus turn at a height.
+type evidenceVoteKey struct {
+	number uint64
+	turn   uint32
+	leader common.Address
+	signer common.Address
+}
+
+type evidenceVote struct {
+	parent *types.Header
+	header *types.Header
+	sign   common.Signature
+}
+
+// NewEvidenceEvent is posted when a double sign evidence is found among the
+// local votes.
+type NewEvidenceEvent struct {
+	Evidence *types.DoubleSignEvidence
+}
+
+// EvidencePool collects the votes received from the peers and keeps the
+// double sign evidence found among them, until it gets included into a block
+// by an evidence transaction.
+type EvidencePool struct {
+	mu      sync.Mutex
+	votes   map[evidenceVoteKey]evidenceVote
+	pending map[common.Hash]*types.DoubleSignEvidence
+	feed    event.Feed
+}
+
+func NewEvidencePool() *EvidencePool {
+	return &EvidencePool{
+		votes:   make(map[evidenceVoteKey]evidenceVote),
+		pending: make(map[common.Hash]*types.DoubleSignEvidence),
+	}
+}
+
+// AddVote records a vote on a header received in the given consensus turn,
+// returning the double sign evidence if the signer already voted for another
+// block of the same leader in that turn. New evidence is posted to the
+// subscribers to be relayed to the other validators.
+func (pool *EvidencePool) AddVote(parent *types.Header, header *types.Header, sign common.Signature, turn uint32) *types.DoubleSignEvidence {
+	if parent == nil || header == nil || header.Number == nil {
+		return nil
+	}
+	signHash := header.HashNoSignsAndNonce()
+	signer, validate, err := crypto.VerifySignWithValidate(signHash.Bytes(), sign.Bytes())
+	if err != nil || !validate {
+		return nil
+	}
+
+	ev := pool.addVote(parent, header, sign, signer, turn)
+	if ev != nil {
+		pool.feed.Send(NewEvidenceEvent{Evidence: ev})
+	}
+	return ev
+}
+
+func (pool *EvidencePool) addVote(parent *types.Header, header *types.Header, sign common.Signature, signer common.Address, turn uint32) *types.DoubleSignEvidence {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	number := header.Number.Uint64()
+	pool.prune(number)
+
+	key := evidenceVoteKey{number: number, turn: turn, leader: header.Leader, signer: signer}
+	exist, ok := pool.votes[key]
+	if !ok {
+		pool.votes[key] = evidenceVote{parent: types.CopyHeader(parent), header: types.CopyHeader(header), sign: sign}
+		return nil
+	}
+	if exist.header.HashNoSignsAndNonce() == header.HashNoSignsAndNonce() || exist.header.ParentHash != header.ParentHash {
+		return nil
+	}
+
+	ev := &types.DoubleSignEvidence{Parent: exist.parent, HeaderA: exist.header, HeaderB: types.CopyHeader(header), SignA: exist.sign, SignB: sign}
+	hash := ev.Hash()
+	if _, ok := pool.pending[hash]; ok {
+		return nil
+	}
+	pool.pending[hash] = ev
+	log.Warn(ModuleName, "发现双签", signer.Hex(), "高度", number, "轮次", turn, "leader", header.Leader.Hex())
+	return ev
+}
+
+// AddEvidence adds a double sign evidence received from a peer. Evidence new
+// to the pool is posted to the subscribers to be relayed further.
+func (pool *EvidencePool) AddEvidence(ev *types.DoubleSignEvidence) error {
+	if ev == nil {
+		return types.ErrEvidenceHeaderNil
+	}
+	if _, err := ev.Verify(); err != nil {
+		return err
+	}
+
+	hash := ev.Hash()
+	pool.mu.Lock()
+	_, known := pool.pending[hash]
+	if !known {
+		pool.pending[hash] = ev
+	}
+	pool.mu.Unlock()
+
+	if !known {
+		pool.feed.Send(NewEvidenceEvent{Evidence: ev})
+	}
+	return nil
+}
+
+// SubscribeNewEvidence registers a subscription of NewEvidenceEvent.
+func (pool *EvidencePool) SubscribeNewEvidence(ch chan<- NewEvidenceEvent) event.Subscription {
+	return pool.feed.Subscribe(ch)
+}
+
+// Pending returns the evidence waiting to be included, ordered by height.
+func (pool *EvidencePool) Pending() []*types.DoubleSignEvidence {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	list := make([]*types.DoubleSignEvidence, 0, len(pool.pending))
+	for _, ev := range pool.pending {
+		list = append(list, ev)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Number() < list[j].Number()
+	})
+	return list
+}
+
+// Remove drops an evidence from the pool.
+func (pool *EvidencePool) Remove(hash common.Hash) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	delete(pool.pending, hash)
+}
+
+// prune drops the votes and the evidence which are too old to be punished.
+func (pool *EvidencePool) prune(number uint64) {
+	if number <= evidencePoolMaxAge {
+		return
+	}
+	limit := number - evidencePoolMaxAge
+	for key := range pool.votes {
+		if key.number < limit {
+			delete(pool.votes, key)
+		}
+	}
+	for hash, ev := range pool.pending {
+		if ev.Number() < limit {
+			delete(pool.pending, hash)
+		}
+	}
+}
+
+// checkDoubleSignEvidence verifies an evidence against the state of the block
+// including it and returns the deposit account of the offender.
+func checkDoubleSignEvidence(st vm.StateDBManager, ev *types.DoubleSignEvidence, number uint64, cfg *mc.DoubleSignSlashCfg) (common.Address, error) {
+	if !cfg.Switcher || !ForkActive(st, mc.ForkDoubleSign, number) {
+		return common.Address{}, ErrEvidenceDisabled
+	}
+	signer, err := ev.Verify()
+	if err != nil {
+		return common.Address{}, err
+	}
+	if err := checkEvidenceTurn(st, ev); err != nil {
+		return common.Address{}, err
+	}
+	evNumber := ev.Number()
+	if evNumber >= number {
+		return common.Address{}, ErrEvidenceFuture
+	}
+	if number-evNumber > cfg.EvidenceMaxAge {
+		return common.Address{}, ErrEvidenceTooOld
+	}
+
+	account := depoistInfo.GetDepositAccount(st, signer)
+	if (account == common.Address{}) {
+		return common.Address{}, ErrEvidenceNotValidator
+	}
+	if !isElectedValidator(st, account) {
+		return common.Address{}, ErrEvidenceNotValidator
+	}
+
+	records, err := matrixstate.GetDoubleSignRecords(st)
+	if err != nil {
+		return common.Address{}, err
+	}
+	for _, record := range records.Records {
+		if record.Account == account && record.Number == evNumber {
+			return common.Address{}, ErrEvidenceDuplicate
+		}
+	}
+	return account, nil
+}
+
+// checkEvidenceTurn 检查证据中的两个区块头时间落在同一个共识轮次内.
+// 验证者只对时间在当前轮次内的区块投票, 不同轮次对同一leader的区块分别投票不构成双签
+func checkEvidenceTurn(st vm.StateDBManager, ev *types.DoubleSignEvidence) error {
+	leaderCfg, err := matrixstate.GetLeaderConfig(st)
+	if err != nil {
+		return err
+	}
+	bcInterval, err := matrixstate.GetBroadcastInterval(st)
+	if err != nil {
+		return err
+	}
+	number := ev.Number()
+	parentMiningTime := int64(leaderCfg.ParentMiningTime)
+	if manversion.VersionCmp(string(ev.Parent.Version), manversion.VersionAIMine) >= 0 || number >= manversion.VersionNumAIMine {
+		if params.IsAIBlock(number, bcInterval.GetBroadcastInterval()) {
+			parentMiningTime = int64(leaderCfg.ParentMiningTime * params.AIMineLeaderAIBlockMiningTimeRatio)
+		}
+	}
+	begin := ev.Parent.Time.Int64()
+	turnA, okA := consensusTurnAt(ev.HeaderA.Time.Int64()-begin, parentMiningTime, int64(leaderCfg.PosOutTime))
+	turnB, okB := consensusTurnAt(ev.HeaderB.Time.Int64()-begin, parentMiningTime, int64(leaderCfg.PosOutTime))
+	if !okA || !okB || turnA != turnB {
+		return ErrEvidenceTurnMismatch
+	}
+	return nil
+}
+
+// consensusTurnAt 返回父区块之后elapsed秒所在的共识轮次. 首轮包含父区块挖矿时间,
+// 轮次起止时间都有效, 时间正好落在两轮边界上时无法确定轮次, 返回false
+func consensusTurnAt(elapsed int64, parentMiningTime int64, turnOutTime int64) (uint32, bool) {
+	if elapsed < 0 || turnOutTime <= 0 {
+		return 0, false
+	}
+	d := elapsed - parentMiningTime
+	if d < turnOutTime {
+		return 0, true
+	}
+	if d%turnOutTime == 0 {
+		return 0, false
+	}
+	return uint32(d / turnOutTime), true
+}
+
+// CheckDoubleSignEvidence verifies whether an evidence can still be included
+// into the block following the given state.
+func CheckDoubleSignEvidence(st vm.StateDBManager, ev *types.DoubleSignEvidence, number uint64) error {
+	cfg, err := matrixstate.GetDoubleSignSlashCfg(st)
+	if err != nil {
+		return err
+	}
+	_, err = checkDoubleSignEvidence(st, ev, number, cfg)
+	return err
+}
+
+// isElectedValidator reports whether an account is a validator of the current
+// topology or of the last election.
+func isElectedValidator(st vm.StateDBManager, account common.Address) bool {
+	if topology, err := matrixstate.GetTopologyGraph(st); err == nil && topology != nil {
+		for _, node := range topology.NodeList {
+			if node.Account == account && (node.Type == common.RoleValidator || node.Type == common.RoleBackupValidator) {
+				return true
+			}
+		}
+	}
+	if elect, err := matrixstate.GetElectGraph(st); err == nil && elect != nil {
+		for _, node := range elect.ElectList {
+			if node.Account == account && (node.Type == common.RoleValidator || node.Type == common.RoleBackupValidator) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyDoubleSignEvidence punishes the offender of an evidence included in the
+// block at number, returning its deposit account and the penalty.
+func applyDoubleSignEvidence(st vm.StateDBManager, ev *types.DoubleSignEvidence, number uint64, v1Deposit func(common.Address) *big.Int) (common.Address, *big.Int, error) {
+	cfg, err := matrixstate.GetDoubleSignSlashCfg(st)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	account, err := checkDoubleSignEvidence(st, ev, number, cfg)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+
+	penalty := slashDepositByRate(st, account, cfg.SlashRate, func() *big.Int {
+		return v1Deposit(account)
+	})
+
+	records, err := matrixstate.GetDoubleSignRecords(st)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	kept := make([]mc.DoubleSignRecord, 0, len(records.Records)+1)
+	for _, record := range records.Records {
+		// 超过有效期的证据不会再被接受, 其记录无需保留
+		if number-record.Number <= cfg.EvidenceMaxAge {
+			kept = append(kept, record)
+		}
+	}
+	kept = append(kept, mc.DoubleSignRecord{Account: account, Number: ev.Number()})
+	if err := matrixstate.SetDoubleSignRecords(st, &mc.DoubleSignRecords{Records: kept}); err != nil {
+		return common.Address{}, nil, err
+	}
+	log.Info(ModuleName, "双签惩罚账户", account.Hex(), "惩罚金额", penalty, "双签高度", ev.Number(), "高度", number)
+	return account, penalty, nil
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import "testing"
+
+func TestConsensusTurnAt(t *testing.T) {
+	//父区块挖矿时间20秒, 每轮超时40秒
+	tests := []struct {
+		elapsed int64
+		turn    uint32
+		ok      bool
+	}{
+		{-1, 0, false},
+		{0, 0, true},
+		{59, 0, true},
+		{60, 0, false}, // 第0轮与第1轮的边界
+		{61, 1, true},
+		{99, 1, true},
+		{100, 0, false},
+		{130, 2, true},
+	}
+	for _, test := range tests {
+		turn, ok := consensusTurnAt(test.elapsed, 20, 40)
+		if ok != test.ok || (ok && turn != test.turn) {
+			t.Errorf("elapsed %d: 轮次 %d,%v, 期望 %d,%v", test.elapsed, turn, ok, test.turn, test.ok)
+		}
+	}
+}
@@ -56,16 +56,23 @@ func NewEVMContext(sender common.Address, gasprice *big.Int, header *types.Heade
 		beneficiary = *author
 	}
 	return vm.Context{
-		CanTransfer: CanTransfer,
-		Transfer:    Transfer,
-		GetHash:     GetHashFn(header, chain),
-		Origin:      sender,
-		Coinbase:    beneficiary,
-		BlockNumber: new(big.Int).Set(header.Number),
-		Time:        new(big.Int).Set(header.Time),
-		Difficulty:  new(big.Int).Set(header.Difficulty),
-		GasLimit:    header.GasLimit,
-		GasPrice:    new(big.Int).Set(gasprice),
+		CanTransfer:       CanTransfer,
+		Transfer:          Transfer,
+		GetHash:           GetHashFn(header, chain),
+		AddUnbonding:      AddUnbonding,
+		CheckUnbonding:    CheckUnbonding,
+		GetElected:        GetElected,
+		BroadcastSchedule: BroadcastSchedule,
+		HasRole:           HasRole,
+		WasmEnabled:       WasmEnabled,
+		PrecompileEnabled: PrecompileEnabled,
+		Origin:            sender,
+		Coinbase:          beneficiary,
+		BlockNumber:       new(big.Int).Set(header.Number),
+		Time:              new(big.Int).Set(header.Time),
+		Difficulty:        new(big.Int).Set(header.Difficulty),
+		GasLimit:          header.GasLimit,
+		GasPrice:          new(big.Int).Set(gasprice),
 	}
 }
 
@@ -0,0 +1,411 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/crypto/bls"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+)
+
+// 检查点终局: 当选验证者对每Interval个区块的检查点区块进行BLS投票, 超过2/3的投票由后续区块的领导者
+// 聚合到区块头的聚合投票中. 区块头的检查点投票验证通过后检查点成为终局检查点, 主链不再回滚到终局检查点之前
+
+var (
+	ErrCheckpointVersion       = errors.New("checkpoint is not enabled in this version")
+	ErrCheckpointDisabled      = errors.New("checkpoint vote is disabled")
+	ErrCheckpointNumber        = errors.New("block is not a checkpoint")
+	ErrCheckpointStale         = errors.New("checkpoint is not newer than the finalized checkpoint")
+	ErrCheckpointTooOld        = errors.New("checkpoint is too old")
+	ErrCheckpointFuture        = errors.New("checkpoint is beyond the current block")
+	ErrCheckpointNotValidator  = errors.New("checkpoint voter is not a current validator")
+	ErrCheckpointVoted         = errors.New("checkpoint voter already voted")
+	ErrCheckpointVoteCount     = errors.New("header carries more than one checkpoint vote")
+	ErrCheckpointHash          = errors.New("checkpoint hash mismatch the canonical chain")
+	ErrCheckpointBitmap        = errors.New("checkpoint vote bitmap mismatch validators")
+	ErrCheckpointKey           = errors.New("checkpoint voter's bls public key is invalid")
+	ErrCheckpointSign          = errors.New("checkpoint aggregate sign verify failed")
+	ErrCheckpointSupermajority = errors.New("checkpoint lacks a validator supermajority")
+	ErrReorgBehindFinalized    = errors.New("reorg behind the finalized checkpoint")
+)
+
+// checkpointMaxAge 检查点投票须在检查点之后的checkpointMaxAge个区块内上链, 以便按区块哈希校验检查点
+const checkpointMaxAge = 256
+
+// CheckpointSignHash 验证者对检查点投票签名的哈希
+func CheckpointSignHash(number uint64, hash common.Hash) common.Hash {
+	return types.RlpHash([]interface{}{"checkpoint", number, hash})
+}
+
+// checkpointValidators 按拓扑图顺序返回验证者账户, 与mtxdpos.ValidatorAccounts的位图顺序一致
+func checkpointValidators(st vm.StateDBManager) ([]common.Address, error) {
+	topology, err := matrixstate.GetTopologyGraph(st)
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]common.Address, 0)
+	exist := make(map[common.Address]bool)
+	for _, node := range topology.NodeList {
+		if node.Type != common.RoleValidator || exist[node.Account] {
+			continue
+		}
+		exist[node.Account] = true
+		accounts = append(accounts, node.Account)
+	}
+	return accounts, nil
+}
+
+type checkpointKey struct {
+	number uint64
+	hash   common.Hash
+}
+
+// CheckpointPool 收集验证者对检查点的BLS投票, 直到检查点投票上链
+type CheckpointPool struct {
+	mu    sync.Mutex
+	votes map[checkpointKey]map[common.Address][]byte
+}
+
+func NewCheckpointPool() *CheckpointPool {
+	return &CheckpointPool{
+		votes: make(map[checkpointKey]map[common.Address][]byte),
+	}
+}
+
+// AddVote 记录验证者对检查点的BLS签名. st为head高度的状态, 只接受当前验证者对不晚于head且仍可上链的
+// 检查点的有效签名, 每个验证者对每个检查点高度只能投一票, 因此投票数受验证者数及检查点窗口限制
+func (pool *CheckpointPool) AddVote(st vm.StateDBManager, head uint64, number uint64, hash common.Hash, account common.Address, sign []byte) error {
+	if number > head {
+		return ErrCheckpointFuture
+	}
+	if number+checkpointMaxAge < head {
+		return ErrCheckpointTooOld
+	}
+	cfg, err := matrixstate.GetFinalityCfg(st)
+	if err != nil {
+		return err
+	}
+	if !cfg.IsCheckpoint(number) {
+		return ErrCheckpointNumber
+	}
+	validators, err := checkpointValidators(st)
+	if err != nil {
+		return err
+	}
+	isValidator := false
+	for _, validator := range validators {
+		if validator == account {
+			isValidator = true
+			break
+		}
+	}
+	if !isValidator {
+		return ErrCheckpointNotValidator
+	}
+	pubKey, err := bls.PublicKeyFromBytes(depoistInfo.GetConsensusBLSPubKey(st, account, head))
+	if err != nil {
+		return ErrCheckpointKey
+	}
+	blsSign, err := bls.SignatureFromBytes(sign)
+	if err != nil || !blsSign.Verify(pubKey, CheckpointSignHash(number, hash).Bytes()) {
+		return ErrCheckpointSign
+	}
+	return pool.add(number, hash, account, sign)
+}
+
+// add 记录已验证的检查点投票
+func (pool *CheckpointPool) add(number uint64, hash common.Hash, account common.Address, sign []byte) error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for key, votes := range pool.votes {
+		if _, exist := votes[account]; exist && key.number == number {
+			return ErrCheckpointVoted
+		}
+	}
+	key := checkpointKey{number: number, hash: hash}
+	votes, ok := pool.votes[key]
+	if !ok {
+		votes = make(map[common.Address][]byte)
+		pool.votes[key] = votes
+	}
+	votes[account] = common.CopyBytes(sign)
+	return nil
+}
+
+// Votes 检查点的投票
+func (pool *CheckpointPool) Votes(number uint64, hash common.Hash) map[common.Address][]byte {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	votes := make(map[common.Address][]byte)
+	for account, sign := range pool.votes[checkpointKey{number: number, hash: hash}] {
+		votes[account] = sign
+	}
+	return votes
+}
+
+// Pending 返回高于finalized的检查点及其投票数, 按高度从高到低排列
+func (pool *CheckpointPool) Pending(finalized uint64) ([]uint64, []common.Hash, []int) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	keys := make([]checkpointKey, 0, len(pool.votes))
+	for key := range pool.votes {
+		if key.number <= finalized {
+			delete(pool.votes, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].number > keys[j].number })
+
+	numbers := make([]uint64, 0, len(keys))
+	hashes := make([]common.Hash, 0, len(keys))
+	counts := make([]int, 0, len(keys))
+	for _, key := range keys {
+		numbers = append(numbers, key.number)
+		hashes = append(hashes, key.hash)
+		counts = append(counts, len(pool.votes[key]))
+	}
+	return numbers, hashes, counts
+}
+
+// Prune 删除number高度已无法上链的检查点投票
+func (pool *CheckpointPool) Prune(number uint64) {
+	if number <= checkpointMaxAge {
+		return
+	}
+	limit := number - checkpointMaxAge
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for key := range pool.votes {
+		if key.number < limit {
+			delete(pool.votes, key)
+		}
+	}
+}
+
+// BuildCheckpointVote 按number高度的验证者及BLS公钥聚合检查点投票, 验证失败的投票被忽略
+func BuildCheckpointVote(st vm.StateDBManager, checkpoint uint64, hash common.Hash, votes map[common.Address][]byte, number uint64) (*types.AggregateVote, error) {
+	validators, err := checkpointValidators(st)
+	if err != nil {
+		return nil, err
+	}
+	signHash := CheckpointSignHash(checkpoint, hash)
+	bitmap := types.NewVoteBitmap(len(validators))
+	signs := make([]*bls.Signature, 0)
+	for i, account := range validators {
+		vote, ok := votes[account]
+		if !ok {
+			continue
+		}
+		pubKey, err := bls.PublicKeyFromBytes(depoistInfo.GetConsensusBLSPubKey(st, account, number))
+		if err != nil {
+			continue
+		}
+		sign, err := bls.SignatureFromBytes(vote)
+		if err != nil || !sign.Verify(pubKey, signHash.Bytes()) {
+			log.Warn(ModuleName, "检查点投票 BLS签名验证失败 node", account.Hex(), "检查点", checkpoint)
+			continue
+		}
+		types.SetVoter(bitmap, i)
+		signs = append(signs, sign)
+	}
+	if len(validators) == 0 || len(signs)*3 <= len(validators)*2 {
+		return nil, ErrCheckpointSupermajority
+	}
+	aggregate, err := bls.AggregateSignatures(signs)
+	if err != nil {
+		return nil, err
+	}
+	return &types.AggregateVote{Bitmap: bitmap, Sign: aggregate.Bytes(), Number: hexutil.Uint64(checkpoint)}, nil
+}
+
+// CheckCheckpointVote 验证number高度区块头携带的检查点投票, getHash返回区块所在分支的区块哈希
+func CheckCheckpointVote(st vm.StateDBManager, getHash func(uint64) common.Hash, vote *types.AggregateVote, number uint64) error {
+	if manversion.VersionCmp(matrixstate.GetVersionInfo(st), manversion.VersionAIMine) < 0 {
+		return ErrCheckpointVersion
+	}
+	cfg, err := matrixstate.GetFinalityCfg(st)
+	if err != nil {
+		return err
+	}
+	if !cfg.Active(number) {
+		return ErrCheckpointDisabled
+	}
+	checkpoint := uint64(vote.Number)
+	if !cfg.IsCheckpoint(checkpoint) {
+		return ErrCheckpointNumber
+	}
+	finalized, err := matrixstate.GetFinalizedCheckpoint(st)
+	if err != nil {
+		return err
+	}
+	if checkpoint <= finalized.Number {
+		return ErrCheckpointStale
+	}
+	if checkpoint >= number || checkpoint+checkpointMaxAge < number {
+		return ErrCheckpointTooOld
+	}
+	hash := getHash(checkpoint)
+	if hash == (common.Hash{}) {
+		return ErrCheckpointHash
+	}
+
+	validators, err := checkpointValidators(st)
+	if err != nil {
+		return err
+	}
+	if len(vote.Bitmap) != len(types.NewVoteBitmap(len(validators))) {
+		return ErrCheckpointBitmap
+	}
+	voters := vote.Voters()
+	// 需要超过2/3的当选验证者投票
+	if len(voters)*3 <= len(validators)*2 {
+		log.Warn(ModuleName, "检查点投票数不足", len(voters), "验证者总数", len(validators))
+		return ErrCheckpointSupermajority
+	}
+	pubKeys := make([]*bls.PublicKey, 0, len(voters))
+	for _, index := range voters {
+		if index >= len(validators) {
+			return ErrCheckpointBitmap
+		}
+		pubKey, err := bls.PublicKeyFromBytes(depoistInfo.GetConsensusBLSPubKey(st, validators[index], number))
+		if err != nil {
+			log.Error(ModuleName, "检查点投票 BLS公钥错误 node", validators[index].Hex(), "err", err)
+			return ErrCheckpointKey
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+	sign, err := bls.SignatureFromBytes(vote.Sign)
+	if err != nil || !sign.FastAggregateVerify(pubKeys, CheckpointSignHash(checkpoint, hash).Bytes()) {
+		return ErrCheckpointSign
+	}
+	return nil
+}
+
+// ProcessCheckpointVote 验证区块头携带的检查点投票并更新终局检查点
+func (bc *BlockChain) ProcessCheckpointVote(version string, state *state.StateDBManage, header *types.Header) error {
+	if nil == state {
+		return ErrStatePtrIsNil
+	}
+	if nil == header {
+		return ErrHeaderPtrIsNil
+	}
+	vote := header.CheckpointVote()
+	if vote == nil {
+		return nil
+	}
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		return ErrCheckpointVersion
+	}
+	count := 0
+	for _, v := range header.AggregateVotes {
+		if v.Number != 0 {
+			count++
+		}
+	}
+	if count != 1 {
+		return ErrCheckpointVoteCount
+	}
+	number := header.Number.Uint64()
+	getHash := GetHashFn(header, bc)
+	if err := CheckCheckpointVote(state, getHash, vote, number); err != nil {
+		log.Error(ModuleName, "检查点投票验证失败", err, "检查点", uint64(vote.Number), "高度", number)
+		return err
+	}
+	checkpoint := uint64(vote.Number)
+	hash := getHash(checkpoint)
+	log.Info(ModuleName, "检查点终局, 检查点高度", checkpoint, "hash", hash.TerminalString(), "高度", number)
+	return matrixstate.SetFinalizedCheckpoint(state, &mc.FinalizedCheckpoint{Number: checkpoint, Hash: hash})
+}
+
+// PendingCheckpointVote 返回领导者生成header时应携带的检查点投票: 在header所在分支上, 终局检查点之后
+// 收集到超过2/3投票的最新检查点. 没有可终局的检查点时返回nil
+func (bc *BlockChain) PendingCheckpointVote(header *types.Header) *types.AggregateVote {
+	if manversion.VersionCmp(string(header.Version), manversion.VersionAIMine) < 0 {
+		return nil
+	}
+	parent := bc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return nil
+	}
+	st, err := bc.StateAt(parent.Roots)
+	if err != nil {
+		return nil
+	}
+	number := header.Number.Uint64()
+	cfg, err := matrixstate.GetFinalityCfg(st)
+	if err != nil || !cfg.Active(number) {
+		return nil
+	}
+	finalized, err := matrixstate.GetFinalizedCheckpoint(st)
+	if err != nil {
+		return nil
+	}
+	getHash := GetHashFn(header, bc)
+	numbers, hashes, _ := bc.checkpointPool.Pending(finalized.Number)
+	for i, checkpoint := range numbers {
+		if checkpoint >= number || checkpoint+checkpointMaxAge < number || getHash(checkpoint) != hashes[i] {
+			continue
+		}
+		vote, err := BuildCheckpointVote(st, checkpoint, hashes[i], bc.checkpointPool.Votes(checkpoint, hashes[i]), number)
+		if err != nil {
+			continue
+		}
+		log.Debug(ModuleName, "区块头携带检查点投票, 检查点高度", checkpoint, "高度", number)
+		return vote
+	}
+	return nil
+}
+
+func (bc *BlockChain) CheckpointPool() *CheckpointPool {
+	return bc.checkpointPool
+}
+
+// GetFinalizedCheckpoint 当前主链的终局检查点, 尚无终局检查点时高度为0
+func (bc *BlockChain) GetFinalizedCheckpoint() (*mc.FinalizedCheckpoint, error) {
+	st, err := bc.State()
+	if err != nil {
+		return nil, err
+	}
+	finalized, err := matrixstate.GetFinalizedCheckpoint(st)
+	if err == matrixstate.ErrOptNotExist {
+		return &mc.FinalizedCheckpoint{}, nil
+	}
+	return finalized, err
+}
+
+// reorgBehindFinalized 切换到block所在分支是否会回滚终局检查点.
+// 超级区块可以覆盖终局检查点
+func (bc *BlockChain) reorgBehindFinalized(block *types.Block) bool {
+	finalized, err := bc.GetFinalizedCheckpoint()
+	if err != nil || finalized.Number == 0 {
+		return false
+	}
+	header := block.Header()
+	for header != nil && header.Number.Uint64() > finalized.Number {
+		if header.IsSuperHeader() {
+			return false
+		}
+		header = bc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	if header == nil || header.Number.Uint64() < finalized.Number {
+		return true
+	}
+	return header.Hash() != finalized.Hash
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+)
+
+func Test_checkpointSignHash(t *testing.T) {
+	hash := common.HexToHash("0x01")
+	if CheckpointSignHash(200, hash) == CheckpointSignHash(300, hash) {
+		t.Errorf("不同高度的检查点签名哈希相同")
+	}
+	if CheckpointSignHash(200, hash) == CheckpointSignHash(200, common.HexToHash("0x02")) {
+		t.Errorf("不同区块的检查点签名哈希相同")
+	}
+}
+
+func Test_finalityCfgIsCheckpoint(t *testing.T) {
+	cfg := &mc.FinalityCfg{Switcher: true, Interval: 100, ActivateNumber: 1000}
+	if cfg.IsCheckpoint(900) {
+		t.Errorf("生效前不应为检查点")
+	}
+	if !cfg.IsCheckpoint(1100) || cfg.IsCheckpoint(1150) {
+		t.Errorf("检查点判断错误")
+	}
+	cfg.Switcher = false
+	if cfg.IsCheckpoint(1100) {
+		t.Errorf("关闭后不应为检查点")
+	}
+}
+
+func Test_CheckpointPool(t *testing.T) {
+	pool := NewCheckpointPool()
+	hash := common.HexToHash("0x01")
+	accountA, accountB := common.HexToAddress("0x0a"), common.HexToAddress("0x0b")
+
+	if pool.add(100, hash, accountA, []byte{1}) != nil || pool.add(100, hash, accountB, []byte{2}) != nil {
+		t.Fatalf("添加投票失败")
+	}
+	// 每个验证者对每个检查点高度只能投一票
+	if err := pool.add(100, hash, accountA, []byte{3}); err != ErrCheckpointVoted {
+		t.Fatalf("重复投票不应添加 %v", err)
+	}
+	if err := pool.add(100, common.HexToHash("0x03"), accountA, []byte{3}); err != ErrCheckpointVoted {
+		t.Fatalf("对同一高度的其他区块投票不应添加 %v", err)
+	}
+	pool.add(200, common.HexToHash("0x02"), accountA, []byte{4})
+
+	// 未来及过期检查点的投票在验签前被拒绝
+	if err := pool.AddVote(nil, 150, 200, hash, accountB, []byte{5}); err != ErrCheckpointFuture {
+		t.Errorf("未来检查点的投票应返回 %v, 实际 %v", ErrCheckpointFuture, err)
+	}
+	if err := pool.AddVote(nil, 200+checkpointMaxAge+1, 200, hash, accountB, []byte{5}); err != ErrCheckpointTooOld {
+		t.Errorf("过期检查点的投票应返回 %v, 实际 %v", ErrCheckpointTooOld, err)
+	}
+
+	numbers, _, counts := pool.Pending(0)
+	if len(numbers) != 2 || numbers[0] != 200 || counts[1] != 2 {
+		t.Fatalf("待上链检查点错误 %v %v", numbers, counts)
+	}
+	if votes := pool.Votes(100, hash); len(votes) != 2 || votes[accountB][0] != 2 {
+		t.Fatalf("检查点投票错误 %v", votes)
+	}
+
+	// 低于终局检查点的投票被删除
+	if numbers, _, _ := pool.Pending(100); len(numbers) != 1 || numbers[0] != 200 {
+		t.Fatalf("终局检查点之前的投票未删除 %v", numbers)
+	}
+	pool.Prune(200 + checkpointMaxAge + 1)
+	if numbers, _, _ := pool.Pending(0); len(numbers) != 0 {
+		t.Fatalf("过期投票未删除 %v", numbers)
+	}
+}
+
+func Test_setFinalityCfg(t *testing.T) {
+	st := newForkTestState()
+	if err := (&GenesisMState{FinalityCfg: &mc.FinalityCfg{Switcher: true}}).setFinalityCfg(st, 100, manversion.VersionAIMine); err == nil {
+		t.Fatalf("检查点间隔为0应返回错误")
+	}
+	cfg := &mc.FinalityCfg{Switcher: true, Interval: 50, ActivateNumber: 200}
+	if err := (&GenesisMState{FinalityCfg: cfg}).setFinalityCfg(st, 100, manversion.VersionAIMine); err != nil {
+		t.Fatalf("设置检查点投票配置错误 %v", err)
+	}
+	if got, err := matrixstate.GetFinalityCfg(st); err != nil || !got.IsCheckpoint(200) {
+		t.Fatalf("检查点投票配置未生效 %v %v", got, err)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// ForkActive 检查状态中的硬分叉生效高度, 返回number高度是否启用名为name的硬分叉.
+// 硬分叉由创世文件或超级区块的ForkSchedule配置
+func ForkActive(st matrixstate.StateDB, name string, number uint64) bool {
+	schedule, err := matrixstate.GetForkSchedule(st)
+	if err != nil {
+		return false
+	}
+	return schedule.Active(name, number)
+}
+
+// precompiledForks 需硬分叉启用的预编译合约
+var precompiledForks = map[common.Address]string{
+	vm.ElectedSetAddress:     mc.ForkElectedSet,
+	vm.MatrixScheduleAddress: mc.ForkMatrixSchedule,
+}
+
+// PrecompileEnabled 返回number高度是否启用addr地址的预编译合约
+func PrecompileEnabled(st vm.StateDBManager, addr common.Address, number uint64) bool {
+	fork, ok := precompiledForks[addr]
+	if !ok {
+		return true
+	}
+	return ForkActive(st, fork, number)
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+)
+
+func TestSetForkSchedule(t *testing.T) {
+	//超级区块修改硬分叉生效高度测试
+
+	chaindb := mandb.NewMemDatabase()
+	roots := []common.CoinRoot{{Cointyp: params.MAN_COIN, Root: common.Hash{}}}
+	st, _ := state.NewStateDBManage(roots, chaindb, state.NewDatabase(chaindb))
+	matrixstate.SetVersionInfo(st, manversion.VersionAIMine)
+
+	schedule := func(forks ...mc.ForkActivation) *GenesisMState {
+		return &GenesisMState{ForkSchedule: &mc.ForkSchedule{Forks: forks}}
+	}
+	if err := schedule(mc.ForkActivation{Name: mc.ForkElectedSet, ActivateNumber: 100}).setForkSchedule(st, 50, manversion.VersionAIMine); err != nil {
+		t.Fatalf("设置硬分叉错误 %v", err)
+	}
+	if ForkActive(st, mc.ForkElectedSet, 99) || !ForkActive(st, mc.ForkElectedSet, 100) {
+		t.Fatalf("硬分叉生效高度错误")
+	}
+
+	// 未生效的硬分叉可以推迟
+	if err := schedule(mc.ForkActivation{Name: mc.ForkElectedSet, ActivateNumber: 300}).setForkSchedule(st, 80, manversion.VersionAIMine); err != nil {
+		t.Fatalf("推迟未生效的硬分叉错误 %v", err)
+	}
+	// 已生效的硬分叉不可修改或删除
+	if err := schedule(mc.ForkActivation{Name: mc.ForkElectedSet, ActivateNumber: 500}).setForkSchedule(st, 400, manversion.VersionAIMine); err == nil {
+		t.Errorf("修改已生效的硬分叉应返回错误")
+	}
+	if err := schedule().setForkSchedule(st, 400, manversion.VersionAIMine); err == nil {
+		t.Errorf("删除已生效的硬分叉应返回错误")
+	}
+	// 新的硬分叉不能在当前高度之前生效
+	if err := schedule(mc.ForkActivation{Name: mc.ForkElectedSet, ActivateNumber: 300}, mc.ForkActivation{Name: "test", ActivateNumber: 400}).setForkSchedule(st, 400, manversion.VersionAIMine); err == nil {
+		t.Errorf("新硬分叉生效高度过低应返回错误")
+	}
+	if !ForkActive(st, mc.ForkElectedSet, 300) {
+		t.Errorf("失败的设置不应修改状态")
+	}
+}
+
+// newForkTestState 创建启用forks硬分叉的AIMine版本状态
+func newForkTestState(forks ...mc.ForkActivation) *state.StateDBManage {
+	chaindb := mandb.NewMemDatabase()
+	roots := []common.CoinRoot{{Cointyp: params.MAN_COIN, Root: common.Hash{}}}
+	st, _ := state.NewStateDBManage(roots, chaindb, state.NewDatabase(chaindb))
+	matrixstate.SetVersionInfo(st, manversion.VersionAIMine)
+	matrixstate.SetForkSchedule(st, &mc.ForkSchedule{Forks: forks})
+	return st
+}
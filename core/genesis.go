@@ -202,6 +202,10 @@ func SetupGenesisBlock(db mandb.Database, genesis *Genesis) (*params.ChainConfig
 		if err != nil {
 			return nil, common.Hash{}, errGenGenesisBlockNoConfig
 		}
+		// 新数据库直接使用最新的键布局
+		if err := WriteSchemaVersion(db, LatestSchemaVersion()); err != nil {
+			return nil, common.Hash{}, err
+		}
 		return genesis.Config, block.Hash(), err
 	}
 
@@ -583,33 +587,102 @@ func DefaultRinkebyGenesisBlock() *Genesis {
 	}
 }
 
-// DeveloperGenesisBlock returns the 'gman --dev' genesis block. Note, this must
-// be seeded with the
-func DeveloperGenesisBlock(period uint64, faucet common.Address) *Genesis {
-	// Override the default period to the user requested one
-	config := *params.AllCliqueProtocolChanges
-	//config.Clique.Period = period
-	//todo 把clique设为空，默认启动ethash引擎挖矿
-	config.Clique = nil
+// developerBalance is the balance of the pre-funded accounts of the developer
+// chains, one billion MAN.
+var developerBalance = new(big.Int).Mul(big.NewInt(1e9), big.NewInt(1e18))
+
+// DeveloperGenesisBlock returns the 'gman --dev' genesis block. The developer
+// account holds all the roles of the single-node chain: it is the only elected
+// validator, backed by a deposit, as well as the broadcast node, the inner miner
+// and the super account. The broadcast interval is shortened to the given number
+// of blocks, and the developer and the given accounts are pre-funded. Note, the
+// version must still be signed by the developer.
+func DeveloperGenesisBlock(interval uint64, developer common.Address, prefund []common.Address) *Genesis {
+	genesis := new(Genesis)
+	if err := json.Unmarshal([]byte(AllGenesisJson), genesis); err != nil {
+		panic(err)
+	}
+	// A single validator is enough to reach the consensus
+	config := *genesis.Config
+	config.SimpleMode = true
+	genesis.Config = &config
+	genesis.VersionSignatures = nil
+
+	genesis.NetTopology = common.NetTopology{
+		Type:            common.NetTopoTypeAll,
+		NetTopologyData: []common.NetTopologyData{{Account: developer, Position: common.GeneratePosition(0, common.ElectRoleValidator)}},
+	}
+	ms, roles := genesis.MState, []GenesisAddress{GenesisAddress(developer)}
+	ms.CurElect = &[]GenesisElect{{Account: GenesisAddress(developer), Stock: 1, Type: common.ElectRoleValidator}}
+	ms.Broadcasts = &roles
+	ms.InnerMiners = &roles
+	ms.VersionSuperAccounts = &roles
+	ms.BlockSuperAccounts = &roles
+	ms.BCICfg = &mc.BCIntervalInfo{BCInterval: interval}
+
+	// Replace the deposits of the main net validators by the developer one
+	for address, account := range DepositAlloc([]GenesisDeposit{{Address: developer, SignAddress: developer, Amount: GenesisValidatorDeposit}}) {
+		genesis.Alloc[address] = account
+	}
+	genesis.Alloc[developer] = GenesisAccount{Balance: developerBalance}
+	for _, address := range prefund {
+		genesis.Alloc[address] = GenesisAccount{Balance: developerBalance}
+	}
+	return genesis
+}
 
-	// Assemble and return the genesis with the precompiles and faucet pre-funded
-	return &Genesis{
-		Config:     &config,
-		ExtraData:  append(append(make([]byte, 32), faucet[:]...), make([]byte, 65)...),
-		GasLimit:   6283185,
-		Difficulty: big.NewInt(1000000),
-		Alloc: map[common.Address]GenesisAccount{
-			common.BytesToAddress([]byte{1}): {Balance: big.NewInt(1)}, // ECRecover
-			common.BytesToAddress([]byte{2}): {Balance: big.NewInt(1)}, // SHA256
-			common.BytesToAddress([]byte{3}): {Balance: big.NewInt(1)}, // RIPEMD
-			common.BytesToAddress([]byte{4}): {Balance: big.NewInt(1)}, // Identity
-			common.BytesToAddress([]byte{5}): {Balance: big.NewInt(1)}, // ModExp
-			common.BytesToAddress([]byte{6}): {Balance: big.NewInt(1)}, // ECAdd
-			common.BytesToAddress([]byte{7}): {Balance: big.NewInt(1)}, // ECScalarMul
-			common.BytesToAddress([]byte{8}): {Balance: big.NewInt(1)}, // ECPairing
-			faucet:                           {Balance: new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(9))},
-		},
+// testnetBalance is the balance of the node accounts of the private test
+// networks, one million MAN each for the transaction fees.
+var testnetBalance = new(big.Int).Mul(big.NewInt(1e6), big.NewInt(1e18))
+
+// TestnetGenesisBlock returns the genesis block of a private test network. The
+// given validators and miners are elected from their genesis deposits and make
+// up the topology, the broadcast account runs the broadcast node and the super
+// account signs the version and the super blocks. All the node accounts are
+// pre-funded. Note, the version must still be signed by the super account.
+func TestnetGenesisBlock(interval uint64, validators, miners []common.Address, broadcast, super common.Address) *Genesis {
+	genesis := new(Genesis)
+	if err := json.Unmarshal([]byte(AllGenesisJson), genesis); err != nil {
+		panic(err)
 	}
+	genesis.VersionSignatures = nil
+
+	var (
+		ms       = genesis.MState
+		elect    []GenesisElect
+		topology []common.NetTopologyData
+		deposits []GenesisDeposit
+	)
+	for i, validator := range validators {
+		elect = append(elect, GenesisElect{Account: GenesisAddress(validator), Stock: 1, Type: common.ElectRoleValidator})
+		topology = append(topology, common.NetTopologyData{Account: validator, Position: common.GeneratePosition(uint16(i), common.ElectRoleValidator)})
+		deposits = append(deposits, GenesisDeposit{Address: validator, SignAddress: validator, Amount: GenesisValidatorDeposit})
+	}
+	for i, miner := range miners {
+		elect = append(elect, GenesisElect{Account: GenesisAddress(miner), Stock: 1, Type: common.ElectRoleMiner})
+		topology = append(topology, common.NetTopologyData{Account: miner, Position: common.GeneratePosition(uint16(i), common.ElectRoleMiner)})
+		deposits = append(deposits, GenesisDeposit{Address: miner, SignAddress: miner, Amount: GenesisMinerDeposit})
+	}
+	genesis.NetTopology = common.NetTopology{Type: common.NetTopoTypeAll, NetTopologyData: topology}
+	ms.CurElect = &elect
+
+	broadcasts, supers := []GenesisAddress{GenesisAddress(broadcast)}, []GenesisAddress{GenesisAddress(super)}
+	ms.Broadcasts = &broadcasts
+	ms.InnerMiners = &[]GenesisAddress{}
+	ms.VersionSuperAccounts = &supers
+	ms.BlockSuperAccounts = &supers
+	ms.MultiCoinSuperAccounts = &supers
+	ms.SubChainSuperAccounts = &supers
+	ms.BCICfg = &mc.BCIntervalInfo{BCInterval: interval}
+
+	// Replace the deposits of the main net nodes by the test network ones
+	for address, account := range DepositAlloc(deposits) {
+		genesis.Alloc[address] = account
+	}
+	for _, address := range append(append([]common.Address{broadcast, super}, validators...), miners...) {
+		genesis.Alloc[address] = GenesisAccount{Balance: testnetBalance}
+	}
+	return genesis
 }
 
 func decodePrealloc(data string) GenesisAlloc {
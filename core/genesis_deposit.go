@@ -0,0 +1,50 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+var (
+	// GenesisValidatorDeposit is the minimum deposit of the validators.
+	GenesisValidatorDeposit = new(big.Int).Mul(big.NewInt(100000), big.NewInt(1e18))
+	// GenesisMinerDeposit is the minimum deposit of the miners.
+	GenesisMinerDeposit = new(big.Int).Mul(big.NewInt(10000), big.NewInt(1e18))
+
+	depositContractAddress = common.BytesToAddress([]byte{10})
+)
+
+// GenesisDeposit is a deposit made at the genesis, making an account electable
+// from the first election.
+type GenesisDeposit struct {
+	Address     common.Address // Account holding the deposit
+	SignAddress common.Address // Account signing on behalf of the deposit account
+	Amount      *big.Int
+}
+
+// DepositAlloc returns the genesis allocation of the deposit contract holding
+// the deposits, laid out in the storage as the deposit contract keeps them.
+func DepositAlloc(deposits []GenesisDeposit) GenesisAlloc {
+	var (
+		storage = make(map[common.Hash]common.Hash)
+		balance = new(big.Int)
+	)
+	contract := depositContractAddress
+	storage[common.BytesToHash(append(contract[:], 'D', 'N', 'U', 'M'))] = common.BigToHash(big.NewInt(int64(len(deposits))))
+	for i, deposit := range deposits {
+		index := make([]byte, 8)
+		binary.BigEndian.PutUint64(index, uint64(i))
+		storage[common.BytesToHash(append(append(contract[:], 'D', 'I'), index...))] = common.BytesToHash(deposit.Address[:])
+
+		address := deposit.Address
+		storage[common.BytesToHash(append(address[:], 'D'))] = common.BigToHash(deposit.Amount)
+		storage[common.BytesToHash(append(address[:], 'N', 'X'))] = common.BytesToHash(deposit.SignAddress[:])
+		balance.Add(balance, deposit.Amount)
+	}
+	return GenesisAlloc{contract: {Storage: storage, Balance: balance}}
+}
@@ -68,6 +68,17 @@ type GenesisMState struct {
 	MinDifficulty                *big.Int                         `json:"MinDifficulty,omitempty" gencodec:"required"`
 	MaxDifficulty                *big.Int                         `json:"MaxDifficulty,omitempty" gencodec:"required"`
 	ReelectionDifficulty         *big.Int                         `json:"ReelectionDifficulty,omitempty" gencodec:"required"`
+	ForkSchedule                 *mc.ForkSchedule                 `json:"ForkSchedule,omitempty"`
+	FinalityCfg                  *mc.FinalityCfg                  `json:"FinalityCfg,omitempty"`
+	LeaderSelectionCfg           *mc.LeaderSelectionCfg           `json:"LeaderSelectionCfg,omitempty"`
+	ElectorForks                 []mc.ElectorFork                 `json:"ElectorForks,omitempty"`
+	HeartbeatSlashCfg            *mc.HeartbeatSlashCfg            `json:"HeartbeatSlashCfg,omitempty"`
+	DoubleSignSlashCfg           *mc.DoubleSignSlashCfg           `json:"DoubleSignSlashCfg,omitempty"`
+	UnbondingCfg                 *mc.UnbondingCfg                 `json:"UnbondingCfg,omitempty"`
+	BLSVoteCfg                   *mc.BLSVoteCfg                   `json:"BLSVoteCfg,omitempty"`
+	WasmCfg                      *mc.WasmCfg                      `json:"WasmCfg,omitempty"`
+	SeedCommitRevealCfg          *mc.SeedCommitRevealCfg          `json:"SeedCommitRevealCfg,omitempty"`
+	ReceiptMetaCfg               *mc.ReceiptMetaCfg               `json:"ReceiptMetaCfg,omitempty"`
 }
 
 func (ms *GenesisMState) setMatrixState(state *state.StateDBManage, netTopology common.NetTopology, nextElect []common.Elect, newVersion string, oldVersion string, num uint64) error {
@@ -206,6 +217,39 @@ func (ms *GenesisMState) setMatrixState(state *state.StateDBManage, netTopology
 	if err := ms.setReelectionDifficulty(state, num, newVersion); err != nil {
 		return err
 	}
+	if err := ms.setForkSchedule(state, num, newVersion); err != nil {
+		return err
+	}
+	if err := ms.setFinalityCfg(state, num, newVersion); err != nil {
+		return err
+	}
+	if err := ms.setLeaderSelectionCfg(state, num, newVersion); err != nil {
+		return err
+	}
+	if err := ms.setElectorForks(state, num); err != nil {
+		return err
+	}
+	if err := ms.setHeartbeatSlashCfg(state, num, newVersion); err != nil {
+		return err
+	}
+	if err := ms.setDoubleSignSlashCfg(state, num, newVersion); err != nil {
+		return err
+	}
+	if err := ms.setUnbondingCfg(state, num, newVersion); err != nil {
+		return err
+	}
+	if err := ms.setBLSVoteCfg(state, num, newVersion); err != nil {
+		return err
+	}
+	if err := ms.setWasmCfg(state, num, newVersion); err != nil {
+		return err
+	}
+	if err := ms.setSeedCommitRevealCfg(state, num, newVersion); err != nil {
+		return err
+	}
+	if err := ms.setReceiptMetaCfg(state, num, newVersion); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -1107,3 +1151,266 @@ func (g *GenesisMState) setBasePowerSlashStatsStatus(state *state.StateDBManage,
 	}
 
 }
+
+func (g *GenesisMState) setForkSchedule(state *state.StateDBManage, num uint64, version string) error {
+	if g.ForkSchedule == nil {
+		return nil
+	}
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		log.Error("Geneis", "setForkSchedule", "链版本号过低", "version", version)
+		return errors.New("setForkSchedule: 链版本号过低")
+	}
+	if num != 0 {
+		// 超级区块不能修改已生效的硬分叉, 新的硬分叉只能在之后的高度生效
+		cur, err := matrixstate.GetForkSchedule(state)
+		if err != nil {
+			return err
+		}
+		for _, fork := range cur.Forks {
+			if fork.ActivateNumber > num {
+				continue
+			}
+			if activate, ok := g.ForkSchedule.Activation(fork.Name); !ok || activate != fork.ActivateNumber {
+				log.Error("Geneis", "setForkSchedule", "已生效的硬分叉不可修改", "fork", fork.Name)
+				return errors.New("setForkSchedule: 已生效的硬分叉不可修改")
+			}
+		}
+		for _, fork := range g.ForkSchedule.Forks {
+			if activate, ok := cur.Activation(fork.Name); fork.ActivateNumber <= num && (!ok || activate != fork.ActivateNumber) {
+				log.Error("Geneis", "setForkSchedule", "硬分叉生效高度过低", "fork", fork.Name, "number", fork.ActivateNumber)
+				return errors.New("setForkSchedule: 硬分叉生效高度过低")
+			}
+		}
+	}
+	log.Info("Geneis", "ForkSchedule", g.ForkSchedule)
+	return matrixstate.SetForkSchedule(state, g.ForkSchedule)
+}
+
+func (g *GenesisMState) setFinalityCfg(state *state.StateDBManage, num uint64, version string) error {
+	if g.FinalityCfg == nil {
+		return nil
+	}
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		log.Error("Geneis", "setFinalityCfg", "链版本号过低", "version", version)
+		return errors.New("setFinalityCfg: 链版本号过低")
+	}
+	if g.FinalityCfg.Switcher && g.FinalityCfg.Interval == 0 {
+		log.Error("Geneis", "setFinalityCfg", "检查点间隔为0")
+		return errors.New("setFinalityCfg: 检查点间隔为0")
+	}
+	log.Info("Geneis", "FinalityCfg", g.FinalityCfg)
+	return matrixstate.SetFinalityCfg(state, g.FinalityCfg)
+}
+
+func (g *GenesisMState) setLeaderSelectionCfg(state *state.StateDBManage, num uint64, version string) error {
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		if g.LeaderSelectionCfg == nil {
+			return nil
+		}
+		log.Error("Geneis", "setLeaderSelectionCfg", "链版本号过低", "version", version)
+		return errors.New("setLeaderSelectionCfg: 链版本号过低")
+	}
+	cfg := g.LeaderSelectionCfg
+	if cfg == nil {
+		if num != 0 {
+			log.Info("Geneis", "未修改leader选取方式", "")
+			return nil
+		}
+		// 创世区块默认按前一个leader顺序轮换, 之后可由超级区块切换为VRF方式
+		cfg = &mc.LeaderSelectionCfg{Mode: mc.LeaderSelectRotation}
+	}
+	if cfg.Mode > mc.LeaderSelectVRF {
+		log.Error("Geneis", "setLeaderSelectionCfg", "未知的leader选取方式", "mode", cfg.Mode)
+		return errors.New("setLeaderSelectionCfg: 未知的leader选取方式")
+	}
+	log.Info("Geneis", "LeaderSelectionCfg", cfg)
+	return matrixstate.SetLeaderSelection(state, cfg)
+}
+
+func (g *GenesisMState) setElectorForks(state *state.StateDBManage, num uint64) error {
+	if g.ElectorForks == nil {
+		log.Info("Geneis", "没有配置选举算法切换高度", "")
+		return nil
+	}
+	for i, fork := range g.ElectorForks {
+		if fork.Elector == "" {
+			return errors.New("setElectorForks: 选举算法名称为空")
+		}
+		if i > 0 && fork.Number <= g.ElectorForks[i-1].Number {
+			return errors.New("setElectorForks: 选举算法切换高度未递增")
+		}
+	}
+	// 超级区块只能追加之后高度的切换, 已生效的切换不能修改
+	if num != 0 {
+		current, err := matrixstate.GetElectorForks(state)
+		if err != nil {
+			return err
+		}
+		active := make(map[mc.ElectorFork]bool)
+		for _, fork := range current {
+			if fork.Number <= num {
+				active[fork] = true
+			}
+		}
+		for _, fork := range g.ElectorForks {
+			if fork.Number > num {
+				break
+			}
+			if !active[fork] {
+				log.Error("Geneis", "setElectorForks", "修改了已生效的选举算法切换", "number", fork.Number, "elector", fork.Elector)
+				return errors.New("setElectorForks: 不能修改已生效的选举算法切换")
+			}
+			delete(active, fork)
+		}
+		if len(active) != 0 {
+			return errors.New("setElectorForks: 不能删除已生效的选举算法切换")
+		}
+	}
+	log.Info("Geneis", "ElectorForks", g.ElectorForks)
+	return matrixstate.SetElectorForks(state, g.ElectorForks)
+}
+
+func (g *GenesisMState) setHeartbeatSlashCfg(state *state.StateDBManage, num uint64, version string) error {
+	if g.HeartbeatSlashCfg == nil {
+		return nil
+	}
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		log.Error("Geneis", "setHeartbeatSlashCfg", "链版本号过低", "version", version)
+		return errors.New("setHeartbeatSlashCfg: 链版本号过低")
+	}
+	if g.HeartbeatSlashCfg.SlashRate > slashRateBase {
+		log.Error("Geneis", "setHeartbeatSlashCfg", "惩罚比例超过100%", "rate", g.HeartbeatSlashCfg.SlashRate)
+		return errors.New("setHeartbeatSlashCfg: 惩罚比例超过100%")
+	}
+	log.Info("Geneis", "HeartbeatSlashCfg", g.HeartbeatSlashCfg)
+	return matrixstate.SetHeartbeatSlashCfg(state, g.HeartbeatSlashCfg)
+}
+
+func (g *GenesisMState) setDoubleSignSlashCfg(state *state.StateDBManage, num uint64, version string) error {
+	if g.DoubleSignSlashCfg == nil {
+		return nil
+	}
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		log.Error("Geneis", "setDoubleSignSlashCfg", "链版本号过低", "version", version)
+		return errors.New("setDoubleSignSlashCfg: 链版本号过低")
+	}
+	if g.DoubleSignSlashCfg.SlashRate > slashRateBase {
+		log.Error("Geneis", "setDoubleSignSlashCfg", "惩罚比例超过100%", "rate", g.DoubleSignSlashCfg.SlashRate)
+		return errors.New("setDoubleSignSlashCfg: 惩罚比例超过100%")
+	}
+	if g.DoubleSignSlashCfg.Switcher && g.DoubleSignSlashCfg.EvidenceMaxAge == 0 {
+		log.Error("Geneis", "setDoubleSignSlashCfg", "证据有效区块数为0")
+		return errors.New("setDoubleSignSlashCfg: 证据有效区块数为0")
+	}
+	log.Info("Geneis", "DoubleSignSlashCfg", g.DoubleSignSlashCfg)
+	return matrixstate.SetDoubleSignSlashCfg(state, g.DoubleSignSlashCfg)
+}
+
+func (g *GenesisMState) setUnbondingCfg(state *state.StateDBManage, num uint64, version string) error {
+	if g.UnbondingCfg == nil {
+		return nil
+	}
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		log.Error("Geneis", "setUnbondingCfg", "链版本号过低", "version", version)
+		return errors.New("setUnbondingCfg: 链版本号过低")
+	}
+	log.Info("Geneis", "UnbondingCfg", g.UnbondingCfg)
+	return matrixstate.SetUnbondingCfg(state, g.UnbondingCfg)
+}
+
+func (g *GenesisMState) setBLSVoteCfg(state *state.StateDBManage, num uint64, version string) error {
+	if g.BLSVoteCfg == nil {
+		return nil
+	}
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		log.Error("Geneis", "setBLSVoteCfg", "链版本号过低", "version", version)
+		return errors.New("setBLSVoteCfg: 链版本号过低")
+	}
+	// 超级区块不能修改已生效的配置, 新配置的生效高度必须在超级区块之后
+	if num != 0 {
+		current, err := matrixstate.GetBLSVoteCfg(state)
+		if err != nil {
+			return err
+		}
+		if current.Active(num) && *current != *g.BLSVoteCfg {
+			log.Error("Geneis", "setBLSVoteCfg", "修改了已生效的BLS聚合投票配置", "current", current)
+			return errors.New("setBLSVoteCfg: 不能修改已生效的配置")
+		}
+		if !current.Active(num) && g.BLSVoteCfg.Active(num) {
+			log.Error("Geneis", "setBLSVoteCfg", "生效高度不在超级区块之后", "activate", g.BLSVoteCfg.ActivateNumber)
+			return errors.New("setBLSVoteCfg: 生效高度不在超级区块之后")
+		}
+	}
+	log.Info("Geneis", "BLSVoteCfg", g.BLSVoteCfg)
+	return matrixstate.SetBLSVoteCfg(state, g.BLSVoteCfg)
+}
+
+func (g *GenesisMState) setWasmCfg(state *state.StateDBManage, num uint64, version string) error {
+	if g.WasmCfg == nil {
+		return nil
+	}
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		log.Error("Geneis", "setWasmCfg", "链版本号过低", "version", version)
+		return errors.New("setWasmCfg: 链版本号过低")
+	}
+	// 已部署的WASM合约依赖执行开关, 超级区块不能修改已生效的配置
+	if num != 0 {
+		current, err := matrixstate.GetWasmCfg(state)
+		if err != nil {
+			return err
+		}
+		if current.Active(num) && *current != *g.WasmCfg {
+			log.Error("Geneis", "setWasmCfg", "修改了已生效的WASM合约配置", "current", current)
+			return errors.New("setWasmCfg: 不能修改已生效的配置")
+		}
+		if !current.Active(num) && g.WasmCfg.Active(num) {
+			log.Error("Geneis", "setWasmCfg", "生效高度不在超级区块之后", "activate", g.WasmCfg.ActivateNumber)
+			return errors.New("setWasmCfg: 生效高度不在超级区块之后")
+		}
+	}
+	log.Info("Geneis", "WasmCfg", g.WasmCfg)
+	return matrixstate.SetWasmCfg(state, g.WasmCfg)
+}
+
+func (g *GenesisMState) setSeedCommitRevealCfg(state *state.StateDBManage, num uint64, version string) error {
+	if g.SeedCommitRevealCfg == nil {
+		return nil
+	}
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		log.Error("Geneis", "setSeedCommitRevealCfg", "链版本号过低", "version", version)
+		return errors.New("setSeedCommitRevealCfg: 链版本号过低")
+	}
+	if g.SeedCommitRevealCfg.SlashRate > slashRateBase {
+		log.Error("Geneis", "setSeedCommitRevealCfg", "惩罚比例超过100%", "rate", g.SeedCommitRevealCfg.SlashRate)
+		return errors.New("setSeedCommitRevealCfg: 惩罚比例超过100%")
+	}
+	log.Info("Geneis", "SeedCommitRevealCfg", g.SeedCommitRevealCfg)
+	return matrixstate.SetSeedCommitRevealCfg(state, g.SeedCommitRevealCfg)
+}
+
+func (g *GenesisMState) setReceiptMetaCfg(state *state.StateDBManage, num uint64, version string) error {
+	if g.ReceiptMetaCfg == nil {
+		return nil
+	}
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		log.Error("Geneis", "setReceiptMetaCfg", "链版本号过低", "version", version)
+		return errors.New("setReceiptMetaCfg: 链版本号过低")
+	}
+	// 收据内容参与收据根计算, 超级区块不能修改已生效的配置
+	if num != 0 {
+		current, err := matrixstate.GetReceiptMetaCfg(state)
+		if err != nil {
+			return err
+		}
+		if current.Active(num) && *current != *g.ReceiptMetaCfg {
+			log.Error("Geneis", "setReceiptMetaCfg", "修改了已生效的收据信息配置", "current", current)
+			return errors.New("setReceiptMetaCfg: 不能修改已生效的配置")
+		}
+		if !current.Active(num) && g.ReceiptMetaCfg.Active(num) {
+			log.Error("Geneis", "setReceiptMetaCfg", "生效高度不在超级区块之后", "activate", g.ReceiptMetaCfg.ActivateNumber)
+			return errors.New("setReceiptMetaCfg: 生效高度不在超级区块之后")
+		}
+	}
+	log.Info("Geneis", "ReceiptMetaCfg", g.ReceiptMetaCfg)
+	return matrixstate.SetReceiptMetaCfg(state, g.ReceiptMetaCfg)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"fmt"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+// Validate checks the consistency of the matrix state of a genesis: the elected
+// nodes against the election config, the topology against the elected nodes
+// and the broadcast interval against the election timing. It returns all the
+// problems found, an empty list for a consistent genesis.
+func (g *Genesis) Validate() []error {
+	var errs []error
+	report := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	ms := g.MState
+	if ms == nil {
+		report("mstate is missing")
+		return errs
+	}
+
+	// The broadcast blocks must fall on the interval
+	if ms.BCICfg == nil || ms.BCICfg.BCInterval == 0 {
+		report("broadcast interval is missing")
+	} else {
+		interval := ms.BCICfg.BCInterval
+		if ms.BCICfg.LastBCNumber%interval != 0 {
+			report("last broadcast number %d is not a multiple of the broadcast interval %d", ms.BCICfg.LastBCNumber, interval)
+		}
+		if ms.BCICfg.LastReelectNumber%interval != 0 {
+			report("last reelection number %d is not a multiple of the broadcast interval %d", ms.BCICfg.LastReelectNumber, interval)
+		}
+		if ms.BCICfg.BackupBCInterval != 0 && ms.BCICfg.BackupEnableNumber%interval != 0 {
+			report("backup interval enable number %d is not a multiple of the broadcast interval %d", ms.BCICfg.BackupEnableNumber, interval)
+		}
+		// The elections run in the blocks before the next broadcast
+		if t := ms.EleTimeCfg; t != nil {
+			for _, item := range []struct {
+				name  string
+				value uint16
+			}{{"MinerGen", t.MinerGen}, {"MinerNetChange", t.MinerNetChange}, {"ValidatorGen", t.ValidatorGen}, {"ValidatorNetChange", t.ValidatorNetChange}, {"VoteBeforeTime", t.VoteBeforeTime}} {
+				if uint64(item.value) >= interval {
+					report("election time %s %d is not below the broadcast interval %d", item.name, item.value, interval)
+				}
+			}
+			if t.MinerGen <= t.MinerNetChange {
+				report("election time MinerGen %d must be above MinerNetChange %d", t.MinerGen, t.MinerNetChange)
+			}
+			if t.ValidatorGen <= t.ValidatorNetChange {
+				report("election time ValidatorGen %d must be above ValidatorNetChange %d", t.ValidatorGen, t.ValidatorNetChange)
+			}
+		}
+	}
+	if ms.Broadcasts == nil || len(*ms.Broadcasts) == 0 {
+		report("no broadcast account")
+	}
+
+	// The elected nodes must fit the election config and hold a deposit stock
+	if ms.CurElect == nil || len(*ms.CurElect) == 0 {
+		if g.Number == 0 {
+			report("no elected node")
+		}
+		return errs
+	}
+	var (
+		elected                     = make(map[common.Address]common.ElectRoleType)
+		validators, backups, miners int
+	)
+	for _, node := range *ms.CurElect {
+		account := common.Address(node.Account)
+		name := base58.Base58EncodeToString(params.MAN_COIN, account)
+		if _, ok := elected[account]; ok {
+			report("node %s elected twice", name)
+		}
+		elected[account] = node.Type
+		if node.Stock == 0 {
+			report("elected node %s has no deposit stock", name)
+		}
+		switch node.Type {
+		case common.ElectRoleValidator:
+			validators++
+		case common.ElectRoleValidatorBackUp:
+			backups++
+		case common.ElectRoleMiner:
+			miners++
+		default:
+			report("elected node %s has invalid role %d", name, node.Type)
+		}
+	}
+	if validators == 0 {
+		report("no elected validator")
+	}
+	if ms.EleInfoCfg != nil {
+		if validators > int(ms.EleInfoCfg.ValidatorNum) {
+			report("%d elected validators, election config allows %d", validators, ms.EleInfoCfg.ValidatorNum)
+		}
+		if backups > int(ms.EleInfoCfg.BackValidator) {
+			report("%d elected backup validators, election config allows %d", backups, ms.EleInfoCfg.BackValidator)
+		}
+	}
+	if ms.ElectMinerNumCfg != nil && miners > int(ms.ElectMinerNumCfg.MinerNum) {
+		report("%d elected miners, election config allows %d", miners, ms.ElectMinerNumCfg.MinerNum)
+	}
+	// Only the node of a single-node chain may be both elected and broadcasting
+	if ms.Broadcasts != nil && len(elected) > 1 {
+		for _, account := range *ms.Broadcasts {
+			if _, ok := elected[common.Address(account)]; ok {
+				report("broadcast account %s is also elected", base58.Base58EncodeToString(params.MAN_COIN, common.Address(account)))
+			}
+		}
+	}
+
+	// The topology is made of elected nodes at distinct positions
+	positions := make(map[uint16]bool)
+	for _, node := range g.NetTopology.NetTopologyData {
+		name := base58.Base58EncodeToString(params.MAN_COIN, node.Account)
+		if _, ok := elected[node.Account]; !ok {
+			report("topology node %s is not elected", name)
+		}
+		if positions[node.Position] {
+			report("topology position %d used twice", node.Position)
+		}
+		positions[node.Position] = true
+	}
+	return errs
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+func TestGenesisValidate(t *testing.T) {
+	load := func() *Genesis {
+		genesis := new(Genesis)
+		if err := json.Unmarshal([]byte(AllGenesisJson), genesis); err != nil {
+			t.Fatalf("failed to decode genesis: %v", err)
+		}
+		return genesis
+	}
+	if errs := load().Validate(); len(errs) != 0 {
+		t.Fatalf("default genesis invalid: %v", errs)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(g *Genesis)
+	}{
+		{"interval", func(g *Genesis) { g.MState.BCICfg.LastBCNumber = g.MState.BCICfg.BCInterval + 1 }},
+		{"election time", func(g *Genesis) { g.MState.EleTimeCfg.MinerGen = uint16(g.MState.BCICfg.BCInterval) }},
+		{"validator count", func(g *Genesis) { g.MState.EleInfoCfg.ValidatorNum = 1 }},
+		{"stock", func(g *Genesis) { (*g.MState.CurElect)[0].Stock = 0 }},
+		{"topology", func(g *Genesis) {
+			g.NetTopology.NetTopologyData = append(g.NetTopology.NetTopologyData, common.NetTopologyData{Account: common.HexToAddress("0x01"), Position: 0xffff})
+		}},
+		{"broadcast", func(g *Genesis) { *g.MState.Broadcasts = append(*g.MState.Broadcasts, (*g.MState.CurElect)[0].Account) }},
+	}
+	for _, test := range tests {
+		genesis := load()
+		test.modify(genesis)
+		if errs := genesis.Validate(); len(errs) != 1 {
+			t.Errorf("%s: have %d errors, want 1: %v", test.name, len(errs), errs)
+		}
+	}
+}
+
+func TestDeveloperGenesisValidate(t *testing.T) {
+	var (
+		developer = common.HexToAddress("0x01")
+		funded    = common.HexToAddress("0x02")
+	)
+	genesis := DeveloperGenesisBlock(20, developer, []common.Address{funded})
+	if errs := genesis.Validate(); len(errs) != 0 {
+		t.Fatalf("developer genesis invalid: %v", errs)
+	}
+	if !genesis.Config.SimpleMode {
+		t.Error("developer genesis not in simple mode")
+	}
+	for _, address := range []common.Address{developer, funded} {
+		if balance := genesis.Alloc[address].Balance; balance == nil || balance.Cmp(developerBalance) != 0 {
+			t.Errorf("account %x: have balance %v, want %v", address, balance, developerBalance)
+		}
+	}
+	deposits := genesis.Alloc[depositContractAddress]
+	if balance := deposits.Balance; balance.Cmp(GenesisValidatorDeposit) != 0 {
+		t.Errorf("deposit contract: have balance %v, want %v", balance, GenesisValidatorDeposit)
+	}
+}
+
+func TestTestnetGenesisValidate(t *testing.T) {
+	var (
+		validators = []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02"), common.HexToAddress("0x03")}
+		miners     = []common.Address{common.HexToAddress("0x04")}
+		broadcast  = common.HexToAddress("0x05")
+	)
+	genesis := TestnetGenesisBlock(20, validators, miners, broadcast, broadcast)
+	if errs := genesis.Validate(); len(errs) != 0 {
+		t.Fatalf("test network genesis invalid: %v", errs)
+	}
+	if have := len(genesis.NetTopology.NetTopologyData); have != len(validators)+len(miners) {
+		t.Errorf("topology size mismatch: have %d, want %d", have, len(validators)+len(miners))
+	}
+	want := new(big.Int).Mul(GenesisValidatorDeposit, big.NewInt(int64(len(validators))))
+	want.Add(want, GenesisMinerDeposit)
+	if balance := genesis.Alloc[depositContractAddress].Balance; balance.Cmp(want) != 0 {
+		t.Errorf("deposit contract: have balance %v, want %v", balance, want)
+	}
+	if balance := genesis.Alloc[broadcast].Balance; balance == nil || balance.Cmp(testnetBalance) != 0 {
+		t.Errorf("broadcast account: have balance %v, want %v", balance, testnetBalance)
+	}
+}
+
+func TestDepositAlloc(t *testing.T) {
+	genesis := new(Genesis)
+	if err := json.Unmarshal([]byte(AllGenesisJson), genesis); err != nil {
+		t.Fatalf("failed to decode genesis: %v", err)
+	}
+	// Rebuild the deposits of the default genesis from its storage
+	var (
+		contract = depositContractAddress
+		storage  = genesis.Alloc[contract].Storage
+		deposits []GenesisDeposit
+	)
+	count := storage[common.BytesToHash(append(contract[:], 'D', 'N', 'U', 'M'))].Big().Uint64()
+	for i := uint64(0); i < count; i++ {
+		index := make([]byte, 8)
+		binary.BigEndian.PutUint64(index, i)
+		entry := storage[common.BytesToHash(append(append(contract[:], 'D', 'I'), index...))]
+		address := common.BytesToAddress(entry[:])
+		sign := storage[common.BytesToHash(append(address[:], 'N', 'X'))]
+		deposits = append(deposits, GenesisDeposit{
+			Address:     address,
+			SignAddress: common.BytesToAddress(sign[:]),
+			Amount:      storage[common.BytesToHash(append(address[:], 'D'))].Big(),
+		})
+	}
+	alloc := DepositAlloc(deposits)[contract]
+	if !reflect.DeepEqual(alloc.Storage, storage) {
+		t.Errorf("storage mismatch: have %v, want %v", alloc.Storage, storage)
+	}
+	if alloc.Balance.Cmp(genesis.Alloc[contract].Balance) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", alloc.Balance, genesis.Alloc[contract].Balance)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+	"github.com/pkg/errors"
+)
+
+// ProcessHeartbeatSlash 在广播区块后的首个区块执行, 对上个广播周期内轮到发送心跳却未发送的
+// 当选节点, 按配置比例惩罚抵押并在若干广播周期内禁止参选, 返回产生的惩罚事件
+func (bc *BlockChain) ProcessHeartbeatSlash(version string, state *state.StateDBManage, header *types.Header) ([]mc.SlashEvent, error) {
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		return nil, nil
+	}
+	if nil == state {
+		return nil, ErrStatePtrIsNil
+	}
+	if nil == header {
+		return nil, ErrHeaderPtrIsNil
+	}
+
+	slashCfg, err := matrixstate.GetHeartbeatSlashCfg(state)
+	if err != nil {
+		log.Error(ModuleName, "获取心跳惩罚配置失败", err)
+		return nil, err
+	}
+	if !slashCfg.Switcher {
+		return nil, nil
+	}
+
+	bcInterval, err := manparams.GetBCIntervalInfoByHash(header.ParentHash)
+	if err != nil {
+		log.Error(ModuleName, "获取广播周期失败", err)
+		return nil, err
+	}
+	number := header.Number.Uint64()
+	lastBroadcast := bcInterval.GetLastBroadcastNumber()
+	if number < bcInterval.GetBroadcastInterval() || number != lastBroadcast+1 {
+		return nil, nil
+	}
+	latestNum, err := matrixstate.GetHeartbeatSlashNum(state)
+	if err != nil {
+		return nil, err
+	}
+	if latestNum >= lastBroadcast {
+		return nil, nil
+	}
+	if err := matrixstate.SetHeartbeatSlashNum(state, lastBroadcast); err != nil {
+		return nil, err
+	}
+
+	blackList, err := matrixstate.GetHeartbeatSlashBlackList(state)
+	if err != nil {
+		return nil, err
+	}
+	blackList = decreaseHeartbeatBlackList(blackList)
+
+	superBlkCfg, err := matrixstate.GetSuperBlockCfg(state)
+	if err != nil {
+		return nil, errors.Errorf("get super seq error")
+	}
+	if sbh := superBlkCfg.Num; sbh < lastBroadcast && sbh >= lastBroadcast-bcInterval.GetBroadcastInterval() {
+		log.Debug(ModuleName, "上个广播周期存在超级区块", "跳过心跳惩罚", "高度", number)
+		return nil, matrixstate.SetHeartbeatSlashBlackList(state, blackList)
+	}
+
+	missed, err := bc.getHeartbeatMissedAccounts(header, bcInterval)
+	if err != nil {
+		log.Error(ModuleName, "获取未发送心跳的账户失败", err, "高度", number)
+		return nil, err
+	}
+
+	events := make([]mc.SlashEvent, 0, len(missed))
+	for _, account := range missed {
+		penalty := bc.slashHeartbeatDeposit(state, header.ParentHash, account, slashCfg.SlashRate)
+		blackList = addHeartbeatBlackList(blackList, account, slashCfg.ProhibitCycleNum)
+		log.Info(ModuleName, "心跳惩罚账户", account.Hex(), "惩罚金额", penalty, "禁止参选周期", slashCfg.ProhibitCycleNum, "高度", number)
+		events = append(events, mc.SlashEvent{
+			Number:         number,
+			Account:        account,
+			Reason:         mc.SlashReasonHeartbeat,
+			Penalty:        penalty,
+			ProhibitCycles: slashCfg.ProhibitCycleNum,
+		})
+	}
+	if err := matrixstate.SetHeartbeatSlashBlackList(state, blackList); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// getHeartbeatMissedAccounts 获取上个广播周期轮到发送心跳, 但广播区块中没有其心跳的当选账户(A0), 按地址排序
+func (bc *BlockChain) getHeartbeatMissedAccounts(header *types.Header, bcInterval *mc.BCIntervalInfo) ([]common.Address, error) {
+	lastStateRoot, beforeLastStateRoot, err := bc.getPreRoot(header, bcInterval)
+	if err != nil {
+		return nil, err
+	}
+	originValidatorMap, originMinerMap, err := bc.getElectMap(header.ParentHash, bcInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	heartbeatOriginMap, err := GetBroadcastTxMap(bc, lastStateRoot, mc.Heartbeat)
+	if err != nil {
+		log.Warn(ModuleName, "获取主动心跳交易错误", err)
+	}
+	heartbeats := make(map[common.Address]bool, len(heartbeatOriginMap))
+	for k := range heartbeatOriginMap {
+		account0, _, err := bc.GetA0AccountFromAnyAccount(k, header.ParentHash)
+		if nil != err {
+			continue
+		}
+		heartbeats[account0] = true
+	}
+
+	missed := make([]common.Address, 0)
+	for _, elected := range []map[common.Address]uint32{originValidatorMap, originMinerMap} {
+		for account := range elected {
+			if !IsHeartbeatTurn(beforeLastStateRoot, account, bcInterval.GetBroadcastInterval()) || heartbeats[account] {
+				continue
+			}
+			missed = append(missed, account)
+		}
+	}
+	sort.Slice(missed, func(i, j int) bool {
+		return bytes.Compare(missed[i].Bytes(), missed[j].Bytes()) < 0
+	})
+	return missed, nil
+}
+
+// slashHeartbeatDeposit 按比例惩罚账户的抵押, 返回惩罚金额
+func (bc *BlockChain) slashHeartbeatDeposit(state *state.StateDBManage, parentHash common.Hash, account common.Address, rate uint64) *big.Int {
+	return slashDepositByRate(state, account, rate, func() *big.Int {
+		return bc.getHeartbeatDeposit(parentHash, account)
+	})
+}
+
+// getHeartbeatDeposit 获取参选账户的抵押金额
+func (bc *BlockChain) getHeartbeatDeposit(parentHash common.Hash, account common.Address) *big.Int {
+	for _, role := range []common.RoleType{common.RoleValidator, common.RoleMiner} {
+		deposits, err := ca.GetElectedByHeightAndRoleByHash(parentHash, role)
+		if err != nil {
+			continue
+		}
+		for _, v := range deposits {
+			if v.Address == account && v.Deposit != nil {
+				return v.Deposit
+			}
+		}
+	}
+	return new(big.Int)
+}
+
+// decreaseHeartbeatBlackList 每个广播周期禁止参选周期数减一, 到期的账户移出黑名单
+func decreaseHeartbeatBlackList(blackList *mc.HeartbeatSlashBlackList) *mc.HeartbeatSlashBlackList {
+	result := &mc.HeartbeatSlashBlackList{BlackList: make([]mc.HeartbeatSlash, 0)}
+	if nil == blackList {
+		return result
+	}
+	for _, item := range blackList.BlackList {
+		if item.ProhibitCycleCounter > 1 {
+			result.BlackList = append(result.BlackList, mc.HeartbeatSlash{Address: item.Address, ProhibitCycleCounter: item.ProhibitCycleCounter - 1})
+		}
+	}
+	return result
+}
+
+// addHeartbeatBlackList 账户加入黑名单, 已在黑名单中的重新计算禁止参选周期数
+func addHeartbeatBlackList(blackList *mc.HeartbeatSlashBlackList, account common.Address, prohibitCycleNum uint16) *mc.HeartbeatSlashBlackList {
+	if 0 == prohibitCycleNum {
+		return blackList
+	}
+	for i, item := range blackList.BlackList {
+		if item.Address == account {
+			if item.ProhibitCycleCounter < prohibitCycleNum {
+				blackList.BlackList[i].ProhibitCycleCounter = prohibitCycleNum
+			}
+			return blackList
+		}
+	}
+	blackList.BlackList = append(blackList.BlackList, mc.HeartbeatSlash{Address: account, ProhibitCycleCounter: prohibitCycleNum})
+	return blackList
+}
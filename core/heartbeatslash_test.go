@@ -0,0 +1,54 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func Test_calcSlashPenalty(t *testing.T) {
+	deposit := new(big.Int).Mul(big.NewInt(10000), big.NewInt(1e18))
+	if penalty := calcSlashPenalty(deposit, 10); penalty.Cmp(new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18))) != 0 {
+		t.Errorf("惩罚金额错误 %v", penalty)
+	}
+	if penalty := calcSlashPenalty(nil, 10); penalty.Sign() != 0 {
+		t.Errorf("空抵押惩罚金额错误 %v", penalty)
+	}
+	if penalty := calcSlashPenalty(deposit, 0); penalty.Sign() != 0 {
+		t.Errorf("零比例惩罚金额错误 %v", penalty)
+	}
+}
+
+func Test_heartbeatBlackList(t *testing.T) {
+	addrA := common.HexToAddress("0x01")
+	addrB := common.HexToAddress("0x02")
+
+	blackList := &mc.HeartbeatSlashBlackList{BlackList: []mc.HeartbeatSlash{{Address: addrA, ProhibitCycleCounter: 1}, {Address: addrB, ProhibitCycleCounter: 2}}}
+	blackList = decreaseHeartbeatBlackList(blackList)
+	if len(blackList.BlackList) != 1 || blackList.BlackList[0].Address != addrB || blackList.BlackList[0].ProhibitCycleCounter != 1 {
+		t.Fatalf("黑名单周期递减错误 %v", blackList.BlackList)
+	}
+
+	blackList = addHeartbeatBlackList(blackList, addrB, 3)
+	blackList = addHeartbeatBlackList(blackList, addrA, 3)
+	if len(blackList.BlackList) != 2 {
+		t.Fatalf("黑名单数量错误 %v", blackList.BlackList)
+	}
+	for _, item := range blackList.BlackList {
+		if item.ProhibitCycleCounter != 3 {
+			t.Errorf("黑名单周期错误 %v", item)
+		}
+	}
+
+	if blackList = addHeartbeatBlackList(blackList, common.HexToAddress("0x03"), 0); len(blackList.BlackList) != 2 {
+		t.Errorf("禁止周期为0时不应加入黑名单 %v", blackList.BlackList)
+	}
+	if empty := decreaseHeartbeatBlackList(nil); len(empty.BlackList) != 0 {
+		t.Errorf("空黑名单递减错误 %v", empty.BlackList)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// intervalCalendarKey 广播周期日历在数据库中的键
+var intervalCalendarKey = []byte("BroadcastIntervalCalendar")
+
+// ErrIntervalNotRecorded 广播周期日历中没有该高度的记录
+var ErrIntervalNotRecorded = errors.New("broadcast interval of the height is not recorded")
+
+// IntervalRecord 自Number高度(含)起生效的广播周期
+type IntervalRecord struct {
+	Number   uint64
+	Interval mc.BCIntervalInfo
+}
+
+// IntervalCalendar 广播周期日历, 记录主链上每次广播周期变更, 按高度查询当时生效的广播周期.
+// manparams.GetBCIntervalInfo只反映当前配置, 校验历史区块时需使用日历中的历史记录,
+// 日历保存在数据库中, 不依赖(可能已裁剪的)历史状态
+type IntervalCalendar struct {
+	db      mandb.Database
+	mu      sync.RWMutex
+	records []IntervalRecord // 按Number升序排列
+}
+
+// NewIntervalCalendar 从数据库加载广播周期日历
+func NewIntervalCalendar(db mandb.Database) (*IntervalCalendar, error) {
+	calendar := &IntervalCalendar{db: db}
+	data, _ := db.Get(intervalCalendarKey)
+	if len(data) == 0 {
+		return calendar, nil
+	}
+	if err := rlp.DecodeBytes(data, &calendar.records); err != nil {
+		return nil, err
+	}
+	return calendar, nil
+}
+
+// Empty 日历中是否没有记录
+func (c *IntervalCalendar) Empty() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.records) == 0
+}
+
+// Records 日历中的全部记录
+func (c *IntervalCalendar) Records() []IntervalRecord {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]IntervalRecord{}, c.records...)
+}
+
+// Record 记录主链number高度区块状态中的广播周期, 即number+1高度起生效的广播周期.
+// 区块须按高度顺序成为主链区块, 分叉切换时高于number的记录会被丢弃
+func (c *IntervalCalendar) Record(number uint64, info *mc.BCIntervalInfo) error {
+	if info == nil || info.BCInterval == 0 {
+		return errors.New("invalid broadcast interval")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	records := c.records
+	start := sort.Search(len(records), func(i int) bool { return records[i].Number > number })
+	dropped := start < len(records)
+	records = records[:start]
+
+	if size := len(records); size > 0 && sameIntervalPhase(&records[size-1].Interval, info) {
+		if !dropped {
+			return nil
+		}
+	} else {
+		log.Info(ModuleName, "广播周期日历记录变更, 生效高度", number+1, "广播周期", info.BCInterval,
+			"广播区块", info.LastBCNumber, "选举区块", info.LastReelectNumber)
+		records = append(records, IntervalRecord{Number: number + 1, Interval: *info})
+	}
+
+	data, err := rlp.EncodeToBytes(records)
+	if err != nil {
+		return err
+	}
+	if err := c.db.Put(intervalCalendarKey, data); err != nil {
+		return err
+	}
+	c.records = records
+	return nil
+}
+
+// GetBroadcastIntervalAt 查询number高度生效的广播周期
+func (c *IntervalCalendar) GetBroadcastIntervalAt(number uint64) (*mc.BCIntervalInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	index := sort.Search(len(c.records), func(i int) bool { return c.records[i].Number > number })
+	if index == 0 {
+		return nil, ErrIntervalNotRecorded
+	}
+	info := c.records[index-1].Interval
+	return &info, nil
+}
+
+// IsBroadcastNumberAt 按number高度生效的广播周期判断是否为广播区块
+func (c *IntervalCalendar) IsBroadcastNumberAt(number uint64) (bool, error) {
+	info, err := c.GetBroadcastIntervalAt(number)
+	if err != nil {
+		return false, err
+	}
+	return info.IsBroadcastNumber(number), nil
+}
+
+// sameIntervalPhase 两个广播周期是否一致: 周期相同, 且广播区块和选举区块的相位相同.
+// 周期不变时最后广播(选举)高度按周期前移, 不需要新增记录
+func sameIntervalPhase(prev, cur *mc.BCIntervalInfo) bool {
+	if prev.BCInterval != cur.BCInterval || cur.LastBCNumber < prev.LastBCNumber || cur.LastReelectNumber < prev.LastReelectNumber {
+		return false
+	}
+	if (cur.LastBCNumber-prev.LastBCNumber)%cur.GetBroadcastInterval() != 0 {
+		return false
+	}
+	return (cur.LastReelectNumber-prev.LastReelectNumber)%cur.GetReElectionInterval() == 0
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func TestIntervalCalendar(t *testing.T) {
+	db := mandb.NewMemDatabase()
+	calendar, err := NewIntervalCalendar(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := calendar.GetBroadcastIntervalAt(10); err != ErrIntervalNotRecorded {
+		t.Fatalf("空日历查询错误 %v", err)
+	}
+
+	// 周期100, 广播区块前移不新增记录
+	for number, last := uint64(0), uint64(0); number < 300; number++ {
+		if number > 0 && number%100 == 0 {
+			last = number
+		}
+		if err := calendar.Record(number, &mc.BCIntervalInfo{LastBCNumber: last, BCInterval: 100}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if records := calendar.Records(); len(records) != 1 || records[0].Number != 1 {
+		t.Fatalf("周期不变时日历记录错误 %+v", records)
+	}
+	// 300高度起周期变为50
+	if err := calendar.Record(300, &mc.BCIntervalInfo{LastBCNumber: 300, LastReelectNumber: 300, BCInterval: 50}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := calendar.IsBroadcastNumberAt(200); err != nil || !ok {
+		t.Fatalf("变更前广播区块判断错误 %v %v", ok, err)
+	}
+	if ok, _ := calendar.IsBroadcastNumberAt(250); ok {
+		t.Fatalf("变更前使用了新广播周期")
+	}
+	if ok, err := calendar.IsBroadcastNumberAt(350); err != nil || !ok {
+		t.Fatalf("变更后广播区块判断错误 %v %v", ok, err)
+	}
+
+	// 重新加载后记录不变
+	reloaded, err := NewIntervalCalendar(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := reloaded.GetBroadcastIntervalAt(301); err != nil || info.BCInterval != 50 {
+		t.Fatalf("重新加载后广播周期错误 %+v %v", info, err)
+	}
+
+	// 分叉切换到未变更周期的链, 丢弃分叉点之后的记录
+	if err := reloaded.Record(299, &mc.BCIntervalInfo{LastBCNumber: 200, BCInterval: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := reloaded.GetBroadcastIntervalAt(350); err != nil || info.BCInterval != 100 {
+		t.Fatalf("分叉切换后广播周期错误 %+v %v", info, err)
+	}
+	if records := reloaded.Records(); len(records) != 1 {
+		t.Fatalf("分叉切换后日历记录错误 %+v", records)
+	}
+}
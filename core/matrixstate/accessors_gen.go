@@ -0,0 +1,238 @@
+// Code generated by gen_accessors.go. DO NOT EDIT.
+
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// 广播区块周期
+func GetBroadcastInterval(st StateDB) (*mc.BCIntervalInfo, error) {
+	value, err := getValue(st, mc.MSKeyBroadcastInterval)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.BCIntervalInfo), nil
+}
+
+func SetBroadcastInterval(st StateDB, interval *mc.BCIntervalInfo) error {
+	return setValue(st, mc.MSKeyBroadcastInterval, interval)
+}
+
+// 广播账户
+func GetBroadcastAccounts(st StateDB) ([]common.Address, error) {
+	value, err := getValue(st, mc.MSKeyAccountBroadcasts)
+	if err != nil {
+		return nil, err
+	}
+	return value.([]common.Address), nil
+}
+
+func SetBroadcastAccounts(st StateDB, accounts []common.Address) error {
+	return setValue(st, mc.MSKeyAccountBroadcasts, accounts)
+}
+
+// 广播交易
+func GetBroadcastTxs(st StateDB) (common.BroadTxSlice, error) {
+	value, err := getValue(st, mc.MSKeyBroadcastTx)
+	if err != nil {
+		return nil, err
+	}
+	return value.(common.BroadTxSlice), nil
+}
+
+func SetBroadcastTxs(st StateDB, txs common.BroadTxSlice) error {
+	return setValue(st, mc.MSKeyBroadcastTx, txs)
+}
+
+// 前广播区块root信息
+func GetPreBroadcastRoot(st StateDB) (*mc.PreBroadStateRoot, error) {
+	value, err := getValue(st, mc.MSKeyPreBroadcastRoot)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.PreBroadStateRoot), nil
+}
+
+// 拓扑图
+func GetTopologyGraph(st StateDB) (*mc.TopologyGraph, error) {
+	value, err := getValue(st, mc.MSKeyTopologyGraph)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.TopologyGraph), nil
+}
+
+func SetTopologyGraph(st StateDB, graph *mc.TopologyGraph) error {
+	return setValue(st, mc.MSKeyTopologyGraph, graph)
+}
+
+// 选举图
+func GetElectGraph(st StateDB) (*mc.ElectGraph, error) {
+	value, err := getValue(st, mc.MSKeyElectGraph)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.ElectGraph), nil
+}
+
+func SetElectGraph(st StateDB, graph *mc.ElectGraph) error {
+	return setValue(st, mc.MSKeyElectGraph, graph)
+}
+
+// 选举节点在线信息
+func GetElectOnlineState(st StateDB) (*mc.ElectOnlineStatus, error) {
+	value, err := getValue(st, mc.MSKeyElectOnlineState)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.ElectOnlineStatus), nil
+}
+
+func SetElectOnlineState(st StateDB, onlineState *mc.ElectOnlineStatus) error {
+	return setValue(st, mc.MSKeyElectOnlineState, onlineState)
+}
+
+// 选举生成时间
+func GetElectGenTime(st StateDB) (*mc.ElectGenTimeStruct, error) {
+	value, err := getValue(st, mc.MSKeyElectGenTime)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.ElectGenTimeStruct), nil
+}
+
+func SetElectGenTime(st StateDB, genTime *mc.ElectGenTimeStruct) error {
+	return setValue(st, mc.MSKeyElectGenTime, genTime)
+}
+
+// 选举配置
+func GetElectConfigInfo(st StateDB) (*mc.ElectConfigInfo, error) {
+	value, err := getValue(st, mc.MSKeyElectConfigInfo)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.ElectConfigInfo), nil
+}
+
+func SetElectConfigInfo(st StateDB, cfg *mc.ElectConfigInfo) error {
+	return setValue(st, mc.MSKeyElectConfigInfo, cfg)
+}
+
+// 矿工选举数量
+func GetElectMinerNum(st StateDB) (*mc.ElectMinerNumStruct, error) {
+	value, err := getValue(st, mc.MSKeyElectMinerNum)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.ElectMinerNumStruct), nil
+}
+
+func SetElectMinerNum(st StateDB, num *mc.ElectMinerNumStruct) error {
+	return setValue(st, mc.MSKeyElectMinerNum, num)
+}
+
+// 待生效的链参数更新
+func GetParamUpdates(st StateDB) (*mc.ParamUpdateQueue, error) {
+	value, err := getValue(st, mc.MSKeyParamUpdates)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.ParamUpdateQueue), nil
+}
+
+func SetParamUpdates(st StateDB, queue *mc.ParamUpdateQueue) error {
+	return setValue(st, mc.MSKeyParamUpdates, queue)
+}
+
+// 检查点投票配置
+func GetFinalityCfg(st StateDB) (*mc.FinalityCfg, error) {
+	value, err := getValue(st, mc.MSKeyFinalityCfg)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.FinalityCfg), nil
+}
+
+func SetFinalityCfg(st StateDB, cfg *mc.FinalityCfg) error {
+	return setValue(st, mc.MSKeyFinalityCfg, cfg)
+}
+
+// 最新的终局检查点
+func GetFinalizedCheckpoint(st StateDB) (*mc.FinalizedCheckpoint, error) {
+	value, err := getValue(st, mc.MSKeyFinalizedCheckpoint)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.FinalizedCheckpoint), nil
+}
+
+func SetFinalizedCheckpoint(st StateDB, checkpoint *mc.FinalizedCheckpoint) error {
+	return setValue(st, mc.MSKeyFinalizedCheckpoint, checkpoint)
+}
+
+// WASM合约执行配置
+func GetWasmCfg(st StateDB) (*mc.WasmCfg, error) {
+	value, err := getValue(st, mc.MSKeyWasmCfg)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.WasmCfg), nil
+}
+
+func SetWasmCfg(st StateDB, cfg *mc.WasmCfg) error {
+	return setValue(st, mc.MSKeyWasmCfg, cfg)
+}
+
+// 随机种子提交-揭示配置
+func GetSeedCommitRevealCfg(st StateDB) (*mc.SeedCommitRevealCfg, error) {
+	value, err := getValue(st, mc.MSKeySeedCommitRevealCfg)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.SeedCommitRevealCfg), nil
+}
+
+func SetSeedCommitRevealCfg(st StateDB, cfg *mc.SeedCommitRevealCfg) error {
+	return setValue(st, mc.MSKeySeedCommitRevealCfg, cfg)
+}
+
+// 随机种子提交-揭示状态
+func GetSeedCommitRevealState(st StateDB) (*mc.SeedCommitRevealState, error) {
+	value, err := getValue(st, mc.MSKeySeedCommitRevealState)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.SeedCommitRevealState), nil
+}
+
+func SetSeedCommitRevealState(st StateDB, crState *mc.SeedCommitRevealState) error {
+	return setValue(st, mc.MSKeySeedCommitRevealState, crState)
+}
+
+// 收据记录Matrix交易信息的配置
+func GetReceiptMetaCfg(st StateDB) (*mc.ReceiptMetaCfg, error) {
+	value, err := getValue(st, mc.MSKeyReceiptMetaCfg)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.ReceiptMetaCfg), nil
+}
+
+func SetReceiptMetaCfg(st StateDB, cfg *mc.ReceiptMetaCfg) error {
+	return setValue(st, mc.MSKeyReceiptMetaCfg, cfg)
+}
+
+// 硬分叉生效高度
+func GetForkSchedule(st StateDB) (*mc.ForkSchedule, error) {
+	value, err := getValue(st, mc.MSKeyForkSchedule)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.ForkSchedule), nil
+}
+
+func SetForkSchedule(st StateDB, schedule *mc.ForkSchedule) error {
+	return setValue(st, mc.MSKeyForkSchedule, schedule)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/pkg/errors"
+)
+
+// 广播交易规范编码: 按(类型, 账户)排序, 字段顺序固定, 账户及数据均为小写16进制,
+// 不依赖map遍历顺序及平台字长, 各节点编码结果逐字节一致
+type broadcastTxsJSON struct {
+	Version uint64            `json:"version"`
+	Txs     []broadcastTxJSON `json:"txs"`
+}
+
+type broadcastTxJSON struct {
+	Key     string `json:"key"`
+	Address string `json:"address"`
+	Value   string `json:"value"`
+}
+
+// 规范编码以'{'开头, rlp编码的列表以0xc0以上字节开头, 可据此区分新旧数据
+func isCanonicalBroadcastTxs(data []byte) bool {
+	return len(data) > 0 && data[0] == '{'
+}
+
+func encodeBroadcastTxs(txs common.BroadTxSlice) ([]byte, error) {
+	sorted := make(common.BroadTxSlice, len(txs))
+	copy(sorted, txs)
+	sort.SliceStable(sorted, func(i, j int) bool { return common.Less(sorted[i].Key, sorted[j].Key) })
+
+	msg := broadcastTxsJSON{
+		Version: mc.BroadcastTxCodecCanonical,
+		Txs:     make([]broadcastTxJSON, 0, len(sorted)),
+	}
+	for i, tx := range sorted {
+		if i > 0 && !common.Less(sorted[i-1].Key, tx.Key) {
+			return nil, errors.Errorf("duplicate broadcast tx: key %s, address %s", tx.Key.Key, tx.Key.Address.Hex())
+		}
+		msg.Txs = append(msg.Txs, broadcastTxJSON{
+			Key:     tx.Key.Key,
+			Address: hexutil.Encode(tx.Key.Address[:]),
+			Value:   hexutil.Encode(tx.Value),
+		})
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, errors.Errorf("json encode failed: %s", err)
+	}
+	return data, nil
+}
+
+func decodeBroadcastTxs(data []byte) (common.BroadTxSlice, error) {
+	var msg broadcastTxsJSON
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, errors.Errorf("json decode failed: %s", err)
+	}
+	if msg.Version != mc.BroadcastTxCodecCanonical {
+		return nil, errors.Errorf("unsupported broadcast txs codec version: %d", msg.Version)
+	}
+	txs := make(common.BroadTxSlice, 0, len(msg.Txs))
+	for _, tx := range msg.Txs {
+		addr, err := hexutil.Decode(tx.Address)
+		if err != nil || len(addr) != common.AddressLength {
+			return nil, errors.Errorf("invalid broadcast tx address: %s", tx.Address)
+		}
+		value, err := hexutil.Decode(tx.Value)
+		if err != nil {
+			return nil, errors.Errorf("invalid broadcast tx value: %s", err)
+		}
+		txs = append(txs, common.BroadTxValue{Key: common.BroadTxkey{Key: tx.Key, Address: common.BytesToAddress(addr)}, Value: value})
+	}
+	// 只接受规范编码, 防止同一数据存在多种编码
+	canonical, err := encodeBroadcastTxs(txs)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(canonical, data) {
+		return nil, errors.New("broadcast txs not canonically encoded")
+	}
+	return txs, nil
+}
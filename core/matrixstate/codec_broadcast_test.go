@@ -0,0 +1,158 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func testBroadcastTxs() common.BroadTxSlice {
+	// 故意乱序, 编码结果须与插入顺序无关
+	return common.BroadTxSlice{
+		{Key: common.BroadTxkey{Key: "Publickey", Address: common.HexToAddress("0x02")}, Value: []byte{0x01, 0x02}},
+		{Key: common.BroadTxkey{Key: "Heartbeat", Address: common.HexToAddress("0x01")}, Value: []byte{}},
+		{Key: common.BroadTxkey{Key: "Privatekey", Address: common.HexToAddress("0x01")}, Value: []byte{0xff, 0x00}},
+		{Key: common.BroadTxkey{Key: "Publickey", Address: common.HexToAddress("0x01")}, Value: []byte{0xab, 0xcd}},
+	}
+}
+
+func Test_encodeBroadcastTxsGolden(t *testing.T) {
+	golden := filepath.Join("testdata", "broadcast_txs_v1.golden")
+
+	data, err := encodeBroadcastTxs(testBroadcastTxs())
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if *updateGolden {
+		if err := ioutil.WriteFile(golden, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("encoding mismatch\ngot:  %s\nwant: %s", data, want)
+	}
+
+	txs, err := decodeBroadcastTxs(want)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(txs) != 4 || txs[0].Key.Key != "Heartbeat" || txs[3].Key.Address != common.HexToAddress("0x02") {
+		t.Fatalf("decoded txs not sorted: %v", txs)
+	}
+}
+
+func Test_encodeBroadcastTxsOrder(t *testing.T) {
+	txs := testBroadcastTxs()
+	want, err := encodeBroadcastTxs(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(txs); i++ {
+		rotated := append(append(common.BroadTxSlice{}, txs[i:]...), txs[:i]...)
+		data, err := encodeBroadcastTxs(rotated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Fatalf("rotation %d: encoding depends on input order", i)
+		}
+	}
+}
+
+func Test_encodeBroadcastTxsDuplicate(t *testing.T) {
+	txs := append(testBroadcastTxs(), common.BroadTxValue{Key: common.BroadTxkey{Key: "Publickey", Address: common.HexToAddress("0x01")}})
+	if _, err := encodeBroadcastTxs(txs); err == nil {
+		t.Fatal("duplicate broadcast tx encoded")
+	}
+}
+
+func Test_decodeBroadcastTxsNonCanonical(t *testing.T) {
+	tests := []string{
+		// 未排序
+		`{"version":1,"txs":[{"key":"Publickey","address":"0x0000000000000000000000000000000000000002","value":"0x"},{"key":"Heartbeat","address":"0x0000000000000000000000000000000000000001","value":"0x"}]}`,
+		// 大写16进制
+		`{"version":1,"txs":[{"key":"Heartbeat","address":"0x00000000000000000000000000000000000000AB","value":"0x"}]}`,
+		// 多余空白
+		`{"version":1, "txs":[]}`,
+		// 未知版本
+		`{"version":2,"txs":[]}`,
+		// 账户长度错误
+		`{"version":1,"txs":[{"key":"Heartbeat","address":"0x01","value":"0x"}]}`,
+	}
+	for i, data := range tests {
+		if _, err := decodeBroadcastTxs([]byte(data)); err == nil {
+			t.Errorf("test %d: non canonical data decoded", i)
+		}
+	}
+}
+
+func Test_operatorBroadcastTxCodec(t *testing.T) {
+	st := newTestState()
+	opt := newBroadcastTxOpt()
+
+	var want common.BroadTxSlice
+	for _, tx := range testBroadcastTxs() {
+		want.Insert(tx.Key.Key, tx.Key.Address, tx.Value)
+	}
+
+	// 未配置编码版本时沿用rlp编码
+	if err := opt.SetValue(st, want); err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := rlp.EncodeToBytes(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(st.GetMatrixData(opt.KeyHash()), legacy) {
+		t.Fatal("legacy data not rlp encoded")
+	}
+
+	// 切换为规范编码后, 新旧数据均可读取
+	if err := SetCodecVersion(st, mc.MSKeyBroadcastTx, mc.BroadcastTxCodecCanonical); err != nil {
+		t.Fatal(err)
+	}
+	value, err := opt.GetValue(st)
+	if err != nil {
+		t.Fatalf("legacy data decode failed: %v", err)
+	}
+	if len(value.(common.BroadTxSlice)) != len(want) {
+		t.Fatalf("legacy data mismatch: %v", value)
+	}
+	if err := opt.SetValue(st, want); err != nil {
+		t.Fatal(err)
+	}
+	golden, err := ioutil.ReadFile(filepath.Join("testdata", "broadcast_txs_v1.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(st.GetMatrixData(opt.KeyHash()), golden) {
+		t.Fatalf("state data mismatch\ngot:  %s\nwant: %s", st.GetMatrixData(opt.KeyHash()), golden)
+	}
+	value, err = opt.GetValue(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(value, want) {
+		t.Fatalf("canonical data mismatch: %v", value)
+	}
+
+	if err := SetCodecVersion(st, mc.MSKeyBroadcastTx, uint64(2)); err != ErrUnknownCodec {
+		t.Fatalf("unknown codec error mismatch: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// StateDiff key数据在两个状态间的变化, 数据不存在时为nil
+type StateDiff struct {
+	Key  string
+	From interface{}
+	To   interface{}
+}
+
+// Keys 管理类中的所有key, 按字典序排列
+func (self *Manager) Keys() []string {
+	keys := make([]string, 0, len(self.operators))
+	for key := range self.operators {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DiffState 比较两个状态中key的数据, 返回数据有变化的key, 按字典序排列;
+// keys为空时比较两个状态版本下的全部key
+func DiffState(from, to StateDB, keys []string) ([]StateDiff, error) {
+	if err := checkStateDB(from); err != nil {
+		return nil, err
+	}
+	if err := checkStateDB(to); err != nil {
+		return nil, err
+	}
+	fromMgr := GetManager(GetVersionInfo(from))
+	toMgr := GetManager(GetVersionInfo(to))
+	if fromMgr == nil || toMgr == nil {
+		return nil, ErrFindManager
+	}
+
+	if len(keys) == 0 {
+		keys = append(fromMgr.Keys(), toMgr.Keys()...)
+		keys = append(keys, mc.MSKeyVersionInfo)
+	} else {
+		keys = append([]string{}, keys...)
+	}
+	sort.Strings(keys)
+
+	diffs := make([]StateDiff, 0)
+	for i, key := range keys {
+		if i > 0 && keys[i-1] == key {
+			continue
+		}
+		fromOpt, fromErr := findDiffOperator(fromMgr, key)
+		toOpt, toErr := findDiffOperator(toMgr, key)
+		if fromErr != nil && toErr != nil {
+			return nil, toErr
+		}
+
+		fromData, toData := diffData(from, fromOpt), diffData(to, toOpt)
+		if bytes.Equal(fromData, toData) {
+			continue
+		}
+		fromValue, err := diffValue(from, fromOpt, fromData)
+		if err != nil {
+			log.Error(logInfo, "diff state failed", err, "key", key)
+			return nil, err
+		}
+		toValue, err := diffValue(to, toOpt, toData)
+		if err != nil {
+			log.Error(logInfo, "diff state failed", err, "key", key)
+			return nil, err
+		}
+		diffs = append(diffs, StateDiff{Key: key, From: fromValue, To: toValue})
+	}
+	return diffs, nil
+}
+
+func findDiffOperator(mgr *Manager, key string) (MatrixOperator, error) {
+	// 版本信息不在管理类中
+	if key == mc.MSKeyVersionInfo {
+		return versionOpt, nil
+	}
+	opt, exist := mgr.operators[key]
+	if !exist {
+		return nil, ErrOptNotExist
+	}
+	return opt, nil
+}
+
+func diffData(st StateDB, opt MatrixOperator) []byte {
+	if opt == nil {
+		return nil
+	}
+	return st.GetMatrixData(opt.KeyHash())
+}
+
+func diffValue(st StateDB, opt MatrixOperator, data []byte) (interface{}, error) {
+	if opt == nil || len(data) == 0 {
+		return nil, nil
+	}
+	return opt.GetValue(st)
+}
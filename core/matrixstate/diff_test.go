@@ -0,0 +1,60 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+)
+
+func Test_DiffState(t *testing.T) {
+	from, to := newTestState(), newTestState()
+	for _, st := range []*TestState{from, to} {
+		if err := SetVersionInfo(st, manversion.VersionAIMine); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetBroadcastInterval(st, &mc.BCIntervalInfo{BCInterval: 100}); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetElectBlackList(st, []common.Address{common.HexToAddress("0x01")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SetElectBlackList(to, []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetElectWhiteList(to, []common.Address{common.HexToAddress("0x03")}); err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err := DiffState(from, to, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 2 || diffs[0].Key != mc.MSKeyElectBlackList || diffs[1].Key != mc.MSKeyElectWhiteList {
+		t.Fatalf("diff keys mismatch: %v", diffs)
+	}
+	if len(diffs[0].From.([]common.Address)) != 1 || len(diffs[0].To.([]common.Address)) != 2 {
+		t.Fatalf("black list diff mismatch: %v", diffs[0])
+	}
+	if diffs[1].From != nil || len(diffs[1].To.([]common.Address)) != 1 {
+		t.Fatalf("white list diff mismatch: %v", diffs[1])
+	}
+
+	// 只比较指定的key
+	diffs, err = DiffState(from, to, []string{mc.MSKeyBroadcastInterval, mc.MSKeyElectWhiteList})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].Key != mc.MSKeyElectWhiteList {
+		t.Fatalf("diff keys mismatch: %v", diffs)
+	}
+
+	if _, err := DiffState(from, to, []string{"not_exist"}); err != ErrOptNotExist {
+		t.Fatalf("unknown key error mismatch: %v", err)
+	}
+}
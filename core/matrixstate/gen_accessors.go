@@ -0,0 +1,83 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+// +build ignore
+
+// gen_accessors generates the typed getters and setters of accessors_gen.go.
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"text/template"
+)
+
+type accessor struct {
+	Name   string // 函数名后缀
+	Key    string // mc中的key常量
+	Type   string // 数据类型
+	Param  string // setter参数名
+	NoSet  bool   // 只读
+	Remark string // 说明
+}
+
+var accessors = []accessor{
+	{Name: "BroadcastInterval", Key: "MSKeyBroadcastInterval", Type: "*mc.BCIntervalInfo", Param: "interval", Remark: "广播区块周期"},
+	{Name: "BroadcastAccounts", Key: "MSKeyAccountBroadcasts", Type: "[]common.Address", Param: "accounts", Remark: "广播账户"},
+	{Name: "BroadcastTxs", Key: "MSKeyBroadcastTx", Type: "common.BroadTxSlice", Param: "txs", Remark: "广播交易"},
+	{Name: "PreBroadcastRoot", Key: "MSKeyPreBroadcastRoot", Type: "*mc.PreBroadStateRoot", NoSet: true, Remark: "前广播区块root信息"},
+	{Name: "TopologyGraph", Key: "MSKeyTopologyGraph", Type: "*mc.TopologyGraph", Param: "graph", Remark: "拓扑图"},
+	{Name: "ElectGraph", Key: "MSKeyElectGraph", Type: "*mc.ElectGraph", Param: "graph", Remark: "选举图"},
+	{Name: "ElectOnlineState", Key: "MSKeyElectOnlineState", Type: "*mc.ElectOnlineStatus", Param: "onlineState", Remark: "选举节点在线信息"},
+	{Name: "ElectGenTime", Key: "MSKeyElectGenTime", Type: "*mc.ElectGenTimeStruct", Param: "genTime", Remark: "选举生成时间"},
+	{Name: "ElectConfigInfo", Key: "MSKeyElectConfigInfo", Type: "*mc.ElectConfigInfo", Param: "cfg", Remark: "选举配置"},
+	{Name: "ElectMinerNum", Key: "MSKeyElectMinerNum", Type: "*mc.ElectMinerNumStruct", Param: "num", Remark: "矿工选举数量"},
+	{Name: "ParamUpdates", Key: "MSKeyParamUpdates", Type: "*mc.ParamUpdateQueue", Param: "queue", Remark: "待生效的链参数更新"},
+	{Name: "FinalityCfg", Key: "MSKeyFinalityCfg", Type: "*mc.FinalityCfg", Param: "cfg", Remark: "检查点投票配置"},
+	{Name: "FinalizedCheckpoint", Key: "MSKeyFinalizedCheckpoint", Type: "*mc.FinalizedCheckpoint", Param: "checkpoint", Remark: "最新的终局检查点"},
+	{Name: "WasmCfg", Key: "MSKeyWasmCfg", Type: "*mc.WasmCfg", Param: "cfg", Remark: "WASM合约执行配置"},
+	{Name: "SeedCommitRevealCfg", Key: "MSKeySeedCommitRevealCfg", Type: "*mc.SeedCommitRevealCfg", Param: "cfg", Remark: "随机种子提交-揭示配置"},
+	{Name: "SeedCommitRevealState", Key: "MSKeySeedCommitRevealState", Type: "*mc.SeedCommitRevealState", Param: "crState", Remark: "随机种子提交-揭示状态"},
+	{Name: "ReceiptMetaCfg", Key: "MSKeyReceiptMetaCfg", Type: "*mc.ReceiptMetaCfg", Param: "cfg", Remark: "收据记录Matrix交易信息的配置"},
+	{Name: "ForkSchedule", Key: "MSKeyForkSchedule", Type: "*mc.ForkSchedule", Param: "schedule", Remark: "硬分叉生效高度"},
+}
+
+var accessorsTmpl = template.Must(template.New("").Parse(`// Code generated by gen_accessors.go. DO NOT EDIT.
+
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+{{range .}}
+// {{.Remark}}
+func Get{{.Name}}(st StateDB) ({{.Type}}, error) {
+	value, err := getValue(st, mc.{{.Key}})
+	if err != nil {
+		return nil, err
+	}
+	return value.({{.Type}}), nil
+}
+{{if not .NoSet}}
+func Set{{.Name}}(st StateDB, {{.Param}} {{.Type}}) error {
+	return setValue(st, mc.{{.Key}}, {{.Param}})
+}
+{{end}}{{end}}`))
+
+func main() {
+	buf := new(bytes.Buffer)
+	if err := accessorsTmpl.Execute(buf, accessors); err != nil {
+		log.Fatal(err)
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("accessors_gen.go", code, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -84,6 +84,7 @@ func newManger(version string) *Manager {
 				mc.MSKeyElectGenTime:           newElectGenTimeOpt(),
 				mc.MSKeyElectMinerNum:          newElectMinerNumOpt(),
 				mc.MSKeyElectConfigInfo:        newElectConfigInfoOpt(),
+				mc.MSKeyElectorForks:           newElectorForksOpt(),
 				mc.MSKeyElectBlackList:         newElectBlackListOpt(),
 				mc.MSKeyElectWhiteList:         newElectWhiteListOpt(),
 				mc.MSKeyElectWhiteListSwitcher: newElectWhiteListSwitcherOpt(),
@@ -97,6 +98,7 @@ func newManger(version string) *Manager {
 				mc.MSKeyVIPConfig:              newVIPConfigOpt(),
 				mc.MSKeyPreBroadcastRoot:       newPreBroadcastRootOpt(),
 				mc.MSKeyLeaderConfig:           newLeaderConfigOpt(),
+				mc.MSKeyLeaderSelection:        newLeaderSelectionOpt(),
 				mc.MSKeyMinHash:                newMinHashOpt(),
 				mc.MSKeySuperBlockCfg:          newSuperBlockCfgOpt(),
 
@@ -142,6 +144,7 @@ func newManger(version string) *Manager {
 				mc.MSKeyElectGenTime:           newElectGenTimeOpt(),
 				mc.MSKeyElectMinerNum:          newElectMinerNumOpt(),
 				mc.MSKeyElectConfigInfo:        newElectConfigInfoOpt(),
+				mc.MSKeyElectorForks:           newElectorForksOpt(),
 				mc.MSKeyElectBlackList:         newElectBlackListOpt(),
 				mc.MSKeyElectWhiteList:         newElectWhiteListOpt(),
 				mc.MSKeyElectWhiteListSwitcher: newElectWhiteListSwitcherOpt(),
@@ -155,6 +158,7 @@ func newManger(version string) *Manager {
 				mc.MSKeyVIPConfig:              newVIPConfigOpt(),
 				mc.MSKeyPreBroadcastRoot:       newPreBroadcastRootOpt(),
 				mc.MSKeyLeaderConfig:           newLeaderConfigOpt(),
+				mc.MSKeyLeaderSelection:        newLeaderSelectionOpt(),
 				mc.MSKeyMinHash:                newMinHashOpt(),
 				mc.MSKeySuperBlockCfg:          newSuperBlockCfgOpt(),
 
@@ -200,6 +204,7 @@ func newManger(version string) *Manager {
 				mc.MSKeyElectGenTime:           newElectGenTimeOpt(),
 				mc.MSKeyElectMinerNum:          newElectMinerNumOpt(),
 				mc.MSKeyElectConfigInfo:        newElectConfigInfoOpt(),
+				mc.MSKeyElectorForks:           newElectorForksOpt(),
 				mc.MSKeyElectBlackList:         newElectBlackListOpt(),
 				mc.MSKeyElectWhiteList:         newElectWhiteListOpt(),
 				mc.MSKeyElectWhiteListSwitcher: newElectWhiteListSwitcherOpt(),
@@ -213,6 +218,7 @@ func newManger(version string) *Manager {
 				mc.MSKeyVIPConfig:              newVIPConfigOpt(),
 				mc.MSKeyPreBroadcastRoot:       newPreBroadcastRootOpt(),
 				mc.MSKeyLeaderConfig:           newLeaderConfigOpt(),
+				mc.MSKeyLeaderSelection:        newLeaderSelectionOpt(),
 				mc.MSKeyMinHash:                newMinHashOpt(),
 				mc.MSKeySuperBlockCfg:          newSuperBlockCfgOpt(),
 
@@ -259,6 +265,7 @@ func newManger(version string) *Manager {
 				mc.MSKeyElectGenTime:           newElectGenTimeOpt(),
 				mc.MSKeyElectMinerNum:          newElectMinerNumOpt(),
 				mc.MSKeyElectConfigInfo:        newElectConfigInfoOpt(),
+				mc.MSKeyElectorForks:           newElectorForksOpt(),
 				mc.MSKeyElectBlackList:         newElectBlackListOpt(),
 				mc.MSKeyElectWhiteList:         newElectWhiteListOpt(),
 				mc.MSKeyElectWhiteListSwitcher: newElectWhiteListSwitcherOpt(),
@@ -272,6 +279,7 @@ func newManger(version string) *Manager {
 				mc.MSKeyVIPConfig:              newVIPConfigOpt(),
 				mc.MSKeyPreBroadcastRoot:       newPreBroadcastRootOpt(),
 				mc.MSKeyLeaderConfig:           newLeaderConfigOpt(),
+				mc.MSKeyLeaderSelection:        newLeaderSelectionOpt(),
 				mc.MSKeyMinHash:                newMinHashOpt(),
 				mc.MSKeySuperBlockCfg:          newSuperBlockCfgOpt(),
 				mc.MSKeyMinimumDifficulty:      newMinDiffcultyOpt(),
@@ -317,6 +325,26 @@ func newManger(version string) *Manager {
 				mc.MSKeyBasePowerSlashCfg:       newBasePowerSlashCfgOpt(),
 				mc.MSKeyBasePowerStats:          newBasePowerStatsOpt(),
 				mc.MSKeyBasePowerBlackList:      newBasePowerBlackListOpt(),
+				mc.MSKeyHeartbeatSlashCfg:       newHeartbeatSlashCfgOpt(),
+				mc.MSKeyHeartbeatSlashNum:       newHeartbeatSlashNumOpt(),
+				mc.MSKeyHeartbeatSlashBlackList: newHeartbeatSlashBlackListOpt(),
+				mc.MSKeyDoubleSignSlashCfg:      newDoubleSignSlashCfgOpt(),
+				mc.MSKeyDoubleSignRecords:       newDoubleSignRecordsOpt(),
+				mc.MSKeyUnbondingCfg:            newUnbondingCfgOpt(),
+				mc.MSKeyUnbondingQueue:          newUnbondingQueueOpt(),
+				mc.MSKeyRewardGovernance:        newRewardGovernanceOpt(),
+				mc.MSKeyRevocableQueue:          newRevocableQueueOpt(),
+				mc.MSKeyConsensusKeyCfg:         newConsensusKeyCfgOpt(),
+				mc.MSKeyConsensusKeys:           newConsensusKeysOpt(),
+				mc.MSKeyBLSVoteCfg:              newBLSVoteCfgOpt(),
+				mc.MSKeyFinalityCfg:             newFinalityCfgOpt(),
+				mc.MSKeyFinalizedCheckpoint:     newFinalizedCheckpointOpt(),
+				mc.MSKeyWasmCfg:                 newWasmCfgOpt(),
+				mc.MSKeySeedCommitRevealCfg:     newSeedCommitRevealCfgOpt(),
+				mc.MSKeySeedCommitRevealState:   newSeedCommitRevealStateOpt(),
+				mc.MSKeyReceiptMetaCfg:          newReceiptMetaCfgOpt(),
+				mc.MSKeyParamUpdates:            newParamUpdatesOpt(),
+				mc.MSKeyForkSchedule:            newForkScheduleOpt(),
 				mc.MSKeyElectDynamicPollingInfo: newDynamicPollingOpt(),
 				mc.MSCurrencyHeader:             newCurrencyHeaderCfgOpt(),
 			},
@@ -0,0 +1,62 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// BLS聚合投票配置
+type operatorBLSVoteCfg struct {
+	key common.Hash
+}
+
+func newBLSVoteCfgOpt() *operatorBLSVoteCfg {
+	return &operatorBLSVoteCfg{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyBLSVoteCfg),
+	}
+}
+
+func (opt *operatorBLSVoteCfg) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorBLSVoteCfg) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		// 未配置时不启用
+		return &mc.BLSVoteCfg{Switcher: false}, nil
+	}
+
+	value := new(mc.BLSVoteCfg)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "blsVoteCfg rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorBLSVoteCfg) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "blsVoteCfg rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
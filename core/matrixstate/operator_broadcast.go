@@ -38,6 +38,14 @@ func (opt *operatorBroadcastTx) GetValue(st StateDB) (interface{}, error) {
 	if len(data) == 0 {
 		return value, nil
 	}
+	if isCanonicalBroadcastTxs(data) {
+		txs, err := decodeBroadcastTxs(data)
+		if err != nil {
+			log.Error(logInfo, "broadcastTx canonical decode failed", err)
+			return nil, err
+		}
+		return txs, nil
+	}
 	if err := rlp.DecodeBytes(data, &value); err != nil {
 		log.Error(logInfo, "broadcastTx rlp decode failed", err)
 		return nil, err
@@ -56,11 +64,24 @@ func (opt *operatorBroadcastTx) SetValue(st StateDB, value interface{}) error {
 		log.Error(logInfo, "input param(broadcastTx) err", "reflect failed")
 		return ErrParamReflect
 	}
-	data, err := rlp.EncodeToBytes(txs)
+	codec, err := GetCodecVersion(st, mc.MSKeyBroadcastTx)
 	if err != nil {
-		log.Error(logInfo, "broadcastTx rlp encode failed", err)
 		return err
 	}
+	var data []byte
+	if codec == mc.BroadcastTxCodecCanonical {
+		data, err = encodeBroadcastTxs(txs)
+		if err != nil {
+			log.Error(logInfo, "broadcastTx canonical encode failed", err)
+			return err
+		}
+	} else {
+		data, err = rlp.EncodeToBytes(txs)
+		if err != nil {
+			log.Error(logInfo, "broadcastTx rlp encode failed", err)
+			return err
+		}
+	}
 	st.SetMatrixData(opt.key, data)
 	return nil
 }
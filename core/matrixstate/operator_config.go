@@ -430,6 +430,56 @@ func (opt *operatorLeaderConfig) SetValue(st StateDB, value interface{}) error {
 	return nil
 }
 
+/////////////////////////////////////////////////////////////////////////////////////////
+// leader选取方式
+type operatorLeaderSelection struct {
+	key common.Hash
+}
+
+func newLeaderSelectionOpt() *operatorLeaderSelection {
+	return &operatorLeaderSelection{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyLeaderSelection),
+	}
+}
+
+func (opt *operatorLeaderSelection) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorLeaderSelection) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	value := &mc.LeaderSelectionCfg{Mode: mc.LeaderSelectRotation}
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		// 未配置时保持按顺序轮换
+		return value, nil
+	}
+
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "leaderSelection rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorLeaderSelection) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "leaderSelection rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
 /////////////////////////////////////////////////////////////////////////////////////////
 // 最小hash
 type operatorMinHash struct {
@@ -0,0 +1,113 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// 共识密钥默认在注册300个区块后生效
+const defaultConsensusKeyActivateDelay = 300
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 共识密钥配置
+type operatorConsensusKeyCfg struct {
+	key common.Hash
+}
+
+func newConsensusKeyCfgOpt() *operatorConsensusKeyCfg {
+	return &operatorConsensusKeyCfg{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyConsensusKeyCfg),
+	}
+}
+
+func (opt *operatorConsensusKeyCfg) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorConsensusKeyCfg) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.ConsensusKeyCfg{ActivateDelay: defaultConsensusKeyActivateDelay}, nil
+	}
+
+	value := new(mc.ConsensusKeyCfg)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "consensusKeyCfg rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorConsensusKeyCfg) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "consensusKeyCfg rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 抵押账户注册的共识密钥
+type operatorConsensusKeys struct {
+	key common.Hash
+}
+
+func newConsensusKeysOpt() *operatorConsensusKeys {
+	return &operatorConsensusKeys{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyConsensusKeys),
+	}
+}
+
+func (opt *operatorConsensusKeys) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorConsensusKeys) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.ConsensusKeys{Keys: make([]mc.ConsensusKey, 0)}, nil
+	}
+
+	value := new(mc.ConsensusKeys)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "consensusKeys rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorConsensusKeys) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "consensusKeys rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
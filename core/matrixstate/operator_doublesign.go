@@ -0,0 +1,110 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 双签惩罚配置
+type operatorDoubleSignSlashCfg struct {
+	key common.Hash
+}
+
+func newDoubleSignSlashCfgOpt() *operatorDoubleSignSlashCfg {
+	return &operatorDoubleSignSlashCfg{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyDoubleSignSlashCfg),
+	}
+}
+
+func (opt *operatorDoubleSignSlashCfg) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorDoubleSignSlashCfg) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.DoubleSignSlashCfg{Switcher: false, SlashRate: 1000, EvidenceMaxAge: 1000}, nil
+	}
+
+	value := new(mc.DoubleSignSlashCfg)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "doubleSignSlashCfg rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorDoubleSignSlashCfg) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "doubleSignSlashCfg rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 已惩罚的双签记录
+type operatorDoubleSignRecords struct {
+	key common.Hash
+}
+
+func newDoubleSignRecordsOpt() *operatorDoubleSignRecords {
+	return &operatorDoubleSignRecords{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyDoubleSignRecords),
+	}
+}
+
+func (opt *operatorDoubleSignRecords) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorDoubleSignRecords) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.DoubleSignRecords{Records: make([]mc.DoubleSignRecord, 0)}, nil
+	}
+
+	value := new(mc.DoubleSignRecords)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "doubleSignRecords rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorDoubleSignRecords) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "doubleSignRecords rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
@@ -569,3 +569,52 @@ func (opt *operatorDynamicPollingInfo) SetValue(st StateDB, value interface{}) e
 	st.SetMatrixData(opt.key, data)
 	return nil
 }
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 选举算法切换高度
+type operatorElectorForks struct {
+	key common.Hash
+}
+
+func newElectorForksOpt() *operatorElectorForks {
+	return &operatorElectorForks{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyElectorForks),
+	}
+}
+
+func (opt *operatorElectorForks) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorElectorForks) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	forks := make([]mc.ElectorFork, 0)
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		// 未配置时不切换选举算法
+		return forks, nil
+	}
+	err := rlp.DecodeBytes(data, &forks)
+	if err != nil {
+		log.Error(logInfo, "electorForks rlp decode failed", err)
+		return nil, err
+	}
+	return forks, nil
+}
+
+func (opt *operatorElectorForks) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "electorForks rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
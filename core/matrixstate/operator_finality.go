@@ -0,0 +1,112 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 检查点投票配置
+type operatorFinalityCfg struct {
+	key common.Hash
+}
+
+func newFinalityCfgOpt() *operatorFinalityCfg {
+	return &operatorFinalityCfg{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyFinalityCfg),
+	}
+}
+
+func (opt *operatorFinalityCfg) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorFinalityCfg) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		// 未配置时不启用
+		return &mc.FinalityCfg{Switcher: false}, nil
+	}
+
+	value := new(mc.FinalityCfg)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "finalityCfg rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorFinalityCfg) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "finalityCfg rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 最新的终局检查点
+type operatorFinalizedCheckpoint struct {
+	key common.Hash
+}
+
+func newFinalizedCheckpointOpt() *operatorFinalizedCheckpoint {
+	return &operatorFinalizedCheckpoint{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyFinalizedCheckpoint),
+	}
+}
+
+func (opt *operatorFinalizedCheckpoint) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorFinalizedCheckpoint) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		// 尚无终局检查点
+		return &mc.FinalizedCheckpoint{}, nil
+	}
+
+	value := new(mc.FinalizedCheckpoint)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "finalizedCheckpoint rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorFinalizedCheckpoint) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "finalizedCheckpoint rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
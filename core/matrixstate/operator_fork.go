@@ -0,0 +1,62 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 硬分叉生效高度
+type operatorForkSchedule struct {
+	key common.Hash
+}
+
+func newForkScheduleOpt() *operatorForkSchedule {
+	return &operatorForkSchedule{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyForkSchedule),
+	}
+}
+
+func (opt *operatorForkSchedule) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorForkSchedule) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		// 未配置时所有硬分叉均不启用
+		return &mc.ForkSchedule{}, nil
+	}
+
+	value := new(mc.ForkSchedule)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "forkSchedule rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorForkSchedule) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "forkSchedule rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 心跳惩罚配置
+type operatorHeartbeatSlashCfg struct {
+	key common.Hash
+}
+
+func newHeartbeatSlashCfgOpt() *operatorHeartbeatSlashCfg {
+	return &operatorHeartbeatSlashCfg{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyHeartbeatSlashCfg),
+	}
+}
+
+func (opt *operatorHeartbeatSlashCfg) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorHeartbeatSlashCfg) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.HeartbeatSlashCfg{Switcher: false, SlashRate: 10, ProhibitCycleNum: 2}, nil
+	}
+
+	value := new(mc.HeartbeatSlashCfg)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "heartbeatSlashCfg rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorHeartbeatSlashCfg) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "heartbeatSlashCfg rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 心跳惩罚状态(已处理的广播区块高度)
+type operatorHeartbeatSlashNum struct {
+	key common.Hash
+}
+
+func newHeartbeatSlashNumOpt() *operatorHeartbeatSlashNum {
+	return &operatorHeartbeatSlashNum{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyHeartbeatSlashNum),
+	}
+}
+
+func (opt *operatorHeartbeatSlashNum) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorHeartbeatSlashNum) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return uint64(0), err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return uint64(0), nil
+	}
+	num, err := decodeUint64(data)
+	if err != nil {
+		log.Error(logInfo, "heartbeatSlashNum decode failed", err)
+		return uint64(0), err
+	}
+	return num, nil
+}
+
+func (opt *operatorHeartbeatSlashNum) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	num, OK := value.(uint64)
+	if !OK {
+		log.Error(logInfo, "input param(heartbeatSlashNum) err", "reflect failed")
+		return ErrParamReflect
+	}
+	st.SetMatrixData(opt.key, encodeUint64(num))
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 心跳惩罚黑名单
+type operatorHeartbeatSlashBlackList struct {
+	key common.Hash
+}
+
+func newHeartbeatSlashBlackListOpt() *operatorHeartbeatSlashBlackList {
+	return &operatorHeartbeatSlashBlackList{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyHeartbeatSlashBlackList),
+	}
+}
+
+func (opt *operatorHeartbeatSlashBlackList) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorHeartbeatSlashBlackList) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.HeartbeatSlashBlackList{BlackList: make([]mc.HeartbeatSlash, 0)}, nil
+	}
+
+	value := new(mc.HeartbeatSlashBlackList)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "heartbeatSlashBlackList rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorHeartbeatSlashBlackList) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "heartbeatSlashBlackList rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
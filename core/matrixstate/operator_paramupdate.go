@@ -0,0 +1,61 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 待生效的链参数更新
+type operatorParamUpdates struct {
+	key common.Hash
+}
+
+func newParamUpdatesOpt() *operatorParamUpdates {
+	return &operatorParamUpdates{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyParamUpdates),
+	}
+}
+
+func (opt *operatorParamUpdates) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorParamUpdates) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.ParamUpdateQueue{Updates: make([]mc.ParamUpdate, 0)}, nil
+	}
+
+	value := new(mc.ParamUpdateQueue)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "paramUpdates rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorParamUpdates) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "paramUpdates rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 收据记录Matrix交易信息的配置
+type operatorReceiptMetaCfg struct {
+	key common.Hash
+}
+
+func newReceiptMetaCfgOpt() *operatorReceiptMetaCfg {
+	return &operatorReceiptMetaCfg{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyReceiptMetaCfg),
+	}
+}
+
+func (opt *operatorReceiptMetaCfg) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorReceiptMetaCfg) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		// 未配置时不启用
+		return &mc.ReceiptMetaCfg{Switcher: false}, nil
+	}
+
+	value := new(mc.ReceiptMetaCfg)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "receiptMetaCfg rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorReceiptMetaCfg) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "receiptMetaCfg rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 可撤销交易队列
+type operatorRevocableQueue struct {
+	key common.Hash
+}
+
+func newRevocableQueueOpt() *operatorRevocableQueue {
+	return &operatorRevocableQueue{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyRevocableQueue),
+	}
+}
+
+func (opt *operatorRevocableQueue) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorRevocableQueue) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.RevocableQueue{Entries: make([]mc.RevocableEntry, 0)}, nil
+	}
+
+	value := new(mc.RevocableQueue)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "revocableQueue rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorRevocableQueue) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "revocableQueue rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 奖励分配比例治理状态
+type operatorRewardGovernance struct {
+	key common.Hash
+}
+
+func newRewardGovernanceOpt() *operatorRewardGovernance {
+	return &operatorRewardGovernance{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyRewardGovernance),
+	}
+}
+
+func (opt *operatorRewardGovernance) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorRewardGovernance) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.RewardGovernanceStatus{Pending: false}, nil
+	}
+
+	value := new(mc.RewardGovernanceStatus)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "rewardGovernance rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorRewardGovernance) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "rewardGovernance rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 随机种子提交-揭示配置
+type operatorSeedCommitRevealCfg struct {
+	key common.Hash
+}
+
+func newSeedCommitRevealCfgOpt() *operatorSeedCommitRevealCfg {
+	return &operatorSeedCommitRevealCfg{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeySeedCommitRevealCfg),
+	}
+}
+
+func (opt *operatorSeedCommitRevealCfg) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorSeedCommitRevealCfg) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.SeedCommitRevealCfg{Switcher: false, SlashRate: 100}, nil
+	}
+
+	value := new(mc.SeedCommitRevealCfg)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "seedCommitRevealCfg rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorSeedCommitRevealCfg) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "seedCommitRevealCfg rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 随机种子提交-揭示状态
+type operatorSeedCommitRevealState struct {
+	key common.Hash
+}
+
+func newSeedCommitRevealStateOpt() *operatorSeedCommitRevealState {
+	return &operatorSeedCommitRevealState{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeySeedCommitRevealState),
+	}
+}
+
+func (opt *operatorSeedCommitRevealState) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorSeedCommitRevealState) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.SeedCommitRevealState{Commits: make([]mc.SeedCommitment, 0)}, nil
+	}
+
+	value := new(mc.SeedCommitRevealState)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "seedCommitRevealState rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorSeedCommitRevealState) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "seedCommitRevealState rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 抵押解绑配置
+type operatorUnbondingCfg struct {
+	key common.Hash
+}
+
+func newUnbondingCfgOpt() *operatorUnbondingCfg {
+	return &operatorUnbondingCfg{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyUnbondingCfg),
+	}
+}
+
+func (opt *operatorUnbondingCfg) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorUnbondingCfg) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.UnbondingCfg{Intervals: 0}, nil
+	}
+
+	value := new(mc.UnbondingCfg)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "unbondingCfg rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorUnbondingCfg) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "unbondingCfg rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 抵押解绑队列
+type operatorUnbondingQueue struct {
+	key common.Hash
+}
+
+func newUnbondingQueueOpt() *operatorUnbondingQueue {
+	return &operatorUnbondingQueue{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyUnbondingQueue),
+	}
+}
+
+func (opt *operatorUnbondingQueue) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorUnbondingQueue) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.UnbondingQueue{Entries: make([]mc.UnbondingEntry, 0)}, nil
+	}
+
+	value := new(mc.UnbondingQueue)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "unbondingQueue rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorUnbondingQueue) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "unbondingQueue rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
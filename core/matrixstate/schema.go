@@ -0,0 +1,138 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+//go:generate go run gen_accessors.go
+
+// 每个key的编码版本存储在"key_codec"下, 未存储时为0(各operator的原始编码)
+const codecKeySuffix = "_codec"
+
+// 当前代码支持的各key最高编码版本, 未列出的key为0
+var codecVersions = map[string]uint64{
+	mc.MSKeyBroadcastTx: mc.BroadcastTxCodecCanonical,
+}
+
+func codecKeyHash(key string) common.Hash {
+	return types.RlpHash(matrixStatePrefix + key + codecKeySuffix)
+}
+
+// GetCodecVersion 获取key数据的编码版本
+func GetCodecVersion(st StateDB, key string) (uint64, error) {
+	if err := checkStateDB(st); err != nil {
+		return 0, err
+	}
+	data := st.GetMatrixData(codecKeyHash(key))
+	if len(data) == 0 {
+		return 0, nil
+	}
+	return decodeUint64(data)
+}
+
+// SetCodecVersion 设置key数据的编码版本, 之后写入的数据按该版本编码
+func SetCodecVersion(st StateDB, key string, version uint64) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+	if version > codecVersions[key] {
+		log.Error(logInfo, "set codec version failed", "unknown version", "key", key, "version", version)
+		return ErrUnknownCodec
+	}
+	st.SetMatrixData(codecKeyHash(key), encodeUint64(version))
+	return nil
+}
+
+func getValue(st StateDB, key string) (interface{}, error) {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return nil, ErrFindManager
+	}
+	opt, err := mgr.FindOperator(key)
+	if err != nil {
+		return nil, err
+	}
+	version, err := GetCodecVersion(st, key)
+	if err != nil {
+		return nil, err
+	}
+	if version <= codecVersions[key] {
+		return opt.GetValue(st)
+	}
+	return getValueForward(st, opt)
+}
+
+func setValue(st StateDB, key string, value interface{}) error {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return ErrFindManager
+	}
+	opt, err := mgr.FindOperator(key)
+	if err != nil {
+		return err
+	}
+	// 不能以旧编码覆盖新版本的数据
+	version, err := GetCodecVersion(st, key)
+	if err != nil {
+		return err
+	}
+	if version > codecVersions[key] {
+		log.Error(logInfo, "set value failed", "unknown codec version", "key", key, "version", version)
+		return ErrUnknownCodec
+	}
+	return opt.SetValue(st, value)
+}
+
+// getValueForward 前向兼容解码: 新版本的编码只在rlp列表末尾追加字段,
+// 依次去掉末尾的字段重试, 以当前代码认识的字段解码
+func getValueForward(st StateDB, opt MatrixOperator) (interface{}, error) {
+	value, err := opt.GetValue(st)
+	if err == nil {
+		return value, nil
+	}
+	data := st.GetMatrixData(opt.KeyHash())
+	content, _, splitErr := rlp.SplitList(data)
+	if splitErr != nil {
+		return nil, err
+	}
+	fields := make([]rlp.RawValue, 0)
+	for len(content) > 0 {
+		_, _, rest, splitErr := rlp.Split(content)
+		if splitErr != nil {
+			return nil, err
+		}
+		fields = append(fields, content[:len(content)-len(rest)])
+		content = rest
+	}
+	for n := len(fields) - 1; n > 0; n-- {
+		trimmed, encErr := rlp.EncodeToBytes(fields[:n])
+		if encErr != nil {
+			return nil, encErr
+		}
+		if value, decErr := opt.GetValue(&forwardStateDB{StateDB: st, key: opt.KeyHash(), data: trimmed}); decErr == nil {
+			return value, nil
+		}
+	}
+	return nil, err
+}
+
+// forwardStateDB 以去掉末尾字段后的数据替代key的原数据
+type forwardStateDB struct {
+	StateDB
+	key  common.Hash
+	data []byte
+}
+
+func (st *forwardStateDB) GetMatrixData(hash common.Hash) []byte {
+	if hash == st.key {
+		return st.data
+	}
+	return st.StateDB.GetMatrixData(hash)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+type testCfgV1 struct {
+	Switcher bool
+	Number   uint64
+}
+
+type testCfgV2 struct {
+	Switcher bool
+	Number   uint64
+	Accounts []common.Address
+	Remark   string
+}
+
+type testCfgOpt struct {
+	key common.Hash
+}
+
+func (opt *testCfgOpt) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *testCfgOpt) GetValue(st StateDB) (interface{}, error) {
+	value := new(testCfgV1)
+	if err := rlp.DecodeBytes(st.GetMatrixData(opt.key), value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *testCfgOpt) SetValue(st StateDB, value interface{}) error {
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
+func Test_CodecVersion(t *testing.T) {
+	st := newTestState()
+
+	version, err := GetCodecVersion(st, mc.MSKeyBroadcastTx)
+	if err != nil || version != 0 {
+		t.Fatalf("default codec version mismatch: %d, %v", version, err)
+	}
+	if err := SetCodecVersion(st, mc.MSKeyBroadcastTx, mc.BroadcastTxCodecCanonical); err != nil {
+		t.Fatal(err)
+	}
+	version, err = GetCodecVersion(st, mc.MSKeyBroadcastTx)
+	if err != nil || version != mc.BroadcastTxCodecCanonical {
+		t.Fatalf("codec version mismatch: %d, %v", version, err)
+	}
+	if err := SetCodecVersion(st, mc.MSKeyElectGenTime, 1); err != ErrUnknownCodec {
+		t.Fatalf("unsupported codec version set: %v", err)
+	}
+	if st.GetMatrixData(codecKeyHash(mc.MSKeyBroadcastTx)) == nil || st.GetMatrixData(codecKeyHash(mc.MSKeyElectGenTime)) != nil {
+		t.Fatal("codec version stored under wrong key")
+	}
+}
+
+func Test_getValueForward(t *testing.T) {
+	st := newTestState()
+	opt := &testCfgOpt{key: types.RlpHash(matrixStatePrefix + "test_cfg")}
+
+	// 新版本编码在末尾追加了字段
+	newer := &testCfgV2{Switcher: true, Number: 100, Accounts: []common.Address{common.HexToAddress("0x01")}, Remark: "v2"}
+	if err := opt.SetValue(st, newer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opt.GetValue(st); err == nil {
+		t.Fatal("newer data decoded by older codec")
+	}
+	value, err := getValueForward(st, opt)
+	if err != nil {
+		t.Fatalf("forward decode failed: %v", err)
+	}
+	cfg := value.(*testCfgV1)
+	if !cfg.Switcher || cfg.Number != 100 {
+		t.Fatalf("forward decode mismatch: %+v", cfg)
+	}
+
+	// 原数据不变
+	stored := new(testCfgV2)
+	if err := rlp.DecodeBytes(st.GetMatrixData(opt.key), stored); err != nil || stored.Remark != "v2" {
+		t.Fatalf("stored data changed: %v", err)
+	}
+
+	// 非rlp列表的数据无法前向兼容
+	st.SetMatrixData(opt.key, []byte{0x80})
+	if _, err := getValueForward(st, opt); err == nil {
+		t.Fatal("invalid data decoded")
+	}
+}
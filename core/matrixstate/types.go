@@ -6,6 +6,7 @@ package matrixstate
 
 import (
 	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/pkg/errors"
 	"reflect"
@@ -20,6 +21,7 @@ var (
 	ErrAccountNil   = errors.New("account is empty account")
 	ErrDataSize     = errors.New("data size err")
 	ErrFindManager  = errors.New("find manger err")
+	ErrUnknownCodec = errors.New("unknown codec version")
 )
 
 type StateDB interface {
@@ -31,6 +33,11 @@ const (
 	matrixStatePrefix = "ms_"
 )
 
+// KeyHash returns the hash under which the matrix state entry of a key is stored.
+func KeyHash(key string) common.Hash {
+	return types.RlpHash(matrixStatePrefix + key)
+}
+
 func checkStateDB(st StateDB) error {
 	if st == nil {
 		log.Error(logInfo, "stateDB err", ErrStateDBNil)
@@ -27,22 +27,6 @@ func SetVersionInfo(st StateDB, version string) error {
 	return versionOpt.SetValue(st, version)
 }
 
-func GetBroadcastInterval(st StateDB) (*mc.BCIntervalInfo, error) {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return nil, ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyBroadcastInterval)
-	if err != nil {
-		return nil, err
-	}
-	value, err := opt.GetValue(st)
-	if err != nil {
-		return nil, err
-	}
-	return value.(*mc.BCIntervalInfo), nil
-}
-
 func GetBroadcastIntervalByVersion(st StateDB, version string) (*mc.BCIntervalInfo, error) {
 	mgr := GetManager(GetVersionInfo(st))
 	if mgr == nil {
@@ -59,48 +43,6 @@ func GetBroadcastIntervalByVersion(st StateDB, version string) (*mc.BCIntervalIn
 	return value.(*mc.BCIntervalInfo), nil
 }
 
-func SetBroadcastInterval(st StateDB, interval *mc.BCIntervalInfo) error {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyBroadcastInterval)
-	if err != nil {
-		return err
-	}
-	return opt.SetValue(st, interval)
-}
-
-func GetBroadcastAccounts(st StateDB) ([]common.Address, error) {
-	version := GetVersionInfo(st)
-	mgr := GetManager(version)
-	if mgr == nil {
-		return nil, ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyAccountBroadcasts)
-	if err != nil {
-		return nil, err
-	}
-	value, err := opt.GetValue(st)
-	if err != nil {
-		return nil, err
-	}
-	return value.([]common.Address), nil
-}
-
-func SetBroadcastAccounts(st StateDB, accounts []common.Address) error {
-	version := GetVersionInfo(st)
-	mgr := GetManager(version)
-	if mgr == nil {
-		return ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyAccountBroadcasts)
-	if err != nil {
-		return err
-	}
-	return opt.SetValue(st, accounts)
-}
-
 func GetInnerMinerAccounts(st StateDB) ([]common.Address, error) {
 	mgr := GetManager(GetVersionInfo(st))
 	if mgr == nil {
@@ -269,12 +211,12 @@ func SetSubChainSuperAccounts(st StateDB, accounts []common.Address) error {
 	return opt.SetValue(st, accounts)
 }
 
-func GetPreBroadcastRoot(st StateDB) (*mc.PreBroadStateRoot, error) {
+func GetLeaderConfig(st StateDB) (*mc.LeaderConfig, error) {
 	mgr := GetManager(GetVersionInfo(st))
 	if mgr == nil {
 		return nil, ErrFindManager
 	}
-	opt, err := mgr.FindOperator(mc.MSKeyPreBroadcastRoot)
+	opt, err := mgr.FindOperator(mc.MSKeyLeaderConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -282,15 +224,27 @@ func GetPreBroadcastRoot(st StateDB) (*mc.PreBroadStateRoot, error) {
 	if err != nil {
 		return nil, err
 	}
-	return value.(*mc.PreBroadStateRoot), nil
+	return value.(*mc.LeaderConfig), nil
 }
 
-func GetLeaderConfig(st StateDB) (*mc.LeaderConfig, error) {
+func SetLeaderConfig(st StateDB, cfg *mc.LeaderConfig) error {
 	mgr := GetManager(GetVersionInfo(st))
 	if mgr == nil {
-		return nil, ErrFindManager
+		return ErrFindManager
 	}
 	opt, err := mgr.FindOperator(mc.MSKeyLeaderConfig)
+	if err != nil {
+		return err
+	}
+	return opt.SetValue(st, cfg)
+}
+
+func GetLeaderSelection(st StateDB) (*mc.LeaderSelectionCfg, error) {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return nil, ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyLeaderSelection)
 	if err != nil {
 		return nil, err
 	}
@@ -298,15 +252,15 @@ func GetLeaderConfig(st StateDB) (*mc.LeaderConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	return value.(*mc.LeaderConfig), nil
+	return value.(*mc.LeaderSelectionCfg), nil
 }
 
-func SetLeaderConfig(st StateDB, cfg *mc.LeaderConfig) error {
+func SetLeaderSelection(st StateDB, cfg *mc.LeaderSelectionCfg) error {
 	mgr := GetManager(GetVersionInfo(st))
 	if mgr == nil {
 		return ErrFindManager
 	}
-	opt, err := mgr.FindOperator(mc.MSKeyLeaderConfig)
+	opt, err := mgr.FindOperator(mc.MSKeyLeaderSelection)
 	if err != nil {
 		return err
 	}
@@ -425,35 +379,6 @@ func SetReelectionDifficulty(st StateDB, reelectionDifficulty *big.Int) error {
 	return opt.SetValue(st, reelectionDifficulty)
 }
 
-//func GetBroadcastTxs(st StateDB) (map[string]map[common.Address][]byte, error)
-func GetBroadcastTxs(st StateDB) (common.BroadTxSlice, error) {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return nil, ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyBroadcastTx)
-	if err != nil {
-		return nil, err
-	}
-	value, err := opt.GetValue(st)
-	if err != nil {
-		return nil, err
-	}
-	return value.(common.BroadTxSlice), nil
-}
-
-func SetBroadcastTxs(st StateDB, txs common.BroadTxSlice) error {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyBroadcastTx)
-	if err != nil {
-		return err
-	}
-	return opt.SetValue(st, txs)
-}
-
 func GetTxpoolGasLimit(st StateDB) (*big.Int, error) {
 	version := GetVersionInfo(st)
 	mgr := GetManager(version)
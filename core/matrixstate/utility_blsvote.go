@@ -0,0 +1,34 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import "github.com/MatrixAINetwork/go-matrix/mc"
+
+func GetBLSVoteCfg(st StateDB) (*mc.BLSVoteCfg, error) {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return nil, ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyBLSVoteCfg)
+	if err != nil {
+		return nil, err
+	}
+	value, err := opt.GetValue(st)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.BLSVoteCfg), nil
+}
+
+func SetBLSVoteCfg(st StateDB, cfg *mc.BLSVoteCfg) error {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyBLSVoteCfg)
+	if err != nil {
+		return err
+	}
+	return opt.SetValue(st, cfg)
+}
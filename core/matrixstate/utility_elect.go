@@ -8,22 +8,6 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/mc"
 )
 
-func GetTopologyGraph(st StateDB) (*mc.TopologyGraph, error) {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return nil, ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyTopologyGraph)
-	if err != nil {
-		return nil, err
-	}
-	value, err := opt.GetValue(st)
-	if err != nil {
-		return nil, err
-	}
-	return value.(*mc.TopologyGraph), nil
-}
-
 func GetTopologyGraphByVersion(st StateDB, version string) (*mc.TopologyGraph, error) {
 	mgr := GetManager(version)
 	if mgr == nil {
@@ -40,158 +24,6 @@ func GetTopologyGraphByVersion(st StateDB, version string) (*mc.TopologyGraph, e
 	return value.(*mc.TopologyGraph), nil
 }
 
-func SetTopologyGraph(st StateDB, graph *mc.TopologyGraph) error {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyTopologyGraph)
-	if err != nil {
-		return err
-	}
-	return opt.SetValue(st, graph)
-}
-
-func GetElectGraph(st StateDB) (*mc.ElectGraph, error) {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return nil, ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyElectGraph)
-	if err != nil {
-		return nil, err
-	}
-	value, err := opt.GetValue(st)
-	if err != nil {
-		return nil, err
-	}
-	return value.(*mc.ElectGraph), nil
-}
-
-func SetElectGraph(st StateDB, graph *mc.ElectGraph) error {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyElectGraph)
-	if err != nil {
-		return err
-	}
-	return opt.SetValue(st, graph)
-}
-
-func GetElectOnlineState(st StateDB) (*mc.ElectOnlineStatus, error) {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return nil, ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyElectOnlineState)
-	if err != nil {
-		return nil, err
-	}
-	value, err := opt.GetValue(st)
-	if err != nil {
-		return nil, err
-	}
-	return value.(*mc.ElectOnlineStatus), nil
-}
-
-func SetElectOnlineState(st StateDB, onlineState *mc.ElectOnlineStatus) error {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyElectOnlineState)
-	if err != nil {
-		return err
-	}
-	return opt.SetValue(st, onlineState)
-}
-
-func GetElectGenTime(st StateDB) (*mc.ElectGenTimeStruct, error) {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return nil, ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyElectGenTime)
-	if err != nil {
-		return nil, err
-	}
-	value, err := opt.GetValue(st)
-	if err != nil {
-		return nil, err
-	}
-	return value.(*mc.ElectGenTimeStruct), nil
-}
-
-func SetElectGenTime(st StateDB, genTime *mc.ElectGenTimeStruct) error {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyElectGenTime)
-	if err != nil {
-		return err
-	}
-	return opt.SetValue(st, genTime)
-}
-
-func GetElectConfigInfo(st StateDB) (*mc.ElectConfigInfo, error) {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return nil, ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyElectConfigInfo)
-	if err != nil {
-		return nil, err
-	}
-	value, err := opt.GetValue(st)
-	if err != nil {
-		return nil, err
-	}
-	return value.(*mc.ElectConfigInfo), nil
-}
-
-func SetElectConfigInfo(st StateDB, cfg *mc.ElectConfigInfo) error {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyElectConfigInfo)
-	if err != nil {
-		return err
-	}
-	return opt.SetValue(st, cfg)
-}
-
-func GetElectMinerNum(st StateDB) (*mc.ElectMinerNumStruct, error) {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return nil, ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyElectMinerNum)
-	if err != nil {
-		return nil, err
-	}
-	value, err := opt.GetValue(st)
-	if err != nil {
-		return nil, err
-	}
-	return value.(*mc.ElectMinerNumStruct), nil
-}
-
-func SetElectMinerNum(st StateDB, num *mc.ElectMinerNumStruct) error {
-	mgr := GetManager(GetVersionInfo(st))
-	if mgr == nil {
-		return ErrFindManager
-	}
-	opt, err := mgr.FindOperator(mc.MSKeyElectMinerNum)
-	if err != nil {
-		return err
-	}
-	return opt.SetValue(st, num)
-}
-
 func GetElectWhiteListSwitcher(st StateDB) (bool, error) {
 	mgr := GetManager(GetVersionInfo(st))
 	if mgr == nil {
@@ -347,3 +179,31 @@ func SetElectDynamicPollingInfo(st StateDB, info *mc.ElectDynamicPollingInfo) er
 	}
 	return opt.SetValue(st, info)
 }
+
+func GetElectorForks(st StateDB) ([]mc.ElectorFork, error) {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return nil, ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyElectorForks)
+	if err != nil {
+		return nil, err
+	}
+	value, err := opt.GetValue(st)
+	if err != nil {
+		return nil, err
+	}
+	return value.([]mc.ElectorFork), nil
+}
+
+func SetElectorForks(st StateDB, forks []mc.ElectorFork) error {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyElectorForks)
+	if err != nil {
+		return err
+	}
+	return opt.SetValue(st, forks)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package matrixstate
+
+import "github.com/MatrixAINetwork/go-matrix/mc"
+
+func GetHeartbeatSlashCfg(st StateDB) (*mc.HeartbeatSlashCfg, error) {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return nil, ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyHeartbeatSlashCfg)
+	if err != nil {
+		return nil, err
+	}
+	value, err := opt.GetValue(st)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.HeartbeatSlashCfg), nil
+}
+
+func SetHeartbeatSlashCfg(st StateDB, cfg *mc.HeartbeatSlashCfg) error {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyHeartbeatSlashCfg)
+	if err != nil {
+		return err
+	}
+	return opt.SetValue(st, cfg)
+}
+
+func GetHeartbeatSlashNum(st StateDB) (uint64, error) {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return uint64(0), ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyHeartbeatSlashNum)
+	if err != nil {
+		return uint64(0), err
+	}
+	value, err := opt.GetValue(st)
+	if err != nil {
+		return uint64(0), err
+	}
+	return value.(uint64), nil
+}
+
+func SetHeartbeatSlashNum(st StateDB, num uint64) error {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyHeartbeatSlashNum)
+	if err != nil {
+		return err
+	}
+	return opt.SetValue(st, num)
+}
+
+func GetHeartbeatSlashBlackList(st StateDB) (*mc.HeartbeatSlashBlackList, error) {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return nil, ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyHeartbeatSlashBlackList)
+	if err != nil {
+		return nil, err
+	}
+	value, err := opt.GetValue(st)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.HeartbeatSlashBlackList), nil
+}
+
+func SetHeartbeatSlashBlackList(st StateDB, blackList *mc.HeartbeatSlashBlackList) error {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyHeartbeatSlashBlackList)
+	if err != nil {
+		return err
+	}
+	return opt.SetValue(st, blackList)
+}
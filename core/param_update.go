@@ -0,0 +1,215 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+var (
+	ErrParamUpdateDisabled      = errors.New("param update is not enabled at this height")
+	ErrParamUpdateEmpty         = errors.New("param update changes nothing")
+	ErrParamUpdateInterval      = errors.New("param update broadcast interval is invalid")
+	ErrParamUpdateNumber        = errors.New("param update proposal number is invalid")
+	ErrParamUpdateActivate      = errors.New("param update activate number is not a future reelection number")
+	ErrParamUpdateSupermajority = errors.New("param update proposal lacks a validator supermajority")
+)
+
+// ParamUpdateProposal is a chain parameter update signed by the elected
+// validators. It takes effect at the reelection block it is scheduled for.
+type ParamUpdateProposal struct {
+	Update mc.ParamUpdate
+	Number uint64             // Height the proposal was made at, proposals must be increasing
+	Signs  []common.Signature // Agreement signatures of the validators on SignHash
+}
+
+// SignHash returns the hash signed by the validators agreeing with the proposal.
+func (p *ParamUpdateProposal) SignHash() common.Hash {
+	return types.RlpHash([]interface{}{p.Update, p.Number})
+}
+
+// EncodeParamUpdateProposal encodes the proposal as the payload of a param
+// update transaction.
+func EncodeParamUpdateProposal(p *ParamUpdateProposal) ([]byte, error) {
+	return rlp.EncodeToBytes(p)
+}
+
+// DecodeParamUpdateProposal decodes the payload of a param update transaction.
+func DecodeParamUpdateProposal(data []byte) (*ParamUpdateProposal, error) {
+	p := new(ParamUpdateProposal)
+	if err := rlp.DecodeBytes(data, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// broadcastIntervalAt 按待生效的广播周期更新, 推算number高度生效的广播周期
+func broadcastIntervalAt(info *mc.BCIntervalInfo, queue *mc.ParamUpdateQueue, number uint64) *mc.BCIntervalInfo {
+	result := *info
+	for _, update := range queue.Updates {
+		if update.ActivateNumber > number {
+			break
+		}
+		if update.BCInterval == 0 {
+			continue
+		}
+		result.SetLastBCNumber(update.ActivateNumber)
+		result.SetLastReelectNumber(update.ActivateNumber)
+		result.SetBackupBCInterval(update.BCInterval, 0)
+		result.UsingBackupInterval()
+	}
+	return &result
+}
+
+// applyParamUpdateProposal 验证提案并加入待生效队列, 在生效高度的选举区块生效
+func applyParamUpdateProposal(st vm.StateDBManager, p *ParamUpdateProposal, number uint64) error {
+	if !ForkActive(st, mc.ForkParamUpdate, number) {
+		return ErrParamUpdateDisabled
+	}
+	update := p.Update
+	if update.Empty() {
+		return ErrParamUpdateEmpty
+	}
+	// 心跳按广播周期-1取模, 广播周期至少为2
+	if update.BCInterval != 0 && update.BCInterval < 2 {
+		return ErrParamUpdateInterval
+	}
+	queue, err := matrixstate.GetParamUpdates(st)
+	if err != nil {
+		return err
+	}
+	if p.Number >= number || p.Number <= queue.ProposalNumber {
+		return ErrParamUpdateNumber
+	}
+	// 只能在已排期的更新之后追加, 生效高度须为届时广播周期下的选举区块
+	if update.ActivateNumber <= number {
+		return ErrParamUpdateActivate
+	}
+	if size := len(queue.Updates); size > 0 && update.ActivateNumber <= queue.Updates[size-1].ActivateNumber {
+		return ErrParamUpdateActivate
+	}
+	info, err := matrixstate.GetBroadcastInterval(st)
+	if err != nil {
+		return err
+	}
+	if !broadcastIntervalAt(info, queue, update.ActivateNumber).IsReElectionNumber(update.ActivateNumber) {
+		return ErrParamUpdateActivate
+	}
+	agreed, total, err := countProposalSigners(st, p.SignHash(), p.Signs)
+	if err != nil {
+		return err
+	}
+	// 需要超过2/3的当选验证者同意
+	if total == 0 || agreed*3 <= total*2 {
+		log.Warn(ModuleName, "链参数更新提案同意数不足", agreed, "验证者总数", total)
+		return ErrParamUpdateSupermajority
+	}
+
+	log.Info(ModuleName, "链参数更新提案通过, 提案高度", p.Number, "生效高度", update.ActivateNumber, "高度", number)
+	queue.Updates = append(queue.Updates, update)
+	queue.ProposalNumber = p.Number
+	return matrixstate.SetParamUpdates(st, queue)
+}
+
+// readParamUpdates 读取上个区块的待生效链参数更新, 旧版本无此配置时为空
+func readParamUpdates(readFn PreStateReadFn) (*mc.ParamUpdateQueue, error) {
+	data, err := readFn(mc.MSKeyParamUpdates)
+	if err == matrixstate.ErrOptNotExist {
+		return &mc.ParamUpdateQueue{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	queue, OK := data.(*mc.ParamUpdateQueue)
+	if OK == false {
+		return nil, errors.New("pre param updates reflect failed")
+	}
+	return queue, nil
+}
+
+// paramUpdateAt 在number高度生效的链参数更新
+func paramUpdateAt(readFn PreStateReadFn, number uint64) (*mc.ParamUpdate, error) {
+	queue, err := readParamUpdates(readFn)
+	if err != nil {
+		return nil, err
+	}
+	for i := range queue.Updates {
+		if queue.Updates[i].ActivateNumber == number {
+			return &queue.Updates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ProduceParamUpdatesData 移除已生效的链参数更新
+func ProduceParamUpdatesData(block *types.Block, state *state.StateDBManage, readFn PreStateReadFn) (interface{}, error) {
+	queue, err := readParamUpdates(readFn)
+	if err != nil {
+		log.Error("ProduceParamUpdatesData", "read pre param updates err", err)
+		return nil, err
+	}
+	number := block.NumberU64()
+	activated := 0
+	for activated < len(queue.Updates) && queue.Updates[activated].ActivateNumber <= number {
+		activated++
+	}
+	if activated == 0 {
+		return nil, nil
+	}
+	queue.Updates = queue.Updates[activated:]
+	return queue, nil
+}
+
+// ProduceElectConfigInfoData 在生效高度更新验证者选举数量
+func ProduceElectConfigInfoData(block *types.Block, state *state.StateDBManage, readFn PreStateReadFn) (interface{}, error) {
+	update, err := paramUpdateAt(readFn, block.NumberU64())
+	if err != nil {
+		log.Error("ProduceElectConfigInfoData", "read pre param updates err", err)
+		return nil, err
+	}
+	if update == nil || (update.ValidatorNum == 0 && update.BackValidator == 0) {
+		return nil, nil
+	}
+	data, err := readFn(mc.MSKeyElectConfigInfo)
+	if err != nil {
+		log.Error("ProduceElectConfigInfoData", "read pre elect config err", err)
+		return nil, err
+	}
+	cfg, OK := data.(*mc.ElectConfigInfo)
+	if OK == false {
+		return nil, errors.New("pre elect config reflect failed")
+	}
+	newCfg := *cfg
+	if update.ValidatorNum != 0 {
+		newCfg.ValidatorNum = update.ValidatorNum
+	}
+	if update.BackValidator != 0 {
+		newCfg.BackValidator = update.BackValidator
+	}
+	log.Info("ProduceElectConfigInfoData", "validator num", newCfg.ValidatorNum, "back validator", newCfg.BackValidator, "block number", block.NumberU64())
+	return &newCfg, nil
+}
+
+// ProduceElectMinerNumData 在生效高度更新矿工选举数量
+func ProduceElectMinerNumData(block *types.Block, state *state.StateDBManage, readFn PreStateReadFn) (interface{}, error) {
+	update, err := paramUpdateAt(readFn, block.NumberU64())
+	if err != nil {
+		log.Error("ProduceElectMinerNumData", "read pre param updates err", err)
+		return nil, err
+	}
+	if update == nil || update.MinerNum == 0 {
+		return nil, nil
+	}
+	log.Info("ProduceElectMinerNumData", "miner num", update.MinerNum, "block number", block.NumberU64())
+	return &mc.ElectMinerNumStruct{MinerNum: update.MinerNum}, nil
+}
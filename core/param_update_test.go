@@ -0,0 +1,142 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func testParamUpdateReadFn(queue *mc.ParamUpdateQueue, cfg *mc.ElectConfigInfo) PreStateReadFn {
+	return func(key string) (interface{}, error) {
+		switch key {
+		case mc.MSKeyParamUpdates:
+			if queue == nil {
+				return nil, matrixstate.ErrOptNotExist
+			}
+			return queue, nil
+		case mc.MSKeyElectConfigInfo:
+			return cfg, nil
+		}
+		return nil, matrixstate.ErrOptNotExist
+	}
+}
+
+func testParamUpdateBlock(number int64) *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number)})
+}
+
+func Test_paramUpdateProposalEncode(t *testing.T) {
+	proposal := &ParamUpdateProposal{Update: mc.ParamUpdate{ActivateNumber: 300, BCInterval: 200}, Number: 100}
+	data, err := EncodeParamUpdateProposal(proposal)
+	if err != nil {
+		t.Fatalf("提案编码错误 %v", err)
+	}
+	decoded, err := DecodeParamUpdateProposal(data)
+	if err != nil {
+		t.Fatalf("提案解码错误 %v", err)
+	}
+	if decoded.SignHash() != proposal.SignHash() {
+		t.Errorf("提案签名哈希不一致")
+	}
+	other := &ParamUpdateProposal{Update: mc.ParamUpdate{ActivateNumber: 600, BCInterval: 200}, Number: 100}
+	if other.SignHash() == proposal.SignHash() {
+		t.Errorf("不同生效高度的提案签名哈希相同")
+	}
+}
+
+func Test_broadcastIntervalAt(t *testing.T) {
+	info := &mc.BCIntervalInfo{LastBCNumber: 100, LastReelectNumber: 0, BCInterval: 100}
+	queue := &mc.ParamUpdateQueue{Updates: []mc.ParamUpdate{
+		{ActivateNumber: 300, BCInterval: 50},
+		{ActivateNumber: 450, MinerNum: 21},
+		{ActivateNumber: 600, BCInterval: 200},
+	}}
+
+	if at := broadcastIntervalAt(info, queue, 299); at.GetBroadcastInterval() != 100 {
+		t.Fatalf("生效前广播周期错误 %d", at.GetBroadcastInterval())
+	}
+	at := broadcastIntervalAt(info, queue, 300)
+	if at.GetBroadcastInterval() != 50 || at.GetLastBroadcastNumber() != 300 || at.GetLastReElectionNumber() != 300 {
+		t.Fatalf("生效后广播周期错误 %+v", at)
+	}
+	if !at.IsReElectionNumber(450) || at.IsReElectionNumber(400) {
+		t.Fatalf("新广播周期的选举区块错误")
+	}
+	if at := broadcastIntervalAt(info, queue, 1000); at.GetBroadcastInterval() != 200 || at.GetLastReElectionNumber() != 600 {
+		t.Fatalf("多次更新后广播周期错误 %+v", at)
+	}
+	if info.GetBroadcastInterval() != 100 || info.GetLastBroadcastNumber() != 100 {
+		t.Fatalf("原广播周期被修改 %+v", info)
+	}
+}
+
+func Test_ProduceParamUpdatesData(t *testing.T) {
+	queue := &mc.ParamUpdateQueue{Updates: []mc.ParamUpdate{
+		{ActivateNumber: 300, ValidatorNum: 19, MinerNum: 32},
+		{ActivateNumber: 600, BCInterval: 200},
+	}, ProposalNumber: 10}
+	cfg := &mc.ElectConfigInfo{ValidatorNum: 11, BackValidator: 5, ElectPlug: "layerd"}
+	readFn := testParamUpdateReadFn(queue, cfg)
+
+	// 未到生效高度
+	for _, produce := range []ProduceMatrixStateDataFn{ProduceParamUpdatesData, ProduceElectConfigInfoData, ProduceElectMinerNumData} {
+		data, err := produce(testParamUpdateBlock(299), nil, readFn)
+		if err != nil || data != nil {
+			t.Fatalf("未到生效高度时生成数据 %v %v", data, err)
+		}
+	}
+
+	data, err := ProduceParamUpdatesData(testParamUpdateBlock(300), nil, readFn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest := data.(*mc.ParamUpdateQueue); len(rest.Updates) != 1 || rest.Updates[0].ActivateNumber != 600 || rest.ProposalNumber != 10 {
+		t.Fatalf("生效后的队列错误 %+v", rest)
+	}
+
+	data, err = ProduceElectConfigInfoData(testParamUpdateBlock(300), nil, readFn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newCfg := data.(*mc.ElectConfigInfo); newCfg.ValidatorNum != 19 || newCfg.BackValidator != 5 || newCfg.ElectPlug != "layerd" {
+		t.Fatalf("选举配置更新错误 %+v", newCfg)
+	}
+	if cfg.ValidatorNum != 11 {
+		t.Fatalf("原选举配置被修改 %+v", cfg)
+	}
+
+	data, err = ProduceElectMinerNumData(testParamUpdateBlock(300), nil, readFn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num := data.(*mc.ElectMinerNumStruct); num.MinerNum != 32 {
+		t.Fatalf("矿工数量更新错误 %+v", num)
+	}
+
+	// 只更新广播周期时不修改选举配置
+	if data, err := ProduceElectConfigInfoData(testParamUpdateBlock(600), nil, readFn); err != nil || data != nil {
+		t.Fatalf("选举配置被错误修改 %v %v", data, err)
+	}
+
+	// 旧版本无链参数更新配置
+	if data, err := ProduceParamUpdatesData(testParamUpdateBlock(300), nil, testParamUpdateReadFn(nil, cfg)); err != nil || data != nil {
+		t.Fatalf("旧版本生成数据 %v %v", data, err)
+	}
+}
+
+func Test_paramUpdateFork(t *testing.T) {
+	st := newForkTestState(mc.ForkActivation{Name: mc.ForkParamUpdate, ActivateNumber: 100})
+	proposal := &ParamUpdateProposal{Update: mc.ParamUpdate{BCInterval: 1, ActivateNumber: 200}, Number: 90}
+	if err := applyParamUpdateProposal(st, proposal, 99); err != ErrParamUpdateDisabled {
+		t.Fatalf("硬分叉前提案应返回 %v, 实际 %v", ErrParamUpdateDisabled, err)
+	}
+	if err := applyParamUpdateProposal(st, proposal, 100); err != ErrParamUpdateInterval {
+		t.Fatalf("硬分叉后应检查提案内容, 实际 %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+)
+
+// ReceiptMetaEnabled 检查number高度的收据是否记录Matrix交易信息
+func ReceiptMetaEnabled(st vm.StateDBManager, number uint64) bool {
+	cfg, err := matrixstate.GetReceiptMetaCfg(st)
+	if err != nil {
+		return false
+	}
+	return cfg.Active(number)
+}
+
+// newReceiptMatrixInfo 生成收据中的Matrix交易信息, extraStatus为附加交易的执行状态, 为nil时附加交易均未执行
+func newReceiptMatrixInfo(tx types.SelfTransaction, extraStatus []uint64) *types.ReceiptMatrixInfo {
+	info := &types.ReceiptMatrixInfo{ExtraStatus: make([]uint64, 0)}
+	if extra := tx.GetMatrix_EX(); len(extra) > 0 {
+		info.TxType = extra[0].TxType
+		info.ExtraStatus = make([]uint64, len(extra[0].ExtraTo))
+		copy(info.ExtraStatus, extraStatus)
+	}
+	if tx.TxType() == types.BroadCastTxIndex {
+		info.BroadcastType = broadcastCategory(tx.Data())
+	}
+	return info
+}
+
+// broadcastCategory 返回广播交易内容中的广播类型, 多个类型按名称排序以逗号分隔
+func broadcastCategory(payload []byte) string {
+	data, err := types.DecodeBroadcastPayload(payload)
+	if err != nil {
+		return ""
+	}
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+)
+
+var (
+	ErrRevocableWindow = errors.New("revocable window is zero or exceeds the limit")
+	ErrRevocableHeight = errors.New("revocable transactions by block height are not enabled")
+	ErrRevertDuplicate = errors.New("revocable transaction is already being reverted")
+	ErrRevertUnknownTx = errors.New("revert target is not a revocable transaction of the sender")
+)
+
+// RevocableMaxWindow 可撤销交易按区块高度设置撤销期时的最大区块数
+const RevocableMaxWindow uint64 = 17280
+
+// revocableWindow 获取可撤销交易设置的撤销期区块数. 普通可撤销交易按提交时间撤销, 返回0;
+// 按区块高度撤销的交易由LockHeight设置撤销期, 硬分叉生效后才可执行
+func revocableWindow(st vm.StateDBManager, txType byte, extra []types.Matrix_Extra, number uint64) (uint64, error) {
+	if txType != common.ExtraRevocableHeight {
+		return 0, nil
+	}
+	if !ForkActive(st, mc.ForkRevocableHeight, number) {
+		return 0, ErrRevocableHeight
+	}
+	if len(extra) == 0 || extra[0].LockHeight == 0 || extra[0].LockHeight > RevocableMaxWindow {
+		return 0, ErrRevocableWindow
+	}
+	return extra[0].LockHeight, nil
+}
+
+// isRevocableType 交易类型是否为可撤销交易
+func isRevocableType(txType byte) bool {
+	return txType == common.ExtraRevocable || txType == common.ExtraRevocableHeight
+}
+
+// addRevocableEntry 可撤销交易加入撤销队列, 到达释放高度前可以被撤销交易撤回
+func addRevocableEntry(st vm.StateDBManager, hash common.Hash, release uint64) error {
+	queue, err := matrixstate.GetRevocableQueue(st)
+	if err != nil {
+		return err
+	}
+	queue.Entries = append(queue.Entries, mc.RevocableEntry{Hash: hash, ReleaseNumber: release})
+	return matrixstate.SetRevocableQueue(st, queue)
+}
+
+// ReleaseRevocableTxs 撤销期已结束的可撤销交易, 将托管的金额转给收款人. 已被撤销的交易状态中没有记录, 直接移出队列
+func ReleaseRevocableTxs(st vm.StateDBManager, number uint64) error {
+	if manversion.VersionCmp(matrixstate.GetVersionInfo(st), manversion.VersionAIMine) < 0 {
+		return nil
+	}
+	queue, err := matrixstate.GetRevocableQueue(st)
+	if err != nil {
+		return err
+	}
+	kept := make([]mc.RevocableEntry, 0, len(queue.Entries))
+	for _, entry := range queue.Entries {
+		if entry.ReleaseNumber > number {
+			kept = append(kept, entry)
+			continue
+		}
+		b := st.GetMatrixData(entry.Hash)
+		if len(b) == 0 {
+			continue
+		}
+		var rt common.RecorbleTx
+		if err := json.Unmarshal(b, &rt); err != nil {
+			log.Error(ModuleName, "可撤销交易解码失败", err, "hash", entry.Hash.Hex())
+			continue
+		}
+		for _, vv := range rt.Adam {
+			if st.GetBalanceByType(rt.Cointyp, rt.From, common.WithdrawAccount).Cmp(vv.Amont) < 0 {
+				log.Error(ModuleName, "可撤销交易托管金额不足", rt.From.Hex(), "金额", vv.Amont)
+				continue
+			}
+			st.SubBalance(rt.Cointyp, common.WithdrawAccount, rt.From, vv.Amont)
+			st.AddBalance(rt.Cointyp, common.MainAccount, vv.Addr, vv.Amont)
+		}
+		st.DeleteMxData(entry.Hash, b)
+		log.Info(ModuleName, "可撤销交易撤销期结束", entry.Hash.Hex(), "高度", number)
+	}
+	if len(kept) == len(queue.Entries) {
+		return nil
+	}
+	return matrixstate.SetRevocableQueue(st, &mc.RevocableQueue{Entries: kept})
+}
+
+// revertTargets 获取撤销交易要撤回的可撤销交易hash
+func revertTargets(tx *types.Transaction) []common.Hash {
+	targets := make([]common.Hash, 0)
+	if hash := common.BytesToHash(tx.Data()); !common.EmptyHash(hash) {
+		targets = append(targets, hash)
+	}
+	if extra := tx.GetMatrix_EX(); len(extra) > 0 {
+		for _, ex := range extra[0].ExtraTo {
+			if hash := common.BytesToHash(ex.Payload); !common.EmptyHash(hash) {
+				targets = append(targets, hash)
+			}
+		}
+	}
+	return targets
+}
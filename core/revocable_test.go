@@ -0,0 +1,65 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+)
+
+func Test_revertTargets(t *testing.T) {
+	hashA := common.HexToHash("0x01")
+	hashB := common.HexToHash("0x02")
+	to := common.HexToAddress("0x03")
+	input := hexutil.Bytes(hashB.Bytes())
+
+	ex := []*types.ExtraTo_tr{{To_tr: &to, Value_tr: (*hexutil.Big)(big.NewInt(0)), Input_tr: &input}}
+	tx := types.NewTransactions(0, to, big.NewInt(0), 21000, big.NewInt(1), hashA.Bytes(), big.NewInt(0), big.NewInt(0), big.NewInt(0), ex, 0, common.ExtraRevertTxType, 0, params.MAN_COIN, 0)
+	targets := revertTargets(tx)
+	if len(targets) != 2 || targets[0] != hashA || targets[1] != hashB {
+		t.Fatalf("撤销目标错误 %v", targets)
+	}
+
+	empty := types.NewTransactions(0, to, big.NewInt(0), 21000, big.NewInt(1), nil, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, 0, common.ExtraRevertTxType, 0, params.MAN_COIN, 0)
+	if targets := revertTargets(empty); len(targets) != 0 {
+		t.Errorf("空撤销交易不应有撤销目标 %v", targets)
+	}
+}
+
+func Test_revocableWindow(t *testing.T) {
+	chaindb := mandb.NewMemDatabase()
+	roots := []common.CoinRoot{{Cointyp: params.MAN_COIN, Root: common.Hash{}}}
+	st, _ := state.NewStateDBManage(roots, chaindb, state.NewDatabase(chaindb))
+	matrixstate.SetVersionInfo(st, manversion.VersionAIMine)
+	matrixstate.SetForkSchedule(st, &mc.ForkSchedule{Forks: []mc.ForkActivation{{Name: mc.ForkRevocableHeight, ActivateNumber: 100}}})
+	extra := func(lock uint64) []types.Matrix_Extra {
+		return []types.Matrix_Extra{{LockHeight: lock}}
+	}
+
+	// 普通可撤销交易的LockHeight由钱包填写, 不作为撤销期
+	if window, err := revocableWindow(st, common.ExtraRevocable, extra(1600000000), 200); err != nil || window != 0 {
+		t.Errorf("普通可撤销交易撤销期错误 %d %v", window, err)
+	}
+	if _, err := revocableWindow(st, common.ExtraRevocableHeight, extra(10), 99); err != ErrRevocableHeight {
+		t.Errorf("硬分叉前应返回错误 %v", err)
+	}
+	if window, err := revocableWindow(st, common.ExtraRevocableHeight, extra(10), 100); err != nil || window != 10 {
+		t.Errorf("按区块高度的撤销期错误 %d %v", window, err)
+	}
+	for _, lock := range []uint64{0, RevocableMaxWindow + 1} {
+		if _, err := revocableWindow(st, common.ExtraRevocableHeight, extra(lock), 100); err != ErrRevocableWindow {
+			t.Errorf("撤销期%d应返回错误 %v", lock, err)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+var (
+	ErrGovernanceVersion       = errors.New("reward governance is not enabled in this version")
+	ErrGovernanceRate          = errors.New("reward schedule rates do not add up")
+	ErrGovernanceNumber        = errors.New("reward governance proposal number is invalid")
+	ErrGovernanceSupermajority = errors.New("reward governance proposal lacks a validator supermajority")
+)
+
+// RewardGovernanceProposal is a new reward schedule signed by the elected
+// validators. It takes effect at the broadcast block following its inclusion.
+type RewardGovernanceProposal struct {
+	Schedule mc.RewardSchedule
+	Number   uint64             // Height the proposal was made at, proposals must be increasing
+	Signs    []common.Signature // Agreement signatures of the validators on SignHash
+}
+
+// SignHash returns the hash signed by the validators agreeing with the proposal.
+func (p *RewardGovernanceProposal) SignHash() common.Hash {
+	return types.RlpHash([]interface{}{p.Schedule, p.Number})
+}
+
+// EncodeRewardGovernanceProposal encodes the proposal as the payload of a
+// reward governance transaction.
+func EncodeRewardGovernanceProposal(p *RewardGovernanceProposal) ([]byte, error) {
+	return rlp.EncodeToBytes(p)
+}
+
+// DecodeRewardGovernanceProposal decodes the payload of a reward governance
+// transaction.
+func DecodeRewardGovernanceProposal(data []byte) (*RewardGovernanceProposal, error) {
+	p := new(RewardGovernanceProposal)
+	if err := rlp.DecodeBytes(data, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetRewardSchedule 获取当前生效的奖励分配比例
+func GetRewardSchedule(st vm.StateDBManager) (*mc.RewardSchedule, error) {
+	blkCfg, err := matrixstate.GetAIBlkRewardCfg(st)
+	if err != nil {
+		return nil, err
+	}
+	txsCfg, err := matrixstate.GetTxsRewardCfg(st)
+	if err != nil {
+		return nil, err
+	}
+	return &mc.RewardSchedule{
+		BlkRewardRate:     blkCfg.RewardRate,
+		TxsMinersRate:     txsCfg.MinersRate,
+		TxsValidatorsRate: txsCfg.ValidatorsRate,
+		TxsRewardRate:     txsCfg.RewardRate,
+	}, nil
+}
+
+// checkRewardSchedule 检查奖励分配比例, 规则与创世配置一致
+func checkRewardSchedule(schedule *mc.RewardSchedule) error {
+	blkRate := schedule.BlkRewardRate
+	if RewardFullRate != blkRate.MinerOutRate+blkRate.ElectedMinerRate+blkRate.FoundationMinerRate+blkRate.AIMinerOutRate ||
+		RewardFullRate != blkRate.LeaderRate+blkRate.ElectedValidatorsRate+blkRate.FoundationValidatorRate ||
+		RewardFullRate != blkRate.OriginElectOfflineRate+blkRate.BackupRewardRate {
+		return ErrGovernanceRate
+	}
+	txsRate := schedule.TxsRewardRate
+	if RewardFullRate != schedule.TxsMinersRate+schedule.TxsValidatorsRate ||
+		RewardFullRate != txsRate.MinerOutRate+txsRate.ElectedMinerRate+txsRate.FoundationMinerRate ||
+		RewardFullRate != txsRate.LeaderRate+txsRate.ElectedValidatorsRate+txsRate.FoundationValidatorRate ||
+		RewardFullRate != txsRate.OriginElectOfflineRate+txsRate.BackupRewardRate {
+		return ErrGovernanceRate
+	}
+	return nil
+}
+
+// countProposalSigners 统计对提案签名同意的当选验证者数量, 返回同意数和验证者总数
+func countProposalSigners(st vm.StateDBManager, signHash common.Hash, signs []common.Signature) (int, int, error) {
+	validators, err := currentValidators(st)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	agreed := make(map[common.Address]bool)
+	for _, sign := range signs {
+		signer, validate, err := crypto.VerifySignWithValidate(signHash.Bytes(), sign.Bytes())
+		if err != nil || !validate {
+			continue
+		}
+		account := depoistInfo.GetDepositAccount(st, signer)
+		if validators[account] {
+			agreed[account] = true
+		}
+	}
+	return len(agreed), len(validators), nil
+}
+
+// currentValidators 返回拓扑图中的当前验证者抵押账户
+func currentValidators(st matrixstate.StateDB) (map[common.Address]bool, error) {
+	topology, err := matrixstate.GetTopologyGraph(st)
+	if err != nil {
+		return nil, err
+	}
+	validators := make(map[common.Address]bool)
+	for _, node := range topology.NodeList {
+		if node.Type == common.RoleValidator {
+			validators[node.Account] = true
+		}
+	}
+	return validators, nil
+}
+
+// applyRewardGovernanceProposal 验证提案并记录为待生效状态, 在下个广播区块生效
+func applyRewardGovernanceProposal(st vm.StateDBManager, p *RewardGovernanceProposal, number uint64) error {
+	if manversion.VersionCmp(matrixstate.GetVersionInfo(st), manversion.VersionAIMine) < 0 {
+		return ErrGovernanceVersion
+	}
+	if err := checkRewardSchedule(&p.Schedule); err != nil {
+		return err
+	}
+	status, err := matrixstate.GetRewardGovernance(st)
+	if err != nil {
+		return err
+	}
+	if p.Number >= number || p.Number <= status.ProposalNumber {
+		return ErrGovernanceNumber
+	}
+	agreed, total, err := countProposalSigners(st, p.SignHash(), p.Signs)
+	if err != nil {
+		return err
+	}
+	// 需要超过2/3的当选验证者同意
+	if total == 0 || agreed*3 <= total*2 {
+		log.Warn(ModuleName, "奖励治理提案同意数不足", agreed, "验证者总数", total)
+		return ErrGovernanceSupermajority
+	}
+
+	log.Info(ModuleName, "奖励治理提案通过, 提案高度", p.Number, "同意数", agreed, "验证者总数", total, "高度", number)
+	return matrixstate.SetRewardGovernance(st, &mc.RewardGovernanceStatus{Pending: true, Schedule: p.Schedule, ProposalNumber: p.Number})
+}
+
+// ProcessRewardGovernance 在广播区块将通过的奖励分配比例写入奖励配置
+func (bc *BlockChain) ProcessRewardGovernance(version string, state *state.StateDBManage, header *types.Header) error {
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		return nil
+	}
+	if nil == state {
+		return ErrStatePtrIsNil
+	}
+	if nil == header {
+		return ErrHeaderPtrIsNil
+	}
+
+	bcInterval, err := matrixstate.GetBroadcastInterval(state)
+	if err != nil {
+		return err
+	}
+	if !bcInterval.IsBroadcastNumber(header.Number.Uint64()) {
+		return nil
+	}
+	status, err := matrixstate.GetRewardGovernance(state)
+	if err != nil {
+		return err
+	}
+	if !status.Pending {
+		return nil
+	}
+
+	blkCfg, err := matrixstate.GetAIBlkRewardCfg(state)
+	if err != nil {
+		return err
+	}
+	blkCfg.RewardRate = status.Schedule.BlkRewardRate
+	if err := matrixstate.SetAIBlkRewardCfg(state, blkCfg); err != nil {
+		return err
+	}
+	txsCfg, err := matrixstate.GetTxsRewardCfg(state)
+	if err != nil {
+		return err
+	}
+	txsCfg.MinersRate = status.Schedule.TxsMinersRate
+	txsCfg.ValidatorsRate = status.Schedule.TxsValidatorsRate
+	txsCfg.RewardRate = status.Schedule.TxsRewardRate
+	if err := matrixstate.SetTxsRewardCfg(state, txsCfg); err != nil {
+		return err
+	}
+
+	log.Info(ModuleName, "奖励分配比例生效, 提案高度", status.ProposalNumber, "高度", header.Number.Uint64())
+	status.Pending = false
+	return matrixstate.SetRewardGovernance(state, status)
+}
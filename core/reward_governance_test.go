@@ -0,0 +1,62 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func testRewardSchedule() mc.RewardSchedule {
+	return mc.RewardSchedule{
+		BlkRewardRate: mc.AIRewardRateCfg{
+			MinerOutRate: 4000, AIMinerOutRate: 2000, ElectedMinerRate: 3000, FoundationMinerRate: 1000,
+			LeaderRate: 4000, ElectedValidatorsRate: 5000, FoundationValidatorRate: 1000,
+			OriginElectOfflineRate: 5000, BackupRewardRate: 5000,
+		},
+		TxsMinersRate:     4000,
+		TxsValidatorsRate: 6000,
+		TxsRewardRate: mc.RewardRateCfg{
+			MinerOutRate: 4000, ElectedMinerRate: 5000, FoundationMinerRate: 1000,
+			LeaderRate: 4000, ElectedValidatorsRate: 5000, FoundationValidatorRate: 1000,
+			OriginElectOfflineRate: 5000, BackupRewardRate: 5000,
+		},
+	}
+}
+
+func Test_checkRewardSchedule(t *testing.T) {
+	schedule := testRewardSchedule()
+	if err := checkRewardSchedule(&schedule); err != nil {
+		t.Fatalf("奖励分配比例检查错误 %v", err)
+	}
+	schedule.BlkRewardRate.AIMinerOutRate++
+	if err := checkRewardSchedule(&schedule); err != ErrGovernanceRate {
+		t.Errorf("矿工区块奖励比例错误未检出 %v", err)
+	}
+	schedule = testRewardSchedule()
+	schedule.TxsMinersRate--
+	if err := checkRewardSchedule(&schedule); err != ErrGovernanceRate {
+		t.Errorf("交易费奖励比例错误未检出 %v", err)
+	}
+}
+
+func Test_rewardGovernanceProposalEncode(t *testing.T) {
+	proposal := &RewardGovernanceProposal{Schedule: testRewardSchedule(), Number: 100}
+	data, err := EncodeRewardGovernanceProposal(proposal)
+	if err != nil {
+		t.Fatalf("提案编码错误 %v", err)
+	}
+	decoded, err := DecodeRewardGovernanceProposal(data)
+	if err != nil {
+		t.Fatalf("提案解码错误 %v", err)
+	}
+	if decoded.SignHash() != proposal.SignHash() {
+		t.Errorf("提案签名哈希不一致")
+	}
+	other := &RewardGovernanceProposal{Schedule: testRewardSchedule(), Number: 101}
+	if other.SignHash() == proposal.SignHash() {
+		t.Errorf("不同高度的提案签名哈希相同")
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+// RewardBreakdown 账户在一个区块中获得的各类奖励
+type RewardBreakdown struct {
+	Mining     *big.Int
+	Validation *big.Int
+	Interest   *big.Int
+	Lottery    *big.Int
+	TxFees     map[string]*big.Int // 按币种统计的交易费奖励
+}
+
+func newRewardBreakdown() *RewardBreakdown {
+	return &RewardBreakdown{
+		Mining:     new(big.Int),
+		Validation: new(big.Int),
+		Interest:   new(big.Int),
+		Lottery:    new(big.Int),
+		TxFees:     make(map[string]*big.Int),
+	}
+}
+
+// BlockRewardRecord 一个区块发放的奖励明细
+type BlockRewardRecord struct {
+	Number  uint64
+	Rewards map[common.Address]*RewardBreakdown
+}
+
+func newBlockRewardRecord(number uint64, rewards []common.RewarTx) *BlockRewardRecord {
+	record := &BlockRewardRecord{Number: number, Rewards: make(map[common.Address]*RewardBreakdown)}
+	for _, reward := range rewards {
+		for addr, amount := range reward.To_Amont {
+			if amount == nil {
+				continue
+			}
+			breakdown, ok := record.Rewards[addr]
+			if !ok {
+				breakdown = newRewardBreakdown()
+				record.Rewards[addr] = breakdown
+			}
+			switch reward.RewardTyp {
+			case common.RewardMinerType:
+				breakdown.Mining.Add(breakdown.Mining, amount)
+			case common.RewardValidatorType:
+				breakdown.Validation.Add(breakdown.Validation, amount)
+			case common.RewardInterestType:
+				breakdown.Interest.Add(breakdown.Interest, amount)
+			case common.RewardLotteryType:
+				breakdown.Lottery.Add(breakdown.Lottery, amount)
+			case common.RewardTxsType:
+				coin := reward.CoinType
+				if coin == "" {
+					coin = params.MAN_COIN
+				}
+				fee, ok := breakdown.TxFees[coin]
+				if !ok {
+					fee = new(big.Int)
+					breakdown.TxFees[coin] = fee
+				}
+				fee.Add(fee, amount)
+			}
+		}
+	}
+	return record
+}
+
+// rewardsFromLogs 由区块的奖励日志还原奖励交易, 每条日志对应一个账户的一笔奖励
+func rewardsFromLogs(logs []types.CoinLogs) []common.RewarTx {
+	rewards := make([]common.RewarTx, 0)
+	for _, coinLogs := range logs {
+		for _, l := range coinLogs.Logs {
+			if len(l.Topics) != 3 || l.Topics[0] != RewardLogTopic {
+				continue
+			}
+			rewards = append(rewards, common.RewarTx{
+				CoinRange: coinLogs.CoinType,
+				CoinType:  coinLogs.CoinType,
+				Fromaddr:  l.Address,
+				To_Amont:  map[common.Address]*big.Int{common.BytesToAddress(l.Topics[1][:]): new(big.Int).SetBytes(l.Data)},
+				RewardTyp: byte(l.Topics[2].Big().Uint64()),
+			})
+		}
+	}
+	return rewards
+}
+
+// GetRewardRecord 由保存的系统日志返回区块发放的奖励明细, 本节点未执行过的区块返回nil
+func (bc *BlockChain) GetRewardRecord(hash common.Hash, number uint64) *BlockRewardRecord {
+	logs := ReadSystemLogs(bc.db, hash, number)
+	if logs == nil {
+		return nil
+	}
+	return newBlockRewardRecord(number, rewardsFromLogs(logs))
+}
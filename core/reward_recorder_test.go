@@ -0,0 +1,44 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+func Test_newBlockRewardRecord(t *testing.T) {
+	addrA := common.HexToAddress("0x01")
+	addrB := common.HexToAddress("0x02")
+
+	rewards := []common.RewarTx{
+		{CoinType: params.MAN_COIN, To_Amont: map[common.Address]*big.Int{addrA: big.NewInt(10)}, RewardTyp: common.RewardMinerType},
+		{CoinType: params.MAN_COIN, To_Amont: map[common.Address]*big.Int{addrA: big.NewInt(5), addrB: big.NewInt(7)}, RewardTyp: common.RewardValidatorType},
+		{CoinType: params.MAN_COIN, To_Amont: map[common.Address]*big.Int{addrB: big.NewInt(3)}, RewardTyp: common.RewardTxsType},
+		{CoinRange: "BTC", CoinType: "BTC", To_Amont: map[common.Address]*big.Int{addrB: big.NewInt(2)}, RewardTyp: common.RewardTxsType},
+		{CoinType: params.MAN_COIN, To_Amont: map[common.Address]*big.Int{addrA: big.NewInt(1)}, RewardTyp: common.RewardInterestType},
+	}
+	record := newBlockRewardRecord(100, rewards)
+	if record.Number != 100 || len(record.Rewards) != 2 {
+		t.Fatalf("奖励明细错误 %v", record)
+	}
+	a := record.Rewards[addrA]
+	if a.Mining.Int64() != 10 || a.Validation.Int64() != 5 || a.Interest.Int64() != 1 || len(a.TxFees) != 0 {
+		t.Errorf("账户A奖励明细错误 %v", a)
+	}
+	b := record.Rewards[addrB]
+	if b.Validation.Int64() != 7 || b.TxFees[params.MAN_COIN].Int64() != 3 || b.TxFees["BTC"].Int64() != 2 {
+		t.Errorf("账户B奖励明细错误 %v", b)
+	}
+
+	// 奖励明细可由保存的奖励日志还原
+	restored := newBlockRewardRecord(100, rewardsFromLogs(newRewardLogs(rewards)))
+	if !reflect.DeepEqual(restored, record) {
+		t.Errorf("由奖励日志还原的明细错误: have %v, want %v", restored, record)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+var (
+	ErrScheduledTxNotMature = errors.New("scheduled transaction is not mature")
+	ErrScheduledTxTooFar    = errors.New("scheduled transaction lock too far in the future")
+	ErrScheduledNonceKnown  = errors.New("scheduled transaction with the same nonce exists")
+	ErrScheduledTxFull      = errors.New("scheduled transaction slots are full")
+)
+
+// ScheduledTimeThreshold 预约交易的LockHeight小于该值时表示区块高度, 否则表示unix时间戳(秒)
+const ScheduledTimeThreshold = 500000000
+
+const (
+	scheduledMaxBlocks = 200000           // 交易池接受的预约交易最多延后的区块数
+	scheduledMaxDelay  = 7 * 24 * 60 * 60 // 交易池接受的预约交易最多延后的秒数
+)
+
+// IsScheduledTx 判断是否为预约交易
+func IsScheduledTx(tx types.SelfTransaction) bool {
+	return tx.GetMatrixType() == common.ExtraScheduledTxType
+}
+
+// IsScheduledTxMature 判断预约交易在指定高度和时间的区块中是否可以执行
+func IsScheduledTxMature(extra []types.Matrix_Extra, number uint64, time uint64) bool {
+	if len(extra) == 0 {
+		return true
+	}
+	lock := extra[0].LockHeight
+	if lock < ScheduledTimeThreshold {
+		return number >= lock
+	}
+	return time >= lock
+}
+
+// scheduledTxInHorizon 判断预约交易的到期高度或时间是否在交易池接受的范围内
+func scheduledTxInHorizon(extra []types.Matrix_Extra, number uint64, time uint64) bool {
+	if len(extra) == 0 {
+		return true
+	}
+	lock := extra[0].LockHeight
+	if lock < ScheduledTimeThreshold {
+		return lock <= number+scheduledMaxBlocks
+	}
+	return lock <= time+scheduledMaxDelay
+}
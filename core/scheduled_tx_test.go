@@ -0,0 +1,56 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+func TestIsScheduledTxMature(t *testing.T) {
+	extra := func(lock uint64) []types.Matrix_Extra {
+		return []types.Matrix_Extra{{TxType: common.ExtraScheduledTxType, LockHeight: lock}}
+	}
+	tests := []struct {
+		extra  []types.Matrix_Extra
+		number uint64
+		time   uint64
+		want   bool
+	}{
+		{nil, 1, 1, true},
+		{extra(100), 99, 1600000000, false},
+		{extra(100), 100, 0, true},
+		{extra(1600000000), 1000000, 1599999999, false},
+		{extra(1600000000), 1, 1600000000, true},
+	}
+	for i, tt := range tests {
+		if have := IsScheduledTxMature(tt.extra, tt.number, tt.time); have != tt.want {
+			t.Errorf("test %d: maturity mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+func TestScheduledTxInHorizon(t *testing.T) {
+	extra := func(lock uint64) []types.Matrix_Extra {
+		return []types.Matrix_Extra{{TxType: common.ExtraScheduledTxType, LockHeight: lock}}
+	}
+	tests := []struct {
+		lock   uint64
+		number uint64
+		time   uint64
+		want   bool
+	}{
+		{100 + scheduledMaxBlocks, 100, 1600000000, true},
+		{101 + scheduledMaxBlocks, 100, 1600000000, false},
+		{1600000000 + scheduledMaxDelay, 100, 1600000000, true},
+		{1600000001 + scheduledMaxDelay, 100, 1600000000, false},
+	}
+	for i, tt := range tests {
+		if have := scheduledTxInHorizon(extra(tt.lock), tt.number, tt.time); have != tt.want {
+			t.Errorf("test %d: horizon mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+var (
+	// schemaVersionKey 数据库键布局版本
+	schemaVersionKey = []byte("DatabaseSchemaVersion")
+
+	// schemaMigrationProgressKey 进行中的迁移及其检查点, 中断后从检查点继续
+	schemaMigrationProgressKey = []byte("SchemaMigrationProgress")
+)
+
+var (
+	// ErrSchemaTooNew 数据库由更新版本的程序写入
+	ErrSchemaTooNew = errors.New("database schema is newer than supported, upgrade gman")
+
+	// ErrSchemaMigrationInterrupted 迁移被中断, 进度已保存
+	ErrSchemaMigrationInterrupted = errors.New("schema migration interrupted, progress saved")
+)
+
+// SchemaMigration 一次数据库键布局迁移.
+// Step从检查点checkpoint开始执行一段迁移, 返回下一段的检查点, done为true表示迁移完成.
+// 每段之后检查点会写入数据库, 因此每段的写入必须可以安全地重复执行.
+// Auto为true的迁移足够快, 节点启动时自动执行, 其他迁移需通过gman db migrate离线执行
+type SchemaMigration struct {
+	Version uint64
+	Name    string
+	Auto    bool
+	Step    func(db mandb.Database, checkpoint []byte) (next []byte, done bool, err error)
+}
+
+// schemaMigrations 按版本升序排列的所有迁移, 新的键布局变更在末尾追加
+var schemaMigrations = []SchemaMigration{
+	{
+		Version: 1,
+		Name:    "schema version marker",
+		Auto:    true,
+		Step: func(db mandb.Database, checkpoint []byte) ([]byte, bool, error) {
+			return nil, true, nil
+		},
+	},
+}
+
+// schemaMigrationProgress 迁移进度
+type schemaMigrationProgress struct {
+	Version    uint64
+	Checkpoint []byte
+}
+
+// LatestSchemaVersion 返回当前程序的数据库键布局版本
+func LatestSchemaVersion() uint64 {
+	return schemaMigrations[len(schemaMigrations)-1].Version
+}
+
+// ReadSchemaVersion 读取数据库键布局版本, 未标记的数据库为0
+func ReadSchemaVersion(db mandb.Database) uint64 {
+	data, _ := db.Get(schemaVersionKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteSchemaVersion 写入数据库键布局版本
+func WriteSchemaVersion(db mandb.Putter, version uint64) error {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, version)
+	return db.Put(schemaVersionKey, enc)
+}
+
+// PendingSchemaMigrations 返回数据库尚未执行的迁移
+func PendingSchemaMigrations(db mandb.Database) []SchemaMigration {
+	version := ReadSchemaVersion(db)
+	for i, migration := range schemaMigrations {
+		if migration.Version > version {
+			return schemaMigrations[i:]
+		}
+	}
+	return nil
+}
+
+// CheckSchemaVersion 节点启动时检查数据库键布局版本, 自动执行可自动执行的迁移,
+// 仍有待执行的迁移时返回错误, 提示离线执行gman db migrate
+func CheckSchemaVersion(db mandb.Database) error {
+	if version := ReadSchemaVersion(db); version > LatestSchemaVersion() {
+		return fmt.Errorf("%v: have %d, supported %d", ErrSchemaTooNew, version, LatestSchemaVersion())
+	}
+	for _, migration := range PendingSchemaMigrations(db) {
+		if !migration.Auto {
+			break
+		}
+		if err := runSchemaMigration(db, migration, nil); err != nil {
+			return err
+		}
+	}
+	pending := PendingSchemaMigrations(db)
+	if len(pending) == 0 {
+		return nil
+	}
+	names := make([]string, len(pending))
+	for i, migration := range pending {
+		names[i] = fmt.Sprintf("%d (%s)", migration.Version, migration.Name)
+	}
+	return fmt.Errorf("database schema version %d requires migrations %s, run gman db migrate", ReadSchemaVersion(db), strings.Join(names, ", "))
+}
+
+// MigrateSchema 依次执行所有待执行的迁移, 返回完成的迁移数. interrupt返回true时在下一段前停止,
+// 已完成的段不会重复执行
+func MigrateSchema(db mandb.Database, interrupt func() bool) (int, error) {
+	if ReadSchemaVersion(db) > LatestSchemaVersion() {
+		return 0, ErrSchemaTooNew
+	}
+	applied := 0
+	for _, migration := range PendingSchemaMigrations(db) {
+		if err := runSchemaMigration(db, migration, interrupt); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+func runSchemaMigration(db mandb.Database, migration SchemaMigration, interrupt func() bool) error {
+	var progress schemaMigrationProgress
+	if data, _ := db.Get(schemaMigrationProgressKey); len(data) > 0 {
+		if err := rlp.DecodeBytes(data, &progress); err != nil {
+			return fmt.Errorf("invalid schema migration progress: %v", err)
+		}
+	}
+	checkpoint := []byte(nil)
+	if progress.Version == migration.Version {
+		checkpoint = progress.Checkpoint
+		log.Info("Resuming schema migration", "version", migration.Version, "name", migration.Name, "checkpoint", common.ToHex(checkpoint))
+	} else {
+		log.Info("Starting schema migration", "version", migration.Version, "name", migration.Name)
+	}
+	start := time.Now()
+	for {
+		if interrupt != nil && interrupt() {
+			return ErrSchemaMigrationInterrupted
+		}
+		next, done, err := migration.Step(db, checkpoint)
+		if err != nil {
+			return fmt.Errorf("schema migration %d (%s) failed: %v", migration.Version, migration.Name, err)
+		}
+		if done {
+			break
+		}
+		enc, err := rlp.EncodeToBytes(schemaMigrationProgress{Version: migration.Version, Checkpoint: next})
+		if err != nil {
+			return err
+		}
+		if err := db.Put(schemaMigrationProgressKey, enc); err != nil {
+			return err
+		}
+		checkpoint = next
+	}
+	if err := WriteSchemaVersion(db, migration.Version); err != nil {
+		return err
+	}
+	if err := db.Delete(schemaMigrationProgressKey); err != nil {
+		return err
+	}
+	log.Info("Schema migration completed", "version", migration.Version, "name", migration.Name, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
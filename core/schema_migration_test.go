@@ -0,0 +1,66 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+)
+
+func TestSchemaMigrationResume(t *testing.T) {
+	defer func(saved []SchemaMigration) { schemaMigrations = saved }(schemaMigrations)
+
+	// A migration moving three keys, one per step
+	var steps []byte
+	schemaMigrations = append(schemaMigrations[:1:1], SchemaMigration{
+		Version: 2,
+		Name:    "test",
+		Step: func(db mandb.Database, checkpoint []byte) ([]byte, bool, error) {
+			next := byte(0)
+			if len(checkpoint) > 0 {
+				next = checkpoint[0] + 1
+			}
+			if next == 3 {
+				return nil, true, nil
+			}
+			steps = append(steps, next)
+			return []byte{next}, false, db.Put([]byte{'m', next}, []byte{next})
+		},
+	})
+	db := mandb.NewMemDatabase()
+
+	// The marker migration runs at startup, the offline one is reported
+	if err := CheckSchemaVersion(db); err == nil {
+		t.Fatalf("pending offline migration not reported")
+	}
+	if version := ReadSchemaVersion(db); version != 1 {
+		t.Fatalf("startup migration not applied: version %d", version)
+	}
+
+	// Interrupt after the first step, then resume
+	calls := 0
+	interrupt := func() bool { calls++; return calls > 1 }
+	if _, err := MigrateSchema(db, interrupt); err != ErrSchemaMigrationInterrupted {
+		t.Fatalf("migration not interrupted: %v", err)
+	}
+	if applied, err := MigrateSchema(db, nil); err != nil || applied != 1 {
+		t.Fatalf("resumed migration failed: applied %d, err %v", applied, err)
+	}
+	if string(steps) != string([]byte{0, 1, 2}) {
+		t.Errorf("migration steps mismatch: have %v, want [0 1 2]", steps)
+	}
+	if version := ReadSchemaVersion(db); version != 2 {
+		t.Errorf("schema version mismatch: have %d, want 2", version)
+	}
+	if err := CheckSchemaVersion(db); err != nil {
+		t.Errorf("migrated database rejected: %v", err)
+	}
+
+	WriteSchemaVersion(db, 3)
+	if err := CheckSchemaVersion(db); err == nil {
+		t.Errorf("newer schema accepted")
+	}
+}
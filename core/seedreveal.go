@@ -0,0 +1,158 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+)
+
+// ErrInvalidSeedPayload is returned when a seed commit or reveal payload is not
+// 32 bytes long.
+var ErrInvalidSeedPayload = errors.New("invalid seed commit/reveal payload")
+
+// SeedCommitment 计算抵押账户对随机种子secret的承诺, 与账户绑定以防他人复制承诺
+func SeedCommitment(account common.Address, secret []byte) common.Hash {
+	return crypto.Keccak256Hash(secret, account.Bytes())
+}
+
+// checkSeedPayload 承诺及揭示交易的内容均为32字节
+func checkSeedPayload(payload []byte) error {
+	if len(payload) != common.HashLength {
+		return ErrInvalidSeedPayload
+	}
+	return nil
+}
+
+// ProcessSeedCommitReveal 在广播区块后的首个区块执行, 校验上个广播区块中的揭示交易与再上一个广播周期的承诺,
+// 正确揭示的种子混入累计种子, 提交承诺后未揭示或揭示错误的账户按配置比例惩罚抵押, 并记录本周期新的承诺
+func (bc *BlockChain) ProcessSeedCommitReveal(version string, state *state.StateDBManage, header *types.Header) ([]mc.SlashEvent, error) {
+	if manversion.VersionCmp(version, manversion.VersionAIMine) < 0 {
+		return nil, nil
+	}
+	if nil == state {
+		return nil, ErrStatePtrIsNil
+	}
+	if nil == header {
+		return nil, ErrHeaderPtrIsNil
+	}
+
+	cfg, err := matrixstate.GetSeedCommitRevealCfg(state)
+	if err != nil {
+		log.Error(ModuleName, "获取随机种子提交-揭示配置失败", err)
+		return nil, err
+	}
+	if !cfg.Switcher {
+		return nil, nil
+	}
+
+	bcInterval, err := manparams.GetBCIntervalInfoByHash(header.ParentHash)
+	if err != nil {
+		log.Error(ModuleName, "获取广播周期失败", err)
+		return nil, err
+	}
+	number := header.Number.Uint64()
+	lastBroadcast := bcInterval.GetLastBroadcastNumber()
+	if number < bcInterval.GetBroadcastInterval() || number != lastBroadcast+1 {
+		return nil, nil
+	}
+	crState, err := matrixstate.GetSeedCommitRevealState(state)
+	if err != nil {
+		return nil, err
+	}
+	if crState.Number >= lastBroadcast {
+		return nil, nil
+	}
+
+	lastStateRoot, _, err := bc.getPreRoot(header, bcInterval)
+	if err != nil {
+		return nil, err
+	}
+	reveals := bc.getSeedPayloads(lastStateRoot, header.ParentHash, mc.SeedReveal)
+	commits := bc.getSeedPayloads(lastStateRoot, header.ParentHash, mc.SeedCommit)
+
+	seed, missed := verifySeedReveals(crState.Seed, crState.Commits, reveals)
+	events := make([]mc.SlashEvent, 0, len(missed))
+	for _, account := range missed {
+		penalty := slashDepositByRate(state, account, cfg.SlashRate, func() *big.Int {
+			return bc.getHeartbeatDeposit(header.ParentHash, account)
+		})
+		log.Info(ModuleName, "随机种子未揭示惩罚账户", account.Hex(), "惩罚金额", penalty, "高度", number)
+		events = append(events, mc.SlashEvent{
+			Number:  number,
+			Account: account,
+			Reason:  mc.SlashReasonSeedReveal,
+			Penalty: penalty,
+		})
+	}
+
+	newState := &mc.SeedCommitRevealState{Number: lastBroadcast, Commits: make([]mc.SeedCommitment, 0, len(commits)), Seed: seed}
+	for account, commitment := range commits {
+		newState.Commits = append(newState.Commits, mc.SeedCommitment{Account: account, Commitment: common.BytesToHash(commitment)})
+	}
+	sort.Slice(newState.Commits, func(i, j int) bool {
+		return bytes.Compare(newState.Commits[i].Account.Bytes(), newState.Commits[j].Account.Bytes()) < 0
+	})
+	if err := matrixstate.SetSeedCommitRevealState(state, newState); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// getSeedPayloads 获取广播区块中的承诺或揭示交易, 按发送者的抵押账户(A0)索引, 丢弃格式错误的内容
+// 同一抵押账户的多个发送账户按地址顺序取第一个
+func (bc *BlockChain) getSeedPayloads(root []common.CoinRoot, parentHash common.Hash, txtype string) map[common.Address][]byte {
+	result := make(map[common.Address][]byte)
+	txs, err := GetBroadcastTxMap(bc, root, txtype)
+	if err != nil {
+		log.Debug(ModuleName, "获取随机种子交易为空", txtype)
+		return result
+	}
+	senders := make([]common.Address, 0, len(txs))
+	for from := range txs {
+		senders = append(senders, from)
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		return bytes.Compare(senders[i].Bytes(), senders[j].Bytes()) < 0
+	})
+	for _, from := range senders {
+		if checkSeedPayload(txs[from]) != nil {
+			continue
+		}
+		account, _, err := bc.GetA0AccountFromAnyAccount(from, parentHash)
+		if err != nil {
+			continue
+		}
+		if _, ok := result[account]; !ok {
+			result[account] = txs[from]
+		}
+	}
+	return result
+}
+
+// verifySeedReveals 按承诺的顺序校验揭示的种子, 正确的种子依次混入累计种子, 返回新的累计种子及未正确揭示的账户
+func verifySeedReveals(seed common.Hash, commits []mc.SeedCommitment, reveals map[common.Address][]byte) (common.Hash, []common.Address) {
+	missed := make([]common.Address, 0)
+	for _, commit := range commits {
+		secret, ok := reveals[commit.Account]
+		if !ok || SeedCommitment(commit.Account, secret) != commit.Commitment {
+			missed = append(missed, commit.Account)
+			continue
+		}
+		seed = crypto.Keccak256Hash(seed.Bytes(), secret)
+	}
+	return seed, missed
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func TestVerifySeedReveals(t *testing.T) {
+	a := common.HexToAddress("0x01")
+	b := common.HexToAddress("0x02")
+	c := common.HexToAddress("0x03")
+	secretA := common.HexToHash("0xaa").Bytes()
+	secretB := common.HexToHash("0xbb").Bytes()
+	secretC := common.HexToHash("0xcc").Bytes()
+
+	commits := []mc.SeedCommitment{
+		{Account: a, Commitment: SeedCommitment(a, secretA)},
+		{Account: b, Commitment: SeedCommitment(b, secretB)},
+		{Account: c, Commitment: SeedCommitment(c, secretC)},
+	}
+	// b揭示了其他账户的种子, c未揭示
+	reveals := map[common.Address][]byte{a: secretA, b: secretC}
+
+	prev := common.HexToHash("0x1234")
+	seed, missed := verifySeedReveals(prev, commits, reveals)
+	if len(missed) != 2 || missed[0] != b || missed[1] != c {
+		t.Fatalf("未揭示账户错误 %v", missed)
+	}
+	if want := crypto.Keccak256Hash(prev.Bytes(), secretA); seed != want {
+		t.Errorf("累计种子错误 %x, 期望 %x", seed, want)
+	}
+
+	// 承诺与账户绑定, 复制他人的承诺无法揭示
+	if SeedCommitment(a, secretA) == SeedCommitment(b, secretA) {
+		t.Errorf("不同账户的承诺不应相同")
+	}
+	if _, missed := verifySeedReveals(prev, []mc.SeedCommitment{{Account: b, Commitment: commits[0].Commitment}}, map[common.Address][]byte{b: secretA}); len(missed) != 1 {
+		t.Errorf("复制的承诺不应揭示成功")
+	}
+
+	if checkSeedPayload(secretA) != nil || checkSeedPayload(secretA[1:]) == nil {
+		t.Errorf("种子交易内容校验错误")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
+	"github.com/MatrixAINetwork/go-matrix/log"
+)
+
+// 惩罚比例的基数(万分比)
+const slashRateBase = 10000
+
+// slashDepositByRate 按比例惩罚账户的抵押, 返回惩罚金额.
+// 新版抵押按仓位惩罚, 旧版抵押按v1Deposit返回的账户总抵押惩罚
+func slashDepositByRate(st vm.StateDBManager, account common.Address, rate uint64, v1Deposit func() *big.Int) *big.Int {
+	penalty := new(big.Int)
+	slash, err := depoistInfo.GetSlash_v2(st, account)
+	if err != nil {
+		log.Error(ModuleName, "获取账户惩罚失败", err, "账户", account.Hex())
+		return penalty
+	}
+
+	positional := false
+	for i, position := range slash.CalcDeposit {
+		if nil == position.DepositAmount {
+			continue
+		}
+		positional = true
+		amount := calcSlashPenalty(position.DepositAmount, rate)
+		if nil == position.OperAmount {
+			position.OperAmount = new(big.Int)
+		}
+		slash.CalcDeposit[i].OperAmount = new(big.Int).Add(position.OperAmount, amount)
+		penalty.Add(penalty, amount)
+	}
+	if positional {
+		if err := depoistInfo.AddSlash_v2(st, account, slash); err != nil {
+			log.Error(ModuleName, "设置账户惩罚失败", err, "账户", account.Hex())
+			return new(big.Int)
+		}
+		return penalty
+	}
+
+	penalty = calcSlashPenalty(v1Deposit(), rate)
+	if err := depoistInfo.AddSlash(st, account, penalty); err != nil {
+		log.Error(ModuleName, "设置账户惩罚失败", err, "账户", account.Hex())
+		return new(big.Int)
+	}
+	return penalty
+}
+
+func calcSlashPenalty(deposit *big.Int, rate uint64) *big.Int {
+	if nil == deposit || deposit.Sign() <= 0 {
+		return new(big.Int)
+	}
+	penalty := new(big.Int).Mul(deposit, new(big.Int).SetUint64(rate))
+	return penalty.Div(penalty, big.NewInt(slashRateBase))
+}
@@ -65,10 +65,18 @@ type Trie interface {
 // intermediate trie-node memory pool between the low level storage layer and the
 // high level trie abstraction.
 func NewDatabase(db mandb.Database) Database {
+	return NewDatabaseWithCache(db, 0)
+}
+
+// NewDatabaseWithCache creates a backing store for state. The returned database
+// is safe for concurrent use and retains both a few recent expanded trie nodes in
+// memory, as well as a clean node cache of at most cache megabytes in front of
+// the disk database.
+func NewDatabaseWithCache(db mandb.Database, cache int) Database {
 	csc, _ := lru.New(codeSizeCacheSize)
 	past, _ := lru.New(PastTriesSize)
 	return &cachingDB{
-		db:            trie.NewDatabase(db),
+		db:            trie.NewDatabaseWithCache(db, cache),
 		pastTries:     past,
 		codeSizeCache: csc,
 	}
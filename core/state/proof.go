@@ -0,0 +1,236 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+	"github.com/MatrixAINetwork/go-matrix/trie"
+)
+
+var (
+	// ErrNoMatrixCoinRoot is returned when proving or verifying matrix state data
+	// against roots lacking the MAN coin.
+	ErrNoMatrixCoinRoot = errors.New("no MAN coin root")
+
+	// ErrShardRootsMismatch is returned when the shard roots of a proof don't hash
+	// to the coin root they are verified against.
+	ErrShardRootsMismatch = errors.New("shard roots mismatch")
+
+	// ErrNoCoinRoot is returned when proving or verifying an account of a coin
+	// the roots lack.
+	ErrNoCoinRoot = errors.New("no coin root")
+)
+
+// MatrixDataProof is a Merkle proof of a matrix state entry. The matrix state is
+// kept in the first address range shard of the MAN coin, so the proof carries the
+// shard roots committed to by the coin root and the trie nodes on the path to the
+// entry in the first shard.
+type MatrixDataProof struct {
+	ShardRoots []common.Hash
+	Nodes      [][]byte
+}
+
+// ProveMatrixData constructs a proof of the matrix state entry stored under the
+// given hash. The proof is also valid for absent entries.
+func (shard *StateDBManage) ProveMatrixData(hash common.Hash) (*MatrixDataProof, error) {
+	root, ok := matrixCoinRoot(shard.coinRoot)
+	if !ok {
+		return nil, ErrNoMatrixCoinRoot
+	}
+	shardRoots, err := GetShardRoots(shard.mdb, root)
+	if err != nil {
+		return nil, err
+	}
+	for _, cm := range shard.shardings {
+		if cm.Cointyp != params.MAN_COIN {
+			continue
+		}
+		nodes, err := proveTrie(cm.Rmanage[0].State.trie, hash[:])
+		if err != nil {
+			return nil, err
+		}
+		return &MatrixDataProof{ShardRoots: shardRoots, Nodes: nodes}, nil
+	}
+	return nil, ErrNoMatrixCoinRoot
+}
+
+// AccountProof is a Merkle proof of an account of a coin, and of some entries
+// of its storage. The account is kept in the address range shard of the coin
+// selected by the first byte of its address, so the proof carries the shard
+// roots committed to by the coin root and the trie nodes on the path to the
+// account in its shard.
+type AccountProof struct {
+	ShardRoots []common.Hash
+	Nodes      [][]byte
+	Storage    []StorageProof
+}
+
+// StorageProof is a Merkle proof of a storage entry against the storage root
+// of its account.
+type StorageProof struct {
+	Key   common.Hash
+	Nodes [][]byte
+}
+
+// ProveAccount constructs a proof of the account of the coin and of the given
+// storage entries. The proof is also valid for absent accounts and entries.
+func (shard *StateDBManage) ProveAccount(cointyp string, addr common.Address, keys []common.Hash) (*AccountProof, error) {
+	if cointyp == "" {
+		cointyp = params.MAN_COIN
+	}
+	root, ok := coinRoot(shard.coinRoot, cointyp)
+	if !ok {
+		return nil, ErrNoCoinRoot
+	}
+	shardRoots, err := GetShardRoots(shard.mdb, root)
+	if err != nil {
+		return nil, err
+	}
+	sd, err := shard.GetStateDb(cointyp, addr)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := proveTrie(sd.trie, addr[:])
+	if err != nil {
+		return nil, err
+	}
+	proof := &AccountProof{ShardRoots: shardRoots, Nodes: nodes, Storage: make([]StorageProof, 0, len(keys))}
+
+	storage := sd.StorageTrie(addr)
+	for _, key := range keys {
+		entry := StorageProof{Key: key}
+		if storage != nil {
+			if entry.Nodes, err = proveTrie(storage, key[:]); err != nil {
+				return nil, err
+			}
+		}
+		proof.Storage = append(proof.Storage, entry)
+	}
+	return proof, nil
+}
+
+// VerifyAccountProof checks an account proof against the coin roots of a block
+// header and returns the proven account, nil if the account does not exist.
+func VerifyAccountProof(roots []common.CoinRoot, cointyp string, addr common.Address, proof *AccountProof) (*Account, error) {
+	if cointyp == "" {
+		cointyp = params.MAN_COIN
+	}
+	root, ok := coinRoot(roots, cointyp)
+	if !ok {
+		return nil, ErrNoCoinRoot
+	}
+	if proof == nil || len(proof.ShardRoots) <= int(addr[0]) {
+		return nil, ErrShardRootsMismatch
+	}
+	if _, h := types.RlpEncodeAndHash(proof.ShardRoots); h != root {
+		return nil, ErrShardRootsMismatch
+	}
+	val, err := verifyTrieProof(proof.ShardRoots[addr[0]], addr[:], proof.Nodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account proof: %v", err)
+	}
+	if len(val) == 0 {
+		return nil, nil
+	}
+	account := new(Account)
+	if err := rlp.DecodeBytes(val, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// VerifyStorageProof checks a storage proof against the storage root of the
+// proven account and returns the value of the entry, nil if it does not exist.
+func VerifyStorageProof(storageRoot common.Hash, proof *StorageProof) ([]byte, error) {
+	val, err := verifyTrieProof(storageRoot, proof.Key[:], proof.Nodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage proof: %v", err)
+	}
+	if len(val) == 0 {
+		return nil, nil
+	}
+	_, content, _, err := rlp.Split(val)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// proveTrie collects the nodes on the path to a key of a secure trie.
+func proveTrie(tr Trie, key []byte) ([][]byte, error) {
+	proofDb := mandb.NewMemDatabase()
+	if err := tr.Prove(crypto.Keccak256(key), 0, proofDb); err != nil {
+		return nil, err
+	}
+	nodes := make([][]byte, 0)
+	for _, hash := range proofDb.Keys() {
+		node, _ := proofDb.Get(hash)
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// verifyTrieProof checks the nodes on the path to a key of a secure trie.
+func verifyTrieProof(root common.Hash, key []byte, nodes [][]byte) ([]byte, error) {
+	// Empty tries have no nodes to prove the absence with
+	if root == (common.Hash{}) || root == types.EmptyRootHash {
+		if len(nodes) != 0 {
+			return nil, errors.New("unexpected nodes for empty trie")
+		}
+		return nil, nil
+	}
+	proofDb := mandb.NewMemDatabase()
+	for _, node := range nodes {
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	val, _, err := trie.VerifyProof(root, crypto.Keccak256(key), proofDb)
+	return val, err
+}
+
+// VerifyMatrixDataProof checks a matrix state proof against the coin roots of a
+// block header and returns the proven value, nil if the entry does not exist.
+func VerifyMatrixDataProof(roots []common.CoinRoot, hash common.Hash, proof *MatrixDataProof) ([]byte, error) {
+	root, ok := matrixCoinRoot(roots)
+	if !ok {
+		return nil, ErrNoMatrixCoinRoot
+	}
+	if proof == nil || len(proof.ShardRoots) == 0 {
+		return nil, ErrShardRootsMismatch
+	}
+	if _, h := types.RlpEncodeAndHash(proof.ShardRoots); h != root {
+		return nil, ErrShardRootsMismatch
+	}
+	val, err := verifyTrieProof(proof.ShardRoots[0], hash[:], proof.Nodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid matrix state proof: %v", err)
+	}
+	if bytes.HasPrefix(val, []byte("MAN-")) {
+		val = val[4:]
+	}
+	return val, nil
+}
+
+// matrixCoinRoot finds the state root of the MAN coin.
+func matrixCoinRoot(roots []common.CoinRoot) (common.Hash, bool) {
+	return coinRoot(roots, params.MAN_COIN)
+}
+
+// coinRoot finds the state root of a coin.
+func coinRoot(roots []common.CoinRoot, cointyp string) (common.Hash, bool) {
+	for _, cr := range roots {
+		if cr.Cointyp == cointyp {
+			return cr.Root, true
+		}
+	}
+	return common.Hash{}, false
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+// Tests that matrix state entries can be proven and verified against the coin
+// roots of a committed state, and that tampered proofs are rejected.
+func TestMatrixDataProof(t *testing.T) {
+	diskdb := mandb.NewMemDatabase()
+	sdb := NewDatabase(diskdb)
+
+	st, _ := NewStateDBManage(nil, diskdb, sdb)
+	key, other := types.RlpHash("ms_key"), types.RlpHash("ms_other")
+	st.SetMatrixData(key, []byte("value"))
+	st.SetMatrixData(other, []byte("other value"))
+	roots, _, err := st.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	st, _ = NewStateDBManage(roots, diskdb, sdb)
+
+	proof, err := st.ProveMatrixData(key)
+	if err != nil {
+		t.Fatalf("failed to prove entry: %v", err)
+	}
+	val, err := VerifyMatrixDataProof(roots, key, proof)
+	if err != nil {
+		t.Fatalf("failed to verify proof: %v", err)
+	}
+	if !bytes.Equal(val, []byte("value")) {
+		t.Fatalf("proven value mismatch: have %q, want %q", val, "value")
+	}
+	// Absent entries are proven with a nil value
+	missing := types.RlpHash("ms_missing")
+	proof, err = st.ProveMatrixData(missing)
+	if err != nil {
+		t.Fatalf("failed to prove absent entry: %v", err)
+	}
+	if val, err := VerifyMatrixDataProof(roots, missing, proof); err != nil || val != nil {
+		t.Fatalf("absent entry: have %q, %v, want nil", val, err)
+	}
+	// Tampered shard roots must be rejected
+	proof, _ = st.ProveMatrixData(key)
+	proof.ShardRoots[0] = common.Hash{0x01}
+	if _, err := VerifyMatrixDataProof(roots, key, proof); err != ErrShardRootsMismatch {
+		t.Fatalf("tampered shard roots: have %v, want %v", err, ErrShardRootsMismatch)
+	}
+}
+
+// Tests that accounts and their storage can be proven and verified against the
+// coin roots of a committed state.
+func TestAccountProof(t *testing.T) {
+	diskdb := mandb.NewMemDatabase()
+	sdb := NewDatabase(diskdb)
+
+	st, _ := NewStateDBManage(nil, diskdb, sdb)
+	addr := common.HexToAddress("0x1234")
+	slot, value := common.HexToHash("0x01"), common.HexToHash("0xff")
+	st.AddBalance(params.MAN_COIN, common.MainAccount, addr, big.NewInt(42))
+	st.SetNonce(params.MAN_COIN, addr, 3)
+	st.SetState(params.MAN_COIN, addr, slot, value)
+	roots, _, err := st.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	st, _ = NewStateDBManage(roots, diskdb, sdb)
+
+	missingSlot := common.HexToHash("0x02")
+	proof, err := st.ProveAccount(params.MAN_COIN, addr, []common.Hash{slot, missingSlot})
+	if err != nil {
+		t.Fatalf("failed to prove account: %v", err)
+	}
+	account, err := VerifyAccountProof(roots, params.MAN_COIN, addr, proof)
+	if err != nil {
+		t.Fatalf("failed to verify account proof: %v", err)
+	}
+	if account == nil || account.Nonce != 3 {
+		t.Fatalf("proven account mismatch: %+v", account)
+	}
+	val, err := VerifyStorageProof(account.Root, &proof.Storage[0])
+	if err != nil {
+		t.Fatalf("failed to verify storage proof: %v", err)
+	}
+	if common.BytesToHash(val) != value {
+		t.Fatalf("proven storage mismatch: have %x, want %x", val, value)
+	}
+	if val, err := VerifyStorageProof(account.Root, &proof.Storage[1]); err != nil || val != nil {
+		t.Fatalf("absent storage entry: have %x, %v, want nil", val, err)
+	}
+	// Absent accounts are proven with a nil account
+	missing := common.HexToAddress("0x5678")
+	proof, err = st.ProveAccount(params.MAN_COIN, missing, nil)
+	if err != nil {
+		t.Fatalf("failed to prove absent account: %v", err)
+	}
+	if account, err := VerifyAccountProof(roots, params.MAN_COIN, missing, proof); err != nil || account != nil {
+		t.Fatalf("absent account: have %+v, %v, want nil", account, err)
+	}
+}
@@ -35,6 +35,7 @@ type StateDBManage struct {
 	shardings    []*CoinManage
 	coinRoot     []common.CoinRoot
 	retcoinRoot  []common.CoinRoot
+	systemLogs   []types.CoinLogs // 区块执行产生的奖励和惩罚日志, 随状态一起写入数据库
 }
 type CoinTrie struct {
 	Coin     string
@@ -103,6 +104,21 @@ func (shard *StateDBManage) MakeStatedb(cointyp string, isCheck bool) {
 	}
 	shard.addShardings(cointyp)
 }
+
+// GetShardRoots resolves a coin state root into the state trie roots of its
+// address range shards.
+func GetShardRoots(mdb mandb.Database, root common.Hash) ([]common.Hash, error) {
+	blob, err := mdb.Get(root[:])
+	if err != nil {
+		return nil, err
+	}
+	var hashs []common.Hash
+	if err := rlp.DecodeBytes(blob, &hashs); err != nil {
+		return nil, err
+	}
+	return hashs, nil
+}
+
 func (shard *StateDBManage) addShardings(cointyp string) {
 	//获取指定的币种root
 	for _, cr := range shard.coinRoot {
@@ -227,6 +243,16 @@ func (shard *StateDBManage) Logs() []types.CoinLogs {
 	return logs
 }
 
+// AddSystemLogs 追加区块执行产生的系统日志
+func (shard *StateDBManage) AddSystemLogs(logs []types.CoinLogs) {
+	shard.systemLogs = append(shard.systemLogs, logs...)
+}
+
+// SystemLogs 返回区块执行产生的系统日志
+func (shard *StateDBManage) SystemLogs() []types.CoinLogs {
+	return shard.systemLogs
+}
+
 // AddPreimage records a SHA3 preimage seen by the VM.
 func (shard *StateDBManage) AddPreimage(cointype string, addr common.Address, hash common.Hash, preimage []byte) {
 
@@ -606,6 +632,7 @@ func (shard *StateDBManage) Copy() *StateDBManage {
 		shardings: make([]*CoinManage, 0),
 		coinRoot:  make([]common.CoinRoot, 0),
 	}
+	state.systemLogs = append(state.systemLogs, shard.systemLogs...)
 	for _, cm := range shard.shardings {
 		rms := make([]*RangeManage, 0, 256)
 		for _, rm := range cm.Rmanage {
@@ -630,6 +657,20 @@ func (shard *StateDBManage) Copy() *StateDBManage {
 
 }
 
+// DirtyAccounts 返回各币种日志(journal)中记录的被修改账户.
+// 拷贝出来的状态日志为空, 因此可用来获取在拷贝上执行交易的写集合
+func (shard *StateDBManage) DirtyAccounts() map[string][]common.Address {
+	dirties := make(map[string][]common.Address)
+	for _, cm := range shard.shardings {
+		for _, rm := range cm.Rmanage {
+			for addr := range rm.State.journal.dirties {
+				dirties[cm.Cointyp] = append(dirties[cm.Cointyp], addr)
+			}
+		}
+	}
+	return dirties
+}
+
 //var gss = make([]int,256)
 // Snapshot returns an identifier for the current revision of the state.
 func (shard *StateDBManage) Snapshot(cointyp string) []int {
@@ -371,6 +371,16 @@ func (c *stateObject) ReturnGas(gas *big.Int) {}
 
 func (self *stateObject) deepCopy(db *StateDB) *stateObject {
 	stateObject := newObject(db, self.address, self.data)
+	// 余额是切片, setBalance原地修改, 不能与原对象共用
+	if self.data.Balance != nil {
+		stateObject.data.Balance = make(common.BalanceType, len(self.data.Balance))
+		for i, tAccount := range self.data.Balance {
+			stateObject.data.Balance[i].AccountType = tAccount.AccountType
+			if tAccount.Balance != nil {
+				stateObject.data.Balance[i].Balance = new(big.Int).Set(tAccount.Balance)
+			}
+		}
+	}
 	if self.trie != nil {
 		stateObject.trie = db.db.CopyTrie(self.trie)
 	}
@@ -413,6 +413,24 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 
 // TestCopyOfCopy tests that modified objects are carried over to the copy, and the copy of the copy.
 // See https://github.com/MatrixAINetwork/go-matrix/pull/15225#issuecomment-380191512
+// Tests that modifying the balances of a copied dirty account doesn't leak into
+// the original state.
+func TestCopyBalanceIndependent(t *testing.T) {
+	orig, _ := New(common.Hash{}, NewDatabase(mandb.NewMemDatabase()))
+	addr := common.HexToAddress("aaaa")
+	orig.AddBalance(common.MainAccount, addr, big.NewInt(42))
+
+	copy := orig.Copy()
+	copy.AddBalance(common.MainAccount, addr, big.NewInt(1))
+
+	if have := orig.GetBalanceByType(addr, common.MainAccount); have.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("original balance mismatch: have %v, want 42", have)
+	}
+	if have := copy.GetBalanceByType(addr, common.MainAccount); have.Cmp(big.NewInt(43)) != 0 {
+		t.Fatalf("copied balance mismatch: have %v, want 43", have)
+	}
+}
+
 func TestCopyOfCopy(t *testing.T) {
 	//sdb, _ := New(common.Hash{}, NewDatabase(mandb.NewMemDatabase()))
 	//addr := common.HexToAddress("aaaa")
@@ -331,6 +331,8 @@ func (p *StateProcessor) checkCoinShard(coinShard []common.CoinSharding) []commo
 	}
 	return coinShard
 }
+
+// myCoinsort 币种执行顺序: 先MAN, 再按名称排序的其他币种
 func myCoinsort(coins []string) []string {
 	coinsnoman := make([]string, 0, len(coins))
 	retCoins := make([]string, 0, len(coins))
@@ -340,11 +342,60 @@ func myCoinsort(coins []string) []string {
 		}
 		coinsnoman = append(coinsnoman, coinname)
 	}
+	sort.Strings(coinsnoman)
 	retCoins = append(retCoins, params.MAN_COIN)
 	retCoins = append(retCoins, coinsnoman...)
 	return retCoins
 }
 
+// IsRewardTx 是否奖励交易, 奖励交易在所有普通交易之后执行
+func IsRewardTx(tx types.SelfTransaction) bool {
+	switch tx.GetMatrixType() {
+	case common.ExtraUnGasMinerTxType, common.ExtraUnGasValidatorTxType, common.ExtraUnGasInterestTxType,
+		common.ExtraUnGasTxsType, common.ExtraUnGasLotteryTxType:
+		return true
+	}
+	return false
+}
+
+// splitBlockTxs 按币种将区块交易分为普通交易和奖励交易
+func splitBlockTxs(block *types.Block) (txsmap map[string]types.SelfTransactions, rewardTxmap map[string]types.SelfTransactions) {
+	txsmap = make(map[string]types.SelfTransactions)
+	rewardTxmap = make(map[string]types.SelfTransactions)
+	for _, cb := range block.Currencies() {
+		for _, tx := range cb.Transactions.GetTransactions() {
+			if IsRewardTx(tx) {
+				rewardTxmap[cb.CurrencyName] = append(rewardTxmap[cb.CurrencyName], tx)
+			} else {
+				txsmap[cb.CurrencyName] = append(txsmap[cb.CurrencyName], tx)
+			}
+		}
+	}
+	return txsmap, rewardTxmap
+}
+
+// mapCoins 返回交易表中的币种
+func mapCoins(txsmap map[string]types.SelfTransactions) []string {
+	coins := make([]string, 0, len(txsmap))
+	for coin := range txsmap {
+		coins = append(coins, coin)
+	}
+	return coins
+}
+
+// ExecutionOrderTxs 按ProcessTxs的执行顺序返回区块交易: 先按币种顺序执行普通交易, 再按币种顺序执行奖励交易
+func ExecutionOrderTxs(block *types.Block) []types.SelfTransaction {
+	txsmap, rewardTxmap := splitBlockTxs(block)
+	txs := make([]types.SelfTransaction, 0)
+	for _, coinname := range myCoinsort(mapCoins(txsmap)) {
+		txs = append(txs, txsmap[coinname]...)
+	}
+	for _, coinname := range myCoinsort(mapCoins(rewardTxmap)) {
+		txs = append(txs, rewardTxmap[coinname]...)
+	}
+	return txs
+}
+
 // Process processes the state changes according to the Matrix rules by running
 // the transaction messages using the statedb and applying any rewards to both
 // the processor (coinbase) and any included uncles.
@@ -372,38 +423,22 @@ func (p *StateProcessor) ProcessTxs(block *types.Block, statedb *state.StateDBMa
 	// Iterate over and process the individual transactions
 	statedb.UpdateTxForBtree(uint32(block.Time().Uint64()))
 	statedb.UpdateTxForBtreeBytime(uint32(block.Time().Uint64()))
+	if err := ReleaseRevocableTxs(statedb, block.NumberU64()); err != nil {
+		log.Error("state_processor", "ReleaseRevocableTxs err", err)
+		return nil, 0, err
+	}
 	txs := make([]types.SelfTransaction, 0)
 	var txcount int
 	tmpMaptx := make(map[string]types.SelfTransactions)
 	tmpMapre := make(map[string]types.Receipts)
-	tMaptxs := make(map[string]types.SelfTransactions)
-	for _, cb := range block.Currencies() {
-		alltxs := tMaptxs[cb.CurrencyName]
-		alltxs = append(alltxs, cb.Transactions.GetTransactions()...)
-		tMaptxs[cb.CurrencyName] = alltxs
-	}
 	var waitG = &sync.WaitGroup{}
 	maxProcs := runtime.NumCPU() //获取cpu个数
 	if maxProcs >= 2 {
 		runtime.GOMAXPROCS(maxProcs - 1) //限制同时运行的goroutines数量
 	}
 
-	rewardTxmap := make(map[string]types.SelfTransactions) //存放所有奖励交易
-	txsmap := make(map[string]types.SelfTransactions)      //存放所有普通交易
-	for coinname, txs := range tMaptxs {
-		for _, tx := range txs {
-			if tx.GetMatrixType() == common.ExtraUnGasMinerTxType || tx.GetMatrixType() == common.ExtraUnGasValidatorTxType ||
-				tx.GetMatrixType() == common.ExtraUnGasInterestTxType || tx.GetMatrixType() == common.ExtraUnGasTxsType || tx.GetMatrixType() == common.ExtraUnGasLotteryTxType {
-				rewardtxs := rewardTxmap[coinname]
-				rewardtxs = append(rewardtxs, tx)
-				rewardTxmap[coinname] = rewardtxs
-				continue
-			}
-			tmptxs := txsmap[coinname]
-			tmptxs = append(tmptxs, tx)
-			txsmap[coinname] = tmptxs
-		}
-	}
+	//txsmap存放所有普通交易, rewardTxmap存放所有奖励交易
+	txsmap, rewardTxmap := splitBlockTxs(block)
 
 	for _, txs := range txsmap {
 		for _, tx := range txs {
@@ -417,50 +452,66 @@ func (p *StateProcessor) ProcessTxs(block *types.Block, statedb *state.StateDBMa
 	waitG.Wait()
 	from := make(map[string][]common.Address)
 	isvadter := p.isValidater(header.ParentHash)
-	coins := make([]string, 0)
-	coinsnoman := make([]string, 0)
-	for coin, _ := range txsmap {
-		if coin == params.MAN_COIN {
-			continue
-		}
-		coinsnoman = append(coinsnoman, coin)
-	}
-	sort.Strings(coinsnoman)
-	coins = append(coins, params.MAN_COIN)
-	coins = append(coins, coinsnoman...)
 	//先跑MAN交易,再跑其他币种交易
+	coins := myCoinsort(mapCoins(txsmap))
+	parallel := cfg.ParallelExec && p.config.IsByzantium(header.Number)
 	for _, coinname := range coins {
 		txs = txsmap[coinname]
+		waveStart, waveEnd, results := 0, 0, []*parallelResult(nil)
 		for i, tx := range txs {
-			if tx.IsEntrustTx() {
-				from := tx.From()
-				entrustFrom := statedb.GetGasAuthFrom(tx.GetTxCurrency(), from, p.bc.CurrentBlock().NumberU64()) //
-				if !entrustFrom.Equal(common.Address{}) {
-					tx.Setentrustfrom(entrustFrom)
-					tx.SetIsEntrustGas(true)
-				} else {
-					entrustFrom := statedb.GetGasAuthFromByTime(tx.GetTxCurrency(), from, uint64(block.Time().Uint64()))
+			//并行执行互不冲突的普通转账交易, 冲突或失败时按顺序执行
+			if parallel && i >= waveEnd {
+				sets := make(map[int]*txAccessSet)
+				access := func(i int) *txAccessSet {
+					if sets[i] == nil {
+						sets[i] = newTxAccessSet(statedb, txs[i])
+					}
+					return sets[i]
+				}
+				waveStart, results = i, nil
+				if waveEnd = parallelWaveEnd(access, i, len(txs)); waveEnd-i > 1 {
+					results = p.executeWave(block, statedb, txs, i, waveEnd, sets, gp, usedGas, cfg)
+				}
+			}
+			var (
+				receipt *types.Receipt
+				gas     uint64
+				shard   []uint
+			)
+			if results != nil {
+				receipt, gas, shard = results[i-waveStart].receipt, results[i-waveStart].gas, results[i-waveStart].shard
+			} else {
+				if tx.IsEntrustTx() {
+					from := tx.From()
+					entrustFrom := statedb.GetGasAuthFrom(tx.GetTxCurrency(), from, p.bc.CurrentBlock().NumberU64()) //
 					if !entrustFrom.Equal(common.Address{}) {
 						tx.Setentrustfrom(entrustFrom)
 						tx.SetIsEntrustGas(true)
-						tx.SetIsEntrustByTime(true)
 					} else {
-						entrustFrom := statedb.GetGasAuthFromByCount(tx.GetTxCurrency(), from)
+						entrustFrom := statedb.GetGasAuthFromByTime(tx.GetTxCurrency(), from, uint64(block.Time().Uint64()))
 						if !entrustFrom.Equal(common.Address{}) {
 							tx.Setentrustfrom(entrustFrom)
 							tx.SetIsEntrustGas(true)
-							tx.SetIsEntrustByCount(true)
+							tx.SetIsEntrustByTime(true)
 						} else {
-							log.Error("下载过程:该用户没有被授权过委托Gas或授权失效")
-							return nil, 0, ErrWithoutAuth
+							entrustFrom := statedb.GetGasAuthFromByCount(tx.GetTxCurrency(), from)
+							if !entrustFrom.Equal(common.Address{}) {
+								tx.Setentrustfrom(entrustFrom)
+								tx.SetIsEntrustGas(true)
+								tx.SetIsEntrustByCount(true)
+							} else {
+								log.Error("下载过程:该用户没有被授权过委托Gas或授权失效")
+								return nil, 0, ErrWithoutAuth
+							}
 						}
 					}
 				}
-			}
-			statedb.Prepare(tx.Hash(), block.Hash(), i)
-			receipt, gas, shard, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
-			if err != nil {
-				return nil, 0, err
+				statedb.Prepare(tx.Hash(), block.Hash(), i)
+				var err error
+				receipt, gas, shard, err = ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
+				if err != nil {
+					return nil, 0, err
+				}
 			}
 			allreceipts[tx.GetTxCurrency()] = append(allreceipts[tx.GetTxCurrency()], receipt)
 			//retAllGas[tx.GetTxCurrency()] += gas
@@ -495,6 +546,7 @@ func (p *StateProcessor) ProcessTxs(block *types.Block, statedb *state.StateDBMa
 	}
 	//statedb.Finalise("MAN",true)
 	rewarts := p.ProcessReward(statedb, block.Header(), upTime, from, retAllGas)
+	statedb.AddSystemLogs(newRewardLogs(rewarts))
 	tmpmapcoin := make(map[string]bool) //为了拿到币种,v值无意义
 	for _, rewart := range rewarts {
 		tmpmapcoin[rewart.CoinRange] = true
@@ -649,10 +701,39 @@ func (p *StateProcessor) Process(block *types.Block, parent *types.Block, stated
 		p.bc.reportBlock(block, nil, err)
 		return nil, nil, 0, err
 	}
+	slashEvents, err := p.bc.ProcessHeartbeatSlash(string(block.Version()), statedb, block.Header())
+	if err != nil {
+		log.Trace("BlockChain insertChain in3 Process Block err6")
+		p.bc.reportBlock(block, nil, err)
+		return nil, nil, 0, err
+	}
+	seedEvents, err := p.bc.ProcessSeedCommitReveal(string(block.Version()), statedb, block.Header())
+	if err != nil {
+		log.Trace("BlockChain insertChain in3 Process Block err7")
+		p.bc.reportBlock(block, nil, err)
+		return nil, nil, 0, err
+	}
+	slashEvents = append(slashEvents, seedEvents...)
+	err = p.bc.ProcessCheckpointVote(string(block.Version()), statedb, block.Header())
+	if err != nil {
+		log.Trace("BlockChain insertChain in3 Process Block err8")
+		p.bc.reportBlock(block, nil, err)
+		return nil, nil, 0, err
+	}
+	for _, ev := range slashEvents {
+		mc.PublishEvent(mc.Slash_Notify, ev)
+	}
+	statedb.AddSystemLogs(newSlashLogs(slashEvents))
+	err = p.bc.ProcessRewardGovernance(string(block.Version()), statedb, block.Header())
+	if err != nil {
+		log.Trace("BlockChain insertChain in3 Process Block err9")
+		p.bc.reportBlock(block, nil, err)
+		return nil, nil, 0, err
+	}
 	// Process block using the parent state as reference point.
 	logs, usedGas, err := p.ProcessTxs(block, statedb, cfg, uptimeMap)
 	if err != nil {
-		log.Trace("BlockChain insertChain in3 Process Block err6")
+		log.Trace("BlockChain insertChain in3 Process Block err10")
 		p.bc.reportBlock(block, nil, err)
 		return nil, logs, usedGas, err
 	}
@@ -660,12 +741,12 @@ func (p *StateProcessor) Process(block *types.Block, parent *types.Block, stated
 	// Process matrix state
 	err = p.bc.matrixProcessor.ProcessMatrixState(block, string(parent.Version()), statedb)
 	if err != nil {
-		log.Trace("BlockChain insertChain in3 Process Block err7")
+		log.Trace("BlockChain insertChain in3 Process Block err11")
 		return nil, logs, usedGas, err
 	}
 	err = p.bc.UpdateCurrencyHeaderState(statedb, string(block.Version()), block.Root()[1:], block.Sharding()[1:])
 	if err != nil {
-		log.Trace("BlockChain insertChain in3 Process Block err8")
+		log.Trace("BlockChain insertChain in3 Process Block err12")
 		return nil, logs, usedGas, err
 	}
 	return nil, logs, usedGas, nil
@@ -699,6 +780,7 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	// Apply the transaction to the current state (included in the env)
 	var gas uint64
 	var failed bool
+	var extraStatus []uint64
 	shardings := make([]uint, 0)
 	if tx.TxType() == types.BroadCastTxIndex {
 		if extx := tx.GetMatrix_EX(); (extx != nil) && len(extx) > 0 && extx[0].TxType == 1 {
@@ -706,7 +788,7 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 			failed = true
 		}
 	} else {
-		_, gas, failed, shardings, err = ApplyMessage(vmenv, tx, gp)
+		_, gas, failed, shardings, extraStatus, err = applyMessage(vmenv, tx, gp)
 		if tx.IsEntrustTx() && tx.GetIsEntrustByCount() {
 			statedb.GasAuthCountSubOne(tx.GetTxCurrency(), from)              //授权次数减1
 			statedb.GasEntrustCountSubOne(tx.GetTxCurrency(), tx.AmontFrom()) //委托次数减1
@@ -737,6 +819,9 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	// Set the receipt logs and create a bloom for filtering
 	receipt.Logs = statedb.GetLogs(tx.GetTxCurrency(), tx.From(), tx.Hash())
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	if ReceiptMetaEnabled(statedb, header.Number.Uint64()) {
+		receipt.Matrix = newReceiptMatrixInfo(tx, extraStatus)
+	}
 
 	return receipt, gas, shardings, err
 }
@@ -0,0 +1,243 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+var (
+	errParallelLogs     = errors.New("concurrent run emitted logs")
+	errParallelWriteSet = errors.New("concurrent run wrote outside its access set")
+
+	parallelWaveMeter     = metrics.NewRegisteredMeter("chain/parallel/waves", nil)
+	parallelTxMeter       = metrics.NewRegisteredMeter("chain/parallel/txs", nil)
+	parallelConflictMeter = metrics.NewRegisteredMeter("chain/parallel/conflicts", nil)
+)
+
+// txAccessSet is the predicted set of accounts a transaction reads and writes.
+// The gas reward account is left out on purpose, gas payments commute.
+type txAccessSet struct {
+	accounts []common.Address
+	barrier  bool // Access set cannot be predicted, the transaction must run alone
+}
+
+// newTxAccessSet predicts the accounts touched by a transaction. Only plain MAN
+// value transfers between accounts without code are predictable, anything
+// running contract code or a special transaction type is a barrier.
+func newTxAccessSet(statedb *state.StateDBManage, tx types.SelfTransaction) *txAccessSet {
+	set := new(txAccessSet)
+	if tx.GetMatrixType() != common.ExtraNormalTxType || tx.IsEntrustTx() || tx.TxType() == types.BroadCastTxIndex || tx.GetTxCurrency() != params.MAN_COIN {
+		set.barrier = true
+		return set
+	}
+	plain := func(addr *common.Address) bool {
+		return addr != nil && *addr != common.TxGasRewardAddress && statedb.GetCodeSize(params.MAN_COIN, *addr) == 0 && !vm.IsPrecompiled(*addr, statedb)
+	}
+	from := tx.From()
+	if !plain(&from) || !plain(tx.To()) {
+		set.barrier = true
+		return set
+	}
+	set.accounts = append(set.accounts, from, *tx.To())
+	for _, ex := range tx.GetMatrix_EX() {
+		for _, extra := range ex.ExtraTo {
+			if !plain(extra.Recipient) {
+				set.barrier = true
+				return set
+			}
+			set.accounts = append(set.accounts, *extra.Recipient)
+		}
+	}
+	return set
+}
+
+// parallelWaveEnd returns the end of the wave starting at start: the longest run
+// of transactions up to end whose access sets don't share any account. Barrier
+// transactions get a wave of their own, so executing the waves in order keeps
+// the block order semantics. The access sets are requested lazily, as they must
+// be predicted on the state the wave starts from.
+func parallelWaveEnd(access func(i int) *txAccessSet, start, end int) int {
+	if access(start).barrier {
+		return start + 1
+	}
+	touched := make(map[common.Address]struct{})
+	for i := start; i < end; i++ {
+		set := access(i)
+		if set.barrier {
+			return i
+		}
+		for _, addr := range set.accounts {
+			if _, ok := touched[addr]; ok {
+				return i
+			}
+		}
+		for _, addr := range set.accounts {
+			touched[addr] = struct{}{}
+		}
+	}
+	return end
+}
+
+// parallelResult is the outcome of a transaction executed on a private copy of
+// the state.
+type parallelResult struct {
+	state   *state.StateDBManage
+	receipt *types.Receipt
+	gas     uint64
+	shard   []uint
+	err     error
+}
+
+// executeWave runs the transactions of a conflict free wave concurrently, each
+// on its own copy of the block state. The runs are only accepted if every one
+// succeeded, wrote nothing but the accounts of its access set and the gas reward
+// account, and the block gas pool admits them in block order. The changes are
+// then merged into the block state in block order and the receipts are
+// returned. Otherwise the block state is left untouched and nil is returned, the
+// caller has to execute the wave sequentially.
+func (p *StateProcessor) executeWave(block *types.Block, statedb *state.StateDBManage, txs []types.SelfTransaction, start, end int, sets map[int]*txAccessSet, gp *GasPool, usedGas *uint64, cfg vm.Config) []*parallelResult {
+	var (
+		header  = block.Header()
+		results = make([]*parallelResult, end-start)
+		tasks   = make(chan int, end-start)
+		workers = runtime.NumCPU()
+		wg      sync.WaitGroup
+	)
+	// Copying the block state is not thread safe, do it up front
+	for i := start; i < end; i++ {
+		results[i-start] = &parallelResult{state: statedb.Copy()}
+		tasks <- i
+	}
+	close(tasks)
+
+	if workers > end-start {
+		workers = end - start
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range tasks {
+				res := results[i-start]
+				res.state.Prepare(txs[i].Hash(), block.Hash(), i)
+				res.receipt, res.gas, res.shard, res.err = ApplyTransaction(p.config, p.bc, nil, new(GasPool).AddGas(block.GasLimit()), res.state, header, txs[i], new(uint64), cfg)
+			}
+		}()
+	}
+	wg.Wait()
+
+	parallelWaveMeter.Mark(1)
+	parallelTxMeter.Mark(int64(end - start))
+
+	// Validate the runs against their access sets and the block gas pool
+	var (
+		pool    = *gp
+		dirties = make([][]common.Address, len(results))
+		touched = make(map[common.Address]struct{})
+	)
+	for k, res := range results {
+		i := start + k
+		if err := checkParallelResult(txs[i], sets[i], res, &pool); err != nil {
+			parallelConflictMeter.Mark(1)
+			log.Debug("Parallel execution conflict, falling back to sequential execution", "number", block.NumberU64(), "index", i, "err", err)
+			return nil
+		}
+		dirties[k] = res.state.DirtyAccounts()[params.MAN_COIN]
+		for _, addr := range dirties[k] {
+			touched[addr] = struct{}{}
+		}
+	}
+	// Snapshot the touched accounts before merging, the gas reward account is
+	// written by every transaction and only its balance delta may be applied
+	preBalance := make(map[common.Address]map[uint32]*big.Int, len(touched))
+	preNonce := make(map[common.Address]uint64, len(touched))
+	for addr := range touched {
+		preBalance[addr] = make(map[uint32]*big.Int)
+		for _, tAccount := range statedb.GetBalance(params.MAN_COIN, addr) {
+			if tAccount.Balance != nil {
+				preBalance[addr][tAccount.AccountType] = new(big.Int).Set(tAccount.Balance)
+			}
+		}
+		preNonce[addr] = statedb.GetNonce(params.MAN_COIN, addr)
+	}
+	for k, res := range results {
+		for _, addr := range dirties[k] {
+			mergeParallelAccount(statedb, res.state, addr, preBalance[addr], preNonce[addr])
+		}
+		*usedGas += res.gas
+		res.receipt.CumulativeGasUsed = *usedGas
+	}
+	*gp = pool
+	return results
+}
+
+// checkParallelResult verifies a concurrent run of a transaction and charges its
+// gas to the pool in block order.
+func checkParallelResult(tx types.SelfTransaction, set *txAccessSet, res *parallelResult, pool *GasPool) error {
+	if res.err != nil {
+		return res.err
+	}
+	if len(res.receipt.Logs) > 0 {
+		return errParallelLogs
+	}
+	allowed := map[common.Address]struct{}{common.TxGasRewardAddress: {}}
+	for _, addr := range set.accounts {
+		allowed[addr] = struct{}{}
+	}
+	for coin, addrs := range res.state.DirtyAccounts() {
+		for _, addr := range addrs {
+			if _, ok := allowed[addr]; !ok || coin != params.MAN_COIN {
+				return errParallelWriteSet
+			}
+		}
+	}
+	if err := pool.SubGas(tx.Gas()); err != nil {
+		return err
+	}
+	pool.AddGas(tx.Gas() - res.gas)
+	return nil
+}
+
+// mergeParallelAccount applies the changes a concurrent run made to an account
+// onto the block state. Balances are merged as deltas against the state the wave
+// started from, so the commuting gas rewards of all runs add up.
+func mergeParallelAccount(statedb, run *state.StateDBManage, addr common.Address, preBalance map[uint32]*big.Int, preNonce uint64) {
+	if !statedb.Exist(params.MAN_COIN, addr) && run.Exist(params.MAN_COIN, addr) {
+		statedb.CreateAccount(params.MAN_COIN, addr)
+	}
+	// Touch the account like the run did, empty accounts are cleared on finalise
+	statedb.AddBalance(params.MAN_COIN, common.MainAccount, addr, new(big.Int))
+
+	for _, tAccount := range run.GetBalance(params.MAN_COIN, addr) {
+		if tAccount.Balance == nil {
+			continue
+		}
+		delta := new(big.Int).Set(tAccount.Balance)
+		if pre := preBalance[tAccount.AccountType]; pre != nil {
+			delta.Sub(delta, pre)
+		}
+		switch delta.Sign() {
+		case 1:
+			statedb.AddBalance(params.MAN_COIN, tAccount.AccountType, addr, delta)
+		case -1:
+			statedb.SubBalance(params.MAN_COIN, tAccount.AccountType, addr, delta.Neg(delta))
+		}
+	}
+	if nonce := run.GetNonce(params.MAN_COIN, addr); nonce != preNonce {
+		statedb.SetNonce(params.MAN_COIN, addr, nonce)
+	}
+}
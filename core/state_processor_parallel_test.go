@@ -0,0 +1,40 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+// Tests that transactions are split into conflict free waves in block order.
+func TestParallelWaveEnd(t *testing.T) {
+	a, b, c, d := common.HexToAddress("0x0a"), common.HexToAddress("0x0b"), common.HexToAddress("0x0c"), common.HexToAddress("0x0d")
+	sets := []*txAccessSet{
+		{accounts: []common.Address{a, b}},
+		{accounts: []common.Address{c, d}},
+		{accounts: []common.Address{b, c}}, // conflicts with both previous ones
+		{accounts: []common.Address{a, d}},
+		{barrier: true},
+		{accounts: []common.Address{a, b}},
+	}
+	access := func(i int) *txAccessSet { return sets[i] }
+
+	var have [][]int
+	for start := 0; start < len(sets); {
+		end := parallelWaveEnd(access, start, len(sets))
+		var wave []int
+		for i := start; i < end; i++ {
+			wave = append(wave, i)
+		}
+		have, start = append(have, wave), end
+	}
+	want := [][]int{{0, 1}, {2, 3}, {4}, {5}}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("schedule mismatch: have %v, want %v", have, want)
+	}
+}
@@ -12,9 +12,12 @@ import (
 
 	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
 
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
 	"github.com/MatrixAINetwork/go-matrix/mandb"
 	"github.com/MatrixAINetwork/go-matrix/mc"
 	"github.com/MatrixAINetwork/go-matrix/params"
@@ -368,3 +371,169 @@ func TestStateProcessor_ProcessMultiCoinReward3(t *testing.T) {
 		}
 	}
 }
+
+func TestStateProcessor_ElectedPrecompile(t *testing.T) {
+	//查询当选节点的预编译合约测试
+
+	log.InitLog(3)
+	chaindb := mandb.NewMemDatabase()
+	roots := make([]common.CoinRoot, 0)
+	roots = append(roots, common.CoinRoot{Cointyp: params.MAN_COIN, Root: common.Hash{}})
+	preState, _ := state.NewStateDBManage(roots, chaindb, state.NewDatabase(chaindb))
+	matrixstate.SetVersionInfo(preState, manversion.VersionAIMine)
+	matrixstate.SetForkSchedule(preState, &mc.ForkSchedule{Forks: []mc.ForkActivation{{Name: mc.ForkElectedSet, ActivateNumber: 200}}})
+	matrixstate.SetBroadcastInterval(preState, &mc.BCIntervalInfo{LastBCNumber: 0, LastReelectNumber: 0, BCInterval: 100})
+	matrixstate.SetElectGraph(preState, &mc.ElectGraph{
+		Number: 249,
+		ElectList: []mc.ElectNodeInfo{
+			{Account: common.HexToAddress("1"), Type: common.RoleValidator},
+			{Account: common.HexToAddress("2"), Type: common.RoleValidator},
+			{Account: common.HexToAddress("3"), Type: common.RoleMiner},
+		},
+		NextValidatorElect: []mc.ElectNodeInfo{
+			{Account: common.HexToAddress("4"), Type: common.RoleValidator},
+		},
+	})
+
+	newEVM := func(number int64) *vm.EVM {
+		ctx := vm.Context{
+			CanTransfer:       CanTransfer,
+			Transfer:          Transfer,
+			GetElected:        GetElected,
+			PrecompileEnabled: PrecompileEnabled,
+			BlockNumber:       big.NewInt(number),
+			Time:              big.NewInt(0),
+			Difficulty:        big.NewInt(0),
+			GasPrice:          big.NewInt(0),
+		}
+		return vm.NewEVM(ctx, preState, params.TestChainConfig, vm.Config{}, params.MAN_COIN)
+	}
+	evm := newEVM(150)
+	call := func(height uint64, role common.RoleType) ([]byte, uint64, error) {
+		input := append(common.LeftPadBytes(new(big.Int).SetUint64(height).Bytes(), 32), common.LeftPadBytes(big.NewInt(int64(role)).Bytes(), 32)...)
+		ret, left, _, err := evm.Call(vm.AccountRef(common.HexToAddress("100")), vm.ElectedSetAddress, input, 100000, big.NewInt(0))
+		return ret, 100000 - left, err
+	}
+
+	// 硬分叉前预编译合约不存在
+	if ret, used, err := call(100, common.RoleValidator); err != nil || len(ret) != 0 || used != 0 {
+		t.Fatalf("硬分叉前不应执行预编译合约 %x %d %v", ret, used, err)
+	}
+	evm = newEVM(250)
+
+	// 当前选举周期
+	ret, used, err := call(100, common.RoleValidator)
+	if err != nil {
+		t.Fatalf("查询当前选举周期错误 %v", err)
+	}
+	if len(ret) != 4*32 || new(big.Int).SetBytes(ret[32:64]).Uint64() != 2 || common.BytesToAddress(ret[96:128]) != common.HexToAddress("2") {
+		t.Fatalf("当前选举周期验证者错误 %x", ret)
+	}
+	if used != 2000+2*200 {
+		t.Errorf("gas计算错误 %d", used)
+	}
+	if ret, _, err = call(299, common.RoleMiner); err != nil || len(ret) != 3*32 || common.BytesToAddress(ret[64:96]) != common.HexToAddress("3") {
+		t.Fatalf("当前选举周期矿工错误 %x %v", ret, err)
+	}
+
+	// 下一选举周期
+	if ret, _, err = call(400, common.RoleValidator); err != nil || len(ret) != 3*32 || common.BytesToAddress(ret[64:96]) != common.HexToAddress("4") {
+		t.Fatalf("下一选举周期验证者错误 %x %v", ret, err)
+	}
+	if _, _, err = call(400, common.RoleMiner); err == nil {
+		t.Errorf("下一选举周期矿工未生成, 应返回错误")
+	}
+	if _, _, err = call(700, common.RoleValidator); err == nil {
+		t.Errorf("超出范围的高度应返回错误")
+	}
+}
+
+func TestStateProcessor_SchedulePrecompile(t *testing.T) {
+	//查询广播周期及账户角色的预编译合约测试
+
+	log.InitLog(3)
+	chaindb := mandb.NewMemDatabase()
+	roots := make([]common.CoinRoot, 0)
+	roots = append(roots, common.CoinRoot{Cointyp: params.MAN_COIN, Root: common.Hash{}})
+	preState, _ := state.NewStateDBManage(roots, chaindb, state.NewDatabase(chaindb))
+	matrixstate.SetVersionInfo(preState, manversion.VersionAIMine)
+	matrixstate.SetForkSchedule(preState, &mc.ForkSchedule{Forks: []mc.ForkActivation{{Name: mc.ForkMatrixSchedule, ActivateNumber: 250}}})
+	matrixstate.SetBroadcastInterval(preState, &mc.BCIntervalInfo{LastBCNumber: 200, LastReelectNumber: 0, BCInterval: 100})
+	matrixstate.SetBroadcastAccounts(preState, []common.Address{common.HexToAddress("5")})
+	matrixstate.SetTopologyGraph(preState, &mc.TopologyGraph{
+		NodeList: []mc.TopologyNodeInfo{{Account: common.HexToAddress("1"), Type: common.RoleValidator}},
+	})
+
+	newEVM := func(number int64) *vm.EVM {
+		ctx := vm.Context{
+			CanTransfer:       CanTransfer,
+			Transfer:          Transfer,
+			BroadcastSchedule: BroadcastSchedule,
+			HasRole:           HasRole,
+			PrecompileEnabled: PrecompileEnabled,
+			BlockNumber:       big.NewInt(number),
+			Time:              big.NewInt(0),
+			Difficulty:        big.NewInt(0),
+			GasPrice:          big.NewInt(0),
+		}
+		return vm.NewEVM(ctx, preState, params.TestChainConfig, vm.Config{}, params.MAN_COIN)
+	}
+	evm := newEVM(249)
+	call := func(method string, args ...[]byte) []byte {
+		input := crypto.Keccak256([]byte(method))[:4]
+		for _, arg := range args {
+			input = append(input, common.LeftPadBytes(arg, 32)...)
+		}
+		ret, _, _, err := evm.Call(vm.AccountRef(common.HexToAddress("100")), vm.MatrixScheduleAddress, input, 100000, big.NewInt(0))
+		if err != nil {
+			t.Fatalf("%s 调用错误 %v", method, err)
+		}
+		return ret
+	}
+	word := func(ret []byte, i int) uint64 {
+		return new(big.Int).SetBytes(ret[i*32 : (i+1)*32]).Uint64()
+	}
+
+	// 硬分叉前预编译合约不存在
+	if ret := call("currentInterval()"); len(ret) != 0 {
+		t.Fatalf("硬分叉前不应执行预编译合约 %x", ret)
+	}
+	evm = newEVM(250)
+
+	if word(call("isBroadcastBlock(uint256)", big.NewInt(300).Bytes()), 0) != 1 {
+		t.Errorf("300应为广播区块")
+	}
+	if word(call("isBroadcastBlock(uint256)", big.NewInt(250).Bytes()), 0) != 0 {
+		t.Errorf("250不应为广播区块")
+	}
+	if ret := call("currentInterval()"); word(ret, 0) != 100 || word(ret, 1) != 200 || word(ret, 2) != 300 {
+		t.Errorf("当前广播周期错误 %d %d %d", word(ret, 0), word(ret, 1), word(ret, 2))
+	}
+	role := big.NewInt(int64(common.RoleValidator)).Bytes()
+	if word(call("hasRole(address,uint256)", common.HexToAddress("1").Bytes(), role), 0) != 1 {
+		t.Errorf("拓扑中的验证者角色错误")
+	}
+	if word(call("hasRole(address,uint256)", common.HexToAddress("2").Bytes(), role), 0) != 0 {
+		t.Errorf("不在拓扑中的账户不应为验证者")
+	}
+	role = big.NewInt(int64(common.RoleBroadcast)).Bytes()
+	if word(call("hasRole(address,uint256)", common.HexToAddress("5").Bytes(), role), 0) != 1 {
+		t.Errorf("广播节点角色错误")
+	}
+}
+
+func TestMyCoinsort(t *testing.T) {
+	coins := myCoinsort([]string{"ZZZ", "BBB", params.MAN_COIN, "AAA"})
+	want := []string{params.MAN_COIN, "AAA", "BBB", "ZZZ"}
+	if len(coins) != len(want) {
+		t.Fatalf("coin count mismatch: have %v, want %v", coins, want)
+	}
+	for i := range want {
+		if coins[i] != want[i] {
+			t.Fatalf("coin order mismatch: have %v, want %v", coins, want)
+		}
+	}
+	if coins := myCoinsort(nil); len(coins) != 1 || coins[0] != params.MAN_COIN {
+		t.Errorf("MAN not first without transactions: %v", coins)
+	}
+}
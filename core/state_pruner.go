@@ -0,0 +1,280 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+	"github.com/MatrixAINetwork/go-matrix/trie"
+)
+
+// statePruneInterval is the number of blocks between two online pruning runs.
+const statePruneInterval = 1024
+
+// statePruneBatch is the number of old block states swept per chain lock.
+const statePruneBatch = 64
+
+// lastPrunedStateKey tracks the number of the last block whose state was pruned,
+// the checkpoint pruning resumes from.
+var lastPrunedStateKey = []byte("LastPrunedStateNumber")
+
+// ErrStateRetentionTooLow is returned when the state retention window is shorter
+// than the number of block states held in memory.
+var ErrStateRetentionTooLow = errors.New("state retention shorter than the in-memory state window")
+
+// nodeSet is a set of trie node hashes.
+type nodeSet map[common.Hash]struct{}
+
+// StatePruner deletes the state trie nodes persisted for old blocks. The states
+// of the last blocks within the retention window are kept, as are the states of
+// the broadcast interval boundary blocks, whose broadcast transactions are read
+// back by GetBroadcastTxMap. Pruning progresses in batches of blocks and checkpoints
+// its progress into the database, so that an interrupted run is safely resumed.
+type StatePruner struct {
+	bc        *BlockChain
+	db        mandb.Database
+	retention uint64
+	running   int32 // Flag whether a pruning run is in progress, must be accessed atomically
+}
+
+// NewStatePruner creates a state pruner keeping the states of the last retention
+// blocks of the chain.
+func NewStatePruner(bc *BlockChain, retention uint64) (*StatePruner, error) {
+	if retention < triesInMemory {
+		return nil, ErrStateRetentionTooLow
+	}
+	return &StatePruner{bc: bc, db: bc.db, retention: retention}, nil
+}
+
+// LastPruned retrieves the number of the last block whose state was pruned.
+func (p *StatePruner) LastPruned() uint64 {
+	data, _ := p.db.Get(lastPrunedStateKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// trigger starts a pruning run in the background, unless one is in progress.
+func (p *StatePruner) trigger() {
+	if !atomic.CompareAndSwapInt32(&p.running, 0, 1) {
+		return
+	}
+	p.bc.wg.Add(1)
+	go func() {
+		defer p.bc.wg.Done()
+		defer atomic.StoreInt32(&p.running, 0)
+
+		if _, err := p.Prune(); err != nil {
+			log.Error("Failed to prune state", "err", err)
+		}
+	}()
+}
+
+// Prune deletes the state trie nodes only reachable from states of blocks below
+// the retention window, returning the number of deleted nodes. The retained
+// states are marked and the old ones swept without holding the chain lock, which
+// is only taken for a short while per batch of blocks to mark the states inserted
+// in the meantime and delete the batch's unmarked nodes.
+func (p *StatePruner) Prune() (int, error) {
+	head := p.bc.CurrentBlock()
+	if head.NumberU64() <= p.retention {
+		return 0, nil
+	}
+	from, target := p.LastPruned()+1, head.NumberU64()-p.retention
+	if from > target {
+		return 0, nil
+	}
+	start := time.Now()
+
+	// Safety checkpoint: make sure the head state is persisted before deleting
+	// anything, so that a crash while pruning leaves a usable database.
+	triedb := p.bc.stateCache.TrieDB()
+	p.bc.chainmu.Lock()
+	err := triedb.CommitRoots(head.Root(), false)
+	p.bc.chainmu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	// Mark all nodes reachable from the retained states
+	marked := make(nodeSet)
+	p.markRetained(triedb, target, marked)
+	for number := uint64(1); number <= target; number++ {
+		if header := p.bc.GetHeaderByNumber(number); header != nil && manparams.IsBroadcastNumberByHash(number, header.ParentHash) {
+			p.markState(triedb, header.Roots, marked)
+		}
+	}
+	log.Info("Marked retained state", "nodes", len(marked), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Sweep the states of the old blocks batch by batch
+	var (
+		diskdb  = trie.NewDatabase(p.db)
+		visited = make(nodeSet)
+		deleted int
+	)
+	for batch := from; batch <= target; batch += statePruneBatch {
+		select {
+		case <-p.bc.quit:
+			return deleted, nil
+		default:
+		}
+		last := batch + statePruneBatch - 1
+		if last > target {
+			last = target
+		}
+		var unmarked []common.Hash
+		for number := batch; number <= last; number++ {
+			if header := p.bc.GetHeaderByNumber(number); header != nil && !manparams.IsBroadcastNumberByHash(number, header.ParentHash) {
+				unmarked = append(unmarked, p.sweepState(diskdb, header.Roots, marked, visited)...)
+			}
+		}
+		n, err := p.deleteBatch(triedb, target, last, unmarked, marked)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	log.Info("Pruned state", "from", from, "to", target, "nodes", deleted, "elapsed", common.PrettyDuration(time.Since(start)))
+	return deleted, nil
+}
+
+// markRetained marks the states of the canonical blocks above target. Nodes
+// marked before end the walks early, so remarking only visits the new nodes.
+func (p *StatePruner) markRetained(triedb *trie.Database, target uint64, marked nodeSet) {
+	head := p.bc.CurrentBlock().NumberU64()
+	for number := target + 1; number <= head; number++ {
+		if header := p.bc.GetHeaderByNumber(number); header != nil {
+			p.markState(triedb, header.Roots, marked)
+		}
+	}
+}
+
+// deleteBatch deletes the unmarked nodes of a batch of old states under the
+// chain lock. The states inserted since the last marking may share nodes with
+// the old states, they are marked first so that none of their nodes is deleted.
+// The progress is checkpointed after the deletions, resweeping a block is harmless.
+func (p *StatePruner) deleteBatch(triedb *trie.Database, target, last uint64, unmarked []common.Hash, marked nodeSet) (int, error) {
+	p.bc.chainmu.Lock()
+	defer p.bc.chainmu.Unlock()
+
+	p.markRetained(triedb, target, marked)
+	deleted := 0
+	for _, hash := range unmarked {
+		if _, ok := marked[hash]; ok {
+			continue
+		}
+		if err := p.db.Delete(hash[:]); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, last)
+	return deleted, p.db.Put(lastPrunedStateKey, enc)
+}
+
+// markState adds all nodes of the state tries of a block to the marked set.
+func (p *StatePruner) markState(triedb *trie.Database, roots []common.CoinRoot, marked nodeSet) {
+	for _, cr := range roots {
+		marked[cr.Root] = struct{}{}
+		shards, err := state.GetShardRoots(p.db, cr.Root)
+		if err != nil {
+			continue
+		}
+		for _, root := range shards {
+			err := walkStateTrie(triedb, root, func(hash common.Hash) bool {
+				if _, ok := marked[hash]; ok {
+					return false
+				}
+				marked[hash] = struct{}{}
+				return true
+			})
+			if err != nil {
+				log.Debug("Retained state incomplete", "coin", cr.Cointyp, "root", root, "err", err)
+			}
+		}
+	}
+}
+
+// sweepState collects the persisted nodes of the state tries of a block which
+// are not marked for retention.
+func (p *StatePruner) sweepState(diskdb *trie.Database, roots []common.CoinRoot, marked, visited nodeSet) []common.Hash {
+	var unmarked []common.Hash
+	visit := func(hash common.Hash) bool {
+		if _, ok := marked[hash]; ok {
+			return false
+		}
+		if _, ok := visited[hash]; ok {
+			return false
+		}
+		visited[hash] = struct{}{}
+		unmarked = append(unmarked, hash)
+		return true
+	}
+	for _, cr := range roots {
+		shards, err := state.GetShardRoots(p.db, cr.Root)
+		if err != nil {
+			continue
+		}
+		for _, root := range shards {
+			walkStateTrie(diskdb, root, visit)
+		}
+		visit(cr.Root)
+	}
+	return unmarked
+}
+
+// walkStateTrie iterates the nodes of a state trie and the storage tries of its
+// accounts, calling visit for every node stored by hash. The children of a node
+// are only iterated if visit returns true. Missing nodes end the walk of a trie.
+func walkStateTrie(triedb *trie.Database, root common.Hash, visit func(common.Hash) bool) error {
+	return walkTrie(triedb, root, visit, func(leaf []byte) error {
+		var obj state.Account
+		if err := rlp.Decode(bytes.NewReader(leaf), &obj); err != nil {
+			// Matrix state entries share the trie with the accounts
+			return nil
+		}
+		return walkTrie(triedb, obj.Root, visit, nil)
+	})
+}
+
+// walkTrie iterates the nodes of a single trie, see walkStateTrie.
+func walkTrie(triedb *trie.Database, root common.Hash, visit func(common.Hash) bool, onLeaf func([]byte) error) error {
+	if root == (common.Hash{}) || root == types.EmptyRootHash {
+		return nil
+	}
+	if !visit(root) {
+		return nil
+	}
+	t, err := trie.New(root, triedb)
+	if err != nil {
+		return err
+	}
+	it := t.NodeIterator(nil)
+	for descend := true; it.Next(descend); {
+		descend = true
+		if hash := it.Hash(); hash != (common.Hash{}) && hash != root {
+			descend = visit(hash)
+			continue
+		}
+		if onLeaf != nil && it.Leaf() {
+			if err := onLeaf(it.LeafBlob()); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Error()
+}
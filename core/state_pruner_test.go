@@ -0,0 +1,81 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/trie"
+)
+
+// Tests that sweeping an old state only deletes the nodes which are not shared
+// with the retained state.
+func TestStatePrunerSweep(t *testing.T) {
+	diskdb := mandb.NewMemDatabase()
+	triedb := trie.NewDatabase(diskdb)
+
+	commit := func(tr *trie.Trie) common.Hash {
+		root, err := tr.Commit(nil)
+		if err != nil {
+			t.Fatalf("failed to commit trie: %v", err)
+		}
+		if err := triedb.Commit(root, false); err != nil {
+			t.Fatalf("failed to flush trie: %v", err)
+		}
+		return root
+	}
+	tr, _ := trie.New(common.Hash{}, triedb)
+	for i := 0; i < 500; i++ {
+		tr.Update([]byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%064d", i)))
+	}
+	oldRoot := commit(tr)
+	for i := 0; i < 10; i++ {
+		tr.Update([]byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("updated-%064d", i)))
+	}
+	newRoot := commit(tr)
+
+	// Mark the retained state and sweep the old one
+	marked := make(nodeSet)
+	err := walkStateTrie(trie.NewDatabase(diskdb), newRoot, func(hash common.Hash) bool {
+		if _, ok := marked[hash]; ok {
+			return false
+		}
+		marked[hash] = struct{}{}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("failed to mark retained state: %v", err)
+	}
+	var unmarked []common.Hash
+	walkStateTrie(trie.NewDatabase(diskdb), oldRoot, func(hash common.Hash) bool {
+		if _, ok := marked[hash]; ok {
+			return false
+		}
+		unmarked = append(unmarked, hash)
+		return true
+	})
+	if len(unmarked) == 0 {
+		t.Fatalf("no stale nodes found")
+	}
+	for _, hash := range unmarked {
+		diskdb.Delete(hash[:])
+	}
+	// The retained state must be intact, the old one gone
+	retained, err := trie.New(newRoot, trie.NewDatabase(diskdb))
+	if err != nil {
+		t.Fatalf("retained state damaged: %v", err)
+	}
+	for i := 0; i < 500; i++ {
+		if _, err := retained.TryGet([]byte(fmt.Sprintf("key-%03d", i))); err != nil {
+			t.Fatalf("retained key %d unreadable: %v", i, err)
+		}
+	}
+	if _, err := trie.New(oldRoot, trie.NewDatabase(diskdb)); err == nil {
+		t.Fatalf("old state root not pruned")
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"bufio"
 	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/ca"
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
 	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
@@ -23,6 +24,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/mc"
 	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
 	"os"
 )
 
@@ -40,6 +42,8 @@ type StateTransition struct {
 	data       []byte
 	state      vm.StateDBManager
 	evm        *vm.EVM
+
+	extraStatus []uint64 // 附加交易(ExtraTo)的执行状态
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
@@ -79,14 +83,26 @@ func NewStateTransition(evm *vm.EVM, msg txinterface.Message, gp *GasPool) *Stat
 	//	log.Error("NewStateTransition err")
 	//	return nil
 	//}
+	var extraStatus []uint64
+	if extra := msg.GetMatrix_EX(); len(extra) > 0 {
+		extraStatus = make([]uint64, len(extra[0].ExtraTo))
+	}
 	return &StateTransition{
-		gp:       gp,
-		evm:      evm,
-		msg:      msg,
-		gasPrice: new(big.Int).SetUint64(params.TxGasPrice),
-		value:    msg.Value(),
-		data:     msg.Data(),
-		state:    evm.StateDB,
+		gp:          gp,
+		evm:         evm,
+		msg:         msg,
+		gasPrice:    new(big.Int).SetUint64(params.TxGasPrice),
+		value:       msg.Value(),
+		data:        msg.Data(),
+		state:       evm.StateDB,
+		extraStatus: extraStatus,
+	}
+}
+
+// setExtraStatus 记录第i笔附加交易的执行结果, 未执行的附加交易保持失败状态
+func (st *StateTransition) setExtraStatus(i int, err error) {
+	if i < len(st.extraStatus) && err == nil {
+		st.extraStatus[i] = types.ReceiptStatusSuccessful
 	}
 }
 
@@ -207,6 +223,11 @@ func (st *StateTransition) PreCheck() error {
 			return ErrNonceTooLow
 		}
 	}
+	if from, payer := st.msg.From(), st.msg.AmontFrom(); payer != from {
+		if !EntrustTxTypeAllowed(st.state, st.msg.GetTxCurrency(), from, payer, st.msg.GetMatrixType()) {
+			return ErrEntrustTxType
+		}
+	}
 	return st.BuyGas()
 }
 
@@ -218,22 +239,29 @@ func (st *StateTransition) PreCheck() error {
 // indicates a core error meaning that the message would always fail for that particular
 // state and would never be accepted within a block.
 func ApplyMessage(evm *vm.EVM, tx txinterface.Message, gp *GasPool) ([]byte, uint64, bool, []uint, error) {
-	var stsi txinterface.StateTransitioner
+	ret, usedGas, failed, shardings, _, err := applyMessage(evm, tx, gp)
+	return ret, usedGas, failed, shardings, err
+}
+
+// applyMessage 执行交易, 并返回每笔附加交易(ExtraTo)的执行状态
+func applyMessage(evm *vm.EVM, tx txinterface.Message, gp *GasPool) ([]byte, uint64, bool, []uint, []uint64, error) {
+	var st *StateTransition
 	switch tx.TxType() {
 	case types.NormalTxIndex:
-		stsi = NewStateTransition(evm, tx, gp)
+		st = NewStateTransition(evm, tx, gp)
 	}
-	if stsi == nil {
+	if st == nil {
 		log.Error("state_transition", "AppleMessage", "interface is nil")
 	}
-	return stsi.TransitionDb()
+	ret, usedGas, failed, shardings, err := st.TransitionDb()
+	return ret, usedGas, failed, shardings, st.extraStatus, err
 }
 func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bool, shardings []uint, err error) {
 	tx := st.msg //因为st.msg的接口全部在transaction中实现,所以此处的局部变量msg实际是transaction类型
 	txtype := tx.GetMatrixType()
 	if txtype != common.ExtraNormalTxType && txtype != common.ExtraAItxType {
 		switch txtype {
-		case common.ExtraRevocable:
+		case common.ExtraRevocable, common.ExtraRevocableHeight:
 			return st.CallRevocableNormalTx()
 		case common.ExtraRevertTxType:
 			return st.CallRevertNormalTx()
@@ -251,6 +279,21 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 			return st.CallMakeCoinTx()
 		case common.ExtraSetBlackListTxType:
 			return st.CallSetBlackListTx()
+		case common.ExtraEvidenceTxType:
+			return st.CallEvidenceTx()
+		case common.ExtraRewardGovernanceTx:
+			return st.CallRewardGovernanceTx()
+		case common.ExtraConsensusKeyTxType:
+			return st.CallConsensusKeyTx()
+		case common.ExtraParamUpdateTxType:
+			return st.CallParamUpdateTx()
+		case common.ExtraBridgeAttestTxType:
+			return st.CallBridgeAttestTx()
+		case common.ExtraScheduledTxType:
+			if !IsScheduledTxMature(tx.GetMatrix_EX(), st.evm.BlockNumber.Uint64(), st.evm.Time.Uint64()) {
+				return nil, 0, false, nil, ErrScheduledTxNotMature
+			}
+			return st.CallNormalTx()
 		default:
 			log.Info("state transition unknown extra txtype")
 			return nil, 0, false, nil, ErrTXUnknownType
@@ -310,12 +353,13 @@ func (st *StateTransition) CallTimeNormalTx() (ret []byte, usedGas uint64, faile
 	mapTOAmont := common.AddrAmont{Addr: st.To(), Amont: st.value}
 	mapTOAmonts = append(mapTOAmonts, mapTOAmont)
 	if vmerr == nil && (&tmpExtra) != nil && len(tmpExtra) > 0 {
-		for _, ex := range tmpExtra[0].ExtraTo {
+		for i, ex := range tmpExtra[0].ExtraTo {
 			st.state.AddBalance(st.msg.GetTxCurrency(), common.WithdrawAccount, usefrom, ex.Amount)
 			st.state.SubBalance(st.msg.GetTxCurrency(), common.MainAccount, usefrom, ex.Amount)
 			mapTOAmont = common.AddrAmont{Addr: *ex.Recipient, Amont: ex.Amount}
 			shardings = append(shardings, uint(ex.Recipient[0]))
 			mapTOAmonts = append(mapTOAmonts, mapTOAmont)
+			st.setExtraStatus(i, vmerr)
 			if vmerr != nil {
 				break
 			}
@@ -384,9 +428,10 @@ func (st *StateTransition) CallRevertNormalTx() (ret []byte, usedGas uint64, fai
 	var hash common.Hash = common.BytesToHash(tx.Data())
 	hashlist = append(hashlist, hash)
 	if vmerr == nil && (&tmpExtra) != nil && len(tmpExtra) > 0 {
-		for _, ex := range tmpExtra[0].ExtraTo {
+		for i, ex := range tmpExtra[0].ExtraTo {
 			hash = common.BytesToHash(ex.Payload)
 			hashlist = append(hashlist, hash)
+			st.setExtraStatus(i, vmerr)
 			if vmerr != nil {
 				break
 			}
@@ -417,7 +462,7 @@ func (st *StateTransition) CallRevertNormalTx() (ret []byte, usedGas uint64, fai
 			log.Error("state_transition", "CallRevertNormalTx, err", "Revert tx from different Revocable tx from")
 			continue
 		}
-		if rt.Typ != common.ExtraRevocable {
+		if !isRevocableType(rt.Typ) {
 			log.Info("state_transition", "CallRevertNormalTx:err:type is ", rt.Typ, "Revert tx type should ", common.ExtraRevocable)
 			continue
 		}
@@ -432,13 +477,16 @@ func (st *StateTransition) CallRevertNormalTx() (ret []byte, usedGas uint64, fai
 			st.state.SubBalance(rt.Cointyp, common.WithdrawAccount, rt.From, vv.Amont)
 			shardings = append(shardings, uint(vv.Addr[0]))
 		}
-		if val, ok := delval[rt.Tim]; ok {
-			val = append(val, hash)
-			delval[rt.Tim] = val
-		} else {
-			delhashs := make([]common.Hash, 0)
-			delhashs = append(delhashs, hash)
-			delval[rt.Tim] = delhashs
+		//按区块高度撤销的交易不在按时间释放的btree中, 撤销队列释放时跳过已删除的记录
+		if rt.Typ == common.ExtraRevocable {
+			if val, ok := delval[rt.Tim]; ok {
+				val = append(val, hash)
+				delval[rt.Tim] = val
+			} else {
+				delhashs := make([]common.Hash, 0)
+				delhashs = append(delhashs, hash)
+				delval[rt.Tim] = delhashs
+			}
 		}
 		st.state.DeleteMxData(tmphash, b)
 		shardings = append(shardings, uint(rt.From[0]))
@@ -649,6 +697,10 @@ func (st *StateTransition) CallRevocableNormalTx() (ret []byte, usedGas uint64,
 	mapTOAmonts := make([]common.AddrAmont, 0)
 	//
 	tmpExtra := tx.GetMatrix_EX() //Extra()
+	window, err := revocableWindow(st.state, tx.GetMatrixType(), tmpExtra, st.evm.BlockNumber.Uint64())
+	if err != nil {
+		return nil, 0, false, shardings, err
+	}
 	if (&tmpExtra) != nil && len(tmpExtra) > 0 {
 		if uint64(len(tmpExtra[0].ExtraTo)) > params.TxCount-1 { //减1是为了和txpool中的验证统一，因为还要算上外层的那笔交易
 			return nil, 0, false, shardings, ErrTXCountOverflow
@@ -673,12 +725,13 @@ func (st *StateTransition) CallRevocableNormalTx() (ret []byte, usedGas uint64,
 	mapTOAmont := common.AddrAmont{Addr: st.To(), Amont: st.value}
 	mapTOAmonts = append(mapTOAmonts, mapTOAmont)
 	if vmerr == nil && (&tmpExtra) != nil && len(tmpExtra) > 0 {
-		for _, ex := range tmpExtra[0].ExtraTo {
+		for i, ex := range tmpExtra[0].ExtraTo {
 			st.state.AddBalance(st.msg.GetTxCurrency(), common.WithdrawAccount, usefrom, ex.Amount)
 			st.state.SubBalance(st.msg.GetTxCurrency(), common.MainAccount, usefrom, ex.Amount)
 			mapTOAmont = common.AddrAmont{Addr: *ex.Recipient, Amont: ex.Amount}
 			shardings = append(shardings, uint(ex.Recipient[0]))
 			mapTOAmonts = append(mapTOAmonts, mapTOAmont)
+			st.setExtraStatus(i, vmerr)
 			if vmerr != nil {
 				break
 			}
@@ -703,9 +756,16 @@ func (st *StateTransition) CallRevocableNormalTx() (ret []byte, usedGas uint64,
 	}
 	txHash := tx.Hash()
 	//log.Info("file state_transition","func CallRevocableNormalTx:txHash",txHash)
-	mapHashamont := make(map[common.Hash][]byte)
-	mapHashamont[txHash] = b
-	st.state.SaveTx(st.msg.GetTxCurrency(), st.msg.From(), tx.GetMatrixType(), rt.Tim, mapHashamont)
+	if window > 0 {
+		//按区块高度设置撤销期的交易不进入按时间释放的btree
+		if err = addRevocableEntry(st.state, txHash, st.evm.BlockNumber.Uint64()+window); err != nil {
+			return nil, 0, false, nil, err
+		}
+	} else {
+		mapHashamont := make(map[common.Hash][]byte)
+		mapHashamont[txHash] = b
+		st.state.SaveTx(st.msg.GetTxCurrency(), st.msg.From(), tx.GetMatrixType(), rt.Tim, mapHashamont)
+	}
 	st.state.SetMatrixData(txHash, b)
 	gasaddr, coinrange := st.getCoinAddress(tx.GetTxCurrency())
 	st.RefundGas(coinrange)
@@ -750,7 +810,7 @@ func (st *StateTransition) CallUnGasNormalTx() (ret []byte, usedGas uint64, fail
 		ret, st.gas, tmpshard, vmerr = evm.Call(sender, st.To(), st.data, st.gas, st.value)
 	}
 	if vmerr == nil && (&tmpExtra) != nil && len(tmpExtra) > 0 {
-		for _, ex := range tmpExtra[0].ExtraTo {
+		for i, ex := range tmpExtra[0].ExtraTo {
 			if toaddr == nil {
 				log.Error("state_transition callUnGasNormalTx Extro to is nil")
 				return nil, 0, false, shardings, ErrTXToNil
@@ -762,6 +822,7 @@ func (st *StateTransition) CallUnGasNormalTx() (ret []byte, usedGas uint64, fail
 				// Increment the nonce for the next transaction
 				ret, st.gas, tmpshard, vmerr = evm.Call(sender, *ex.Recipient, ex.Payload, st.gas, ex.Amount)
 			}
+			st.setExtraStatus(i, vmerr)
 			if vmerr != nil {
 				break
 			}
@@ -848,6 +909,134 @@ func (st *StateTransition) CallSetBlackListTx() (ret []byte, usedGas uint64, fai
 	return ret, st.GasUsed(), true, shardings, err
 }
 
+// callSystemTx 执行由交易数据描述的系统交易: 预检查并扣除固有gas后由apply解码执行交易数据,
+// 成功后增加发送者nonce并结算gas. name用于错误信息
+func (st *StateTransition) callSystemTx(name string, apply func(from common.Address, data []byte) error) (ret []byte, usedGas uint64, failed bool, shardings []uint, err error) {
+	if err = st.PreCheck(); err != nil {
+		return
+	}
+	tx := st.msg //因为st.msg的接口全部在transaction中实现,所以此处的局部变量msg实际是transaction类型
+	var addr common.Address
+	from := tx.From()
+	if from == addr {
+		return nil, 0, false, shardings, errors.New("state_transition," + name + " ,from is nil")
+	}
+	gas, err := IntrinsicGas(st.data)
+	if err != nil {
+		return nil, 0, false, shardings, err
+	}
+	if err = st.UseGas(gas); err != nil {
+		return nil, 0, false, shardings, err
+	}
+	if err = apply(from, tx.Data()); err != nil {
+		return nil, 0, false, shardings, err
+	}
+
+	st.state.SetNonce(st.msg.GetTxCurrency(), from, st.state.GetNonce(st.msg.GetTxCurrency(), from)+1)
+	shardings = append(shardings, uint(from[0]))
+	gasaddr, coinrange := st.getCoinAddress(tx.GetTxCurrency())
+	st.RefundGas(coinrange)
+	st.state.AddBalance(coinrange, common.MainAccount, gasaddr, new(big.Int).Mul(new(big.Int).SetUint64(st.GasUsed()), st.gasPrice)) //给对应币种奖励账户加钱
+	return ret, st.GasUsed(), false, shardings, nil
+}
+
+// CallEvidenceTx 执行双签证据交易, 证据有效时惩罚作恶的验证者
+func (st *StateTransition) CallEvidenceTx() (ret []byte, usedGas uint64, failed bool, shardings []uint, err error) {
+	return st.callSystemTx("evidence tx", func(from common.Address, data []byte) error {
+		ev, err := types.DecodeDoubleSignEvidence(data)
+		if err != nil {
+			log.Error("CallEvidenceTx", "decode evidence err", err)
+			return err
+		}
+
+		number := st.evm.BlockNumber.Uint64()
+		v1Deposit := func(account common.Address) *big.Int {
+			if st.evm.GetHash == nil || number == 0 {
+				return new(big.Int)
+			}
+			deposits, err := ca.GetElectedByHeightAndRoleByHash(st.evm.GetHash(number-1), common.RoleValidator)
+			if err != nil {
+				return new(big.Int)
+			}
+			for _, v := range deposits {
+				if v.Address == account && v.Deposit != nil {
+					return v.Deposit
+				}
+			}
+			return new(big.Int)
+		}
+		if _, _, err = applyDoubleSignEvidence(st.state, ev, number, v1Deposit); err != nil {
+			log.Error("CallEvidenceTx", "apply evidence err", err, "evidence", ev.Hash().Hex())
+			return err
+		}
+		return nil
+	})
+}
+
+// CallRewardGovernanceTx 执行奖励分配比例治理交易, 提案经超过2/3的当选验证者签名后在下个广播区块生效
+func (st *StateTransition) CallRewardGovernanceTx() (ret []byte, usedGas uint64, failed bool, shardings []uint, err error) {
+	return st.callSystemTx("reward governance tx", func(from common.Address, data []byte) error {
+		proposal, err := DecodeRewardGovernanceProposal(data)
+		if err != nil {
+			log.Error("CallRewardGovernanceTx", "decode proposal err", err)
+			return err
+		}
+		if err = applyRewardGovernanceProposal(st.state, proposal, st.evm.BlockNumber.Uint64()); err != nil {
+			log.Error("CallRewardGovernanceTx", "apply proposal err", err, "proposal number", proposal.Number)
+			return err
+		}
+		return nil
+	})
+}
+
+// CallParamUpdateTx 执行链参数更新治理交易, 提案经超过2/3的当选验证者签名后在排期的选举区块生效
+func (st *StateTransition) CallParamUpdateTx() (ret []byte, usedGas uint64, failed bool, shardings []uint, err error) {
+	return st.callSystemTx("param update tx", func(from common.Address, data []byte) error {
+		proposal, err := DecodeParamUpdateProposal(data)
+		if err != nil {
+			log.Error("CallParamUpdateTx", "decode proposal err", err)
+			return err
+		}
+		if err = applyParamUpdateProposal(st.state, proposal, st.evm.BlockNumber.Uint64()); err != nil {
+			log.Error("CallParamUpdateTx", "apply proposal err", err, "proposal number", proposal.Number)
+			return err
+		}
+		return nil
+	})
+}
+
+// CallBridgeAttestTx 执行跨链证明交易, 超过2/3的当选验证者签名的跨链事件记录到状态中供中继者读取
+func (st *StateTransition) CallBridgeAttestTx() (ret []byte, usedGas uint64, failed bool, shardings []uint, err error) {
+	return st.callSystemTx("bridge attest tx", func(from common.Address, data []byte) error {
+		att, err := DecodeBridgeAttestation(data)
+		if err != nil {
+			log.Error("CallBridgeAttestTx", "decode attestation err", err)
+			return err
+		}
+		if err = applyBridgeAttestation(st.state, st.evm.GetHash, att, st.evm.BlockNumber.Uint64()); err != nil {
+			log.Error("CallBridgeAttestTx", "apply attestation err", err, "event number", att.Event.BlockNumber)
+			return err
+		}
+		return nil
+	})
+}
+
+// CallConsensusKeyTx 执行共识密钥注册交易, 交易发送者为注册共识密钥的抵押账户
+func (st *StateTransition) CallConsensusKeyTx() (ret []byte, usedGas uint64, failed bool, shardings []uint, err error) {
+	return st.callSystemTx("consensus key tx", func(from common.Address, data []byte) error {
+		registration, err := DecodeConsensusKeyRegistration(data)
+		if err != nil {
+			log.Error("CallConsensusKeyTx", "decode registration err", err)
+			return err
+		}
+		if err = applyConsensusKeyRegistration(st.state, from, registration, st.evm.BlockNumber.Uint64()); err != nil {
+			log.Error("CallConsensusKeyTx", "apply registration err", err, "account", from.Hex())
+			return err
+		}
+		return nil
+	})
+}
+
 func (st *StateTransition) CallNormalTx() (ret []byte, usedGas uint64, failed bool, shardings []uint, err error) {
 	if err = st.PreCheck(); err != nil {
 		return
@@ -885,6 +1074,9 @@ func (st *StateTransition) CallNormalTx() (ret []byte, usedGas uint64, failed bo
 			gas += tmpgas
 		}
 	}
+	if IsWasmCreation(st.state, toaddr, st.data, evm.BlockNumber.Uint64()) {
+		gas += WasmIntrinsicGas(st.data)
+	}
 	if err = st.UseGas(gas); err != nil {
 		return nil, 0, false, shardings, err
 	}
@@ -898,7 +1090,7 @@ func (st *StateTransition) CallNormalTx() (ret []byte, usedGas uint64, failed bo
 		ret, st.gas, tmpshard, vmerr = evm.Call(sender, st.To(), st.data, st.gas, st.value)
 	}
 	if vmerr == nil && (&tmpExtra) != nil && len(tmpExtra) > 0 {
-		for _, ex := range tmpExtra[0].ExtraTo {
+		for i, ex := range tmpExtra[0].ExtraTo {
 			var caddr common.Address
 			if toaddr == nil {
 				ret, caddr, st.gas, vmerr = evm.Create(sender, ex.Payload, st.gas, ex.Amount)
@@ -907,6 +1099,7 @@ func (st *StateTransition) CallNormalTx() (ret []byte, usedGas uint64, failed bo
 				// Increment the nonce for the next transaction
 				ret, st.gas, tmpshard, vmerr = evm.Call(sender, *ex.Recipient, ex.Payload, st.gas, ex.Amount)
 			}
+			st.setExtraStatus(i, vmerr)
 			if vmerr != nil {
 				break
 			}
@@ -975,7 +1168,7 @@ func (st *StateTransition) CallAuthTx() (ret []byte, usedGas uint64, failed bool
 		ret, st.gas, tmpshard, vmerr = evm.Call(sender, st.To(), st.data, st.gas, st.value)
 	}
 	if vmerr == nil && (&tmpExtra) != nil && len(tmpExtra) > 0 {
-		for _, ex := range tmpExtra[0].ExtraTo {
+		for i, ex := range tmpExtra[0].ExtraTo {
 			if toaddr == nil {
 				log.Error("state_transition callAuthTx extro to is nil")
 				return nil, 0, false, shardings, ErrTXToNil
@@ -983,6 +1176,7 @@ func (st *StateTransition) CallAuthTx() (ret []byte, usedGas uint64, failed bool
 				// Increment the nonce for the next transaction
 				ret, st.gas, tmpshard, vmerr = evm.Call(sender, *ex.Recipient, ex.Payload, st.gas, ex.Amount)
 			}
+			st.setExtraStatus(i, vmerr)
 			if vmerr != nil {
 				break
 			}
@@ -1008,6 +1202,12 @@ func (st *StateTransition) CallAuthTx() (ret []byte, usedGas uint64, failed bool
 		log.Error("CallAuthTx Unmarshal err")
 		return nil, st.GasUsed(), true, shardings, nil
 	}
+	if manversion.VersionCmp(matrixstate.GetVersionInfo(st.state), manversion.VersionAIMine) < 0 {
+		//版本升级前不支持按交易类型委托
+		for i := range EntrustList {
+			EntrustList[i].EntrustTxTypes = nil
+		}
+	}
 
 	HeightAuthDataList := make([]common.AuthType, 0) //按高度存储授权数据列表
 	TimeAuthDataList := make([]common.AuthType, 0)   //按时间存储授权数据列表
@@ -1102,6 +1302,7 @@ func (st *StateTransition) CallAuthTx() (ret []byte, usedGas uint64, failed bool
 			t_authData.EndHeight = EntrustData.EndHeight
 			t_authData.IsEntrustSign = EntrustData.IsEntrustSign
 			t_authData.IsEntrustGas = EntrustData.IsEntrustGas
+			t_authData.EntrustTxTypes = EntrustData.EntrustTxTypes
 			t_authData.AuthAddres = Authfrom
 			t_authData.EntrustCount = EntrustData.EntrustCount
 			HeightAuthDataList = append(HeightAuthDataList, *t_authData)
@@ -1122,6 +1323,7 @@ func (st *StateTransition) CallAuthTx() (ret []byte, usedGas uint64, failed bool
 			t_authData.EndTime = EntrustData.EndTime
 			t_authData.IsEntrustSign = EntrustData.IsEntrustSign
 			t_authData.IsEntrustGas = EntrustData.IsEntrustGas
+			t_authData.EntrustTxTypes = EntrustData.EntrustTxTypes
 			t_authData.AuthAddres = Authfrom
 			t_authData.EntrustCount = EntrustData.EntrustCount
 			TimeAuthDataList = append(TimeAuthDataList, *t_authData)
@@ -1143,6 +1345,7 @@ func (st *StateTransition) CallAuthTx() (ret []byte, usedGas uint64, failed bool
 				t_authData.EndTime = EntrustData.EndTime
 				t_authData.IsEntrustSign = EntrustData.IsEntrustSign
 				t_authData.IsEntrustGas = EntrustData.IsEntrustGas
+				t_authData.EntrustTxTypes = EntrustData.EntrustTxTypes
 				t_authData.AuthAddres = Authfrom
 				t_authData.EntrustCount = EntrustData.EntrustCount
 				CountAuthDataList = append(CountAuthDataList, *t_authData)
@@ -1256,7 +1459,7 @@ func (st *StateTransition) CallCancelAuthTx() (ret []byte, usedGas uint64, faile
 		ret, st.gas, tmpshard, vmerr = evm.Call(sender, st.To(), st.data, st.gas, st.value)
 	}
 	if vmerr == nil && (&tmpExtra) != nil && len(tmpExtra) > 0 {
-		for _, ex := range tmpExtra[0].ExtraTo {
+		for i, ex := range tmpExtra[0].ExtraTo {
 			if toaddr == nil {
 				log.Error("state transition callAuthTx Extro to is nil")
 				return nil, 0, false, shardings, ErrTXToNil
@@ -1264,6 +1467,7 @@ func (st *StateTransition) CallCancelAuthTx() (ret []byte, usedGas uint64, faile
 				// Increment the nonce for the next transaction
 				ret, st.gas, tmpshard, vmerr = evm.Call(sender, *ex.Recipient, ex.Payload, st.gas, ex.Amount)
 			}
+			st.setExtraStatus(i, vmerr)
 			if vmerr != nil {
 				break
 			}
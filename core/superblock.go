@@ -0,0 +1,168 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"bytes"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/pkg/errors"
+)
+
+// ErrSuperBlockMismatch 合并签名的文件不是同一个超级区块
+var ErrSuperBlockMismatch = errors.New("super block files describe different blocks")
+
+// SuperBlockSignHash 超级节点签名的超级区块哈希
+func SuperBlockSignHash(superBlockGen *Genesis) (common.Hash, error) {
+	block := superBlockGen.ToSuperBlock()
+	if block == nil {
+		return common.Hash{}, errors.New("genesis super block failed")
+	}
+	return block.HashNoSigns(), nil
+}
+
+// MergeSuperBlockSignatures 合并同一超级区块多份签名文件中的签名, 重复的签名只保留一份.
+// 返回第一份文件的副本, 各文件中除签名外的内容必须一致
+func MergeSuperBlockSignatures(gens []*Genesis) (*Genesis, error) {
+	if len(gens) == 0 {
+		return nil, errors.New("no super block to merge")
+	}
+	hash, err := SuperBlockSignHash(gens[0])
+	if err != nil {
+		return nil, err
+	}
+	merged := *gens[0]
+	merged.Signatures = make([]common.Signature, 0)
+	for i, gen := range gens {
+		if i > 0 {
+			other, err := SuperBlockSignHash(gen)
+			if err != nil {
+				return nil, err
+			}
+			if other != hash {
+				return nil, ErrSuperBlockMismatch
+			}
+		}
+		for _, sign := range gen.Signatures {
+			if !containsSignature(merged.Signatures, sign) {
+				merged.Signatures = append(merged.Signatures, sign)
+			}
+		}
+	}
+	return &merged, nil
+}
+
+func containsSignature(signs []common.Signature, sign common.Signature) bool {
+	for _, exist := range signs {
+		if bytes.Equal(exist.Bytes(), sign.Bytes()) {
+			return true
+		}
+	}
+	return false
+}
+
+// SuperBlockSigners 恢复签名账户, 返回其中属于授权超级节点的账户, 同一账户只计一次
+func SuperBlockSigners(hash common.Hash, signs []common.Signature, authorities []common.Address) []common.Address {
+	signers := make([]common.Address, 0)
+	for _, sign := range signs {
+		account, validate, err := crypto.VerifySignWithValidate(hash.Bytes(), sign.Bytes())
+		if err != nil || !validate {
+			continue
+		}
+		authorized := false
+		for _, authority := range authorities {
+			if authority == account {
+				authorized = true
+				break
+			}
+		}
+		if !authorized {
+			continue
+		}
+		duplicate := false
+		for _, signer := range signers {
+			if signer == account {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			signers = append(signers, account)
+		}
+	}
+	return signers
+}
+
+// SuperBlockAuthorities 超级区块须由其签名的超级节点账户, 与共识引擎导入超级区块时一样取自当前区块状态
+func (bc *BlockChain) SuperBlockAuthorities(superBlockGen *Genesis) ([]common.Address, error) {
+	return bc.GetBlockSuperAccounts(bc.CurrentBlock().Hash())
+}
+
+// VerifySuperBlock 生成超级区块并校验状态根、交易、超级区块序号和超级节点签名, 不插入区块链.
+// 超级区块已是本地最新的超级区块时known为true
+func (bc *BlockChain) VerifySuperBlock(superBlockGen *Genesis) (block *types.Block, known bool, err error) {
+	if nil == superBlockGen {
+		return nil, false, errors.New("super block is nil")
+	}
+	if superBlockGen.Number <= 0 {
+		return nil, false, errors.Errorf("super block`s number(%d) is too low", superBlockGen.Number)
+	}
+	parent := bc.GetBlockByHash(superBlockGen.ParentHash)
+	if nil == parent {
+		return nil, false, errors.Errorf("get parent block by hash(%s) err", superBlockGen.ParentHash.Hex())
+	}
+	if parent.NumberU64()+1 != superBlockGen.Number {
+		return nil, false, errors.Errorf("parent block number(%d) + 1 != super block number(%d)", parent.NumberU64(), superBlockGen.Number)
+	}
+
+	block = superBlockGen.GenSuperBlock(parent.Header(), bc.db, bc.stateCache, bc.chainConfig)
+	if nil == block {
+		return nil, false, errors.New("genesis super block failed")
+	}
+
+	if !block.IsSuperBlock() {
+		return nil, false, errors.New("err, genesis block is not super block!")
+	}
+	blockHash := types.RlpHash(block.Root())
+	superHash := types.RlpHash(superBlockGen.Roots)
+	if blockHash != superHash {
+		return nil, false, errors.Errorf("root not match, calc root(%s) != genesis root(%s)", blockHash, superHash)
+	}
+
+	for _, currencie := range block.Currencies() {
+		for _, coinRoot := range superBlockGen.Roots {
+			if currencie.CurrencyName == coinRoot.Cointyp {
+				bltxHash := types.DeriveShaHash(currencie.Transactions.TxHashs)
+				if bltxHash != coinRoot.TxHash {
+					return nil, false, errors.Errorf("txHash not match, calc txHash(%s) != genesis txHash(%s)", currencie.Header.TxHash.TerminalString(), coinRoot.TxHash.TerminalString())
+				}
+			}
+		}
+	}
+	sbh, err := bc.GetSuperBlockNum()
+	if nil != err {
+		return nil, false, errors.Errorf("get super seq error")
+	}
+	superBlock := bc.GetBlockByNumber(sbh)
+	if nil != superBlock {
+		if block.Hash() == superBlock.Hash() {
+			return block, true, nil
+		}
+	}
+	sbs, err := bc.GetSuperBlockSeq()
+	if nil != err {
+		return nil, false, errors.Errorf("get super seq error")
+	}
+	if block.Header().SuperBlockSeq() <= sbs {
+		return nil, false, errors.Errorf("SuperBlockSeq not match, current seq(%v) < genesis block(%v)", sbs, block.Header().SuperBlockSeq())
+	}
+
+	if err := bc.DPOSEngine(block.Header().Version).VerifyBlock(bc, block.Header()); err != nil {
+		return nil, false, errors.Errorf("verify super block err(%v)", err)
+	}
+	return block, false, nil
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+)
+
+func testSuperBlockGen(timestamp uint64) *Genesis {
+	return &Genesis{
+		ParentHash: common.HexToHash("0x01"),
+		Number:     100,
+		Timestamp:  timestamp,
+		Difficulty: big.NewInt(1),
+		ExtraData:  make([]byte, 8),
+		Signatures: make([]common.Signature, 0),
+	}
+}
+
+func testSignSuperBlock(t *testing.T, gen *Genesis, key *ecdsa.PrivateKey) {
+	hash, err := SuperBlockSignHash(gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign, err := crypto.Sign(hash.Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen.Signatures = append(gen.Signatures, common.BytesToSignature(sign))
+}
+
+func TestMergeSuperBlockSignatures(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	authorities := make([]common.Address, 2)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		if i < len(authorities) {
+			authorities[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		}
+	}
+
+	first, second := testSuperBlockGen(1000), testSuperBlockGen(1000)
+	testSignSuperBlock(t, first, keys[0])
+	testSignSuperBlock(t, second, keys[0])
+	testSignSuperBlock(t, second, keys[1])
+	testSignSuperBlock(t, second, keys[2])
+
+	merged, err := MergeSuperBlockSignatures([]*Genesis{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Signatures) != 3 {
+		t.Fatalf("合并后签名数量错误 %d", len(merged.Signatures))
+	}
+	hash, _ := SuperBlockSignHash(merged)
+	if signers := SuperBlockSigners(hash, merged.Signatures, authorities); len(signers) != 2 {
+		t.Fatalf("授权签名账户数量错误 %v", signers)
+	}
+	if len(first.Signatures) != 1 {
+		t.Fatalf("原文件签名被修改")
+	}
+
+	// 不同的超级区块不能合并
+	if _, err := MergeSuperBlockSignatures([]*Genesis{first, testSuperBlockGen(2000)}); err != ErrSuperBlockMismatch {
+		t.Fatalf("合并不同超级区块错误 %v", err)
+	}
+}
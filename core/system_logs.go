@@ -0,0 +1,154 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// 系统日志: 区块奖励和惩罚不是由交易产生的日志, 不进入收据和区块头的bloom,
+// 由节点在执行区块时生成并保存在本地, 合并到bloombits索引和日志查询中
+
+var (
+	// RewardLogTopic 奖励日志的topic, topics为[RewardLogTopic, 账户, 奖励类型], data为奖励金额
+	RewardLogTopic = crypto.Keccak256Hash([]byte("Reward(address,uint8,uint256)"))
+
+	// SlashLogTopic 惩罚日志的topic, topics为[SlashLogTopic, 抵押账户, keccak256(原因)],
+	// data为惩罚金额和禁止参选的广播周期数
+	SlashLogTopic = crypto.Keccak256Hash([]byte("Slash(address,string,uint256,uint16)"))
+
+	// systemLogsPrefix 系统日志的键前缀, systemLogsPrefix + num (uint64 big endian) + hash
+	systemLogsPrefix = []byte("SystemLogs-")
+)
+
+func systemLogsKey(hash common.Hash, number uint64) []byte {
+	key := make([]byte, len(systemLogsPrefix)+8+common.HashLength)
+	copy(key, systemLogsPrefix)
+	binary.BigEndian.PutUint64(key[len(systemLogsPrefix):], number)
+	copy(key[len(systemLogsPrefix)+8:], hash[:])
+	return key
+}
+
+// newRewardLogs 根据区块的奖励交易生成奖励日志, 按币种分组, 日志地址为奖励的发放账户
+func newRewardLogs(rewards []common.RewarTx) []types.CoinLogs {
+	var (
+		coins  []string
+		byCoin = make(map[string][]*types.Log)
+	)
+	for _, reward := range rewards {
+		coin := reward.CoinRange
+		if coin == "" {
+			coin = params.MAN_COIN
+		}
+		accounts := make([]common.Address, 0, len(reward.To_Amont))
+		for account, amount := range reward.To_Amont {
+			if amount != nil && amount.Sign() > 0 {
+				accounts = append(accounts, account)
+			}
+		}
+		// 奖励按map保存, 排序后日志顺序与执行无关
+		sort.Slice(accounts, func(i, j int) bool { return bytes.Compare(accounts[i][:], accounts[j][:]) < 0 })
+
+		for _, account := range accounts {
+			if _, ok := byCoin[coin]; !ok {
+				coins = append(coins, coin)
+			}
+			byCoin[coin] = append(byCoin[coin], &types.Log{
+				Address: reward.Fromaddr,
+				Topics:  []common.Hash{RewardLogTopic, account.Hash(), common.BigToHash(new(big.Int).SetUint64(uint64(reward.RewardTyp)))},
+				Data:    common.BigToHash(reward.To_Amont[account]).Bytes(),
+			})
+		}
+	}
+	logs := make([]types.CoinLogs, 0, len(coins))
+	for _, coin := range coins {
+		logs = append(logs, types.CoinLogs{CoinType: coin, Logs: byCoin[coin]})
+	}
+	return logs
+}
+
+// newSlashLogs 根据惩罚事件生成惩罚日志, 日志地址为抵押合约账户
+func newSlashLogs(events []mc.SlashEvent) []types.CoinLogs {
+	if len(events) == 0 {
+		return nil
+	}
+	logs := make([]*types.Log, 0, len(events))
+	for _, ev := range events {
+		penalty := ev.Penalty
+		if penalty == nil {
+			penalty = new(big.Int)
+		}
+		data := make([]byte, 0, 2*common.HashLength)
+		data = append(data, common.BigToHash(penalty).Bytes()...)
+		data = append(data, common.BigToHash(new(big.Int).SetUint64(uint64(ev.ProhibitCycles))).Bytes()...)
+
+		logs = append(logs, &types.Log{
+			Address: common.ContractAddress,
+			Topics:  []common.Hash{SlashLogTopic, ev.Account.Hash(), crypto.Keccak256Hash([]byte(ev.Reason))},
+			Data:    data,
+		})
+	}
+	return []types.CoinLogs{{CoinType: params.MAN_COIN, Logs: logs}}
+}
+
+// SystemLogsBloom 计算系统日志的bloom
+func SystemLogsBloom(logs []types.CoinLogs) types.Bloom {
+	var all []*types.Log
+	for _, coinLogs := range logs {
+		all = append(all, coinLogs.Logs...)
+	}
+	return types.BytesToBloom(types.LogsBloom(all).Bytes())
+}
+
+// WriteSystemLogs 保存区块的系统日志
+func WriteSystemLogs(db mandb.Putter, hash common.Hash, number uint64, logs []types.CoinLogs) {
+	data, err := rlp.EncodeToBytes(logs)
+	if err != nil {
+		log.Crit("Failed to RLP encode system logs", "err", err)
+	}
+	if err := db.Put(systemLogsKey(hash, number), data); err != nil {
+		log.Crit("Failed to store system logs", "err", err)
+	}
+}
+
+// ReadSystemLogs 读取区块的系统日志, 并填充日志的区块信息. 系统日志的交易哈希为空, 序号在系统日志内从0开始
+func ReadSystemLogs(db mandb.Database, hash common.Hash, number uint64) []types.CoinLogs {
+	data, _ := db.Get(systemLogsKey(hash, number))
+	if len(data) == 0 {
+		return nil
+	}
+	var logs []types.CoinLogs
+	if err := rlp.DecodeBytes(data, &logs); err != nil {
+		log.Error("Invalid system logs RLP", "hash", hash, "err", err)
+		return nil
+	}
+	index := uint(0)
+	for _, coinLogs := range logs {
+		for _, l := range coinLogs.Logs {
+			l.BlockNumber = number
+			l.BlockHash = hash
+			l.Index = index
+			index++
+		}
+	}
+	return logs
+}
+
+// GetSystemLogs 返回区块的奖励和惩罚日志
+func (bc *BlockChain) GetSystemLogs(hash common.Hash, number uint64) []types.CoinLogs {
+	return ReadSystemLogs(bc.db, hash, number)
+}
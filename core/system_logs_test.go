@@ -0,0 +1,77 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+func TestSystemLogs(t *testing.T) {
+	var (
+		miner     = common.HexToAddress("0x01")
+		validator = common.HexToAddress("0x02")
+		offender  = common.HexToAddress("0x03")
+	)
+	rewards := []common.RewarTx{{
+		CoinRange: params.MAN_COIN,
+		Fromaddr:  common.BlkMinerRewardAddress,
+		To_Amont:  map[common.Address]*big.Int{validator: big.NewInt(2), miner: big.NewInt(1), offender: new(big.Int)},
+		RewardTyp: common.RewardMinerType,
+	}}
+	slashes := []mc.SlashEvent{{Number: 10, Account: offender, Reason: "heartbeat", Penalty: big.NewInt(5), ProhibitCycles: 2}}
+
+	logs := append(newSlashLogs(slashes), newRewardLogs(rewards)...)
+	if len(logs) != 2 || len(logs[0].Logs) != 1 || len(logs[1].Logs) != 2 {
+		t.Fatalf("log count mismatch: %v", logs)
+	}
+	// Zero rewards are skipped, the others are ordered by account
+	if have := logs[1].Logs[0].Topics[1]; have != miner.Hash() {
+		t.Errorf("reward order mismatch: have %x, want %x", have, miner.Hash())
+	}
+	if have := new(big.Int).SetBytes(logs[1].Logs[1].Data); have.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("reward amount mismatch: have %v, want 2", have)
+	}
+	bloom := SystemLogsBloom(logs)
+	for _, topic := range []common.Hash{RewardLogTopic, SlashLogTopic, offender.Hash()} {
+		if !types.BloomLookup(bloom, topic) {
+			t.Errorf("topic %x missing from bloom", topic)
+		}
+	}
+
+	db := mandb.NewMemDatabase()
+	hash := common.HexToHash("0xff")
+	WriteSystemLogs(db, hash, 10, logs)
+
+	stored := ReadSystemLogs(db, hash, 10)
+	if len(stored) != 2 || stored[0].CoinType != params.MAN_COIN {
+		t.Fatalf("stored logs mismatch: %v", stored)
+	}
+	for i, l := range append(stored[0].Logs, stored[1].Logs...) {
+		if l.BlockHash != hash || l.BlockNumber != 10 || l.Index != uint(i) {
+			t.Errorf("log %d: block fields mismatch: hash %x, number %d, index %d", i, l.BlockHash, l.BlockNumber, l.Index)
+		}
+	}
+	if ReadSystemLogs(db, hash, 11) != nil {
+		t.Errorf("logs returned for another block")
+	}
+}
+
+func TestSystemLogsFollowState(t *testing.T) {
+	st := newForkTestState()
+	st.AddSystemLogs(newSlashLogs([]mc.SlashEvent{{Account: common.HexToAddress("0x02"), Reason: "test", Penalty: big.NewInt(1)}}))
+	st.AddSystemLogs(newRewardLogs([]common.RewarTx{{Fromaddr: common.HexToAddress("0x03"), To_Amont: map[common.Address]*big.Int{common.HexToAddress("0x04"): big.NewInt(2)}}}))
+
+	// The logs travel with the state to the block writer, whatever the sealed block hash
+	if logs := st.Copy().SystemLogs(); len(logs) != 2 {
+		t.Fatalf("copied state system logs mismatch: have %d, want 2", len(logs))
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+// senderCacher is a concurrent transaction sender recoverer and cacher, shared
+// by the normal and the broadcast transaction pools.
+var senderCacher = newTxSenderCacher(runtime.NumCPU())
+
+// txSenderCacherRequest is a request for recovering transaction senders with a
+// specific signature scheme and caching it into the transactions themselves.
+//
+// The inc field defines the number of transactions to skip after each recovery,
+// which is used to feed the same underlying input array to different threads but
+// ensure they process the early transactions fast.
+type txSenderCacherRequest struct {
+	signer types.Signer
+	txs    []types.SelfTransaction
+	inc    int
+	done   *sync.WaitGroup
+}
+
+// txSenderCacher is a helper structure to concurrently ecrecover transaction
+// senders from digital signatures on background threads.
+type txSenderCacher struct {
+	threads int
+	tasks   chan *txSenderCacherRequest
+}
+
+// newTxSenderCacher creates a new transaction sender background cacher and starts
+// as many processing goroutines as allowed by the GOMAXPROCS on construction.
+func newTxSenderCacher(threads int) *txSenderCacher {
+	cacher := &txSenderCacher{
+		tasks:   make(chan *txSenderCacherRequest, threads),
+		threads: threads,
+	}
+	for i := 0; i < threads; i++ {
+		go cacher.cache()
+	}
+	return cacher
+}
+
+// cache is an infinite loop, caching transaction senders from various forms of
+// data structures.
+func (cacher *txSenderCacher) cache() {
+	for task := range cacher.tasks {
+		for i := 0; i < len(task.txs); i += task.inc {
+			types.Sender(task.signer, task.txs[i])
+		}
+		if task.done != nil {
+			task.done.Done()
+		}
+	}
+}
+
+// recover recovers the senders from a batch of transactions and caches them
+// back into the same data structures. There is no validation being done, nor
+// any reaction to invalid signatures. That is up to calling code later.
+func (cacher *txSenderCacher) recover(signer types.Signer, txs []types.SelfTransaction) {
+	cacher.schedule(signer, txs, nil)
+}
+
+// recoverWait is the blocking version of recover, returning only once every
+// sender of the batch has been recovered. Calling it before acquiring a pool
+// lock keeps the expensive ecrecover out of the critical section.
+func (cacher *txSenderCacher) recoverWait(signer types.Signer, txs []types.SelfTransaction) {
+	var done sync.WaitGroup
+	cacher.schedule(signer, txs, &done)
+	done.Wait()
+}
+
+// schedule splits the batch between the cacher threads.
+func (cacher *txSenderCacher) schedule(signer types.Signer, txs []types.SelfTransaction, done *sync.WaitGroup) {
+	// If there's nothing to recover, abort
+	if len(txs) == 0 {
+		return
+	}
+	// Ensure we have meaningful task sizes and schedule the recoveries
+	tasks := cacher.threads
+	if len(txs) < tasks*4 {
+		tasks = (len(txs) + 3) / 4
+	}
+	if done != nil {
+		done.Add(tasks)
+	}
+	for i := 0; i < tasks; i++ {
+		cacher.tasks <- &txSenderCacherRequest{
+			signer: signer,
+			txs:    txs[i:],
+			inc:    tasks,
+			done:   done,
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+)
+
+// Tests that local transactions written into the journal are replayed on load,
+// and that rotation regenerates the journal from the given pool contents.
+func TestTxJournalRotateLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txjournal")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	txs := []*types.Transaction{transaction(0, 100000, key), transaction(1, 100000, key)}
+
+	journal := newTxJournal(filepath.Join(dir, "transactions.rlp"))
+	if err := journal.rotate(map[common.Address][]*types.Transaction{addr: txs[:1]}); err != nil {
+		t.Fatalf("failed to rotate journal: %v", err)
+	}
+	if err := journal.insert(txs[1]); err != nil {
+		t.Fatalf("failed to insert transaction: %v", err)
+	}
+	journal.close()
+
+	loaded := make([]*types.Transaction, 0)
+	err = newTxJournal(journal.path).load(func(batch []*types.Transaction) []error {
+		loaded = append(loaded, batch...)
+		return make([]error, len(batch))
+	})
+	if err != nil {
+		t.Fatalf("failed to load journal: %v", err)
+	}
+	if len(loaded) != len(txs) {
+		t.Fatalf("loaded transaction count mismatch: have %d, want %d", len(loaded), len(txs))
+	}
+	for i, tx := range loaded {
+		if tx.Hash() != txs[i].Hash() {
+			t.Errorf("transaction %d: hash mismatch: have %x, want %x", i, tx.Hash(), txs[i].Hash())
+		}
+	}
+}
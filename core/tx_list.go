@@ -10,6 +10,7 @@ import (
 	"math/big"
 	"sort"
 
+	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/params"
 )
@@ -272,23 +273,23 @@ func (l *txList) Overlaps(tx *types.Transaction) bool {
 // If the new transaction is accepted into the list, the lists' cost and gas
 // thresholds are also potentially updated.
 func (l *txList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Transaction) {
-	//// If there's an older better transaction, abort
-	//old := l.txs.Get(tx.Nonce())
-	//if old != nil {
-	//	threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(priceBump))), big.NewInt(100))
-	//	// Have to ensure that the new gas price is higher than the old gas
-	//	// price as well as checking the percentage threshold to ensure that
-	//	// this is accurate for low (Wei-level) gas price replacements
-	//	if old.GasPrice().Cmp(tx.GasPrice()) >= 0 || threshold.Cmp(tx.GasPrice()) > 0 {
-	//		return false, nil
-	//	}
-	//}
-	// Otherwise overwrite the old transaction with the current one
 	sm, ok := l.txs[tx.GetTxCurrency()]
 	if !ok {
 		l.txs[tx.GetTxCurrency()] = newTxSortedMap()
 		sm = l.txs[tx.GetTxCurrency()]
 	}
+	// If there's an older better transaction, abort
+	old := sm.Get(tx.Nonce())
+	if old != nil {
+		threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(priceBump))), big.NewInt(100))
+		// Have to ensure that the new gas price is higher than the old gas
+		// price as well as checking the percentage threshold to ensure that
+		// this is accurate for low (Wei-level) gas price replacements
+		if old.GasPrice().Cmp(tx.GasPrice()) >= 0 || threshold.Cmp(tx.GasPrice()) > 0 {
+			return false, nil
+		}
+	}
+	// Otherwise overwrite the old transaction with the current one
 	sm.Put(tx)
 	if cost := tx.Cost(); l.costcap.Cmp(cost) < 0 {
 		l.costcap = cost
@@ -296,7 +297,7 @@ func (l *txList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Tran
 	if gas := tx.Gas(); l.gascap < gas {
 		l.gascap = gas
 	}
-	return true, nil
+	return true, old
 }
 
 // Forward removes all transactions from the list with a nonce lower than the
@@ -403,7 +404,6 @@ func (l *txList) Empty(typ string) bool {
 //	return l.txs.Flatten()
 //}
 
-/*
 // priceHeap is a heap.Interface implementation over transactions for retrieving
 // price-sorted transactions to discard when the pool fills up.
 type priceHeap []*types.Transaction
@@ -476,45 +476,28 @@ func (l *txPricedList) Removed() {
 	heap.Init(l.items)
 }
 
-// Cap finds all the transactions below the given price threshold, drops them
-// from the priced list and returs them for further removal from the entire pool.
-func (l *txPricedList) Cap(threshold *big.Int, local *accountSet) types.Transactions {
-	drop := make(types.Transactions, 0, 128) // Remote underpriced transactions to drop
-	save := make(types.Transactions, 0, 64)  // Local underpriced transactions to keep
-
-	for len(*l.items) > 0 {
-		// Discard stale transactions if found during cleanup
-		tx := heap.Pop(l.items).(*types.Transaction)
-		if l.all.Get(tx.Hash()) == nil {
-			l.stales--
-			continue
-		}
-		// Stop the discards if we've reached the threshold
-		if tx.GasPrice().Cmp(threshold) >= 0 {
-			save = append(save, tx)
-			break
-		}
-		// Non stale transaction found, discard unless local
-		if local.containsTx(tx) {
-			save = append(save, tx)
-		} else {
-			drop = append(drop, tx)
-		}
-	}
-	for _, tx := range save {
-		heap.Push(l.items, tx)
-	}
-	return drop
-}
-
 // Underpriced checks whether a transaction is cheaper than (or as cheap as) the
-// lowest priced transaction currently being tracked.
+// lowest priced remote transaction currently being tracked.
 func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) bool {
 	// Local transactions cannot be underpriced
 	if local.containsTx(tx) {
 		return false
 	}
-	// Discard stale price points if found at the heap start
+	cheapest := l.cheapest(local)
+	if cheapest == nil {
+		return false
+	}
+	return cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0
+}
+
+// cheapest 返回堆中价格最低的远程交易, 顺带清理堆顶的过期交易
+func (l *txPricedList) cheapest(local *accountSet) *types.Transaction {
+	save := make(types.Transactions, 0, 64)
+	defer func() {
+		for _, tx := range save {
+			heap.Push(l.items, tx)
+		}
+	}()
 	for len(*l.items) > 0 {
 		head := []*types.Transaction(*l.items)[0]
 		if l.all.Get(head.Hash()) == nil {
@@ -522,15 +505,13 @@ func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) boo
 			heap.Pop(l.items)
 			continue
 		}
-		break
-	}
-	// Check if the transaction is underpriced or not
-	if len(*l.items) == 0 {
-		log.Error("Pricing query for empty pool") // This cannot happen, print to catch programming errors
-		return false
+		if local.containsTx(head) {
+			save = append(save, heap.Pop(l.items).(*types.Transaction))
+			continue
+		}
+		return head
 	}
-	cheapest := []*types.Transaction(*l.items)[0]
-	return cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0
+	return nil
 }
 
 // Discard finds a number of most underpriced transactions, removes them from the
@@ -559,5 +540,3 @@ func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions
 	}
 	return drop
 }
-
-*/
@@ -5,9 +5,11 @@
 package core
 
 import (
+	"math/big"
 	"math/rand"
 	"testing"
 
+	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/crypto"
 )
@@ -37,3 +39,97 @@ func TestStrictTxListAdd(t *testing.T) {
 		}
 	}
 }
+
+// Tests that a transaction with the same nonce only replaces the listed one if
+// its gas price is bumped by at least the configured percentage.
+func TestTxListReplace(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	orig := pricedTransaction(0, 100000, big.NewInt(100), key)
+	list := newTxList(true, orig.GetTxCurrency())
+	if inserted, old := list.Add(orig, 10); !inserted || old != nil {
+		t.Fatalf("original transaction insert mismatch: inserted %v, old %v", inserted, old)
+	}
+	if inserted, _ := list.Add(pricedTransaction(0, 100000, big.NewInt(109), key), 10); inserted {
+		t.Errorf("underpriced replacement accepted")
+	}
+	replace := pricedTransaction(0, 100000, big.NewInt(110), key)
+	inserted, old := list.Add(replace, 10)
+	if !inserted {
+		t.Fatalf("bumped replacement rejected")
+	}
+	if old != orig {
+		t.Errorf("replaced transaction mismatch: have %v, want %v", old, orig)
+	}
+}
+
+// Tests that the priced list evicts the cheapest remote transaction and never
+// hands out transactions of local accounts.
+func TestTxPricedListDiscard(t *testing.T) {
+	remoteKey, _ := crypto.GenerateKey()
+	localKey, _ := crypto.GenerateKey()
+	remote, local := crypto.PubkeyToAddress(remoteKey.PublicKey), crypto.PubkeyToAddress(localKey.PublicKey)
+
+	all := newTxLookup()
+	priced := newTxPricedList(all)
+	locals := newAccountSet()
+	locals.add(local)
+
+	put := func(tx *types.Transaction, from common.Address) *types.Transaction {
+		tx.SetFromLoad(from)
+		all.Add(tx)
+		priced.Put(tx)
+		return tx
+	}
+	cheapLocal := put(pricedTransaction(0, 100000, big.NewInt(1), localKey), local)
+	cheapRemote := put(pricedTransaction(0, 100000, big.NewInt(2), remoteKey), remote)
+	dearRemote := put(pricedTransaction(1, 100000, big.NewInt(3), remoteKey), remote)
+
+	incoming := pricedTransaction(0, 100000, big.NewInt(2), remoteKey)
+	incoming.SetFromLoad(remote)
+	if !priced.Underpriced(incoming, locals) {
+		t.Errorf("transaction as cheap as the cheapest remote accepted")
+	}
+	for _, want := range []*types.Transaction{cheapRemote, dearRemote} {
+		drops := priced.Discard(1, locals)
+		if len(drops) != 1 || drops[0] != want {
+			t.Fatalf("discarded transactions mismatch: have %v, want %v", drops, want)
+		}
+		all.Remove(want.Hash())
+		priced.Removed()
+	}
+	if drops := priced.Discard(1, locals); len(drops) != 0 {
+		t.Errorf("local transaction discarded: %v", drops)
+	}
+	if all.Get(cheapLocal.Hash()) == nil {
+		t.Errorf("local transaction missing from the pool")
+	}
+}
+
+// Tests that numbered transactions are never replaced and that replacements
+// are limited per account and block.
+func TestCheckReplace(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	orig := pricedTransaction(0, 100000, big.NewInt(100), key)
+	list := newTxList(false, orig.GetTxCurrency())
+	list.Add(orig, 0)
+	pool := &NormalTxPool{
+		pending:  map[common.Address]*txList{from: list},
+		replaced: make(map[common.Address]int),
+	}
+	replace := pricedTransaction(0, 100000, big.NewInt(200), key)
+	if err := pool.checkReplace(from, replace); err != nil {
+		t.Fatalf("replacement of unnumbered transaction rejected: %v", err)
+	}
+	pool.replaced[from] = maxReplacementsPerBlock
+	if err := pool.checkReplace(from, replace); err != ErrReplaceRateLimited {
+		t.Errorf("replacement rate limit mismatch: have %v, want %v", err, ErrReplaceRateLimited)
+	}
+	pool.replaced[from] = 0
+	orig.N = []uint32{1}
+	if err := pool.checkReplace(from, replace); err != ErrReplaceNumbered {
+		t.Errorf("numbered replacement mismatch: have %v, want %v", err, ErrReplaceNumbered)
+	}
+}
@@ -9,15 +9,16 @@ import (
 	"errors"
 	//"github.com/MatrixAINetwork/go-matrix/p2p/discover"
 	"math/big"
+	"sort"
 	"sync"
 	"time"
 
-	"runtime"
-
 	"github.com/MatrixAINetwork/go-matrix/ca"
 	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
 	"github.com/MatrixAINetwork/go-matrix/event"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/mc"
@@ -52,6 +53,14 @@ var (
 	// with a different one without the required price bump.
 	ErrReplaceUnderpriced = errors.New("replacement transaction underpriced")
 
+	// ErrReplaceNumbered is returned if the transaction to be replaced has already
+	// been numbered and flooded, so verifiers may reference it by its N.
+	ErrReplaceNumbered = errors.New("replacement of numbered transaction")
+
+	// ErrReplaceRateLimited is returned if an account replaces its pending
+	// transactions more often than allowed within one block.
+	ErrReplaceRateLimited = errors.New("replacement rate limited")
+
 	// ErrInsufficientFunds is returned if the total cost of executing a transaction
 	// is higher than the balance of the user's account.
 	ErrInsufficientFunds        = errors.New("insufficient funds for gas * price + value")
@@ -85,9 +94,12 @@ var (
 	ErrRepeatEntrust   = errors.New("Repeat Entrust")
 	ErrWithoutAuth     = errors.New("gas entrust not set ")
 	ErrinterestAmont   = errors.New("Incorrect total interest")
+	ErrUnknownCurrency = errors.New("unknown currency")
 	//ErrSpecialTxFailed = errors.New("Run special tx failed")
 )
 
+const maxReplacementsPerBlock = 4 // 每个账户在一个区块内允许替换交易的次数
+
 var (
 	evictionInterval    = time.Minute     // Time interval to check for evictable transactions
 	statsReportInterval = 8 * time.Second // Time interval to report transaction pool stats
@@ -109,6 +121,8 @@ var (
 	// General tx metrics
 	invalidTxCounter     = metrics.NewRegisteredCounter("txpool/invalid", nil)
 	underpricedTxCounter = metrics.NewRegisteredCounter("txpool/underpriced", nil)
+	pendingGauge         = metrics.NewRegisteredGauge("txpool/pending", nil)
+	recoverTimer         = metrics.NewRegisteredTimer("txpool/recover", nil)
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -149,7 +163,10 @@ type blockChain interface {
 	StateAt(root []common.CoinRoot) (*state.StateDBManage, error)
 	State() (*state.StateDBManage, error)
 	SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Subscription
+	SubscribeReorgTxsEvent(ch chan<- ReorgTxsEvent) event.Subscription
 	GetA0AccountFromAnyAccountAtSignHeight(account common.Address, blockHash common.Hash, signHeight uint64) (common.Address, common.Address, error)
+	GetBroadcastIntervalByHash(blockHash common.Hash) (*mc.BCIntervalInfo, error)
+	GetBroadcastIntervalAt(number uint64) (*mc.BCIntervalInfo, error)
 }
 
 type ConsensusNTx struct {
@@ -159,23 +176,45 @@ type ConsensusNTx struct {
 
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
+	NoLocals  bool          // Whether local transaction handling should be disabled
+	Journal   string        // Journal of local transactions to survive node restarts
+	Rejournal time.Duration // Time interval to regenerate the local transaction journal
+
 	PriceLimit   uint64 // Minimum gas price to enforce for acceptance into the pool
+	PriceBump    uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 	AccountSlots uint64 // Minimum number of executable transaction slots guaranteed per account
 	GlobalSlots  uint64 // Maximum number of executable transaction slots for all accounts
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 	txTimeout    time.Duration
+
+	BroadcastAccountSlots uint64 // Maximum number of special transactions permitted per broadcast sender
+	BroadcastGlobalSlots  uint64 // Maximum number of special transactions held by the broadcast pool
+	BroadcastGossip       bool   // Whether broadcast transactions flood through the broadcast nodes
+
+	ScheduledAccountSlots uint64 // Maximum number of immature scheduled transactions permitted per account
+	ScheduledGlobalSlots  uint64 // Maximum number of immature scheduled transactions for all accounts
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
 // pool.
 var DefaultTxPoolConfig = TxPoolConfig{
+	Journal:   "transactions.rlp",
+	Rejournal: time.Hour,
+
 	PriceLimit:   params.TxGasPrice, // 2018-08-29 由1改为此值
+	PriceBump:    10,
 	AccountSlots: 16,
 	GlobalSlots:  4096 * 5 * 5 * 10, // 2018-08-30 改为乘以5
 	AccountQueue: 64 * 1000,
 	GlobalQueue:  1024 * 60,
 	txTimeout:    180 * time.Second,
+
+	BroadcastAccountSlots: 16,
+	BroadcastGlobalSlots:  1024 * 16,
+
+	ScheduledAccountSlots: 16,
+	ScheduledGlobalSlots:  1024 * 4,
 }
 
 type NormalTxPool struct {
@@ -186,6 +225,8 @@ type NormalTxPool struct {
 	chainHeadCh  chan ChainHeadEvent
 	sendTxCh     chan NewTxsEvent
 	chainHeadSub event.Subscription
+	replaceFeed  event.Feed
+	scope        event.SubscriptionScope
 	signer       types.Signer
 	mu           sync.RWMutex
 
@@ -193,8 +234,15 @@ type NormalTxPool struct {
 	pendingState  *state.ManagedState  // Pending state tracking virtual nonces
 	currentMaxGas uint64               // Current gas limit for transaction caps
 
-	pending map[common.Address]*txList // All currently processable transactions
-	all     *txLookup                  // All transactions to allow lookups
+	locals  *accountSet // Set of local transaction to exempt from eviction rules
+	journal *txJournal  // Journal of local transaction to back up to disk
+
+	pending   map[common.Address]*txList         // All currently processable transactions
+	all       *txLookup                          // All transactions to allow lookups
+	priced    *txPricedList                      // All transactions sorted by price
+	scheduled map[common.Hash]*types.Transaction // 尚未到期的预约交易
+	reverts   map[common.Hash]common.Hash        // 可撤销交易hash -> 池中撤销它的交易hash
+	replaced  map[common.Address]int             // 当前区块内各账户已替换交易的次数
 
 	SContainer map[common.Hash]*types.Transaction
 	NContainer map[uint32]*types.Transaction
@@ -216,10 +264,34 @@ type NormalTxPool struct {
 // unreasonable or unworkable.
 func (config *TxPoolConfig) sanitize() TxPoolConfig {
 	conf := *config
+	if conf.Rejournal < time.Second {
+		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
+		conf.Rejournal = time.Second
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
 	}
+	if conf.PriceBump < 1 {
+		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
+		conf.PriceBump = DefaultTxPoolConfig.PriceBump
+	}
+	if conf.BroadcastAccountSlots < 1 {
+		log.Warn("Sanitizing invalid txpool broadcast account slots", "provided", conf.BroadcastAccountSlots, "updated", DefaultTxPoolConfig.BroadcastAccountSlots)
+		conf.BroadcastAccountSlots = DefaultTxPoolConfig.BroadcastAccountSlots
+	}
+	if conf.BroadcastGlobalSlots < 1 {
+		log.Warn("Sanitizing invalid txpool broadcast global slots", "provided", conf.BroadcastGlobalSlots, "updated", DefaultTxPoolConfig.BroadcastGlobalSlots)
+		conf.BroadcastGlobalSlots = DefaultTxPoolConfig.BroadcastGlobalSlots
+	}
+	if conf.ScheduledAccountSlots < 1 {
+		log.Warn("Sanitizing invalid txpool scheduled account slots", "provided", conf.ScheduledAccountSlots, "updated", DefaultTxPoolConfig.ScheduledAccountSlots)
+		conf.ScheduledAccountSlots = DefaultTxPoolConfig.ScheduledAccountSlots
+	}
+	if conf.ScheduledGlobalSlots < 1 {
+		log.Warn("Sanitizing invalid txpool scheduled global slots", "provided", conf.ScheduledGlobalSlots, "updated", DefaultTxPoolConfig.ScheduledGlobalSlots)
+		conf.ScheduledGlobalSlots = DefaultTxPoolConfig.ScheduledGlobalSlots
+	}
 	return conf
 }
 
@@ -232,7 +304,11 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		chainconfig:   chainconfig,
 		chain:         chain,
 		signer:        types.NewEIP155Signer(chainconfig.ChainId),
+		locals:        newAccountSet(),
 		pending:       make(map[common.Address]*txList),
+		scheduled:     make(map[common.Hash]*types.Transaction),
+		reverts:       make(map[common.Hash]common.Hash),
+		replaced:      make(map[common.Address]int),
 		SContainer:    make(map[common.Hash]*types.Transaction), //by
 		NContainer:    make(map[uint32]*types.Transaction),      //by
 		udptxsCh:      make(chan []*types.Transaction_Mx, 0),    //
@@ -247,7 +323,12 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		mapTxsTiming:  make(map[common.Hash]time.Time),        //  需要做定时删除的交易
 		mapHighttx:    make(map[uint64][]uint32, 0),
 	}
+	nPool.priced = newTxPricedList(nPool.all)
 	nPool.reset(nil, chain.CurrentBlock().Header())
+	// 本地交易日志在事件循环启动时载入, 载入的交易需要通过sendTxCh发出
+	if !config.NoLocals && config.Journal != "" {
+		nPool.journal = newTxJournal(config.Journal)
+	}
 	// Subscribe events from blockchain
 	nPool.chainHeadSub = nPool.chain.SubscribeChainHeadEvent(nPool.chainHeadCh)
 	nPool.sendTxCh = sendch
@@ -264,6 +345,12 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 	return nPool
 }
 
+// SubscribeTxReplacedEvent registers a subscription of TxReplacedEvent, fired
+// whenever a pending transaction is replaced by a higher priced one.
+func (nPool *NormalTxPool) SubscribeTxReplacedEvent(ch chan<- TxReplacedEvent) event.Subscription {
+	return nPool.scope.Track(nPool.replaceFeed.Subscribe(ch))
+}
+
 // Type return txpool type.
 func (nPool *NormalTxPool) Type() byte {
 	return types.NormalTxIndex
@@ -276,6 +363,23 @@ func (nPool *NormalTxPool) loop() {
 	defer nPool.wg.Done()
 	delteTime := time.NewTicker(10 * time.Second)
 	defer delteTime.Stop()
+
+	// If local transactions and journaling is enabled, load from disk
+	var rejournal <-chan time.Time
+	if nPool.journal != nil {
+		if err := nPool.journal.load(nPool.AddLocals); err != nil {
+			log.Warn("Failed to load transaction journal", "err", err)
+		}
+		nPool.mu.Lock()
+		if err := nPool.journal.rotate(nPool.local()); err != nil {
+			log.Warn("Failed to rotate transaction journal", "err", err)
+		}
+		nPool.mu.Unlock()
+
+		journal := time.NewTicker(nPool.config.Rejournal)
+		defer journal.Stop()
+		rejournal = journal.C
+	}
 	// Track the previous head headers for transaction reorgs
 	head := nPool.chain.CurrentBlock()
 	// Keep waiting for and reacting to the various events
@@ -295,6 +399,8 @@ func (nPool *NormalTxPool) loop() {
 				}
 				delete(nPool.mapHighttx, h)
 				txpoolCache.DeleteTxCache(head.Header().HashNoSignsAndNonce(), head.Number().Uint64())
+				pending, _ := nPool.stats()
+				pendingGauge.Update(int64(pending))
 				nPool.mu.Unlock()
 				nPool.getPendingTx() //
 			}
@@ -304,9 +410,18 @@ func (nPool *NormalTxPool) loop() {
 		case <-delteTime.C:
 			nPool.mu.Lock()
 			nPool.blockTiming() //
+			pending, _ := nPool.stats()
+			pendingGauge.Update(int64(pending))
 			nPool.mu.Unlock()
 			nPool.getPendingTx()
 
+			// Handle local transaction journal rotation
+		case <-rejournal:
+			nPool.mu.Lock()
+			if err := nPool.journal.rotate(nPool.local()); err != nil {
+				log.Warn("Failed to rotate local tx journal", "err", err)
+			}
+			nPool.mu.Unlock()
 		}
 	}
 }
@@ -405,10 +520,10 @@ func (nPool *NormalTxPool) packageSNList() {
 }
 
 // ProcessMsg
-func (nPool *NormalTxPool) ProcessMsg(m NetworkMsgData) {
+func (nPool *NormalTxPool) ProcessMsg(m NetworkMsgData) error {
 	if len(m.Data) <= 0 {
 		log.Error("NormalTxPool::ProcessMsg  data is nil")
-		return
+		return ErrEmptyNetworkMsg
 	}
 	var (
 		msgData = m.Data[0]
@@ -464,6 +579,7 @@ func (nPool *NormalTxPool) ProcessMsg(m NetworkMsgData) {
 		}
 		nPool.RecvErrTx(common.HexToAddress(m.SendAddress.String()), listS)
 	}
+	return err
 }
 
 // SendMsg
@@ -567,6 +683,13 @@ func (nPool *NormalTxPool) reset(oldHead, newHead *types.Header) {
 	// have been invalidated because of another transaction (e.g.
 	// higher gas price)
 	nPool.DemoteUnexecutables()
+	nPool.promoteScheduled(newHead)
+	nPool.replaced = make(map[common.Address]int)
+	for target, hash := range nPool.reverts {
+		if nPool.all.Get(hash) == nil {
+			delete(nPool.reverts, target)
+		}
+	}
 	// Update all accounts to the latest known pending nonce
 	for addr, list := range nPool.pending {
 		for cointype, txs := range list.txs {
@@ -579,11 +702,15 @@ func (nPool *NormalTxPool) reset(oldHead, newHead *types.Header) {
 // Stop terminates the transaction pool.
 func (nPool *NormalTxPool) Stop() {
 	// Unsubscribe subscriptions registered from blockchain
+	nPool.scope.Close()
 	nPool.chainHeadSub.Unsubscribe()
 	nPool.udptxsSub.Unsubscribe()
 	nPool.quit <- struct{}{}
 	nPool.wg.Wait()
 	close(nPool.quit)
+	if nPool.journal != nil {
+		nPool.journal.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -610,30 +737,108 @@ func (nPool *NormalTxPool) GetTxNmap() map[uint32]*types.Transaction {
 // stats retrieves the current pool stats, namely the number of pending and the
 // number of queued (non-executable) transactions.
 func (nPool *NormalTxPool) stats() (int, int) {
-	pending := 0
-	for _, list := range nPool.pending {
-		for _, txs := range list.txs {
-			pending += txs.Len()
+	pending, queued := 0, 0
+	for addr, list := range nPool.pending {
+		for typ, txs := range list.txs {
+			ready, gapped := splitNonceGap(txs.Flatten(), nPool.currentState.GetNonce(typ, addr))
+			pending += len(ready)
+			queued += len(gapped)
 		}
 	}
-	queued := 0
 	return pending, queued
 }
 
+// splitNonceGap 按账户当前nonce将同一币种的交易分为可执行的连续交易和nonce缺口之后的排队交易, txs需按nonce排序
+func splitNonceGap(txs []*types.Transaction, nonce uint64) ([]*types.Transaction, []*types.Transaction) {
+	for i, tx := range txs {
+		if tx.Nonce() != nonce {
+			return txs[:i], txs[i:]
+		}
+		nonce++
+	}
+	return txs, nil
+}
+
 // Content retrieves the data content of the transaction pool, returning all the
 // pending as well as queued transactions, grouped by account and sorted by nonce.
-func (nPool *NormalTxPool) Content() map[common.Address][]*types.Transaction {
+// Queued transactions are the ones behind a nonce gap of their account.
+func (nPool *NormalTxPool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
 	nPool.mu.Lock()
 	defer nPool.mu.Unlock()
 	pending := make(map[common.Address][]*types.Transaction)
+	queued := make(map[common.Address][]*types.Transaction)
 	for addr, list := range nPool.pending {
-		txlist := make([]*types.Transaction, 0)
-		for _, txs := range list.txs {
-			txlist = append(txlist, txs.Flatten()...)
+		for typ, txs := range list.txs {
+			ready, gapped := splitNonceGap(txs.Flatten(), nPool.currentState.GetNonce(typ, addr))
+			if len(ready) > 0 {
+				pending[addr] = append(pending[addr], ready...)
+			}
+			if len(gapped) > 0 {
+				queued[addr] = append(queued[addr], gapped...)
+			}
+		}
+	}
+	return pending, queued
+}
+
+// Scheduled retrieves the scheduled transactions which are not mature yet,
+// grouped by account and sorted by nonce.
+func (nPool *NormalTxPool) Scheduled() map[common.Address][]*types.Transaction {
+	nPool.mu.RLock()
+	defer nPool.mu.RUnlock()
+	scheduled := make(map[common.Address][]*types.Transaction)
+	for _, tx := range nPool.scheduled {
+		from := tx.From()
+		scheduled[from] = append(scheduled[from], tx)
+	}
+	for _, txs := range scheduled {
+		sort.Slice(txs, func(i, j int) bool {
+			return txs[i].Nonce() < txs[j].Nonce()
+		})
+	}
+	return scheduled
+}
+
+// validateScheduledTx 检查未到期的预约交易能否放入定时队列: 到期高度或时间不能过远,
+// 同一账户相同nonce的预约交易只保留一笔, 且不超过账户和全局的数量上限
+func (nPool *NormalTxPool) validateScheduledTx(tx *types.Transaction, from common.Address, head *types.Header) error {
+	if !scheduledTxInHorizon(tx.GetMatrix_EX(), head.Number.Uint64()+1, head.Time.Uint64()) {
+		return ErrScheduledTxTooFar
+	}
+	if uint64(len(nPool.scheduled)) >= nPool.config.ScheduledGlobalSlots {
+		return ErrScheduledTxFull
+	}
+	count := uint64(0)
+	for _, other := range nPool.scheduled {
+		if other.From() != from {
+			continue
+		}
+		if other.Nonce() == tx.Nonce() && other.GetTxCurrency() == tx.GetTxCurrency() {
+			return ErrScheduledNonceKnown
+		}
+		count++
+	}
+	if count >= nPool.config.ScheduledAccountSlots {
+		return ErrScheduledTxFull
+	}
+	return nil
+}
+
+// promoteScheduled 将在下个区块到期的预约交易加入pending, 并丢弃nonce已失效的预约交易
+func (nPool *NormalTxPool) promoteScheduled(head *types.Header) {
+	for hash, tx := range nPool.scheduled {
+		if !IsScheduledTxMature(tx.GetMatrix_EX(), head.Number.Uint64()+1, head.Time.Uint64()) {
+			if nPool.currentState.GetNonce(tx.GetTxCurrency(), tx.From()) > tx.Nonce() {
+				log.Trace("Discarding stale scheduled transaction", "hash", hash)
+				delete(nPool.scheduled, hash)
+			}
+			continue
+		}
+		delete(nPool.scheduled, hash)
+		if _, err := nPool.add(tx, false); err != nil {
+			log.Trace("Discarding mature scheduled transaction", "hash", hash, "err", err)
 		}
-		pending[addr] = txlist
 	}
-	return pending
 }
 
 // Pending retrieves all currently processable transactions, groupped by origin
@@ -1107,27 +1312,13 @@ func (nPool *NormalTxPool) blockTiming() {
 
 // 根据交易获取交易中的from
 func (nPool *NormalTxPool) getFromByTx(txs []*types.Transaction) {
-	var waitG = &sync.WaitGroup{}
-	routineNum := len(txs)/100 + 1
-	if routineNum > 1 {
-		maxProcs := runtime.GOMAXPROCS(0) //获取cpu个数
-		if maxProcs > 2 {
-			maxProcs /= 2
-		}
-		if maxProcs < routineNum {
-			routineNum = maxProcs
-		}
-	}
-	routChan := make(chan types.SelfTransaction, 0)
-	for i := 0; i < routineNum; i++ {
-		waitG.Add(1)
-		go types.Sender_sub(nPool.signer, routChan, waitG)
+	selfTxs := make([]types.SelfTransaction, len(txs))
+	for i, tx := range txs {
+		selfTxs[i] = tx
 	}
-	for _, tx := range txs {
-		routChan <- tx
-	}
-	close(routChan)
-	waitG.Wait()
+	start := time.Now()
+	senderCacher.recoverWait(nPool.signer, selfTxs)
+	recoverTimer.UpdateSince(start)
 }
 
 // 检查交易中是否存在from
@@ -1145,10 +1336,33 @@ func (nPool *NormalTxPool) checkTxFrom(tx *types.Transaction) (common.Address, e
 	}
 }
 
+// isIssuedCurrency reports whether transactions can be made in the given coin.
+func (nPool *NormalTxPool) isIssuedCurrency(coin string) bool {
+	if coin == params.MAN_COIN {
+		return true
+	}
+	coinCfgs, err := matrixstate.GetCoinConfig(nPool.currentState)
+	if err != nil {
+		return false
+	}
+	return isCurrencyInConfig(coin, coinCfgs)
+}
+
+// isCurrencyInConfig reports whether a coin is issued and packed into blocks.
+func isCurrencyInConfig(coin string, coinCfgs []common.CoinConfig) bool {
+	for _, cfg := range coinCfgs {
+		if cfg.CoinType == coin && cfg.PackNum > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (nPool *NormalTxPool) validateTx(tx *types.Transaction, local bool) error {
 	txEx := tx.GetMatrix_EX()
+	wasmCreation := IsWasmCreation(nPool.currentState, tx.To(), tx.Data(), nPool.chain.CurrentBlock().NumberU64()+1)
 	var txcount uint64
 	if len(txEx) > 0 {
 		txcount = 1
@@ -1183,7 +1397,12 @@ func (nPool *NormalTxPool) validateTx(tx *types.Transaction, local bool) error {
 		}
 	} else {
 		// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
-		if uint64(tx.Size()) > params.TxSize {
+		var txsize uint64 = params.TxSize
+		if wasmCreation {
+			// 部署WASM合约的交易可包含更大的合约代码
+			txsize += vm.MaxWasmCodeSize
+		}
+		if uint64(tx.Size()) > txsize {
 			return ErrOversizedData
 		}
 	}
@@ -1192,6 +1411,10 @@ func (nPool *NormalTxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Value().Sign() < 0 {
 		return ErrNegativeValue
 	}
+	// 交易币种必须是已发行的币种, 余额和nonce都按该币种检查
+	if !nPool.isIssuedCurrency(tx.GetTxCurrency()) {
+		return ErrUnknownCurrency
+	}
 	// Ensure the transaction doesn't exceed the current block limit gas.
 	if nPool.currentMaxGas < tx.Gas() {
 		return ErrGasLimit
@@ -1201,23 +1424,27 @@ func (nPool *NormalTxPool) validateTx(tx *types.Transaction, local bool) error {
 	if addrerr != nil {
 		return addrerr
 	}
+	if err := filterAddress(nPool.currentState, from, tx.To()); err != nil {
+		return err
+	}
+	local = local || nPool.locals.contains(from) // account may be local even if the transaction arrived from the network
 	// Drop non-local transactions under our own minimal accepted gas price
 	//gasprice, err := matrixstate.GetTxpoolGasLimit(nPool.currentState)
 	//if err != nil {
 	//	return errors.New("get txpool gasPrice err")
 	//}
 	//nPool.gasPrice.Set(gasprice)
-	if nPool.gasPrice.Cmp(tx.GasPrice()) > 0 {
+	if !local && nPool.gasPrice.Cmp(tx.GasPrice()) > 0 {
 		return ErrUnderpriced
 	}
 	// Ensure the transaction adheres to nonce ordering
-	if nPool.currentState.GetNonce(tx.Currency, from) > tx.Nonce() {
+	if nPool.currentState.GetNonce(tx.GetTxCurrency(), from) > tx.Nonce() {
 		return ErrNonceTooLow
 	}
 	balance := big.NewInt(0)
 	entrustbalance := big.NewInt(0)
 	//当前账户余额
-	for _, tAccount := range nPool.currentState.GetBalance(tx.Currency, from) {
+	for _, tAccount := range nPool.currentState.GetBalance(tx.GetTxCurrency(), from) {
 		if tAccount.AccountType == common.MainAccount {
 			balance = tAccount.Balance
 			break
@@ -1225,7 +1452,7 @@ func (nPool *NormalTxPool) validateTx(tx *types.Transaction, local bool) error {
 	}
 
 	if tx.IsEntrustGas {
-		for _, tAccount := range nPool.currentState.GetBalance(tx.Currency, tx.AmontFrom()) {
+		for _, tAccount := range nPool.currentState.GetBalance(tx.GetTxCurrency(), tx.AmontFrom()) {
 			if tAccount.AccountType == common.MainAccount {
 				entrustbalance = tAccount.Balance
 				break
@@ -1259,6 +1486,9 @@ func (nPool *NormalTxPool) validateTx(tx *types.Transaction, local bool) error {
 			}
 		}
 	}
+	if wasmCreation {
+		intrGas += WasmIntrinsicGas(tx.Data())
+	}
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
@@ -1291,11 +1521,14 @@ func (nPool *NormalTxPool) add(tx *types.Transaction, local bool) (bool, error)
 				}
 			}
 		}
+		if !EntrustTxTypeAllowed(nPool.currentState, tx.Currency, from, tx.AmontFrom(), tx.GetMatrixType()) {
+			return false, ErrEntrustTxType
+		}
 	}
 	//普通交易
 	hash := tx.Hash()
 	// If the transaction is already known, discard it
-	if nPool.all.Get(hash) != nil {
+	if nPool.all.Get(hash) != nil || nPool.scheduled[hash] != nil {
 		log.Trace("Discarding already known transaction", "hash", hash)
 		return false, ErrKnownTransaction
 	}
@@ -1305,27 +1538,78 @@ func (nPool *NormalTxPool) add(tx *types.Transaction, local bool) (bool, error)
 		invalidTxCounter.Inc(1)
 		return false, err
 	}
-	// 池子满了之后就不再加入
-	// If the transaction pool is full, discard transactions
-	if uint64(nPool.all.Count()) >= nPool.config.GlobalSlots+nPool.config.GlobalQueue {
-		return false, ErrTXPoolFull
-	}
-
 	// 如果交易中已经有了from就不需要在做解签
 	from, addrerr := nPool.checkTxFrom(tx)
 	if addrerr != nil {
 		return false, addrerr
 	}
-	if list := nPool.pending[from]; list != nil && list.Overlaps(tx) {
-		return false, ErrTXNonceSame
+	local = local || nPool.locals.contains(from)
+	// 池子满了之后淘汰价格最低的远程交易, 没有可淘汰的交易时不再加入
+	// If the transaction pool is full, discard underpriced transactions
+	if uint64(nPool.all.Count()+len(nPool.scheduled)) >= nPool.config.GlobalSlots+nPool.config.GlobalQueue {
+		if err := nPool.discardUnderpriced(tx, local); err != nil {
+			return false, err
+		}
+	}
+	// Mark local addresses and journal local transactions
+	if local && !nPool.locals.contains(from) {
+		log.Info("Setting new local account", "address", from)
+		nPool.locals.add(from)
+	}
+	// 未到期的预约交易放入定时队列, 到期后再加入pending
+	if IsScheduledTx(tx) {
+		head := nPool.chain.CurrentBlock().Header()
+		if !IsScheduledTxMature(tx.GetMatrix_EX(), head.Number.Uint64()+1, head.Time.Uint64()) {
+			if err := nPool.validateScheduledTx(tx, from, head); err != nil {
+				log.Trace("Discarding invalid scheduled transaction", "hash", hash, "err", err)
+				return false, err
+			}
+			nPool.scheduled[hash] = tx
+			nPool.journalTx(from, tx)
+			log.Trace("Scheduled transaction queued", "hash", hash, "lockHeight", tx.GetMatrix_EX()[0].LockHeight)
+			return true, nil
+		}
 	}
-	//将交易加入pending
-	if nPool.pending[from] == nil {
-		nPool.pending[from] = newTxList(false, tx.GetTxCurrency())
+	if tx.GetMatrixType() == common.ExtraRevertTxType {
+		if err := nPool.checkRevertTx(tx, from); err != nil {
+			return false, err
+		}
+		for _, target := range revertTargets(tx) {
+			nPool.reverts[target] = hash
+		}
 	}
-	nPool.pending[from].Add(tx, 0)
-	nPool.all.Add(tx)
-	nPool.pendingState.SetNonce(tx.Currency, from, tx.Nonce()+1)
+	if list := nPool.pending[from]; list != nil && list.Overlaps(tx) {
+		// 已经编号(洪泛)的交易可能已被验证者按N引用, 不允许替换; 替换次数按区块限流
+		if err := nPool.checkReplace(from, tx); err != nil {
+			pendingDiscardCounter.Inc(1)
+			return false, err
+		}
+		// 相同nonce的交易只有gas价格提高足够比例时才能替换原交易
+		inserted, old := list.Add(tx, nPool.config.PriceBump)
+		if !inserted {
+			pendingDiscardCounter.Inc(1)
+			return false, ErrReplaceUnderpriced
+		}
+		nPool.replaced[from]++
+		nPool.all.Remove(old.Hash())
+		nPool.priced.Removed()
+		nPool.deleteMap(old)
+		nPool.all.Add(tx)
+		nPool.priced.Put(tx)
+		pendingReplaceCounter.Inc(1)
+		log.Trace("Pending transaction replaced", "old", old.Hash(), "new", hash, "from", from, "nonce", tx.Nonce())
+		go nPool.replaceFeed.Send(TxReplacedEvent{Old: old, New: tx})
+	} else {
+		//将交易加入pending
+		if nPool.pending[from] == nil {
+			nPool.pending[from] = newTxList(false, tx.GetTxCurrency())
+		}
+		nPool.pending[from].Add(tx, 0)
+		nPool.all.Add(tx)
+		nPool.priced.Put(tx)
+		nPool.pendingState.SetNonce(tx.Currency, from, tx.Nonce()+1)
+	}
+	nPool.journalTx(from, tx)
 	//selfRole := ca.GetRole()
 	switch ca.GetRole() {
 	case common.RoleMiner, common.RoleValidator:
@@ -1349,9 +1633,142 @@ func (nPool *NormalTxPool) add(tx *types.Transaction, local bool) (bool, error)
 	return true, nil
 }
 
+// checkReplace 检查pending中与tx相同nonce的交易能否被替换
+func (nPool *NormalTxPool) checkReplace(from common.Address, tx *types.Transaction) error {
+	if txs, ok := nPool.pending[from].txs[tx.GetTxCurrency()]; ok {
+		if old := txs.Get(tx.Nonce()); old != nil && len(old.N) > 0 {
+			return ErrReplaceNumbered
+		}
+	}
+	if nPool.replaced[from] >= maxReplacementsPerBlock {
+		return ErrReplaceRateLimited
+	}
+	return nil
+}
+
+// discardUnderpriced 交易池满时按价格堆淘汰一笔gas价格最低的远程交易, 为新交易腾出位置.
+// 本地账户的交易不会被淘汰; 本地交易可以挤掉任意价格的远程交易, 但没有可淘汰的远程交易时同样返回ErrTXPoolFull
+func (nPool *NormalTxPool) discardUnderpriced(tx *types.Transaction, local bool) error {
+	if !local && nPool.priced.Underpriced(tx, nPool.locals) {
+		log.Trace("Discarding underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
+		underpricedTxCounter.Inc(1)
+		return ErrUnderpriced
+	}
+	drops := nPool.priced.Discard(1, nPool.locals)
+	if len(drops) == 0 {
+		return ErrTXPoolFull
+	}
+	for _, drop := range drops {
+		log.Trace("Discarding freshly underpriced transaction", "hash", drop.Hash(), "price", drop.GasPrice())
+		underpricedTxCounter.Inc(1)
+		// 同账户nonce更大的交易在淘汰后无法执行, 一并移除避免留下nonce空洞
+		for _, later := range nPool.laterTxs(drop) {
+			nPool.removeTx(later.Hash(), false)
+		}
+		nPool.removeTx(drop.Hash(), false)
+	}
+	return nil
+}
+
+// laterTxs 返回与tx同账户同币种且nonce更大的pending交易
+func (nPool *NormalTxPool) laterTxs(tx *types.Transaction) []*types.Transaction {
+	from, err := nPool.checkTxFrom(tx)
+	if err != nil {
+		return nil
+	}
+	list := nPool.pending[from]
+	if list == nil {
+		return nil
+	}
+	txs, ok := list.txs[tx.GetTxCurrency()]
+	if !ok {
+		return nil
+	}
+	var later []*types.Transaction
+	for _, pending := range txs.Flatten() {
+		if pending.Nonce() > tx.Nonce() {
+			later = append(later, pending)
+		}
+	}
+	return later
+}
+
+// journalTx adds the specified transaction to the local disk journal if it is
+// deemed to have been sent from a local account.
+func (nPool *NormalTxPool) journalTx(from common.Address, tx *types.Transaction) {
+	// Only journal if it's enabled and the transaction is local
+	if nPool.journal == nil || !nPool.locals.contains(from) {
+		return
+	}
+	if err := nPool.journal.insert(tx); err != nil {
+		log.Warn("Failed to journal local transaction", "err", err)
+	}
+}
+
+// local retrieves all currently known local transactions, groupped by origin
+// account. The returned transaction set is a copy and can be freely modified
+// by calling code.
+func (nPool *NormalTxPool) local() map[common.Address][]*types.Transaction {
+	txs := make(map[common.Address][]*types.Transaction)
+	for addr := range nPool.locals.accounts {
+		if list := nPool.pending[addr]; list != nil {
+			for _, sm := range list.txs {
+				txs[addr] = append(txs[addr], sm.Flatten()...)
+			}
+		}
+	}
+	for _, tx := range nPool.scheduled {
+		if from, err := nPool.checkTxFrom(tx); err == nil && nPool.locals.contains(from) {
+			txs[from] = append(txs[from], tx)
+		}
+	}
+	return txs
+}
+
+// checkRevertTx 每笔可撤销交易在池中只能有一笔撤销交易, 且撤销目标必须是发送者尚在池中或撤销期内的可撤销交易
+func (nPool *NormalTxPool) checkRevertTx(tx *types.Transaction, from common.Address) error {
+	targets := revertTargets(tx)
+	if len(targets) == 0 {
+		return ErrRevertUnknownTx
+	}
+	for _, target := range targets {
+		if hash, ok := nPool.reverts[target]; ok && nPool.all.Get(hash) != nil {
+			return ErrRevertDuplicate
+		}
+		if original := nPool.all.Get(target); original != nil {
+			if !isRevocableType(original.GetMatrixType()) || original.From() != from {
+				return ErrRevertUnknownTx
+			}
+			continue
+		}
+		if len(nPool.currentState.GetMatrixData(target)) == 0 {
+			return ErrRevertUnknownTx
+		}
+	}
+	return nil
+}
+
 // AddLocal enqueues a single transaction into the pool if it is valid, marking
 // the sender as a local one in the mean time, ensuring it goes around the local
 // pricing constraints.
+func (nPool *NormalTxPool) AddLocal(txer types.SelfTransaction) error {
+	return nPool.addTxs([]*types.Transaction{txer.(*types.Transaction)}, !nPool.config.NoLocals)
+}
+
+// AddLocals enqueues a batch of transactions into the pool if they are valid,
+// marking the senders as local ones in the mean time.
+func (nPool *NormalTxPool) AddLocals(txs []*types.Transaction) []error {
+	nPool.getFromByTx(txs)
+	nPool.mu.Lock()
+	defer nPool.mu.Unlock()
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		_, errs[i] = nPool.add(tx, !nPool.config.NoLocals)
+	}
+	return errs
+}
+
+// AddTxPool enqueues a single transaction into the pool if it is valid.
 func (nPool *NormalTxPool) AddTxPool(txer types.SelfTransaction) error {
 	txs := make([]*types.Transaction, 0)
 	tx := txer.(*types.Transaction)
@@ -1418,6 +1835,7 @@ func (nPool *NormalTxPool) removeTx(hash common.Hash, outofbound bool) {
 	addr, _ := nPool.checkTxFrom(tx)
 	// Remove it from the list of known transactions
 	nPool.all.Remove(hash)
+	nPool.priced.Removed()
 	nPool.deleteMap(tx)
 	// Remove the transaction from the pending lists and reset the account nonce
 	if pending := nPool.pending[addr]; pending != nil {
@@ -1449,6 +1867,7 @@ func (nPool *NormalTxPool) DemoteUnexecutables() {
 				hash := tx.Hash()
 				//log.Trace("Removed old pending transaction", "hash", hash)
 				nPool.all.Remove(hash)
+				nPool.priced.Removed()
 			}
 			// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 			tBalance := new(big.Int)
@@ -1464,6 +1883,7 @@ func (nPool *NormalTxPool) DemoteUnexecutables() {
 				hash := tx.Hash()
 				log.Trace("Removed unpayable pending transaction", "hash", hash)
 				nPool.all.Remove(hash)
+				nPool.priced.Removed()
 				pendingNofundsCounter.Inc(1)
 			}
 			// Delete the entire queue entry if it became empty.
@@ -1536,3 +1956,34 @@ func (t *txLookup) Remove(hash common.Hash) {
 	defer t.lock.Unlock()
 	delete(t.all, hash)
 }
+
+// accountSet is simply a set of addresses to check for existence.
+type accountSet struct {
+	accounts map[common.Address]struct{}
+}
+
+// newAccountSet creates a new address set.
+func newAccountSet() *accountSet {
+	return &accountSet{
+		accounts: make(map[common.Address]struct{}),
+	}
+}
+
+// contains checks if a given address is contained within the set.
+func (as *accountSet) contains(addr common.Address) bool {
+	_, exist := as.accounts[addr]
+	return exist
+}
+
+// containsTx checks if the sender of a given tx is within the set.
+func (as *accountSet) containsTx(tx *types.Transaction) bool {
+	if addr, err := tx.GetTxFrom(); err == nil {
+		return as.contains(addr)
+	}
+	return false
+}
+
+// add inserts a new address into the set to track.
+func (as *accountSet) add(addr common.Address) {
+	as.accounts[addr] = struct{}{}
+}
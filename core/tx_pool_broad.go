@@ -7,11 +7,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/big"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/MatrixAINetwork/go-matrix/ca"
 	"github.com/MatrixAINetwork/go-matrix/common"
 	//"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/core/state"
@@ -19,6 +18,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/event"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
 	"github.com/MatrixAINetwork/go-matrix/p2p"
 	"github.com/MatrixAINetwork/go-matrix/params"
 	"github.com/MatrixAINetwork/go-matrix/params/manparams"
@@ -27,29 +27,240 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 )
 
+var (
+	// ErrBroadcastAccountFull is returned if the sender of a broadcast transaction
+	// already holds the maximum number of special transactions in the pool.
+	ErrBroadcastAccountFull = errors.New("broadcast account slots exhausted")
+
+	// ErrDuplicateBroadcastTx is returned if the sender already sent a broadcast
+	// transaction of the same type within the current broadcast interval.
+	ErrDuplicateBroadcastTx = errors.New("known broadcast transaction")
+
+	// ErrWrongBroadcastInterval is returned if a broadcast transaction targets
+	// another broadcast interval than the current one.
+	ErrWrongBroadcastInterval = errors.New("wrong broadcast interval")
+
+	// ErrUnauthorizedBroadcaster is returned if the sender does not hold the role
+	// required to send the given type of broadcast transaction.
+	ErrUnauthorizedBroadcaster = errors.New("unauthorized broadcast sender")
+
+	// ErrUnknownBroadcastType is returned if a transaction is not a special
+	// transaction or carries an unknown broadcast type.
+	ErrUnknownBroadcastType = errors.New("unknown broadcast transaction type")
+)
+
+var (
+	// Metrics for the broadcast pool
+	broadcastSizeGauge     = metrics.NewRegisteredGauge("txpool/broadcast/size", nil)
+	broadcastDrainGauge    = metrics.NewRegisteredGauge("txpool/broadcast/drain", nil) // Size of the last drained broadcast round
+	broadcastRecoverTimer  = metrics.NewRegisteredTimer("txpool/broadcast/recover", nil)
+	broadcastAcceptCounter = metrics.NewRegisteredCounter("txpool/broadcast/accepted", nil)
+	broadcastEvictCounter  = metrics.NewRegisteredCounter("txpool/broadcast/evicted", nil)
+
+	// Rejected broadcast transactions, by reason
+	broadcastDuplicateCounter    = metrics.NewRegisteredCounter("txpool/broadcast/rejected/duplicate", nil)
+	broadcastIntervalCounter     = metrics.NewRegisteredCounter("txpool/broadcast/rejected/interval", nil)
+	broadcastUnauthorizedCounter = metrics.NewRegisteredCounter("txpool/broadcast/rejected/unauthorized", nil)
+	broadcastUnknownTypeCounter  = metrics.NewRegisteredCounter("txpool/broadcast/rejected/unknowntype", nil)
+	broadcastFullCounter         = metrics.NewRegisteredCounter("txpool/broadcast/rejected/full", nil)
+	broadcastInvalidCounter      = metrics.NewRegisteredCounter("txpool/broadcast/rejected/invalid", nil)
+)
+
+// markBroadcastReject accounts a rejected broadcast transaction to the counter
+// of its reason.
+func markBroadcastReject(err error) {
+	switch err {
+	case ErrDuplicateBroadcastTx:
+		broadcastDuplicateCounter.Inc(1)
+	case ErrWrongBroadcastInterval:
+		broadcastIntervalCounter.Inc(1)
+	case ErrUnauthorizedBroadcaster:
+		broadcastUnauthorizedCounter.Inc(1)
+	case ErrUnknownBroadcastType:
+		broadcastUnknownTypeCounter.Inc(1)
+	case ErrTXPoolFull, ErrBroadcastAccountFull:
+		broadcastFullCounter.Inc(1)
+	default:
+		broadcastInvalidCounter.Inc(1)
+	}
+}
+
 type BroadCastTxPool struct {
-	chain   blockChainBroadCast
-	signer  types.Signer
-	special map[common.Hash]types.SelfTransaction // All special transactions
-	mu      sync.RWMutex
+	config       TxPoolConfig
+	chain        blockChainBroadCast
+	signer       types.Signer
+	special      map[common.Hash]types.SelfTransaction // All special transactions
+	meta         map[common.Hash]*broadcastTxMeta      // Sender and interval of every special transaction
+	senders      map[common.Address]uint64             // Number of special transactions held per sender
+	byN          map[uint32]*broadcastNEntry           // Special transactions indexed by N for block recovery
+	recent       map[uint32]*broadcastRecentTx         // Drained or fetched special transactions kept for block recovery
+	requested    map[uint32]common.Address             // Ns requested by the block recovery, mapped to the peer asked
+	nArrived     chan struct{}                         // Closed and renewed whenever special transactions become retrievable by N
+	journal      *broadcastJournal                     // Journal of special transactions to back up to disk
+	gossip       *broadcastGossip                      // Propagation state of the gossiped transactions, nil if not gossiping
+	chainHeadCh  chan ChainHeadEvent
+	chainHeadSub event.Subscription
+	txFeed       event.Feed
+	scope        event.SubscriptionScope
+	mu           sync.RWMutex
+
+	wg sync.WaitGroup // for shutdown sync
+}
+
+// broadcastTxMeta is the bookkeeping kept for every special transaction.
+type broadcastTxMeta struct {
+	from     common.Address
+	bType    string // broadcast type (heartbeat, publickey, privatekey, calltheroll)
+	interval uint64
+	replayed bool // Loaded from the journal, only the sender was checked
+}
+
+// BroadcastTxContent describes a special transaction held by the broadcast pool.
+type BroadcastTxContent struct {
+	Type     string
+	Interval uint64
+	Tx       types.SelfTransaction
 }
 
 type blockChainBroadCast interface {
 	CurrentBlock() *types.Block
 	GetBlock(hash common.Hash, number uint64) *types.Block
+	State() (*state.StateDBManage, error)
 	SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Subscription
 	GetA0AccountFromAnyAccountAtSignHeight(account common.Address, blockHash common.Hash, signHeight uint64) (common.Address, common.Address, error)
+	GetBroadcastIntervalByHash(blockHash common.Hash) (*mc.BCIntervalInfo, error)
+	GetBroadcastIntervalAt(number uint64) (*mc.BCIntervalInfo, error)
 }
 
-func NewBroadTxPool(chainconfig *params.ChainConfig, chain blockChainBroadCast, path string) *BroadCastTxPool {
+func NewBroadTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain blockChainBroadCast, path string) *BroadCastTxPool {
+	// Sanitize the input to ensure the pool is bounded
+	config = (&config).sanitize()
 	bPool := &BroadCastTxPool{
-		chain:   chain,
-		signer:  types.NewEIP155Signer(chainconfig.ChainId),
-		special: make(map[common.Hash]types.SelfTransaction, 0),
+		config:      config,
+		chain:       chain,
+		signer:      types.NewEIP155Signer(chainconfig.ChainId),
+		special:     make(map[common.Hash]types.SelfTransaction, 0),
+		meta:        make(map[common.Hash]*broadcastTxMeta, 0),
+		senders:     make(map[common.Address]uint64, 0),
+		byN:         make(map[uint32]*broadcastNEntry),
+		recent:      make(map[uint32]*broadcastRecentTx),
+		requested:   make(map[uint32]common.Address),
+		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
+	}
+	if config.BroadcastGossip {
+		bPool.gossip = newBroadcastGossip()
+	}
+	// If a data dir was given, replay the special transactions that survived the last restart
+	if journal, err := newBroadcastJournal(path); err != nil {
+		log.Warn("Failed to open broadcast transaction journal", "err", err)
+	} else {
+		bPool.journal = journal
+		loaded, dropped, err := bPool.journal.load(bPool.currentInterval(), func(hash common.Hash, bType string, interval uint64, tx types.SelfTransaction) {
+			from, err := bPool.checkTxFrom(tx)
+			if err != nil {
+				log.Warn("Discarding journaled broadcast transaction", "hash", hash, "err", err)
+				return
+			}
+			bPool.insert(hash, from, bType, interval, tx)
+			bPool.meta[hash].replayed = true
+		})
+		if err != nil {
+			log.Warn("Failed to load broadcast transaction journal", "err", err)
+		}
+		log.Info("Loaded broadcast transaction journal", "transactions", loaded, "dropped", dropped)
 	}
+	// Subscribe events from blockchain and start the eviction loop
+	bPool.chainHeadSub = bPool.chain.SubscribeChainHeadEvent(bPool.chainHeadCh)
+	bPool.wg.Add(1)
+	go bPool.loop()
 	return bPool
 }
 
+// broadcastIntervalOf returns the broadcast interval special transactions are
+// accepted for on top of the given block, using the broadcast period in effect
+// at it.
+func broadcastIntervalOf(chain BroadcastChainReader, block *types.Block) uint64 {
+	return block.NumberU64()/broadcastIntervalInfo(chain, block).GetBroadcastInterval() + 1
+}
+
+// currentInterval returns the broadcast interval special transactions are
+// currently accepted for.
+func (bPool *BroadCastTxPool) currentInterval() uint64 {
+	return broadcastIntervalOf(bPool.chain, bPool.chain.CurrentBlock())
+}
+
+// loop is the broadcast pool's event loop, evicting the special transactions of
+// expired broadcast intervals whenever a new chain head arrives.
+func (bPool *BroadCastTxPool) loop() {
+	defer bPool.wg.Done()
+	for {
+		select {
+		case ev := <-bPool.chainHeadCh:
+			if ev.Block != nil {
+				bPool.mu.Lock()
+				bPool.evict(broadcastIntervalOf(bPool.chain, ev.Block))
+				bPool.mu.Unlock()
+			}
+			// Be unsubscribed due to system stopped
+		case <-bPool.chainHeadSub.Err():
+			return
+		}
+	}
+}
+
+// insert adds a special transaction to the pool, whilst assuming the pool lock
+// is already held.
+func (bPool *BroadCastTxPool) insert(hash common.Hash, from common.Address, bType string, interval uint64, tx types.SelfTransaction) {
+	bPool.special[hash] = tx
+	bPool.meta[hash] = &broadcastTxMeta{from: from, bType: bType, interval: interval}
+	bPool.senders[from]++
+	bPool.indexN(tx)
+	broadcastSizeGauge.Update(int64(len(bPool.special)))
+}
+
+// remove drops a special transaction from the pool, whilst assuming the pool
+// lock is already held.
+func (bPool *BroadCastTxPool) remove(hash common.Hash) {
+	if meta, ok := bPool.meta[hash]; ok {
+		if bPool.senders[meta.from] <= 1 {
+			delete(bPool.senders, meta.from)
+		} else {
+			bPool.senders[meta.from]--
+		}
+		delete(bPool.meta, hash)
+	}
+	if tx, ok := bPool.special[hash]; ok {
+		bPool.unindexN(tx)
+	}
+	delete(bPool.special, hash)
+	broadcastSizeGauge.Update(int64(len(bPool.special)))
+}
+
+// evict drops all special transactions belonging to broadcast intervals before
+// the given one, whilst assuming the pool lock is already held.
+func (bPool *BroadCastTxPool) evict(interval uint64) {
+	evicted := 0
+	for hash, meta := range bPool.meta {
+		if meta.interval < interval {
+			bPool.remove(hash)
+			evicted++
+		}
+	}
+	bPool.expireRecent(interval)
+	if bPool.gossip != nil {
+		bPool.gossip.expire(interval)
+	}
+	if bPool.journal != nil {
+		if err := bPool.journal.prune(interval); err != nil {
+			log.Warn("Failed to prune broadcast transaction journal", "err", err)
+		}
+	}
+	if evicted > 0 {
+		broadcastEvictCounter.Inc(int64(evicted))
+		log.Info("BroadCastTxPool evicted expired transactions", "interval", interval, "count", evicted)
+	}
+}
+
 // Type return txpool type.
 func (bPool *BroadCastTxPool) Type() byte {
 	return types.BroadCastTxIndex
@@ -71,24 +282,27 @@ func ProduceMatrixStateData(block *types.Block, stateDb *state.StateDBManage, re
 	if manparams.IsBroadcastNumberByHash(block.Number().Uint64(), block.ParentHash()) == false {
 		return nil, nil
 	}
+	// 硬分叉后广播交易状态数据改为规范编码
+	if err := switchBroadcastTxCodec(stateDb, block.NumberU64()); err != nil {
+		return nil, err
+	}
 
 	var (
 		tempMap = make(map[string]map[common.Address][]byte)
 	)
 	log.Info("ProduceMatrixStateData message", "height", block.Number().Uint64(), "block.Hash=", block.Hash())
 
-	tempMap[mc.Publickey] = make(map[common.Address][]byte)
-	tempMap[mc.Heartbeat] = make(map[common.Address][]byte)
-	tempMap[mc.Privatekey] = make(map[common.Address][]byte)
-	tempMap[mc.CallTheRoll] = make(map[common.Address][]byte)
+	for _, bt := range BroadcastTypes() {
+		tempMap[bt.StateKey] = make(map[common.Address][]byte)
+	}
 	txs := make(types.SelfTransactions, 0)
 	for _, curr := range block.Currencies() {
 		txs = append(txs, curr.Transactions.GetTransactions()...)
 	}
 	for _, tx := range txs {
 		if len(tx.GetMatrix_EX()) > 0 && tx.GetMatrix_EX()[0].TxType == 1 {
-			temp := make(map[string][]byte)
-			if err := json.Unmarshal(tx.Data(), &temp); err != nil {
+			temp, err := decodeBroadcastTxPayload(stateDb, block.NumberU64(), tx.Data())
+			if err != nil {
 				log.Error("SetBroadcastTxs", "unmarshal error", err)
 				continue
 			}
@@ -100,25 +314,19 @@ func ProduceMatrixStateData(block *types.Block, stateDb *state.StateDBManage, re
 				continue
 			}
 			for key, val := range temp {
-				if strings.Contains(key, mc.Publickey) {
-					tempMap[mc.Publickey][from] = val
-				} else if strings.Contains(key, mc.Privatekey) {
-					tempMap[mc.Privatekey][from] = val
-				} else if strings.Contains(key, mc.Heartbeat) {
-					tempMap[mc.Heartbeat][from] = val
-				} else if strings.Contains(key, mc.CallTheRoll) {
-					tempMap[mc.CallTheRoll][from] = val
+				if bt, ok := broadcastTypeOfKeyAt(stateDb, block.NumberU64(), key); ok {
+					tempMap[bt.StateKey][from] = val
 				}
 			}
 		}
 	}
 	if len(tempMap) > 0 {
 		log.Info("ProduceMatrixStateData", "tempMap", tempMap)
-		//这里需把map转成slice存储在状态树上
+		//这里需把map转成slice存储在状态树上, 按(类型, 账户)有序插入, 结果与map遍历顺序无关
 		var broadtxSlice common.BroadTxSlice
-		for keystring, valmap := range tempMap {
-			for keyaddr, valbyte := range valmap {
-				broadtxSlice.Insert(keystring, keyaddr, valbyte)
+		for _, bt := range BroadcastTypes() {
+			for keyaddr, valbyte := range tempMap[bt.StateKey] {
+				broadtxSlice.Insert(bt.StateKey, keyaddr, valbyte)
 			}
 		}
 		return broadtxSlice, nil
@@ -151,29 +359,77 @@ func GetBroadcastTxMap(bc ChainReader, root []common.CoinRoot, txtype string) (r
 	return nil, errors.New("GetBroadcastTxMap is nil")
 }
 
-// ProcessMsg
-func (bPool *BroadCastTxPool) ProcessMsg(m NetworkMsgData) {
+// ProcessMsg decodes every entry of the network message, adding the broadcast
+// transactions to the pool in a single batch and serving the N-list requests
+// of the block recovery protocol. The first decoding or validation error is
+// returned.
+func (bPool *BroadCastTxPool) ProcessMsg(m NetworkMsgData) error {
 	if len(m.Data) <= 0 {
 		log.Error("BroadCastTxPool", "ProcessMsg", "data is nil")
-		return
+		return ErrEmptyNetworkMsg
 	}
-	if m.Data[0].Msgtype != BroadCast {
-		return
+	var (
+		txs     = make([]types.SelfTransaction, 0, len(m.Data))
+		gossips = make(map[int]*broadcastGossipMsg)
+		reerr   error
+	)
+	for _, msg := range m.Data {
+		if msg == nil {
+			continue
+		}
+		var err error
+		switch msg.Msgtype {
+		case BroadCast:
+			txMx := &types.Transaction_Mx{}
+			if err = json.Unmarshal(msg.MsgData, txMx); err == nil {
+				txs = append(txs, types.SetTransactionMx(txMx))
+			}
+		case GossipBroadCast:
+			gossip := new(broadcastGossipMsg)
+			if err = json.Unmarshal(msg.MsgData, gossip); err == nil {
+				if gossip.Tx == nil {
+					err = ErrEmptyNetworkMsg
+					break
+				}
+				if bPool.gossip != nil && bPool.gossip.receive(gossip, bPool.currentInterval()) {
+					gossips[len(txs)] = gossip
+				}
+				txs = append(txs, types.SetTransactionMx(gossip.Tx))
+			}
+		case GetConsensusTxbyN:
+			listN := make([]uint32, 0)
+			if err = json.Unmarshal(msg.MsgData, &listN); err == nil {
+				bPool.getConsensusTxByN(listN, m.SendAddress)
+			}
+		case RecvConsensusTxbyN:
+			ntx := make(map[uint32]*types.Transaction_Mx)
+			if err = json.Unmarshal(msg.MsgData, &ntx); err == nil {
+				bPool.recvConsensusTxByN(ntx, m.SendAddress)
+			}
+		}
+		if err != nil {
+			log.Error("BroadCastTxPool", "ProcessMsg", err, "msgtype", msg.Msgtype, "peer", m.PeerID.TerminalString())
+			if reerr == nil {
+				reerr = err
+			}
+		}
 	}
-
-	txMx := &types.Transaction_Mx{}
-	if err := json.Unmarshal(m.Data[0].MsgData, txMx); err != nil {
-		log.Error("BroadCastTxPool", "ProcessMsg", err)
-		return
+	for i, err := range bPool.AddTxsPool(txs) {
+		if err != nil && reerr == nil {
+			reerr = err
+		}
+		// Only pass on the gossiped transactions accepted by the pool
+		if gossip, ok := gossips[i]; ok && err == nil {
+			bPool.gossip.forward(gossip)
+		}
 	}
-
-	tx := types.SetTransactionMx(txMx)
-	bPool.AddTxPool(tx)
+	return reerr
 }
 
 // SendMsg
 func (bPool *BroadCastTxPool) SendMsg(data MsgStruct) {
-	if data.Msgtype == BroadCast {
+	switch data.Msgtype {
+	case BroadCast, GetConsensusTxbyN, RecvConsensusTxbyN, GossipBroadCast:
 		data.TxpoolType = types.BroadCastTxIndex
 		p2p.SendToSingle(data.SendAddr, common.NetworkMsg, []interface{}{data})
 	}
@@ -181,55 +437,153 @@ func (bPool *BroadCastTxPool) SendMsg(data MsgStruct) {
 
 // Stop terminates the transaction pool.
 func (bPool *BroadCastTxPool) Stop() {
+	// Unsubscribe all subscriptions registered from txpool
+	bPool.scope.Close()
+	// Unsubscribe subscriptions registered from blockchain
+	bPool.chainHeadSub.Unsubscribe()
+	bPool.wg.Wait()
+
+	bPool.mu.Lock()
+	if bPool.journal != nil {
+		bPool.journal.close()
+	}
+	bPool.mu.Unlock()
 	log.Info("Broad Transaction pool stopped")
 }
 
 // AddTxPool
-func (bPool *BroadCastTxPool) AddTxPool(tx types.SelfTransaction) (reerr error) {
-	bPool.mu.Lock()
-	defer bPool.mu.Unlock()
-	if uint64(tx.Size()) > params.TxSize {
-		log.Error("add broadcast tx pool", "tx size is too big", tx.Size())
-		return reerr
+func (bPool *BroadCastTxPool) AddTxPool(tx types.SelfTransaction) error {
+	return bPool.AddTxsPool([]types.SelfTransaction{tx})[0]
+}
+
+// AddTxsPool adds a batch of special transactions to the pool under a single
+// lock acquisition, returning the validation error of each of them.
+func (bPool *BroadCastTxPool) AddTxsPool(txs []types.SelfTransaction) []error {
+	errs := make([]error, len(txs))
+	if len(txs) == 0 {
+		return errs
 	}
-	if len(tx.GetMatrix_EX()) > 0 && tx.GetMatrix_EX()[0].TxType == 1 {
-		from, addrerr := bPool.checkTxFrom(tx)
-		if addrerr != nil {
-			reerr = addrerr
-			return reerr
-		}
-		tmpdt := make(map[string][]byte)
-		err := json.Unmarshal(tx.Data(), &tmpdt)
+	// Recover the senders before taking the lock, checkTxFrom only hits the cache then
+	start := time.Now()
+	senderCacher.recoverWait(bPool.signer, txs)
+	broadcastRecoverTimer.UpdateSince(start)
+
+	var events []BroadcastTxEvent
+	bPool.mu.Lock()
+	for i, tx := range txs {
+		added, err := bPool.add(tx)
+		events = append(events, added...)
+		broadcastAcceptCounter.Inc(int64(len(added)))
 		if err != nil {
-			log.Error("add broadcast tx pool", "json.Unmarshal failed", err)
+			markBroadcastReject(err)
+		}
+		errs[i] = err
+	}
+	bPool.mu.Unlock()
+
+	// Notify the subscribers outside of the pool lock
+	for _, ev := range events {
+		bPool.txFeed.Send(ev)
+	}
+	return errs
+}
+
+// add validates a special transaction and inserts it into the pool, whilst
+// assuming the pool lock is already held. The accepted entries are returned as
+// events to be posted once the lock is released.
+func (bPool *BroadCastTxPool) add(tx types.SelfTransaction) (events []BroadcastTxEvent, reerr error) {
+	from, tmpdt, err := bPool.decodeTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	interval := bPool.currentInterval()
+	for keydata, value := range tmpdt {
+		if err := bPool.checkKey(from, keydata, value); err != nil {
 			reerr = err
-			return reerr
+			break
 		}
-		for keydata, _ := range tmpdt {
-			if !bPool.filter(from, keydata) {
-				break
-			}
-			hash := types.RlpHash(keydata + from.String())
-			if bPool.special[hash] != nil {
-				log.Trace("Discarding already known broadcast transaction", "hash", hash)
-				reerr = fmt.Errorf("known broadcast transaction: %x", hash)
-				continue
+		hash := types.RlpHash(keydata + from.String())
+		if bPool.special[hash] != nil {
+			log.Trace("Discarding already known broadcast transaction", "hash", hash)
+			reerr = ErrDuplicateBroadcastTx
+			continue
+		}
+		// Refuse the transaction if the sender or the whole pool ran out of slots
+		if uint64(len(bPool.special)) >= bPool.config.BroadcastGlobalSlots {
+			log.Warn("Discarding broadcast transaction, pool is full", "hash", hash, "count", len(bPool.special))
+			reerr = ErrTXPoolFull
+			break
+		}
+		if bPool.senders[from] >= bPool.config.BroadcastAccountSlots {
+			log.Warn("Discarding broadcast transaction, sender slots exhausted", "hash", hash, "from", from.Hex())
+			reerr = ErrBroadcastAccountFull
+			break
+		}
+		bType := strings.TrimSuffix(keydata, fmt.Sprintf("%v", interval))
+		bPool.insert(hash, from, bType, interval, tx)
+		if bPool.journal != nil {
+			if err := bPool.journal.insert(hash, bType, interval, tx); err != nil {
+				log.Warn("Failed to journal broadcast transaction", "err", err)
 			}
-			bPool.special[hash] = tx
-			log.Info("tx_pool_broad", "AddTxPool", "broadCast transaction add txpool success")
 		}
-	} else {
-		reerr = errors.New("BroadCastTxPool:AddTxPool  Transaction type is error")
+		events = append(events, BroadcastTxEvent{Tx: tx, From: from, Type: bType, Interval: interval})
+		log.Info("tx_pool_broad", "AddTxPool", "broadCast transaction add txpool success")
+	}
+	return events, reerr //bPool.addTxs(txs, false)
+}
+
+// decodeTx checks the sender of a special transaction and decodes its payload.
+func (bPool *BroadCastTxPool) decodeTx(tx types.SelfTransaction) (common.Address, map[string][]byte, error) {
+	if uint64(tx.Size()) > params.TxSize {
+		log.Error("add broadcast tx pool", "tx size is too big", tx.Size())
+		return common.Address{}, nil, ErrOversizedData
+	}
+	if len(tx.GetMatrix_EX()) == 0 || tx.GetMatrix_EX()[0].TxType != 1 {
 		if len(tx.GetMatrix_EX()) > 0 {
 			log.Error("BroadCastTxPool:AddTxPool()", "transaction type error.Extra_tx type", tx.GetMatrix_EX()[0].TxType)
 		} else {
 			log.Error("BroadCastTxPool:AddTxPool()", "transaction type error.Extra_tx count", len(tx.GetMatrix_EX()))
 		}
-		return reerr
+		return common.Address{}, nil, ErrUnknownBroadcastType
+	}
+	from, err := bPool.checkTxFrom(tx)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	st, err := bPool.chain.State()
+	if err != nil {
+		log.Error("add broadcast tx pool", "get state err", err)
+		return common.Address{}, nil, err
+	}
+	if err := filterAddress(st, from, tx.To()); err != nil {
+		return common.Address{}, nil, err
+	}
+	tmpdt, err := decodeBroadcastTxPayload(st, bPool.chain.CurrentBlock().NumberU64()+1, tx.Data())
+	if err != nil {
+		log.Error("add broadcast tx pool", "decode payload failed", err)
+		return common.Address{}, nil, err
 	}
-	return reerr //bPool.addTxs(txs, false)
+	return from, tmpdt, nil
 }
-func (bPool *BroadCastTxPool) filter(from common.Address, keydata string) (isok bool) {
+
+// checkKey validates a data key of a special transaction and its payload.
+func (bPool *BroadCastTxPool) checkKey(from common.Address, keydata string, value []byte) error {
+	if err := bPool.filter(from, keydata); err != nil {
+		return err
+	}
+	if err := bPool.checkPayload(keydata, value); err != nil {
+		log.Error("add broadcast tx pool", "invalid payload", err, "key", keydata, "from", from.Hex())
+		return err
+	}
+	return nil
+}
+
+// SubscribeBroadcastTxEvent registers a subscription of BroadcastTxEvent and
+// starts sending event to the given channel.
+func (bPool *BroadCastTxPool) SubscribeBroadcastTxEvent(ch chan<- BroadcastTxEvent) event.Subscription {
+	return bPool.scope.Track(bPool.txFeed.Subscribe(ch))
+}
+func (bPool *BroadCastTxPool) filter(from common.Address, keydata string) error {
 	/*    第三个问题不在这实现，上面已经做了判断了
 			1、从ca模块中获取顶层节点的from 然后判断交易的具体类型（心跳、公钥、私钥）查找tx中的from是否存在。
 	  		2、从ca模块中获取参选节点的from（不包括顶层节点） 然后判断交易的具体类型（心跳）查找tx中的from是否存在。
@@ -237,117 +591,137 @@ func (bPool *BroadCastTxPool) filter(from common.Address, keydata string) (isok
 			4、广播交易的类型必须是已知的如果是未知的则丢弃。（心跳、点名、公钥、私钥）
 	*/
 
-	bcInterval := manparams.GetBCIntervalInfo()
-
-	height := bPool.chain.CurrentBlock().Number()
-	blockHash := bPool.chain.CurrentBlock().Hash()
-	curBlockNum := height.Uint64()
-	tval := curBlockNum / bcInterval.GetBroadcastInterval()
-	strVal := fmt.Sprintf("%v", tval+1)
+	head := bPool.chain.CurrentBlock()
+	strVal := fmt.Sprintf("%v", broadcastIntervalOf(bPool.chain, head))
 	index := strings.Index(keydata, strVal)
 	if index < 0 {
-		return false
+		return ErrWrongBroadcastInterval
 	}
 	numStr := keydata[index:]
 	if numStr != strVal {
 		log.Error("Future broadCast block Height error.(func filter())")
-		return false
+		return ErrWrongBroadcastInterval
 	}
-	str := keydata[0:index]
-	bType := mc.ReturnBroadCastType()
-	if _, ok := bType[str]; !ok {
+	bt, ok := LookupBroadcastType(keydata[0:index])
+	if !ok {
 		log.Error("BroadCast Transaction type unknown. (func filter())")
-		return false
-	}
-	switch str {
-	case mc.CallTheRoll:
-		broadcastNum1 := curBlockNum + 1
-		broadcastNum2 := curBlockNum + 2
-		curBroadcastNum := bcInterval.GetNextBroadcastNumber(curBlockNum)
-		if broadcastNum1 != curBroadcastNum && broadcastNum2 != curBroadcastNum {
-			log.Error("The current block height is higher than the broadcast block height. (func filter())")
-			return false
-		}
-		addrs := ca.GetRolesByGroup(common.RoleBroadcast)
-		for _, addr := range addrs {
-			if addr == from {
-				return true
-			}
-		}
-		log.Error("unknown broadcast Address. error (func filter()  BroadCastTxPool) ")
-		return false
-	case mc.Heartbeat:
-		fromDepositAccount, _, err := bPool.chain.GetA0AccountFromAnyAccountAtSignHeight(from, blockHash, bcInterval.GetNextBroadcastNumber(height.Uint64()))
-		if err != nil {
-			log.Error("BroadCastTxPool", "convert from account to deposit account err", err, "from", from.Hex())
-			return false
-		}
+		return ErrUnknownBroadcastType
+	}
+	if bt.Filter == nil {
+		return nil
+	}
+	return bt.Filter(bPool.chain, head, from)
+}
 
-		nodelist, err := ca.GetElectedByHeightByHash(blockHash)
+// checkPayload validates the payload of a transaction data key with the check
+// of its broadcast type, once the fork enforcing the check is active.
+func (bPool *BroadCastTxPool) checkPayload(keydata string, payload []byte) error {
+	if bt, ok := broadcastTypeOfKey(keydata); ok && bt.Fork != "" {
+		st, err := bPool.chain.State()
 		if err != nil {
-			log.Error("getElected error (func filter()   BroadCastTxPool)", "error", err)
-			return false
+			return err
 		}
-		for _, node := range nodelist {
-			if fromDepositAccount == node.Address {
-				currentAcc := fromDepositAccount.Big()
-				ret := new(big.Int).Rem(currentAcc, big.NewInt(int64(bcInterval.GetBroadcastInterval())-1))
-				broadcastBlock := blockHash.Big()
-				val := new(big.Int).Rem(broadcastBlock, big.NewInt(int64(bcInterval.GetBroadcastInterval())-1))
-				if ret.Cmp(val) == 0 {
-					return true
-				}
-			}
+		if !ForkActive(st, bt.Fork, bPool.chain.CurrentBlock().NumberU64()+1) {
+			return nil
 		}
-		log.Warn("Unknown account information (func filter()   BroadCastTxPool),mc.Heartbeat")
-		return false
-	case mc.Privatekey, mc.Publickey:
-		fromDepositAccount, _, err := bPool.chain.GetA0AccountFromAnyAccountAtSignHeight(from, blockHash, bcInterval.GetNextBroadcastNumber(height.Uint64()))
-		if err != nil {
-			log.Error("BroadCastTxPool", "convert from account to deposit account err", err, "from", from.Hex())
-			return false
+	}
+	return checkBroadcastPayload(keydata, payload)
+}
+
+// Pending retrieves the special transactions held by the pool without draining
+// it, grouped by currency and sender.
+func (bPool *BroadCastTxPool) Pending() (map[string]map[common.Address]types.SelfTransactions, error) {
+	bPool.mu.RLock()
+	defer bPool.mu.RUnlock()
+
+	pending := make(map[string]map[common.Address]types.SelfTransactions)
+	for from, txs := range bPool.specialTxsBySender() {
+		for _, tx := range txs {
+			coin := tx.GetTxCurrency()
+			if pending[coin] == nil {
+				pending[coin] = make(map[common.Address]types.SelfTransactions)
+			}
+			pending[coin][from] = append(pending[coin][from], tx)
 		}
-		nodelist, err := ca.GetElectedByHeightAndRoleByHash(blockHash, common.RoleValidator)
-		if err != nil {
-			log.Error("broadCastTxPool filter getElected error", "error", err)
-			return false
+	}
+	return pending, nil
+}
+
+// specialTxsBySender groups the special transactions of the pool by sender. A
+// transaction carrying several broadcast keys is only returned once. The pool
+// lock is assumed to be held.
+func (bPool *BroadCastTxPool) specialTxsBySender() map[common.Address][]types.SelfTransaction {
+	var (
+		txs  = make(map[common.Address][]types.SelfTransaction)
+		seen = make(map[common.Hash]struct{})
+	)
+	for hash, tx := range bPool.special {
+		meta, ok := bPool.meta[hash]
+		if !ok {
+			continue
 		}
-		for _, node := range nodelist {
-			if fromDepositAccount == node.Address {
-				return true
-			}
+		txHash := tx.Hash()
+		if _, ok := seen[txHash]; ok {
+			continue
 		}
-		log.Warn("Unknown account information ,mc.Privatekey,mc.Publickey")
-		return false
-	default:
-		log.Warn("Broadcast transaction type unknown")
-		return false
+		seen[txHash] = struct{}{}
+		txs[meta.from] = append(txs[meta.from], tx)
 	}
+	return txs
 }
 
-// Pending
-func (bPool *BroadCastTxPool) Pending() (map[string]map[common.Address]types.SelfTransactions, error) {
-	return nil, nil
+// Content retrieves the special transactions held by the pool without draining
+// it, grouped by sender and broadcast type.
+func (bPool *BroadCastTxPool) Content() map[common.Address]map[string][]*BroadcastTxContent {
+	bPool.mu.RLock()
+	defer bPool.mu.RUnlock()
+
+	content := make(map[common.Address]map[string][]*BroadcastTxContent)
+	for hash, tx := range bPool.special {
+		meta, ok := bPool.meta[hash]
+		if !ok {
+			continue
+		}
+		if content[meta.from] == nil {
+			content[meta.from] = make(map[string][]*BroadcastTxContent)
+		}
+		content[meta.from][meta.bType] = append(content[meta.from][meta.bType], &BroadcastTxContent{
+			Type:     meta.bType,
+			Interval: meta.interval,
+			Tx:       tx,
+		})
+	}
+	return content
 }
 
-// GetAllSpecialTxs get BroadCast transaction. (use apply SelfTransaction)
+// GetAllSpecialTxs retrieves the special transactions held by the pool grouped
+// by sender, without draining it.
 func (bPool *BroadCastTxPool) GetAllSpecialTxs() map[common.Address][]types.SelfTransaction {
+	bPool.mu.RLock()
+	defer bPool.mu.RUnlock()
+
+	return bPool.specialTxsBySender()
+}
+
+// Drain retrieves the special transactions held by the pool grouped by sender
+// and resets the pool, used when packing them into a broadcast block.
+func (bPool *BroadCastTxPool) Drain() map[common.Address][]types.SelfTransaction {
 	bPool.mu.Lock()
 	defer bPool.mu.Unlock()
-	reqVal := make(map[common.Address][]types.SelfTransaction, 0)
-	log.Info("BroadCastTxPool getAllSpecialTxs", "len(bPool.special)", len(bPool.special))
-	for _, tx := range bPool.special {
-		from, err := bPool.checkTxFrom(tx)
-		if err != nil {
-			log.Error("BroadCastTxPool", "GetAllSpecialTxs err", err)
-			continue
+
+	reqVal := bPool.specialTxsBySender()
+	log.Info("BroadCastTxPool drain", "len(bPool.special)", len(bPool.special), "len(reqVal)", len(reqVal))
+
+	broadcastDrainGauge.Update(int64(len(bPool.special)))
+	bPool.retire()
+	bPool.special = make(map[common.Hash]types.SelfTransaction, 0)
+	bPool.meta = make(map[common.Hash]*broadcastTxMeta, 0)
+	bPool.senders = make(map[common.Address]uint64, 0)
+	broadcastSizeGauge.Update(0)
+	if bPool.journal != nil {
+		if err := bPool.journal.reset(); err != nil {
+			log.Warn("Failed to reset broadcast transaction journal", "err", err)
 		}
-		reqVal[from] = append(reqVal[from], tx)
 	}
-	bPool.special = make(map[common.Hash]types.SelfTransaction, 0)
-	log.Info("BroadCastTxPool getAllSpecialTxs", "len(reqVal)", len(reqVal))
 	return reqVal
 }
-func (bPool *BroadCastTxPool) ReturnAllTxsByN(listN []uint32, resqe byte, addr common.Address, retch chan *RetChan_txpool) {
-
-}
@@ -0,0 +1,170 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+)
+
+const (
+	broadcastJournalDir     = "broadcasttxs" // Directory name of the journal under the node's data dir
+	broadcastJournalCache   = 16             // Megabytes of memory allocated to the journal's leveldb cache
+	broadcastJournalHandles = 16             // Number of open file handles allocated to the journal
+)
+
+var (
+	// broadcastJournalPrefix + interval (uint64 big endian) + hash -> broadcastJournalEntry
+	broadcastJournalPrefix = []byte("bt")
+
+	errNoBroadcastJournal = errors.New("no active broadcast journal")
+)
+
+// broadcastJournalEntry is the on-disk representation of a special transaction
+// accepted by the broadcast pool.
+type broadcastJournalEntry struct {
+	Hash     common.Hash
+	Type     string
+	Interval uint64
+	Tx       *types.Transaction_Mx
+}
+
+// broadcastJournal is a leveldb backed store of the special transactions held
+// by the BroadCastTxPool, allowing them to survive node restarts that happen
+// before the next broadcast block is produced.
+type broadcastJournal struct {
+	db *mandb.LDBDatabase
+}
+
+// newBroadcastJournal opens (or creates) the journal in the given data dir.
+func newBroadcastJournal(path string) (*broadcastJournal, error) {
+	if path == "" {
+		return nil, errNoBroadcastJournal
+	}
+	db, err := mandb.NewLDBDatabase(filepath.Join(path, broadcastJournalDir), broadcastJournalCache, broadcastJournalHandles, 2)
+	if err != nil {
+		return nil, err
+	}
+	return &broadcastJournal{db: db}, nil
+}
+
+// journalKey = broadcastJournalPrefix + interval (uint64 big endian) + hash
+func journalKey(interval uint64, hash common.Hash) []byte {
+	key := make([]byte, len(broadcastJournalPrefix)+8+common.HashLength)
+	copy(key, broadcastJournalPrefix)
+	binary.BigEndian.PutUint64(key[len(broadcastJournalPrefix):], interval)
+	copy(key[len(broadcastJournalPrefix)+8:], hash[:])
+	return key
+}
+
+// insert persists a special transaction accepted for the given broadcast interval.
+func (journal *broadcastJournal) insert(hash common.Hash, bType string, interval uint64, tx types.SelfTransaction) error {
+	if journal.db == nil {
+		return errNoBroadcastJournal
+	}
+	txMx := types.GetTransactionMx(tx)
+	if txMx == nil {
+		return errors.New("broadcast journal: tx is nil or txMx assertion failed")
+	}
+	blob, err := json.Marshal(&broadcastJournalEntry{Hash: hash, Type: bType, Interval: interval, Tx: txMx})
+	if err != nil {
+		return err
+	}
+	return journal.db.Put(journalKey(interval, hash), blob)
+}
+
+// load replays all journaled transactions belonging to the given interval or a
+// later one into the add callback, and deletes the ones of past intervals.
+func (journal *broadcastJournal) load(interval uint64, add func(hash common.Hash, bType string, interval uint64, tx types.SelfTransaction)) (loaded int, dropped int, err error) {
+	if journal.db == nil {
+		return 0, 0, errNoBroadcastJournal
+	}
+	stale := make([][]byte, 0)
+	it := journal.db.NewIteratorWithPrefix(broadcastJournalPrefix)
+	for it.Next() {
+		entry := new(broadcastJournalEntry)
+		if err := json.Unmarshal(it.Value(), entry); err != nil || entry.Tx == nil {
+			log.Warn("Failed to decode journaled broadcast transaction", "err", err)
+			stale = append(stale, common.CopyBytes(it.Key()))
+			continue
+		}
+		if entry.Interval < interval {
+			stale = append(stale, common.CopyBytes(it.Key()))
+			continue
+		}
+		add(entry.Hash, entry.Type, entry.Interval, types.SetTransactionMx(entry.Tx))
+		loaded++
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return loaded, len(stale), err
+	}
+	for _, key := range stale {
+		if err := journal.db.Delete(key); err != nil {
+			return loaded, len(stale), err
+		}
+	}
+	return loaded, len(stale), nil
+}
+
+// prune deletes all journaled transactions belonging to intervals before the given one.
+func (journal *broadcastJournal) prune(interval uint64) error {
+	if journal.db == nil {
+		return errNoBroadcastJournal
+	}
+	stale := make([][]byte, 0)
+	it := journal.db.NewIteratorWithPrefix(broadcastJournalPrefix)
+	for it.Next() {
+		key := it.Key()
+		if len(key) < len(broadcastJournalPrefix)+8 {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(broadcastJournalPrefix):]) >= interval {
+			// Keys are sorted by interval, nothing left to prune
+			break
+		}
+		stale = append(stale, common.CopyBytes(key))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	for _, key := range stale {
+		if err := journal.db.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reset deletes all journaled transactions, used once the pool has been drained
+// into a broadcast block.
+func (journal *broadcastJournal) reset() error {
+	if journal.db == nil {
+		return errNoBroadcastJournal
+	}
+	it := journal.db.NewIteratorWithPrefix(broadcastJournalPrefix)
+	defer it.Release()
+	for it.Next() {
+		if err := journal.db.Delete(common.CopyBytes(it.Key())); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+// close flushes the journal contents to disk and closes the database.
+func (journal *broadcastJournal) close() {
+	if journal.db != nil {
+		journal.db.Close()
+		journal.db = nil
+	}
+}
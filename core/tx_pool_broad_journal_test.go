@@ -0,0 +1,170 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// journaledTx is a special transaction replayed from the broadcast journal.
+type journaledTx struct {
+	hash     common.Hash
+	bType    string
+	interval uint64
+	tx       types.SelfTransaction
+}
+
+func newTestBroadcastJournal(t *testing.T) (*broadcastJournal, string) {
+	dir, err := ioutil.TempDir("", "broadcastjournal")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	journal, err := newBroadcastJournal(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to open journal: %v", err)
+	}
+	return journal, dir
+}
+
+// loadBroadcastJournal reopens the journal in dir and replays it from the given
+// interval on.
+func loadBroadcastJournal(t *testing.T, dir string, interval uint64) ([]journaledTx, int) {
+	journal, err := newBroadcastJournal(dir)
+	if err != nil {
+		t.Fatalf("failed to reopen journal: %v", err)
+	}
+	defer journal.close()
+
+	var txs []journaledTx
+	loaded, dropped, err := journal.load(interval, func(hash common.Hash, bType string, interval uint64, tx types.SelfTransaction) {
+		txs = append(txs, journaledTx{hash, bType, interval, tx})
+	})
+	if err != nil {
+		t.Fatalf("failed to load journal: %v", err)
+	}
+	if loaded != len(txs) {
+		t.Fatalf("loaded count mismatch: have %d, want %d", loaded, len(txs))
+	}
+	return txs, dropped
+}
+
+// Tests that special transactions written into the journal survive a restart,
+// and that the ones of past broadcast intervals are dropped on load.
+func TestBroadcastJournalLoad(t *testing.T) {
+	journal, dir := newTestBroadcastJournal(t)
+	defer os.RemoveAll(dir)
+
+	for interval := uint64(1); interval <= 3; interval++ {
+		tx := types.NewBroadCastTransaction(types.BroadCastTxIndex, []byte{byte(interval)})
+		if err := journal.insert(common.Hash{byte(interval)}, mc.Heartbeat, interval, tx); err != nil {
+			t.Fatalf("failed to journal transaction: %v", err)
+		}
+	}
+	journal.close()
+
+	txs, dropped := loadBroadcastJournal(t, dir, 2)
+	if len(txs) != 2 || dropped != 1 {
+		t.Fatalf("replay mismatch: have %d loaded, %d dropped, want 2, 1", len(txs), dropped)
+	}
+	for i, tx := range txs {
+		interval := uint64(i + 2)
+		if tx.hash != (common.Hash{byte(interval)}) || tx.bType != mc.Heartbeat || tx.interval != interval {
+			t.Errorf("transaction %d: metadata mismatch: have %x/%s/%d", i, tx.hash, tx.bType, tx.interval)
+		}
+		if !bytes.Equal(tx.tx.Data(), []byte{byte(interval)}) {
+			t.Errorf("transaction %d: payload mismatch: have %x", i, tx.tx.Data())
+		}
+	}
+	// The dropped transactions must be deleted from disk
+	if txs, dropped := loadBroadcastJournal(t, dir, 0); len(txs) != 2 || dropped != 0 {
+		t.Fatalf("stale transactions not deleted: have %d loaded, %d dropped", len(txs), dropped)
+	}
+}
+
+// Tests that undecodable journal entries are dropped instead of aborting the
+// replay.
+func TestBroadcastJournalCorrupt(t *testing.T) {
+	journal, dir := newTestBroadcastJournal(t)
+	defer os.RemoveAll(dir)
+
+	tx := types.NewBroadCastTransaction(types.BroadCastTxIndex, []byte("payload"))
+	if err := journal.insert(common.Hash{0x02}, mc.Publickey, 1, tx); err != nil {
+		t.Fatalf("failed to journal transaction: %v", err)
+	}
+	if err := journal.db.Put(journalKey(1, common.Hash{0x01}), []byte("garbage")); err != nil {
+		t.Fatalf("failed to write corrupt entry: %v", err)
+	}
+	journal.close()
+
+	txs, dropped := loadBroadcastJournal(t, dir, 1)
+	if len(txs) != 1 || dropped != 1 {
+		t.Fatalf("replay mismatch: have %d loaded, %d dropped, want 1, 1", len(txs), dropped)
+	}
+	if txs[0].hash != (common.Hash{0x02}) {
+		t.Errorf("wrong transaction replayed: %x", txs[0].hash)
+	}
+}
+
+// Tests that the journal follows the pool: expired intervals are pruned and a
+// drained pool leaves nothing to replay.
+func TestBroadcastJournalPruneReset(t *testing.T) {
+	journal, dir := newTestBroadcastJournal(t)
+	defer os.RemoveAll(dir)
+
+	for interval := uint64(1); interval <= 3; interval++ {
+		tx := types.NewBroadCastTransaction(types.BroadCastTxIndex, []byte{byte(interval)})
+		if err := journal.insert(common.Hash{byte(interval)}, mc.Heartbeat, interval, tx); err != nil {
+			t.Fatalf("failed to journal transaction: %v", err)
+		}
+	}
+	if err := journal.prune(3); err != nil {
+		t.Fatalf("failed to prune journal: %v", err)
+	}
+	journal.close()
+	if txs, _ := loadBroadcastJournal(t, dir, 0); len(txs) != 1 || txs[0].interval != 3 {
+		t.Fatalf("prune mismatch: have %d transactions left, want the one of interval 3", len(txs))
+	}
+
+	// Draining the pool resets its journal
+	pool := newTestBroadTxPool()
+	if pool.journal, _ = newBroadcastJournal(dir); pool.journal == nil {
+		t.Fatalf("failed to reopen journal")
+	}
+	pool.Drain()
+	pool.journal.close()
+	if txs, dropped := loadBroadcastJournal(t, dir, 0); len(txs) != 0 || dropped != 0 {
+		t.Fatalf("drained journal not empty: have %d loaded, %d dropped", len(txs), dropped)
+	}
+}
+
+// Tests that a closed or missing journal reports errors instead of crashing.
+func TestBroadcastJournalClosed(t *testing.T) {
+	if _, err := newBroadcastJournal(""); err != errNoBroadcastJournal {
+		t.Fatalf("journal without data dir error mismatch: have %v, want %v", err, errNoBroadcastJournal)
+	}
+	journal, dir := newTestBroadcastJournal(t)
+	defer os.RemoveAll(dir)
+
+	journal.close()
+	journal.close()
+
+	tx := types.NewBroadCastTransaction(types.BroadCastTxIndex, nil)
+	if err := journal.insert(common.Hash{}, mc.Heartbeat, 1, tx); err != errNoBroadcastJournal {
+		t.Errorf("insert error mismatch: have %v, want %v", err, errNoBroadcastJournal)
+	}
+	if err := journal.prune(1); err != errNoBroadcastJournal {
+		t.Errorf("prune error mismatch: have %v, want %v", err, errNoBroadcastJournal)
+	}
+	if err := journal.reset(); err != errNoBroadcastJournal {
+		t.Errorf("reset error mismatch: have %v, want %v", err, errNoBroadcastJournal)
+	}
+}
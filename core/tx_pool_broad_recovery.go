@@ -0,0 +1,287 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+)
+
+// broadcastNEntry is an entry of the N-list index of the broadcast pool. A
+// special transaction carrying several broadcast keys is referenced once per key.
+type broadcastNEntry struct {
+	tx   types.SelfTransaction
+	refs int
+}
+
+// broadcastRecentTx is a special transaction no longer held by the pool (drained
+// into a broadcast block or fetched from a peer), kept around so that the
+// broadcast block can still be recovered by N-list.
+type broadcastRecentTx struct {
+	tx        types.SelfTransaction
+	interval  uint64
+	validated bool // Whether the transaction passed the checks of the pool
+}
+
+// BroadcastTxN returns the N by which a special transaction is referenced in the
+// TxsCode N-lists of the block recovery protocol, the one the block producer
+// lists it with.
+func BroadcastTxN(tx types.SelfTransaction) uint32 {
+	if tx.GetTxNLen() > 0 {
+		return tx.GetTxN(0)
+	}
+	return types.BroadcastTxN(tx.Hash())
+}
+
+// indexN references a special transaction in the N-list index, whilst assuming
+// the pool lock is already held.
+func (bPool *BroadCastTxPool) indexN(tx types.SelfTransaction) {
+	n := BroadcastTxN(tx)
+	if entry, ok := bPool.byN[n]; ok {
+		entry.refs++
+		return
+	}
+	bPool.byN[n] = &broadcastNEntry{tx: tx, refs: 1}
+	bPool.notifyN()
+}
+
+// notifyN wakes up the recoveries waiting for special transactions by N-list,
+// whilst assuming the pool lock is already held.
+func (bPool *BroadCastTxPool) notifyN() {
+	if bPool.nArrived != nil {
+		close(bPool.nArrived)
+	}
+	bPool.nArrived = make(chan struct{})
+}
+
+// unindexN drops a reference of a special transaction from the N-list index,
+// whilst assuming the pool lock is already held.
+func (bPool *BroadCastTxPool) unindexN(tx types.SelfTransaction) {
+	n := BroadcastTxN(tx)
+	if entry, ok := bPool.byN[n]; ok {
+		if entry.refs--; entry.refs <= 0 {
+			delete(bPool.byN, n)
+		}
+	}
+}
+
+// retire moves all special transactions into the recent set and resets the
+// N-list index, used when draining the pool. The pool lock is assumed to be held.
+func (bPool *BroadCastTxPool) retire() {
+	for hash, meta := range bPool.meta {
+		if tx, ok := bPool.special[hash]; ok {
+			bPool.recent[BroadcastTxN(tx)] = &broadcastRecentTx{tx: tx, interval: meta.interval, validated: !meta.replayed}
+		}
+	}
+	bPool.byN = make(map[uint32]*broadcastNEntry)
+}
+
+// expireRecent drops the recent transactions older than the previous broadcast
+// interval, whilst assuming the pool lock is already held.
+func (bPool *BroadCastTxPool) expireRecent(interval uint64) {
+	for n, recent := range bPool.recent {
+		if recent.interval+1 < interval {
+			delete(bPool.recent, n)
+		}
+	}
+}
+
+// getTxByN retrieves a special transaction by its N, whilst assuming
+// the pool lock is already held.
+func (bPool *BroadCastTxPool) getTxByN(n uint32) types.SelfTransaction {
+	if entry, ok := bPool.byN[n]; ok {
+		return entry.tx
+	}
+	if recent, ok := bPool.recent[n]; ok {
+		return recent.tx
+	}
+	return nil
+}
+
+// lookupN retrieves the special transactions of an N-list in order, returning
+// the Ns which are not known locally.
+func (bPool *BroadCastTxPool) lookupN(listN []uint32) (txs []types.SelfTransaction, missing []uint32) {
+	txs, missing, _ = bPool.lookupNOrWait(listN)
+	return txs, missing
+}
+
+// lookupNOrWait is lookupN also returning a channel closed as soon as new
+// special transactions become retrievable by N.
+func (bPool *BroadCastTxPool) lookupNOrWait(listN []uint32) (txs []types.SelfTransaction, missing []uint32, arrived <-chan struct{}) {
+	bPool.mu.Lock()
+	defer bPool.mu.Unlock()
+
+	txs = make([]types.SelfTransaction, 0, len(listN))
+	for _, n := range listN {
+		if tx := bPool.getTxByN(n); tx != nil {
+			txs = append(txs, tx)
+		} else {
+			missing = append(missing, n)
+		}
+	}
+	if len(missing) > 0 && bPool.nArrived == nil {
+		bPool.nArrived = make(chan struct{})
+	}
+	return txs, missing, bPool.nArrived
+}
+
+// ReturnAllTxsByN retrieves the special transactions of an N-list. Missing
+// transactions are requested from the given peer and waited for a few seconds.
+func (bPool *BroadCastTxPool) ReturnAllTxsByN(listN []uint32, resqe byte, addr common.Address, retch chan *RetChan_txpool) {
+	log.Info("BroadCastTxPool returnAllTxsByN", "listN", listN)
+	if len(listN) <= 0 {
+		retch <- &RetChan_txpool{nil, nil, resqe}
+		return
+	}
+	txs, ns, arrived := bPool.lookupNOrWait(listN)
+	if len(ns) == 0 {
+		retch <- &RetChan_txpool{txs, nil, resqe}
+		log.Trace("BroadCastTxPool", "ReturnAllTxsByN", "return success")
+		return
+	}
+	msData, err := json.Marshal(ns)
+	if err != nil {
+		log.Error("BroadCastTxPool", "ReturnAllTxsByN:Marshal=err", err)
+		retch <- &RetChan_txpool{nil, err, resqe}
+		return
+	}
+	// Request the missing transactions and wait until they arrive or we time out
+	bPool.request(ns, addr)
+	defer bPool.unrequest(ns, addr)
+	bPool.SendMsg(MsgStruct{Msgtype: GetConsensusTxbyN, SendAddr: addr, MsgData: msData})
+
+	rettime := time.NewTimer(4 * time.Second)
+	defer rettime.Stop()
+	for len(ns) > 0 {
+		select {
+		case <-rettime.C:
+			log.Info("BroadCastTxPool returnAllTxsByN", "Time Out, missing", len(ns))
+			retch <- &RetChan_txpool{nil, errors.New("loss broadcast tx"), resqe}
+			return
+		case <-arrived:
+			txs, ns, arrived = bPool.lookupNOrWait(listN)
+		}
+	}
+	retch <- &RetChan_txpool{txs, nil, resqe}
+	log.Trace("BroadCastTxPool", "ReturnAllTxsByN", "recv tx over")
+}
+
+// getConsensusTxByN answers a peer's request for special transactions by N-list.
+func (bPool *BroadCastTxPool) getConsensusTxByN(listN []uint32, addr common.Address) {
+	ntx := make(map[uint32]*types.Transaction_Mx)
+	bPool.mu.RLock()
+	for _, n := range listN {
+		if tx := bPool.getTxByN(n); tx != nil {
+			if txMx := types.GetTransactionMx(tx); txMx != nil {
+				ntx[n] = txMx
+			}
+		}
+	}
+	bPool.mu.RUnlock()
+	if len(ntx) == 0 {
+		log.Trace("BroadCastTxPool", "getConsensusTxByN", "no transaction found")
+		return
+	}
+	msData, err := json.Marshal(ntx)
+	if err != nil {
+		log.Error("BroadCastTxPool", "getConsensusTxByN:Marshal=err", err)
+		return
+	}
+	bPool.SendMsg(MsgStruct{Msgtype: RecvConsensusTxbyN, SendAddr: addr, MsgData: msData})
+}
+
+// request records that the given Ns were requested from a peer, only its
+// responses for them are accepted.
+func (bPool *BroadCastTxPool) request(listN []uint32, addr common.Address) {
+	bPool.mu.Lock()
+	defer bPool.mu.Unlock()
+
+	for _, n := range listN {
+		bPool.requested[n] = addr
+	}
+}
+
+// unrequest forgets the Ns requested from a peer once the recovery is over.
+func (bPool *BroadCastTxPool) unrequest(listN []uint32, addr common.Address) {
+	bPool.mu.Lock()
+	defer bPool.mu.Unlock()
+
+	for _, n := range listN {
+		if bPool.requested[n] == addr {
+			delete(bPool.requested, n)
+		}
+	}
+}
+
+// recvConsensusTxByN stores the special transactions a peer sent in response
+// to a request by N-list. Only the Ns requested from that very peer are taken,
+// and only if the transaction passes the checks of the pool. A validated
+// transaction is never overwritten, the recent set is capped at the pool size.
+func (bPool *BroadCastTxPool) recvConsensusTxByN(ntx map[uint32]*types.Transaction_Mx, addr common.Address) {
+	interval := bPool.currentInterval()
+
+	for n, txMx := range ntx {
+		if txMx == nil {
+			continue
+		}
+		bPool.mu.RLock()
+		asked, ok := bPool.requested[n]
+		bPool.mu.RUnlock()
+		if !ok || asked != addr {
+			log.Trace("BroadCastTxPool", "recvConsensusTxByN", "unsolicited transaction", "n", n, "from", addr.Hex())
+			continue
+		}
+		tx := types.SetTransactionMx(txMx)
+		if BroadcastTxN(tx) != n {
+			log.Warn("BroadCastTxPool", "recvConsensusTxByN", "N mismatch", "n", n)
+			continue
+		}
+		if err := bPool.validateFetched(tx); err != nil {
+			log.Warn("BroadCastTxPool", "recvConsensusTxByN", "invalid transaction", "n", n, "from", addr.Hex(), "err", err)
+			continue
+		}
+		bPool.storeFetched(n, tx, interval)
+	}
+}
+
+// validateFetched runs a special transaction fetched from a peer through the
+// same sender and data key checks as the ones added to the pool.
+func (bPool *BroadCastTxPool) validateFetched(tx types.SelfTransaction) error {
+	from, tmpdt, err := bPool.decodeTx(tx)
+	if err != nil {
+		return err
+	}
+	for keydata, value := range tmpdt {
+		if err := bPool.checkKey(from, keydata, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeFetched keeps a validated special transaction fetched from a peer in the
+// recent set, replacing an unvalidated one known by the same N.
+func (bPool *BroadCastTxPool) storeFetched(n uint32, tx types.SelfTransaction, interval uint64) {
+	bPool.mu.Lock()
+	defer bPool.mu.Unlock()
+
+	if _, ok := bPool.byN[n]; ok {
+		return
+	}
+	recent, ok := bPool.recent[n]
+	if ok && recent.validated {
+		return
+	}
+	if !ok && uint64(len(bPool.recent)) >= bPool.config.BroadcastGlobalSlots {
+		log.Warn("BroadCastTxPool", "recvConsensusTxByN", "recent transactions full", "count", len(bPool.recent))
+		return
+	}
+	bPool.recent[n] = &broadcastRecentTx{tx: tx, interval: interval, validated: true}
+	bPool.notifyN()
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func newTestBroadTxPool() *BroadCastTxPool {
+	return &BroadCastTxPool{
+		config:    DefaultTxPoolConfig,
+		special:   make(map[common.Hash]types.SelfTransaction),
+		meta:      make(map[common.Hash]*broadcastTxMeta),
+		senders:   make(map[common.Address]uint64),
+		byN:       make(map[uint32]*broadcastNEntry),
+		recent:    make(map[uint32]*broadcastRecentTx),
+		requested: make(map[uint32]common.Address),
+	}
+}
+
+// Tests that special transactions of expired broadcast intervals are evicted
+// and the per sender accounting is kept consistent.
+func TestBroadcastPoolEviction(t *testing.T) {
+	pool := newTestBroadTxPool()
+
+	alice, bob := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	tx := types.NewBroadCastTransaction(types.BroadCastTxIndex, nil)
+
+	pool.insert(common.HexToHash("0x01"), alice, mc.Heartbeat, 1, tx)
+	pool.insert(common.HexToHash("0x02"), alice, mc.Heartbeat, 2, tx)
+	pool.insert(common.HexToHash("0x03"), bob, mc.Publickey, 1, tx)
+
+	if pool.senders[alice] != 2 || pool.senders[bob] != 1 {
+		t.Fatalf("sender accounting mismatch: alice %d, bob %d", pool.senders[alice], pool.senders[bob])
+	}
+	pool.evict(2)
+	if len(pool.special) != 1 || len(pool.meta) != 1 {
+		t.Fatalf("pool size mismatch: have %d/%d, want 1/1", len(pool.special), len(pool.meta))
+	}
+	if pool.senders[alice] != 1 {
+		t.Errorf("alice slot count mismatch: have %d, want 1", pool.senders[alice])
+	}
+	if _, ok := pool.senders[bob]; ok {
+		t.Errorf("bob still accounted after eviction")
+	}
+}
+
+// Tests that broadcast types can be registered and resolved from the keys of
+// special transaction payloads.
+func TestBroadcastTypeRegistry(t *testing.T) {
+	if bt, ok := broadcastTypeOfKey(mc.Publickey + "12"); !ok || bt.Name != mc.Publickey {
+		t.Fatalf("public key type not resolved: %v", bt)
+	}
+	if bt, ok := broadcastTypeOfKey(mc.Privatekey + "12"); !ok || bt.Name != mc.Privatekey {
+		t.Fatalf("private key type not resolved: %v", bt)
+	}
+	if err := RegisterBroadcastType(BroadcastType{Name: mc.Heartbeat}); err != ErrBroadcastTypeExists {
+		t.Errorf("duplicate registration error mismatch: have %v, want %v", err, ErrBroadcastTypeExists)
+	}
+	if err := RegisterBroadcastType(BroadcastType{Name: "Vote2"}); err != ErrInvalidBroadcastType {
+		t.Errorf("invalid name error mismatch: have %v, want %v", err, ErrInvalidBroadcastType)
+	}
+	if err := RegisterBroadcastType(BroadcastType{Name: "TestVote"}); err != nil {
+		t.Fatalf("failed to register broadcast type: %v", err)
+	}
+	bt, ok := broadcastTypeOfKey("TestVote7")
+	if !ok || bt.StateKey != "TestVote" {
+		t.Fatalf("registered type not resolved: %v", bt)
+	}
+}
+
+// Tests that retrieving the special transactions leaves the pool intact and
+// only draining empties it.
+func TestBroadcastPoolDrain(t *testing.T) {
+	pool := newTestBroadTxPool()
+
+	alice := common.HexToAddress("0x01")
+	tx := types.NewBroadCastTransaction(types.BroadCastTxIndex, nil)
+	pool.insert(common.HexToHash("0x01"), alice, mc.Heartbeat, 1, tx)
+	pool.insert(common.HexToHash("0x02"), alice, mc.Publickey, 1, tx)
+
+	if txs := pool.GetAllSpecialTxs(); len(txs[alice]) != 1 {
+		t.Fatalf("special transaction count mismatch: have %d, want 1", len(txs[alice]))
+	}
+	if len(pool.special) != 2 {
+		t.Fatalf("pool drained by retrieval: have %d, want 2", len(pool.special))
+	}
+	if txs := pool.Drain(); len(txs[alice]) != 1 {
+		t.Fatalf("drained transaction count mismatch: have %d, want 1", len(txs[alice]))
+	}
+	if len(pool.special) != 0 || len(pool.meta) != 0 || len(pool.senders) != 0 {
+		t.Fatalf("pool not emptied by drain")
+	}
+}
+
+// Tests that special transactions stay retrievable by N-list after the pool was
+// drained into a broadcast block.
+func TestBroadcastPoolLookupN(t *testing.T) {
+	pool := newTestBroadTxPool()
+
+	tx := types.NewBroadCastTransaction(types.BroadCastTxIndex, []byte("payload"))
+	pool.insert(common.HexToHash("0x01"), common.HexToAddress("0x01"), mc.Heartbeat, 1, tx)
+
+	n := BroadcastTxN(tx)
+	if txs, missing := pool.lookupN([]uint32{n, n + 1}); len(txs) != 1 || len(missing) != 1 || missing[0] != n+1 {
+		t.Fatalf("lookup mismatch: have %d txs, missing %v", len(txs), missing)
+	}
+	pool.Drain()
+	if txs, missing := pool.lookupN([]uint32{n}); len(txs) != 1 || len(missing) != 0 {
+		t.Fatalf("drained transaction not retrievable: have %d txs, missing %v", len(txs), missing)
+	}
+	pool.evict(3)
+	if _, missing := pool.lookupN([]uint32{n}); len(missing) != 1 {
+		t.Fatalf("expired transaction still retrievable")
+	}
+}
+
+// Tests that special transactions are looked up by the N the block producer
+// lists them with, and that waiting recoveries are woken up on arrival.
+func TestBroadcastPoolWaitN(t *testing.T) {
+	pool := newTestBroadTxPool()
+
+	tx := types.NewBroadCastTransaction(types.BroadCastTxIndex, []byte("payload"))
+	n := tx.GetTxN(0)
+	if have := BroadcastTxN(tx); have != n {
+		t.Fatalf("N mismatch: have %d, want %d", have, n)
+	}
+	_, missing, arrived := pool.lookupNOrWait([]uint32{n})
+	if len(missing) != 1 {
+		t.Fatalf("unknown transaction retrievable")
+	}
+	select {
+	case <-arrived:
+		t.Fatalf("waiter woken up before arrival")
+	default:
+	}
+	pool.insert(common.HexToHash("0x01"), common.HexToAddress("0x01"), mc.Heartbeat, 1, tx)
+	select {
+	case <-arrived:
+	default:
+		t.Fatalf("waiter not woken up on arrival")
+	}
+	if txs, missing, _ := pool.lookupNOrWait([]uint32{n}); len(txs) != 1 || len(missing) != 0 {
+		t.Fatalf("arrived transaction not retrievable: have %d txs, missing %v", len(txs), missing)
+	}
+}
+
+// Tests that special transactions fetched by N are only taken from the peer they
+// were requested from, and that validated ones are not overwritten.
+func TestBroadcastPoolFetchedN(t *testing.T) {
+	pool := newTestBroadTxPool()
+
+	tx := types.NewBroadCastTransaction(types.BroadCastTxIndex, []byte("payload"))
+	n, peer, other := BroadcastTxN(tx), common.HexToAddress("0x01"), common.HexToAddress("0x02")
+
+	pool.recvConsensusTxByN(map[uint32]*types.Transaction_Mx{n: types.GetTransactionMx(tx)}, peer)
+	pool.request([]uint32{n}, peer)
+	pool.recvConsensusTxByN(map[uint32]*types.Transaction_Mx{n: types.GetTransactionMx(tx)}, other)
+	if _, missing := pool.lookupN([]uint32{n}); len(missing) != 1 {
+		t.Fatalf("unsolicited transaction accepted")
+	}
+	pool.unrequest([]uint32{n}, peer)
+	if len(pool.requested) != 0 {
+		t.Fatalf("request not forgotten: %v", pool.requested)
+	}
+
+	// A validated transaction replaces a replayed one, but not the other way around
+	replayed := types.NewBroadCastTransaction(types.BroadCastTxIndex, []byte("replayed"))
+	pool.recent[n] = &broadcastRecentTx{tx: replayed, interval: 1}
+	pool.storeFetched(n, tx, 1)
+	if recent := pool.recent[n]; recent.tx != tx || !recent.validated {
+		t.Fatalf("unvalidated transaction not replaced")
+	}
+	pool.storeFetched(n, replayed, 1)
+	if pool.recent[n].tx != tx {
+		t.Fatalf("validated transaction overwritten")
+	}
+
+	// The recent set is capped at the pool size
+	pool.config.BroadcastGlobalSlots = 1
+	pool.storeFetched(n+1, tx, 1)
+	if len(pool.recent) != 1 {
+		t.Fatalf("recent set not capped: have %d transactions", len(pool.recent))
+	}
+}
+
+// Tests that transaction data keys are matched by substring before the broadcast
+// types fork and by registered type afterwards.
+func TestBroadcastTypeOfKeyFork(t *testing.T) {
+	st := newForkTestState(mc.ForkActivation{Name: mc.ForkBroadcastTypes, ActivateNumber: 100})
+
+	if bt, ok := broadcastTypeOfKeyAt(st, 99, "x"+mc.Publickey+"12"); !ok || bt.Name != mc.Publickey {
+		t.Fatalf("legacy key not resolved before the fork: %v", bt)
+	}
+	if _, ok := broadcastTypeOfKeyAt(st, 99, mc.SeedCommit+"12"); ok {
+		t.Fatalf("unknown legacy key resolved before the fork")
+	}
+	if _, ok := broadcastTypeOfKeyAt(st, 100, "x"+mc.Publickey+"12"); ok {
+		t.Fatalf("key resolved by substring after the fork")
+	}
+	if bt, ok := broadcastTypeOfKeyAt(st, 100, mc.SeedCommit+"12"); !ok || bt.Name != mc.SeedCommit {
+		t.Fatalf("registered key not resolved after the fork: %v", bt)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+var (
+	// ErrBroadcastTypeExists is returned when registering a broadcast type twice.
+	ErrBroadcastTypeExists = errors.New("broadcast type already registered")
+
+	// ErrInvalidBroadcastType is returned when registering a broadcast type with
+	// an empty name or a name ending in a digit, which would be ambiguous with the
+	// broadcast interval suffixed to the transaction keys.
+	ErrInvalidBroadcastType = errors.New("invalid broadcast type name")
+)
+
+// BroadcastChainReader is the chain access offered to broadcast type filters.
+type BroadcastChainReader interface {
+	CurrentBlock() *types.Block
+	GetA0AccountFromAnyAccountAtSignHeight(account common.Address, blockHash common.Hash, signHeight uint64) (common.Address, common.Address, error)
+	GetBroadcastIntervalByHash(blockHash common.Hash) (*mc.BCIntervalInfo, error)
+	GetBroadcastIntervalAt(number uint64) (*mc.BCIntervalInfo, error)
+}
+
+// broadcastIntervalInfo returns the broadcast period in effect at the block,
+// which on-chain param updates may change. If the state of the block is
+// unavailable, the period recorded for its height is used, and the configured
+// one only as a last resort.
+func broadcastIntervalInfo(chain BroadcastChainReader, block *types.Block) *mc.BCIntervalInfo {
+	bcInterval, err := chain.GetBroadcastIntervalByHash(block.Hash())
+	if err == nil {
+		return bcInterval
+	}
+	if bcInterval, err := chain.GetBroadcastIntervalAt(block.NumberU64() + 1); err == nil {
+		return bcInterval
+	}
+	log.Warn("BroadCastTxPool", "get broadcast interval err", err, "number", block.NumberU64())
+	return manparams.GetBCIntervalInfo()
+}
+
+// BroadcastFilter checks whether the sender is allowed to send a broadcast
+// transaction of a given type on top of the head block.
+type BroadcastFilter func(chain BroadcastChainReader, head *types.Block, from common.Address) error
+
+// BroadcastPayloadCheck checks the payload of a broadcast transaction.
+type BroadcastPayloadCheck func(payload []byte) error
+
+// BroadcastType describes a category of special transactions accepted by the
+// broadcast pool.
+type BroadcastType struct {
+	Name         string                // Key prefix of the type in the transaction data
+	StateKey     string                // Key of the accepted payloads in the broadcast matrix state
+	Filter       BroadcastFilter       // Sender validation, nil accepts any sender
+	CheckPayload BroadcastPayloadCheck // Payload validation, nil accepts any payload
+	Fork         string                // Fork enforcing the payload validation, empty if always enforced
+}
+
+var (
+	broadcastTypesMu sync.RWMutex
+	broadcastTypes   = make(map[string]*BroadcastType)
+)
+
+func init() {
+	for _, bt := range []BroadcastType{
+		{Name: mc.Heartbeat, StateKey: mc.Heartbeat, Filter: filterHeartbeat},
+		{Name: mc.Publickey, StateKey: mc.Publickey, Filter: filterElectedValidator},
+		{Name: mc.Privatekey, StateKey: mc.Privatekey, Filter: filterElectedValidator, CheckPayload: checkPrivatekeyPayload, Fork: mc.ForkSeedThreshold},
+		{Name: mc.VrfPublicKey, StateKey: mc.VrfPublicKey, Filter: filterElectedValidator},
+		{Name: mc.CallTheRoll, StateKey: mc.CallTheRoll, Filter: filterCallTheRoll},
+		{Name: mc.SeedCommit, StateKey: mc.SeedCommit, Filter: filterElectedValidator, CheckPayload: checkSeedPayload},
+		{Name: mc.SeedReveal, StateKey: mc.SeedReveal, Filter: filterElectedValidator, CheckPayload: checkSeedPayload},
+		{Name: mc.SeedShare, StateKey: mc.SeedShare, Filter: filterElectedValidator, CheckPayload: checkSeedSharePayload},
+	} {
+		if err := RegisterBroadcastType(bt); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterBroadcastType adds a new category of special transactions to the set
+// accepted by the broadcast pool. If no state key is given, the payloads are
+// stored in the matrix state under the type name.
+func RegisterBroadcastType(bt BroadcastType) error {
+	if bt.Name == "" || unicode.IsDigit(rune(bt.Name[len(bt.Name)-1])) {
+		return ErrInvalidBroadcastType
+	}
+	if bt.StateKey == "" {
+		bt.StateKey = bt.Name
+	}
+	broadcastTypesMu.Lock()
+	defer broadcastTypesMu.Unlock()
+
+	if _, ok := broadcastTypes[bt.Name]; ok {
+		return ErrBroadcastTypeExists
+	}
+	broadcastTypes[bt.Name] = &bt
+	return nil
+}
+
+// LookupBroadcastType retrieves a registered broadcast type by name.
+func LookupBroadcastType(name string) (*BroadcastType, bool) {
+	broadcastTypesMu.RLock()
+	defer broadcastTypesMu.RUnlock()
+
+	bt, ok := broadcastTypes[name]
+	return bt, ok
+}
+
+// BroadcastTypes returns all registered broadcast types, sorted by name.
+func BroadcastTypes() []*BroadcastType {
+	broadcastTypesMu.RLock()
+	defer broadcastTypesMu.RUnlock()
+
+	list := make([]*BroadcastType, 0, len(broadcastTypes))
+	for _, bt := range broadcastTypes {
+		list = append(list, bt)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// broadcastTypeOfKey resolves the broadcast type of a transaction data key made
+// of the type name followed by the broadcast interval.
+func broadcastTypeOfKey(key string) (*BroadcastType, bool) {
+	return LookupBroadcastType(strings.TrimRightFunc(key, unicode.IsDigit))
+}
+
+// legacyBroadcastTypes are the broadcast types whose names were matched anywhere
+// in the transaction data keys, in this order, before the broadcast types fork.
+var legacyBroadcastTypes = []string{mc.Publickey, mc.Privatekey, mc.Heartbeat, mc.CallTheRoll}
+
+// broadcastTypeOfKeyAt resolves the broadcast type of a transaction data key in
+// a block at the given number. Before the broadcast types fork the key is
+// matched the way it was when the block state was first produced.
+func broadcastTypeOfKeyAt(st matrixstate.StateDB, number uint64, key string) (*BroadcastType, bool) {
+	if ForkActive(st, mc.ForkBroadcastTypes, number) {
+		return broadcastTypeOfKey(key)
+	}
+	for _, name := range legacyBroadcastTypes {
+		if strings.Contains(key, name) {
+			return LookupBroadcastType(name)
+		}
+	}
+	return nil, false
+}
+
+// checkBroadcastPayload validates the payload of a transaction data key with the
+// check of its broadcast type, if any.
+func checkBroadcastPayload(key string, payload []byte) error {
+	bt, ok := broadcastTypeOfKey(key)
+	if !ok || bt.CheckPayload == nil {
+		return nil
+	}
+	return bt.CheckPayload(payload)
+}
+
+// filterCallTheRoll only accepts roll calls of broadcast nodes sent right before
+// the broadcast block.
+func filterCallTheRoll(chain BroadcastChainReader, head *types.Block, from common.Address) error {
+	bcInterval := broadcastIntervalInfo(chain, head)
+	curBlockNum := head.NumberU64()
+	curBroadcastNum := bcInterval.GetNextBroadcastNumber(curBlockNum)
+	if curBlockNum+1 != curBroadcastNum && curBlockNum+2 != curBroadcastNum {
+		log.Error("The current block height is higher than the broadcast block height. (func filter())")
+		return ErrWrongBroadcastInterval
+	}
+	for _, addr := range ca.GetRolesByGroup(common.RoleBroadcast) {
+		if addr == from {
+			return nil
+		}
+	}
+	log.Error("unknown broadcast Address. error (func filter()  BroadCastTxPool) ")
+	return ErrUnauthorizedBroadcaster
+}
+
+// filterHeartbeat only accepts heartbeats of elected nodes whose turn it is in
+// the current broadcast interval.
+func filterHeartbeat(chain BroadcastChainReader, head *types.Block, from common.Address) error {
+	bcInterval := broadcastIntervalInfo(chain, head)
+	blockHash := head.Hash()
+	fromDepositAccount, _, err := chain.GetA0AccountFromAnyAccountAtSignHeight(from, blockHash, bcInterval.GetNextBroadcastNumber(head.NumberU64()))
+	if err != nil {
+		log.Error("BroadCastTxPool", "convert from account to deposit account err", err, "from", from.Hex())
+		return ErrUnauthorizedBroadcaster
+	}
+	nodelist, err := ca.GetElectedByHeightByHash(blockHash)
+	if err != nil {
+		log.Error("getElected error (func filter()   BroadCastTxPool)", "error", err)
+		return err
+	}
+	for _, node := range nodelist {
+		if fromDepositAccount == node.Address {
+			currentAcc := fromDepositAccount.Big()
+			ret := new(big.Int).Rem(currentAcc, big.NewInt(int64(bcInterval.GetBroadcastInterval())-1))
+			broadcastBlock := blockHash.Big()
+			val := new(big.Int).Rem(broadcastBlock, big.NewInt(int64(bcInterval.GetBroadcastInterval())-1))
+			if ret.Cmp(val) == 0 {
+				return nil
+			}
+		}
+	}
+	log.Warn("Unknown account information (func filter()   BroadCastTxPool),mc.Heartbeat")
+	return ErrUnauthorizedBroadcaster
+}
+
+// filterElectedValidator only accepts seed and VRF key transactions of elected
+// validators.
+func filterElectedValidator(chain BroadcastChainReader, head *types.Block, from common.Address) error {
+	bcInterval := broadcastIntervalInfo(chain, head)
+	blockHash := head.Hash()
+	fromDepositAccount, _, err := chain.GetA0AccountFromAnyAccountAtSignHeight(from, blockHash, bcInterval.GetNextBroadcastNumber(head.NumberU64()))
+	if err != nil {
+		log.Error("BroadCastTxPool", "convert from account to deposit account err", err, "from", from.Hex())
+		return ErrUnauthorizedBroadcaster
+	}
+	nodelist, err := ca.GetElectedByHeightAndRoleByHash(blockHash, common.RoleValidator)
+	if err != nil {
+		log.Error("broadCastTxPool filter getElected error", "error", err)
+		return err
+	}
+	for _, node := range nodelist {
+		if fromDepositAccount == node.Address {
+			return nil
+		}
+	}
+	log.Warn("Unknown account information ,mc.Privatekey,mc.Publickey")
+	return ErrUnauthorizedBroadcaster
+}
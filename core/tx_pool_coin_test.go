@@ -0,0 +1,30 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+func TestIsCurrencyInConfig(t *testing.T) {
+	coinCfgs := []common.CoinConfig{
+		{CoinRange: "BTC", CoinType: "BTC", PackNum: 100},
+		{CoinRange: "ETH", CoinType: "ETH", PackNum: 0},
+	}
+	tests := []struct {
+		coin string
+		want bool
+	}{
+		{"BTC", true},
+		{"ETH", false}, // not packed into blocks
+		{"LTC", false},
+	}
+	for _, tt := range tests {
+		if have := isCurrencyInConfig(tt.coin, coinCfgs); have != tt.want {
+			t.Errorf("coin %s: have %v, want %v", tt.coin, have, tt.want)
+		}
+	}
+}
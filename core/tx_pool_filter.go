@@ -0,0 +1,117 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+)
+
+var ErrAddressFiltered = errors.New("transaction address is filtered")
+
+// AddressFilter 交易入池前的地址过滤, 普通交易池和广播交易池都会检查
+type AddressFilter interface {
+	// Filter 返回错误时拒绝交易, st为空时只做本地检查
+	Filter(st *state.StateDBManage, from common.Address, to *common.Address) error
+}
+
+// chainBlackListFilter 链上(matrixstate)配置的账户黑名单
+type chainBlackListFilter struct{}
+
+func (chainBlackListFilter) Filter(st *state.StateDBManage, from common.Address, to *common.Address) error {
+	if st == nil {
+		return nil
+	}
+	blackList, err := matrixstate.GetAccountBlackList(st)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range blackList {
+		if addr == from || (to != nil && addr == *to) {
+			return ErrAddressFiltered
+		}
+	}
+	return nil
+}
+
+// LocalBlacklist 节点本地的账户黑名单, 只影响本节点交易池, 不影响共识
+type LocalBlacklist struct {
+	mu   sync.RWMutex
+	list map[common.Address]bool
+}
+
+func NewLocalBlacklist() *LocalBlacklist {
+	return &LocalBlacklist{list: make(map[common.Address]bool)}
+}
+
+// Set 替换本地黑名单
+func (b *LocalBlacklist) Set(addrs []common.Address) {
+	list := make(map[common.Address]bool, len(addrs))
+	for _, addr := range addrs {
+		list[addr] = true
+	}
+	b.mu.Lock()
+	b.list = list
+	b.mu.Unlock()
+}
+
+// List 获取本地黑名单, 按地址排序
+func (b *LocalBlacklist) List() []common.Address {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	addrs := make([]common.Address, 0, len(b.list))
+	for addr := range b.list {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i].Bytes(), addrs[j].Bytes()) < 0
+	})
+	return addrs
+}
+
+func (b *LocalBlacklist) Filter(st *state.StateDBManage, from common.Address, to *common.Address) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.list[from] || (to != nil && b.list[*to]) {
+		return ErrAddressFiltered
+	}
+	return nil
+}
+
+var (
+	localBlacklist = NewLocalBlacklist()
+
+	addressFiltersMu sync.RWMutex
+	addressFilters   = []AddressFilter{chainBlackListFilter{}, localBlacklist}
+)
+
+// LocalTxBlacklist 获取节点本地黑名单
+func LocalTxBlacklist() *LocalBlacklist {
+	return localBlacklist
+}
+
+// RegisterAddressFilter 注册交易池地址过滤器
+func RegisterAddressFilter(filter AddressFilter) {
+	addressFiltersMu.Lock()
+	defer addressFiltersMu.Unlock()
+	addressFilters = append(addressFilters, filter)
+}
+
+// filterAddress 依次检查所有地址过滤器
+func filterAddress(st *state.StateDBManage, from common.Address, to *common.Address) error {
+	addressFiltersMu.RLock()
+	defer addressFiltersMu.RUnlock()
+	for _, filter := range addressFilters {
+		if err := filter.Filter(st, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+func TestLocalBlacklistFilter(t *testing.T) {
+	addrA := common.HexToAddress("0x01")
+	addrB := common.HexToAddress("0x02")
+	addrC := common.HexToAddress("0x03")
+
+	blacklist := NewLocalBlacklist()
+	blacklist.Set([]common.Address{addrB, addrA})
+	if list := blacklist.List(); len(list) != 2 || list[0] != addrA || list[1] != addrB {
+		t.Fatalf("blacklist mismatch: have %v", list)
+	}
+	if err := blacklist.Filter(nil, addrA, &addrC); err != ErrAddressFiltered {
+		t.Errorf("blacklisted sender not filtered: %v", err)
+	}
+	if err := blacklist.Filter(nil, addrC, &addrB); err != ErrAddressFiltered {
+		t.Errorf("blacklisted recipient not filtered: %v", err)
+	}
+	if err := blacklist.Filter(nil, addrC, nil); err != nil {
+		t.Errorf("contract creation filtered: %v", err)
+	}
+
+	blacklist.Set(nil)
+	if err := blacklist.Filter(nil, addrA, &addrB); err != nil {
+		t.Errorf("cleared blacklist still filters: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
+	"github.com/MatrixAINetwork/go-matrix/p2p"
+)
+
+// In gossip mode a broadcast transaction floods through the broadcast nodes
+// instead of relying on the sender being connected to every one of them. Each
+// message carries a bitmap over the sorted broadcast nodes marking the ones
+// already sent the transaction: every node sends it to the uncovered nodes it is
+// connected to, marking them first, and forwards it only the first time it sees
+// it. Nodes thus never re-send a transaction nor send it to nodes known to have
+// it, only siblings of the flood may reach the same node concurrently.
+
+// maxGossipHops bounds the number of times a broadcast transaction is forwarded.
+const maxGossipHops = 3
+
+var (
+	gossipSentCounter      = metrics.NewRegisteredCounter("txpool/broadcast/gossip/sent", nil)
+	gossipDuplicateCounter = metrics.NewRegisteredCounter("txpool/broadcast/gossip/duplicate", nil)
+	gossipUncoveredCounter = metrics.NewRegisteredCounter("txpool/broadcast/gossip/uncovered", nil)
+	gossipLatencyTimer     = metrics.NewRegisteredTimer("txpool/broadcast/gossip/latency", nil)
+	gossipHopsHistogram    = metrics.NewRegisteredHistogram("txpool/broadcast/gossip/hops", nil, metrics.NewExpDecaySample(1028, 0.015))
+)
+
+// Hooks to the topology and the network, replaced in tests.
+var (
+	gossipMembers = func() []common.Address { return ca.GetRolesByGroup(common.RoleBroadcast) }
+	gossipSelf    = ca.GetDepositAddress
+	gossipReach   = p2p.IsConnected
+	gossipSend    = func(addr common.Address, data MsgStruct) error {
+		return p2p.SendToSingle(addr, common.NetworkMsg, []interface{}{data})
+	}
+)
+
+// broadcastGossipMsg is the payload of a GossipBroadCast message.
+type broadcastGossipMsg struct {
+	Tx      *types.Transaction_Mx `json:"tx"`
+	Members int                   `json:"members"` // Number of broadcast nodes the bitmap covers
+	Covered []byte                `json:"covered"` // Bitmap of the broadcast nodes already sent the transaction
+	Hops    int                   `json:"hops"`    // Number of times the transaction was forwarded
+	Origin  int64                 `json:"origin"`  // Time the transaction was first sent at, in unix nanoseconds
+}
+
+// gossipEntry is the propagation state of a gossiped transaction.
+type gossipEntry struct {
+	covered  []byte
+	interval uint64
+}
+
+// broadcastGossip tracks the propagation of the gossiped broadcast transactions.
+type broadcastGossip struct {
+	lock    sync.Mutex
+	entries map[common.Hash]*gossipEntry
+}
+
+func newBroadcastGossip() *broadcastGossip {
+	return &broadcastGossip{entries: make(map[common.Hash]*gossipEntry)}
+}
+
+// sortedMembers returns the broadcast nodes in the order the bitmaps index them.
+func sortedMembers() []common.Address {
+	members := gossipMembers()
+	sort.Slice(members, func(i, j int) bool { return bytes.Compare(members[i][:], members[j][:]) < 0 })
+	return members
+}
+
+// merge records the nodes covered by a received message, returning whether the
+// transaction was seen for the first time and the known covered nodes.
+func (g *broadcastGossip) merge(hash common.Hash, covered []byte, size int, interval uint64) (bool, []byte) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	entry, known := g.entries[hash]
+	if !known {
+		entry = &gossipEntry{covered: make([]byte, (size+7)/8), interval: interval}
+		g.entries[hash] = entry
+	}
+	for i := 0; i < len(covered) && i < len(entry.covered); i++ {
+		entry.covered[i] |= covered[i]
+	}
+	return !known, common.CopyBytes(entry.covered)
+}
+
+// cover marks the nodes a transaction is being sent to.
+func (g *broadcastGossip) cover(hash common.Hash, targets []int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if entry, ok := g.entries[hash]; ok {
+		for _, i := range targets {
+			if i/8 < len(entry.covered) {
+				entry.covered[i/8] |= 1 << uint(i%8)
+			}
+		}
+	}
+}
+
+// expire drops the propagation state of the transactions of intervals before
+// the given one.
+func (g *broadcastGossip) expire(interval uint64) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	for hash, entry := range g.entries {
+		if entry.interval < interval {
+			delete(g.entries, hash)
+		}
+	}
+}
+
+// gossipTargets selects the uncovered broadcast nodes reachable from the local
+// node, and marks them covered in the bitmap.
+func gossipTargets(members []common.Address, covered []byte) []int {
+	self := gossipSelf()
+	var targets []int
+	for i, member := range members {
+		if covered[i/8]&(1<<uint(i%8)) != 0 {
+			continue
+		}
+		if member == self {
+			covered[i/8] |= 1 << uint(i%8)
+			continue
+		}
+		if gossipReach(member) {
+			covered[i/8] |= 1 << uint(i%8)
+			targets = append(targets, i)
+		}
+	}
+	return targets
+}
+
+// sendGossip sends the transaction to the target broadcast nodes.
+func sendGossip(members []common.Address, targets []int, msg *broadcastGossipMsg) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Error("Failed to encode broadcast gossip", "err", err)
+		return
+	}
+	for _, i := range targets {
+		if err := gossipSend(members[i], MsgStruct{Msgtype: GossipBroadCast, SendAddr: members[i], MsgData: data, TxpoolType: types.BroadCastTxIndex}); err != nil {
+			log.Debug("Failed to gossip broadcast transaction", "to", members[i].Hex(), "err", err)
+			continue
+		}
+		gossipSentCounter.Inc(1)
+	}
+}
+
+// originate starts the flood of a broadcast transaction sent by the local node.
+func (g *broadcastGossip) originate(tx *types.Transaction_Mx, interval uint64) {
+	members := sortedMembers()
+	if len(members) == 0 {
+		log.Warn("No broadcast node to gossip the broadcast transaction to")
+		return
+	}
+	hash := types.SetTransactionMx(tx).Hash()
+	_, covered := g.merge(hash, nil, len(members), interval)
+
+	targets := gossipTargets(members, covered)
+	g.cover(hash, targets)
+	if len(targets) == 0 {
+		log.Warn("No broadcast node reachable to gossip the broadcast transaction to", "members", len(members))
+		return
+	}
+	sendGossip(members, targets, &broadcastGossipMsg{
+		Tx:      tx,
+		Members: len(members),
+		Covered: covered,
+		Origin:  time.Now().UnixNano(),
+	})
+}
+
+// receive records a gossiped transaction, returning whether it is seen for the
+// first time and should be forwarded once accepted by the pool.
+func (g *broadcastGossip) receive(msg *broadcastGossipMsg, interval uint64) bool {
+	members := len(gossipMembers())
+	covered := msg.Covered
+	if msg.Members != members {
+		// The topology changed in between, the bitmap is meaningless
+		covered = nil
+	}
+	first, _ := g.merge(types.SetTransactionMx(msg.Tx).Hash(), covered, members, interval)
+	if !first {
+		gossipDuplicateCounter.Inc(1)
+		return false
+	}
+	if msg.Origin > 0 {
+		if latency := time.Since(time.Unix(0, msg.Origin)); latency > 0 {
+			gossipLatencyTimer.Update(latency)
+		}
+	}
+	gossipHopsHistogram.Update(int64(msg.Hops))
+	return true
+}
+
+// forward sends a newly seen gossiped transaction on to the broadcast nodes not
+// covered yet.
+func (g *broadcastGossip) forward(msg *broadcastGossipMsg) {
+	if msg.Hops >= maxGossipHops {
+		return
+	}
+	members := sortedMembers()
+	hash := types.SetTransactionMx(msg.Tx).Hash()
+	_, covered := g.merge(hash, nil, len(members), 0)
+	if size := (len(members) + 7) / 8; len(covered) < size {
+		covered = append(covered, make([]byte, size-len(covered))...)
+	}
+
+	targets := gossipTargets(members, covered)
+	g.cover(hash, targets)
+	for i := range members {
+		if covered[i/8]&(1<<uint(i%8)) == 0 {
+			gossipUncoveredCounter.Inc(1)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	sendGossip(members, targets, &broadcastGossipMsg{
+		Tx:      msg.Tx,
+		Members: len(members),
+		Covered: covered,
+		Hops:    msg.Hops + 1,
+		Origin:  msg.Origin,
+	})
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+// Tests that a gossiped broadcast transaction reaches every broadcast node of a
+// partially connected topology, without any node sending it twice to the same
+// node or back to the nodes known to have it.
+func TestBroadcastGossipFlood(t *testing.T) {
+	members := []common.Address{
+		common.HexToAddress("0x01"), common.HexToAddress("0x02"), common.HexToAddress("0x03"),
+		common.HexToAddress("0x04"), common.HexToAddress("0x05"),
+	}
+	origin := common.HexToAddress("0xff")
+	// A chain of broadcast nodes, the origin only connected to the first one
+	links := map[common.Address][]common.Address{
+		origin:     {members[0]},
+		members[0]: {members[1], members[2]},
+		members[1]: {members[0], members[3]},
+		members[2]: {members[0], members[3]},
+		members[3]: {members[1], members[2], members[4]},
+		members[4]: {members[3]},
+	}
+	type delivery struct {
+		to  common.Address
+		msg *broadcastGossipMsg
+	}
+	var (
+		self    common.Address
+		queue   []delivery
+		states  = make(map[common.Address]*broadcastGossip)
+		receipt = make(map[common.Address]int)
+		sent    = make(map[[2]common.Address]int)
+	)
+	defer func(m func() []common.Address, s func() common.Address, r func(common.Address) bool, send func(common.Address, MsgStruct) error) {
+		gossipMembers, gossipSelf, gossipReach, gossipSend = m, s, r, send
+	}(gossipMembers, gossipSelf, gossipReach, gossipSend)
+
+	gossipMembers = func() []common.Address { return append([]common.Address{}, members...) }
+	gossipSelf = func() common.Address { return self }
+	gossipReach = func(addr common.Address) bool {
+		for _, peer := range links[self] {
+			if peer == addr {
+				return true
+			}
+		}
+		return false
+	}
+	gossipSend = func(addr common.Address, data MsgStruct) error {
+		msg := new(broadcastGossipMsg)
+		if err := json.Unmarshal(data.MsgData, msg); err != nil {
+			t.Fatalf("failed to decode gossip: %v", err)
+		}
+		queue = append(queue, delivery{addr, msg})
+		sent[[2]common.Address{self, addr}]++
+		return nil
+	}
+	tx := types.GetTransactionMx(types.NewBroadCastTransaction(types.BroadCastTxIndex, nil))
+
+	self = origin
+	newBroadcastGossip().originate(tx, 1)
+	for len(queue) > 0 {
+		d := queue[0]
+		queue = queue[1:]
+		receipt[d.to]++
+
+		if states[d.to] == nil {
+			states[d.to] = newBroadcastGossip()
+		}
+		self = d.to
+		if states[d.to].receive(d.msg, 1) {
+			states[d.to].forward(d.msg)
+		}
+	}
+	for _, member := range members {
+		if receipt[member] == 0 {
+			t.Errorf("member %x never received the transaction", member)
+		}
+	}
+	for link, count := range sent {
+		if count != 1 {
+			t.Errorf("transaction sent %d times from %x to %x", count, link[0], link[1])
+		}
+	}
+	// Only the nodes two paths lead to may be reached twice
+	if receipt[members[0]] != 1 || receipt[members[4]] != 1 {
+		t.Errorf("covered nodes sent the transaction again: %d, %d", receipt[members[0]], receipt[members[4]])
+	}
+}
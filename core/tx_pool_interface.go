@@ -21,6 +21,7 @@ const (
 	BroadCast //
 	GetConsensusTxbyN
 	RecvConsensusTxbyN
+	GossipBroadCast // 广播交易的gossip转发
 )
 
 // TxPool interface
@@ -48,6 +49,7 @@ type RetCallTx struct {
 //
 type NetworkMsgData struct {
 	SendAddress common.Address
+	PeerID      discover.NodeID // Originating peer, used to penalize invalid payloads
 	Data        []*MsgStruct
 }
 
@@ -61,7 +63,7 @@ type MsgStruct struct {
 
 //消息中心的接口（如果需要消息中心就要实现这两个方法）
 type MessageProcess interface {
-	ProcessMsg(m NetworkMsgData)
+	ProcessMsg(m NetworkMsgData) error
 	SendMsg(data MsgStruct)
 }
 
@@ -25,6 +25,7 @@ var (
 	ErrTxPoolAlreadyExist = errors.New("txpool already exist")
 	ErrTxPoolIsNil        = errors.New("txpool is nil")
 	ErrTxPoolNonexistent  = errors.New("txpool nonexistent")
+	ErrEmptyNetworkMsg    = errors.New("network message data is empty")
 
 	blockNumberByfilter = uint64(0)
 )
@@ -110,8 +111,12 @@ type TxPoolManager struct {
 	delPool      chan TxPool
 	sendTxCh     chan NewTxsEvent
 	txFeed       event.Feed
+	broadTxCh    chan BroadcastTxEvent
+	broadTxFeed  event.Feed
 	scope        event.SubscriptionScope
 	chain        blockChain
+	selector     TxSelector
+	gossip       bool // Whether broadcast transactions are gossiped
 }
 
 func NewTxPoolManager(config TxPoolConfig, chainconfig *params.ChainConfig, chain blockChain, path string) *TxPoolManager {
@@ -123,7 +128,10 @@ func NewTxPoolManager(config TxPoolConfig, chainconfig *params.ChainConfig, chai
 		addPool:      make(chan TxPool),
 		delPool:      make(chan TxPool),
 		sendTxCh:     make(chan NewTxsEvent),
+		broadTxCh:    make(chan BroadcastTxEvent, chainHeadChanSize),
 		chain:        chain,
+		selector:     priceNonceSelector{},
+		gossip:       config.BroadcastGossip,
 	}
 	SelfBlackList = NewInitblacklist()
 	go txPoolManager.loop(config, chainconfig, chain, path)
@@ -187,12 +195,17 @@ func (pm *TxPoolManager) loop(config TxPoolConfig, chainconfig *params.ChainConf
 	normalTxPool := NewTxPool(config, chainconfig, chain, pm.sendTxCh)
 	pm.Subscribe(normalTxPool)
 
+	reorgCh := make(chan ReorgTxsEvent, chainHeadChanSize)
+	reorgSub := chain.SubscribeReorgTxsEvent(reorgCh)
+	defer reorgSub.Unsubscribe()
+
 	for {
 		select {
 		case role = <-pm.roleChan:
 			pm.once.Do(func() {
 				if role == common.RoleBroadcast {
-					broadTxPool := NewBroadTxPool(chainconfig, chain, path)
+					broadTxPool := NewBroadTxPool(config, chainconfig, chain, path)
+					broadTxPool.SubscribeBroadcastTxEvent(pm.broadTxCh)
 					pm.Subscribe(broadTxPool)
 					pm.sub.Unsubscribe()
 				}
@@ -209,12 +222,50 @@ func (pm *TxPoolManager) loop(config TxPoolConfig, chainconfig *params.ChainConf
 			}
 		case txevent := <-pm.sendTxCh:
 			pm.txFeed.Send(txevent)
+		case broadevent := <-pm.broadTxCh:
+			pm.broadTxFeed.Send(broadevent)
+		case ev := <-reorgCh:
+			// 交易池加入交易时可能通过sendTxCh通知本循环, 不能在循环内同步加入
+			go pm.reinjectTxs(ev.Txs)
 		case <-pm.quit:
 			return
 		}
 	}
 }
 
+// isReinjectableTx 区块生成的奖励交易和超级区块交易不能重新加入交易池
+func isReinjectableTx(tx types.SelfTransaction) bool {
+	switch tx.GetMatrixType() {
+	case common.ExtraUnGasMinerTxType, common.ExtraUnGasValidatorTxType, common.ExtraUnGasInterestTxType,
+		common.ExtraUnGasTxsType, common.ExtraUnGasLotteryTxType, common.ExtraSuperBlockTx:
+		return false
+	}
+	return true
+}
+
+// reinjectTxs 链重组后将被丢弃区块中未被新链打包的交易重新加入对应的交易池,
+// 广播交易是否仍在有效周期内由广播交易池检查
+func (pm *TxPoolManager) reinjectTxs(txs types.SelfTransactions) {
+	pm.txPoolsMutex.RLock()
+	defer pm.txPoolsMutex.RUnlock()
+	reinjected := 0
+	for _, tx := range txs {
+		if !isReinjectableTx(tx) {
+			continue
+		}
+		pool, ok := pm.txPools[tx.TxType()]
+		if !ok {
+			continue
+		}
+		if err := pool.AddTxPool(tx); err != nil {
+			log.Trace("Discarding reorged transaction", "hash", tx.Hash(), "err", err)
+			continue
+		}
+		reinjected++
+	}
+	log.Debug("Reinjected reorged transactions", "dropped", len(txs), "reinjected", reinjected)
+}
+
 // Stop txpool manager.
 func (pm *TxPoolManager) Stop() {
 	pm.txPoolsMutex.Lock()
@@ -249,6 +300,29 @@ func (pm *TxPoolManager) Pending() (map[string]map[common.Address]types.SelfTran
 	//return pending, nil
 }
 
+// SetTxSelector 替换区块打包交易的选择策略
+func (pm *TxPoolManager) SetTxSelector(selector TxSelector) {
+	pm.txPoolsMutex.Lock()
+	defer pm.txPoolsMutex.Unlock()
+	pm.selector = selector
+}
+
+// SelectTxs 按选择策略和区块预算选择要打包的交易, withSpecials为true时(广播区块高度)优先打包广播池中的特殊交易
+func (pm *TxPoolManager) SelectTxs(budget TxBudget, withSpecials bool) ([]types.CoinSelfTransaction, error) {
+	pending, err := pm.Pending()
+	if err != nil {
+		return nil, err
+	}
+	var specials map[common.Address][]types.SelfTransaction
+	if withSpecials {
+		specials = pm.GetAllSpecialTxs()
+	}
+	pm.txPoolsMutex.RLock()
+	selector := pm.selector
+	pm.txPoolsMutex.RUnlock()
+	return selector.SelectTxs(pending, specials, budget), nil
+}
+
 func GetMatrixCoin(state *state.StateDBManage) ([]string, error) {
 	bs := state.GetMatrixData(types.RlpHash(params.COIN_NAME))
 	var tmpcoinlist []string
@@ -409,6 +483,16 @@ func (pm *TxPoolManager) AddRemote(tx types.SelfTransaction) (err error) {
 	err = pm.txPools[tx.TxType()].AddTxPool(tx)
 	return err
 }
+
+// AddLocal 本地RPC提交的交易, 普通交易按本地交易加入交易池
+func (pm *TxPoolManager) AddLocal(tx types.SelfTransaction) (err error) {
+	pm.txPoolsMutex.Lock()
+	defer pm.txPoolsMutex.Unlock()
+	if nPool, ok := pm.txPools[tx.TxType()].(*NormalTxPool); ok {
+		return nPool.AddLocal(tx)
+	}
+	return pm.txPools[tx.TxType()].AddTxPool(tx)
+}
 func (pm *TxPoolManager) AddRemotes(txs []types.SelfTransaction) []error {
 	for _, tx := range txs {
 		pm.txPools[tx.TxType()].AddTxPool(tx)
@@ -420,33 +504,40 @@ func (pm *TxPoolManager) SubscribeNewTxsEvent(ch chan NewTxsEvent) (ev event.Sub
 	return pm.scope.Track(pm.txFeed.Subscribe(ch))
 }
 
+// SubscribeBroadcastTxEvent registers a subscription of BroadcastTxEvent, fired
+// whenever the broadcast pool accepts a special transaction.
+func (pm *TxPoolManager) SubscribeBroadcastTxEvent(ch chan<- BroadcastTxEvent) event.Subscription {
+	return pm.scope.Track(pm.broadTxFeed.Subscribe(ch))
+}
+
 // ProcessMsg
-func (pm *TxPoolManager) ProcessMsg(m NetworkMsgData) {
+func (pm *TxPoolManager) ProcessMsg(m NetworkMsgData) error {
 	pm.txPoolsMutex.RLock()
 	defer pm.txPoolsMutex.RUnlock()
 
 	if len(m.Data) <= 0 {
 		log.Error("TxPoolManager processmsg data is empty")
-		return
+		return ErrEmptyNetworkMsg
 	}
 	messageType := m.Data[0].TxpoolType
 
 	pool, ok := pm.txPools[messageType]
 	if !ok {
 		log.Error("TxPoolManager", "unknown type txpool", messageType)
-		return
+		return ErrTxPoolNonexistent
 	}
 	switch messageType {
 	case types.NormalTxIndex:
 		if nPool, ok := pool.(*NormalTxPool); ok {
-			nPool.ProcessMsg(m)
+			return nPool.ProcessMsg(m)
 		}
 	case types.BroadCastTxIndex:
 		log.Info("TxPoolManager", "Receive broadtx from", m.SendAddress.Hex())
 		if bPool, ok := pool.(*BroadCastTxPool); ok {
-			bPool.ProcessMsg(m)
+			return bPool.ProcessMsg(m)
 		}
 	}
+	return nil
 }
 
 // SendMsg
@@ -467,6 +558,10 @@ func (pm *TxPoolManager) AddBroadTx(tx types.SelfTransaction, bType bool) (err e
 
 			return errors.New("TxPoolManager tx is nil or txMx assertion failed")
 		}
+		if pm.gossip {
+			newBroadcastGossip().originate(txMx, 0)
+			return nil
+		}
 		msData, err := json.Marshal(txMx)
 		if err != nil {
 			return err
@@ -482,6 +577,12 @@ func (pm *TxPoolManager) AddBroadTx(tx types.SelfTransaction, bType bool) (err e
 		if err := pool.AddTxPool(tx); err != nil {
 			return err
 		}
+		// Pass the transaction on to the other broadcast nodes
+		if bPool, ok := pool.(*BroadCastTxPool); ok && bPool.gossip != nil {
+			if txMx := types.GetTransactionMx(tx); txMx != nil {
+				bPool.gossip.originate(txMx, bPool.currentInterval())
+			}
+		}
 	}
 	return nil
 }
@@ -550,6 +651,22 @@ func (pm *TxPoolManager) GetAllSpecialTxs() (reqVal map[common.Address][]types.S
 	return
 }
 
+// DrainSpecialTxs retrieves all special transactions and empties the broadcast pool.
+func (pm *TxPoolManager) DrainSpecialTxs() (reqVal map[common.Address][]types.SelfTransaction) {
+	pm.txPoolsMutex.RLock()
+	defer pm.txPoolsMutex.RUnlock()
+
+	bPool, ok := pm.txPools[types.BroadCastTxIndex]
+	if !ok {
+		log.Error("TxPoolManager", "get broadcast txpool error", ErrTxPoolNonexistent)
+		return
+	}
+	if bTxPool, ok := bPool.(*BroadCastTxPool); ok {
+		reqVal = bTxPool.Drain()
+	}
+	return
+}
+
 func (pm *TxPoolManager) Stats() (int, int) {
 	return 0, 0
 }
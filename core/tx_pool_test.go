@@ -1820,3 +1820,23 @@ func benchmarkPoolBatchInsert(b *testing.B, size int) {
 		pool.AddRemotes(batch)
 	}
 }
+
+// Tests that pool transactions are split into executable ones and the ones
+// queued behind a nonce gap.
+func TestSplitNonceGap(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	txs := []*types.Transaction{transaction(3, 100000, key), transaction(4, 100000, key), transaction(6, 100000, key)}
+
+	pending, queued := splitNonceGap(txs, 3)
+	if len(pending) != 2 || len(queued) != 1 || queued[0].Nonce() != 6 {
+		t.Errorf("split mismatch: pending %d, queued %d", len(pending), len(queued))
+	}
+	pending, queued = splitNonceGap(txs, 2)
+	if len(pending) != 0 || len(queued) != 3 {
+		t.Errorf("gapped split mismatch: pending %d, queued %d", len(pending), len(queued))
+	}
+	pending, queued = splitNonceGap(txs[:2], 3)
+	if len(pending) != 2 || len(queued) != 0 {
+		t.Errorf("contiguous split mismatch: pending %d, queued %d", len(pending), len(queued))
+	}
+}
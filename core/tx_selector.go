@@ -0,0 +1,177 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"bytes"
+	"container/heap"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+// TxBudget 区块打包交易的预算, 各项为0时不限制
+type TxBudget struct {
+	Gas       uint64 // 区块内交易gas总量上限
+	Bytes     uint64 // 区块内交易字节数上限
+	SenderGas uint64 // 单个账户在一个区块内可使用的gas上限
+}
+
+// TxSelector 决定区块打包哪些交易以及打包顺序, 可以通过TxPoolManager.SetTxSelector替换默认策略
+type TxSelector interface {
+	// SelectTxs pending为交易池中按币种和账户分组的交易, specials为广播区块高度时需要优先打包的特殊交易
+	SelectTxs(pending map[string]map[common.Address]types.SelfTransactions, specials map[common.Address][]types.SelfTransaction, budget TxBudget) []types.CoinSelfTransaction
+}
+
+// txBudgetTracker 记录已选交易占用的区块预算和各账户使用的gas
+type txBudgetTracker struct {
+	budget    TxBudget
+	gas       uint64
+	bytes     uint64
+	senderGas map[common.Address]uint64
+}
+
+func newTxBudgetTracker(budget TxBudget) *txBudgetTracker {
+	return &txBudgetTracker{budget: budget, senderGas: make(map[common.Address]uint64)}
+}
+
+// consume 预算足够时扣除交易占用的预算并返回true
+func (t *txBudgetTracker) consume(from common.Address, tx types.SelfTransaction) bool {
+	gas, size := tx.Gas(), uint64(tx.Size())
+	if t.budget.Gas > 0 && t.gas+gas > t.budget.Gas {
+		return false
+	}
+	if t.budget.Bytes > 0 && t.bytes+size > t.budget.Bytes {
+		return false
+	}
+	if t.budget.SenderGas > 0 && t.senderGas[from]+gas > t.budget.SenderGas {
+		return false
+	}
+	t.gas += gas
+	t.bytes += size
+	t.senderGas[from] += gas
+	return true
+}
+
+// senderTxs 单个账户nonce连续的待选交易
+type senderTxs struct {
+	from common.Address
+	txs  types.SelfTransactions
+}
+
+// senderHeads 按账户当前首笔交易的gas价格排序的堆, 价格相同时按账户地址排序, 保证选择结果确定
+type senderHeads []*senderTxs
+
+func (h senderHeads) Len() int { return len(h) }
+func (h senderHeads) Less(i, j int) bool {
+	if cmp := h[i].txs[0].GasPrice().Cmp(h[j].txs[0].GasPrice()); cmp != 0 {
+		return cmp > 0
+	}
+	return bytes.Compare(h[i].from[:], h[j].from[:]) < 0
+}
+func (h senderHeads) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *senderHeads) Push(x interface{}) { *h = append(*h, x.(*senderTxs)) }
+func (h *senderHeads) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// sequentialTxs 按nonce排序, 并截断在第一个nonce缺口处
+func sequentialTxs(txs types.SelfTransactions) types.SelfTransactions {
+	sorted := make(types.SelfTransactions, len(txs))
+	copy(sorted, txs)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Nonce() < sorted[j].Nonce() })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Nonce() != sorted[i-1].Nonce()+1 {
+			return sorted[:i]
+		}
+	}
+	return sorted
+}
+
+// sortedAddresses 按地址排序, 保证遍历顺序确定
+func sortedAddresses(addrs []common.Address) []common.Address {
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	return addrs
+}
+
+// priceNonceSelector 默认的交易选择策略:
+// 特殊交易优先, 之后按币种(MAN优先)依次选择, 同一币种内按gas价格从高到低, 同一账户的交易必须nonce连续.
+// 某个账户的交易超出预算时, 该账户后续nonce的交易都不再打包
+type priceNonceSelector struct{}
+
+func (priceNonceSelector) SelectTxs(pending map[string]map[common.Address]types.SelfTransactions, specials map[common.Address][]types.SelfTransaction, budget TxBudget) []types.CoinSelfTransaction {
+	tracker := newTxBudgetTracker(budget)
+	selected := make(map[string]types.SelfTransactions)
+
+	// 广播区块高度的特殊交易优先打包
+	senders := make([]common.Address, 0, len(specials))
+	for from := range specials {
+		senders = append(senders, from)
+	}
+	for _, from := range sortedAddresses(senders) {
+		for _, tx := range sequentialTxs(specials[from]) {
+			if !tracker.consume(from, tx) {
+				break
+			}
+			selected[tx.GetTxCurrency()] = append(selected[tx.GetTxCurrency()], tx)
+		}
+	}
+
+	coins := make([]string, 0, len(pending))
+	for coin := range pending {
+		coins = append(coins, coin)
+	}
+	sort.Slice(coins, func(i, j int) bool {
+		if coins[i] == params.MAN_COIN || coins[j] == params.MAN_COIN {
+			return coins[i] == params.MAN_COIN && coins[j] != params.MAN_COIN
+		}
+		return coins[i] < coins[j]
+	})
+	for _, coin := range coins {
+		heads := make(senderHeads, 0, len(pending[coin]))
+		for from, txs := range pending[coin] {
+			if seq := sequentialTxs(txs); len(seq) > 0 {
+				heads = append(heads, &senderTxs{from: from, txs: seq})
+			}
+		}
+		heap.Init(&heads)
+		for heads.Len() > 0 {
+			head := heads[0]
+			if !tracker.consume(head.from, head.txs[0]) {
+				heap.Pop(&heads)
+				continue
+			}
+			selected[coin] = append(selected[coin], head.txs[0])
+			if head.txs = head.txs[1:]; len(head.txs) == 0 {
+				heap.Pop(&heads)
+			} else {
+				heap.Fix(&heads, 0)
+			}
+		}
+	}
+
+	result := make([]types.CoinSelfTransaction, 0, len(selected))
+	for _, coin := range coins {
+		if txs, ok := selected[coin]; ok {
+			result = append(result, types.CoinSelfTransaction{CoinType: coin, Txser: txs})
+			delete(selected, coin)
+		}
+	}
+	// 只有特殊交易的币种
+	rest := make([]string, 0, len(selected))
+	for coin := range selected {
+		rest = append(rest, coin)
+	}
+	sort.Strings(rest)
+	for _, coin := range rest {
+		result = append(result, types.CoinSelfTransaction{CoinType: coin, Txser: selected[coin]})
+	}
+	return result
+}
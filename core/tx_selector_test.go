@@ -0,0 +1,85 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+// selectorSigner signs the transactions of the selector tests.
+var selectorSigner = types.NewEIP155Signer(big.NewInt(1))
+
+// selectorTransaction creates a signed MAN transfer with the given nonce and gas
+// price.
+func selectorTransaction(nonce uint64, gaslimit uint64, gasprice *big.Int, key *ecdsa.PrivateKey) types.SelfTransaction {
+	tx := types.NewTransaction(nonce, common.Address{}, big.NewInt(100), gaslimit, gasprice, nil, big.NewInt(0), big.NewInt(0), big.NewInt(0), 0, 0, params.MAN_COIN, 0)
+	signed, _ := types.SignTx(tx, selectorSigner, key)
+	return signed
+}
+
+// Tests that the default selector orders transactions by price across senders,
+// keeps the nonce order of every sender and stops a sender at a nonce gap.
+func TestPriceNonceSelectorOrder(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+
+	a0 := selectorTransaction(0, 21000, big.NewInt(10), keyA)
+	a1 := selectorTransaction(1, 21000, big.NewInt(30), keyA)
+	b0 := selectorTransaction(0, 21000, big.NewInt(20), keyB)
+
+	// The nonce 3 transaction of A is stuck behind the nonce gap
+	pending := map[string]map[common.Address]types.SelfTransactions{
+		params.MAN_COIN: {
+			crypto.PubkeyToAddress(keyA.PublicKey): {selectorTransaction(3, 21000, big.NewInt(50), keyA), a1, a0},
+			crypto.PubkeyToAddress(keyB.PublicKey): {b0},
+		},
+	}
+	selected := priceNonceSelector{}.SelectTxs(pending, nil, TxBudget{})
+	if len(selected) != 1 || selected[0].CoinType != params.MAN_COIN {
+		t.Fatalf("coin selection mismatch: %v", selected)
+	}
+	want := []types.SelfTransaction{b0, a0, a1}
+	if len(selected[0].Txser) != len(want) {
+		t.Fatalf("selected count mismatch: have %d, want %d", len(selected[0].Txser), len(want))
+	}
+	for i, tx := range selected[0].Txser {
+		if tx.Hash() != want[i].Hash() {
+			t.Errorf("tx %d: have %x, want %x", i, tx.Hash(), want[i].Hash())
+		}
+	}
+}
+
+// Tests that a sender exceeding the budget is dropped together with all its
+// subsequent transactions, while other senders are still selected.
+func TestPriceNonceSelectorBudget(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	addrB := crypto.PubkeyToAddress(keyB.PublicKey)
+
+	pending := map[string]map[common.Address]types.SelfTransactions{
+		params.MAN_COIN: {
+			crypto.PubkeyToAddress(keyA.PublicKey): {selectorTransaction(0, 21000, big.NewInt(30), keyA), selectorTransaction(1, 21000, big.NewInt(30), keyA)},
+			addrB:                                  {selectorTransaction(0, 21000, big.NewInt(10), keyB)},
+		},
+	}
+	selected := priceNonceSelector{}.SelectTxs(pending, nil, TxBudget{SenderGas: 30000})
+	if len(selected) != 1 || len(selected[0].Txser) != 2 {
+		t.Fatalf("selected mismatch: %v", selected)
+	}
+	if from, _ := types.Sender(selectorSigner, selected[0].Txser[1]); from != addrB {
+		t.Errorf("second transaction sender mismatch: have %x, want %x", from, addrB)
+	}
+
+	selected = priceNonceSelector{}.SelectTxs(pending, nil, TxBudget{Gas: 21000})
+	if len(selected) != 1 || len(selected[0].Txser) != 1 {
+		t.Fatalf("block gas budget mismatch: %v", selected)
+	}
+}
@@ -21,6 +21,9 @@ import (
 type Validator interface {
 	// ValidateBody validates the given block's content.
 	ValidateBody(block *types.Block) error
+	// ValidateBodyContent validates the roots of the given block's content,
+	// without reading the chain.
+	ValidateBodyContent(block *types.Block) error
 	// ValidateBody validates the given block's content.
 	ValidateHeader(header *types.Header) error
 	// ValidateState validates the given statedb and optionally the receipts and
@@ -0,0 +1,85 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package types
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+)
+
+// AggregateVote is the aggregation of the BLS votes of validators on a block.
+// The bitmap marks the voters by their position among the validators of the
+// block topology, and the signature aggregates their votes on the header hash
+// without signatures and nonce.
+//
+// A non-zero number marks the vote on the checkpoint block of that height,
+// which a later header carries to finalize the checkpoint.
+type AggregateVote struct {
+	Bitmap hexutil.Bytes  `json:"bitmap"`
+	Sign   hexutil.Bytes  `json:"sign"`
+	Number hexutil.Uint64 `json:"number,omitempty"`
+}
+
+// NewVoteBitmap creates an empty bitmap for n voters.
+func NewVoteBitmap(n int) []byte {
+	return make([]byte, (n+7)/8)
+}
+
+// SetVoter marks the voter at index i in the bitmap.
+func SetVoter(bitmap []byte, i int) {
+	bitmap[i/8] |= 1 << uint(i%8)
+}
+
+// HasVoter reports whether the voter at index i is marked in the bitmap.
+func HasVoter(bitmap []byte, i int) bool {
+	if i < 0 || i/8 >= len(bitmap) {
+		return false
+	}
+	return bitmap[i/8]&(1<<uint(i%8)) != 0
+}
+
+// Voters returns the indexes of the voters marked in the bitmap.
+func (v *AggregateVote) Voters() []int {
+	voters := make([]int, 0)
+	for i := 0; i < len(v.Bitmap)*8; i++ {
+		if HasVoter(v.Bitmap, i) {
+			voters = append(voters, i)
+		}
+	}
+	return voters
+}
+
+// Copy returns a deep copy of the vote.
+func (v AggregateVote) Copy() AggregateVote {
+	cpy := AggregateVote{
+		Bitmap: make([]byte, len(v.Bitmap)),
+		Sign:   make([]byte, len(v.Sign)),
+		Number: v.Number,
+	}
+	copy(cpy.Bitmap, v.Bitmap)
+	copy(cpy.Sign, v.Sign)
+	return cpy
+}
+
+// BlockVote returns the aggregated vote on the block of the header, nil if the
+// header carries none.
+func (h *Header) BlockVote() *AggregateVote {
+	for i := range h.AggregateVotes {
+		if h.AggregateVotes[i].Number == 0 {
+			return &h.AggregateVotes[i]
+		}
+	}
+	return nil
+}
+
+// CheckpointVote returns the aggregated vote on a previous checkpoint carried
+// by the header, nil if the header carries none.
+func (h *Header) CheckpointVote() *AggregateVote {
+	for i := range h.AggregateVotes {
+		if h.AggregateVotes[i].Number != 0 {
+			return &h.AggregateVotes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package types
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+func TestAggregateVoteBitmap(t *testing.T) {
+	bitmap := NewVoteBitmap(10)
+	if len(bitmap) != 2 {
+		t.Fatalf("bitmap size mismatch: have %d, want 2", len(bitmap))
+	}
+	SetVoter(bitmap, 0)
+	SetVoter(bitmap, 9)
+	vote := AggregateVote{Bitmap: bitmap}
+	if voters := vote.Voters(); !reflect.DeepEqual(voters, []int{0, 9}) {
+		t.Fatalf("voters mismatch: have %v, want [0 9]", voters)
+	}
+	if HasVoter(bitmap, 16) || HasVoter(bitmap, -1) {
+		t.Fatal("voter out of the bitmap reported")
+	}
+}
+
+func TestHeaderAggregateVotesRLP(t *testing.T) {
+	header := &Header{
+		Difficulty: big.NewInt(1),
+		Number:     big.NewInt(100),
+		Time:       big.NewInt(1),
+		Version:    []byte(manversion.VersionAIMine),
+	}
+	// Headers without aggregated votes keep their encoding and hash
+	plain := header.Hash()
+	signHash := header.HashNoSignsAndNonce()
+
+	header.AggregateVotes = []AggregateVote{{Bitmap: []byte{0x05}, Sign: []byte{1, 2, 3}}}
+	if header.Hash() == plain {
+		t.Fatal("aggregated votes not committed by the header hash")
+	}
+	if header.HashNoSignsAndNonce() != signHash {
+		t.Fatal("aggregated votes committed by the signed hash")
+	}
+
+	data, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(Header)
+	if err := rlp.DecodeBytes(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.AggregateVotes, header.AggregateVotes) {
+		t.Fatalf("aggregated votes mismatch: have %v, want %v", decoded.AggregateVotes, header.AggregateVotes)
+	}
+	if decoded.Hash() != header.Hash() {
+		t.Fatal("decoded header hash mismatch")
+	}
+}
+
+func TestHeaderCheckpointVote(t *testing.T) {
+	header := &Header{
+		Difficulty: big.NewInt(1),
+		Number:     big.NewInt(100),
+		Time:       big.NewInt(1),
+		Version:    []byte(manversion.VersionAIMine),
+	}
+	signHash := header.HashNoSignsAndNonce()
+
+	// The checkpoint vote is proposed with the block and committed by the signed hash
+	checkpoint := AggregateVote{Bitmap: []byte{0x07}, Sign: []byte{4, 5, 6}, Number: 90}
+	header.AggregateVotes = []AggregateVote{checkpoint}
+	if header.BlockVote() != nil {
+		t.Fatal("checkpoint vote reported as the block vote")
+	}
+	if vote := header.CheckpointVote(); vote == nil || !reflect.DeepEqual(*vote, checkpoint) {
+		t.Fatalf("checkpoint vote mismatch: have %v, want %v", vote, checkpoint)
+	}
+	proposed := header.HashNoSignsAndNonce()
+	if proposed == signHash {
+		t.Fatal("checkpoint vote not committed by the signed hash")
+	}
+
+	// Aggregating the block vote afterwards keeps the signed hash
+	header.AggregateVotes = append([]AggregateVote{{Bitmap: []byte{0x05}, Sign: []byte{1, 2, 3}}}, header.AggregateVotes...)
+	if header.HashNoSignsAndNonce() != proposed {
+		t.Fatal("block vote committed by the signed hash")
+	}
+	if vote := header.BlockVote(); vote == nil || vote.Number != 0 {
+		t.Fatalf("block vote mismatch: have %v", vote)
+	}
+	if vote := header.CheckpointVote(); vote == nil || vote.Number != 90 {
+		t.Fatalf("checkpoint vote mismatch: have %v", vote)
+	}
+}
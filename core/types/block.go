@@ -632,6 +632,12 @@ func CopyHeader(h *Header) *Header {
 		cpy.BasePowers = make([]BasePowers, len(h.BasePowers))
 		copy(cpy.BasePowers, h.BasePowers)
 	}
+	if len(h.AggregateVotes) > 0 {
+		cpy.AggregateVotes = make([]AggregateVote, len(h.AggregateVotes))
+		for i, vote := range h.AggregateVotes {
+			cpy.AggregateVotes[i] = vote.Copy()
+		}
+	}
 	return &cpy
 }
 
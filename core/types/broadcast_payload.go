@@ -0,0 +1,69 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// BroadcastPayloadRLP is the version byte prefixing RLP encoded broadcast
+// transaction payloads. Payloads without it are legacy JSON maps, which always
+// start with '{'.
+const BroadcastPayloadRLP byte = 0x01
+
+var ErrEmptyBroadcastPayload = errors.New("empty broadcast payload")
+
+// broadcastPayloadEntry is a single key/value pair of an RLP encoded broadcast
+// payload. Maps are not supported by RLP, so the entries are sorted by key.
+type broadcastPayloadEntry struct {
+	Key   string
+	Value []byte
+}
+
+// EncodeBroadcastPayload encodes the data of a broadcast transaction in the
+// versioned RLP format.
+func EncodeBroadcastPayload(data map[string][]byte) ([]byte, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]broadcastPayloadEntry, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, broadcastPayloadEntry{Key: key, Value: data[key]})
+	}
+	blob, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{BroadcastPayloadRLP}, blob...), nil
+}
+
+// DecodeBroadcastPayload decodes the data of a broadcast transaction, falling
+// back to JSON for payloads without a version byte.
+func DecodeBroadcastPayload(payload []byte) (map[string][]byte, error) {
+	if len(payload) == 0 {
+		return nil, ErrEmptyBroadcastPayload
+	}
+	data := make(map[string][]byte)
+	if payload[0] != BroadcastPayloadRLP {
+		if err := json.Unmarshal(payload, &data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	var entries []broadcastPayloadEntry
+	if err := rlp.DecodeBytes(payload[1:], &entries); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		data[entry.Key] = entry.Value
+	}
+	return data, nil
+}
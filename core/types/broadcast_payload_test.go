@@ -0,0 +1,52 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestBroadcastPayloadEncoding(t *testing.T) {
+	data := map[string][]byte{
+		"Heartbeat12": []byte{0x01, 0x02},
+		"SeedProof12": []byte("proof"),
+	}
+	// RLP encoded payloads must round trip
+	blob, err := EncodeBroadcastPayload(data)
+	if err != nil {
+		t.Fatalf("failed to encode payload: %v", err)
+	}
+	if blob[0] != BroadcastPayloadRLP {
+		t.Fatalf("version byte mismatch: have %#x, want %#x", blob[0], BroadcastPayloadRLP)
+	}
+	decoded, err := DecodeBroadcastPayload(blob)
+	if err != nil {
+		t.Fatalf("failed to decode RLP payload: %v", err)
+	}
+	checkBroadcastPayload(t, decoded, data)
+
+	// Legacy JSON payloads must still be accepted
+	legacy, _ := json.Marshal(data)
+	if decoded, err = DecodeBroadcastPayload(legacy); err != nil {
+		t.Fatalf("failed to decode JSON payload: %v", err)
+	}
+	checkBroadcastPayload(t, decoded, data)
+
+	if _, err := DecodeBroadcastPayload(nil); err != ErrEmptyBroadcastPayload {
+		t.Errorf("empty payload error mismatch: have %v, want %v", err, ErrEmptyBroadcastPayload)
+	}
+}
+
+func checkBroadcastPayload(t *testing.T, have, want map[string][]byte) {
+	if len(have) != len(want) {
+		t.Fatalf("entry count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for key, val := range want {
+		if !bytes.Equal(have[key], val) {
+			t.Errorf("entry %s mismatch: have %x, want %x", key, have[key], val)
+		}
+	}
+}
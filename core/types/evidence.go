@@ -0,0 +1,112 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package types
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+var (
+	ErrEvidenceHeaderNil      = errors.New("evidence header is nil")
+	ErrEvidenceHeightMismatch = errors.New("evidence headers are not at the same height")
+	ErrEvidenceLeaderMismatch = errors.New("evidence headers are not proposed by the same leader")
+	ErrEvidenceSameHeader     = errors.New("evidence headers are identical")
+	ErrEvidenceSignerMismatch = errors.New("evidence votes are not signed by the same account")
+	ErrEvidenceNotAgree       = errors.New("evidence vote is not an agreement")
+	ErrEvidenceParentMismatch = errors.New("evidence headers do not extend the given parent")
+)
+
+// DoubleSignEvidence proves that a validator voted for two different blocks
+// proposed by the same leader on the same parent. The parent allows to check
+// that both votes fall in the same consensus turn, as a validator may vote for
+// a new block of the same leader in a later turn.
+type DoubleSignEvidence struct {
+	Parent  *Header
+	HeaderA *Header
+	HeaderB *Header
+	SignA   common.Signature // Vote of the offender on HeaderA
+	SignB   common.Signature // Vote of the offender on HeaderB
+}
+
+// Number returns the height of the conflicting votes.
+func (ev *DoubleSignEvidence) Number() uint64 {
+	if ev.HeaderA == nil || ev.HeaderA.Number == nil {
+		return 0
+	}
+	return ev.HeaderA.Number.Uint64()
+}
+
+// Hash identifies the evidence independently of the order of the votes.
+func (ev *DoubleSignEvidence) Hash() common.Hash {
+	hashA, hashB := ev.HeaderA.HashNoSignsAndNonce(), ev.HeaderB.HashNoSignsAndNonce()
+	signA, signB := ev.SignA, ev.SignB
+	if bytes.Compare(hashA.Bytes(), hashB.Bytes()) > 0 {
+		hashA, hashB = hashB, hashA
+		signA, signB = signB, signA
+	}
+	return RlpHash([]interface{}{hashA, hashB, signA, signB})
+}
+
+// Verify checks that the evidence is a genuine double sign and returns the
+// account which signed both votes. The account is the signing account of the
+// validator, not its deposit account.
+func (ev *DoubleSignEvidence) Verify() (common.Address, error) {
+	if ev.HeaderA == nil || ev.HeaderB == nil || ev.HeaderA.Number == nil || ev.HeaderB.Number == nil {
+		return common.Address{}, ErrEvidenceHeaderNil
+	}
+	if ev.HeaderA.Number.Cmp(ev.HeaderB.Number) != 0 {
+		return common.Address{}, ErrEvidenceHeightMismatch
+	}
+	if ev.HeaderA.Leader != ev.HeaderB.Leader {
+		return common.Address{}, ErrEvidenceLeaderMismatch
+	}
+	hashA, hashB := ev.HeaderA.HashNoSignsAndNonce(), ev.HeaderB.HashNoSignsAndNonce()
+	if hashA == hashB {
+		return common.Address{}, ErrEvidenceSameHeader
+	}
+	if ev.Parent == nil || ev.Parent.Number == nil {
+		return common.Address{}, ErrEvidenceHeaderNil
+	}
+	if parentHash := ev.Parent.Hash(); ev.HeaderA.ParentHash != parentHash || ev.HeaderB.ParentHash != parentHash {
+		return common.Address{}, ErrEvidenceParentMismatch
+	}
+
+	signerA, validateA, err := crypto.VerifySignWithValidate(hashA.Bytes(), ev.SignA.Bytes())
+	if err != nil {
+		return common.Address{}, err
+	}
+	signerB, validateB, err := crypto.VerifySignWithValidate(hashB.Bytes(), ev.SignB.Bytes())
+	if err != nil {
+		return common.Address{}, err
+	}
+	// 反对票不构成双签
+	if !validateA || !validateB {
+		return common.Address{}, ErrEvidenceNotAgree
+	}
+	if signerA != signerB {
+		return common.Address{}, ErrEvidenceSignerMismatch
+	}
+	return signerA, nil
+}
+
+// EncodeDoubleSignEvidence encodes the evidence as the payload of an evidence
+// transaction.
+func EncodeDoubleSignEvidence(ev *DoubleSignEvidence) ([]byte, error) {
+	return rlp.EncodeToBytes(ev)
+}
+
+// DecodeDoubleSignEvidence decodes the payload of an evidence transaction.
+func DecodeDoubleSignEvidence(data []byte) (*DoubleSignEvidence, error) {
+	ev := new(DoubleSignEvidence)
+	if err := rlp.DecodeBytes(data, ev); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
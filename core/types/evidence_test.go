@@ -0,0 +1,62 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+func evidenceHeader(number int64, leader common.Address, time int64) *Header {
+	return &Header{Number: big.NewInt(number), Leader: leader, Time: big.NewInt(time), Difficulty: big.NewInt(1)}
+}
+
+func TestDoubleSignEvidenceVerifyHeaders(t *testing.T) {
+	leader := common.HexToAddress("0x01")
+	tests := []struct {
+		ev   *DoubleSignEvidence
+		want error
+	}{
+		{&DoubleSignEvidence{HeaderA: evidenceHeader(10, leader, 1)}, ErrEvidenceHeaderNil},
+		{&DoubleSignEvidence{HeaderA: evidenceHeader(10, leader, 1), HeaderB: evidenceHeader(11, leader, 2)}, ErrEvidenceHeightMismatch},
+		{&DoubleSignEvidence{HeaderA: evidenceHeader(10, leader, 1), HeaderB: evidenceHeader(10, common.HexToAddress("0x02"), 2)}, ErrEvidenceLeaderMismatch},
+		{&DoubleSignEvidence{HeaderA: evidenceHeader(10, leader, 1), HeaderB: evidenceHeader(10, leader, 1)}, ErrEvidenceSameHeader},
+		{&DoubleSignEvidence{HeaderA: evidenceHeader(10, leader, 1), HeaderB: evidenceHeader(10, leader, 2)}, ErrEvidenceHeaderNil},
+		{&DoubleSignEvidence{Parent: evidenceHeader(9, leader, 0), HeaderA: evidenceHeader(10, leader, 1), HeaderB: evidenceHeader(10, leader, 2)}, ErrEvidenceParentMismatch},
+	}
+	for i, tt := range tests {
+		if _, err := tt.ev.Verify(); err != tt.want {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+}
+
+func TestDoubleSignEvidenceHashOrder(t *testing.T) {
+	leader := common.HexToAddress("0x01")
+	headerA, headerB := evidenceHeader(10, leader, 1), evidenceHeader(10, leader, 2)
+	signA, signB := common.Signature{0x01}, common.Signature{0x02}
+
+	ev := &DoubleSignEvidence{HeaderA: headerA, HeaderB: headerB, SignA: signA, SignB: signB}
+	swapped := &DoubleSignEvidence{HeaderA: headerB, HeaderB: headerA, SignA: signB, SignB: signA}
+	if ev.Hash() != swapped.Hash() {
+		t.Errorf("evidence hash depends on the vote order")
+	}
+	if ev.Number() != 10 {
+		t.Errorf("evidence number mismatch: have %d, want 10", ev.Number())
+	}
+
+	blob, err := EncodeDoubleSignEvidence(ev)
+	if err != nil {
+		t.Fatalf("failed to encode evidence: %v", err)
+	}
+	decoded, err := DecodeDoubleSignEvidence(blob)
+	if err != nil {
+		t.Fatalf("failed to decode evidence: %v", err)
+	}
+	if decoded.Hash() != ev.Hash() {
+		t.Errorf("evidence hash mismatch after round trip")
+	}
+}
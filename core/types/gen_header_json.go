@@ -46,6 +46,7 @@ func (h Header) MarshalJSON() ([]byte, error) {
 			AICoinbase        common.Address     `json:"aiMiner"            gencodec:"required"`
 			Sm3Nonce          BlockNonce         `json:"sm3Nonce"           gencodec:"required"`
 			BasePowers        []BasePowers       `json:"basePowers"         gencodec:"required"`
+			AggregateVotes    []AggregateVote    `json:"aggregateVotes,omitempty"`
 
 			Hash common.Hash `json:"hash"`
 		}
@@ -74,6 +75,7 @@ func (h Header) MarshalJSON() ([]byte, error) {
 		enc.AIHash = h.AIHash
 		enc.AICoinbase = h.AICoinbase
 		enc.BasePowers = h.BasePowers
+		enc.AggregateVotes = h.AggregateVotes
 
 		enc.Hash = h.Hash()
 		return json.Marshal(&enc)
@@ -157,6 +159,7 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		AICoinbase        *common.Address     `json:"aiMiner"            gencodec:"required"`
 		Sm3Nonce          *BlockNonce         `json:"sm3Nonce"           gencodec:"required"`
 		BasePowers        *[]BasePowers       `json:"basePowers"         gencodec:"required"`
+		AggregateVotes    []AggregateVote     `json:"aggregateVotes,omitempty"`
 	}
 	var dec Header
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -273,6 +276,7 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 			return errors.New("missing required field 'basePowers' for Header")
 		}
 		h.BasePowers = *dec.BasePowers
+		h.AggregateVotes = dec.AggregateVotes
 	} else {
 		h.AIHash = common.Hash{}
 		h.AICoinbase = common.Address{}
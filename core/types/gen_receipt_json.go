@@ -18,14 +18,15 @@ var _ = (*receiptMarshaling)(nil)
 // MarshalJSON marshals as JSON.
 func (r Receipt) MarshalJSON() ([]byte, error) {
 	type Receipt struct {
-		PostState         hexutil.Bytes  `json:"root"`
-		Status            hexutil.Uint64 `json:"status"`
-		CumulativeGasUsed hexutil.Uint64 `json:"cumulativeGasUsed" gencodec:"required"`
-		Bloom             Bloom          `json:"logsBloom"         gencodec:"required"`
-		Logs              []*Log         `json:"logs"              gencodec:"required"`
-		TxHash            common.Hash    `json:"transactionHash" gencodec:"required"`
-		ContractAddress   common.Address `json:"contractAddress"`
-		GasUsed           hexutil.Uint64 `json:"gasUsed" gencodec:"required"`
+		PostState         hexutil.Bytes      `json:"root"`
+		Status            hexutil.Uint64     `json:"status"`
+		CumulativeGasUsed hexutil.Uint64     `json:"cumulativeGasUsed" gencodec:"required"`
+		Bloom             Bloom              `json:"logsBloom"         gencodec:"required"`
+		Logs              []*Log             `json:"logs"              gencodec:"required"`
+		Matrix            *ReceiptMatrixInfo `json:"matrix,omitempty"`
+		TxHash            common.Hash        `json:"transactionHash" gencodec:"required"`
+		ContractAddress   common.Address     `json:"contractAddress"`
+		GasUsed           hexutil.Uint64     `json:"gasUsed" gencodec:"required"`
 	}
 	var enc Receipt
 	enc.PostState = r.PostState
@@ -33,6 +34,7 @@ func (r Receipt) MarshalJSON() ([]byte, error) {
 	enc.CumulativeGasUsed = hexutil.Uint64(r.CumulativeGasUsed)
 	enc.Bloom = r.Bloom
 	enc.Logs = r.Logs
+	enc.Matrix = r.Matrix
 	enc.TxHash = r.TxHash
 	enc.ContractAddress = r.ContractAddress
 	enc.GasUsed = hexutil.Uint64(r.GasUsed)
@@ -42,14 +44,15 @@ func (r Receipt) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON unmarshals from JSON.
 func (r *Receipt) UnmarshalJSON(input []byte) error {
 	type Receipt struct {
-		PostState         *hexutil.Bytes  `json:"root"`
-		Status            *hexutil.Uint64 `json:"status"`
-		CumulativeGasUsed *hexutil.Uint64 `json:"cumulativeGasUsed" gencodec:"required"`
-		Bloom             *Bloom          `json:"logsBloom"         gencodec:"required"`
-		Logs              []*Log          `json:"logs"              gencodec:"required"`
-		TxHash            *common.Hash    `json:"transactionHash" gencodec:"required"`
-		ContractAddress   *common.Address `json:"contractAddress"`
-		GasUsed           *hexutil.Uint64 `json:"gasUsed" gencodec:"required"`
+		PostState         *hexutil.Bytes     `json:"root"`
+		Status            *hexutil.Uint64    `json:"status"`
+		CumulativeGasUsed *hexutil.Uint64    `json:"cumulativeGasUsed" gencodec:"required"`
+		Bloom             *Bloom             `json:"logsBloom"         gencodec:"required"`
+		Logs              []*Log             `json:"logs"              gencodec:"required"`
+		Matrix            *ReceiptMatrixInfo `json:"matrix,omitempty"`
+		TxHash            *common.Hash       `json:"transactionHash" gencodec:"required"`
+		ContractAddress   *common.Address    `json:"contractAddress"`
+		GasUsed           *hexutil.Uint64    `json:"gasUsed" gencodec:"required"`
 	}
 	var dec Receipt
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -73,6 +76,9 @@ func (r *Receipt) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'logs' for Receipt")
 	}
 	r.Logs = dec.Logs
+	if dec.Matrix != nil {
+		r.Matrix = dec.Matrix
+	}
 	if dec.TxHash == nil {
 		return errors.New("missing required field 'transactionHash' for Receipt")
 	}
@@ -47,6 +47,7 @@ type Header struct {
 	VrfValue          []byte             `json:"vrfvalue"          gencodec:"required"`
 	Sm3Nonce          BlockNonce         `json:"sm3Nonce"          gencodec:"required"`
 	BasePowers        []BasePowers       `json:"basePowers"        gencodec:"required"`
+	AggregateVotes    []AggregateVote    `json:"aggregateVotes"    rlp:"tail"`
 }
 
 type BasePowers struct {
@@ -75,7 +76,7 @@ func (h *Header) Hash() common.Hash {
 // HashNoNonce returns the hash which is used as input for the proof-of-work search.
 func (h *Header) HashNoNonce() common.Hash {
 	if manversion.VersionCmp(string(h.Version), manversion.VersionAIMine) >= 0 {
-		return rlpHash([]interface{}{
+		fields := []interface{}{
 			h.ParentHash,
 			h.UncleHash,
 			h.Leader,
@@ -93,7 +94,13 @@ func (h *Header) HashNoNonce() common.Hash {
 			h.Version,
 			h.VersionSignatures,
 			h.VrfValue,
-		})
+		}
+		// The aggregated votes are sealed along with the signatures, headers
+		// without them keep their hash
+		if len(h.AggregateVotes) > 0 {
+			fields = append(fields, h.AggregateVotes)
+		}
+		return rlpHash(fields)
 	} else {
 		return rlpHash([]interface{}{
 			h.ParentHash,
@@ -169,7 +176,7 @@ func (h *Header) HashNoSigns() common.Hash {
 
 func (h *Header) HashNoSignsAndNonce() common.Hash {
 	if manversion.VersionCmp(string(h.Version), manversion.VersionAIMine) >= 0 {
-		return rlpHash([]interface{}{
+		fields := []interface{}{
 			h.ParentHash,
 			h.UncleHash,
 			h.Leader,
@@ -188,7 +195,13 @@ func (h *Header) HashNoSignsAndNonce() common.Hash {
 			h.VrfValue,
 			h.AIHash,
 			h.AICoinbase,
-		})
+		}
+		// The checkpoint vote is proposed along with the block and signed by
+		// the validators, unlike the vote aggregated on the block itself
+		if vote := h.CheckpointVote(); vote != nil {
+			fields = append(fields, vote)
+		}
+		return rlpHash(fields)
 	} else {
 		return rlpHash([]interface{}{
 			h.ParentHash,
@@ -293,6 +306,7 @@ func (h *Header) EncodeRLP(w io.Writer) error {
 			VrfValue:          h.VrfValue,
 			Sm3Nonce:          h.Sm3Nonce,
 			BasePowers:        h.BasePowers,
+			AggregateVotes:    h.AggregateVotes,
 		}
 		return rlp.Encode(w, &sh)
 	} else {
@@ -39,12 +39,22 @@ type Receipt struct {
 	Bloom             Bloom  `json:"logsBloom"         gencodec:"required"`
 	Logs              []*Log `json:"logs"              gencodec:"required"`
 
+	// Matrix specific outcome, recorded from the receipt metadata fork on
+	Matrix *ReceiptMatrixInfo `json:"matrix,omitempty"`
+
 	// Implementation fields (don't reorder!)
 	TxHash          common.Hash    `json:"transactionHash" gencodec:"required"`
 	ContractAddress common.Address `json:"contractAddress"`
 	GasUsed         uint64         `json:"gasUsed" gencodec:"required"`
 }
 
+// ReceiptMatrixInfo is the Matrix specific outcome of a transaction.
+type ReceiptMatrixInfo struct {
+	TxType        byte     `json:"txType"`        // TxType of the Matrix_EX extension
+	BroadcastType string   `json:"broadcastType"` // Categories of a broadcast transaction, empty otherwise
+	ExtraStatus   []uint64 `json:"extraStatus"`   // Execution status of each extra transfer
+}
+
 type receiptMarshaling struct {
 	PostState         hexutil.Bytes
 	Status            hexutil.Uint64
@@ -58,6 +68,7 @@ type receiptRLP struct {
 	CumulativeGasUsed uint64
 	Bloom             Bloom
 	Logs              []*Log
+	Matrix            []ReceiptMatrixInfo `rlp:"tail"` // Empty before the receipt metadata fork
 }
 
 type receiptStorageRLP struct {
@@ -68,6 +79,7 @@ type receiptStorageRLP struct {
 	ContractAddress   common.Address
 	Logs              []*LogForStorage
 	GasUsed           uint64
+	Matrix            []ReceiptMatrixInfo `rlp:"tail"`
 }
 
 // NewReceipt creates a barebone transaction receipt, copying the init fields.
@@ -84,7 +96,7 @@ func NewReceipt(root []byte, failed bool, cumulativeGasUsed uint64) *Receipt {
 // EncodeRLP implements rlp.Encoder, and flattens the consensus fields of a receipt
 // into an RLP stream. If no post state is present, byzantium fork is assumed.
 func (r *Receipt) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs})
+	return rlp.Encode(w, &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs, r.matrixEncoding()})
 }
 
 // DecodeRLP implements rlp.Decoder, and loads the consensus fields of a receipt
@@ -98,7 +110,7 @@ func (r *Receipt) DecodeRLP(s *rlp.Stream) error {
 		return err
 	}
 	r.CumulativeGasUsed, r.Bloom, r.Logs = dec.CumulativeGasUsed, dec.Bloom, dec.Logs
-	return nil
+	return r.setMatrix(dec.Matrix)
 }
 func (r *Receipt) setStatus(postStateOrStatus []byte) error {
 	switch {
@@ -114,6 +126,27 @@ func (r *Receipt) setStatus(postStateOrStatus []byte) error {
 	return nil
 }
 
+// matrixEncoding returns the optional Matrix outcome as the RLP tail, so that
+// the receipts without it keep their original encoding.
+func (r *Receipt) matrixEncoding() []ReceiptMatrixInfo {
+	if r.Matrix == nil {
+		return nil
+	}
+	return []ReceiptMatrixInfo{*r.Matrix}
+}
+
+func (r *Receipt) setMatrix(tail []ReceiptMatrixInfo) error {
+	switch len(tail) {
+	case 0:
+		r.Matrix = nil
+	case 1:
+		r.Matrix = &tail[0]
+	default:
+		return fmt.Errorf("invalid receipt matrix info count %d", len(tail))
+	}
+	return nil
+}
+
 func (r *Receipt) statusEncoding() []byte {
 	if len(r.PostState) == 0 {
 		if r.Status == ReceiptStatusFailed {
@@ -133,6 +166,9 @@ func (r *Receipt) Size() common.StorageSize {
 	for _, log := range r.Logs {
 		size += common.StorageSize(len(log.Topics)*common.HashLength + len(log.Data))
 	}
+	if r.Matrix != nil {
+		size += common.StorageSize(unsafe.Sizeof(*r.Matrix)) + common.StorageSize(len(r.Matrix.BroadcastType)+8*len(r.Matrix.ExtraStatus))
+	}
 	return size
 }
 func (r *Receipt) Hash() common.Hash {
@@ -159,6 +195,7 @@ func (r *ReceiptForStorage) EncodeRLP(w io.Writer) error {
 		ContractAddress:   r.ContractAddress,
 		Logs:              make([]*LogForStorage, len(r.Logs)),
 		GasUsed:           r.GasUsed,
+		Matrix:            (*Receipt)(r).matrixEncoding(),
 	}
 	for i, log := range r.Logs {
 		enc.Logs[i] = (*LogForStorage)(log)
@@ -184,7 +221,7 @@ func (r *ReceiptForStorage) DecodeRLP(s *rlp.Stream) error {
 	}
 	// Assign the implementation fields
 	r.TxHash, r.ContractAddress, r.GasUsed = dec.TxHash, dec.ContractAddress, dec.GasUsed
-	return nil
+	return (*Receipt)(r).setMatrix(dec.Matrix)
 }
 
 type CoinReceipts struct {
@@ -0,0 +1,61 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package types
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+func TestReceiptMatrixInfoEncoding(t *testing.T) {
+	legacy := NewReceipt(nil, false, 21000)
+	legacy.Logs = []*Log{}
+
+	// Receipts without the Matrix info keep the original consensus encoding
+	blob, err := rlp.EncodeToBytes(legacy)
+	if err != nil {
+		t.Fatalf("failed to encode receipt: %v", err)
+	}
+	original, _ := rlp.EncodeToBytes(&receiptRLP{legacy.statusEncoding(), legacy.CumulativeGasUsed, legacy.Bloom, legacy.Logs, nil})
+	if !bytes.Equal(blob, original) {
+		t.Fatalf("legacy encoding changed: have %x, want %x", blob, original)
+	}
+
+	withInfo := NewReceipt(nil, true, 42000)
+	withInfo.Logs = []*Log{}
+	withInfo.Matrix = &ReceiptMatrixInfo{TxType: 1, BroadcastType: "heartbeat", ExtraStatus: []uint64{1, 0}}
+	if legacy.Hash() == withInfo.Hash() {
+		t.Fatalf("matrix info not part of the receipt hash")
+	}
+
+	for i, receipt := range []*Receipt{legacy, withInfo} {
+		receipt.TxHash = common.HexToHash("0x01")
+		blob, err := rlp.EncodeToBytes((*ReceiptForStorage)(receipt))
+		if err != nil {
+			t.Fatalf("receipt %d: failed to encode for storage: %v", i, err)
+		}
+		dec := new(ReceiptForStorage)
+		if err := rlp.DecodeBytes(blob, dec); err != nil {
+			t.Fatalf("receipt %d: failed to decode from storage: %v", i, err)
+		}
+		if !reflect.DeepEqual(dec.Matrix, receipt.Matrix) || dec.Status != receipt.Status || dec.TxHash != receipt.TxHash {
+			t.Errorf("receipt %d: storage round trip mismatch: have %+v, want %+v", i, dec, receipt)
+		}
+
+		if blob, err = rlp.EncodeToBytes(receipt); err != nil {
+			t.Fatalf("receipt %d: failed to encode: %v", i, err)
+		}
+		cons := new(Receipt)
+		if err := rlp.DecodeBytes(blob, cons); err != nil {
+			t.Fatalf("receipt %d: failed to decode: %v", i, err)
+		}
+		if !reflect.DeepEqual(cons.Matrix, receipt.Matrix) {
+			t.Errorf("receipt %d: matrix info mismatch: have %+v, want %+v", i, cons.Matrix, receipt.Matrix)
+		}
+	}
+}
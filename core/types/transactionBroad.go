@@ -4,6 +4,7 @@
 package types
 
 import (
+	"encoding/binary"
 	"errors"
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/params"
@@ -40,8 +41,14 @@ func (tx *TransactionBroad) DecodeRLP(s *rlp.Stream) error {
 	}
 	return err
 }
+// GetTxN 广播交易不经过泛洪分配N, 在区块的TxsCode N列表中以交易哈希的前4字节作为其N
 func (tx *TransactionBroad) GetTxN(index int) uint32 {
-	return 0
+	return BroadcastTxN(tx.Hash())
+}
+
+// BroadcastTxN 哈希为hash的广播交易在TxsCode N列表中的N
+func BroadcastTxN(hash common.Hash) uint32 {
+	return binary.BigEndian.Uint32(hash[:4])
 }
 
 // 广播交易
@@ -161,7 +168,7 @@ func (tx *TransactionBroad) GetTxR() *big.Int { return tx.data.R }
 //
 func (tx *TransactionBroad) GetTxS() *big.Int { return tx.data.S }
 func (tx *TransactionBroad) GetTxNLen() int {
-	return 0
+	return 1
 }
 
 // 在传递交易时用来操作Nonce
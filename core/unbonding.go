@@ -0,0 +1,133 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+)
+
+var ErrDepositUnbonding = errors.New("deposit is still unbonding")
+
+// AddUnbonding 退出抵押时将仓位加入解绑队列, 在配置的广播周期数内不能退还抵押
+func AddUnbonding(db vm.StateDBManager, account common.Address, position uint64, amount *big.Int, number uint64) error {
+	if manversion.VersionCmp(matrixstate.GetVersionInfo(db), manversion.VersionAIMine) < 0 {
+		return nil
+	}
+	cfg, err := matrixstate.GetUnbondingCfg(db)
+	if err != nil {
+		return err
+	}
+	if cfg.Intervals == 0 {
+		return nil
+	}
+	bcInterval, err := matrixstate.GetBroadcastInterval(db)
+	if err != nil {
+		return err
+	}
+	queue, err := matrixstate.GetUnbondingQueue(db)
+	if err != nil {
+		return err
+	}
+	queue = pruneUnbondingQueue(queue, number)
+
+	entry := mc.UnbondingEntry{
+		Account:       account,
+		Position:      position,
+		Amount:        new(big.Int).Set(amount),
+		RequestNumber: number,
+		ReleaseNumber: number + cfg.Intervals*bcInterval.GetBroadcastInterval(),
+	}
+	queue.Entries = append(queue.Entries, entry)
+	log.Info(ModuleName, "退出抵押进入解绑期", account.Hex(), "仓位", position, "金额", amount, "解绑高度", entry.ReleaseNumber)
+	return matrixstate.SetUnbondingQueue(db, queue)
+}
+
+// CheckUnbonding 仓位仍有未到期的退出请求时不能退还抵押
+func CheckUnbonding(db vm.StateDBManager, account common.Address, position uint64, number uint64) error {
+	if manversion.VersionCmp(matrixstate.GetVersionInfo(db), manversion.VersionAIMine) < 0 {
+		return nil
+	}
+	queue, err := matrixstate.GetUnbondingQueue(db)
+	if err != nil {
+		return err
+	}
+	for _, entry := range queue.Entries {
+		if entry.Account == account && entry.Position == position && entry.ReleaseNumber > number {
+			return ErrDepositUnbonding
+		}
+	}
+	return nil
+}
+
+// GetUnbondingEntries 获取账户在当前高度仍处于解绑期的退出请求
+func GetUnbondingEntries(db vm.StateDBManager, account common.Address, number uint64) ([]mc.UnbondingEntry, error) {
+	queue, err := matrixstate.GetUnbondingQueue(db)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]mc.UnbondingEntry, 0)
+	for _, entry := range pruneUnbondingQueue(queue, number).Entries {
+		if entry.Account == account {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// GetUnbondingPositions 获取在当前高度仍处于解绑期的账户仓位
+func GetUnbondingPositions(db vm.StateDBManager, number uint64) (map[common.Address]map[uint64]bool, error) {
+	queue, err := matrixstate.GetUnbondingQueue(db)
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[common.Address]map[uint64]bool)
+	for _, entry := range pruneUnbondingQueue(queue, number).Entries {
+		if positions[entry.Account] == nil {
+			positions[entry.Account] = make(map[uint64]bool)
+		}
+		positions[entry.Account][entry.Position] = true
+	}
+	return positions, nil
+}
+
+// ElectableDeposit 扣除解绑期内仓位剩余的抵押金额, 得到账户参与选举的抵押金额
+func ElectableDeposit(db vm.StateDBManager, account common.Address, deposit *big.Int, positions map[uint64]bool) *big.Int {
+	result := new(big.Int).Set(deposit)
+	base := depoistInfo.GetDepositBase(db, account)
+	if base == nil {
+		return result
+	}
+	for _, msg := range base.Dpstmsg {
+		if positions[msg.Position] && msg.DepositAmount != nil {
+			result.Sub(result, msg.DepositAmount)
+		}
+	}
+	if result.Sign() < 0 {
+		result.SetUint64(0)
+	}
+	return result
+}
+
+// pruneUnbondingQueue 移除已解绑完成的退出请求
+func pruneUnbondingQueue(queue *mc.UnbondingQueue, number uint64) *mc.UnbondingQueue {
+	result := &mc.UnbondingQueue{Entries: make([]mc.UnbondingEntry, 0)}
+	if nil == queue {
+		return result
+	}
+	for _, entry := range queue.Entries {
+		if entry.ReleaseNumber > number {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	return result
+}
@@ -0,0 +1,32 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func Test_pruneUnbondingQueue(t *testing.T) {
+	addrA := common.HexToAddress("0x01")
+	addrB := common.HexToAddress("0x02")
+
+	queue := &mc.UnbondingQueue{Entries: []mc.UnbondingEntry{
+		{Account: addrA, Position: 0, Amount: big.NewInt(1), RequestNumber: 10, ReleaseNumber: 110},
+		{Account: addrB, Position: 1, Amount: big.NewInt(2), RequestNumber: 50, ReleaseNumber: 150},
+	}}
+	if result := pruneUnbondingQueue(queue, 109); len(result.Entries) != 2 {
+		t.Fatalf("解绑期内的请求不应移除 %v", result.Entries)
+	}
+	result := pruneUnbondingQueue(queue, 110)
+	if len(result.Entries) != 1 || result.Entries[0].Account != addrB {
+		t.Fatalf("解绑完成的请求未移除 %v", result.Entries)
+	}
+	if empty := pruneUnbondingQueue(nil, 0); len(empty.Entries) != 0 {
+		t.Errorf("空队列移除错误 %v", empty.Entries)
+	}
+}
@@ -139,17 +139,22 @@ func (bc *BlockChain) getElectMap(parentHash common.Hash, bcInterval *mc.BCInter
 	return originValidatorMap, originMinerMap, nil
 }
 
+// IsHeartbeatTurn reports whether an account has to send a heartbeat in the
+// broadcast interval whose heartbeats are checked against the state roots of
+// the broadcast block preceding it.
+func IsHeartbeatTurn(seedRoots []common.CoinRoot, account common.Address, broadcastInterval uint64) bool {
+	modulus := big.NewInt(int64(broadcastInterval) - 1)
+	val := new(big.Int).Rem(types.RlpHash(seedRoots).Big(), modulus)
+	return new(big.Int).Rem(account.Big(), modulus).Cmp(val) == 0
+}
+
 func (bc *BlockChain) getHeatBeatAccount(beforeLastStateRoot []common.CoinRoot, bcInterval *mc.BCIntervalInfo, blockNum uint64, accounts []common.Address, heatBeatAccounts map[common.Address][]byte) map[common.Address]bool {
 	HeatBeatReqAccounts := make([]common.Address, 0)
 	HeartBeatMap := make(map[common.Address]bool, 0)
 	//subVal就是最新的广播区块，例如当前区块高度是198或者是101，那么subVal就是100
 
-	broadcastBlock := types.RlpHash(beforeLastStateRoot).Big()
-	val := new(big.Int).Rem(broadcastBlock, big.NewInt(int64(bcInterval.GetBroadcastInterval())-1))
 	for _, v := range accounts {
-		currentAcc := v.Big()
-		ret := new(big.Int).Rem(currentAcc, big.NewInt(int64(bcInterval.GetBroadcastInterval())-1))
-		if ret.Cmp(val) == 0 {
+		if IsHeartbeatTurn(beforeLastStateRoot, v, bcInterval.GetBroadcastInterval()) {
 			HeatBeatReqAccounts = append(HeatBeatReqAccounts, v)
 			if _, ok := heatBeatAccounts[v]; ok {
 				HeartBeatMap[v] = true
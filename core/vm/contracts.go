@@ -38,6 +38,8 @@ var PrecompiledContractsByzantium = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{7}):  &bn256ScalarMul{},
 	common.BytesToAddress([]byte{8}):  &bn256Pairing{},
 	common.BytesToAddress([]byte{10}): &MatrixDepositVersion{},
+	common.BytesToAddress([]byte{11}): &electedSet{},
+	common.BytesToAddress([]byte{12}): &matrixSchedule{},
 //	ValidatorGroupContractAddress:  NewValidatorGroupContract(),
 }
 func getPrecompiledContract(preCompiledMap map[common.Address]PrecompiledContract,address common.Address,state StateDBManager)PrecompiledContract{
@@ -60,6 +62,34 @@ func getPrecompiledContract(preCompiledMap map[common.Address]PrecompiledContrac
 	}
 	return nil
 }
+
+// IsPrecompiled reports whether addr is served by a precompiled contract in the
+// given state, regardless of the forks enabling it.
+func IsPrecompiled(addr common.Address, state StateDBManager) bool {
+	return getPrecompiledContract(PrecompiledContractsByzantium, addr, state) != nil
+}
+
+// forkedPrecompiles are the precompiles which only run once enabled by a fork.
+var forkedPrecompiles = map[common.Address]struct{}{
+	ElectedSetAddress:     {},
+	MatrixScheduleAddress: {},
+}
+
+// precompile returns the precompiled contract at addr, or nil if there is none
+// or its fork is not active at the current block.
+func (evm *EVM) precompile(addr common.Address) PrecompiledContract {
+	p := getPrecompiledContract(PrecompiledContractsByzantium, addr, evm.StateDB)
+	if p == nil {
+		return nil
+	}
+	if _, gated := forkedPrecompiles[addr]; gated {
+		if evm.PrecompileEnabled == nil || !evm.PrecompileEnabled(evm.StateDB, addr, evm.BlockNumber.Uint64()) {
+			return nil
+		}
+	}
+	return p
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract, evm *EVM) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
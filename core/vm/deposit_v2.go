@@ -628,10 +628,34 @@ func (md *MatrixDeposit002) withdraw_v2(in []byte, contract *Contract, evm *EVM)
 	if err != nil {
 		return nil, err
 	}
+	if evm.AddUnbonding != nil {
+		err = evm.AddUnbonding(evm.StateDB, contract.CallerAddress, depositNum.Uint64(), withdrawAmount, evm.BlockNumber.Uint64())
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return []byte{1}, nil
 }
 
+// PackDepositInput packs the input of a deposit call to the deposit contract,
+// depositing as a validator or a miner with the sign account signAddress.
+func PackDepositInput(role common.RoleType, signAddress common.Address, depositType uint64) ([]byte, error) {
+	switch role {
+	case common.RoleValidator:
+		return depositAbi_v2.Pack("valiDeposit", signAddress, new(big.Int).SetUint64(depositType))
+	case common.RoleMiner:
+		return depositAbi_v2.Pack("minerDeposit", signAddress, new(big.Int).SetUint64(depositType))
+	default:
+		return nil, errors.New("invalid deposit role")
+	}
+}
+
+// PackWithdrawInput packs the input of a withdraw call to the deposit contract.
+func PackWithdrawInput(position uint64, amount *big.Int) ([]byte, error) {
+	return depositAbi_v2.Pack("withdraw", new(big.Int).SetUint64(position), amount)
+}
+
 func (md *MatrixDeposit002) modifyRefundState_v2(contract *Contract, depositNum uint64, evm *EVM) (*big.Int, error) {
 	deposit := md.GetDepositBase(contract, evm.StateDB, contract.CallerAddress)
 	if deposit == nil {
@@ -838,6 +862,12 @@ func (md *MatrixDeposit002) refund_v2(in []byte, contract *Contract, evm *EVM) (
 	if err != nil {
 		return nil, errDeposit
 	}
+	if evm.CheckUnbonding != nil {
+		err = evm.CheckUnbonding(evm.StateDB, contract.CallerAddress, depositNum.Uint64(), evm.BlockNumber.Uint64())
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	value, err := md.modifyRefundState_v2(contract, depositNum.Uint64(), evm)
 	if err != nil {
@@ -0,0 +1,59 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package vm
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+const (
+	electedSetBaseGas       uint64 = 2000 // 查询当选节点的基础gas
+	electedSetPerAccountGas uint64 = 200  // 每个返回的当选账户的gas
+)
+
+var (
+	// ElectedSetAddress 查询当选节点的预编译合约地址
+	ElectedSetAddress = common.BytesToAddress([]byte{11})
+
+	errElectedUnavailable = errors.New("elected set is unavailable")
+)
+
+// electedSet 查询当选节点的预编译合约
+// 输入: abi编码的(uint256 height, uint256 role), role为common.RoleType的取值
+// 输出: abi编码的address[], 即height高度该角色的当选账户
+type electedSet struct{}
+
+func (c *electedSet) RequiredGas(input []byte) uint64 {
+	return electedSetBaseGas
+}
+
+func (c *electedSet) Run(input []byte, contract *Contract, evm *EVM) ([]byte, error) {
+	if evm.GetElected == nil {
+		return nil, errElectedUnavailable
+	}
+	height := new(big.Int).SetBytes(getData(input, 0, 32))
+	role := new(big.Int).SetBytes(getData(input, 32, 32))
+	if !height.IsUint64() || !role.IsUint64() || role.Uint64() > uint64(^uint32(0)) {
+		return nil, errParameters
+	}
+	accounts, err := evm.GetElected(evm.StateDB, evm.BlockNumber.Uint64(), height.Uint64(), common.RoleType(role.Uint64()))
+	if err != nil {
+		return nil, err
+	}
+	// 按返回的账户数计费, 账户数由状态决定, 因此gas是确定的
+	if !contract.UseGas(uint64(len(accounts)) * electedSetPerAccountGas) {
+		return nil, ErrOutOfGas
+	}
+
+	ret := make([]byte, 0, 64+32*len(accounts))
+	ret = append(ret, common.LeftPadBytes(big.NewInt(32).Bytes(), 32)...)
+	ret = append(ret, common.LeftPadBytes(big.NewInt(int64(len(accounts))).Bytes(), 32)...)
+	for _, account := range accounts {
+		ret = append(ret, common.LeftPadBytes(account.Bytes(), 32)...)
+	}
+	return ret, nil
+}
@@ -24,6 +24,20 @@ type (
 	// GetHashFunc returns the nth block hash in the blockchain
 	// and is used by the BLOCKHASH EVM op code.
 	GetHashFunc func(uint64) common.Hash
+	// AddUnbondingFunc 记录抵押仓位的退出请求, 参数为账户、仓位、退出金额和当前高度
+	AddUnbondingFunc func(StateDBManager, common.Address, uint64, *big.Int, uint64) error
+	// CheckUnbondingFunc 检查抵押仓位在当前高度是否已解绑完成
+	CheckUnbondingFunc func(StateDBManager, common.Address, uint64, uint64) error
+	// GetElectedFunc 返回当前高度查询到的指定高度、指定角色的当选账户, 参数为当前高度、查询高度和角色
+	GetElectedFunc func(StateDBManager, uint64, uint64, common.RoleType) ([]common.Address, error)
+	// BroadcastScheduleFunc 返回状态中的广播周期及最后广播区块高度
+	BroadcastScheduleFunc func(StateDBManager) (uint64, uint64, error)
+	// HasRoleFunc 检查账户在当前拓扑中是否为指定角色
+	HasRoleFunc func(StateDBManager, common.Address, common.RoleType) (bool, error)
+	// WasmEnabledFunc 检查指定高度是否启用WASM合约执行
+	WasmEnabledFunc func(StateDBManager, uint64) bool
+	// PrecompileEnabledFunc 检查指定高度是否启用指定地址的预编译合约
+	PrecompileEnabledFunc func(StateDBManager, common.Address, uint64) bool
 )
 
 //200376420520689664
@@ -31,11 +45,13 @@ type (
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
 func run(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
 	if contract.CodeAddr != nil {
-		precompiles := PrecompiledContractsByzantium
-		if p := getPrecompiledContract(precompiles, *contract.CodeAddr, evm.StateDB); p != nil {
+		if p := evm.precompile(*contract.CodeAddr); p != nil {
 			return RunPrecompiledContract(p, input, contract, evm)
 		}
 	}
+	if IsWasmCode(contract.Code) && evm.wasmEnabled() {
+		return runWasm(evm, contract, input)
+	}
 	return evm.interpreter.Run(contract, input)
 }
 
@@ -49,6 +65,20 @@ type Context struct {
 	Transfer TransferFunc
 	// GetHash returns the hash corresponding to n
 	GetHash GetHashFunc
+	// AddUnbonding records a deposit withdrawal into the unbonding queue
+	AddUnbonding AddUnbondingFunc
+	// CheckUnbonding rejects refunds of deposits which are still unbonding
+	CheckUnbonding CheckUnbondingFunc
+	// GetElected returns the elected accounts of a role at a height
+	GetElected GetElectedFunc
+	// BroadcastSchedule returns the broadcast interval in effect
+	BroadcastSchedule BroadcastScheduleFunc
+	// HasRole checks the role of an account in the current topology
+	HasRole HasRoleFunc
+	// WasmEnabled reports whether WASM contracts run at a height
+	WasmEnabled WasmEnabledFunc
+	// PrecompileEnabled reports whether a fork gated precompile runs at a height
+	PrecompileEnabled PrecompileEnabledFunc
 
 	// Message information
 	Origin   common.Address // Provides information for ORIGIN
@@ -97,6 +127,8 @@ type EVM struct {
 	// applied in opCall*.
 	callGasTemp uint64
 	Cointyp     string
+	// wasmActive caches whether WASM contracts run at the current block
+	wasmActive *bool
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
@@ -142,8 +174,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		snapshot = evm.StateDB.Snapshot(evm.Cointyp)
 	)
 	if !evm.StateDB.Exist(evm.Cointyp, addr) {
-		precompiles := PrecompiledContractsByzantium
-		if getPrecompiledContract(precompiles, addr, evm.StateDB) == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
+		if evm.precompile(addr) == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
 			// Calling a non existing account, don't do antything, but ping the tracer
 			if evm.vmConfig.Debug && evm.depth == 0 {
 				evm.vmConfig.Tracer.CaptureStart(caller.Address(), addr, false, input, gas, value)
@@ -306,8 +337,8 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	return ret, contract.Gas, err
 }
 
-// Create creates a new contract using code as deployment code.
-func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+// create creates a new contract at the given address using code as deployment code.
+func (evm *EVM) create(caller ContractRef, code []byte, gas uint64, value *big.Int, contractAddr common.Address) (ret []byte, _ common.Address, leftOverGas uint64, err error) {
 
 	// Depth check execution. Fail if we're trying to execute above the
 	// limit.
@@ -317,11 +348,10 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	if !evm.CanTransfer(evm.StateDB, caller.Address(), value, evm.Cointyp) {
 		return nil, common.Address{}, gas, ErrInsufficientBalance
 	}
-	// Ensure there's no existing contract already at the designated address
 	nonce := evm.StateDB.GetNonce(evm.Cointyp, caller.Address())
 	evm.StateDB.SetNonce(evm.Cointyp, caller.Address(), nonce+1)
 
-	contractAddr = crypto.CreateAddress(caller.Address(), nonce)
+	// Ensure there's no existing contract already at the designated address
 	contractHash := evm.StateDB.GetCodeHash(evm.Cointyp, contractAddr)
 	if evm.StateDB.GetNonce(evm.Cointyp, contractAddr) != params.NonceAddOne || (contractHash != (common.Hash{}) && contractHash != emptyCodeHash) {
 		return nil, common.Address{}, 0, ErrContractAddressCollision
@@ -351,8 +381,16 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 
 	ret, err = run(evm, contract, nil)
 
+	// WASM contract code is allowed to be larger, but must be a valid module
+	maxCodeSize := params.MaxCodeSize
+	if IsWasmCode(ret) && evm.wasmEnabled() {
+		maxCodeSize = MaxWasmCodeSize
+		if err == nil && len(ret) <= maxCodeSize && ValidateWasmCode(ret) != nil {
+			err = errInvalidWasmCode
+		}
+	}
 	// check whether the max code size has been exceeded
-	maxCodeSizeExceeded := evm.ChainConfig().IsEIP158(evm.BlockNumber) && len(ret) > params.MaxCodeSize
+	maxCodeSizeExceeded := evm.ChainConfig().IsEIP158(evm.BlockNumber) && len(ret) > maxCodeSize
 	// if the contract creation ran successfully and no errors were returned
 	// calculate the gas required to store the code. If the code could not
 	// be stored due to not enough gas set an error and let it be handled
@@ -385,6 +423,27 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	return ret, contractAddr, contract.Gas, err
 }
 
+// Create creates a new contract using code as deployment code.
+func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	contractAddr = crypto.CreateAddress(caller.Address(), evm.StateDB.GetNonce(evm.Cointyp, caller.Address()))
+	return evm.create(caller, code, gas, value, contractAddr)
+}
+
+// Create2 creates a new contract using code as deployment code.
+//
+// The difference between Create2 and Create is that Create2 uses sha3(0xff ++ msg.sender ++ salt ++ sha3(init_code))[12:]
+// instead of the usual sender-and-nonce-hash as the address where the contract is initialized at.
+func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *big.Int, salt *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	contractAddr = createAddress2(caller.Address(), common.BigToHash(salt), crypto.Keccak256(code))
+	return evm.create(caller, code, gas, endowment, contractAddr)
+}
+
+// createAddress2 creates a contract address given the address bytes, the salt
+// and the hash of the init code (EIP-1014).
+func createAddress2(b common.Address, salt common.Hash, inithash []byte) common.Address {
+	return common.BytesToAddress(crypto.Keccak256([]byte{0xff}, b.Bytes(), salt.Bytes(), inithash)[12:])
+}
+
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
 
@@ -21,6 +21,13 @@ const (
 	GasReturn       uint64 = 0
 	GasStop         uint64 = 0
 	GasContractByte uint64 = 200
+
+	ExtcodeHashGasConstantinople uint64 = 400
+
+	// Istanbul repricing of the state access opcodes (EIP-1884)
+	SloadGasEIP1884       uint64 = 800
+	BalanceGasEIP1884     uint64 = 700
+	ExtcodeHashGasEIP1884 uint64 = 700
 )
 
 // calcGas returns the actual gas cost of the call.
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
 	"github.com/MatrixAINetwork/go-matrix/params"
 )
 
@@ -413,3 +414,69 @@ func BenchmarkOpIsZero(b *testing.B) {
 	x := "FBCDEF090807060504030201ffffffffFBCDEF090807060504030201ffffffff"
 	opBenchmark(b, opIszero, x)
 }
+
+func TestCreate2Addresses(t *testing.T) {
+	// Test vectors of EIP-1014
+	type testcase struct {
+		origin   string
+		salt     string
+		code     string
+		expected string
+	}
+
+	for i, tt := range []testcase{
+		{
+			origin:   "0x0000000000000000000000000000000000000000",
+			salt:     "0x0000000000000000000000000000000000000000",
+			code:     "0x00",
+			expected: "0x4d1a2e2bb4f88f0250f26ffff098b0b30b26bf38",
+		},
+		{
+			origin:   "0xdeadbeef00000000000000000000000000000000",
+			salt:     "0x0000000000000000000000000000000000000000",
+			code:     "0x00",
+			expected: "0xb928f69bb1d91cd65274e3c79d8986362984fda3",
+		},
+		{
+			origin:   "0xdeadbeef00000000000000000000000000000000",
+			salt:     "0xfeed000000000000000000000000000000000000",
+			code:     "0x00",
+			expected: "0xd04116cdd17bebe565eb2422f2497e06cc1c9833",
+		},
+		{
+			origin:   "0x0000000000000000000000000000000000000000",
+			salt:     "0x0000000000000000000000000000000000000000",
+			code:     "0xdeadbeef",
+			expected: "0x70f2b2914a2a4b783faefb75f459a580616fcb5e",
+		},
+		{
+			origin:   "0x00000000000000000000000000000000deadbeef",
+			salt:     "0xcafebabe",
+			code:     "0xdeadbeef",
+			expected: "0x60f3f640a8508fc6a86d45df051962668e1e8ac7",
+		},
+	} {
+		origin := common.BytesToAddress(common.FromHex(tt.origin))
+		salt := common.BytesToHash(common.FromHex(tt.salt))
+		code := common.FromHex(tt.code)
+		address := createAddress2(origin, salt, crypto.Keccak256(code))
+		if expected := common.BytesToAddress(common.FromHex(tt.expected)); address != expected {
+			t.Errorf("test %d: expected %s, got %s", i, expected.Hex(), address.Hex())
+		}
+	}
+}
+
+func TestIstanbulInstructionSet(t *testing.T) {
+	for _, op := range []OpCode{CHAINID, SELFBALANCE, EXTCODEHASH, CREATE2} {
+		if byzantiumInstructionSet[op].valid {
+			t.Errorf("%v should be invalid before the fork", op)
+		}
+		if !istanbulInstructionSet[op].valid {
+			t.Errorf("%v should be valid after the fork", op)
+		}
+	}
+	gas, _ := istanbulInstructionSet[SLOAD].gasCost(params.GasTableEIP158, nil, nil, nil, nil, 0)
+	if gas != SloadGasEIP1884 {
+		t.Errorf("SLOAD gas mismatch: have %d, want %d", gas, SloadGasEIP1884)
+	}
+}
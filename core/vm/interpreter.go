@@ -23,6 +23,8 @@ type Config struct {
 	NoRecursion bool
 	// Enable recording of SHA3/keccak preimages
 	EnablePreimageRecording bool
+	// Execute conflict free transactions of a block concurrently
+	ParallelExec bool
 	// JumpTable contains the EVM instruction table. This
 	// may be left uninitialised and will be set to the default
 	// table.
@@ -48,7 +50,13 @@ func NewInterpreter(evm *EVM, cfg Config) *Interpreter {
 	// We use the STOP instruction whether to see
 	// the jump table was initialised. If it was not
 	// we'll set the default jump table.
-	cfg.JumpTable = byzantiumInstructionSet
+	// The constantinople fork block of the chain config activates the newer
+	// opcodes along with the istanbul gas schedule.
+	if evm.ChainConfig().IsConstantinople(evm.BlockNumber) {
+		cfg.JumpTable = istanbulInstructionSet
+	} else {
+		cfg.JumpTable = byzantiumInstructionSet
+	}
 	//	if !cfg.JumpTable[STOP].valid {
 	//		switch {
 	//		case evm.ChainConfig().IsConstantinople(evm.BlockNumber):
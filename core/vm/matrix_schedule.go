@@ -0,0 +1,132 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package vm
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts/abi"
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+const (
+	matrixScheduleGas uint64 = 1000 // 查询广播周期的gas
+	matrixHasRoleGas  uint64 = 2000 // 查询账户角色的gas
+)
+
+var (
+	// MatrixScheduleAddress 查询广播周期及账户角色的预编译合约地址
+	MatrixScheduleAddress = common.BytesToAddress([]byte{12})
+
+	matrixScheduleDef = ` [{"constant": true,"inputs": [{"name": "height","type": "uint256"}],"name": "isBroadcastBlock","outputs": [{"name": "","type": "bool"}],"payable": false,"stateMutability": "view","type": "function"},
+			{"constant": true,"inputs": [],"name": "currentInterval","outputs": [{"name": "interval","type": "uint256"},{"name": "lastBroadcast","type": "uint256"},{"name": "nextBroadcast","type": "uint256"}],"payable": false,"stateMutability": "view","type": "function"},
+			{"constant": true,"inputs": [{"name": "addr","type": "address"},{"name": "role","type": "uint256"}],"name": "hasRole","outputs": [{"name": "","type": "bool"}],"payable": false,"stateMutability": "view","type": "function"}]`
+
+	matrixScheduleAbi, _                                = abi.JSON(strings.NewReader(matrixScheduleDef))
+	isBroadcastBlockArr, currentIntervalArr, hasRoleArr [4]byte
+
+	errScheduleUnavailable = errors.New("broadcast schedule is unavailable")
+)
+
+func init() {
+	copy(isBroadcastBlockArr[:], matrixScheduleAbi.Methods["isBroadcastBlock"].Id())
+	copy(currentIntervalArr[:], matrixScheduleAbi.Methods["currentInterval"].Id())
+	copy(hasRoleArr[:], matrixScheduleAbi.Methods["hasRole"].Id())
+}
+
+// matrixSchedule 查询广播周期及账户角色的预编译合约, 供链上治理及抵押合约按协议的广播周期执行.
+// 广播周期取自当前状态, 查询历史高度时按当前的广播周期推算
+type matrixSchedule struct{}
+
+func (c *matrixSchedule) RequiredGas(input []byte) uint64 {
+	if len(input) >= 4 {
+		var methodIdArr [4]byte
+		copy(methodIdArr[:], input[:4])
+		if methodIdArr == hasRoleArr {
+			return matrixHasRoleGas
+		}
+	}
+	return matrixScheduleGas
+}
+
+func (c *matrixSchedule) Run(in []byte, contract *Contract, evm *EVM) ([]byte, error) {
+	if len(in) < 4 {
+		return nil, errParameters
+	}
+	var methodIdArr [4]byte
+	copy(methodIdArr[:], in[:4])
+	switch methodIdArr {
+	case isBroadcastBlockArr:
+		return c.isBroadcastBlock(in[4:], evm)
+	case currentIntervalArr:
+		return c.currentInterval(evm)
+	case hasRoleArr:
+		return c.hasRole(in[4:], evm)
+	}
+	return nil, errMethodId
+}
+
+func (c *matrixSchedule) schedule(evm *EVM) (uint64, uint64, error) {
+	if evm.BroadcastSchedule == nil {
+		return 0, 0, errScheduleUnavailable
+	}
+	interval, last, err := evm.BroadcastSchedule(evm.StateDB)
+	if err != nil {
+		return 0, 0, err
+	}
+	if interval == 0 {
+		return 0, 0, errScheduleUnavailable
+	}
+	return interval, last, nil
+}
+
+func (c *matrixSchedule) isBroadcastBlock(in []byte, evm *EVM) ([]byte, error) {
+	height := new(big.Int).SetBytes(getData(in, 0, 32))
+	if !height.IsUint64() {
+		return nil, errParameters
+	}
+	interval, last, err := c.schedule(evm)
+	if err != nil {
+		return nil, err
+	}
+	number := height.Uint64()
+	var isBroadcast bool
+	if number >= last {
+		isBroadcast = (number-last)%interval == 0
+	} else {
+		isBroadcast = (last-number)%interval == 0
+	}
+	return matrixScheduleAbi.Methods["isBroadcastBlock"].Outputs.Pack(isBroadcast)
+}
+
+// currentInterval 返回广播周期及当前区块所在广播周期的起止广播区块高度
+func (c *matrixSchedule) currentInterval(evm *EVM) ([]byte, error) {
+	interval, last, err := c.schedule(evm)
+	if err != nil {
+		return nil, err
+	}
+	number := evm.BlockNumber.Uint64()
+	if number > last {
+		last += (number - last) / interval * interval
+	}
+	return matrixScheduleAbi.Methods["currentInterval"].Outputs.Pack(new(big.Int).SetUint64(interval), new(big.Int).SetUint64(last), new(big.Int).SetUint64(last+interval))
+}
+
+func (c *matrixSchedule) hasRole(in []byte, evm *EVM) ([]byte, error) {
+	if evm.HasRole == nil {
+		return nil, errScheduleUnavailable
+	}
+	addr := common.BytesToAddress(getData(in, 12, 20))
+	role := new(big.Int).SetBytes(getData(in, 32, 32))
+	if !role.IsUint64() || role.Uint64() > uint64(^uint32(0)) {
+		return nil, errParameters
+	}
+	has, err := evm.HasRole(evm.StateDB, addr, common.RoleType(role.Uint64()))
+	if err != nil {
+		return nil, err
+	}
+	return matrixScheduleAbi.Methods["hasRole"].Outputs.Pack(has)
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package vm
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+)
+
+// NativeTracer is a Tracer implemented in Go that can be selected by name
+// instead of a JavaScript tracer expression.
+type NativeTracer interface {
+	Tracer
+	// GetResult returns the JSON encoded result collected during the trace.
+	GetResult() (json.RawMessage, error)
+}
+
+// nativeTracers are the built-in tracers by name. The prestate database must
+// be a copy of the state taken before the traced transaction was applied.
+var nativeTracers = map[string]func(cointyp string, prestate StateDBManager) NativeTracer{
+	"callTracer": func(string, StateDBManager) NativeTracer { return NewCallTracer() },
+	"prestateTracer": func(cointyp string, prestate StateDBManager) NativeTracer {
+		return NewPrestateTracer(cointyp, prestate)
+	},
+}
+
+// LookupNativeTracer returns the built-in tracer registered under name, or
+// false if name is not a native tracer.
+func LookupNativeTracer(name string, cointyp string, prestate StateDBManager) (NativeTracer, bool) {
+	ctor, ok := nativeTracers[name]
+	if !ok {
+		return nil, false
+	}
+	return ctor(cointyp, prestate), true
+}
+
+// callFrame is a single call or contract creation captured by the CallTracer.
+type callFrame struct {
+	Type    string         `json:"type"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	Value   *hexutil.Big   `json:"value,omitempty"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Input   hexutil.Bytes  `json:"input"`
+	Output  hexutil.Bytes  `json:"output,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Calls   []*callFrame   `json:"calls,omitempty"`
+
+	gasIn   uint64 // gas of the caller before the call op
+	gasCost uint64 // cost of the call op, including the gas handed to the callee
+	outOff  int64  // memory offset of the call return data in the caller
+	outLen  int64  // size of the call return data in the caller
+	entered bool   // whether the callee executed any code
+}
+
+// CallTracer collects the tree of internal calls made by a transaction,
+// reconstructed from the CALL and CREATE family of opcodes.
+type CallTracer struct {
+	root  *callFrame
+	stack []*callFrame // open frames, the frame at depth d is stack[d-1]
+}
+
+// NewCallTracer returns a new call tracer.
+func NewCallTracer() *CallTracer {
+	return &CallTracer{}
+}
+
+func (t *CallTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	typ := "CALL"
+	if create {
+		typ = "CREATE"
+	}
+	t.root = &callFrame{
+		Type:  typ,
+		From:  from,
+		To:    to,
+		Value: (*hexutil.Big)(new(big.Int).Set(value)),
+		Gas:   hexutil.Uint64(gas),
+		Input: common.CopyBytes(input),
+	}
+	t.stack = []*callFrame{t.root}
+	return nil
+}
+
+func (t *CallTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	if t.root == nil {
+		return nil
+	}
+	// Execution returned into a caller, close every frame above this depth
+	for len(t.stack) > depth {
+		t.exit(gas, memory, stack)
+	}
+	// First step of a freshly entered frame, record the gas handed over
+	if len(t.stack) == depth && depth > 1 {
+		if frame := t.stack[depth-1]; !frame.entered {
+			frame.entered = true
+			frame.Gas = hexutil.Uint64(gas)
+		}
+	}
+	if err != nil {
+		t.stack[len(t.stack)-1].Error = err.Error()
+		return nil
+	}
+	parent := t.stack[len(t.stack)-1]
+	frame := &callFrame{From: contract.Address(), gasIn: gas, gasCost: cost}
+	switch op {
+	case CREATE:
+		if stack.len() < 3 {
+			return nil
+		}
+		off, size := stack.Back(1).Int64(), stack.Back(2).Int64()
+		frame.Type = "CREATE"
+		frame.Value = (*hexutil.Big)(new(big.Int).Set(stack.Back(0)))
+		frame.Input = memory.Get(off, size)
+
+	case CALL, CALLCODE:
+		if stack.len() < 7 {
+			return nil
+		}
+		frame.Type = op.String()
+		frame.To = common.BigToAddress(stack.Back(1))
+		frame.Value = (*hexutil.Big)(new(big.Int).Set(stack.Back(2)))
+		frame.Input = memory.Get(stack.Back(3).Int64(), stack.Back(4).Int64())
+		frame.outOff, frame.outLen = stack.Back(5).Int64(), stack.Back(6).Int64()
+
+	case DELEGATECALL, STATICCALL:
+		if stack.len() < 6 {
+			return nil
+		}
+		frame.Type = op.String()
+		frame.To = common.BigToAddress(stack.Back(1))
+		frame.Input = memory.Get(stack.Back(2).Int64(), stack.Back(3).Int64())
+		frame.outOff, frame.outLen = stack.Back(4).Int64(), stack.Back(5).Int64()
+
+	case SELFDESTRUCT:
+		if stack.len() < 1 {
+			return nil
+		}
+		parent.Calls = append(parent.Calls, &callFrame{
+			Type:  "SELFDESTRUCT",
+			From:  contract.Address(),
+			To:    common.BigToAddress(stack.Back(0)),
+			Value: (*hexutil.Big)(env.StateDB.GetBalanceByType(env.Cointyp, contract.Address(), common.MainAccount)),
+		})
+		return nil
+
+	default:
+		return nil
+	}
+	parent.Calls = append(parent.Calls, frame)
+	t.stack = append(t.stack, frame)
+	return nil
+}
+
+// exit closes the innermost open frame once execution is back in its caller.
+// gas, memory and stack belong to the caller at the instruction following the
+// call op.
+func (t *CallTracer) exit(gas uint64, memory *Memory, stack *Stack) {
+	frame := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+
+	// Gas left over by the callee is refunded on top of what the caller kept
+	var refunded uint64
+	if kept := frame.gasIn - frame.gasCost; gas > kept {
+		refunded = gas - kept
+	}
+	if !frame.entered {
+		frame.Gas = hexutil.Uint64(refunded)
+	}
+	if uint64(frame.Gas) > refunded {
+		frame.GasUsed = hexutil.Uint64(uint64(frame.Gas) - refunded)
+	}
+	if stack.len() == 0 {
+		return
+	}
+	ret := stack.Back(0)
+	if frame.Type == "CREATE" {
+		frame.To = common.BigToAddress(ret)
+		if ret.Sign() == 0 && frame.Error == "" {
+			frame.Error = "internal failure"
+		}
+		return
+	}
+	if ret.Sign() == 0 && frame.Error == "" {
+		frame.Error = "internal failure"
+	}
+	if frame.outLen > 0 {
+		frame.Output = memory.Get(frame.outOff, frame.outLen)
+	}
+}
+
+func (t *CallTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	if t.root == nil || err == nil {
+		return nil
+	}
+	if depth > 0 && depth <= len(t.stack) {
+		t.stack[depth-1].Error = err.Error()
+	}
+	return nil
+}
+
+func (t *CallTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	if t.root == nil {
+		return nil
+	}
+	t.root.Output = common.CopyBytes(output)
+	t.root.GasUsed = hexutil.Uint64(gasUsed)
+	if err != nil {
+		t.root.Error = err.Error()
+	}
+	return nil
+}
+
+// GetResult returns the JSON encoded call tree.
+func (t *CallTracer) GetResult() (json.RawMessage, error) {
+	if t.root == nil {
+		return nil, errors.New("no call captured")
+	}
+	return json.Marshal(t.root)
+}
+
+// prestateAccount is the state of an account before the traced transaction.
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// PrestateTracer collects the accounts and storage slots touched by a
+// transaction and reports their values from before the transaction.
+type PrestateTracer struct {
+	cointyp  string
+	prestate StateDBManager
+	touched  map[common.Address]map[common.Hash]struct{}
+}
+
+// NewPrestateTracer returns a new prestate tracer reading the original values
+// of the touched accounts from prestate.
+func NewPrestateTracer(cointyp string, prestate StateDBManager) *PrestateTracer {
+	return &PrestateTracer{
+		cointyp:  cointyp,
+		prestate: prestate,
+		touched:  make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+func (t *PrestateTracer) touch(addr common.Address) map[common.Hash]struct{} {
+	slots, ok := t.touched[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		t.touched[addr] = slots
+	}
+	return slots
+}
+
+func (t *PrestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.touch(from)
+	t.touch(to)
+	return nil
+}
+
+func (t *PrestateTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	if err != nil {
+		return nil
+	}
+	slots := t.touch(contract.Address())
+	switch op {
+	case SLOAD, SSTORE:
+		if stack.len() >= 1 {
+			slots[common.BigToHash(stack.Back(0))] = struct{}{}
+		}
+	case BALANCE, EXTCODESIZE, EXTCODECOPY, SELFDESTRUCT:
+		if stack.len() >= 1 {
+			t.touch(common.BigToAddress(stack.Back(0)))
+		}
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		if stack.len() >= 2 {
+			t.touch(common.BigToAddress(stack.Back(1)))
+		}
+	}
+	return nil
+}
+
+func (t *PrestateTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	return nil
+}
+
+func (t *PrestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the JSON encoded pre-transaction state of the touched
+// accounts, keyed by address.
+func (t *PrestateTracer) GetResult() (json.RawMessage, error) {
+	if t.prestate == nil {
+		return nil, errors.New("prestate unavailable")
+	}
+	result := make(map[common.Address]*prestateAccount, len(t.touched))
+	for addr, slots := range t.touched {
+		if !t.prestate.Exist(t.cointyp, addr) {
+			continue
+		}
+		balance := t.prestate.GetBalanceByType(t.cointyp, addr, common.MainAccount)
+		if balance == nil {
+			balance = new(big.Int)
+		}
+		account := &prestateAccount{
+			Balance: (*hexutil.Big)(new(big.Int).Set(balance)),
+			Nonce:   t.prestate.GetNonce(t.cointyp, addr),
+			Code:    common.CopyBytes(t.prestate.GetCode(t.cointyp, addr)),
+		}
+		if len(slots) > 0 {
+			account.Storage = make(map[common.Hash]common.Hash, len(slots))
+			for slot := range slots {
+				account.Storage[slot] = t.prestate.GetState(t.cointyp, addr, slot)
+			}
+		}
+		result[addr] = account
+	}
+	return json.Marshal(result)
+}
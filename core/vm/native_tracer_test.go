@@ -0,0 +1,71 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+// Tests that the call tracer reconstructs a nested call from the opcode steps
+// and accounts the gas refunded by the callee.
+func TestCallTracerNestedCall(t *testing.T) {
+	var (
+		tracer   = NewCallTracer()
+		mem      = NewMemory()
+		caller   = common.HexToAddress("0x01")
+		callee   = common.HexToAddress("0x02")
+		contract = NewContract(AccountRef(caller), AccountRef(caller), new(big.Int), 100000, params.MAN_COIN)
+	)
+	tracer.CaptureStart(common.Address{}, caller, false, nil, 100000, new(big.Int))
+
+	// CALL(gas, addr, value, inOff, inSize, outOff, outSize), top of stack first
+	stack := newstack()
+	for _, v := range []int64{0, 0, 0, 0, 0} {
+		stack.push(big.NewInt(v))
+	}
+	stack.push(new(big.Int).SetBytes(callee[:]))
+	stack.push(big.NewInt(5000))
+	tracer.CaptureState(nil, 0, CALL, 90000, 5700, mem, stack, contract, 1, nil)
+
+	inner := NewContract(AccountRef(caller), AccountRef(callee), new(big.Int), 5000, params.MAN_COIN)
+	tracer.CaptureState(nil, 0, PUSH1, 5000, 3, mem, newstack(), inner, 2, nil)
+
+	ret := newstack()
+	ret.push(big.NewInt(1))
+	tracer.CaptureState(nil, 1, POP, 84300+4000, 2, mem, ret, contract, 1, nil)
+	tracer.CaptureEnd(nil, 20000, 0, nil)
+
+	if len(tracer.root.Calls) != 1 {
+		t.Fatalf("call count mismatch: have %d, want 1", len(tracer.root.Calls))
+	}
+	call := tracer.root.Calls[0]
+	if call.Type != "CALL" || call.To != callee || call.From != caller {
+		t.Errorf("call mismatch: %+v", call)
+	}
+	if call.Gas != 5000 || call.GasUsed != 1000 {
+		t.Errorf("gas mismatch: have %d/%d, want 5000/1000", call.Gas, call.GasUsed)
+	}
+	if call.Error != "" {
+		t.Errorf("unexpected error: %s", call.Error)
+	}
+	if _, err := tracer.GetResult(); err != nil {
+		t.Errorf("failed to encode result: %v", err)
+	}
+}
+
+func TestLookupNativeTracer(t *testing.T) {
+	for _, name := range []string{"callTracer", "prestateTracer"} {
+		if _, ok := LookupNativeTracer(name, params.MAN_COIN, nil); !ok {
+			t.Errorf("native tracer %s not found", name)
+		}
+	}
+	if _, ok := LookupNativeTracer("{}", params.MAN_COIN, nil); ok {
+		t.Errorf("javascript tracer resolved as native tracer")
+	}
+}
@@ -0,0 +1,594 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package wasm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	errStackUnderflow = errors.New("wasm: operand stack underflow")
+	errTypeMismatch   = errors.New("wasm: type mismatch")
+	errNoMemory       = errors.New("wasm: memory instruction without memory")
+)
+
+// branch is a resolved branch: the instruction to continue at, the number
+// of values carried to the label and the operand stack height of the label
+// relative to the frame.
+type branch struct {
+	target int
+	arity  int
+	height int
+}
+
+// instr is a compiled instruction. Structured control flow is compiled
+// into jumps with resolved targets, so the interpreter never scans the
+// code for the end of a block.
+type instr struct {
+	op    byte
+	a     uint64   // immediate: constant, index, memory offset or gas
+	br    branch   // target of the jumps and branches, arity of return
+	table []branch // targets of br_table, the last one is the default
+}
+
+// function is a compiled function of the module.
+type function struct {
+	typ       FuncType
+	numLocals int // parameters and declared locals
+	code      []instr
+	maxStack  int // maximum height of the operand stack
+}
+
+// fixup refers to a branch target patched when the end of a block is
+// compiled, entry is -1 for the br field or the index in the table.
+type fixup struct {
+	instr int
+	entry int
+}
+
+// ctrlFrame is a structured control instruction being compiled.
+type ctrlFrame struct {
+	op          byte // opBlock, opLoop, opIf, opElse or opNop for the function body
+	results     []ValueType
+	height      int
+	unreachable bool
+	loopPC      int
+	fixups      []fixup
+	elseFixup   int // the opJumpIfZero of an if, -1 after the else
+}
+
+// labelTypes returns the types of the values carried by a branch to the
+// frame, a loop label has none in the MVP.
+func (f *ctrlFrame) labelTypes() []ValueType {
+	if f.op == opLoop {
+		return nil
+	}
+	return f.results
+}
+
+type compiler struct {
+	module  *Module
+	fn      *function
+	locals  []ValueType
+	r       *reader
+	operand []ValueType
+	ctrls   []*ctrlFrame
+	meter   int // the opGas instruction of the current basic block
+}
+
+func (m *Module) compile() error {
+	m.compiled = make([]*function, len(m.Funcs))
+	for i, typeIndex := range m.Funcs {
+		ft := m.Types[typeIndex]
+		c := &compiler{
+			module: m,
+			fn:     &function{typ: ft, numLocals: len(ft.Params) + len(m.Codes[i].Locals)},
+			locals: append(append([]ValueType{}, ft.Params...), m.Codes[i].Locals...),
+			r:      newReader(m.Codes[i].Body),
+		}
+		if err := c.compile(); err != nil {
+			return fmt.Errorf("%v in function %d", err, len(m.Imports)+i)
+		}
+		m.compiled[i] = c.fn
+	}
+	return nil
+}
+
+func (c *compiler) push(t ValueType) {
+	c.operand = append(c.operand, t)
+	if len(c.operand) > c.fn.maxStack {
+		c.fn.maxStack = len(c.operand)
+	}
+}
+
+func (c *compiler) pop() (ValueType, error) {
+	frame := c.ctrls[len(c.ctrls)-1]
+	if len(c.operand) == frame.height {
+		if frame.unreachable {
+			return unknown, nil
+		}
+		return 0, errStackUnderflow
+	}
+	t := c.operand[len(c.operand)-1]
+	c.operand = c.operand[:len(c.operand)-1]
+	return t, nil
+}
+
+func (c *compiler) popExpect(want ValueType) error {
+	t, err := c.pop()
+	if err != nil {
+		return err
+	}
+	if t != want && t != unknown {
+		return errTypeMismatch
+	}
+	return nil
+}
+
+func (c *compiler) popTypes(types []ValueType) error {
+	for i := len(types) - 1; i >= 0; i-- {
+		if err := c.popExpect(types[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *compiler) pushTypes(types []ValueType) {
+	for _, t := range types {
+		c.push(t)
+	}
+}
+
+func (c *compiler) setUnreachable() {
+	frame := c.ctrls[len(c.ctrls)-1]
+	c.operand = c.operand[:frame.height]
+	frame.unreachable = true
+}
+
+func (c *compiler) emit(in instr) int {
+	c.fn.code = append(c.fn.code, in)
+	return len(c.fn.code) - 1
+}
+
+// newMeter starts a basic block, the gas of the instructions compiled
+// afterwards is charged by its meter.
+func (c *compiler) newMeter() int {
+	c.meter = c.emit(instr{op: opGas})
+	return c.meter
+}
+
+func (c *compiler) charge(gas uint64) {
+	c.fn.code[c.meter].a += gas
+}
+
+// patch resolves the branches to the end of a block.
+func (c *compiler) patch(fixups []fixup, target int) {
+	for _, f := range fixups {
+		if f.entry < 0 {
+			c.fn.code[f.instr].br.target = target
+		} else {
+			c.fn.code[f.instr].table[f.entry].target = target
+		}
+	}
+}
+
+// label resolves a branch to the label at depth.
+func (c *compiler) label(depth uint32) (branch, *ctrlFrame, error) {
+	if int(depth) >= len(c.ctrls) {
+		return branch{}, nil, fmt.Errorf("wasm: unknown label %d", depth)
+	}
+	frame := c.ctrls[len(c.ctrls)-1-int(depth)]
+	b := branch{arity: len(frame.labelTypes()), height: frame.height}
+	if frame.op == opLoop {
+		b.target = frame.loopPC
+	}
+	return b, frame, nil
+}
+
+func (c *compiler) blockType() ([]ValueType, error) {
+	b, err := c.r.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch t := ValueType(b); t {
+	case 0x40:
+		return nil, nil
+	case I32, I64:
+		return []ValueType{t}, nil
+	}
+	return nil, fmt.Errorf("wasm: unsupported block type 0x%x", b)
+}
+
+func (c *compiler) memarg(size uint64) (uint64, error) {
+	if c.module.Memory == nil {
+		return 0, errNoMemory
+	}
+	align, err := c.r.u32()
+	if err != nil {
+		return 0, err
+	}
+	if align >= 64 || uint64(1)<<align > size {
+		return 0, errors.New("wasm: alignment must not be larger than natural")
+	}
+	offset, err := c.r.u32()
+	return uint64(offset), err
+}
+
+// endFrame checks the operand stack holds exactly the results of frame.
+func (c *compiler) endFrame(frame *ctrlFrame) error {
+	if err := c.popTypes(frame.results); err != nil {
+		return err
+	}
+	if len(c.operand) != frame.height {
+		return errTypeMismatch
+	}
+	return nil
+}
+
+func (c *compiler) compile() error {
+	c.ctrls = []*ctrlFrame{{op: opNop, results: c.fn.typ.Results, elseFixup: -1}}
+	c.newMeter()
+	for len(c.ctrls) > 0 {
+		op, err := c.r.byte()
+		if err != nil {
+			return err
+		}
+		c.charge(instrGas(op))
+		if err := c.compileInstr(op); err != nil {
+			return err
+		}
+	}
+	if !c.r.eof() {
+		return errors.New("wasm: instructions after the end of the function")
+	}
+	return nil
+}
+
+func (c *compiler) compileInstr(op byte) error {
+	r := c.r
+	switch op {
+	case opUnreachable:
+		c.emit(instr{op: op})
+		c.setUnreachable()
+		c.newMeter()
+
+	case opNop:
+
+	case opBlock, opLoop:
+		results, err := c.blockType()
+		if err != nil {
+			return err
+		}
+		frame := &ctrlFrame{op: op, results: results, height: len(c.operand), elseFixup: -1}
+		if op == opLoop {
+			frame.loopPC = c.newMeter()
+		}
+		c.ctrls = append(c.ctrls, frame)
+
+	case opIf:
+		results, err := c.blockType()
+		if err != nil {
+			return err
+		}
+		if err := c.popExpect(I32); err != nil {
+			return err
+		}
+		jump := c.emit(instr{op: opJumpIfZero})
+		c.ctrls = append(c.ctrls, &ctrlFrame{op: op, results: results, height: len(c.operand), elseFixup: jump})
+		c.newMeter()
+
+	case opElse:
+		frame := c.ctrls[len(c.ctrls)-1]
+		if frame.op != opIf {
+			return errors.New("wasm: else without if")
+		}
+		if err := c.endFrame(frame); err != nil {
+			return err
+		}
+		jump := c.emit(instr{op: opJump})
+		frame.fixups = append(frame.fixups, fixup{instr: jump, entry: -1})
+		c.fn.code[frame.elseFixup].br.target = c.newMeter()
+		frame.op, frame.elseFixup, frame.unreachable = opElse, -1, false
+
+	case opEnd:
+		frame := c.ctrls[len(c.ctrls)-1]
+		if err := c.endFrame(frame); err != nil {
+			return err
+		}
+		if frame.op == opIf && len(frame.results) != 0 {
+			return errors.New("wasm: if with result but without else")
+		}
+		c.ctrls = c.ctrls[:len(c.ctrls)-1]
+		c.pushTypes(frame.results)
+		var target int
+		if len(c.ctrls) == 0 {
+			target = c.emit(instr{op: opReturn, br: branch{arity: len(frame.results)}})
+		} else {
+			target = c.newMeter()
+		}
+		c.patch(frame.fixups, target)
+		if frame.elseFixup >= 0 {
+			c.fn.code[frame.elseFixup].br.target = target
+		}
+
+	case opBr, opBrIf:
+		depth, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if op == opBrIf {
+			if err := c.popExpect(I32); err != nil {
+				return err
+			}
+		}
+		b, frame, err := c.label(depth)
+		if err != nil {
+			return err
+		}
+		if err := c.popTypes(frame.labelTypes()); err != nil {
+			return err
+		}
+		index := c.emit(instr{op: op, br: b})
+		if frame.op != opLoop {
+			frame.fixups = append(frame.fixups, fixup{instr: index, entry: -1})
+		}
+		if op == opBr {
+			c.setUnreachable()
+		} else {
+			c.pushTypes(frame.labelTypes())
+		}
+		c.newMeter()
+
+	case opBrTable:
+		n, err := vec(r)
+		if err != nil {
+			return err
+		}
+		depths := make([]uint32, n+1)
+		for i := range depths {
+			if depths[i], err = r.u32(); err != nil {
+				return err
+			}
+		}
+		if err := c.popExpect(I32); err != nil {
+			return err
+		}
+		_, defaultFrame, err := c.label(depths[n])
+		if err != nil {
+			return err
+		}
+		types := defaultFrame.labelTypes()
+		in := instr{op: op, table: make([]branch, len(depths))}
+		index := len(c.fn.code)
+		for i, depth := range depths {
+			b, frame, err := c.label(depth)
+			if err != nil {
+				return err
+			}
+			if !equalTypes(frame.labelTypes(), types) {
+				return errors.New("wasm: br_table targets have inconsistent types")
+			}
+			in.table[i] = b
+			if frame.op != opLoop {
+				frame.fixups = append(frame.fixups, fixup{instr: index, entry: i})
+			}
+		}
+		if err := c.popTypes(types); err != nil {
+			return err
+		}
+		c.emit(in)
+		c.setUnreachable()
+		c.newMeter()
+
+	case opReturn:
+		results := c.fn.typ.Results
+		if err := c.popTypes(results); err != nil {
+			return err
+		}
+		c.emit(instr{op: op, br: branch{arity: len(results)}})
+		c.setUnreachable()
+		c.newMeter()
+
+	case opCall:
+		index, err := r.u32()
+		if err != nil {
+			return err
+		}
+		ft, err := c.module.FuncType(index)
+		if err != nil {
+			return err
+		}
+		if err := c.popTypes(ft.Params); err != nil {
+			return err
+		}
+		c.pushTypes(ft.Results)
+		c.emit(instr{op: op, a: uint64(index)})
+
+	case opCallIndirect:
+		index, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if int(index) >= len(c.module.Types) {
+			return fmt.Errorf("wasm: unknown type %d", index)
+		}
+		if reserved, err := r.byte(); err != nil || reserved != 0 {
+			return errors.New("wasm: invalid call_indirect table")
+		}
+		if c.module.Table == nil {
+			return errors.New("wasm: call_indirect without table")
+		}
+		if err := c.popExpect(I32); err != nil {
+			return err
+		}
+		ft := c.module.Types[index]
+		if err := c.popTypes(ft.Params); err != nil {
+			return err
+		}
+		c.pushTypes(ft.Results)
+		c.emit(instr{op: op, a: uint64(index)})
+
+	case opDrop:
+		if _, err := c.pop(); err != nil {
+			return err
+		}
+		c.emit(instr{op: op})
+
+	case opSelect:
+		if err := c.popExpect(I32); err != nil {
+			return err
+		}
+		t1, err := c.pop()
+		if err != nil {
+			return err
+		}
+		t2, err := c.pop()
+		if err != nil {
+			return err
+		}
+		if t1 != t2 && t1 != unknown && t2 != unknown {
+			return errTypeMismatch
+		}
+		if t1 == unknown {
+			t1 = t2
+		}
+		c.push(t1)
+		c.emit(instr{op: op})
+
+	case opLocalGet, opLocalSet, opLocalTee:
+		index, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if int(index) >= len(c.locals) {
+			return fmt.Errorf("wasm: unknown local %d", index)
+		}
+		t := c.locals[index]
+		if op != opLocalGet {
+			if err := c.popExpect(t); err != nil {
+				return err
+			}
+		}
+		if op != opLocalSet {
+			c.push(t)
+		}
+		c.emit(instr{op: op, a: uint64(index)})
+
+	case opGlobalGet, opGlobalSet:
+		index, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if int(index) >= len(c.module.Globals) {
+			return fmt.Errorf("wasm: unknown global %d", index)
+		}
+		g := c.module.Globals[index]
+		if op == opGlobalGet {
+			c.push(g.Type)
+		} else {
+			if !g.Mutable {
+				return fmt.Errorf("wasm: global %d is immutable", index)
+			}
+			if err := c.popExpect(g.Type); err != nil {
+				return err
+			}
+		}
+		c.emit(instr{op: op, a: uint64(index)})
+
+	case opMemorySize, opMemoryGrow:
+		if c.module.Memory == nil {
+			return errNoMemory
+		}
+		if reserved, err := r.byte(); err != nil || reserved != 0 {
+			return errors.New("wasm: invalid memory index")
+		}
+		if op == opMemoryGrow {
+			if err := c.popExpect(I32); err != nil {
+				return err
+			}
+		}
+		c.push(I32)
+		c.emit(instr{op: op})
+
+	case opI32Const:
+		v, err := r.s32()
+		if err != nil {
+			return err
+		}
+		c.push(I32)
+		c.emit(instr{op: op, a: uint64(uint32(v))})
+
+	case opI64Const:
+		v, err := r.s64()
+		if err != nil {
+			return err
+		}
+		c.push(I64)
+		c.emit(instr{op: op, a: uint64(v)})
+
+	case opI32WrapI64:
+		if err := c.popExpect(I64); err != nil {
+			return err
+		}
+		c.push(I32)
+		c.emit(instr{op: op})
+
+	case opI64ExtendI32S, opI64ExtendI32U:
+		if err := c.popExpect(I32); err != nil {
+			return err
+		}
+		c.push(I64)
+		c.emit(instr{op: op})
+
+	default:
+		return c.compileNumeric(op)
+	}
+	return nil
+}
+
+func (c *compiler) compileNumeric(op byte) error {
+	if mem, ok := memoryOps[op]; ok {
+		offset, err := c.memarg(mem.size)
+		if err != nil {
+			return err
+		}
+		if isStore(op) {
+			if err := c.popExpect(mem.typ); err != nil {
+				return err
+			}
+			if err := c.popExpect(I32); err != nil {
+				return err
+			}
+		} else {
+			if err := c.popExpect(I32); err != nil {
+				return err
+			}
+			c.push(mem.typ)
+		}
+		c.emit(instr{op: op, a: offset})
+		return nil
+	}
+
+	var operands []ValueType
+	var result ValueType
+	if t, ok := testOps[op]; ok {
+		operands, result = []ValueType{t}, I32
+	} else if t, ok := compareOps[op]; ok {
+		operands, result = []ValueType{t, t}, I32
+	} else if t, ok := unaryOps[op]; ok {
+		operands, result = []ValueType{t}, t
+	} else if t, ok := binaryOps[op]; ok {
+		operands, result = []ValueType{t, t}, t
+	} else {
+		return fmt.Errorf("wasm: unsupported instruction 0x%x", op)
+	}
+	if err := c.popTypes(operands); err != nil {
+		return err
+	}
+	c.push(result)
+	c.emit(instr{op: op})
+	return nil
+}
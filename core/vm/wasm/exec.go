@@ -0,0 +1,433 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package wasm
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+func b2u(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// unwind carries the values of a branch to its label and returns the new
+// top of the operand stack.
+func unwind(stack []uint64, base, sp int, b branch) int {
+	dst := base + b.height
+	copy(stack[dst:dst+b.arity], stack[sp-b.arity:sp])
+	return dst + b.arity
+}
+
+// address returns the effective address of a memory access of size bytes.
+func (inst *Instance) address(addr, offset, size uint64) (uint64, error) {
+	ea := uint64(uint32(addr)) + offset
+	if ea+size > uint64(len(inst.Memory)) {
+		return 0, ErrOutOfBounds
+	}
+	return ea, nil
+}
+
+// execute runs a compiled function on the operand stack. The validation
+// guarantees the operands of every instruction are on the stack, and call
+// reserved the maximum height of the function.
+func (inst *Instance) execute(fn *function, locals []uint64) error {
+	var (
+		stack = inst.stack
+		base  = inst.sp
+		sp    = base
+		code  = fn.code
+		pc    = 0
+	)
+	for {
+		in := &code[pc]
+		pc++
+		switch op := in.op; op {
+		case opGas:
+			if err := inst.UseGas(in.a); err != nil {
+				return err
+			}
+
+		case opUnreachable:
+			return ErrUnreachable
+
+		case opJump:
+			pc = in.br.target
+
+		case opJumpIfZero:
+			sp--
+			if uint32(stack[sp]) == 0 {
+				pc = in.br.target
+			}
+
+		case opBr:
+			sp = unwind(stack, base, sp, in.br)
+			pc = in.br.target
+
+		case opBrIf:
+			sp--
+			if uint32(stack[sp]) != 0 {
+				sp = unwind(stack, base, sp, in.br)
+				pc = in.br.target
+			}
+
+		case opBrTable:
+			sp--
+			i := uint64(uint32(stack[sp]))
+			if i >= uint64(len(in.table)-1) {
+				i = uint64(len(in.table) - 1)
+			}
+			b := in.table[i]
+			sp = unwind(stack, base, sp, b)
+			pc = b.target
+
+		case opReturn:
+			arity := in.br.arity
+			copy(stack[base:base+arity], stack[sp-arity:sp])
+			inst.sp = base + arity
+			return nil
+
+		case opCall, opCallIndirect:
+			index := uint32(in.a)
+			if op == opCallIndirect {
+				sp--
+				i := uint32(stack[sp])
+				if uint64(i) >= uint64(len(inst.table)) {
+					return ErrUndefinedElement
+				}
+				if index = inst.table[i]; index == nullElement {
+					return ErrUninitializedElement
+				}
+				ft, err := inst.Module.FuncType(index)
+				if err != nil || !ft.Equal(inst.Module.Types[in.a]) {
+					return ErrIndirectCallType
+				}
+			}
+			inst.sp = sp
+			if err := inst.call(index); err != nil {
+				return err
+			}
+			stack, sp = inst.stack, inst.sp
+
+		case opDrop:
+			sp--
+
+		case opSelect:
+			sp -= 2
+			if uint32(stack[sp+1]) == 0 {
+				stack[sp-1] = stack[sp]
+			}
+
+		case opLocalGet:
+			stack[sp] = locals[in.a]
+			sp++
+		case opLocalSet:
+			sp--
+			locals[in.a] = stack[sp]
+		case opLocalTee:
+			locals[in.a] = stack[sp-1]
+		case opGlobalGet:
+			stack[sp] = inst.globals[in.a]
+			sp++
+		case opGlobalSet:
+			sp--
+			inst.globals[in.a] = stack[sp]
+
+		case opMemorySize:
+			stack[sp] = uint64(len(inst.Memory) / PageSize)
+			sp++
+		case opMemoryGrow:
+			n := uint64(uint32(stack[sp-1]))
+			old := uint64(len(inst.Memory) / PageSize)
+			if old+n > uint64(inst.maxPages) {
+				stack[sp-1] = uint64(math.MaxUint32)
+				break
+			}
+			if err := inst.UseGas(n * GasMemoryPage); err != nil {
+				return err
+			}
+			inst.Memory = append(inst.Memory, make([]byte, n*PageSize)...)
+			stack[sp-1] = old
+
+		case opI32Const, opI64Const:
+			stack[sp] = in.a
+			sp++
+
+		case opI32WrapI64:
+			stack[sp-1] = uint64(uint32(stack[sp-1]))
+		case opI64ExtendI32S:
+			stack[sp-1] = uint64(int64(int32(stack[sp-1])))
+		case opI64ExtendI32U:
+			stack[sp-1] = uint64(uint32(stack[sp-1]))
+
+		default:
+			var err error
+			if _, ok := memoryOps[op]; ok {
+				sp, err = inst.executeMemory(in, stack, sp)
+			} else if op >= opI64Eqz && op <= opI64GeU || op >= opI64Clz && op <= opI64Rotr || op >= opI64Extend8S {
+				sp, err = executeI64(op, stack, sp)
+			} else {
+				sp, err = executeI32(op, stack, sp)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (inst *Instance) executeMemory(in *instr, stack []uint64, sp int) (int, error) {
+	mem := memoryOps[in.op]
+	if isStore(in.op) {
+		value := stack[sp-1]
+		ea, err := inst.address(stack[sp-2], in.a, mem.size)
+		if err != nil {
+			return sp, err
+		}
+		dst := inst.Memory[ea:]
+		switch mem.size {
+		case 1:
+			dst[0] = byte(value)
+		case 2:
+			binary.LittleEndian.PutUint16(dst, uint16(value))
+		case 4:
+			binary.LittleEndian.PutUint32(dst, uint32(value))
+		case 8:
+			binary.LittleEndian.PutUint64(dst, value)
+		}
+		return sp - 2, nil
+	}
+
+	ea, err := inst.address(stack[sp-1], in.a, mem.size)
+	if err != nil {
+		return sp, err
+	}
+	src := inst.Memory[ea:]
+	var value uint64
+	switch in.op {
+	case opI32Load, opI64Load32U:
+		value = uint64(binary.LittleEndian.Uint32(src))
+	case opI64Load:
+		value = binary.LittleEndian.Uint64(src)
+	case opI32Load8S:
+		value = uint64(uint32(int32(int8(src[0]))))
+	case opI32Load8U, opI64Load8U:
+		value = uint64(src[0])
+	case opI32Load16S:
+		value = uint64(uint32(int32(int16(binary.LittleEndian.Uint16(src)))))
+	case opI32Load16U, opI64Load16U:
+		value = uint64(binary.LittleEndian.Uint16(src))
+	case opI64Load8S:
+		value = uint64(int64(int8(src[0])))
+	case opI64Load16S:
+		value = uint64(int64(int16(binary.LittleEndian.Uint16(src))))
+	case opI64Load32S:
+		value = uint64(int64(int32(binary.LittleEndian.Uint32(src))))
+	}
+	stack[sp-1] = value
+	return sp, nil
+}
+
+func executeI32(op byte, stack []uint64, sp int) (int, error) {
+	switch op {
+	case opI32Eqz:
+		stack[sp-1] = b2u(uint32(stack[sp-1]) == 0)
+		return sp, nil
+	case opI32Clz:
+		stack[sp-1] = uint64(bits.LeadingZeros32(uint32(stack[sp-1])))
+		return sp, nil
+	case opI32Ctz:
+		stack[sp-1] = uint64(bits.TrailingZeros32(uint32(stack[sp-1])))
+		return sp, nil
+	case opI32Popcnt:
+		stack[sp-1] = uint64(bits.OnesCount32(uint32(stack[sp-1])))
+		return sp, nil
+	case opI32Extend8S:
+		stack[sp-1] = uint64(uint32(int32(int8(stack[sp-1]))))
+		return sp, nil
+	case opI32Extend16S:
+		stack[sp-1] = uint64(uint32(int32(int16(stack[sp-1]))))
+		return sp, nil
+	}
+
+	sp--
+	a, b := uint32(stack[sp-1]), uint32(stack[sp])
+	var r uint32
+	switch op {
+	case opI32Eq:
+		r = uint32(b2u(a == b))
+	case opI32Ne:
+		r = uint32(b2u(a != b))
+	case opI32LtS:
+		r = uint32(b2u(int32(a) < int32(b)))
+	case opI32LtU:
+		r = uint32(b2u(a < b))
+	case opI32GtS:
+		r = uint32(b2u(int32(a) > int32(b)))
+	case opI32GtU:
+		r = uint32(b2u(a > b))
+	case opI32LeS:
+		r = uint32(b2u(int32(a) <= int32(b)))
+	case opI32LeU:
+		r = uint32(b2u(a <= b))
+	case opI32GeS:
+		r = uint32(b2u(int32(a) >= int32(b)))
+	case opI32GeU:
+		r = uint32(b2u(a >= b))
+	case opI32Add:
+		r = a + b
+	case opI32Sub:
+		r = a - b
+	case opI32Mul:
+		r = a * b
+	case opI32DivS:
+		if b == 0 {
+			return sp, ErrDivideByZero
+		}
+		if int32(a) == math.MinInt32 && int32(b) == -1 {
+			return sp, ErrIntegerOverflow
+		}
+		r = uint32(int32(a) / int32(b))
+	case opI32DivU:
+		if b == 0 {
+			return sp, ErrDivideByZero
+		}
+		r = a / b
+	case opI32RemS:
+		if b == 0 {
+			return sp, ErrDivideByZero
+		}
+		if int32(b) != -1 {
+			r = uint32(int32(a) % int32(b))
+		}
+	case opI32RemU:
+		if b == 0 {
+			return sp, ErrDivideByZero
+		}
+		r = a % b
+	case opI32And:
+		r = a & b
+	case opI32Or:
+		r = a | b
+	case opI32Xor:
+		r = a ^ b
+	case opI32Shl:
+		r = a << (b & 31)
+	case opI32ShrS:
+		r = uint32(int32(a) >> (b & 31))
+	case opI32ShrU:
+		r = a >> (b & 31)
+	case opI32Rotl:
+		r = bits.RotateLeft32(a, int(b&31))
+	case opI32Rotr:
+		r = bits.RotateLeft32(a, -int(b&31))
+	}
+	stack[sp-1] = uint64(r)
+	return sp, nil
+}
+
+func executeI64(op byte, stack []uint64, sp int) (int, error) {
+	switch op {
+	case opI64Eqz:
+		stack[sp-1] = b2u(stack[sp-1] == 0)
+		return sp, nil
+	case opI64Clz:
+		stack[sp-1] = uint64(bits.LeadingZeros64(stack[sp-1]))
+		return sp, nil
+	case opI64Ctz:
+		stack[sp-1] = uint64(bits.TrailingZeros64(stack[sp-1]))
+		return sp, nil
+	case opI64Popcnt:
+		stack[sp-1] = uint64(bits.OnesCount64(stack[sp-1]))
+		return sp, nil
+	case opI64Extend8S:
+		stack[sp-1] = uint64(int64(int8(stack[sp-1])))
+		return sp, nil
+	case opI64Extend16S:
+		stack[sp-1] = uint64(int64(int16(stack[sp-1])))
+		return sp, nil
+	case opI64Extend32S:
+		stack[sp-1] = uint64(int64(int32(stack[sp-1])))
+		return sp, nil
+	}
+
+	sp--
+	a, b := stack[sp-1], stack[sp]
+	var r uint64
+	switch op {
+	case opI64Eq:
+		r = b2u(a == b)
+	case opI64Ne:
+		r = b2u(a != b)
+	case opI64LtS:
+		r = b2u(int64(a) < int64(b))
+	case opI64LtU:
+		r = b2u(a < b)
+	case opI64GtS:
+		r = b2u(int64(a) > int64(b))
+	case opI64GtU:
+		r = b2u(a > b)
+	case opI64LeS:
+		r = b2u(int64(a) <= int64(b))
+	case opI64LeU:
+		r = b2u(a <= b)
+	case opI64GeS:
+		r = b2u(int64(a) >= int64(b))
+	case opI64GeU:
+		r = b2u(a >= b)
+	case opI64Add:
+		r = a + b
+	case opI64Sub:
+		r = a - b
+	case opI64Mul:
+		r = a * b
+	case opI64DivS:
+		if b == 0 {
+			return sp, ErrDivideByZero
+		}
+		if int64(a) == math.MinInt64 && int64(b) == -1 {
+			return sp, ErrIntegerOverflow
+		}
+		r = uint64(int64(a) / int64(b))
+	case opI64DivU:
+		if b == 0 {
+			return sp, ErrDivideByZero
+		}
+		r = a / b
+	case opI64RemS:
+		if b == 0 {
+			return sp, ErrDivideByZero
+		}
+		if int64(b) != -1 {
+			r = uint64(int64(a) % int64(b))
+		}
+	case opI64RemU:
+		if b == 0 {
+			return sp, ErrDivideByZero
+		}
+		r = a % b
+	case opI64And:
+		r = a & b
+	case opI64Or:
+		r = a | b
+	case opI64Xor:
+		r = a ^ b
+	case opI64Shl:
+		r = a << (b & 63)
+	case opI64ShrS:
+		r = uint64(int64(a) >> (b & 63))
+	case opI64ShrU:
+		r = a >> (b & 63)
+	case opI64Rotl:
+		r = bits.RotateLeft64(a, int(b&63))
+	case opI64Rotr:
+		r = bits.RotateLeft64(a, -int(b&63))
+	}
+	stack[sp-1] = r
+	return sp, nil
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package wasm
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// MaxCallDepth limits the nested calls of a module.
+	MaxCallDepth = 1024
+	// MaxStackSize limits the values on the operand stack of an instance.
+	MaxStackSize = 1 << 16
+
+	nullElement = ^uint32(0)
+)
+
+var (
+	ErrOutOfGas             = errors.New("wasm: out of gas")
+	ErrUnreachable          = errors.New("wasm: unreachable executed")
+	ErrCallStackExhausted   = errors.New("wasm: call stack exhausted")
+	ErrStackOverflow        = errors.New("wasm: operand stack overflow")
+	ErrOutOfBounds          = errors.New("wasm: out of bounds memory access")
+	ErrDivideByZero         = errors.New("wasm: integer divide by zero")
+	ErrIntegerOverflow      = errors.New("wasm: integer overflow")
+	ErrUndefinedElement     = errors.New("wasm: undefined element")
+	ErrUninitializedElement = errors.New("wasm: uninitialized element")
+	ErrIndirectCallType     = errors.New("wasm: indirect call type mismatch")
+)
+
+// HostFunction is a function provided by the host to the modules. The
+// arguments are passed as raw values, i32 in the low 32 bits.
+type HostFunction struct {
+	Type FuncType
+	Fn   func(inst *Instance, args []uint64) (uint64, error)
+}
+
+// Imports maps the module and field names to the host functions.
+type Imports map[string]map[string]*HostFunction
+
+// Instance is an instantiated module with its own memory, globals and
+// table. An instance is not safe for concurrent use.
+type Instance struct {
+	Module *Module
+	Memory []byte
+	Gas    uint64 // gas left
+
+	hosts    []*HostFunction
+	globals  []uint64
+	table    []uint32
+	maxPages uint32
+	stack    []uint64
+	sp       int
+	depth    int
+}
+
+// Instantiate creates an instance of the module with gas, resolving the
+// imports, initializing the memory and the table and running the start
+// function. The initial memory is charged per page.
+func Instantiate(m *Module, imports Imports, gas uint64) (*Instance, error) {
+	inst := &Instance{Module: m, Gas: gas, maxPages: MaxMemoryPages}
+	inst.hosts = make([]*HostFunction, len(m.Imports))
+	for i, imp := range m.Imports {
+		host := imports[imp.Module][imp.Name]
+		if host == nil {
+			return nil, fmt.Errorf("wasm: unknown import %s.%s", imp.Module, imp.Name)
+		}
+		if !host.Type.Equal(m.Types[imp.Type]) {
+			return nil, fmt.Errorf("wasm: import %s.%s: signature %v, expected %v", imp.Module, imp.Name, m.Types[imp.Type], host.Type)
+		}
+		inst.hosts[i] = host
+	}
+
+	if m.Memory != nil {
+		if err := inst.UseGas(uint64(m.Memory.Min) * GasMemoryPage); err != nil {
+			return nil, err
+		}
+		inst.Memory = make([]byte, int(m.Memory.Min)*PageSize)
+		if m.Memory.HasMax && m.Memory.Max < inst.maxPages {
+			inst.maxPages = m.Memory.Max
+		}
+	}
+	inst.globals = make([]uint64, len(m.Globals))
+	for i, g := range m.Globals {
+		inst.globals[i] = g.Init
+	}
+	if m.Table != nil {
+		inst.table = make([]uint32, m.Table.Min)
+		for i := range inst.table {
+			inst.table[i] = nullElement
+		}
+	}
+	for _, elem := range m.Elements {
+		if uint64(elem.Offset)+uint64(len(elem.Funcs)) > uint64(len(inst.table)) {
+			return nil, errors.New("wasm: element segment does not fit")
+		}
+		copy(inst.table[elem.Offset:], elem.Funcs)
+	}
+	for _, data := range m.Data {
+		if uint64(data.Offset)+uint64(len(data.Init)) > uint64(len(inst.Memory)) {
+			return nil, errors.New("wasm: data segment does not fit")
+		}
+		copy(inst.Memory[data.Offset:], data.Init)
+	}
+
+	inst.stack = make([]uint64, 256)
+	if m.Start != nil {
+		if err := inst.call(*m.Start); err != nil {
+			return nil, err
+		}
+	}
+	return inst, nil
+}
+
+// Invoke calls the exported function name with args and returns its
+// results.
+func (inst *Instance) Invoke(name string, args ...uint64) ([]uint64, error) {
+	export, ok := inst.Module.Exports[name]
+	if !ok || export.Kind != ExternalFunction {
+		return nil, fmt.Errorf("wasm: function %q is not exported", name)
+	}
+	ft, err := inst.Module.FuncType(export.Index)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != len(ft.Params) {
+		return nil, fmt.Errorf("wasm: function %q takes %d arguments", name, len(ft.Params))
+	}
+	if err := inst.reserve(len(args)); err != nil {
+		return nil, err
+	}
+	base := inst.sp
+	for i, arg := range args {
+		inst.stack[inst.sp] = normalize(ft.Params[i], arg)
+		inst.sp++
+	}
+	if err := inst.call(export.Index); err != nil {
+		return nil, err
+	}
+	results := append([]uint64{}, inst.stack[base:inst.sp]...)
+	inst.sp = base
+	return results, nil
+}
+
+// UseGas charges gas, exhausting the gas left if it is insufficient.
+func (inst *Instance) UseGas(gas uint64) error {
+	if inst.Gas < gas {
+		inst.Gas = 0
+		return ErrOutOfGas
+	}
+	inst.Gas -= gas
+	return nil
+}
+
+// Read returns a copy of size bytes of the memory at offset.
+func (inst *Instance) Read(offset, size uint32) ([]byte, error) {
+	if uint64(offset)+uint64(size) > uint64(len(inst.Memory)) {
+		return nil, ErrOutOfBounds
+	}
+	return append([]byte{}, inst.Memory[offset:offset+size]...), nil
+}
+
+// Write copies data into the memory at offset.
+func (inst *Instance) Write(offset uint32, data []byte) error {
+	if uint64(offset)+uint64(len(data)) > uint64(len(inst.Memory)) {
+		return ErrOutOfBounds
+	}
+	copy(inst.Memory[offset:], data)
+	return nil
+}
+
+func normalize(t ValueType, v uint64) uint64 {
+	if t == I32 {
+		return uint64(uint32(v))
+	}
+	return v
+}
+
+// reserve grows the operand stack to hold n more values.
+func (inst *Instance) reserve(n int) error {
+	need := inst.sp + n
+	if need <= len(inst.stack) {
+		return nil
+	}
+	if need > MaxStackSize {
+		return ErrStackOverflow
+	}
+	size := 2 * len(inst.stack)
+	if size < need {
+		size = need
+	}
+	if size > MaxStackSize {
+		size = MaxStackSize
+	}
+	stack := make([]uint64, size)
+	copy(stack, inst.stack[:inst.sp])
+	inst.stack = stack
+	return nil
+}
+
+// call calls the function at index with the arguments on the operand
+// stack, leaving its results in place of the arguments.
+func (inst *Instance) call(index uint32) error {
+	if int(index) < len(inst.hosts) {
+		host := inst.hosts[index]
+		n := len(host.Type.Params)
+		args := append([]uint64{}, inst.stack[inst.sp-n:inst.sp]...)
+		inst.sp -= n
+		ret, err := host.Fn(inst, args)
+		if err != nil {
+			return err
+		}
+		if len(host.Type.Results) > 0 {
+			if err := inst.reserve(1); err != nil {
+				return err
+			}
+			inst.stack[inst.sp] = normalize(host.Type.Results[0], ret)
+			inst.sp++
+		}
+		return nil
+	}
+
+	if inst.depth >= MaxCallDepth {
+		return ErrCallStackExhausted
+	}
+	fn := inst.Module.compiled[int(index)-len(inst.hosts)]
+	n := len(fn.typ.Params)
+	locals := make([]uint64, fn.numLocals)
+	copy(locals, inst.stack[inst.sp-n:inst.sp])
+	inst.sp -= n
+	if err := inst.reserve(fn.maxStack); err != nil {
+		return err
+	}
+	inst.depth++
+	err := inst.execute(fn, locals)
+	inst.depth--
+	return err
+}
@@ -0,0 +1,696 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+// Package wasm implements a deterministic WebAssembly engine for contracts.
+//
+// The engine accepts the integer subset of the WebAssembly MVP plus the
+// sign-extension operators. Floating point types and instructions are
+// rejected at decoding since their results are not bit-exact across
+// platforms. Every function is compiled with gas meters injected at the
+// start of its basic blocks, so a module runs out of gas deterministically.
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ValueType is the type of a WebAssembly value.
+type ValueType byte
+
+const (
+	I32 ValueType = 0x7f
+	I64 ValueType = 0x7e
+
+	f32 ValueType = 0x7d
+	f64 ValueType = 0x7c
+
+	// unknown is the polymorphic type on the stack of an unreachable block.
+	unknown ValueType = 0
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case I32:
+		return "i32"
+	case I64:
+		return "i64"
+	case f32:
+		return "f32"
+	case f64:
+		return "f64"
+	}
+	return "unknown"
+}
+
+// External kinds of the imports and exports.
+const (
+	ExternalFunction byte = 0x00
+	ExternalTable    byte = 0x01
+	ExternalMemory   byte = 0x02
+	ExternalGlobal   byte = 0x03
+)
+
+const (
+	// PageSize is the size of a page of the linear memory.
+	PageSize = 65536
+	// MaxMemoryPages limits the linear memory of a contract to 4MB.
+	MaxMemoryPages = 64
+	// MaxTableSize limits the elements of the table of a contract.
+	MaxTableSize = 8192
+	// MaxLocals limits the locals declared by a function.
+	MaxLocals = 4096
+	// MaxFunctions limits the functions declared by a module.
+	MaxFunctions = 8192
+)
+
+var (
+	magic   = []byte{0x00, 0x61, 0x73, 0x6d}
+	version = []byte{0x01, 0x00, 0x00, 0x00}
+
+	ErrMagic = errors.New("wasm: invalid magic number")
+)
+
+// FuncType is the signature of a function. The MVP allows a single result.
+type FuncType struct {
+	Params  []ValueType
+	Results []ValueType
+}
+
+// Equal reports whether two signatures are identical.
+func (ft FuncType) Equal(other FuncType) bool {
+	return equalTypes(ft.Params, other.Params) && equalTypes(ft.Results, other.Results)
+}
+
+func (ft FuncType) String() string {
+	return fmt.Sprintf("%v -> %v", ft.Params, ft.Results)
+}
+
+func equalTypes(a, b []ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Import is an imported function, the only kind of import supported.
+type Import struct {
+	Module string
+	Name   string
+	Type   uint32
+}
+
+// Limits is the size range of a memory or a table.
+type Limits struct {
+	Min    uint32
+	Max    uint32
+	HasMax bool
+}
+
+// Global is a global variable defined by the module.
+type Global struct {
+	Type    ValueType
+	Mutable bool
+	Init    uint64
+}
+
+// Export is a definition exported by the module.
+type Export struct {
+	Kind  byte
+	Index uint32
+}
+
+// Element initializes a range of the table with function indices.
+type Element struct {
+	Offset uint32
+	Funcs  []uint32
+}
+
+// Data initializes a range of the linear memory.
+type Data struct {
+	Offset uint32
+	Init   []byte
+}
+
+// Code is the body of a defined function.
+type Code struct {
+	Locals []ValueType
+	Body   []byte
+}
+
+// Module is a decoded and validated WebAssembly module. A module is
+// immutable once decoded and may be instantiated any number of times.
+type Module struct {
+	Types    []FuncType
+	Imports  []Import
+	Funcs    []uint32 // type indices of the defined functions
+	Table    *Limits
+	Memory   *Limits
+	Globals  []Global
+	Exports  map[string]Export
+	Start    *uint32
+	Elements []Element
+	Codes    []Code
+	Data     []Data
+
+	compiled []*function
+}
+
+// NumFuncs returns the size of the function index space.
+func (m *Module) NumFuncs() int {
+	return len(m.Imports) + len(m.Funcs)
+}
+
+// FuncType returns the signature of the function at index.
+func (m *Module) FuncType(index uint32) (FuncType, error) {
+	if int(index) < len(m.Imports) {
+		return m.Types[m.Imports[index].Type], nil
+	}
+	if int(index) < m.NumFuncs() {
+		return m.Types[m.Funcs[int(index)-len(m.Imports)]], nil
+	}
+	return FuncType{}, fmt.Errorf("wasm: unknown function %d", index)
+}
+
+const (
+	sectionCustom   = 0
+	sectionType     = 1
+	sectionImport   = 2
+	sectionFunction = 3
+	sectionTable    = 4
+	sectionMemory   = 5
+	sectionGlobal   = 6
+	sectionExport   = 7
+	sectionStart    = 8
+	sectionElement  = 9
+	sectionCode     = 10
+	sectionData     = 11
+)
+
+// Decode decodes, validates and compiles a WebAssembly binary.
+func Decode(code []byte) (*Module, error) {
+	if len(code) < 8 || !bytes.Equal(code[:4], magic) {
+		return nil, ErrMagic
+	}
+	if !bytes.Equal(code[4:8], version) {
+		return nil, fmt.Errorf("wasm: unsupported version %x", code[4:8])
+	}
+	m := &Module{Exports: make(map[string]Export)}
+	r := newReader(code[8:])
+	var last byte
+	for !r.eof() {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := r.bytes(size)
+		if err != nil {
+			return nil, err
+		}
+		if id == sectionCustom {
+			continue
+		}
+		if id <= last {
+			return nil, fmt.Errorf("wasm: section %d out of order", id)
+		}
+		last = id
+		sr := newReader(payload)
+		if err := m.decodeSection(id, sr); err != nil {
+			return nil, err
+		}
+		if !sr.eof() {
+			return nil, fmt.Errorf("wasm: section %d size mismatch", id)
+		}
+	}
+	if len(m.Funcs) != len(m.Codes) {
+		return nil, errors.New("wasm: function and code section have inconsistent lengths")
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	if err := m.compile(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Module) decodeSection(id byte, r *reader) error {
+	switch id {
+	case sectionType:
+		return m.decodeTypes(r)
+	case sectionImport:
+		return m.decodeImports(r)
+	case sectionFunction:
+		return m.decodeFunctions(r)
+	case sectionTable:
+		return m.decodeTable(r)
+	case sectionMemory:
+		return m.decodeMemory(r)
+	case sectionGlobal:
+		return m.decodeGlobals(r)
+	case sectionExport:
+		return m.decodeExports(r)
+	case sectionStart:
+		index, err := r.u32()
+		if err != nil {
+			return err
+		}
+		m.Start = &index
+		return nil
+	case sectionElement:
+		return m.decodeElements(r)
+	case sectionCode:
+		return m.decodeCodes(r)
+	case sectionData:
+		return m.decodeData(r)
+	}
+	return fmt.Errorf("wasm: unsupported section %d", id)
+}
+
+// vec reads the length of a vector, rejecting lengths exceeding the
+// remaining bytes to prevent huge allocations.
+func vec(r *reader) (uint32, error) {
+	n, err := r.u32()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(n) > uint64(len(r.buf)-r.pos) {
+		return 0, errUnexpectedEOF
+	}
+	return n, nil
+}
+
+func readValueType(r *reader) (ValueType, error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch t := ValueType(b); t {
+	case I32, I64:
+		return t, nil
+	case f32, f64:
+		return 0, fmt.Errorf("wasm: floating point type %v is not supported", t)
+	}
+	return 0, fmt.Errorf("wasm: invalid value type 0x%x", b)
+}
+
+func readValueTypes(r *reader) ([]ValueType, error) {
+	n, err := vec(r)
+	if err != nil {
+		return nil, err
+	}
+	types := make([]ValueType, n)
+	for i := range types {
+		if types[i], err = readValueType(r); err != nil {
+			return nil, err
+		}
+	}
+	return types, nil
+}
+
+func (m *Module) decodeTypes(r *reader) error {
+	n, err := vec(r)
+	if err != nil {
+		return err
+	}
+	m.Types = make([]FuncType, n)
+	for i := range m.Types {
+		form, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if form != 0x60 {
+			return fmt.Errorf("wasm: invalid function type form 0x%x", form)
+		}
+		if m.Types[i].Params, err = readValueTypes(r); err != nil {
+			return err
+		}
+		if m.Types[i].Results, err = readValueTypes(r); err != nil {
+			return err
+		}
+		if len(m.Types[i].Results) > 1 {
+			return errors.New("wasm: multiple results are not supported")
+		}
+	}
+	return nil
+}
+
+func (m *Module) decodeImports(r *reader) error {
+	n, err := vec(r)
+	if err != nil {
+		return err
+	}
+	m.Imports = make([]Import, n)
+	for i := range m.Imports {
+		imp := &m.Imports[i]
+		if imp.Module, err = r.name(); err != nil {
+			return err
+		}
+		if imp.Name, err = r.name(); err != nil {
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if kind != ExternalFunction {
+			return fmt.Errorf("wasm: import %s.%s: only functions can be imported", imp.Module, imp.Name)
+		}
+		if imp.Type, err = r.u32(); err != nil {
+			return err
+		}
+		if int(imp.Type) >= len(m.Types) {
+			return fmt.Errorf("wasm: import %s.%s: unknown type %d", imp.Module, imp.Name, imp.Type)
+		}
+	}
+	return nil
+}
+
+func (m *Module) decodeFunctions(r *reader) error {
+	n, err := vec(r)
+	if err != nil {
+		return err
+	}
+	if n > MaxFunctions {
+		return errors.New("wasm: too many functions")
+	}
+	m.Funcs = make([]uint32, n)
+	for i := range m.Funcs {
+		if m.Funcs[i], err = r.u32(); err != nil {
+			return err
+		}
+		if int(m.Funcs[i]) >= len(m.Types) {
+			return fmt.Errorf("wasm: function %d: unknown type %d", i, m.Funcs[i])
+		}
+	}
+	return nil
+}
+
+func readLimits(r *reader, max uint32) (*Limits, error) {
+	flag, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	limits := new(Limits)
+	if limits.Min, err = r.u32(); err != nil {
+		return nil, err
+	}
+	switch flag {
+	case 0x00:
+	case 0x01:
+		if limits.Max, err = r.u32(); err != nil {
+			return nil, err
+		}
+		if limits.Max < limits.Min {
+			return nil, errors.New("wasm: size minimum must not be greater than maximum")
+		}
+		limits.HasMax = true
+	default:
+		return nil, fmt.Errorf("wasm: invalid limits flag 0x%x", flag)
+	}
+	if limits.Min > max {
+		return nil, fmt.Errorf("wasm: initial size %d exceeds the limit %d", limits.Min, max)
+	}
+	return limits, nil
+}
+
+func (m *Module) decodeTable(r *reader) error {
+	n, err := vec(r)
+	if err != nil {
+		return err
+	}
+	if n > 1 {
+		return errors.New("wasm: multiple tables")
+	}
+	if n == 1 {
+		elemType, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if elemType != 0x70 {
+			return fmt.Errorf("wasm: invalid table element type 0x%x", elemType)
+		}
+		if m.Table, err = readLimits(r, MaxTableSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Module) decodeMemory(r *reader) error {
+	n, err := vec(r)
+	if err != nil {
+		return err
+	}
+	if n > 1 {
+		return errors.New("wasm: multiple memories")
+	}
+	if n == 1 {
+		if m.Memory, err = readLimits(r, MaxMemoryPages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readConstExpr reads an initializer expression, a single constant
+// instruction of type want followed by end.
+func readConstExpr(r *reader, want ValueType) (uint64, error) {
+	op, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	var value uint64
+	switch {
+	case op == opI32Const && want == I32:
+		v, err := r.s32()
+		if err != nil {
+			return 0, err
+		}
+		value = uint64(uint32(v))
+	case op == opI64Const && want == I64:
+		v, err := r.s64()
+		if err != nil {
+			return 0, err
+		}
+		value = uint64(v)
+	default:
+		return 0, fmt.Errorf("wasm: unsupported initializer expression 0x%x of type %v", op, want)
+	}
+	if end, err := r.byte(); err != nil || end != opEnd {
+		return 0, errors.New("wasm: initializer expression not terminated")
+	}
+	return value, nil
+}
+
+func (m *Module) decodeGlobals(r *reader) error {
+	n, err := vec(r)
+	if err != nil {
+		return err
+	}
+	m.Globals = make([]Global, n)
+	for i := range m.Globals {
+		g := &m.Globals[i]
+		if g.Type, err = readValueType(r); err != nil {
+			return err
+		}
+		mut, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if mut > 1 {
+			return fmt.Errorf("wasm: invalid global mutability 0x%x", mut)
+		}
+		g.Mutable = mut == 1
+		if g.Init, err = readConstExpr(r, g.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Module) decodeExports(r *reader) error {
+	n, err := vec(r)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		name, err := r.name()
+		if err != nil {
+			return err
+		}
+		if _, ok := m.Exports[name]; ok {
+			return fmt.Errorf("wasm: duplicate export %q", name)
+		}
+		var export Export
+		if export.Kind, err = r.byte(); err != nil {
+			return err
+		}
+		if export.Index, err = r.u32(); err != nil {
+			return err
+		}
+		m.Exports[name] = export
+	}
+	return nil
+}
+
+func (m *Module) decodeElements(r *reader) error {
+	n, err := vec(r)
+	if err != nil {
+		return err
+	}
+	m.Elements = make([]Element, n)
+	for i := range m.Elements {
+		table, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if table != 0 {
+			return fmt.Errorf("wasm: element segment %d: unknown table %d", i, table)
+		}
+		offset, err := readConstExpr(r, I32)
+		if err != nil {
+			return err
+		}
+		m.Elements[i].Offset = uint32(offset)
+		count, err := vec(r)
+		if err != nil {
+			return err
+		}
+		m.Elements[i].Funcs = make([]uint32, count)
+		for j := range m.Elements[i].Funcs {
+			if m.Elements[i].Funcs[j], err = r.u32(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Module) decodeCodes(r *reader) error {
+	n, err := vec(r)
+	if err != nil {
+		return err
+	}
+	m.Codes = make([]Code, n)
+	for i := range m.Codes {
+		size, err := r.u32()
+		if err != nil {
+			return err
+		}
+		body, err := r.bytes(size)
+		if err != nil {
+			return err
+		}
+		br := newReader(body)
+		groups, err := vec(br)
+		if err != nil {
+			return err
+		}
+		var locals []ValueType
+		for j := uint32(0); j < groups; j++ {
+			count, err := br.u32()
+			if err != nil {
+				return err
+			}
+			if uint64(len(locals))+uint64(count) > MaxLocals {
+				return fmt.Errorf("wasm: function %d: too many locals", i)
+			}
+			t, err := readValueType(br)
+			if err != nil {
+				return err
+			}
+			for k := uint32(0); k < count; k++ {
+				locals = append(locals, t)
+			}
+		}
+		m.Codes[i] = Code{Locals: locals, Body: body[br.pos:]}
+	}
+	return nil
+}
+
+func (m *Module) decodeData(r *reader) error {
+	n, err := vec(r)
+	if err != nil {
+		return err
+	}
+	m.Data = make([]Data, n)
+	for i := range m.Data {
+		mem, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if mem != 0 {
+			return fmt.Errorf("wasm: data segment %d: unknown memory %d", i, mem)
+		}
+		offset, err := readConstExpr(r, I32)
+		if err != nil {
+			return err
+		}
+		size, err := vec(r)
+		if err != nil {
+			return err
+		}
+		init, err := r.bytes(size)
+		if err != nil {
+			return err
+		}
+		m.Data[i] = Data{Offset: uint32(offset), Init: init}
+	}
+	return nil
+}
+
+// validate checks the references between the sections, the function
+// bodies are validated when compiled.
+func (m *Module) validate() error {
+	for name, export := range m.Exports {
+		var ok bool
+		switch export.Kind {
+		case ExternalFunction:
+			ok = int(export.Index) < m.NumFuncs()
+		case ExternalTable:
+			ok = export.Index == 0 && m.Table != nil
+		case ExternalMemory:
+			ok = export.Index == 0 && m.Memory != nil
+		case ExternalGlobal:
+			ok = int(export.Index) < len(m.Globals)
+		}
+		if !ok {
+			return fmt.Errorf("wasm: export %q refers to an unknown definition", name)
+		}
+	}
+	if m.Start != nil {
+		ft, err := m.FuncType(*m.Start)
+		if err != nil {
+			return err
+		}
+		if len(ft.Params) != 0 || len(ft.Results) != 0 {
+			return errors.New("wasm: start function must not have parameters or results")
+		}
+	}
+	if len(m.Elements) > 0 && m.Table == nil {
+		return errors.New("wasm: element segment without table")
+	}
+	for _, elem := range m.Elements {
+		for _, index := range elem.Funcs {
+			if int(index) >= m.NumFuncs() {
+				return fmt.Errorf("wasm: element refers to unknown function %d", index)
+			}
+		}
+	}
+	if len(m.Data) > 0 && m.Memory == nil {
+		return errors.New("wasm: data segment without memory")
+	}
+	return nil
+}
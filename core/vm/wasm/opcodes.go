@@ -0,0 +1,195 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package wasm
+
+// WebAssembly opcodes of the supported instructions.
+const (
+	opUnreachable  byte = 0x00
+	opNop          byte = 0x01
+	opBlock        byte = 0x02
+	opLoop         byte = 0x03
+	opIf           byte = 0x04
+	opElse         byte = 0x05
+	opEnd          byte = 0x0b
+	opBr           byte = 0x0c
+	opBrIf         byte = 0x0d
+	opBrTable      byte = 0x0e
+	opReturn       byte = 0x0f
+	opCall         byte = 0x10
+	opCallIndirect byte = 0x11
+
+	opDrop   byte = 0x1a
+	opSelect byte = 0x1b
+
+	opLocalGet  byte = 0x20
+	opLocalSet  byte = 0x21
+	opLocalTee  byte = 0x22
+	opGlobalGet byte = 0x23
+	opGlobalSet byte = 0x24
+
+	opI32Load    byte = 0x28
+	opI64Load    byte = 0x29
+	opI32Load8S  byte = 0x2c
+	opI32Load8U  byte = 0x2d
+	opI32Load16S byte = 0x2e
+	opI32Load16U byte = 0x2f
+	opI64Load8S  byte = 0x30
+	opI64Load8U  byte = 0x31
+	opI64Load16S byte = 0x32
+	opI64Load16U byte = 0x33
+	opI64Load32S byte = 0x34
+	opI64Load32U byte = 0x35
+	opI32Store   byte = 0x36
+	opI64Store   byte = 0x37
+	opI32Store8  byte = 0x3a
+	opI32Store16 byte = 0x3b
+	opI64Store8  byte = 0x3c
+	opI64Store16 byte = 0x3d
+	opI64Store32 byte = 0x3e
+	opMemorySize byte = 0x3f
+	opMemoryGrow byte = 0x40
+
+	opI32Const byte = 0x41
+	opI64Const byte = 0x42
+
+	opI32Eqz byte = 0x45
+	opI32Eq  byte = 0x46
+	opI32Ne  byte = 0x47
+	opI32LtS byte = 0x48
+	opI32LtU byte = 0x49
+	opI32GtS byte = 0x4a
+	opI32GtU byte = 0x4b
+	opI32LeS byte = 0x4c
+	opI32LeU byte = 0x4d
+	opI32GeS byte = 0x4e
+	opI32GeU byte = 0x4f
+
+	opI64Eqz byte = 0x50
+	opI64Eq  byte = 0x51
+	opI64Ne  byte = 0x52
+	opI64LtS byte = 0x53
+	opI64LtU byte = 0x54
+	opI64GtS byte = 0x55
+	opI64GtU byte = 0x56
+	opI64LeS byte = 0x57
+	opI64LeU byte = 0x58
+	opI64GeS byte = 0x59
+	opI64GeU byte = 0x5a
+
+	opI32Clz    byte = 0x67
+	opI32Ctz    byte = 0x68
+	opI32Popcnt byte = 0x69
+	opI32Add    byte = 0x6a
+	opI32Sub    byte = 0x6b
+	opI32Mul    byte = 0x6c
+	opI32DivS   byte = 0x6d
+	opI32DivU   byte = 0x6e
+	opI32RemS   byte = 0x6f
+	opI32RemU   byte = 0x70
+	opI32And    byte = 0x71
+	opI32Or     byte = 0x72
+	opI32Xor    byte = 0x73
+	opI32Shl    byte = 0x74
+	opI32ShrS   byte = 0x75
+	opI32ShrU   byte = 0x76
+	opI32Rotl   byte = 0x77
+	opI32Rotr   byte = 0x78
+
+	opI64Clz    byte = 0x79
+	opI64Ctz    byte = 0x7a
+	opI64Popcnt byte = 0x7b
+	opI64Add    byte = 0x7c
+	opI64Sub    byte = 0x7d
+	opI64Mul    byte = 0x7e
+	opI64DivS   byte = 0x7f
+	opI64DivU   byte = 0x80
+	opI64RemS   byte = 0x81
+	opI64RemU   byte = 0x82
+	opI64And    byte = 0x83
+	opI64Or     byte = 0x84
+	opI64Xor    byte = 0x85
+	opI64Shl    byte = 0x86
+	opI64ShrS   byte = 0x87
+	opI64ShrU   byte = 0x88
+	opI64Rotl   byte = 0x89
+	opI64Rotr   byte = 0x8a
+
+	opI32WrapI64    byte = 0xa7
+	opI64ExtendI32S byte = 0xac
+	opI64ExtendI32U byte = 0xad
+
+	opI32Extend8S  byte = 0xc0
+	opI32Extend16S byte = 0xc1
+	opI64Extend8S  byte = 0xc2
+	opI64Extend16S byte = 0xc3
+	opI64Extend32S byte = 0xc4
+)
+
+// Internal opcodes emitted by the compiler, unused by WebAssembly.
+const (
+	opJump       byte = 0xe0 // unconditional jump, skips the else branch
+	opJumpIfZero byte = 0xe1 // conditional jump of if
+	opGas        byte = 0xe2 // injected gas meter of a basic block
+)
+
+// Gas costs of the instructions.
+const (
+	GasDefault    uint64 = 1    // most instructions
+	GasArithmetic uint64 = 3    // multiplication, division and remainder
+	GasMemory     uint64 = 3    // loads and stores
+	GasCall       uint64 = 10   // calls
+	GasMemoryPage uint64 = 1024 // each page of the linear memory, initial or grown
+)
+
+func instrGas(op byte) uint64 {
+	switch {
+	case op == opCall || op == opCallIndirect:
+		return GasCall
+	case op >= opI32Load && op <= opMemoryGrow:
+		return GasMemory
+	case op >= opI32Mul && op <= opI32RemU, op >= opI64Mul && op <= opI64RemU:
+		return GasArithmetic
+	}
+	return GasDefault
+}
+
+// The operand types of the numeric instructions, the tests and comparisons
+// result in an i32 and the others in their operand type.
+var (
+	testOps = map[byte]ValueType{
+		opI32Eqz: I32, opI64Eqz: I64,
+	}
+	compareOps = map[byte]ValueType{
+		opI32Eq: I32, opI32Ne: I32, opI32LtS: I32, opI32LtU: I32, opI32GtS: I32, opI32GtU: I32,
+		opI32LeS: I32, opI32LeU: I32, opI32GeS: I32, opI32GeU: I32,
+		opI64Eq: I64, opI64Ne: I64, opI64LtS: I64, opI64LtU: I64, opI64GtS: I64, opI64GtU: I64,
+		opI64LeS: I64, opI64LeU: I64, opI64GeS: I64, opI64GeU: I64,
+	}
+	unaryOps = map[byte]ValueType{
+		opI32Clz: I32, opI32Ctz: I32, opI32Popcnt: I32, opI32Extend8S: I32, opI32Extend16S: I32,
+		opI64Clz: I64, opI64Ctz: I64, opI64Popcnt: I64, opI64Extend8S: I64, opI64Extend16S: I64, opI64Extend32S: I64,
+	}
+	binaryOps = map[byte]ValueType{
+		opI32Add: I32, opI32Sub: I32, opI32Mul: I32, opI32DivS: I32, opI32DivU: I32, opI32RemS: I32, opI32RemU: I32,
+		opI32And: I32, opI32Or: I32, opI32Xor: I32, opI32Shl: I32, opI32ShrS: I32, opI32ShrU: I32, opI32Rotl: I32, opI32Rotr: I32,
+		opI64Add: I64, opI64Sub: I64, opI64Mul: I64, opI64DivS: I64, opI64DivU: I64, opI64RemS: I64, opI64RemU: I64,
+		opI64And: I64, opI64Or: I64, opI64Xor: I64, opI64Shl: I64, opI64ShrS: I64, opI64ShrU: I64, opI64Rotl: I64, opI64Rotr: I64,
+	}
+	// memoryOps maps the loads and stores to the value type and access size
+	memoryOps = map[byte]struct {
+		typ  ValueType
+		size uint64
+	}{
+		opI32Load: {I32, 4}, opI64Load: {I64, 8},
+		opI32Load8S: {I32, 1}, opI32Load8U: {I32, 1}, opI32Load16S: {I32, 2}, opI32Load16U: {I32, 2},
+		opI64Load8S: {I64, 1}, opI64Load8U: {I64, 1}, opI64Load16S: {I64, 2}, opI64Load16U: {I64, 2},
+		opI64Load32S: {I64, 4}, opI64Load32U: {I64, 4},
+		opI32Store: {I32, 4}, opI64Store: {I64, 8}, opI32Store8: {I32, 1}, opI32Store16: {I32, 2},
+		opI64Store8: {I64, 1}, opI64Store16: {I64, 2}, opI64Store32: {I64, 4},
+	}
+)
+
+func isStore(op byte) bool {
+	return op >= opI32Store && op <= opI64Store32
+}
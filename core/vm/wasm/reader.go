@@ -0,0 +1,140 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package wasm
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+var (
+	errUnexpectedEOF = errors.New("wasm: unexpected end of binary")
+	errBadLEB128     = errors.New("wasm: malformed LEB128 integer")
+	errBadName       = errors.New("wasm: malformed UTF-8 name")
+)
+
+// reader decodes the primitive values of the WebAssembly binary format.
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func newReader(buf []byte) *reader {
+	return &reader{buf: buf}
+}
+
+func (r *reader) eof() bool {
+	return r.pos >= len(r.buf)
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errUnexpectedEOF
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) bytes(n uint32) ([]byte, error) {
+	if uint64(n) > uint64(len(r.buf)-r.pos) {
+		return nil, errUnexpectedEOF
+	}
+	b := r.buf[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+func (r *reader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(n)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(b) {
+		return "", errBadName
+	}
+	return string(b), nil
+}
+
+func (r *reader) u32() (uint32, error) {
+	v, err := r.uleb(32)
+	return uint32(v), err
+}
+
+func (r *reader) s32() (int32, error) {
+	v, err := r.sleb(32)
+	return int32(v), err
+}
+
+func (r *reader) s64() (int64, error) {
+	return r.sleb(64)
+}
+
+// uleb reads an unsigned LEB128 integer of at most maxBits bits.
+func (r *reader) uleb(maxBits uint) (uint64, error) {
+	var (
+		result uint64
+		shift  uint
+	)
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if shift >= maxBits || (shift+7 > maxBits && uint64(b&0x7f)>>(maxBits-shift) != 0) {
+			return 0, errBadLEB128
+		}
+		result |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result, nil
+		}
+		shift += 7
+	}
+}
+
+// sleb reads a signed LEB128 integer of at most maxBits bits.
+func (r *reader) sleb(maxBits uint) (int64, error) {
+	var (
+		result int64
+		shift  uint
+		b      byte
+		err    error
+	)
+	for n := uint(0); ; n++ {
+		if n == (maxBits+6)/7 {
+			return 0, errBadLEB128
+		}
+		if b, err = r.byte(); err != nil {
+			return 0, err
+		}
+		if shift < 64 {
+			result |= int64(b&0x7f) << shift
+		}
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if shift < 64 && b&0x40 != 0 {
+		result |= -1 << shift
+	}
+	if shift > maxBits {
+		// the unused bits of the last byte must be the sign extension
+		if maxBits < 64 {
+			if result < -(1<<(maxBits-1)) || result >= 1<<(maxBits-1) {
+				return 0, errBadLEB128
+			}
+		} else if shift > 64 {
+			last := int8(b<<1) >> 1 // sign extended payload of the last byte
+			if last != 0 && last != -1 {
+				return 0, errBadLEB128
+			}
+		}
+	}
+	return result, nil
+}
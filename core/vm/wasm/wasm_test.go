@@ -0,0 +1,255 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package wasm
+
+import (
+	"bytes"
+	"testing"
+)
+
+func leb(v uint32) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			b = append(b, c|0x80)
+			continue
+		}
+		return append(b, c)
+	}
+}
+
+func str(s string) []byte {
+	return append(leb(uint32(len(s))), s...)
+}
+
+func vector(items ...[]byte) []byte {
+	b := leb(uint32(len(items)))
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+func section(id byte, payload []byte) []byte {
+	return append(append([]byte{id}, leb(uint32(len(payload)))...), payload...)
+}
+
+func body(locals []byte, code ...byte) []byte {
+	b := append(locals, code...)
+	return append(leb(uint32(len(b))), b...)
+}
+
+func testModule() []byte {
+	types := vector(
+		[]byte{0x60, 0x01, 0x7e, 0x01, 0x7e},       // 0: (i64) -> i64
+		[]byte{0x60, 0x01, 0x7f, 0x01, 0x7f},       // 1: (i32) -> i32
+		[]byte{0x60, 0x00, 0x00},                   // 2: () -> ()
+		[]byte{0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7f}, // 3: (i32, i32) -> i32
+	)
+	imports := vector(append(append(str("env"), str("double")...), 0x00, 0x01))
+	funcs := vector([]byte{0}, []byte{1}, []byte{2}, []byte{3}, []byte{1})
+	memory := vector([]byte{0x00, 0x01})
+	exports := vector(
+		append(str("fac"), 0x00, 0x01),
+		append(str("fib"), 0x00, 0x02),
+		append(str("store"), 0x00, 0x03),
+		append(str("div"), 0x00, 0x04),
+		append(str("switch"), 0x00, 0x05),
+		append(str("memory"), 0x02, 0x00),
+	)
+	codes := vector(
+		// fac: iterative factorial
+		body([]byte{0x01, 0x01, 0x7e},
+			0x42, 0x01, 0x21, 0x01,
+			0x02, 0x40, 0x03, 0x40,
+			0x20, 0x00, 0x50, 0x0d, 0x01,
+			0x20, 0x01, 0x20, 0x00, 0x7e, 0x21, 0x01,
+			0x20, 0x00, 0x42, 0x01, 0x7d, 0x21, 0x00,
+			0x0c, 0x00, 0x0b, 0x0b,
+			0x20, 0x01, 0x0b),
+		// fib: recursive fibonacci
+		body([]byte{0x00},
+			0x20, 0x00, 0x41, 0x02, 0x49, 0x04, 0x7f,
+			0x20, 0x00,
+			0x05,
+			0x20, 0x00, 0x41, 0x01, 0x6b, 0x10, 0x02,
+			0x20, 0x00, 0x41, 0x02, 0x6b, 0x10, 0x02, 0x6a,
+			0x0b, 0x0b),
+		// store: memory[8] = double(21)
+		body([]byte{0x00},
+			0x41, 0x08, 0x41, 0x15, 0x10, 0x00, 0x36, 0x02, 0x00, 0x0b),
+		// div: i32.div_s
+		body([]byte{0x00}, 0x20, 0x00, 0x20, 0x01, 0x6d, 0x0b),
+		// switch: br_table returning 10, 20 or 30
+		body([]byte{0x00},
+			0x02, 0x40, 0x02, 0x40, 0x02, 0x40,
+			0x20, 0x00, 0x0e, 0x02, 0x00, 0x01, 0x02, 0x0b,
+			0x41, 0x0a, 0x0f, 0x0b,
+			0x41, 0x14, 0x0f, 0x0b,
+			0x41, 0x1e, 0x0b),
+	)
+	data := vector(append([]byte{0x00, 0x41, 0x00, 0x0b}, str("hi")...))
+
+	code := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	code = append(code, section(sectionType, types)...)
+	code = append(code, section(sectionImport, imports)...)
+	code = append(code, section(sectionFunction, funcs)...)
+	code = append(code, section(sectionMemory, memory)...)
+	code = append(code, section(sectionExport, exports)...)
+	code = append(code, section(sectionCode, codes)...)
+	code = append(code, section(sectionData, data)...)
+	return code
+}
+
+func testImports() Imports {
+	return Imports{"env": {"double": {
+		Type: FuncType{Params: []ValueType{I32}, Results: []ValueType{I32}},
+		Fn: func(inst *Instance, args []uint64) (uint64, error) {
+			return args[0] * 2, nil
+		},
+	}}}
+}
+
+func testInstance(t *testing.T, gas uint64) *Instance {
+	m, err := Decode(testModule())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	inst, err := Instantiate(m, testImports(), gas)
+	if err != nil {
+		t.Fatalf("instantiate: %v", err)
+	}
+	return inst
+}
+
+func TestExecute(t *testing.T) {
+	inst := testInstance(t, 1000000)
+	if !bytes.Equal(inst.Memory[:2], []byte("hi")) {
+		t.Errorf("data segment not initialized: %x", inst.Memory[:2])
+	}
+	tests := []struct {
+		name string
+		args []uint64
+		want uint64
+	}{
+		{"fac", []uint64{0}, 1},
+		{"fac", []uint64{20}, 2432902008176640000},
+		{"fib", []uint64{10}, 55},
+		{"div", []uint64{uint64(uint32(0xfffffff9)), 2}, uint64(uint32(0xfffffffd))},
+		{"switch", []uint64{0}, 10},
+		{"switch", []uint64{1}, 20},
+		{"switch", []uint64{7}, 30},
+	}
+	for _, test := range tests {
+		results, err := inst.Invoke(test.name, test.args...)
+		if err != nil {
+			t.Fatalf("%s%v: %v", test.name, test.args, err)
+		}
+		if len(results) != 1 || results[0] != test.want {
+			t.Errorf("%s%v = %v, want %d", test.name, test.args, results, test.want)
+		}
+	}
+	if _, err := inst.Invoke("store"); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if !bytes.Equal(inst.Memory[8:12], []byte{42, 0, 0, 0}) {
+		t.Errorf("store wrote %x", inst.Memory[8:12])
+	}
+}
+
+func TestTraps(t *testing.T) {
+	inst := testInstance(t, 1000000)
+	if _, err := inst.Invoke("div", 1, 0); err != ErrDivideByZero {
+		t.Errorf("div by zero: %v", err)
+	}
+	if _, err := inst.Invoke("div", 0x80000000, uint64(uint32(0xffffffff))); err != ErrIntegerOverflow {
+		t.Errorf("div overflow: %v", err)
+	}
+}
+
+func TestMetering(t *testing.T) {
+	inst := testInstance(t, 1000000)
+	gas := inst.Gas
+	if _, err := inst.Invoke("fac", 5); err != nil {
+		t.Fatal(err)
+	}
+	used5 := gas - inst.Gas
+	gas = inst.Gas
+	if _, err := inst.Invoke("fac", 10); err != nil {
+		t.Fatal(err)
+	}
+	used10 := gas - inst.Gas
+	// every iteration of the loop charges the same gas
+	if used5 == 0 || used10 <= used5 || (used10-used5)%5 != 0 {
+		t.Errorf("gas used fac(5) %d, fac(10) %d", used5, used10)
+	}
+
+	inst = testInstance(t, GasMemoryPage+50)
+	if _, err := inst.Invoke("fac", 1000); err != ErrOutOfGas {
+		t.Errorf("expected out of gas, got %v", err)
+	}
+	if inst.Gas != 0 {
+		t.Errorf("gas left after out of gas: %d", inst.Gas)
+	}
+	if _, err := Instantiate(inst.Module, testImports(), GasMemoryPage-1); err != ErrOutOfGas {
+		t.Errorf("initial memory not charged: %v", err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	valid := testModule()
+	tests := map[string][]byte{
+		"magic":     append([]byte{0x00, 0x61, 0x73, 0x6e}, valid[4:]...),
+		"version":   append(append([]byte{}, valid[:4]...), append([]byte{0x02, 0x00, 0x00, 0x00}, valid[8:]...)...),
+		"truncated": valid[:len(valid)-3],
+		"float": append(append([]byte{}, valid[:8]...),
+			section(sectionType, vector([]byte{0x60, 0x01, 0x7d, 0x00}))...),
+		"stack": append(append([]byte{}, valid[:8]...), append(
+			section(sectionType, vector([]byte{0x60, 0x00, 0x01, 0x7f})),
+			append(section(sectionFunction, vector([]byte{0})),
+				section(sectionCode, vector(body([]byte{0x00}, 0x6a, 0x0b)))...)...)...),
+	}
+	for name, code := range tests {
+		if _, err := Decode(code); err == nil {
+			t.Errorf("%s: invalid module decoded", name)
+		}
+	}
+	if _, err := Instantiate(mustDecode(t, valid), Imports{}, 1000000); err == nil {
+		t.Errorf("missing import resolved")
+	}
+}
+
+func mustDecode(t *testing.T, code []byte) *Module {
+	m, err := Decode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestLEB128(t *testing.T) {
+	tests := []struct {
+		input []byte
+		bits  uint
+		want  int64
+		ok    bool
+	}{
+		{[]byte{0x7f}, 32, -1, true},
+		{[]byte{0x80, 0x7f}, 32, -128, true},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0x07}, 32, 0x7fffffff, true},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x78}, 32, -0x80000000, true},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0x0f}, 32, 0, false},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, 32, 0, false},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x7f}, 64, -1 << 63, true},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x3f}, 64, 0, false},
+	}
+	for i, test := range tests {
+		v, err := newReader(test.input).sleb(test.bits)
+		if (err == nil) != test.ok || (test.ok && v != test.want) {
+			t.Errorf("test %d: got %d, %v", i, v, err)
+		}
+	}
+}
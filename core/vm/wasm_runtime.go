@@ -0,0 +1,494 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm/wasm"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// MaxWasmCodeSize is the maximum code size of a WASM contract, WASM
+// binaries are larger than the EVM byte code of the same contract.
+const MaxWasmCodeSize = 128 * 1024
+
+// wasmHostModule is the module name of the host functions, the Ethereum
+// Environment Interface of eWASM.
+const wasmHostModule = "ethereum"
+
+var (
+	wasmMagic = []byte{0x00, 0x61, 0x73, 0x6d}
+
+	errWasmFinish        = errors.New("wasm: finish")
+	errWasmRevert        = errors.New("wasm: revert")
+	errInvalidWasmCode   = errors.New("wasm: invalid contract code")
+	errWasmValueOverflow = errors.New("wasm: value exceeds 128 bits")
+
+	// wasmModules caches the compiled modules by code hash
+	wasmModules, _ = lru.New(64)
+)
+
+// IsWasmCode reports whether code is a WASM binary. Once WASM is enabled
+// such code runs on the WASM engine instead of the EVM interpreter.
+func IsWasmCode(code []byte) bool {
+	return len(code) >= len(wasmMagic) && bytes.Equal(code[:len(wasmMagic)], wasmMagic)
+}
+
+// ValidateWasmCode checks code is a WASM contract: a valid module exporting
+// its memory and a main function without parameters and results.
+func ValidateWasmCode(code []byte) error {
+	_, err := compileWasm(common.Hash{}, code)
+	return err
+}
+
+func compileWasm(hash common.Hash, code []byte) (*wasm.Module, error) {
+	if hash != (common.Hash{}) {
+		if module, ok := wasmModules.Get(hash); ok {
+			return module.(*wasm.Module), nil
+		}
+	}
+	module, err := wasm.Decode(code)
+	if err != nil {
+		return nil, err
+	}
+	if export, ok := module.Exports["memory"]; !ok || export.Kind != wasm.ExternalMemory {
+		return nil, errInvalidWasmCode
+	}
+	export, ok := module.Exports["main"]
+	if !ok || export.Kind != wasm.ExternalFunction {
+		return nil, errInvalidWasmCode
+	}
+	if ft, err := module.FuncType(export.Index); err != nil || len(ft.Params) != 0 || len(ft.Results) != 0 {
+		return nil, errInvalidWasmCode
+	}
+	for _, imp := range module.Imports {
+		if imp.Module != wasmHostModule {
+			return nil, errInvalidWasmCode
+		}
+	}
+	if hash != (common.Hash{}) {
+		wasmModules.Add(hash, module)
+	}
+	return module, nil
+}
+
+// wasmEnabled reports whether WASM contracts run at the current block.
+func (evm *EVM) wasmEnabled() bool {
+	if evm.WasmEnabled == nil {
+		return false
+	}
+	if evm.wasmActive == nil {
+		active := evm.WasmEnabled(evm.StateDB, evm.BlockNumber.Uint64())
+		evm.wasmActive = &active
+	}
+	return *evm.wasmActive
+}
+
+// runWasm runs the main function of a WASM contract. The contract ends by
+// returning from main or calling finish, which succeed, or revert. A
+// contract creation returns the code of the contract to deploy.
+func runWasm(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
+	evm.depth++
+	defer func() { evm.depth-- }()
+
+	module, err := compileWasm(contract.CodeHash, contract.Code)
+	if err != nil {
+		return nil, err
+	}
+	env := &wasmEnv{evm: evm, contract: contract, input: input}
+	inst, err := wasm.Instantiate(module, wasm.Imports{wasmHostModule: env.hostFunctions()}, contract.Gas)
+	if err != nil {
+		return nil, err
+	}
+	_, err = inst.Invoke("main")
+	contract.Gas = inst.Gas
+
+	switch err {
+	case nil, errWasmFinish:
+		return env.output, nil
+	case errWasmRevert:
+		return env.output, errExecutionReverted
+	}
+	return nil, err
+}
+
+// wasmEnv bridges the host functions called by a WASM contract to the EVM
+// and the state.
+type wasmEnv struct {
+	evm        *EVM
+	contract   *Contract
+	input      []byte
+	output     []byte
+	returnData []byte
+}
+
+func hostFunc(in, out []wasm.ValueType, fn func(*wasm.Instance, []uint64) (uint64, error)) *wasm.HostFunction {
+	return &wasm.HostFunction{Type: wasm.FuncType{Params: in, Results: out}, Fn: fn}
+}
+
+func (env *wasmEnv) hostFunctions() map[string]*wasm.HostFunction {
+	i32, i64 := wasm.I32, wasm.I64
+	return map[string]*wasm.HostFunction{
+		"useGas":             hostFunc([]wasm.ValueType{i64}, nil, env.useGas),
+		"getAddress":         hostFunc([]wasm.ValueType{i32}, nil, env.getAddress),
+		"getExternalBalance": hostFunc([]wasm.ValueType{i32, i32}, nil, env.getExternalBalance),
+		"getBlockHash":       hostFunc([]wasm.ValueType{i64, i32}, []wasm.ValueType{i32}, env.getBlockHash),
+		"getCallDataSize":    hostFunc(nil, []wasm.ValueType{i32}, env.getCallDataSize),
+		"callDataCopy":       hostFunc([]wasm.ValueType{i32, i32, i32}, nil, env.callDataCopy),
+		"getCaller":          hostFunc([]wasm.ValueType{i32}, nil, env.getCaller),
+		"getCallValue":       hostFunc([]wasm.ValueType{i32}, nil, env.getCallValue),
+		"getTxOrigin":        hostFunc([]wasm.ValueType{i32}, nil, env.getTxOrigin),
+		"getTxGasPrice":      hostFunc([]wasm.ValueType{i32}, nil, env.getTxGasPrice),
+		"getBlockCoinbase":   hostFunc([]wasm.ValueType{i32}, nil, env.getBlockCoinbase),
+		"getBlockNumber":     hostFunc(nil, []wasm.ValueType{i64}, env.getBlockNumber),
+		"getBlockTimestamp":  hostFunc(nil, []wasm.ValueType{i64}, env.getBlockTimestamp),
+		"getBlockGasLimit":   hostFunc(nil, []wasm.ValueType{i64}, env.getBlockGasLimit),
+		"getGasLeft":         hostFunc(nil, []wasm.ValueType{i64}, env.getGasLeft),
+		"storageLoad":        hostFunc([]wasm.ValueType{i32, i32}, nil, env.storageLoad),
+		"storageStore":       hostFunc([]wasm.ValueType{i32, i32}, nil, env.storageStore),
+		"log":                hostFunc([]wasm.ValueType{i32, i32, i32, i32, i32, i32, i32}, nil, env.log),
+		"call":               hostFunc([]wasm.ValueType{i64, i32, i32, i32, i32}, []wasm.ValueType{i32}, env.call),
+		"callStatic":         hostFunc([]wasm.ValueType{i64, i32, i32, i32}, []wasm.ValueType{i32}, env.callStatic),
+		"getReturnDataSize":  hostFunc(nil, []wasm.ValueType{i32}, env.getReturnDataSize),
+		"returnDataCopy":     hostFunc([]wasm.ValueType{i32, i32, i32}, nil, env.returnDataCopy),
+		"finish":             hostFunc([]wasm.ValueType{i32, i32}, nil, env.finish),
+		"revert":             hostFunc([]wasm.ValueType{i32, i32}, nil, env.revert),
+	}
+}
+
+// writeU128 writes a value as the 128 bits little endian integer of EEI.
+func writeU128(inst *wasm.Instance, offset uint64, value *big.Int) error {
+	if value.Sign() < 0 || value.BitLen() > 128 {
+		return errWasmValueOverflow
+	}
+	le := make([]byte, 16)
+	be := value.Bytes()
+	for i, b := range be {
+		le[len(be)-1-i] = b
+	}
+	return inst.Write(uint32(offset), le)
+}
+
+func readU128(inst *wasm.Instance, offset uint64) (*big.Int, error) {
+	le, err := inst.Read(uint32(offset), 16)
+	if err != nil {
+		return nil, err
+	}
+	be := make([]byte, 16)
+	for i, b := range le {
+		be[15-i] = b
+	}
+	return new(big.Int).SetBytes(be), nil
+}
+
+func readAddress(inst *wasm.Instance, offset uint64) (common.Address, error) {
+	b, err := inst.Read(uint32(offset), common.AddressLength)
+	return common.BytesToAddress(b), err
+}
+
+func readHash(inst *wasm.Instance, offset uint64) (common.Hash, error) {
+	b, err := inst.Read(uint32(offset), common.HashLength)
+	return common.BytesToHash(b), err
+}
+
+func copyGas(size uint64) uint64 {
+	return GasFastestStep + toWordSize(size)*params.CopyGas
+}
+
+func (env *wasmEnv) useGas(inst *wasm.Instance, args []uint64) (uint64, error) {
+	return 0, inst.UseGas(args[0])
+}
+
+func (env *wasmEnv) getAddress(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(GasQuickStep); err != nil {
+		return 0, err
+	}
+	return 0, inst.Write(uint32(args[0]), env.contract.Address().Bytes())
+}
+
+func (env *wasmEnv) getExternalBalance(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(env.evm.interpreter.gasTable.Balance); err != nil {
+		return 0, err
+	}
+	addr, err := readAddress(inst, args[0])
+	if err != nil {
+		return 0, err
+	}
+	balance := new(big.Int)
+	for _, account := range env.evm.StateDB.GetBalance(env.evm.Cointyp, addr) {
+		if account.AccountType == common.MainAccount {
+			balance = account.Balance
+			break
+		}
+	}
+	return 0, writeU128(inst, args[1], balance)
+}
+
+func (env *wasmEnv) getBlockHash(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(GasExtStep); err != nil {
+		return 0, err
+	}
+	number, current := args[0], env.evm.BlockNumber.Uint64()
+	if number >= current || number+256 < current {
+		return 1, nil
+	}
+	return 0, inst.Write(uint32(args[1]), env.evm.GetHash(number).Bytes())
+}
+
+func (env *wasmEnv) getCallDataSize(inst *wasm.Instance, args []uint64) (uint64, error) {
+	return uint64(len(env.input)), inst.UseGas(GasQuickStep)
+}
+
+func (env *wasmEnv) callDataCopy(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(copyGas(args[2])); err != nil {
+		return 0, err
+	}
+	return 0, inst.Write(uint32(args[0]), getData(env.input, args[1], args[2]))
+}
+
+func (env *wasmEnv) getCaller(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(GasQuickStep); err != nil {
+		return 0, err
+	}
+	return 0, inst.Write(uint32(args[0]), env.contract.Caller().Bytes())
+}
+
+func (env *wasmEnv) getCallValue(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(GasQuickStep); err != nil {
+		return 0, err
+	}
+	return 0, writeU128(inst, args[0], env.contract.Value())
+}
+
+func (env *wasmEnv) getTxOrigin(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(GasQuickStep); err != nil {
+		return 0, err
+	}
+	return 0, inst.Write(uint32(args[0]), env.evm.Origin.Bytes())
+}
+
+func (env *wasmEnv) getTxGasPrice(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(GasQuickStep); err != nil {
+		return 0, err
+	}
+	return 0, writeU128(inst, args[0], env.evm.GasPrice)
+}
+
+func (env *wasmEnv) getBlockCoinbase(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(GasQuickStep); err != nil {
+		return 0, err
+	}
+	return 0, inst.Write(uint32(args[0]), env.evm.Coinbase.Bytes())
+}
+
+func (env *wasmEnv) getBlockNumber(inst *wasm.Instance, args []uint64) (uint64, error) {
+	return env.evm.BlockNumber.Uint64(), inst.UseGas(GasQuickStep)
+}
+
+func (env *wasmEnv) getBlockTimestamp(inst *wasm.Instance, args []uint64) (uint64, error) {
+	return env.evm.Time.Uint64(), inst.UseGas(GasQuickStep)
+}
+
+func (env *wasmEnv) getBlockGasLimit(inst *wasm.Instance, args []uint64) (uint64, error) {
+	return env.evm.GasLimit, inst.UseGas(GasQuickStep)
+}
+
+func (env *wasmEnv) getGasLeft(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(GasQuickStep); err != nil {
+		return 0, err
+	}
+	return inst.Gas, nil
+}
+
+func (env *wasmEnv) storageLoad(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(env.evm.interpreter.gasTable.SLoad); err != nil {
+		return 0, err
+	}
+	key, err := readHash(inst, args[0])
+	if err != nil {
+		return 0, err
+	}
+	value := env.evm.StateDB.GetState(env.evm.Cointyp, env.contract.Address(), key)
+	return 0, inst.Write(uint32(args[1]), value.Bytes())
+}
+
+// storageStore charges the gas of SSTORE.
+func (env *wasmEnv) storageStore(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if env.evm.interpreter.readOnly {
+		return 0, errWriteProtection
+	}
+	key, err := readHash(inst, args[0])
+	if err != nil {
+		return 0, err
+	}
+	value, err := readHash(inst, args[1])
+	if err != nil {
+		return 0, err
+	}
+	current := env.evm.StateDB.GetState(env.evm.Cointyp, env.contract.Address(), key)
+	gas := params.SstoreResetGas
+	if common.EmptyHash(current) && !common.EmptyHash(value) {
+		gas = params.SstoreSetGas
+	} else if !common.EmptyHash(current) && common.EmptyHash(value) {
+		gas = params.SstoreClearGas
+	}
+	if err := inst.UseGas(gas); err != nil {
+		return 0, err
+	}
+	env.evm.StateDB.SetState(env.evm.Cointyp, env.contract.Address(), key, value)
+	return 0, nil
+}
+
+// log charges the gas of LOGn, the topics are read from the offsets in the
+// arguments following their number.
+func (env *wasmEnv) log(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if env.evm.interpreter.readOnly {
+		return 0, errWriteProtection
+	}
+	size, count := args[1], args[2]
+	if count > 4 {
+		return 0, errInvalidWasmCode
+	}
+	if err := inst.UseGas(params.LogGas + count*params.LogTopicGas + size*params.LogDataGas); err != nil {
+		return 0, err
+	}
+	data, err := inst.Read(uint32(args[0]), uint32(size))
+	if err != nil {
+		return 0, err
+	}
+	topics := make([]common.Hash, count)
+	for i := range topics {
+		if topics[i], err = readHash(inst, args[3+i]); err != nil {
+			return 0, err
+		}
+	}
+	env.evm.StateDB.AddLog(env.evm.Cointyp, env.contract.CallerAddress, &types.Log{
+		Address:     env.contract.Address(),
+		Topics:      topics,
+		Data:        data,
+		BlockNumber: env.evm.BlockNumber.Uint64(),
+	})
+	return 0, nil
+}
+
+// callResult converts the result of a call to the EEI result: 0 on
+// success, 1 on failure and 2 on revert.
+func (env *wasmEnv) callResult(inst *wasm.Instance, ret []byte, returnGas uint64, err error) (uint64, error) {
+	inst.Gas += returnGas
+	env.returnData = nil
+	switch err {
+	case nil:
+		env.returnData = ret
+		return 0, nil
+	case errExecutionReverted:
+		env.returnData = ret
+		return 2, nil
+	}
+	return 1, nil
+}
+
+// callGas charges the gas of a call and returns the gas passed to the
+// callee, at most all but one 64th of the gas left.
+func (env *wasmEnv) callGas(inst *wasm.Instance, limit, cost uint64) (uint64, error) {
+	if err := inst.UseGas(cost); err != nil {
+		return 0, err
+	}
+	gas := inst.Gas - inst.Gas/64
+	if limit < gas {
+		gas = limit
+	}
+	inst.Gas -= gas
+	return gas, nil
+}
+
+func (env *wasmEnv) call(inst *wasm.Instance, args []uint64) (uint64, error) {
+	addr, err := readAddress(inst, args[1])
+	if err != nil {
+		return 0, err
+	}
+	value, err := readU128(inst, args[2])
+	if err != nil {
+		return 0, err
+	}
+	data, err := inst.Read(uint32(args[3]), uint32(args[4]))
+	if err != nil {
+		return 0, err
+	}
+	cost := env.evm.interpreter.gasTable.Calls
+	if value.Sign() != 0 {
+		if env.evm.interpreter.readOnly {
+			return 0, errWriteProtection
+		}
+		cost += params.CallValueTransferGas
+		if env.evm.StateDB.Empty(env.evm.Cointyp, addr) {
+			cost += params.CallNewAccountGas
+		}
+	}
+	gas, err := env.callGas(inst, args[0], cost)
+	if err != nil {
+		return 0, err
+	}
+	if value.Sign() != 0 {
+		gas += params.CallStipend
+	}
+	ret, returnGas, _, err := env.evm.Call(env.contract, addr, data, gas, value)
+	return env.callResult(inst, ret, returnGas, err)
+}
+
+func (env *wasmEnv) callStatic(inst *wasm.Instance, args []uint64) (uint64, error) {
+	addr, err := readAddress(inst, args[1])
+	if err != nil {
+		return 0, err
+	}
+	data, err := inst.Read(uint32(args[2]), uint32(args[3]))
+	if err != nil {
+		return 0, err
+	}
+	gas, err := env.callGas(inst, args[0], env.evm.interpreter.gasTable.Calls)
+	if err != nil {
+		return 0, err
+	}
+	ret, returnGas, err := env.evm.StaticCall(env.contract, addr, data, gas)
+	return env.callResult(inst, ret, returnGas, err)
+}
+
+func (env *wasmEnv) getReturnDataSize(inst *wasm.Instance, args []uint64) (uint64, error) {
+	return uint64(len(env.returnData)), inst.UseGas(GasQuickStep)
+}
+
+func (env *wasmEnv) returnDataCopy(inst *wasm.Instance, args []uint64) (uint64, error) {
+	if err := inst.UseGas(copyGas(args[2])); err != nil {
+		return 0, err
+	}
+	offset, size := args[1], args[2]
+	if offset+size > uint64(len(env.returnData)) {
+		return 0, errReturnDataOutOfBounds
+	}
+	return 0, inst.Write(uint32(args[0]), env.returnData[offset:offset+size])
+}
+
+func (env *wasmEnv) finish(inst *wasm.Instance, args []uint64) (uint64, error) {
+	output, err := inst.Read(uint32(args[0]), uint32(args[1]))
+	if err != nil {
+		return 0, err
+	}
+	env.output = output
+	return 0, errWasmFinish
+}
+
+func (env *wasmEnv) revert(inst *wasm.Instance, args []uint64) (uint64, error) {
+	output, err := inst.Read(uint32(args[0]), uint32(args[1]))
+	if err != nil {
+		return 0, err
+	}
+	env.output = output
+	return 0, errWasmRevert
+}
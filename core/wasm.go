@@ -0,0 +1,37 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+// WasmEnabled 检查状态中的WASM合约执行配置, 返回number高度是否启用WASM合约执行
+func WasmEnabled(st vm.StateDBManager, number uint64) bool {
+	cfg, err := matrixstate.GetWasmCfg(st)
+	if err != nil {
+		return false
+	}
+	return cfg.Active(number)
+}
+
+// IsWasmCreation 检查交易是否为在number高度部署WASM合约
+func IsWasmCreation(st vm.StateDBManager, to *common.Address, data []byte, number uint64) bool {
+	return to == nil && vm.IsWasmCode(data) && WasmEnabled(st, number)
+}
+
+// WasmIntrinsicGas 返回部署WASM合约的交易在IntrinsicGas之外需支付的gas.
+// WASM二进制中零字节较多, 全部按非零字节计费
+func WasmIntrinsicGas(data []byte) uint64 {
+	var zero uint64
+	for _, b := range data {
+		if b == 0 {
+			zero++
+		}
+	}
+	return zero * (params.TxDataNonZeroGas - params.TxDataZeroGas)
+}
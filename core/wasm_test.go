@@ -0,0 +1,208 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/params/manversion"
+)
+
+func wasmUleb(v uint32) []byte {
+	var b []byte
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// wasmI32Const 返回i32.const指令
+func wasmI32Const(v int32) []byte {
+	b := []byte{0x41}
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func wasmVector(items ...[]byte) []byte {
+	b := wasmUleb(uint32(len(items)))
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+func wasmSection(id byte, payload []byte) []byte {
+	return append(append([]byte{id}, wasmUleb(uint32(len(payload)))...), payload...)
+}
+
+// testWasmContract 构造WASM合约, 导入ethereum模块的函数, 导出main及memory, data为内存0地址的初始数据
+func testWasmContract(imports []string, body []byte, data []byte) []byte {
+	// 类型0: (i32, i32) -> (), 类型1: () -> (), 类型2: (i32, i32, i32) -> ()
+	types := wasmVector([]byte{0x60, 0x02, 0x7f, 0x7f, 0x00}, []byte{0x60, 0x00, 0x00}, []byte{0x60, 0x03, 0x7f, 0x7f, 0x7f, 0x00})
+	importTypes := map[string]byte{"storageStore": 0, "storageLoad": 0, "finish": 0, "callDataCopy": 2}
+	var entries [][]byte
+	for _, name := range imports {
+		entry := append(wasmUleb(8), "ethereum"...)
+		entry = append(append(entry, wasmUleb(uint32(len(name)))...), name...)
+		entries = append(entries, append(entry, 0x00, importTypes[name]))
+	}
+	main := uint32(len(imports))
+	exports := wasmVector(
+		append(append(wasmUleb(4), "main"...), append([]byte{0x00}, wasmUleb(main)...)...),
+		append(append(wasmUleb(6), "memory"...), 0x02, 0x00),
+	)
+	code := append([]byte{0x00}, body...)
+	code = append(code, 0x0b)
+
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	module = append(module, wasmSection(1, types)...)
+	module = append(module, wasmSection(2, wasmVector(entries...))...)
+	module = append(module, wasmSection(3, wasmVector([]byte{0x01}))...)
+	module = append(module, wasmSection(5, wasmVector([]byte{0x00, 0x01}))...)
+	module = append(module, wasmSection(7, exports)...)
+	module = append(module, wasmSection(10, wasmVector(append(wasmUleb(uint32(len(code))), code...)))...)
+	if len(data) > 0 {
+		segment := append([]byte{0x00, 0x41, 0x00, 0x0b}, wasmUleb(uint32(len(data)))...)
+		module = append(module, wasmSection(11, wasmVector(append(segment, data...)))...)
+	}
+	return module
+}
+
+// testWasmDeployer 构造部署合约, 返回runtime作为合约代码
+func testWasmDeployer(runtime []byte) []byte {
+	body := append(wasmI32Const(0), wasmI32Const(int32(len(runtime)))...)
+	body = append(body, 0x10, 0x00)
+	return testWasmContract([]string{"finish"}, body, runtime)
+}
+
+func TestStateProcessor_WasmContract(t *testing.T) {
+	//WASM合约部署及调用测试
+
+	log.InitLog(3)
+	chaindb := mandb.NewMemDatabase()
+	roots := make([]common.CoinRoot, 0)
+	roots = append(roots, common.CoinRoot{Cointyp: params.MAN_COIN, Root: common.Hash{}})
+	preState, _ := state.NewStateDBManage(roots, chaindb, state.NewDatabase(chaindb))
+	matrixstate.SetVersionInfo(preState, manparams.VersionAlpha)
+	matrixstate.SetWasmCfg(preState, &mc.WasmCfg{Switcher: true, ActivateNumber: 100})
+
+	newEVM := func(number int64) *vm.EVM {
+		ctx := vm.Context{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			WasmEnabled: WasmEnabled,
+			BlockNumber: big.NewInt(number),
+			Time:        big.NewInt(0),
+			Difficulty:  big.NewInt(0),
+			GasPrice:    big.NewInt(0),
+		}
+		return vm.NewEVM(ctx, preState, params.TestChainConfig, vm.Config{}, params.MAN_COIN)
+	}
+	sender := vm.AccountRef(common.HexToAddress("100"))
+
+	// 合约将调用数据存入0号存储, 再读出作为返回值
+	var body []byte
+	body = append(append(append(body, wasmI32Const(32)...), wasmI32Const(0)...), wasmI32Const(32)...)
+	body = append(body, 0x10, 0x00)
+	body = append(append(body, wasmI32Const(0)...), wasmI32Const(32)...)
+	body = append(body, 0x10, 0x01)
+	body = append(append(body, wasmI32Const(0)...), wasmI32Const(64)...)
+	body = append(body, 0x10, 0x02)
+	body = append(append(body, wasmI32Const(64)...), wasmI32Const(32)...)
+	body = append(body, 0x10, 0x03)
+	runtime := testWasmContract([]string{"callDataCopy", "storageStore", "storageLoad", "finish"}, body, nil)
+	if err := vm.ValidateWasmCode(runtime); err != nil {
+		t.Fatalf("WASM合约无效 %v", err)
+	}
+
+	evm := newEVM(100)
+	code, addr, _, err := evm.Create(sender, testWasmDeployer(runtime), 1000000, big.NewInt(0))
+	if err != nil {
+		t.Fatalf("部署WASM合约错误 %v", err)
+	}
+	if !bytes.Equal(code, runtime) || !bytes.Equal(preState.GetCode(params.MAN_COIN, addr), runtime) {
+		t.Fatalf("部署的合约代码错误 %x", code)
+	}
+
+	value := common.HexToHash("0x1234")
+	ret, left, _, err := evm.Call(sender, addr, value.Bytes(), 1000000, big.NewInt(0))
+	if err != nil {
+		t.Fatalf("调用WASM合约错误 %v", err)
+	}
+	if !bytes.Equal(ret, value.Bytes()) || preState.GetState(params.MAN_COIN, addr, common.Hash{}) != value {
+		t.Errorf("WASM合约执行结果错误 %x", ret)
+	}
+	if used := 1000000 - left; used < params.SstoreSetGas {
+		t.Errorf("WASM合约消耗的gas错误 %d", used)
+	}
+	if _, _, _, err := evm.Call(sender, addr, value.Bytes(), params.SstoreSetGas, big.NewInt(0)); err == nil {
+		t.Errorf("gas不足时WASM合约不应执行成功")
+	}
+
+	// 未启用时WASM代码按EVM字节码执行, 首字节0x00为STOP
+	ret, _, _, err = newEVM(99).Call(sender, addr, common.HexToHash("0x5678").Bytes(), 1000000, big.NewInt(0))
+	if err != nil || len(ret) != 0 || preState.GetState(params.MAN_COIN, addr, common.Hash{}) != value {
+		t.Errorf("未启用时不应执行WASM合约 %x %v", ret, err)
+	}
+
+	// 部署的代码须为有效的WASM合约
+	if _, _, _, err := newEVM(100).Create(sender, testWasmDeployer(runtime[:8]), 1000000, big.NewInt(0)); err == nil {
+		t.Errorf("无效的WASM合约不应部署")
+	}
+
+	if WasmIntrinsicGas([]byte{0, 1, 0}) != 2*(params.TxDataNonZeroGas-params.TxDataZeroGas) {
+		t.Errorf("WASM合约部署交易的gas错误")
+	}
+}
+
+func TestSetWasmCfg(t *testing.T) {
+	//超级区块修改WASM合约配置测试
+
+	chaindb := mandb.NewMemDatabase()
+	roots := []common.CoinRoot{{Cointyp: params.MAN_COIN, Root: common.Hash{}}}
+	st, _ := state.NewStateDBManage(roots, chaindb, state.NewDatabase(chaindb))
+	matrixstate.SetVersionInfo(st, manversion.VersionAIMine)
+
+	genesis := &GenesisMState{WasmCfg: &mc.WasmCfg{Switcher: true, ActivateNumber: 100}}
+	if err := genesis.setWasmCfg(st, 0, manversion.VersionAIMine); err != nil {
+		t.Fatalf("设置WASM合约配置错误 %v", err)
+	}
+	if err := (&GenesisMState{WasmCfg: &mc.WasmCfg{Switcher: true, ActivateNumber: 200}}).setWasmCfg(st, 50, manversion.VersionAIMine); err != nil {
+		t.Fatalf("修改未生效的配置错误 %v", err)
+	}
+	if err := (&GenesisMState{WasmCfg: &mc.WasmCfg{Switcher: false}}).setWasmCfg(st, 300, manversion.VersionAIMine); err == nil {
+		t.Fatalf("关闭已生效的配置应该失败")
+	}
+	if err := (&GenesisMState{WasmCfg: &mc.WasmCfg{Switcher: true, ActivateNumber: 200}}).setWasmCfg(st, 300, manversion.VersionAIMine); err != nil {
+		t.Fatalf("重复设置已生效的配置错误 %v", err)
+	}
+	if err := (&GenesisMState{WasmCfg: &mc.WasmCfg{Switcher: true, ActivateNumber: 100}}).setWasmCfg(st, 150, manversion.VersionAIMine); err == nil {
+		t.Fatalf("生效高度早于超级区块应该失败")
+	}
+	if err := (&GenesisMState{WasmCfg: &mc.WasmCfg{Switcher: true, ActivateNumber: 50}}).setWasmCfg(st, 10, manversion.VersionAIMine); err != nil {
+		t.Fatalf("提前未生效的配置错误 %v", err)
+	}
+	cfg, err := matrixstate.GetWasmCfg(st)
+	if err != nil || !cfg.Active(50) {
+		t.Fatalf("WASM合约配置错误 %v %v", cfg, err)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+// Package bls implements BLS signatures over the BLS12-381 curve, used to
+// aggregate the votes of the validators. Public keys are G1 points and
+// signatures G2 points (the minimal public key size variant), and rogue key
+// attacks are prevented by proofs of possession of the registered keys.
+package bls
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+const (
+	SecretKeySize = 32 // Size of a serialized secret key
+	PublicKeySize = 48 // Size of a compressed public key
+	SignatureSize = 96 // Size of a compressed signature
+)
+
+var (
+	// Domain separation tags of the proof of possession scheme
+	signDST  = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+	proofDST = []byte("BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+)
+
+var (
+	ErrInvalidSecretKey = errors.New("bls: invalid secret key")
+	ErrInvalidPublicKey = errors.New("bls: invalid public key")
+	ErrInvalidSignature = errors.New("bls: invalid signature")
+	ErrNoSignatures     = errors.New("bls: no signatures to aggregate")
+)
+
+// SecretKey is a BLS secret key.
+type SecretKey struct {
+	sk *blst.SecretKey
+}
+
+// PublicKey is a BLS public key.
+type PublicKey struct {
+	pk *blst.P1Affine
+}
+
+// Signature is a BLS signature, or an aggregation of signatures.
+type Signature struct {
+	sig *blst.P2Affine
+}
+
+// GenerateKey creates a new random secret key.
+func GenerateKey() (*SecretKey, error) {
+	ikm := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, ikm); err != nil {
+		return nil, err
+	}
+	return &SecretKey{sk: blst.KeyGen(ikm)}, nil
+}
+
+// SecretKeyFromBytes parses a serialized secret key.
+func SecretKeyFromBytes(b []byte) (*SecretKey, error) {
+	if len(b) != SecretKeySize {
+		return nil, ErrInvalidSecretKey
+	}
+	sk := new(blst.SecretKey).Deserialize(b)
+	if sk == nil {
+		return nil, ErrInvalidSecretKey
+	}
+	return &SecretKey{sk: sk}, nil
+}
+
+// Bytes serializes the secret key.
+func (k *SecretKey) Bytes() []byte {
+	return k.sk.Serialize()
+}
+
+// PublicKey returns the public key of the secret key.
+func (k *SecretKey) PublicKey() *PublicKey {
+	return &PublicKey{pk: new(blst.P1Affine).From(k.sk)}
+}
+
+// Sign signs a message.
+func (k *SecretKey) Sign(msg []byte) *Signature {
+	return &Signature{sig: new(blst.P2Affine).Sign(k.sk, msg, signDST)}
+}
+
+// ProvePossession signs the public key of the secret key, proving the key is
+// not derived from the keys of other signers.
+func (k *SecretKey) ProvePossession() *Signature {
+	return &Signature{sig: new(blst.P2Affine).Sign(k.sk, k.PublicKey().Bytes(), proofDST)}
+}
+
+// PublicKeyFromBytes parses a compressed public key, rejecting the identity and
+// points outside of the G1 subgroup.
+func PublicKeyFromBytes(b []byte) (*PublicKey, error) {
+	if len(b) != PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+	pk := new(blst.P1Affine).Uncompress(b)
+	if pk == nil || !pk.KeyValidate() {
+		return nil, ErrInvalidPublicKey
+	}
+	return &PublicKey{pk: pk}, nil
+}
+
+// Bytes returns the compressed public key.
+func (pk *PublicKey) Bytes() []byte {
+	return pk.pk.Compress()
+}
+
+// SignatureFromBytes parses a compressed signature, rejecting points outside of
+// the G2 subgroup.
+func SignatureFromBytes(b []byte) (*Signature, error) {
+	if len(b) != SignatureSize {
+		return nil, ErrInvalidSignature
+	}
+	sig := new(blst.P2Affine).Uncompress(b)
+	if sig == nil || !sig.SigValidate(false) {
+		return nil, ErrInvalidSignature
+	}
+	return &Signature{sig: sig}, nil
+}
+
+// Bytes returns the compressed signature.
+func (s *Signature) Bytes() []byte {
+	return s.sig.Compress()
+}
+
+// Verify checks the signature of a message by a public key.
+func (s *Signature) Verify(pk *PublicKey, msg []byte) bool {
+	return s.sig.Verify(false, pk.pk, false, msg, signDST)
+}
+
+// VerifyPossession checks a proof of possession of a public key.
+func VerifyPossession(pk *PublicKey, proof *Signature) bool {
+	return proof.sig.Verify(false, pk.pk, false, pk.Bytes(), proofDST)
+}
+
+// AggregateSignatures aggregates signatures into a single one.
+func AggregateSignatures(sigs []*Signature) (*Signature, error) {
+	if len(sigs) == 0 {
+		return nil, ErrNoSignatures
+	}
+	points := make([]*blst.P2Affine, len(sigs))
+	for i, sig := range sigs {
+		points[i] = sig.sig
+	}
+	agg := new(blst.P2Aggregate)
+	if !agg.Aggregate(points, false) {
+		return nil, ErrInvalidSignature
+	}
+	return &Signature{sig: agg.ToAffine()}, nil
+}
+
+// FastAggregateVerify checks an aggregated signature of the same message by all
+// the public keys. The public keys must have been proven to be possessed.
+func (s *Signature) FastAggregateVerify(pks []*PublicKey, msg []byte) bool {
+	if len(pks) == 0 {
+		return false
+	}
+	points := make([]*blst.P1Affine, len(pks))
+	for i, pk := range pks {
+		points[i] = pk.pk
+	}
+	return s.sig.FastAggregateVerify(false, points, msg, signDST)
+}
+
+// LoadKey loads a hex encoded secret key from a file.
+func LoadKey(file string) (*SecretKey, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	buf := make([]byte, SecretKeySize*2)
+	if _, err := io.ReadFull(bufio.NewReader(fd), buf); err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(string(buf))
+	if err != nil {
+		return nil, err
+	}
+	return SecretKeyFromBytes(key)
+}
+
+// SaveKey saves a secret key to a file in hex encoding, readable by the owner
+// only.
+func SaveKey(file string, key *SecretKey) error {
+	return ioutil.WriteFile(file, []byte(hex.EncodeToString(key.Bytes())), 0600)
+}
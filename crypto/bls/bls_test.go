@@ -0,0 +1,101 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package bls
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("block hash")
+	sig := key.Sign(msg)
+	if !sig.Verify(key.PublicKey(), msg) {
+		t.Fatal("signature not verified")
+	}
+	if sig.Verify(key.PublicKey(), []byte("other hash")) {
+		t.Fatal("signature verified for another message")
+	}
+	// The encodings must round trip
+	pk, err := PublicKeyFromBytes(key.PublicKey().Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := SignatureFromBytes(sig.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Verify(pk, msg) {
+		t.Fatal("decoded signature not verified")
+	}
+	// Signatures of the message aren't proofs of possession
+	if VerifyPossession(pk, sig) {
+		t.Fatal("message signature accepted as proof of possession")
+	}
+	if !VerifyPossession(pk, key.ProvePossession()) {
+		t.Fatal("proof of possession not verified")
+	}
+}
+
+func TestAggregateVerify(t *testing.T) {
+	msg := []byte("block hash")
+
+	var (
+		pks  []*PublicKey
+		sigs []*Signature
+	)
+	for i := 0; i < 5; i++ {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pks = append(pks, key.PublicKey())
+		sigs = append(sigs, key.Sign(msg))
+	}
+	agg, err := AggregateSignatures(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !agg.FastAggregateVerify(pks, msg) {
+		t.Fatal("aggregated signature not verified")
+	}
+	if agg.FastAggregateVerify(pks[1:], msg) {
+		t.Fatal("aggregated signature verified without one of the signers")
+	}
+	if _, err := AggregateSignatures(nil); err != ErrNoSignatures {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNoSignatures)
+	}
+}
+
+func TestSaveLoadKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bls-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "blskey")
+	if err := SaveKey(file, key); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadKey(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.Bytes(), key.Bytes()) {
+		t.Fatal("loaded key mismatch")
+	}
+}
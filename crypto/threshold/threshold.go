@@ -0,0 +1,367 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+// Package threshold implements (t, n) threshold encryption over the secp256k1
+// curve.
+//
+// A message is encrypted under a random secret which is split among n
+// recipients with Shamir's secret sharing, each share being encrypted to the
+// public key of its recipient. Any t decrypted shares reconstruct the secret,
+// fewer reveal nothing about it. The sharing polynomial is committed to with
+// Feldman commitments, so every share can be checked before being combined and
+// a dishonest dealer or shareholder is detected.
+package threshold
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common/math"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+)
+
+var (
+	ErrInvalidThreshold = errors.New("threshold: invalid threshold")
+	ErrInvalidPublicKey = errors.New("threshold: invalid public key")
+	ErrInvalidShare     = errors.New("threshold: invalid share")
+	ErrDuplicateShare   = errors.New("threshold: duplicate share")
+	ErrNotEnoughShares  = errors.New("threshold: not enough shares")
+	ErrShareNotFound    = errors.New("threshold: share not found")
+	ErrInvalidCommit    = errors.New("threshold: invalid commitments")
+	ErrDecrypt          = errors.New("threshold: could not decrypt")
+)
+
+const pointSize = 65 // Size of an uncompressed curve point
+
+// Share is the evaluation of the sharing polynomial at the index of a recipient.
+// Indexes start at 1, the secret being the evaluation at 0.
+type Share struct {
+	Index uint32
+	Value *big.Int
+}
+
+// EncryptedShare is a share encrypted to the public key of its recipient.
+type EncryptedShare struct {
+	Index     uint32
+	Ephemeral []byte // Ephemeral public key of the key agreement
+	Data      []byte // Sealed share value
+}
+
+// Ciphertext is a message encrypted to a threshold of recipients.
+type Ciphertext struct {
+	Threshold   uint32
+	Commitments [][]byte // Commitments to the coefficients of the sharing polynomial
+	Shares      []EncryptedShare
+	Nonce       []byte
+	Data        []byte // Message sealed under the secret
+}
+
+// Split shares a secret among n recipients, any t of them being able to
+// reconstruct it. It returns the shares along with the commitments to the
+// sharing polynomial.
+func Split(secret *big.Int, t, n int, random io.Reader) ([]Share, [][]byte, error) {
+	curve := crypto.S256()
+	order := curve.Params().N
+	if t < 1 || t > n || uint64(n) >= 1<<32 {
+		return nil, nil, ErrInvalidThreshold
+	}
+	if secret.Sign() < 0 || secret.Cmp(order) >= 0 {
+		return nil, nil, ErrInvalidShare
+	}
+	coefficients := make([]*big.Int, t)
+	coefficients[0] = new(big.Int).Set(secret)
+	for i := 1; i < t; i++ {
+		coefficient, err := randScalar(random)
+		if err != nil {
+			return nil, nil, err
+		}
+		coefficients[i] = coefficient
+	}
+	commitments := make([][]byte, t)
+	for i, coefficient := range coefficients {
+		x, y := curve.ScalarBaseMult(math.PaddedBigBytes(coefficient, 32))
+		commitments[i] = marshalPoint(x, y)
+	}
+	shares := make([]Share, n)
+	for i := range shares {
+		index := uint32(i + 1)
+		shares[i] = Share{Index: index, Value: evaluate(coefficients, index)}
+	}
+	return shares, commitments, nil
+}
+
+// VerifyShare checks a share against the commitments to the sharing polynomial.
+func VerifyShare(share Share, commitments [][]byte) bool {
+	curve := crypto.S256()
+	if share.Index == 0 || share.Value == nil || share.Value.Sign() < 0 || share.Value.Cmp(curve.Params().N) >= 0 || len(commitments) == 0 {
+		return false
+	}
+	// g^value must match the product of the commitments to the powers of the index
+	var (
+		x, y  *big.Int
+		power = big.NewInt(1)
+		index = new(big.Int).SetUint64(uint64(share.Index))
+	)
+	for _, commitment := range commitments {
+		cx, cy, err := unmarshalPoint(commitment)
+		if err != nil {
+			return false
+		}
+		px, py := curve.ScalarMult(cx, cy, math.PaddedBigBytes(power, 32))
+		if x == nil {
+			x, y = px, py
+		} else {
+			x, y = curve.Add(x, y, px, py)
+		}
+		power.Mul(power, index)
+		power.Mod(power, curve.Params().N)
+	}
+	gx, gy := curve.ScalarBaseMult(math.PaddedBigBytes(share.Value, 32))
+	return gx.Cmp(x) == 0 && gy.Cmp(y) == 0
+}
+
+// Combine reconstructs the secret from shares by Lagrange interpolation. The
+// shares must be valid, there must be at least threshold of them.
+func Combine(shares []Share) (*big.Int, error) {
+	if len(shares) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+	order := crypto.S256().Params().N
+	seen := make(map[uint32]bool, len(shares))
+	for _, share := range shares {
+		if share.Index == 0 || share.Value == nil {
+			return nil, ErrInvalidShare
+		}
+		if seen[share.Index] {
+			return nil, ErrDuplicateShare
+		}
+		seen[share.Index] = true
+	}
+	secret := new(big.Int)
+	for i, share := range shares {
+		// Lagrange basis polynomial of the share evaluated at 0
+		num, den := big.NewInt(1), big.NewInt(1)
+		xi := new(big.Int).SetUint64(uint64(share.Index))
+		for j, other := range shares {
+			if i == j {
+				continue
+			}
+			xj := new(big.Int).SetUint64(uint64(other.Index))
+			num.Mul(num, xj)
+			num.Mod(num, order)
+			den.Mul(den, new(big.Int).Sub(xj, xi))
+			den.Mod(den, order)
+		}
+		term := new(big.Int).Mul(share.Value, num)
+		term.Mul(term, new(big.Int).ModInverse(den, order))
+		secret.Add(secret, term)
+		secret.Mod(secret, order)
+	}
+	return secret, nil
+}
+
+// Encrypt encrypts a message to the recipients, any t of them being able to
+// decrypt it together. The share of the i-th recipient has index i+1.
+func Encrypt(msg []byte, recipients []*ecdsa.PublicKey, t int) (*Ciphertext, error) {
+	for _, pub := range recipients {
+		if pub == nil || pub.X == nil || !crypto.S256().IsOnCurve(pub.X, pub.Y) {
+			return nil, ErrInvalidPublicKey
+		}
+	}
+	secret, err := randScalar(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shares, commitments, err := Split(secret, t, len(recipients), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	ct := &Ciphertext{Threshold: uint32(t), Commitments: commitments}
+	for i, share := range shares {
+		sealed, err := sealShare(share, recipients[i])
+		if err != nil {
+			return nil, err
+		}
+		ct.Shares = append(ct.Shares, *sealed)
+	}
+	gcm, err := newGCM(secretKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	ct.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, ct.Nonce); err != nil {
+		return nil, err
+	}
+	ct.Data = gcm.Seal(nil, ct.Nonce, msg, nil)
+	return ct, nil
+}
+
+// DecryptShare decrypts the share of the given index with the private key of its
+// recipient, and checks it against the commitments of the ciphertext.
+func (ct *Ciphertext) DecryptShare(index uint32, prv *ecdsa.PrivateKey) (Share, error) {
+	for _, sealed := range ct.Shares {
+		if sealed.Index != index {
+			continue
+		}
+		share, err := openShare(&sealed, prv)
+		if err != nil {
+			return Share{}, err
+		}
+		if !VerifyShare(share, ct.Commitments) {
+			return Share{}, ErrInvalidShare
+		}
+		return share, nil
+	}
+	return Share{}, ErrShareNotFound
+}
+
+// Decrypt reconstructs the secret from the decrypted shares, at least threshold
+// of them, and decrypts the message. Invalid shares are rejected.
+func (ct *Ciphertext) Decrypt(shares []Share) ([]byte, error) {
+	if ct.Threshold == 0 || len(ct.Commitments) != int(ct.Threshold) {
+		return nil, ErrInvalidCommit
+	}
+	if len(shares) < int(ct.Threshold) {
+		return nil, ErrNotEnoughShares
+	}
+	for _, share := range shares {
+		if !VerifyShare(share, ct.Commitments) {
+			return nil, ErrInvalidShare
+		}
+	}
+	secret, err := Combine(shares[:ct.Threshold])
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(secretKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	if len(ct.Nonce) != gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	msg, err := gcm.Open(nil, ct.Nonce, ct.Data, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return msg, nil
+}
+
+// sealShare encrypts a share to a public key with an ephemeral key agreement.
+func sealShare(share Share, pub *ecdsa.PublicKey) (*EncryptedShare, error) {
+	curve := crypto.S256()
+	ephemeral, err := randScalar(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	ex, ey := curve.ScalarBaseMult(math.PaddedBigBytes(ephemeral, 32))
+	sx, _ := curve.ScalarMult(pub.X, pub.Y, math.PaddedBigBytes(ephemeral, 32))
+
+	gcm, err := newGCM(shareKey(sx, share.Index))
+	if err != nil {
+		return nil, err
+	}
+	// Every key is used once, a zero nonce is safe
+	nonce := make([]byte, gcm.NonceSize())
+	return &EncryptedShare{
+		Index:     share.Index,
+		Ephemeral: marshalPoint(ex, ey),
+		Data:      gcm.Seal(nil, nonce, math.PaddedBigBytes(share.Value, 32), nil),
+	}, nil
+}
+
+// openShare decrypts a share with the private key of its recipient.
+func openShare(sealed *EncryptedShare, prv *ecdsa.PrivateKey) (Share, error) {
+	ex, ey, err := unmarshalPoint(sealed.Ephemeral)
+	if err != nil {
+		return Share{}, err
+	}
+	sx, _ := crypto.S256().ScalarMult(ex, ey, math.PaddedBigBytes(prv.D, 32))
+
+	gcm, err := newGCM(shareKey(sx, sealed.Index))
+	if err != nil {
+		return Share{}, err
+	}
+	value, err := gcm.Open(nil, make([]byte, gcm.NonceSize()), sealed.Data, nil)
+	if err != nil {
+		return Share{}, ErrDecrypt
+	}
+	return Share{Index: sealed.Index, Value: new(big.Int).SetBytes(value)}, nil
+}
+
+// evaluate computes the polynomial at x with Horner's method.
+func evaluate(coefficients []*big.Int, x uint32) *big.Int {
+	order := crypto.S256().Params().N
+	bx := new(big.Int).SetUint64(uint64(x))
+	result := new(big.Int)
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result.Mul(result, bx)
+		result.Add(result, coefficients[i])
+		result.Mod(result, order)
+	}
+	return result
+}
+
+// randScalar returns a random non zero scalar of the curve.
+func randScalar(random io.Reader) (*big.Int, error) {
+	order := crypto.S256().Params().N
+	for {
+		k, err := rand.Int(random, order)
+		if err != nil {
+			return nil, err
+		}
+		if k.Sign() > 0 {
+			return k, nil
+		}
+	}
+}
+
+func secretKey(secret *big.Int) []byte {
+	key := sha256.Sum256(math.PaddedBigBytes(secret, 32))
+	return key[:]
+}
+
+func shareKey(shared *big.Int, index uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], index)
+
+	h := sha256.New()
+	h.Write(math.PaddedBigBytes(shared, 32))
+	h.Write(buf[:])
+	return h.Sum(nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func marshalPoint(x, y *big.Int) []byte {
+	point := make([]byte, pointSize)
+	point[0] = 4
+	copy(point[1:33], math.PaddedBigBytes(x, 32))
+	copy(point[33:], math.PaddedBigBytes(y, 32))
+	return point
+}
+
+func unmarshalPoint(point []byte) (*big.Int, *big.Int, error) {
+	if len(point) != pointSize || point[0] != 4 {
+		return nil, nil, ErrInvalidPublicKey
+	}
+	x, y := new(big.Int).SetBytes(point[1:33]), new(big.Int).SetBytes(point[33:])
+	if !crypto.S256().IsOnCurve(x, y) {
+		return nil, nil, ErrInvalidPublicKey
+	}
+	return x, y, nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package threshold
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := big.NewInt(123456789)
+	shares, commitments, err := Split(secret, 3, 5, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, share := range shares {
+		if !VerifyShare(share, commitments) {
+			t.Fatalf("share %d not verified", share.Index)
+		}
+	}
+	// Any threshold of shares reconstructs the secret
+	for _, subset := range [][]Share{shares[:3], shares[2:], {shares[4], shares[0], shares[2]}} {
+		combined, err := Combine(subset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if combined.Cmp(secret) != 0 {
+			t.Fatalf("secret mismatch: have %v, want %v", combined, secret)
+		}
+	}
+	if combined, _ := Combine(shares[:2]); combined.Cmp(secret) == 0 {
+		t.Fatal("secret reconstructed below the threshold")
+	}
+	tampered := Share{Index: shares[0].Index, Value: new(big.Int).Add(shares[0].Value, big.NewInt(1))}
+	if VerifyShare(tampered, commitments) {
+		t.Fatal("tampered share verified")
+	}
+	if _, err := Combine([]Share{shares[0], shares[0]}); err != ErrDuplicateShare {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrDuplicateShare)
+	}
+	if _, _, err := Split(secret, 6, 5, rand.Reader); err != ErrInvalidThreshold {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrInvalidThreshold)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	var (
+		keys []*ecdsa.PrivateKey
+		pubs []*ecdsa.PublicKey
+	)
+	for i := 0; i < 4; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		pubs = append(pubs, &key.PublicKey)
+	}
+	msg := []byte("privatekey broadcast payload")
+	ct, err := Encrypt(msg, pubs, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var shares []Share
+	for i, key := range keys {
+		share, err := ct.DecryptShare(uint32(i+1), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shares = append(shares, share)
+	}
+	// Shares can only be decrypted by their recipient
+	if _, err := ct.DecryptShare(1, keys[1]); err != ErrDecrypt {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	if _, err := ct.Decrypt(shares[:2]); err != ErrNotEnoughShares {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNotEnoughShares)
+	}
+	plain, err := ct.Decrypt(shares[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain, msg) {
+		t.Fatalf("message mismatch: have %q, want %q", plain, msg)
+	}
+	shares[1].Value = big.NewInt(1)
+	if _, err := ct.Decrypt(shares[1:]); err != ErrInvalidShare {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrInvalidShare)
+	}
+}
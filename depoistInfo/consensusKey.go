@@ -0,0 +1,116 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package depoistInfo
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// 抵押账户可注册独立的共识签名账户, 生效后共识签名只通过该账户解析, A1账户可以离线保存
+
+func getConsensusKey(stateDB vm.StateDBManager, depositAccount common.Address) *mc.ConsensusKey {
+	keys, err := matrixstate.GetConsensusKeys(stateDB)
+	if err != nil {
+		return nil
+	}
+	for i := range keys.Keys {
+		if keys.Keys[i].Account == depositAccount {
+			return &keys.Keys[i]
+		}
+	}
+	return nil
+}
+
+func consensusSignerAt(key *mc.ConsensusKey, number uint64) common.Address {
+	if number >= key.ActivateNumber {
+		return key.Signer
+	}
+	return key.PrevSigner
+}
+
+// GetConsensusSigner 获取抵押账户在指定高度生效的共识签名账户, 未注册时返回空地址
+func GetConsensusSigner(stateDB vm.StateDBManager, depositAccount common.Address, number uint64) common.Address {
+	key := getConsensusKey(stateDB, depositAccount)
+	if key == nil {
+		return common.Address{}
+	}
+	return consensusSignerAt(key, number)
+}
+
+// GetConsensusBLSPubKey 获取抵押账户在指定高度生效的BLS共识公钥
+func GetConsensusBLSPubKey(stateDB vm.StateDBManager, depositAccount common.Address, number uint64) []byte {
+	key := getConsensusKey(stateDB, depositAccount)
+	if key == nil {
+		return nil
+	}
+	if number >= key.ActivateNumber {
+		return key.BLSPubKey
+	}
+	return key.PrevBLSPubKey
+}
+
+// GetConsensusAccount 根据共识签名账户获取抵押账户, 不是指定高度生效的共识签名账户时返回空地址
+func GetConsensusAccount(stateDB vm.StateDBManager, signer common.Address, number uint64) common.Address {
+	if signer == (common.Address{}) {
+		return common.Address{}
+	}
+	keys, err := matrixstate.GetConsensusKeys(stateDB)
+	if err != nil {
+		return common.Address{}
+	}
+	for i := range keys.Keys {
+		if consensusSignerAt(&keys.Keys[i], number) == signer {
+			return keys.Keys[i].Account
+		}
+	}
+	return common.Address{}
+}
+
+// SetConsensusSigners 将抵押列表中已注册共识密钥的签名账户替换为指定高度生效的共识签名账户
+func SetConsensusSigners(stateDB vm.StateDBManager, number uint64, deposits []vm.DepositDetail) []vm.DepositDetail {
+	keys, err := matrixstate.GetConsensusKeys(stateDB)
+	if err != nil || len(keys.Keys) == 0 {
+		return deposits
+	}
+	signers := make(map[common.Address]common.Address, len(keys.Keys))
+	for i := range keys.Keys {
+		if signer := consensusSignerAt(&keys.Keys[i], number); signer != (common.Address{}) {
+			signers[keys.Keys[i].Account] = signer
+		}
+	}
+	// 抵押列表可能被缓存共享, 替换在副本上进行
+	result := make([]vm.DepositDetail, len(deposits))
+	copy(result, deposits)
+	for i := range result {
+		if signer, ok := signers[result[i].Address]; ok {
+			result[i].SignAddress = signer
+		}
+	}
+	return result
+}
+
+// SetConsensusSignersByHash 根据区块hash的状态替换抵押列表的共识签名账户
+func SetConsensusSignersByHash(hash common.Hash, number uint64, deposits []vm.DepositDetail) ([]vm.DepositDetail, error) {
+	statedb, _, err := getDepositInfoByHash(hash)
+	if err != nil {
+		return deposits, err
+	}
+	return SetConsensusSigners(statedb, number, deposits), nil
+}
+
+// ResolveConsensusAccount 根据签名账户获取指定高度的抵押账户, 已生效的共识签名账户优先.
+// 抵押账户的共识密钥生效后, 其A1账户不再解析为该抵押账户
+func ResolveConsensusAccount(stateDB vm.StateDBManager, signer common.Address, number uint64) common.Address {
+	if account := GetConsensusAccount(stateDB, signer, number); account != (common.Address{}) {
+		return account
+	}
+	account := GetDepositAccount(stateDB, signer)
+	if account == (common.Address{}) || GetConsensusSigner(stateDB, account, number) != (common.Address{}) {
+		return common.Address{}
+	}
+	return account
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package elector
+
+import (
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/mt19937"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// Names of the built-in election algorithms.
+const (
+	StakeElector      = "stake"      // Pure stake weighted
+	ReputationElector = "reputation" // Stake weighted, scaled by online time
+	AdaptiveElector   = "adaptive"   // Stake weighted, committee growing with the candidates
+
+	// DefaultElector is the algorithm used until the first fork is reached.
+	DefaultElector = StakeElector
+)
+
+func init() {
+	for _, e := range []*Elector{
+		{Name: StakeElector, Source: DepositSource{}, Weigher: StakeWeigher{}, Selector: WeightedSelector{}},
+		{Name: ReputationElector, Source: DepositSource{}, Weigher: ReputationWeigher{Saturation: 1024}, Selector: WeightedSelector{}},
+		{Name: AdaptiveElector, Source: DepositSource{}, Weigher: StakeWeigher{}, Selector: AdaptiveSelector{Selector: WeightedSelector{}, Base: 1024, Factor: 64, Step: 2}},
+	} {
+		if err := Register(e); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// manUnit is the number of wei in a MAN.
+var manUnit = big.NewInt(1e18)
+
+// DepositSource elects among the depositing nodes, honouring the black list and
+// the white list of the election config. Withdrawing nodes are skipped.
+type DepositSource struct{}
+
+// Candidates implements CandidateSource.
+func (DepositSource) Candidates(deposits []vm.DepositDetail, cfg *mc.ElectConfigInfo_All) []Candidate {
+	black := make(map[common.Address]bool)
+	white := make(map[common.Address]bool)
+	if cfg != nil {
+		for _, addr := range cfg.BlackList {
+			black[addr] = true
+		}
+		for _, addr := range cfg.WhiteList {
+			white[addr] = true
+		}
+	}
+	candidates := make([]Candidate, 0, len(deposits))
+	for _, d := range deposits {
+		if d.Deposit == nil || d.Deposit.Sign() <= 0 || (d.WithdrawH != nil && d.WithdrawH.Sign() > 0) {
+			continue
+		}
+		if black[d.Address] || (cfg != nil && cfg.WhiteListSwitcher && !white[d.Address]) {
+			continue
+		}
+		online := d.OnlineTime
+		if online == nil {
+			online = new(big.Int)
+		}
+		candidates = append(candidates, Candidate{Address: d.Address, Deposit: d.Deposit, OnlineTime: online})
+	}
+	return candidates
+}
+
+// StakeWeigher weighs the candidates by their deposit in MAN.
+type StakeWeigher struct{}
+
+// Weigh implements Weigher.
+func (StakeWeigher) Weigh(candidates []Candidate) []WeightedCandidate {
+	weighted := make([]WeightedCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		weighted = append(weighted, WeightedCandidate{Candidate: c, Weight: stake(c)})
+	}
+	return weighted
+}
+
+// ReputationWeigher weighs the candidates by their deposit in MAN, scaled down
+// for nodes which were online for less than Saturation.
+type ReputationWeigher struct {
+	Saturation uint64
+}
+
+// Weigh implements Weigher.
+func (w ReputationWeigher) Weigh(candidates []Candidate) []WeightedCandidate {
+	weighted := make([]WeightedCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		weight := stake(c)
+		if online := c.OnlineTime.Uint64(); c.OnlineTime.IsUint64() && online < w.Saturation {
+			scaled := new(big.Int).Mul(new(big.Int).SetUint64(weight), new(big.Int).SetUint64(online+1))
+			scaled.Div(scaled, new(big.Int).SetUint64(w.Saturation+1))
+			// Fresh nodes keep a minimal chance of being elected
+			if weight = scaled.Uint64(); weight == 0 {
+				weight = 1
+			}
+		}
+		weighted = append(weighted, WeightedCandidate{Candidate: c, Weight: weight})
+	}
+	return weighted
+}
+
+// stake returns the deposit of a candidate in MAN.
+func stake(c Candidate) uint64 {
+	value := new(big.Int).Div(c.Deposit, manUnit)
+	if !value.IsUint64() {
+		return ^uint64(0) >> 16
+	}
+	return value.Uint64()
+}
+
+// WeightedSelector samples the elected nodes without replacement, each pick
+// being proportional to the candidate weights.
+type WeightedSelector struct{}
+
+// Select implements Selector.
+func (WeightedSelector) Select(candidates []WeightedCandidate, num int, seed *big.Int) []WeightedCandidate {
+	pool := make([]WeightedCandidate, 0, len(candidates))
+	total := uint64(0)
+	for _, c := range candidates {
+		if c.Weight > 0 {
+			pool = append(pool, c)
+			total += c.Weight
+		}
+	}
+	if seed == nil {
+		seed = new(big.Int)
+	}
+	rand := mt19937.RandUniformInit(seed.Int64())
+
+	chosen := make([]WeightedCandidate, 0, num)
+	for len(chosen) < num && len(pool) > 0 {
+		pick := uint64(rand.Uniform(0, float64(total)))
+		index := len(pool) - 1
+		for i, c := range pool {
+			if pick < c.Weight {
+				index = i
+				break
+			}
+			pick -= c.Weight
+		}
+		chosen = append(chosen, pool[index])
+		total -= pool[index].Weight
+		pool = append(pool[:index], pool[index+1:]...)
+	}
+	return chosen
+}
+
+// AdaptiveSelector grows the committee by Step nodes for every Factor candidates
+// beyond Base, before selecting with the wrapped Selector.
+type AdaptiveSelector struct {
+	Selector
+	Base   uint64
+	Factor uint64
+	Step   uint64
+}
+
+// Select implements Selector.
+func (s AdaptiveSelector) Select(candidates []WeightedCandidate, num int, seed *big.Int) []WeightedCandidate {
+	if count := uint64(len(candidates)); s.Factor > 0 && count > s.Base {
+		num += int((count - s.Base) / s.Factor * s.Step)
+	}
+	return s.Selector.Select(candidates, num, seed)
+}
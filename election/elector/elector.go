@@ -0,0 +1,120 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+// Package elector splits the election into pluggable stages, so that
+// alternative election algorithms can be activated at fork heights without
+// forking the election plugs.
+package elector
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+var (
+	// ErrElectorExists is returned when registering an elector name twice.
+	ErrElectorExists = errors.New("elector already registered")
+
+	// ErrInvalidElector is returned when registering an elector without a name
+	// or with a missing stage.
+	ErrInvalidElector = errors.New("invalid elector")
+
+	// ErrUnknownElector is returned when a fork activates an unregistered elector.
+	ErrUnknownElector = errors.New("unknown elector")
+)
+
+// Candidate is a node taking part in an election.
+type Candidate struct {
+	Address    common.Address // Deposit account of the node
+	Deposit    *big.Int       // Deposited amount
+	OnlineTime *big.Int       // Accumulated online time of the node
+}
+
+// WeightedCandidate is a candidate along with its election weight.
+type WeightedCandidate struct {
+	Candidate
+	Weight uint64
+}
+
+// CandidateSource turns the deposit list of an election into the candidates
+// eligible to be elected.
+type CandidateSource interface {
+	Candidates(deposits []vm.DepositDetail, cfg *mc.ElectConfigInfo_All) []Candidate
+}
+
+// Weigher assigns the election weight of every candidate. Candidates weighing
+// zero are never elected.
+type Weigher interface {
+	Weigh(candidates []Candidate) []WeightedCandidate
+}
+
+// Selector picks the elected nodes out of the weighted candidates, using seed
+// as the only source of randomness. The result is ordered by election rank.
+type Selector interface {
+	Select(candidates []WeightedCandidate, num int, seed *big.Int) []WeightedCandidate
+}
+
+// Elector is an election algorithm assembled from its three stages.
+type Elector struct {
+	Name     string
+	Source   CandidateSource
+	Weigher  Weigher
+	Selector Selector
+}
+
+// Elect runs all the stages of the election.
+func (e *Elector) Elect(deposits []vm.DepositDetail, cfg *mc.ElectConfigInfo_All, num int, seed *big.Int) []WeightedCandidate {
+	return e.Selector.Select(e.Weigher.Weigh(e.Source.Candidates(deposits, cfg)), num, seed)
+}
+
+var (
+	electorsMu sync.RWMutex
+	electors   = make(map[string]*Elector)
+)
+
+// Register adds an election algorithm to the set that forks can activate.
+func Register(e *Elector) error {
+	if e == nil || e.Name == "" || e.Source == nil || e.Weigher == nil || e.Selector == nil {
+		return ErrInvalidElector
+	}
+	electorsMu.Lock()
+	defer electorsMu.Unlock()
+
+	if _, ok := electors[e.Name]; ok {
+		return ErrElectorExists
+	}
+	electors[e.Name] = e
+	return nil
+}
+
+// Lookup retrieves a registered election algorithm by name.
+func Lookup(name string) (*Elector, bool) {
+	electorsMu.RLock()
+	defer electorsMu.RUnlock()
+
+	e, ok := electors[name]
+	return e, ok
+}
+
+// ElectorAt resolves the election algorithm active at an election height: the
+// one of the last fork at or below the height, or the default one if no fork
+// was reached yet.
+func ElectorAt(forks []mc.ElectorFork, number uint64) (*Elector, error) {
+	name, activated := DefaultElector, uint64(0)
+	for _, fork := range forks {
+		if fork.Number <= number && fork.Number >= activated {
+			name, activated = fork.Elector, fork.Number
+		}
+	}
+	e, ok := Lookup(name)
+	if !ok {
+		return nil, ErrUnknownElector
+	}
+	return e, nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package elector
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func testDeposit(b byte, man int64) vm.DepositDetail {
+	return vm.DepositDetail{
+		Address:    common.BytesToAddress([]byte{b}),
+		Deposit:    new(big.Int).Mul(big.NewInt(man), manUnit),
+		WithdrawH:  new(big.Int),
+		OnlineTime: new(big.Int),
+	}
+}
+
+// Tests that the algorithm of the last reached fork is active.
+func TestElectorAt(t *testing.T) {
+	forks := []mc.ElectorFork{
+		{Number: 1000, Elector: ReputationElector},
+		{Number: 3000, Elector: "missing"},
+		{Number: 2000, Elector: AdaptiveElector},
+	}
+	tests := []struct {
+		number uint64
+		want   string
+		err    error
+	}{
+		{0, DefaultElector, nil},
+		{999, DefaultElector, nil},
+		{1000, ReputationElector, nil},
+		{2500, AdaptiveElector, nil},
+		{3000, "", ErrUnknownElector},
+	}
+	for _, tt := range tests {
+		e, err := ElectorAt(forks, tt.number)
+		if err != tt.err {
+			t.Errorf("number %d: error mismatch: have %v, want %v", tt.number, err, tt.err)
+			continue
+		}
+		if err == nil && e.Name != tt.want {
+			t.Errorf("number %d: elector mismatch: have %s, want %s", tt.number, e.Name, tt.want)
+		}
+	}
+}
+
+// Tests that electors can't be registered twice or incomplete.
+func TestRegister(t *testing.T) {
+	if err := Register(&Elector{Name: StakeElector, Source: DepositSource{}, Weigher: StakeWeigher{}, Selector: WeightedSelector{}}); err != ErrElectorExists {
+		t.Errorf("duplicate registration: have %v, want %v", err, ErrElectorExists)
+	}
+	if err := Register(&Elector{Name: "incomplete", Source: DepositSource{}}); err != ErrInvalidElector {
+		t.Errorf("incomplete registration: have %v, want %v", err, ErrInvalidElector)
+	}
+}
+
+// Tests that the deposit source honours the black and white lists.
+func TestDepositSource(t *testing.T) {
+	deposits := []vm.DepositDetail{testDeposit(1, 10), testDeposit(2, 10), testDeposit(3, 10), testDeposit(4, 0)}
+	withdrawing := testDeposit(5, 10)
+	withdrawing.WithdrawH = big.NewInt(100)
+	deposits = append(deposits, withdrawing)
+
+	cfg := &mc.ElectConfigInfo_All{BlackList: []common.Address{deposits[0].Address}}
+	if have := (DepositSource{}).Candidates(deposits, cfg); len(have) != 2 {
+		t.Errorf("black list: have %d candidates, want 2", len(have))
+	}
+	cfg = &mc.ElectConfigInfo_All{WhiteList: []common.Address{deposits[2].Address}, WhiteListSwitcher: true}
+	if have := (DepositSource{}).Candidates(deposits, cfg); len(have) != 1 || have[0].Address != deposits[2].Address {
+		t.Errorf("white list: have %v, want only %x", have, deposits[2].Address)
+	}
+}
+
+// Tests that the weighted selection is deterministic and never elects a node twice.
+func TestWeightedSelector(t *testing.T) {
+	var deposits []vm.DepositDetail
+	for i := 1; i <= 20; i++ {
+		deposits = append(deposits, testDeposit(byte(i), int64(i*1000)))
+	}
+	e, _ := Lookup(StakeElector)
+	chosen := e.Elect(deposits, nil, 10, big.NewInt(42))
+	if len(chosen) != 10 {
+		t.Fatalf("elected count mismatch: have %d, want 10", len(chosen))
+	}
+	seen := make(map[common.Address]bool)
+	for _, c := range chosen {
+		if seen[c.Address] {
+			t.Errorf("node %x elected twice", c.Address)
+		}
+		seen[c.Address] = true
+	}
+	if again := e.Elect(deposits, nil, 10, big.NewInt(42)); !reflect.DeepEqual(chosen, again) {
+		t.Errorf("election not deterministic")
+	}
+	if all := e.Elect(deposits, nil, 30, big.NewInt(42)); len(all) != len(deposits) {
+		t.Errorf("elected count mismatch: have %d, want %d", len(all), len(deposits))
+	}
+}
+
+// Tests that the adaptive selector grows the committee with the candidates.
+func TestAdaptiveSelector(t *testing.T) {
+	candidates := make([]WeightedCandidate, 20)
+	for i := range candidates {
+		candidates[i] = WeightedCandidate{Candidate: Candidate{Address: common.BytesToAddress([]byte{byte(i)})}, Weight: 1}
+	}
+	s := AdaptiveSelector{Selector: WeightedSelector{}, Base: 10, Factor: 5, Step: 2}
+	if have := s.Select(candidates, 4, big.NewInt(1)); len(have) != 8 {
+		t.Errorf("committee size mismatch: have %d, want 8", len(have))
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package elector
+
+import (
+	"math"
+
+	"github.com/MatrixAINetwork/go-matrix/baseinterface"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/election/support"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// ElectPlugName is the election plug name selecting the pluggable elector in
+// the election config. The algorithm itself is then chosen by the elector forks
+// of the matrix state.
+const ElectPlugName = "layerd_elector"
+
+const logInfo = "选举算法插件"
+
+type electorPlug struct{}
+
+func init() {
+	baseinterface.RegElectPlug(ElectPlugName, RegInit)
+}
+
+func RegInit() baseinterface.ElectionInterface {
+	return &electorPlug{}
+}
+
+// elector resolves the algorithm active at the election height.
+func (self *electorPlug) elector(stateDb *state.StateDBManage, number uint64) *Elector {
+	var forks []mc.ElectorFork
+	if stateDb != nil {
+		var err error
+		if forks, err = matrixstate.GetElectorForks(stateDb); err != nil {
+			log.Error(logInfo, "读取选举算法切换高度失败", err)
+		}
+	}
+	e, err := ElectorAt(forks, number)
+	if err != nil {
+		log.Error(logInfo, "选举算法未注册, 使用默认算法", err, "高度", number)
+		e, _ = Lookup(DefaultElector)
+	}
+	log.Info(logInfo, "选举算法", e.Name, "高度", number)
+	return e
+}
+
+func (self *electorPlug) MinerTopGen(mmrerm *mc.MasterMinerReElectionReqMsg, stateDb *state.StateDBManage) *mc.MasterMinerReElectionRsp {
+	e := self.elector(stateDb, mmrerm.SeqNum)
+	chosen := e.Elect(mmrerm.MinerList, &mmrerm.ElectConfig, int(mmrerm.ElectConfig.MinerNum), mmrerm.RandSeed)
+
+	rsp := &mc.MasterMinerReElectionRsp{SeqNum: mmrerm.SeqNum}
+	for i, c := range chosen {
+		rsp.MasterMiner = append(rsp.MasterMiner, mc.ElectNodeInfo{Account: c.Address, Position: uint16(i), Stock: 1, VIPLevel: common.VIP_Nil, Type: common.RoleMiner})
+	}
+	return rsp
+}
+
+func (self *electorPlug) ValidatorTopGen(mvrerm *mc.MasterValidatorReElectionReqMsg, stateDb *state.StateDBManage) *mc.MasterValidatorReElectionRsq {
+	e := self.elector(stateDb, mvrerm.SeqNum)
+
+	// 出块惩罚期内的节点不参与选举
+	blackList := &mc.BlockProduceSlashBlackList{}
+	prohibited := make(map[common.Address]bool)
+	for _, item := range mvrerm.BlockProduceBlackList.BlackList {
+		if item.ProhibitCycleCounter == 0 {
+			continue
+		}
+		prohibited[item.Address] = true
+		if item.ProhibitCycleCounter > 1 {
+			blackList.BlackList = append(blackList.BlackList, mc.UserBlockProduceSlash{Address: item.Address, ProhibitCycleCounter: item.ProhibitCycleCounter - 1})
+		}
+	}
+	deposits := make([]vm.DepositDetail, 0, len(mvrerm.ValidatorList))
+	for _, d := range mvrerm.ValidatorList {
+		if !prohibited[d.Address] {
+			deposits = append(deposits, d)
+		}
+	}
+
+	cfg := mvrerm.ElectConfig
+	candidateNum := 0
+	if 4*int(cfg.ValidatorNum) > int(cfg.BackValidator) {
+		candidateNum = 4*int(cfg.ValidatorNum) - int(cfg.BackValidator)
+	}
+	chosen := e.Elect(deposits, &cfg, int(cfg.ValidatorNum)+int(cfg.BackValidator)+candidateNum, mvrerm.RandSeed)
+
+	rsp := &mc.MasterValidatorReElectionRsq{SeqNum: mvrerm.SeqNum}
+	for _, c := range chosen {
+		switch {
+		case len(rsp.MasterValidator) < int(cfg.ValidatorNum):
+			rsp.MasterValidator = append(rsp.MasterValidator, validatorNode(c, len(rsp.MasterValidator), common.RoleValidator))
+		case len(rsp.BackUpValidator) < int(cfg.BackValidator):
+			rsp.BackUpValidator = append(rsp.BackUpValidator, validatorNode(c, len(rsp.BackUpValidator), common.RoleBackupValidator))
+		default:
+			rsp.CandidateValidator = append(rsp.CandidateValidator, validatorNode(c, len(rsp.CandidateValidator), common.RoleCandidateValidator))
+		}
+	}
+	if stateDb != nil {
+		matrixstate.SetBlockProduceBlackList(stateDb, blackList)
+	}
+	return rsp
+}
+
+func validatorNode(c WeightedCandidate, position int, role common.RoleType) mc.ElectNodeInfo {
+	stock := c.Weight
+	if stock > math.MaxUint16 {
+		stock = math.MaxUint16
+	} else if stock == 0 {
+		stock = 1
+	}
+	return mc.ElectNodeInfo{Account: c.Address, Position: uint16(position), Stock: uint16(stock), VIPLevel: common.VIP_Nil, Type: role}
+}
+
+func (self *electorPlug) ToPoUpdate(allNative support.AllNative, topoG *mc.TopologyGraph) []mc.Alternative {
+	return support.ToPoUpdate(allNative, topoG)
+}
+
+func (self *electorPlug) PrimarylistUpdate(Q0, Q1, Q2 []mc.TopologyNodeInfo, online mc.TopologyNodeInfo, flag int) ([]mc.TopologyNodeInfo, []mc.TopologyNodeInfo, []mc.TopologyNodeInfo) {
+	return support.PrimarylistUpdate(Q0, Q1, Q2, online, flag)
+}
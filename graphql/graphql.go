@@ -0,0 +1,628 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+// Package graphql provides a GraphQL interface to Matrix node data.
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/man"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+var (
+	errBlockInvariant = errors.New("block can be queried by either number or hash, not both")
+	errUnknownBlock   = errors.New("unknown block")
+)
+
+// Account represents a Matrix account at a particular block.
+type Account struct {
+	backend *man.Matrix
+	address common.Address
+	roots   []common.CoinRoot
+}
+
+func (a *Account) getState() (*state.StateDBManage, error) {
+	return a.backend.BlockChain().StateAt(a.roots)
+}
+
+func (a *Account) Address(ctx context.Context) (Address, error) {
+	return Address{a.address}, nil
+}
+
+func (a *Account) Balance(ctx context.Context, args struct{ Currency *string }) (BigInt, error) {
+	st, err := a.getState()
+	if err != nil {
+		return BigInt{}, err
+	}
+	for _, slice := range st.GetBalance(currencyOf(args.Currency), a.address) {
+		if slice.AccountType == common.MainAccount {
+			return newBigInt(slice.Balance), nil
+		}
+	}
+	return BigInt{}, nil
+}
+
+func (a *Account) TransactionCount(ctx context.Context, args struct{ Currency *string }) (Long, error) {
+	st, err := a.getState()
+	if err != nil {
+		return 0, err
+	}
+	return Long(st.GetNonce(currencyOf(args.Currency), a.address)), nil
+}
+
+func (a *Account) Code(ctx context.Context) (Bytes, error) {
+	st, err := a.getState()
+	if err != nil {
+		return Bytes{}, err
+	}
+	return Bytes{st.GetCode(params.MAN_COIN, a.address)}, nil
+}
+
+func currencyOf(currency *string) string {
+	if currency == nil || *currency == "" {
+		return params.MAN_COIN
+	}
+	return *currency
+}
+
+// Log represents an individual log message. All arguments are mandatory.
+type Log struct {
+	backend     *man.Matrix
+	transaction *Transaction
+	log         *types.Log
+}
+
+func (l *Log) Transaction(ctx context.Context) *Transaction {
+	return l.transaction
+}
+
+func (l *Log) Account(ctx context.Context) *Account {
+	return &Account{backend: l.backend, address: l.log.Address, roots: l.transaction.block.Root()}
+}
+
+func (l *Log) Index(ctx context.Context) int32 {
+	return int32(l.log.Index)
+}
+
+func (l *Log) Topics(ctx context.Context) []Bytes32 {
+	topics := make([]Bytes32, len(l.log.Topics))
+	for i, topic := range l.log.Topics {
+		topics[i] = Bytes32{topic}
+	}
+	return topics
+}
+
+func (l *Log) Data(ctx context.Context) Bytes {
+	return Bytes{l.log.Data}
+}
+
+// Transaction represents a transaction included in a block.
+type Transaction struct {
+	backend  *man.Matrix
+	tx       types.SelfTransaction
+	block    *types.Block
+	currency string
+	index    uint64
+}
+
+func (t *Transaction) Hash(ctx context.Context) Bytes32 {
+	return Bytes32{t.tx.Hash()}
+}
+
+func (t *Transaction) Currency(ctx context.Context) string {
+	return t.currency
+}
+
+func (t *Transaction) Nonce(ctx context.Context) Long {
+	return Long(t.tx.Nonce())
+}
+
+func (t *Transaction) Index(ctx context.Context) *int32 {
+	index := int32(t.index)
+	return &index
+}
+
+func (t *Transaction) From(ctx context.Context) *Account {
+	return &Account{backend: t.backend, address: t.tx.From(), roots: t.block.Root()}
+}
+
+func (t *Transaction) To(ctx context.Context) *Account {
+	to := t.tx.To()
+	if to == nil {
+		return nil
+	}
+	return &Account{backend: t.backend, address: *to, roots: t.block.Root()}
+}
+
+func (t *Transaction) Value(ctx context.Context) BigInt {
+	return newBigInt(t.tx.Value())
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) BigInt {
+	return newBigInt(t.tx.GasPrice())
+}
+
+func (t *Transaction) Gas(ctx context.Context) Long {
+	return Long(t.tx.Gas())
+}
+
+func (t *Transaction) InputData(ctx context.Context) Bytes {
+	return Bytes{t.tx.Data()}
+}
+
+func (t *Transaction) Block(ctx context.Context) *Block {
+	return &Block{backend: t.backend, block: t.block}
+}
+
+func (t *Transaction) getReceipt() *types.Receipt {
+	for _, receipts := range t.backend.BlockChain().GetReceiptsByHash(t.block.Hash()) {
+		if receipts.CoinType != t.currency {
+			continue
+		}
+		for _, receipt := range receipts.Receiptlist {
+			if receipt.TxHash == t.tx.Hash() {
+				return receipt
+			}
+		}
+	}
+	return nil
+}
+
+func (t *Transaction) Status(ctx context.Context) *Long {
+	receipt := t.getReceipt()
+	if receipt == nil {
+		return nil
+	}
+	status := Long(receipt.Status)
+	return &status
+}
+
+func (t *Transaction) GasUsed(ctx context.Context) *Long {
+	receipt := t.getReceipt()
+	if receipt == nil {
+		return nil
+	}
+	gasUsed := Long(receipt.GasUsed)
+	return &gasUsed
+}
+
+func (t *Transaction) Logs(ctx context.Context) *[]*Log {
+	receipt := t.getReceipt()
+	if receipt == nil {
+		return nil
+	}
+	logs := make([]*Log, 0, len(receipt.Logs))
+	for _, log := range receipt.Logs {
+		logs = append(logs, &Log{backend: t.backend, transaction: t, log: log})
+	}
+	return &logs
+}
+
+// ElectedNode represents a node of the election graph, optionally along with
+// its heartbeat in a broadcast interval.
+type ElectedNode struct {
+	backend   *man.Matrix
+	roots     []common.CoinRoot
+	info      mc.ElectNodeInfo
+	required  bool
+	heartbeat []byte
+}
+
+func (n *ElectedNode) Account(ctx context.Context) *Account {
+	return &Account{backend: n.backend, address: n.info.Account, roots: n.roots}
+}
+
+func (n *ElectedNode) Role(ctx context.Context) string {
+	return n.info.Type.String()
+}
+
+func (n *ElectedNode) Position(ctx context.Context) int32 {
+	return int32(n.info.Position)
+}
+
+func (n *ElectedNode) Stock(ctx context.Context) int32 {
+	return int32(n.info.Stock)
+}
+
+func (n *ElectedNode) VipLevel(ctx context.Context) int32 {
+	return int32(n.info.VIPLevel)
+}
+
+func (n *ElectedNode) HeartbeatRequired(ctx context.Context) bool {
+	return n.required
+}
+
+func (n *ElectedNode) Heartbeat(ctx context.Context) *Bytes {
+	if n.heartbeat == nil {
+		return nil
+	}
+	return &Bytes{n.heartbeat}
+}
+
+// roleMatches reports whether an elected role passes a role filter of the
+// schema: a role name or one of the role families "validator" and "miner".
+func roleMatches(rt common.RoleType, role *string) bool {
+	if role == nil {
+		return true
+	}
+	switch *role {
+	case "validator":
+		return rt == common.RoleValidator || rt == common.RoleBackupValidator || rt == common.RoleCandidateValidator
+	case "miner":
+		return rt == common.RoleMiner || rt == common.RoleBackupMiner || rt == common.RoleInnerMiner
+	}
+	return rt.String() == *role
+}
+
+// BroadcastIntervalInfo represents the broadcast interval configuration at a block.
+type BroadcastIntervalInfo struct {
+	info *mc.BCIntervalInfo
+}
+
+func (i *BroadcastIntervalInfo) Interval(ctx context.Context) Long {
+	return Long(i.info.BCInterval)
+}
+
+func (i *BroadcastIntervalInfo) LastBroadcastNumber(ctx context.Context) Long {
+	return Long(i.info.LastBCNumber)
+}
+
+func (i *BroadcastIntervalInfo) LastReelectNumber(ctx context.Context) Long {
+	return Long(i.info.LastReelectNumber)
+}
+
+// Block represents a block of the chain.
+type Block struct {
+	backend *man.Matrix
+	block   *types.Block
+}
+
+func (b *Block) Number(ctx context.Context) Long {
+	return Long(b.block.NumberU64())
+}
+
+func (b *Block) Hash(ctx context.Context) Bytes32 {
+	return Bytes32{b.block.Hash()}
+}
+
+func (b *Block) Parent(ctx context.Context) *Block {
+	if b.block.NumberU64() == 0 {
+		return nil
+	}
+	parent := b.backend.BlockChain().GetBlockByHash(b.block.ParentHash())
+	if parent == nil {
+		return nil
+	}
+	return &Block{backend: b.backend, block: parent}
+}
+
+func (b *Block) Nonce(ctx context.Context) Long {
+	return Long(b.block.Nonce())
+}
+
+func (b *Block) Miner(ctx context.Context) *Account {
+	return &Account{backend: b.backend, address: b.block.Coinbase(), roots: b.block.Root()}
+}
+
+func (b *Block) ExtraData(ctx context.Context) Bytes {
+	return Bytes{b.block.Extra()}
+}
+
+func (b *Block) GasLimit(ctx context.Context) Long {
+	return Long(b.block.GasLimit())
+}
+
+func (b *Block) GasUsed(ctx context.Context) Long {
+	return Long(b.block.GasUsed())
+}
+
+func (b *Block) Timestamp(ctx context.Context) BigInt {
+	return newBigInt(b.block.Time())
+}
+
+func (b *Block) Difficulty(ctx context.Context) BigInt {
+	return newBigInt(b.block.Difficulty())
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (BigInt, error) {
+	td := b.backend.BlockChain().GetTdByHash(b.block.Hash())
+	if td == nil {
+		return BigInt{}, errUnknownBlock
+	}
+	return newBigInt(td), nil
+}
+
+func (b *Block) IsBroadcast(ctx context.Context) bool {
+	return manparams.IsBroadcastNumberByHash(b.block.NumberU64(), b.block.ParentHash())
+}
+
+func (b *Block) TransactionCount(ctx context.Context) int32 {
+	count := 0
+	for _, currency := range b.block.Currencies() {
+		count += len(currency.Transactions.GetTransactions())
+	}
+	return int32(count)
+}
+
+func (b *Block) Transactions(ctx context.Context) []*Transaction {
+	var txs []*Transaction
+	for _, currency := range b.block.Currencies() {
+		for i, tx := range currency.Transactions.GetTransactions() {
+			txs = append(txs, &Transaction{
+				backend:  b.backend,
+				tx:       tx,
+				block:    b.block,
+				currency: currency.CurrencyName,
+				index:    uint64(i),
+			})
+		}
+	}
+	return txs
+}
+
+func (b *Block) Account(ctx context.Context, args struct{ Address Address }) *Account {
+	return &Account{backend: b.backend, address: args.Address.Address, roots: b.block.Root()}
+}
+
+func (b *Block) ElectedNodes(ctx context.Context, args struct{ Role *string }) ([]*ElectedNode, error) {
+	st, err := b.backend.BlockChain().StateAt(b.block.Root())
+	if err != nil {
+		return nil, err
+	}
+	graph, err := matrixstate.GetElectGraph(st)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []*ElectedNode
+	for _, info := range graph.ElectList {
+		if roleMatches(info.Type, args.Role) {
+			nodes = append(nodes, &ElectedNode{backend: b.backend, roots: b.block.Root(), info: info})
+		}
+	}
+	return nodes, nil
+}
+
+func (b *Block) BroadcastInterval(ctx context.Context) (*BroadcastIntervalInfo, error) {
+	st, err := b.backend.BlockChain().StateAt(b.block.Root())
+	if err != nil {
+		return nil, err
+	}
+	info, err := matrixstate.GetBroadcastInterval(st)
+	if err != nil {
+		return nil, err
+	}
+	return &BroadcastIntervalInfo{info}, nil
+}
+
+// BroadcastInterval represents a finished broadcast interval.
+type BroadcastInterval struct {
+	backend  *man.Matrix
+	snapshot core.BroadcastSnapshot
+	block    *types.Block  // Broadcast block closing the interval
+	seed     *types.Header // Broadcast block preceding the interval
+}
+
+func (i *BroadcastInterval) Number(ctx context.Context) Long {
+	return Long(i.snapshot.Interval)
+}
+
+func (i *BroadcastInterval) BroadcastBlock(ctx context.Context) *Block {
+	return &Block{backend: i.backend, block: i.block}
+}
+
+func (i *BroadcastInterval) ElectedNodes(ctx context.Context, args struct {
+	Role            *string
+	MissedHeartbeat *bool
+}) ([]*ElectedNode, error) {
+	bc := i.backend.BlockChain()
+
+	// The election graph in force is the one the interval was built on
+	parent := bc.GetBlockByHash(i.block.ParentHash())
+	if parent == nil {
+		return nil, errUnknownBlock
+	}
+	st, err := bc.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	graph, err := matrixstate.GetElectGraph(st)
+	if err != nil {
+		return nil, err
+	}
+	// Heartbeats may be sent from any account of a node, index them by deposit account
+	heartbeats := make(map[common.Address][]byte)
+	for sender, payload := range i.snapshot.Heartbeats() {
+		account, _, err := bc.GetA0AccountFromAnyAccount(sender, i.block.Hash())
+		if err != nil {
+			continue
+		}
+		heartbeats[account] = payload
+	}
+	period := i.block.NumberU64() - i.seed.Number.Uint64()
+
+	var nodes []*ElectedNode
+	for _, info := range graph.ElectList {
+		if !roleMatches(info.Type, args.Role) {
+			continue
+		}
+		node := &ElectedNode{
+			backend:   i.backend,
+			roots:     i.block.Root(),
+			info:      info,
+			required:  core.IsHeartbeatTurn(i.seed.Roots, info.Account, period),
+			heartbeat: heartbeats[info.Account],
+		}
+		if args.MissedHeartbeat != nil {
+			if !node.required || *args.MissedHeartbeat != (node.heartbeat == nil) {
+				continue
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// SyncState represents the synchronisation status returned from the `syncing` accessor.
+type SyncState struct {
+	startingBlock, currentBlock, highestBlock uint64
+}
+
+func (s *SyncState) StartingBlock() Long {
+	return Long(s.startingBlock)
+}
+
+func (s *SyncState) CurrentBlock() Long {
+	return Long(s.currentBlock)
+}
+
+func (s *SyncState) HighestBlock() Long {
+	return Long(s.highestBlock)
+}
+
+// Resolver is the top-level object in the GraphQL hierarchy.
+type Resolver struct {
+	backend *man.Matrix
+}
+
+type BlockArgs struct {
+	Number *Long
+	Hash   *Bytes32
+}
+
+func (r *Resolver) Block(ctx context.Context, args BlockArgs) (*Block, error) {
+	bc := r.backend.BlockChain()
+
+	var block *types.Block
+	switch {
+	case args.Number != nil && args.Hash != nil:
+		return nil, errBlockInvariant
+	case args.Number != nil:
+		block = bc.GetBlockByNumber(uint64(*args.Number))
+	case args.Hash != nil:
+		block = bc.GetBlockByHash(args.Hash.Hash)
+	default:
+		block = bc.CurrentBlock()
+	}
+	if block == nil {
+		return nil, nil
+	}
+	return &Block{backend: r.backend, block: block}, nil
+}
+
+func (r *Resolver) Blocks(ctx context.Context, args struct {
+	From Long
+	To   *Long
+}) ([]*Block, error) {
+	bc := r.backend.BlockChain()
+
+	var to uint64
+	if args.To != nil {
+		to = uint64(*args.To)
+	} else {
+		to = bc.CurrentBlock().NumberU64()
+	}
+	if to < uint64(args.From) {
+		return []*Block{}, nil
+	}
+	var blocks []*Block
+	for number := uint64(args.From); number <= to; number++ {
+		block := bc.GetBlockByNumber(number)
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, &Block{backend: r.backend, block: block})
+	}
+	return blocks, nil
+}
+
+func (r *Resolver) Transaction(ctx context.Context, args struct{ Hash Bytes32 }) (*Transaction, error) {
+	_, blockHash, _, _ := rawdb.ReadTransaction(r.backend.ChainDb(), args.Hash.Hash)
+	if blockHash == (common.Hash{}) {
+		return nil, nil
+	}
+	block := r.backend.BlockChain().GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, nil
+	}
+	for _, currency := range block.Currencies() {
+		for i, tx := range currency.Transactions.GetTransactions() {
+			if tx.Hash() == args.Hash.Hash {
+				return &Transaction{
+					backend:  r.backend,
+					tx:       tx,
+					block:    block,
+					currency: currency.CurrencyName,
+					index:    uint64(i),
+				}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) BroadcastInterval(ctx context.Context, args struct{ Number Long }) (*BroadcastInterval, error) {
+	bc := r.backend.BlockChain()
+
+	snapshot, err := core.GetBroadcastDataByInterval(bc, uint64(args.Number))
+	if err == core.ErrFutureBroadcastInterval {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	block := bc.GetBlockByHash(snapshot.Hash)
+	if block == nil {
+		return nil, errUnknownBlock
+	}
+	// Locate the broadcast block preceding the interval, seeding the heartbeat turns
+	var seed *types.Header
+	for number := snapshot.Number; number > 0 && seed == nil; {
+		number--
+		header := bc.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		if number == 0 || manparams.IsBroadcastNumberByHash(number, header.ParentHash) {
+			seed = header
+		}
+	}
+	if seed == nil {
+		return nil, core.ErrBroadcastBlockNotFound
+	}
+	return &BroadcastInterval{backend: r.backend, snapshot: snapshot, block: block, seed: seed}, nil
+}
+
+func (r *Resolver) GasPrice(ctx context.Context) (BigInt, error) {
+	price, err := r.backend.APIBackend.SuggestPrice(ctx)
+	return newBigInt(price), err
+}
+
+func (r *Resolver) ProtocolVersion(ctx context.Context) (int32, error) {
+	return int32(r.backend.APIBackend.ProtocolVersion()), nil
+}
+
+func (r *Resolver) Syncing() (*SyncState, error) {
+	progress := r.backend.Downloader().Progress()
+
+	// Return not syncing if the synchronisation already completed
+	if progress.CurrentBlock >= progress.HighestBlock {
+		return nil, nil
+	}
+	return &SyncState{
+		startingBlock: progress.StartingBlock,
+		currentBlock:  progress.CurrentBlock,
+		highestBlock:  progress.HighestBlock,
+	}, nil
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package graphql
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Tests that the schema matches the resolvers.
+func TestBuildSchema(t *testing.T) {
+	if _, err := graphql.ParseSchema(schema, &Resolver{}); err != nil {
+		t.Fatalf("could not create new schema: %v", err)
+	}
+}
+
+// Tests that addresses are accepted both in MAN and in hex format.
+func TestAddressUnmarshal(t *testing.T) {
+	want := common.HexToAddress("0x8111111111111111111111111111111111111111")
+	for _, input := range []string{want.Hex(), base58.Base58EncodeToString(params.MAN_COIN, want)} {
+		var addr Address
+		if err := addr.UnmarshalGraphQL(input); err != nil {
+			t.Fatalf("%s: failed to unmarshal: %v", input, err)
+		}
+		if addr.Address != want {
+			t.Errorf("%s: address mismatch: have %x, want %x", input, addr.Address, want)
+		}
+	}
+	var addr Address
+	if err := addr.UnmarshalGraphQL(int32(1)); err == nil {
+		t.Errorf("non-string address accepted")
+	}
+}
+
+// Tests that role filters match both role names and role families.
+func TestRoleMatches(t *testing.T) {
+	validator, miner, backup := "validator", "miner", "backup validator"
+	tests := []struct {
+		rt   common.RoleType
+		role *string
+		want bool
+	}{
+		{common.RoleValidator, nil, true},
+		{common.RoleValidator, &validator, true},
+		{common.RoleBackupValidator, &validator, true},
+		{common.RoleMiner, &validator, false},
+		{common.RoleInnerMiner, &miner, true},
+		{common.RoleBackupValidator, &backup, true},
+		{common.RoleValidator, &backup, false},
+	}
+	for i, tt := range tests {
+		if have := roleMatches(tt.rt, tt.role); have != tt.want {
+			t.Errorf("test %d: role %v: have %v, want %v", i, tt.rt, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package graphql
+
+const schema string = `
+    # Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
+    scalar Bytes32
+    # Address is a 20 byte account address, represented in the MAN base58
+    # format. Hexadecimal addresses are accepted as input too.
+    scalar Address
+    # Bytes is an arbitrary length binary string, represented as 0x-prefixed hexadecimal.
+    scalar Bytes
+    # BigInt is a large integer, represented as 0x-prefixed hexadecimal.
+    scalar BigInt
+    # Long is a 64 bit unsigned integer.
+    scalar Long
+
+    schema {
+        query: Query
+    }
+
+    # Account is an account at a particular block.
+    type Account {
+        # Address is the address owning the account.
+        address: Address!
+        # Balance is the main account balance in the given currency, MAN by default.
+        balance(currency: String): BigInt!
+        # TransactionCount is the number of transactions sent from this account
+        # in the given currency, MAN by default.
+        transactionCount(currency: String): Long!
+        # Code contains the smart contract code for this account, if the account
+        # is a (non-self-destructed) contract.
+        code: Bytes!
+    }
+
+    # Log is a log entry emitted by a contract.
+    type Log {
+        # Index is the index of this log in the block.
+        index: Int!
+        # Account is the account which generated this log.
+        account: Account!
+        # Topics is the list of 0-4 indexed topics for the log.
+        topics: [Bytes32!]!
+        # Data is unindexed data for this log.
+        data: Bytes!
+        # Transaction is the transaction that generated this log entry.
+        transaction: Transaction!
+    }
+
+    # Transaction is a transaction included in a block.
+    type Transaction {
+        # Hash is the hash of this transaction.
+        hash: Bytes32!
+        # Currency is the coin the transaction is sent in.
+        currency: String!
+        # Nonce is the nonce of the account this transaction was generated with.
+        nonce: Long!
+        # Index is the index of this transaction in the transactions of its
+        # currency within the block.
+        index: Int
+        # From is the account that sent this transaction.
+        from: Account!
+        # To is the account the transaction was sent to. This is null for
+        # contract-creating transactions.
+        to: Account
+        # Value is the value sent along with this transaction.
+        value: BigInt!
+        # GasPrice is the price offered per unit of gas.
+        gasPrice: BigInt!
+        # Gas is the maximum amount of gas this transaction can consume.
+        gas: Long!
+        # InputData is the data supplied to the target of the transaction.
+        inputData: Bytes!
+        # Block is the block this transaction was mined in.
+        block: Block
+        # Status is the return status of the transaction, 1 for success.
+        status: Long
+        # GasUsed is the amount of gas that was used processing this transaction.
+        gasUsed: Long
+        # Logs is a list of log entries emitted by this transaction.
+        logs: [Log!]
+    }
+
+    # ElectedNode is a node of the election graph.
+    type ElectedNode {
+        # Account is the deposit account of the node.
+        account: Account!
+        # Role is the elected role, e.g. "validator" or "backup miner".
+        role: String!
+        # Position is the position of the node in the topology.
+        position: Int!
+        # Stock is the election weight of the node.
+        stock: Int!
+        # VipLevel is the VIP level of the node.
+        vipLevel: Int!
+        # HeartbeatRequired is whether the node had to send a heartbeat in the
+        # broadcast interval. Always false outside of a broadcast interval.
+        heartbeatRequired: Boolean!
+        # Heartbeat is the heartbeat payload the node sent in the broadcast
+        # interval, null if it sent none or outside of a broadcast interval.
+        heartbeat: Bytes
+    }
+
+    # BroadcastInterval is a finished broadcast interval, the blocks between two
+    # broadcast blocks.
+    type BroadcastInterval {
+        # Number is the sequence number of the interval.
+        number: Long!
+        # BroadcastBlock is the broadcast block closing the interval, committing
+        # the broadcast transactions sent within it.
+        broadcastBlock: Block!
+        # ElectedNodes is the election graph in force during the interval. The
+        # role filter accepts a role name, or "validator" and "miner" for all
+        # validator and miner roles. If missedHeartbeat is set, only nodes that
+        # had to send a heartbeat and did (false) or did not (true) are returned.
+        electedNodes(role: String, missedHeartbeat: Boolean): [ElectedNode!]!
+    }
+
+    # Block is a block of the chain.
+    type Block {
+        # Number is the number of this block, starting at 0 for the genesis block.
+        number: Long!
+        # Hash is the block hash of this block.
+        hash: Bytes32!
+        # Parent is the parent block of this block.
+        parent: Block
+        # Nonce is the block nonce, an 8 byte sequence determined by the miner.
+        nonce: Long!
+        # Miner is the account that mined this block.
+        miner: Account!
+        # ExtraData is an arbitrary data field supplied by the miner.
+        extraData: Bytes!
+        # GasLimit is the maximum amount of gas that was available to transactions in this block.
+        gasLimit: Long!
+        # GasUsed is the amount of gas that was used executing transactions in this block.
+        gasUsed: Long!
+        # Timestamp is the unix timestamp at which this block was mined.
+        timestamp: BigInt!
+        # Difficulty is the difficulty of the block.
+        difficulty: BigInt!
+        # TotalDifficulty is the sum of all difficulty values up to and including this block.
+        totalDifficulty: BigInt!
+        # IsBroadcast is whether this is a broadcast block.
+        isBroadcast: Boolean!
+        # TransactionCount is the number of transactions in this block over all currencies.
+        transactionCount: Int!
+        # Transactions is the list of transactions in this block over all currencies.
+        transactions: [Transaction!]!
+        # Account fetches a Matrix account at the current block's state.
+        account(address: Address!): Account!
+        # ElectedNodes is the election graph in force at this block, see
+        # BroadcastInterval.electedNodes for the role filter.
+        electedNodes(role: String): [ElectedNode!]!
+        # BroadcastInterval is the broadcast interval in force at this block.
+        broadcastInterval: BroadcastIntervalInfo!
+    }
+
+    # BroadcastIntervalInfo is the broadcast interval configuration at a block.
+    type BroadcastIntervalInfo {
+        # Interval is the number of blocks between two broadcast blocks.
+        interval: Long!
+        # LastBroadcastNumber is the number of the last broadcast block.
+        lastBroadcastNumber: Long!
+        # LastReelectNumber is the number of the last reelection block.
+        lastReelectNumber: Long!
+    }
+
+    # SyncState contains the current synchronisation state of the client.
+    type SyncState {
+        # StartingBlock is the block number at which synchronisation started.
+        startingBlock: Long!
+        # CurrentBlock is the point at which synchronisation has presently reached.
+        currentBlock: Long!
+        # HighestBlock is the latest known block number.
+        highestBlock: Long!
+    }
+
+    type Query {
+        # Block fetches a block by number or by hash. If neither is supplied,
+        # the most recent known block is returned.
+        block(number: Long, hash: Bytes32): Block
+        # Blocks returns all the blocks between two numbers, inclusive. If to is
+        # not supplied, it defaults to the most recent known block.
+        blocks(from: Long!, to: Long): [Block!]!
+        # Transaction returns a transaction specified by its hash.
+        transaction(hash: Bytes32!): Transaction
+        # BroadcastInterval returns a finished broadcast interval by number.
+        broadcastInterval(number: Long!): BroadcastInterval
+        # GasPrice returns the node's estimate of a gas price sufficient to
+        # ensure a transaction is mined in a timely fashion.
+        gasPrice: BigInt!
+        # ProtocolVersion returns the current wire protocol version number.
+        protocolVersion: Int!
+        # Syncing returns information on the current synchronisation state.
+        syncing: SyncState
+    }
+`
@@ -0,0 +1,115 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package graphql
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+// Long is a 64 bit unsigned integer, accepted as a number or a hex string.
+type Long uint64
+
+func (l Long) ImplementsGraphQLType(name string) bool { return name == "Long" }
+
+func (l *Long) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case string:
+		value, err := hexutil.DecodeUint64(input)
+		*l = Long(value)
+		return err
+	case int32:
+		*l = Long(input)
+	case int64:
+		*l = Long(input)
+	case float64:
+		*l = Long(input)
+	default:
+		return fmt.Errorf("unexpected type %T for Long", input)
+	}
+	return nil
+}
+
+// BigInt is an arbitrary precision integer, encoded as a hex string.
+type BigInt struct{ hexutil.Big }
+
+func newBigInt(b *big.Int) BigInt {
+	if b == nil {
+		return BigInt{}
+	}
+	return BigInt{hexutil.Big(*b)}
+}
+
+func (b BigInt) ImplementsGraphQLType(name string) bool { return name == "BigInt" }
+
+func (b *BigInt) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case string:
+		return b.Big.UnmarshalText([]byte(input))
+	case int32:
+		b.Big = hexutil.Big(*big.NewInt(int64(input)))
+	default:
+		return fmt.Errorf("unexpected type %T for BigInt", input)
+	}
+	return nil
+}
+
+// Bytes is an arbitrary length binary blob, encoded as a hex string.
+type Bytes struct{ hexutil.Bytes }
+
+func (b Bytes) ImplementsGraphQLType(name string) bool { return name == "Bytes" }
+
+func (b *Bytes) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for Bytes", input)
+	}
+	return b.Bytes.UnmarshalText([]byte(s))
+}
+
+// Bytes32 is a 32 byte hash, encoded as a hex string.
+type Bytes32 struct{ common.Hash }
+
+func (h Bytes32) ImplementsGraphQLType(name string) bool { return name == "Bytes32" }
+
+func (h *Bytes32) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for Bytes32", input)
+	}
+	return h.Hash.UnmarshalText([]byte(s))
+}
+
+// Address is an account address. It is rendered in the base58 MAN format and
+// accepted both in that format and as a hex string.
+type Address struct{ common.Address }
+
+func (a Address) ImplementsGraphQLType(name string) bool { return name == "Address" }
+
+func (a *Address) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for Address", input)
+	}
+	if strings.HasPrefix(s, "0x") {
+		return a.Address.UnmarshalText([]byte(s))
+	}
+	addr, err := base58.Base58DecodeToAddress(s)
+	if err != nil {
+		return err
+	}
+	a.Address = addr
+	return nil
+}
+
+func (a Address) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + base58.Base58EncodeToString(params.MAN_COIN, a.Address) + `"`), nil
+}
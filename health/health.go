@@ -0,0 +1,110 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+// Package health serves the liveness and readiness probes of a Matrix node,
+// for container orchestrators and load balancers fronting the RPC nodes.
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Config contains the readiness thresholds of the probes.
+type Config struct {
+	MinPeers    int           // Minimum number of peers of a ready node
+	MaxBlockAge time.Duration // Maximum age of the head block of a ready node, 0 disables the check
+}
+
+// DefaultConfig contains the default readiness thresholds.
+var DefaultConfig = Config{
+	MinPeers:    1,
+	MaxBlockAge: 2 * time.Minute,
+}
+
+// SyncStatus is the synchronisation status of the node.
+type SyncStatus struct {
+	Syncing      bool   `json:"syncing"`
+	CurrentBlock uint64 `json:"currentBlock"`
+	HighestBlock uint64 `json:"highestBlock"`
+	HeadHash     string `json:"headHash"`
+	HeadAge      int64  `json:"headAge"` // Seconds since the head block timestamp
+}
+
+// BroadcastStatus is the last broadcast interval processed by the node.
+type BroadcastStatus struct {
+	Interval      uint64 `json:"interval"`
+	LastBroadcast uint64 `json:"lastBroadcast"`
+	NextBroadcast uint64 `json:"nextBroadcast"`
+}
+
+// PoolStatus contains the sizes of the transaction pools.
+type PoolStatus struct {
+	Pending   int `json:"pending"`
+	Queued    int `json:"queued"`
+	Broadcast int `json:"broadcast"`
+}
+
+// ConsensusStatus is the participation of the node in the consensus.
+type ConsensusStatus struct {
+	Role    string `json:"role"`
+	Account string `json:"account,omitempty"` // Deposit account of the node, if any
+	Elected bool   `json:"elected"`           // Whether the deposit account is elected at the head
+}
+
+// Report is the status of the node served by the probes.
+type Report struct {
+	Ready     bool            `json:"ready"`
+	Reasons   []string        `json:"reasons,omitempty"` // Why the node is not ready
+	Sync      SyncStatus      `json:"sync"`
+	Peers     int             `json:"peers"`
+	Broadcast BroadcastStatus `json:"broadcast"`
+	TxPool    PoolStatus      `json:"txpool"`
+	Consensus ConsensusStatus `json:"consensus"`
+}
+
+// evaluate decides whether the node of the report is ready to serve requests.
+func (r *Report) evaluate(config Config) {
+	r.Reasons = nil
+	if r.Sync.Syncing {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("syncing, block %d of %d", r.Sync.CurrentBlock, r.Sync.HighestBlock))
+	}
+	if r.Peers < config.MinPeers {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("%d peers, need %d", r.Peers, config.MinPeers))
+	}
+	if config.MaxBlockAge > 0 && time.Duration(r.Sync.HeadAge)*time.Second > config.MaxBlockAge {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("head block is %ds old", r.Sync.HeadAge))
+	}
+	r.Ready = len(r.Reasons) == 0
+}
+
+// newHandler returns the HTTP handler of the probes. /health reports the
+// status of a running node and always succeeds, so that a syncing node is not
+// restarted. /ready fails with 503 Service Unavailable until the node is
+// ready.
+func newHandler(collect func() *Report, config Config) http.Handler {
+	serve := func(w http.ResponseWriter, r *http.Request, readiness bool) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		report := collect()
+		report.evaluate(config)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if readiness && !report.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(report)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) { serve(w, r, false) })
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) { serve(w, r, true) })
+	return mux
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProbes(t *testing.T) {
+	report := &Report{Sync: SyncStatus{CurrentBlock: 100, HighestBlock: 100, HeadAge: 5}, Peers: 3}
+	handler := newHandler(func() *Report { r := *report; return &r }, Config{MinPeers: 2, MaxBlockAge: time.Minute})
+
+	get := func(path string) (int, Report) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var got Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: invalid report %q: %v", path, rec.Body.String(), err)
+		}
+		return rec.Code, got
+	}
+
+	if code, got := get("/ready"); code != http.StatusOK || !got.Ready || got.Peers != 3 {
+		t.Errorf("ready node: code %d, report %+v", code, got)
+	}
+
+	tests := []func(r *Report){
+		func(r *Report) { r.Sync.Syncing, r.Sync.HighestBlock = true, 200 },
+		func(r *Report) { r.Peers = 1 },
+		func(r *Report) { r.Sync.HeadAge = 120 },
+	}
+	for i, modify := range tests {
+		report = &Report{Sync: SyncStatus{CurrentBlock: 100, HighestBlock: 100, HeadAge: 5}, Peers: 3}
+		modify(report)
+		code, got := get("/ready")
+		if code != http.StatusServiceUnavailable || got.Ready || len(got.Reasons) != 1 {
+			t.Errorf("test %d: code %d, report %+v", i, code, got)
+		}
+		// the liveness probe does not fail on an unready node
+		if code, _ := get("/health"); code != http.StatusOK {
+			t.Errorf("test %d: health code %d", i, code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/health", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("post: code %d", rec.Code)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package health
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/man"
+	"github.com/MatrixAINetwork/go-matrix/p2p"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// Service serves the health and readiness probes of the node.
+type Service struct {
+	endpoint string      // The host:port endpoint for this service.
+	config   Config      // Readiness thresholds
+	backend  *man.Matrix // The backend reporting the node status.
+	server   *p2p.Server
+	listener net.Listener
+}
+
+// New constructs a new health probe service instance.
+func New(backend *man.Matrix, endpoint string, config Config) (*Service, error) {
+	return &Service{
+		endpoint: endpoint,
+		config:   config,
+		backend:  backend,
+	}, nil
+}
+
+// Protocols returns the list of protocols exported by this service.
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs returns the list of APIs exported by this service.
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start is called after all services have been constructed and the networking
+// layer was also initialized to spawn any goroutines required by the service.
+func (s *Service) Start(server *p2p.Server) error {
+	var err error
+	if s.listener, err = net.Listen("tcp", s.endpoint); err != nil {
+		return err
+	}
+	s.server = server
+	// The probes are not wrapped into the virtual host checks of the RPC
+	// servers, orchestrators connect by the address of the node.
+	go (&http.Server{Handler: newHandler(s.collect, s.config)}).Serve(s.listener)
+
+	log.Info("Health endpoint opened", "url", fmt.Sprintf("http://%s", s.endpoint))
+	return nil
+}
+
+// Stop terminates all goroutines belonging to the service, blocking until they
+// are all terminated.
+func (s *Service) Stop() error {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+		log.Info("Health endpoint closed", "url", fmt.Sprintf("http://%s", s.endpoint))
+	}
+	return nil
+}
+
+// collect gathers the status of the node.
+func (s *Service) collect() *Report {
+	report := new(Report)
+
+	head := s.backend.BlockChain().CurrentBlock()
+	progress := s.backend.Downloader().Progress()
+	report.Sync = SyncStatus{
+		Syncing:      s.backend.Downloader().Synchronising(),
+		CurrentBlock: head.NumberU64(),
+		HighestBlock: progress.HighestBlock,
+		HeadHash:     head.Hash().Hex(),
+		HeadAge:      time.Now().Unix() - head.Time().Int64(),
+	}
+	if report.Sync.HighestBlock < report.Sync.CurrentBlock {
+		report.Sync.HighestBlock = report.Sync.CurrentBlock
+	}
+	if s.server != nil {
+		report.Peers = s.server.PeerCount()
+	}
+
+	if bcInterval, err := s.backend.BlockChain().GetBroadcastIntervalByHash(head.Hash()); err == nil {
+		last := bcInterval.GetLastBroadcastNumber()
+		report.Broadcast = BroadcastStatus{
+			Interval:      last / bcInterval.GetBroadcastInterval(),
+			LastBroadcast: last,
+			NextBroadcast: bcInterval.GetNextBroadcastNumber(head.NumberU64()),
+		}
+	}
+
+	if npool, err := s.backend.TxPool().GetTxPoolByType(types.NormalTxIndex); err == nil {
+		if stats, ok := npool.(interface{ Stats() (int, int) }); ok {
+			report.TxPool.Pending, report.TxPool.Queued = stats.Stats()
+		}
+	}
+	for _, txs := range s.backend.TxPool().GetAllSpecialTxs() {
+		report.TxPool.Broadcast += len(txs)
+	}
+
+	report.Consensus.Role = ca.GetRole().String()
+	if account := ca.GetDepositAddress(); account != (common.Address{}) {
+		report.Consensus.Account = base58.Base58EncodeToString(params.MAN_COIN, account)
+		report.Consensus.Elected = s.isElected(head, account)
+	}
+	return report
+}
+
+// isElected checks whether the deposit account is in the topology of the block.
+func (s *Service) isElected(block *types.Block, account common.Address) bool {
+	st, err := s.backend.BlockChain().StateAtBlockHash(block.Hash())
+	if err != nil {
+		return false
+	}
+	graph, err := matrixstate.GetTopologyGraph(st)
+	if err != nil || graph == nil {
+		return false
+	}
+	for _, node := range graph.NodeList {
+		if node.Account == account {
+			return true
+		}
+	}
+	return false
+}
@@ -10,7 +10,10 @@ package debug
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -36,6 +39,8 @@ type HandlerT struct {
 	cpuFile   string
 	traceW    io.WriteCloser
 	traceFile string
+	pprofSrv  *http.Server
+	pprofAddr string
 }
 
 // Verbosity sets the log verbosity ceiling. The verbosity of individual packages
@@ -194,6 +199,44 @@ func (*HandlerT) SetGCPercent(v int) int {
 	return debug.SetGCPercent(v)
 }
 
+// StartPProf starts the pprof HTTP server on the given address (e.g.
+// "127.0.0.1:6060"), so that a running node can be profiled without a restart.
+func (h *HandlerT) StartPProf(address string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pprofSrv != nil {
+		return fmt.Errorf("pprof server already running on %s", h.pprofAddr)
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	registerPProfHandlers()
+	h.pprofSrv = &http.Server{Handler: http.DefaultServeMux}
+	h.pprofAddr = listener.Addr().String()
+	log.Info("Starting pprof server", "addr", fmt.Sprintf("http://%s/debug/pprof", h.pprofAddr))
+	go func(srv *http.Server) {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error("Failure in running pprof server", "err", err)
+		}
+	}(h.pprofSrv)
+	return nil
+}
+
+// StopPProf stops the running pprof HTTP server.
+func (h *HandlerT) StopPProf() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pprofSrv == nil {
+		return errors.New("pprof server not running")
+	}
+	log.Info("Stopping pprof server", "addr", h.pprofAddr)
+	err := h.pprofSrv.Close()
+	h.pprofSrv = nil
+	h.pprofAddr = ""
+	return err
+}
+
 func writeProfile(name, file string) error {
 	p := pprof.Lookup(name)
 	log.Info("Writing profile records", "count", p.Count(), "type", name, "dump", file)
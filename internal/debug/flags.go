@@ -11,6 +11,7 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"runtime"
+	"sync"
 
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/log/term"
@@ -199,17 +200,25 @@ func Setup(ctx *cli.Context, logdir string) error {
 	return nil
 }
 
+var pprofOnce sync.Once
+
+// registerPProfHandlers adds the metrics and memsize handlers next to the pprof
+// handlers of the default mux. The mux panics on duplicate patterns, so the
+// handlers are added only once however often the server is toggled.
+func registerPProfHandlers() {
+	pprofOnce.Do(func() {
+		// Hook go-metrics into expvar on any /debug/metrics request, load all vars
+		// from the registry into expvar, and execute regular expvar handler.
+		exp.Exp(metrics.DefaultRegistry)
+		http.Handle("/memsize/", http.StripPrefix("/memsize", &Memsize))
+	})
+}
+
+// StartPProf starts the pprof HTTP server on the given address.
 func StartPProf(address string) {
-	// Hook go-metrics into expvar on any /debug/metrics request, load all vars
-	// from the registry into expvar, and execute regular expvar handler.
-	exp.Exp(metrics.DefaultRegistry)
-	http.Handle("/memsize/", http.StripPrefix("/memsize", &Memsize))
-	log.Info("Starting pprof server", "addr", fmt.Sprintf("http://%s/debug/pprof", address))
-	go func() {
-		if err := http.ListenAndServe(address, nil); err != nil {
-			log.Error("Failure in running pprof server", "err", err)
-		}
-	}()
+	if err := Handler.StartPProf(address); err != nil {
+		log.Error("Failure in running pprof server", "err", err)
+	}
 }
 
 // Exit stops all running profiles, flushing their output to the
@@ -217,4 +226,5 @@ func StartPProf(address string) {
 func Exit() {
 	Handler.StopCPUProfile()
 	Handler.StopGoTrace()
+	Handler.StopPProf()
 }
@@ -69,18 +69,50 @@ func NewPublicMatrixAPI(b Backend) *PublicMatrixAPI {
 	return &PublicMatrixAPI{b}
 }
 
-// GasPrice returns a suggestion for a gas price.
+// GasPrice returns a suggestion for a gas price. See suggestGasPrice for what
+// the price buys.
 func (s *PublicMatrixAPI) GasPrice(ctx context.Context) (*big.Int, error) {
-	//return s.b.SuggestPrice(ctx)
-	state, err := s.b.GetState()
+	return suggestGasPrice(ctx, s.b)
+}
+
+// SuggestGasPrice returns the gas price paid by the recent blocks at the
+// configured percentile, never below the minimum accepted by the tx pool.
+// The price only ranks the transaction in the tx pool, execution always charges
+// params.TxGasPrice (see GetGasPrice).
+func (s *PublicMatrixAPI) SuggestGasPrice(ctx context.Context) (*hexutil.Big, error) {
+	price, err := suggestGasPrice(ctx, s.b)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(price), nil
+}
+
+// suggestGasPrice asks the gas price oracle for a price sampled from the recent
+// blocks, falling back to the tx pool minimum gas price.
+//
+// The gas price of a transaction is a priority fee only: the state transition
+// charges every transaction params.TxGasPrice per gas whatever price it carries,
+// and the price merely decides which transactions the tx pool keeps and evicts
+// first when it is full. A suggestion above params.TxGasPrice therefore costs the
+// sender nothing extra.
+func suggestGasPrice(ctx context.Context, b Backend) (*big.Int, error) {
+	state, err := b.GetState()
 	if state == nil || err != nil {
 		return nil, err
 	}
-	gasprice, err := matrixstate.GetTxpoolGasLimit(state)
+	minPrice, err := matrixstate.GetTxpoolGasLimit(state)
 	if err != nil {
 		return nil, err
 	}
-	return gasprice, nil
+	price, err := b.SuggestPrice(ctx)
+	if err != nil {
+		log.Trace("gas price oracle failed", "err", err)
+		return minPrice, nil
+	}
+	if price == nil || price.Cmp(minPrice) < 0 {
+		return minPrice, nil
+	}
+	return price, nil
 }
 
 // ProtocolVersion returns the current Matrix protocol version this node supports
@@ -122,7 +154,18 @@ func NewPublicTxPoolAPI(b Backend) *PublicTxPoolAPI {
 	return &PublicTxPoolAPI{b}
 }
 
+// txPoolKey returns the key of a transaction within the per account pool dumps.
+// Transactions of other currencies are prefixed by their coin, since every
+// currency tracks its own nonces.
+func txPoolKey(tx types.SelfTransaction) string {
+	if coin := tx.GetTxCurrency(); coin != params.MAN_COIN {
+		return fmt.Sprintf("%s:%d", coin, tx.Nonce())
+	}
+	return fmt.Sprintf("%d", tx.Nonce())
+}
+
 // Content returns the transactions contained within the transaction pool.
+// Queued transactions are the ones stuck behind a nonce gap of their account.
 func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransaction {
 	content := map[string]map[string]map[string]*RPCTransaction{
 		"pending": make(map[string]map[string]*RPCTransaction),
@@ -134,7 +177,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[txPoolKey(tx)] = newRPCPendingTransaction(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -142,7 +185,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[txPoolKey(tx)] = newRPCPendingTransaction(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
@@ -177,16 +220,20 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 
 	// Define a formatter to flatten a transaction into a string
 	var format = func(tx types.SelfTransaction) string {
+		desc := fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasPrice())
 		if to := tx.To(); to != nil {
-			return fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To().Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
+			desc = fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To().Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
+		}
+		if typ := tx.GetMatrixType(); typ != common.ExtraNormalTxType {
+			desc = fmt.Sprintf("[type %d] %s", typ, desc)
 		}
-		return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasPrice())
+		return desc
 	}
 	// Flatten the pending transactions
 	for account, txs := range pending {
 		dump := make(map[string]string)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+			dump[txPoolKey(tx)] = format(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -194,13 +241,84 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	for account, txs := range queue {
 		dump := make(map[string]string)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+			dump[txPoolKey(tx)] = format(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
 	return content
 }
 
+// Scheduled returns the scheduled transactions of an account which are held by
+// the transaction pool until their target block height or time.
+func (s *PublicTxPoolAPI) Scheduled(strAddress string) (map[string]*RPCTransaction, error) {
+	account, err := base58.Base58DecodeToAddress(strAddress)
+	if err != nil {
+		return nil, err
+	}
+	dump := make(map[string]*RPCTransaction)
+	for _, tx := range s.b.TxPoolScheduled()[account] {
+		dump[txPoolKey(tx)] = newRPCPendingTransaction(tx)
+	}
+	return dump, nil
+}
+
+// PrivateTxPoolAPI offers node local administration of the transaction pool.
+type PrivateTxPoolAPI struct{}
+
+// NewPrivateTxPoolAPI creates a new private tx pool service.
+func NewPrivateTxPoolAPI() *PrivateTxPoolAPI {
+	return &PrivateTxPoolAPI{}
+}
+
+// SetLocalBlacklist replaces the node local blacklist. Transactions sent from
+// or to a blacklisted address are rejected by the pools of this node only.
+func (s *PrivateTxPoolAPI) SetLocalBlacklist(strAddresses []string) (bool, error) {
+	addrs := make([]common.Address, 0, len(strAddresses))
+	for _, strAddress := range strAddresses {
+		addr, err := base58.Base58DecodeToAddress(strAddress)
+		if err != nil {
+			return false, err
+		}
+		addrs = append(addrs, addr)
+	}
+	core.LocalTxBlacklist().Set(addrs)
+	return true, nil
+}
+
+// LocalBlacklist returns the node local blacklist.
+func (s *PrivateTxPoolAPI) LocalBlacklist() []string {
+	list := make([]string, 0)
+	for _, addr := range core.LocalTxBlacklist().List() {
+		list = append(list, base58.Base58EncodeToString(params.MAN_COIN, addr))
+	}
+	return list
+}
+
+// RPCBroadcastTransaction represents a special transaction held by the broadcast pool.
+type RPCBroadcastTransaction struct {
+	Interval    hexutil.Uint64  `json:"interval"`
+	Transaction *RPCTransaction `json:"transaction"`
+}
+
+// BroadcastContent returns the special transactions contained within the broadcast
+// pool, grouped by sender and broadcast type.
+func (s *PublicTxPoolAPI) BroadcastContent() map[string]map[string][]*RPCBroadcastTransaction {
+	content := make(map[string]map[string][]*RPCBroadcastTransaction)
+	for account, groups := range s.b.TxPoolBroadcastContent() {
+		dump := make(map[string][]*RPCBroadcastTransaction)
+		for bType, txs := range groups {
+			for _, tx := range txs {
+				dump[bType] = append(dump[bType], &RPCBroadcastTransaction{
+					Interval:    hexutil.Uint64(tx.Interval),
+					Transaction: newRPCPendingTransaction(tx.Tx),
+				})
+			}
+		}
+		content[account.Hex()] = dump
+	}
+	return content
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
@@ -667,6 +785,36 @@ func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, strAddress string,
 	}
 	return balance, state.Error()
 }
+
+// GetBalanceByCoin returns the balances of an account in the given coin. The
+// currency prefix of the address is ignored.
+func (s *PublicBlockChainAPI) GetBalanceByCoin(ctx context.Context, coin string, strAddress string, blockNr rpc.BlockNumber) ([]RPCBalanceType, error) {
+	coin = strings.TrimSpace(coin)
+	if !common.IsValidityCurrency(coin) {
+		return nil, errors.New("Invalid currency")
+	}
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	address, err := base58.Base58DecodeToAddress(strAddress)
+	if err != nil {
+		return nil, err
+	}
+	balance := make([]RPCBalanceType, 0)
+	b := state.GetBalance(coin, address)
+	if b == nil {
+		var i uint32
+		for i = 0; i <= common.LastAccount; i++ {
+			balance = append(balance, RPCBalanceType{i, new(hexutil.Big)})
+		}
+	} else {
+		for i := 0; i < len(b); i++ {
+			balance = append(balance, RPCBalanceType{b[i].AccountType, (*hexutil.Big)(b[i].Balance)})
+		}
+	}
+	return balance, state.Error()
+}
 func (s *PublicBlockChainAPI) GetMatrixCoin(ctx context.Context, blockNr rpc.BlockNumber) ([]string, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
@@ -913,6 +1061,10 @@ func (s *PublicBlockChainAPI) GetDepositByAddr(ctx context.Context, straddr stri
 	if depositOutput == nil {
 		return nil, err
 	}
+	return newRpcDepositBase(depositOutput), nil
+}
+
+func newRpcDepositBase(depositOutput *common.DepositBase) *RpcDepositBase {
 	rpcbase := RpcDepositBase{
 		AddressA1:     base58.Base58EncodeToString(params.MAN_COIN, depositOutput.AddressA1),
 		AddressA0:     base58.Base58EncodeToString(params.MAN_COIN, depositOutput.AddressA0),
@@ -947,7 +1099,7 @@ func (s *PublicBlockChainAPI) GetDepositByAddr(ctx context.Context, straddr stri
 		}
 		rpcbase.Dpstmsg = append(rpcbase.Dpstmsg, rpcmsg)
 	}
-	return &rpcbase, nil
+	return &rpcbase
 }
 func (api *PublicBlockChainAPI) GetFutureRewards(ctx context.Context, number rpc.BlockNumber) (interface{}, error) {
 	state, _, err := api.b.StateAndHeaderByNumber(ctx, number-1)
@@ -980,25 +1132,8 @@ func (s *PublicBlockChainAPI) GetEntrustList(strAuthFrom string) []common.Entrus
 		return nil
 	}
 
-	validEntrustList := make([]common.EntrustType, 0)
-	allEntrustList := state.GetAllEntrustList(coin, authFrom)
-	for _, entrustData := range allEntrustList {
-		if entrustData.EnstrustSetType == params.EntrustByHeight {
-			if s.b.CurrentBlock().NumberU64() <= entrustData.EndHeight {
-				validEntrustList = append(validEntrustList, entrustData)
-			}
-		} else if entrustData.EnstrustSetType == params.EntrustByTime {
-			if s.b.CurrentBlock().Time().Uint64() <= entrustData.EndTime {
-				validEntrustList = append(validEntrustList, entrustData)
-			}
-		} else if entrustData.EnstrustSetType == params.EntrustByCount {
-			if entrustData.EntrustCount > 0 {
-				validEntrustList = append(validEntrustList, entrustData)
-			}
-		}
-	}
-
-	return validEntrustList
+	current := s.b.CurrentBlock()
+	return validEntrustList(state.GetAllEntrustList(coin, authFrom), current.NumberU64(), current.Time().Uint64())
 }
 
 func (s *PublicBlockChainAPI) GetBlackList() []string {
@@ -1301,13 +1436,16 @@ type ManCallArgs struct {
 	ExtraTo  []*ExtraTo_Mx1 `json:"extra_to"` //
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, blockOverrides *BlockOverrides, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
 	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
 		return nil, 0, false, err
 	}
+	if header, err = blockOverrides.Apply(header); err != nil {
+		return nil, 0, false, err
+	}
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
@@ -1341,6 +1479,9 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	}
 	msg := &types.TransactionCall{types.NewTransactions(params.NonceAddOne, *args.To, args.Value.ToInt(), gas, gasPrice, args.Data, nil, nil, nil, extra, 0, 0, 0, "MAN", 0)}
 	msg.SetFromLoad(addr)
+	if err := overrides.Apply(msg.GetTxCurrency(), state); err != nil {
+		return nil, 0, false, err
+	}
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
 	var cancel context.CancelFunc
@@ -1422,12 +1563,15 @@ func ManArgsToCallArgs(manargs ManCallArgs) (args CallArgs, err error) {
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, manargs ManCallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+//
+// Additionally, the caller can specify a batch of contract for fields overriding
+// and a set of block header fields (number, time, leader) to execute the call with.
+func (s *PublicBlockChainAPI) Call(ctx context.Context, manargs ManCallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, blockOverrides *BlockOverrides) (hexutil.Bytes, error) {
 	args, err := ManArgsToCallArgs(manargs)
 	if err != nil {
 		return nil, err
 	}
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
+	result, _, _, err := s.doCall(ctx, args, blockNr, overrides, blockOverrides, vm.Config{}, 5*time.Second)
 	return (hexutil.Bytes)(result), err
 }
 
@@ -1461,7 +1605,7 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, manargs ManCallAr
 	executable := func(gas uint64) bool {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.LatestBlockNumber, vm.Config{}, 0)
+		_, _, failed, err := s.doCall(ctx, args, rpc.LatestBlockNumber, nil, nil, vm.Config{}, 0)
 		if err != nil || failed {
 			return false
 		}
@@ -2310,6 +2454,16 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = base58.Base58EncodeToString(tx.GetTxCurrency(), receipt.ContractAddress)
 	}
+	// Matrix specific outcome, recorded from the receipt metadata fork on
+	if receipt.Matrix != nil {
+		extraStatus := make([]hexutil.Uint, len(receipt.Matrix.ExtraStatus))
+		for i, status := range receipt.Matrix.ExtraStatus {
+			extraStatus[i] = hexutil.Uint(status)
+		}
+		fields["matrixTxType"] = hexutil.Uint(receipt.Matrix.TxType)
+		fields["broadcastType"] = receipt.Matrix.BroadcastType
+		fields["extraStatus"] = extraStatus
+	}
 	return fields, nil
 }
 
@@ -2409,12 +2563,19 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	if err != nil {
 		return err
 	}
-	price, err := matrixstate.GetTxpoolGasLimit(state)
+	minPrice, err := matrixstate.GetTxpoolGasLimit(state)
 	if err != nil {
 		return err
 	}
-
-	args.GasPrice = (*hexutil.Big)(price)
+	if args.GasPrice == nil {
+		price, err := suggestGasPrice(ctx, b)
+		if err != nil {
+			return err
+		}
+		args.GasPrice = (*hexutil.Big)(price)
+	} else if args.GasPrice.ToInt().Cmp(minPrice) < 0 {
+		args.GasPrice = (*hexutil.Big)(minPrice)
+	}
 
 	if args.Value == nil {
 		args.Value = new(hexutil.Big)
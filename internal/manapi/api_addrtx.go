@@ -0,0 +1,89 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package manapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+)
+
+// maxAddressTxPageSize is the maximum number of transactions returned by a
+// single address query.
+const maxAddressTxPageSize = 100
+
+// PublicAddressTxAPI provides the transaction history of the accounts, served
+// by the address index of the node.
+type PublicAddressTxAPI struct {
+	b Backend
+}
+
+// NewPublicAddressTxAPI creates a new address transaction API.
+func NewPublicAddressTxAPI(b Backend) *PublicAddressTxAPI {
+	return &PublicAddressTxAPI{b: b}
+}
+
+// RpcAddressTransactions is a page of the transactions of an address.
+type RpcAddressTransactions struct {
+	Address      string             `json:"address"`
+	Page         hexutil.Uint64     `json:"page"`
+	PageSize     hexutil.Uint64     `json:"pageSize"`
+	More         bool               `json:"more"` // Whether there are older transactions
+	Transactions []*RPCTransaction1 `json:"transactions"`
+}
+
+// parseAddressTxDirection parses the direction of the address queries: "from"
+// selects the transactions sent by the address, "to" the ones it received, and
+// an empty direction or "all" both.
+func parseAddressTxDirection(direction string) (sent, received bool, err error) {
+	switch direction {
+	case "from":
+		return true, false, nil
+	case "to":
+		return false, true, nil
+	case "", "all":
+		return true, true, nil
+	default:
+		return false, false, errors.New("invalid direction " + direction)
+	}
+}
+
+// GetTransactionsByAddress returns a page of the transactions sent and/or
+// received by an address, most recent first. Pages are numbered from 0. The
+// node must run with the address index enabled.
+func (s *PublicAddressTxAPI) GetTransactionsByAddress(ctx context.Context, strAddress string, page, pageSize hexutil.Uint64, direction string) (*RpcAddressTransactions, error) {
+	addr, err := base58.Base58DecodeToAddress(strAddress)
+	if err != nil {
+		return nil, err
+	}
+	sent, received, err := parseAddressTxDirection(direction)
+	if err != nil {
+		return nil, err
+	}
+	if pageSize == 0 || pageSize > maxAddressTxPageSize {
+		return nil, fmt.Errorf("invalid page size %d, must be 1-%d", pageSize, maxAddressTxPageSize)
+	}
+	hashes, more, err := s.b.GetAddressTransactions(addr, sent, received, int(page*pageSize), int(pageSize))
+	if err != nil {
+		return nil, err
+	}
+	result := &RpcAddressTransactions{
+		Address:      strAddress,
+		Page:         page,
+		PageSize:     pageSize,
+		More:         more,
+		Transactions: make([]*RPCTransaction1, 0, len(hashes)),
+	}
+	for _, hash := range hashes {
+		if tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.b.ChainDb(), hash); tx != nil {
+			result.Transactions = append(result.Transactions, RPCTransactionToString(newRPCTransaction(tx, blockHash, blockNumber, index)))
+		}
+	}
+	return result, nil
+}
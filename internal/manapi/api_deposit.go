@@ -0,0 +1,197 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package manapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// PublicDepositAPI provides access to the deposits of the validators and
+// miners, and builds the deposit transactions for the staking clients.
+type PublicDepositAPI struct {
+	b         Backend
+	txPool    *PublicTransactionPoolAPI
+	unbonding *PublicUnbondingAPI
+}
+
+// NewPublicDepositAPI creates a new deposit API.
+func NewPublicDepositAPI(b Backend, nonceLock *AddrLocker) *PublicDepositAPI {
+	return &PublicDepositAPI{
+		b:         b,
+		txPool:    NewPublicTransactionPoolAPI(b, nonceLock),
+		unbonding: NewPublicUnbondingAPI(b, nonceLock),
+	}
+}
+
+// RpcPositionInterest is the interest accrued by a deposit position.
+type RpcPositionInterest struct {
+	Position      uint64       `json:"position"`
+	DepositType   uint64       `json:"depositType"`
+	DepositAmount *hexutil.Big `json:"depositAmount"`
+	Interest      *hexutil.Big `json:"interest"`
+}
+
+// RpcDepositInterest is the interest accrued by a deposit account which is
+// not paid yet.
+type RpcDepositInterest struct {
+	Total     *hexutil.Big          `json:"total"`
+	Positions []RpcPositionInterest `json:"positions"`
+}
+
+// SendDepositArgs represents the arguments of a deposit transaction.
+type SendDepositArgs struct {
+	From        string          `json:"from"`
+	SignAddress string          `json:"signAddress"`
+	Role        string          `json:"role"`
+	DepositType hexutil.Uint64  `json:"depositType"`
+	Amount      *hexutil.Big    `json:"amount"`
+	Gas         *hexutil.Uint64 `json:"gas"`
+	GasPrice    *hexutil.Big    `json:"gasPrice"`
+	Nonce       *hexutil.Uint64 `json:"nonce"`
+}
+
+// parseDepositRole parses the role name of the deposit queries, an empty
+// name or "all" selects both the validators and the miners.
+func parseDepositRole(role string) (common.RoleType, error) {
+	switch role {
+	case "validator":
+		return common.RoleValidator, nil
+	case "miner":
+		return common.RoleMiner, nil
+	case "", "all":
+		return common.RoleValidator | common.RoleMiner, nil
+	default:
+		return common.RoleNil, errors.New("invalid deposit role " + role)
+	}
+}
+
+// GetDeposit returns the deposit positions of a deposit account at the given
+// block, or nil if the account has no deposit.
+func (s *PublicDepositAPI) GetDeposit(ctx context.Context, straddr string, blockNr rpc.BlockNumber) (*RpcDepositBase, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	addr, err := base58.Base58DecodeToAddress(straddr)
+	if err != nil {
+		return nil, err
+	}
+	deposit := depoistInfo.GetDepositBase(state, addr)
+	if deposit == nil {
+		return nil, nil
+	}
+	return newRpcDepositBase(deposit), nil
+}
+
+// GetAllDeposits returns the deposits of the role ("validator", "miner" or
+// "all") at the given block.
+func (s *PublicDepositAPI) GetAllDeposits(ctx context.Context, role string, blockNr rpc.BlockNumber) ([]DepositDetail, error) {
+	roleType, err := parseDepositRole(role)
+	if err != nil {
+		return nil, err
+	}
+	header, err := s.b.HeaderByNumber(ctx, blockNr)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	deposits, err := depoistInfo.GetDepositListByHash(header.Hash(), roleType)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]DepositDetail, 0, len(deposits))
+	for _, v := range deposits {
+		result = append(result, DepositDetail{
+			Address:     base58.Base58EncodeToString(params.MAN_COIN, v.Address),
+			SignAddress: base58.Base58EncodeToString(params.MAN_COIN, v.SignAddress),
+			Deposit:     v.Deposit,
+			WithdrawH:   v.WithdrawH,
+			OnlineTime:  v.OnlineTime,
+			Role:        v.Role,
+		})
+	}
+	return result, nil
+}
+
+// GetInterest returns the interest accrued by a deposit account at the given
+// block which is not paid yet.
+func (s *PublicDepositAPI) GetInterest(ctx context.Context, straddr string, blockNr rpc.BlockNumber) (*RpcDepositInterest, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	addr, err := base58.Base58DecodeToAddress(straddr)
+	if err != nil {
+		return nil, err
+	}
+	interest, err := depoistInfo.GetInterest_v2(state, addr)
+	if err != nil {
+		return nil, err
+	}
+	total := new(big.Int)
+	result := &RpcDepositInterest{Positions: make([]RpcPositionInterest, 0, len(interest.CalcDeposit))}
+	for _, calc := range interest.CalcDeposit {
+		amount := new(big.Int)
+		if calc.OperAmount != nil {
+			amount.Set(calc.OperAmount)
+		}
+		total.Add(total, amount)
+		result.Positions = append(result.Positions, RpcPositionInterest{
+			Position:      calc.Position,
+			DepositType:   calc.DepositType,
+			DepositAmount: (*hexutil.Big)(calc.DepositAmount),
+			Interest:      (*hexutil.Big)(amount),
+		})
+	}
+	result.Total = (*hexutil.Big)(total)
+	return result, nil
+}
+
+// GetUnbonding returns the withdrawals of a deposit account which are still
+// unbonding at the given block.
+func (s *PublicDepositAPI) GetUnbonding(ctx context.Context, straddr string, blockNr rpc.BlockNumber) ([]RpcUnbondingEntry, error) {
+	return s.unbonding.WithdrawalStatus(ctx, straddr, blockNr)
+}
+
+// SendDeposit sends a deposit transaction to the deposit contract from the
+// deposit account, depositing amount as a validator or a miner with the
+// sign account of args.
+func (s *PublicDepositAPI) SendDeposit(ctx context.Context, args SendDepositArgs) (common.Hash, error) {
+	if args.Amount == nil || args.Amount.ToInt().Sign() <= 0 {
+		return common.Hash{}, errors.New("invalid deposit amount")
+	}
+	role, err := parseDepositRole(args.Role)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	signAddress, err := base58.Base58DecodeToAddress(args.SignAddress)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	input, err := vm.PackDepositInput(role, signAddress, uint64(args.DepositType))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	to := base58.Base58EncodeToString(params.MAN_COIN, common.ContractAddress)
+	data := hexutil.Bytes(input)
+	return s.txPool.SendTransaction(ctx, SendTxArgs1{
+		From:     args.From,
+		To:       &to,
+		Gas:      args.Gas,
+		GasPrice: args.GasPrice,
+		Value:    args.Amount,
+		Nonce:    args.Nonce,
+		Input:    &data,
+	})
+}
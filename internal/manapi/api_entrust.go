@@ -0,0 +1,66 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package manapi
+
+import (
+	"context"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// PublicEntrustAPI provides access to the entrusts an account granted to
+// other accounts.
+type PublicEntrustAPI struct {
+	b Backend
+}
+
+// NewPublicEntrustAPI creates a new entrust API.
+func NewPublicEntrustAPI(b Backend) *PublicEntrustAPI {
+	return &PublicEntrustAPI{b}
+}
+
+// GetEntrustList returns the entrusts granted by an account which are still
+// valid at the given block, including the transaction types the entrusted
+// gas may pay for.
+func (s *PublicEntrustAPI) GetEntrustList(ctx context.Context, strAuthFrom string, blockNr rpc.BlockNumber) ([]common.EntrustType, error) {
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	coin, err := getCoinFromManAddress(strAuthFrom)
+	if err != nil {
+		return nil, err
+	}
+	authFrom, err := base58.Base58DecodeToAddress(strAuthFrom)
+	if err != nil {
+		return nil, err
+	}
+	return validEntrustList(state.GetAllEntrustList(coin, authFrom), header.Number.Uint64(), header.Time.Uint64()), nil
+}
+
+// validEntrustList filters out the entrusts which expired before the given
+// block number and time.
+func validEntrustList(allEntrustList []common.EntrustType, number uint64, time uint64) []common.EntrustType {
+	validEntrustList := make([]common.EntrustType, 0)
+	for _, entrustData := range allEntrustList {
+		if entrustData.EnstrustSetType == params.EntrustByHeight {
+			if number <= entrustData.EndHeight {
+				validEntrustList = append(validEntrustList, entrustData)
+			}
+		} else if entrustData.EnstrustSetType == params.EntrustByTime {
+			if time <= entrustData.EndTime {
+				validEntrustList = append(validEntrustList, entrustData)
+			}
+		} else if entrustData.EnstrustSetType == params.EntrustByCount {
+			if entrustData.EntrustCount > 0 {
+				validEntrustList = append(validEntrustList, entrustData)
+			}
+		}
+	}
+	return validEntrustList
+}
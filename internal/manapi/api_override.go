@@ -0,0 +1,98 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package manapi
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+// OverrideAccount indicates the overriding fields of account during the execution
+// of a message call. Only the given fields are replaced, StateDiff patches the
+// given storage slots and keeps the others.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64             `json:"nonce"`
+	Code      *hexutil.Bytes              `json:"code"`
+	Balance   *hexutil.Big                `json:"balance"`
+	StateDiff map[common.Hash]common.Hash `json:"stateDiff"`
+}
+
+// StateOverride is the collection of overridden accounts, keyed by MAN address.
+type StateOverride map[string]OverrideAccount
+
+// Apply overrides the fields of the specified accounts of the given coin in the state.
+func (diff *StateOverride) Apply(cointyp string, state *state.StateDBManage) error {
+	if diff == nil {
+		return nil
+	}
+	for manAddress, account := range *diff {
+		addr, err := base58.Base58DecodeToAddress(manAddress)
+		if err != nil {
+			return fmt.Errorf("invalid override address %s: %v", manAddress, err)
+		}
+		if account.Nonce != nil {
+			state.SetNonce(cointyp, addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			state.SetCode(cointyp, addr, *account.Code)
+		}
+		if account.Balance != nil {
+			state.SetBalance(cointyp, common.MainAccount, addr, (*big.Int)(account.Balance))
+		}
+		for key, value := range account.StateDiff {
+			state.SetState(cointyp, addr, key, value)
+		}
+	}
+	return nil
+}
+
+// BlockOverrides is a set of header fields to override during the execution of
+// a message call, so that contracts reading the block context or the matrix
+// precompiles can be simulated at another height, time or leader.
+type BlockOverrides struct {
+	Number   *hexutil.Big    `json:"number"`
+	Time     *hexutil.Big    `json:"time"`
+	GasLimit *hexutil.Uint64 `json:"gasLimit"`
+	Leader   *string         `json:"leader"`
+	Coinbase *string         `json:"coinbase"`
+}
+
+// Apply returns a copy of the header with the overridden fields.
+func (diff *BlockOverrides) Apply(header *types.Header) (*types.Header, error) {
+	if diff == nil {
+		return header, nil
+	}
+	cpy := types.CopyHeader(header)
+	if diff.Number != nil {
+		cpy.Number = new(big.Int).Set((*big.Int)(diff.Number))
+	}
+	if diff.Time != nil {
+		cpy.Time = new(big.Int).Set((*big.Int)(diff.Time))
+	}
+	if diff.GasLimit != nil {
+		cpy.GasLimit = uint64(*diff.GasLimit)
+	}
+	if diff.Leader != nil {
+		leader, err := base58.Base58DecodeToAddress(*diff.Leader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid leader override: %v", err)
+		}
+		cpy.Leader = leader
+	}
+	if diff.Coinbase != nil {
+		coinbase, err := base58.Base58DecodeToAddress(*diff.Coinbase)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coinbase override: %v", err)
+		}
+		cpy.Coinbase = coinbase
+	}
+	return cpy, nil
+}
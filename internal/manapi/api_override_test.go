@@ -0,0 +1,44 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package manapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+func TestBlockOverridesApply(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(100), Time: big.NewInt(1000), Difficulty: big.NewInt(1), GasLimit: 8000000}
+	leader := common.HexToAddress("0x0102")
+	manLeader := base58.Base58EncodeToString("MAN", leader)
+
+	overrides := &BlockOverrides{
+		Number: (*hexutil.Big)(big.NewInt(200)),
+		Leader: &manLeader,
+	}
+	cpy, err := overrides.Apply(header)
+	if err != nil {
+		t.Fatalf("apply block overrides failed: %v", err)
+	}
+	if cpy.Number.Uint64() != 200 || cpy.Leader != leader {
+		t.Errorf("overridden header mismatch: number %v leader %x", cpy.Number, cpy.Leader)
+	}
+	if cpy.Time.Uint64() != 1000 || cpy.GasLimit != 8000000 {
+		t.Errorf("untouched fields changed: time %v gasLimit %d", cpy.Time, cpy.GasLimit)
+	}
+	if header.Number.Uint64() != 100 || header.Leader != (common.Address{}) {
+		t.Errorf("original header modified")
+	}
+
+	var none *BlockOverrides
+	if same, _ := none.Apply(header); same != header {
+		t.Errorf("nil overrides should return the original header")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package manapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// StorageResult is a storage entry of an account along with its Merkle proof
+// against the storage hash of the account.
+type StorageResult struct {
+	Key   common.Hash     `json:"key"`
+	Value hexutil.Bytes   `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// AccountResult is an account of a coin along with its Merkle proof. The
+// account is proven against the shard root of its address range, and the shard
+// roots hash to the coin root of the block header.
+type AccountResult struct {
+	Address      string           `json:"address"`
+	Currency     string           `json:"currency"`
+	Balance      []RPCBalanceType `json:"balance"`
+	Nonce        hexutil.Uint64   `json:"nonce"`
+	CodeHash     common.Hash      `json:"codeHash"`
+	StorageHash  common.Hash      `json:"storageHash"`
+	ShardRoots   []common.Hash    `json:"shardRoots"`
+	AccountProof []hexutil.Bytes  `json:"accountProof"`
+	StorageProof []StorageResult  `json:"storageProof"`
+}
+
+// MatrixStateResult is a matrix state entry along with its Merkle proof. The
+// matrix state is kept in the first shard of the MAN coin.
+type MatrixStateResult struct {
+	Key        string          `json:"key"`
+	KeyHash    common.Hash     `json:"keyHash"`
+	Value      hexutil.Bytes   `json:"value"`
+	ShardRoots []common.Hash   `json:"shardRoots"`
+	Proof      []hexutil.Bytes `json:"proof"`
+}
+
+// GetProof returns the account and the storage entries of the given address
+// along with their Merkle proofs. The currency is taken from the address.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, manAddress string, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+	strlist := strings.Split(manAddress, ".")
+	if len(strlist) < 2 || strlist[0] == "" {
+		return nil, errors.New("Illegal input address")
+	}
+	cointype := strlist[0]
+	address, err := base58.Base58DecodeToAddress(manAddress)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]common.Hash, 0, len(storageKeys))
+	for _, key := range storageKeys {
+		keys = append(keys, common.HexToHash(key))
+	}
+
+	st, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if st == nil || err != nil {
+		return nil, err
+	}
+	proof, err := st.ProveAccount(cointype, address, keys)
+	if err != nil {
+		return nil, err
+	}
+	// Read the account from the proof itself, so the result matches it
+	account, err := state.VerifyAccountProof(header.Roots, cointype, address, proof)
+	if err != nil {
+		return nil, err
+	}
+	result := &AccountResult{
+		Address:      manAddress,
+		Currency:     cointype,
+		Balance:      make([]RPCBalanceType, 0),
+		CodeHash:     crypto.Keccak256Hash(nil),
+		StorageHash:  types.EmptyRootHash,
+		ShardRoots:   proof.ShardRoots,
+		AccountProof: toHexSlice(proof.Nodes),
+		StorageProof: make([]StorageResult, 0, len(proof.Storage)),
+	}
+	if account != nil {
+		for _, balance := range account.Balance {
+			result.Balance = append(result.Balance, RPCBalanceType{balance.AccountType, (*hexutil.Big)(balance.Balance)})
+		}
+		result.Nonce = hexutil.Uint64(account.Nonce)
+		result.CodeHash = common.BytesToHash(account.CodeHash)
+		result.StorageHash = account.Root
+	}
+	for i := range proof.Storage {
+		entry := &proof.Storage[i]
+		value, err := state.VerifyStorageProof(result.StorageHash, entry)
+		if err != nil {
+			return nil, err
+		}
+		result.StorageProof = append(result.StorageProof, StorageResult{Key: entry.Key, Value: value, Proof: toHexSlice(entry.Nodes)})
+	}
+	return result, nil
+}
+
+// GetMatrixStateProof returns the given matrix state entries (elected sets,
+// broadcast data, config values) along with their Merkle proofs.
+func (s *PublicBlockChainAPI) GetMatrixStateProof(ctx context.Context, keys []string, blockNr rpc.BlockNumber) ([]MatrixStateResult, error) {
+	st, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if st == nil || err != nil {
+		return nil, err
+	}
+	results := make([]MatrixStateResult, 0, len(keys))
+	for _, key := range keys {
+		hash := matrixstate.KeyHash(key)
+		proof, err := st.ProveMatrixData(hash)
+		if err != nil {
+			return nil, err
+		}
+		value, err := state.VerifyMatrixDataProof(header.Roots, hash, proof)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, MatrixStateResult{
+			Key:        key,
+			KeyHash:    hash,
+			Value:      value,
+			ShardRoots: proof.ShardRoots,
+			Proof:      toHexSlice(proof.Nodes),
+		})
+	}
+	return results, nil
+}
+
+// toHexSlice converts the proof nodes for the JSON output.
+func toHexSlice(nodes [][]byte) []hexutil.Bytes {
+	result := make([]hexutil.Bytes, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, node)
+	}
+	return result
+}
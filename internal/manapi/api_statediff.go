@@ -0,0 +1,60 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package manapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// PublicMatrixStateAPI provides access to the matrix state entries (elected
+// sets, broadcast data, config values) of the chain.
+type PublicMatrixStateAPI struct {
+	b Backend
+}
+
+// NewPublicMatrixStateAPI creates a new matrix state API.
+func NewPublicMatrixStateAPI(b Backend) *PublicMatrixStateAPI {
+	return &PublicMatrixStateAPI{b: b}
+}
+
+// RpcStateDiff is a matrix state entry changed between two blocks. The value
+// is null where the entry doesn't exist.
+type RpcStateDiff struct {
+	Key  string      `json:"key"`
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// GetStateDiff returns the matrix state entries changed between two blocks,
+// limited to the given keys if any.
+func (s *PublicMatrixStateAPI) GetStateDiff(ctx context.Context, fromBlock, toBlock rpc.BlockNumber, keys []string) ([]RpcStateDiff, error) {
+	fromState, _, err := s.b.StateAndHeaderByNumber(ctx, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	if fromState == nil {
+		return nil, errors.New("from block state not found")
+	}
+	toState, _, err := s.b.StateAndHeaderByNumber(ctx, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	if toState == nil {
+		return nil, errors.New("to block state not found")
+	}
+	diffs, err := matrixstate.DiffState(fromState, toState, keys)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]RpcStateDiff, 0, len(diffs))
+	for _, diff := range diffs {
+		result = append(result, RpcStateDiff{Key: diff.Key, From: diff.From, To: diff.To})
+	}
+	return result, nil
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package manapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// PublicUnbondingAPI provides access to the deposit withdrawals and their
+// unbonding period.
+type PublicUnbondingAPI struct {
+	b      Backend
+	txPool *PublicTransactionPoolAPI
+}
+
+// NewPublicUnbondingAPI creates a new deposit unbonding API.
+func NewPublicUnbondingAPI(b Backend, nonceLock *AddrLocker) *PublicUnbondingAPI {
+	return &PublicUnbondingAPI{b: b, txPool: NewPublicTransactionPoolAPI(b, nonceLock)}
+}
+
+// RpcUnbondingEntry is a deposit withdrawal which is still unbonding.
+type RpcUnbondingEntry struct {
+	Position      uint64       `json:"position"`
+	Amount        *hexutil.Big `json:"amount"`
+	RequestNumber uint64       `json:"requestNumber"`
+	ReleaseNumber uint64       `json:"releaseNumber"`
+}
+
+// WithdrawDeposit sends a withdraw transaction to the deposit contract from
+// the deposit account. The withdrawn amount can only be refunded once the
+// unbonding period is over.
+func (s *PublicUnbondingAPI) WithdrawDeposit(ctx context.Context, from string, position hexutil.Uint64, amount *hexutil.Big) (common.Hash, error) {
+	if amount == nil || amount.ToInt().Sign() <= 0 {
+		return common.Hash{}, errors.New("invalid withdraw amount")
+	}
+	input, err := vm.PackWithdrawInput(uint64(position), amount.ToInt())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	to := base58.Base58EncodeToString(params.MAN_COIN, common.ContractAddress)
+	data := hexutil.Bytes(input)
+	return s.txPool.SendTransaction(ctx, SendTxArgs1{From: from, To: &to, Input: &data})
+}
+
+// WithdrawalStatus returns the withdrawals of a deposit account which are
+// still unbonding at the given block.
+func (s *PublicUnbondingAPI) WithdrawalStatus(ctx context.Context, straddr string, blockNr rpc.BlockNumber) ([]RpcUnbondingEntry, error) {
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	addr, err := base58.Base58DecodeToAddress(straddr)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := core.GetUnbondingEntries(state, addr, header.Number.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]RpcUnbondingEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, RpcUnbondingEntry{
+			Position:      entry.Position,
+			Amount:        (*hexutil.Big)(entry.Amount),
+			RequestNumber: entry.RequestNumber,
+			ReleaseNumber: entry.ReleaseNumber,
+		})
+	}
+	return result, nil
+}
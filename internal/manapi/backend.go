@@ -57,6 +57,8 @@ type Backend interface {
 	Stats() (pending int, queued int)
 	GetTxNmap() map[uint32]*types.Transaction
 	TxPoolContent() (map[common.Address]types.SelfTransactions, map[common.Address]types.SelfTransactions)
+	TxPoolBroadcastContent() map[common.Address]map[string][]*core.BroadcastTxContent
+	TxPoolScheduled() map[common.Address]types.SelfTransactions
 	SubscribeNewTxsEvent(chan core.NewTxsEvent) event.Subscription //Y
 
 	SignTx(signedTx types.SelfTransaction, chainID *big.Int, blkHash common.Hash, signHeight uint64, usingEntrust bool) (types.SelfTransaction, error) //
@@ -70,6 +72,7 @@ type Backend interface {
 	NetRPCService() *PublicNetAPI
 	CurrentBlock() *types.Block
 	GetDepositAccount(signAccount common.Address, blockHash common.Hash) (common.Address, error)
+	GetAddressTransactions(addr common.Address, sent, received bool, offset, limit int) ([]common.Hash, bool, error)
 	GetFutureRewards(*state.StateDBManage, rpc.BlockNumber) (interface{}, error)
 	Genesis() *types.Block
 }
@@ -113,6 +116,11 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicTxPoolAPI(apiBackend),
 			Public:    true,
+		}, {
+			Namespace: "txpool",
+			Version:   "1.0",
+			Service:   NewPrivateTxPoolAPI(),
+			Public:    false,
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -132,6 +140,31 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicAccountAPI(apiBackend),
 			Public:    true,
+		}, {
+			Namespace: "matrix",
+			Version:   "1.0",
+			Service:   NewPublicUnbondingAPI(apiBackend, nonceLock),
+			Public:    true,
+		}, {
+			Namespace: "deposit",
+			Version:   "1.0",
+			Service:   NewPublicDepositAPI(apiBackend, nonceLock),
+			Public:    true,
+		}, {
+			Namespace: "matrix",
+			Version:   "1.0",
+			Service:   NewPublicEntrustAPI(apiBackend),
+			Public:    true,
+		}, {
+			Namespace: "matrix",
+			Version:   "1.0",
+			Service:   NewPublicMatrixStateAPI(apiBackend),
+			Public:    true,
+		}, {
+			Namespace: "matrix",
+			Version:   "1.0",
+			Service:   NewPublicAddressTxAPI(apiBackend),
+			Public:    true,
 		}, {
 			Namespace: "personal",
 			Version:   "1.0",
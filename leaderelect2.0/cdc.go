@@ -58,6 +58,12 @@ func newCDC(number uint64, chain *core.BlockChain, logInfo string) *cdc {
 	return dc
 }
 
+// setState moves the controller to a new consensus stage.
+func (dc *cdc) setState(st stateDef) {
+	dc.state = st
+	stageGauge.Update(int64(st))
+}
+
 func (dc *cdc) AnalysisState(parentHeader *types.Header, parentState StateReader) error {
 	if parentState == nil || parentHeader == nil {
 		return errors.New("parent state or parentHeader is nil")
@@ -79,8 +85,12 @@ func (dc *cdc) AnalysisState(parentHeader *types.Header, parentState StateReader
 	if err != nil {
 		return err
 	}
+	selection, err := matrixstate.GetLeaderSelection(parentState)
+	if err != nil {
+		return err
+	}
 
-	if err := dc.leaderCal.SetValidatorsAndSpecials(parentHeader, validators, specials, bcInterval); err != nil {
+	if err := dc.leaderCal.SetValidatorsAndSpecials(parentHeader, validators, specials, bcInterval, selection); err != nil {
 		log.Warn(dc.logInfo, "SetValidatorsAndSpecials err", err)
 		return err
 	}
@@ -143,6 +153,7 @@ func (dc *cdc) SetReelectTurn(reelectTurn uint32) error {
 	if reelectTurn == 0 {
 		dc.reelectMaster.Set(common.Address{})
 		dc.curReelectTurn = 0
+		reelectTurnGauge.Update(0)
 		return nil
 	}
 	master, err := dc.GetLeader(dc.curConsensusTurn.TotalTurns()+reelectTurn, dc.bcInterval)
@@ -151,6 +162,7 @@ func (dc *cdc) SetReelectTurn(reelectTurn uint32) error {
 	}
 	dc.reelectMaster.Set(master)
 	dc.curReelectTurn = reelectTurn
+	reelectTurnGauge.Update(int64(reelectTurn))
 	return nil
 }
 
@@ -323,6 +335,36 @@ func (dc *cdc) GetBroadcastIntervalByHash(blockHash common.Hash) (*mc.BCInterval
 	return dc.chain.GetBroadcastIntervalByHash(blockHash)
 }
 
+func (dc *cdc) GetBLSVoteCfg(blockHash common.Hash) (*mc.BLSVoteCfg, error) {
+	if (blockHash == common.Hash{}) {
+		return nil, errors.New("输入hash为空")
+	}
+	if blockHash != dc.leaderCal.preHash {
+		return dc.chain.GetBLSVoteCfg(blockHash)
+	}
+	if nil == dc.parentState {
+		return nil, errors.New("cdc: parent stateDB is nil, can't reader data")
+	}
+	return matrixstate.GetBLSVoteCfg(dc.parentState)
+}
+
+func (dc *cdc) GetConsensusBLSPubKeys(accounts []common.Address, blockHash common.Hash) ([][]byte, error) {
+	if (blockHash == common.Hash{}) {
+		return nil, errors.New("输入hash为空")
+	}
+	if blockHash != dc.leaderCal.preHash {
+		return dc.chain.GetConsensusBLSPubKeys(accounts, blockHash)
+	}
+	if nil == dc.parentState {
+		return nil, errors.New("cdc: parent stateDB is nil, can't reader data")
+	}
+	keys := make([][]byte, len(accounts))
+	for i, account := range accounts {
+		keys[i] = depoistInfo.GetConsensusBLSPubKey(dc.parentState, account, dc.number)
+	}
+	return keys, nil
+}
+
 func (dc *cdc) GetSignAccountPassword(signAccounts []common.Address) (common.Address, string, error) {
 	return dc.chain.GetSignAccountPassword(signAccounts)
 }
@@ -386,6 +428,11 @@ func (dc *cdc) getA2Accounts(a0Account common.Address, blockHash common.Hash, si
 		return nil, errors.New("cdc: parent stateDB is nil, can't reader data")
 	}
 
+	//共识密钥生效后只能使用共识签名账户签名
+	if signer := depoistInfo.GetConsensusSigner(dc.parentState, a0Account, signHeight); signer != (common.Address{}) {
+		return []common.Address{signer}, nil
+	}
+
 	a1Account := depoistInfo.GetAuthAccount(dc.parentState, a0Account)
 	if a1Account == (common.Address{}) {
 		log.Error(common.SignLog, "cdc获取A2账户", " 不存在A1账户", " a0Account", a0Account.Hex())
@@ -411,9 +458,17 @@ func (dc *cdc) getA0Account(account common.Address, blockHash common.Hash, signH
 		return common.Address{}, common.Address{}, errors.New("cdc: parent stateDB is nil, can't reader data")
 	}
 
+	//已生效的共识签名账户
+	if a0Account := depoistInfo.GetConsensusAccount(dc.parentState, account, signHeight); a0Account != (common.Address{}) {
+		return a0Account, account, nil
+	}
+
 	//假设传入的account为A1账户, 获取A1账户
 	a0Account := depoistInfo.GetDepositAccount(dc.parentState, account)
 	if a0Account != (common.Address{}) {
+		if err := dc.checkConsensusKey(a0Account, account, signHeight); err != nil {
+			return common.Address{}, common.Address{}, err
+		}
 		log.Debug(common.SignLog, "CDC获取A0账户", "成功", "输入A1", account.Hex(), "输出A0", a0Account.Hex())
 		return a0Account, account, nil
 	}
@@ -428,6 +483,9 @@ func (dc *cdc) getA0Account(account common.Address, blockHash common.Hash, signH
 	// 根据A1获取A0
 	a0Account = depoistInfo.GetDepositAccount(dc.parentState, a1Account)
 	if a0Account != (common.Address{}) {
+		if err := dc.checkConsensusKey(a0Account, account, signHeight); err != nil {
+			return common.Address{}, common.Address{}, err
+		}
 		log.Debug(common.SignLog, "CDC获取A0账户", "成功", "输入A1", a1Account.Hex(), "输出A0", a0Account.Hex())
 		return a0Account, a1Account, nil
 	} else {
@@ -435,3 +493,12 @@ func (dc *cdc) getA0Account(account common.Address, blockHash common.Hash, signH
 		return common.Address{}, common.Address{}, errors.New("获取A0账户失败")
 	}
 }
+
+// checkConsensusKey 抵押账户注册的共识密钥生效后, 拒绝A1及A2账户的共识签名
+func (dc *cdc) checkConsensusKey(a0Account common.Address, account common.Address, signHeight uint64) error {
+	if signer := depoistInfo.GetConsensusSigner(dc.parentState, a0Account, signHeight); signer != (common.Address{}) && signer != account {
+		log.Error(common.SignLog, "CDC获取A0账户", "共识密钥已生效", "签名账户", account.Hex(), "共识账户", signer.Hex())
+		return errors.New("账户已注册共识密钥, 签名账户不是共识账户")
+	}
+	return nil
+}
@@ -588,3 +588,34 @@ func Test_cdc_GetValidatorByHash(t *testing.T) {
 		})
 	}
 }
+
+func Test_calVrfLeaderList(t *testing.T) {
+	validators := getTestValidatorGraph().NodeList
+
+	list := calVrfLeaderList([]byte("vrf value"), validators)
+	if len(list) != len(validators) {
+		t.Fatalf("leader list size mismatch: have %d, want %d", len(list), len(validators))
+	}
+	seen := make(map[common.Address]bool)
+	for i := 0; i < len(list); i++ {
+		seen[list[uint32(i)]] = true
+	}
+	for _, v := range validators {
+		if !seen[v.Account] {
+			t.Errorf("validator %s missing from leader list", v.Account.Hex())
+		}
+	}
+
+	if again := calVrfLeaderList([]byte("vrf value"), validators); !reflect.DeepEqual(list, again) {
+		t.Errorf("leader list not deterministic: %v != %v", list, again)
+	}
+	// The order must follow the seed rather than the topology positions
+	differ := false
+	for i := 0; i < 8 && !differ; i++ {
+		other := calVrfLeaderList([]byte{byte(i)}, validators)
+		differ = !reflect.DeepEqual(list, other)
+	}
+	if !differ {
+		t.Errorf("leader list does not depend on the vrf value")
+	}
+}
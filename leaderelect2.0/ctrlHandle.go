@@ -98,10 +98,10 @@ func (self *controller) handleStartMsg(msg *startControllerMsg) {
 
 	if self.dc.bcInterval.IsBroadcastNumber(self.dc.number) {
 		log.Debug(self.logInfo, "开始消息处理", "区块为广播区块，不开启定时器")
-		self.dc.state = stIdle
+		self.dc.setState(stIdle)
 		self.publishLeaderMsg()
 		self.mp.SaveParentHeader(msg.parentHeader)
-		self.dc.state = stWaiting
+		self.dc.setState(stWaiting)
 		return
 	}
 
@@ -111,7 +111,7 @@ func (self *controller) handleStartMsg(msg *startControllerMsg) {
 			curTime := time.Now().Unix()
 			st, remainTime, reelectTurn := self.dc.turnTime.CalState(0, curTime)
 			log.Debug(self.logInfo, "开始消息处理", "完成", "状态计算结果", st.String(), "剩余时间", remainTime, "重选轮次", reelectTurn)
-			self.dc.state = st
+			self.dc.setState(st)
 			self.dc.curReelectTurn = 0
 			self.setTimer(remainTime, self.timer)
 			if st == stPos {
@@ -156,7 +156,7 @@ func (self *controller) timeOutHandle() {
 	}
 
 	self.setTimer(remainTime, self.timer)
-	self.dc.state = st
+	self.dc.setState(st)
 	self.startReelect(reelectTurn)
 }
 
@@ -173,5 +173,5 @@ func (self *controller) processPOSState() {
 
 	log.Debug(self.logInfo, "POS完成", "状态切换为<挖矿结果等待阶段>")
 	self.setTimer(0, self.timer)
-	self.dc.state = stMining
+	self.dc.setState(stMining)
 }
@@ -52,7 +52,7 @@ func (self *controller) finishReelectWithPOS(posResult *mc.HD_BlkConsensusReqMsg
 	mc.PublishEvent(mc.Leader_RecoveryState, &mc.RecoveryStateMsg{Type: mc.RecoveryTypePOS, Header: posResult.Header, From: from})
 	self.setTimer(0, self.timer)
 	self.setTimer(0, self.reelectTimer)
-	self.dc.state = stMining
+	self.dc.setState(stMining)
 	self.dc.SetReelectTurn(0)
 	self.dc.isMaster = false
 	self.selfCache.ClearSelfInquiryMsg()
@@ -76,7 +76,7 @@ func (self *controller) finishReelectWithRLConsensus(rlResult *mc.HD_V2_ReelectL
 	st, remainTime, reelectTurn := self.dc.turnTime.CalState(consensusTurn.TotalTurns(), curTime)
 	log.Info(self.logInfo, "完成leader重选", "leader重置", "重选轮次", reelectTurn, "旧共识轮次", self.ConsensusTurn().String(), "新共识轮次", consensusTurn.String(), "高度", self.Number(),
 		"状态计算结果", st.String(), "下次超时时间", remainTime, "计算的重选轮次", reelectTurn, "轮次开始时间", self.dc.turnTime.GetBeginTime(self.ConsensusTurn().TotalTurns()))
-	self.dc.state = st
+	self.dc.setState(st)
 	self.dc.curReelectTurn = 0
 	self.setTimer(remainTime, self.timer)
 	if st == stPos {
@@ -5,9 +5,14 @@
 package leaderelect2
 
 import (
+	"bytes"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/baseinterface"
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/mc"
 	"github.com/pkg/errors"
@@ -60,20 +65,34 @@ func (self *leaderCalculator) getRealPreLeader(preHeader *types.Header, bcInterv
 	return header.Leader, preAppearSuper, nil
 }
 
-func (self *leaderCalculator) SetValidatorsAndSpecials(preHeader *types.Header, validators []mc.TopologyNodeInfo, specials *specialAccounts, bcInterval *mc.BCIntervalInfo) error {
-	if preHeader == nil || validators == nil || specials == nil || bcInterval == nil {
+func (self *leaderCalculator) SetValidatorsAndSpecials(preHeader *types.Header, validators []mc.TopologyNodeInfo, specials *specialAccounts, bcInterval *mc.BCIntervalInfo, selection *mc.LeaderSelectionCfg) error {
+	if preHeader == nil || validators == nil || specials == nil || bcInterval == nil || selection == nil {
 		return ErrValidatorsIsNil
 	}
 
-	realPreLeader, preAppearSuper, err := self.getRealPreLeader(preHeader, bcInterval)
-	if err != nil {
-		log.Error(self.logInfo, "计算leader列表", "获取真实的preLeader失败", "err", err)
-		return err
+	var leaderList map[uint32]common.Address
+	if selection.Mode == mc.LeaderSelectVRF {
+		// 以前一区块的VRF值为种子排序，下一个leader无法提前预知
+		// 前一区块没有VRF值时(如广播区块、超级区块)种子固定, 回退到轮换方式
+		_, preVrfValue, err := baseinterface.NewVrf().GetVrfInfoFromHeader(preHeader.VrfValue)
+		if err == nil && len(preVrfValue) > 0 {
+			log.Trace(self.logInfo, "计算leader列表", "开始", "选取方式", "VRF", "高度", self.number, "validators size", len(validators))
+			leaderList = calVrfLeaderList(preVrfValue, validators)
+		} else {
+			log.Warn(self.logInfo, "计算leader列表", "前一区块没有VRF值, 使用轮换方式", "高度", self.number, "err", err)
+		}
 	}
-	log.Trace(self.logInfo, "计算leader列表", "开始", "preLeader", realPreLeader.Hex(), "前区块中出现超级区块", preAppearSuper, "高度", self.number, "validators size", len(validators))
-	leaderList, err := calLeaderList(realPreLeader, self.number, preAppearSuper, validators, bcInterval, self.logInfo)
-	if err != nil {
-		return err
+	if leaderList == nil {
+		realPreLeader, preAppearSuper, err := self.getRealPreLeader(preHeader, bcInterval)
+		if err != nil {
+			log.Error(self.logInfo, "计算leader列表", "获取真实的preLeader失败", "err", err)
+			return err
+		}
+		log.Trace(self.logInfo, "计算leader列表", "开始", "preLeader", realPreLeader.Hex(), "前区块中出现超级区块", preAppearSuper, "高度", self.number, "validators size", len(validators))
+		leaderList, err = calLeaderList(realPreLeader, self.number, preAppearSuper, validators, bcInterval, self.logInfo)
+		if err != nil {
+			return err
+		}
 	}
 	self.leaderList = leaderList
 	self.preLeader.Set(preHeader.Leader)
@@ -149,6 +168,26 @@ func calLeaderList(preLeader common.Address, curNumber uint64, preIsSupper bool,
 	return leaderList, nil
 }
 
+// calVrfLeaderList 按hash(vrfValue, account)升序排列验证者，得到leader轮换顺序
+func calVrfLeaderList(vrfValue []byte, validators []mc.TopologyNodeInfo) map[uint32]common.Address {
+	type vrfNode struct {
+		account common.Address
+		score   []byte
+	}
+	nodes := make([]vrfNode, 0, len(validators))
+	for _, v := range validators {
+		nodes = append(nodes, vrfNode{account: v.Account, score: crypto.Keccak256(vrfValue, v.Account.Bytes())})
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return bytes.Compare(nodes[i].score, nodes[j].score) < 0
+	})
+	leaderList := make(map[uint32]common.Address)
+	for i, node := range nodes {
+		leaderList[uint32(i)] = node.account
+	}
+	return leaderList
+}
+
 func findLeaderIndex(preLeader common.Address, validators []mc.TopologyNodeInfo) (int, error) {
 	for index, v := range validators {
 		if v.Account == preLeader {
@@ -4,8 +4,12 @@
 package leaderelect2
 
 import (
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/ca"
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/event"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/mc"
@@ -155,6 +159,50 @@ func (self *LeaderIdentity) newBlockReadyBCHandle(msg *mc.NewBlockReadyMsg) {
 		parentStateDB: msg.State,
 	}
 	self.ctrlManager.StartController(curNumber+1, supBlkState.Seq, startMsg)
+
+	self.registerVrfPublicKey(msg.Header, msg.State)
+	self.sendSeedCommitReveal(msg.Header, msg.State)
+	self.sendSeedThreshold(msg.Header, msg.State)
+}
+
+// registerVrfPublicKey VRF选取leader时，验证者每个广播周期通过广播交易登记一次VRF公钥
+func (self *LeaderIdentity) registerVrfPublicKey(header *types.Header, st StateReader) {
+	selection, err := matrixstate.GetLeaderSelection(st)
+	if err != nil || selection.Mode != mc.LeaderSelectVRF {
+		return
+	}
+	bcInterval, err := matrixstate.GetBroadcastInterval(st)
+	if err != nil {
+		log.Error(self.extraInfo, "登记VRF公钥", "获取广播周期失败", "err", err)
+		return
+	}
+	number := header.Number.Uint64()
+	if !bcInterval.IsBroadcastNumber(number) {
+		return
+	}
+
+	hash := header.Hash()
+	validators, err := ca.GetElectedByHeightAndRoleByHash(hash, common.RoleValidator)
+	if err != nil {
+		log.Error(self.extraInfo, "登记VRF公钥", "获取验证者抵押列表失败", "err", err)
+		return
+	}
+	selfAddr := ca.GetDepositAddress()
+	for _, v := range validators {
+		if v.Address != selfAddr {
+			continue
+		}
+		// VRF签名返回的第一项即为签名账户的压缩公钥
+		publicKey, _, _, err := self.matrix.SignHelper().SignVrf(hash.Bytes(), hash)
+		if err != nil {
+			log.Error(self.extraInfo, "登记VRF公钥", "获取VRF公钥失败", "err", err)
+			return
+		}
+		height := new(big.Int).SetUint64(bcInterval.GetNextBroadcastNumber(number))
+		mc.PublishEvent(mc.SendBroadCastTx, mc.BroadCastEvent{Txtyps: mc.VrfPublicKey, Height: height, Data: publicKey})
+		log.Debug(self.extraInfo, "登记VRF公钥", "发送广播交易", "高度", number, "广播高度", height)
+		return
+	}
 }
 
 func (self *LeaderIdentity) roleUpdateMsgHandle(msg *mc.RoleUpdatedMsg) {
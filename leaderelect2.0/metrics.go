@@ -0,0 +1,13 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package leaderelect2
+
+import "github.com/MatrixAINetwork/go-matrix/metrics"
+
+var (
+	// stageGauge is the consensus stage (see stateDef) the latest controller moved to
+	stageGauge       = metrics.NewRegisteredGauge("leaderelect/stage", nil)
+	reelectTurnGauge = metrics.NewRegisteredGauge("leaderelect/reelect/turn", nil)
+)
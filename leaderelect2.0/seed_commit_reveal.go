@@ -0,0 +1,92 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package leaderelect2
+
+import (
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// sendSeedCommitReveal 启用随机种子提交-揭示时, 验证者在广播区块后的首个区块, 揭示上个广播区块记录的承诺,
+// 并提交下个广播区块的新承诺
+func (self *LeaderIdentity) sendSeedCommitReveal(header *types.Header, st StateReader) {
+	cfg, err := matrixstate.GetSeedCommitRevealCfg(st)
+	if err != nil || !cfg.Switcher {
+		return
+	}
+	crState, err := matrixstate.GetSeedCommitRevealState(st)
+	if err != nil {
+		log.Error(self.extraInfo, "随机种子提交-揭示", "获取状态失败", "err", err)
+		return
+	}
+	number := header.Number.Uint64()
+	// 上个广播区块的承诺在本区块处理完成
+	if number == 0 || crState.Number != number-1 {
+		return
+	}
+	bcInterval, err := matrixstate.GetBroadcastInterval(st)
+	if err != nil {
+		log.Error(self.extraInfo, "随机种子提交-揭示", "获取广播周期失败", "err", err)
+		return
+	}
+
+	hash := header.Hash()
+	validators, err := ca.GetElectedByHeightAndRoleByHash(hash, common.RoleValidator)
+	if err != nil {
+		log.Error(self.extraInfo, "随机种子提交-揭示", "获取验证者抵押列表失败", "err", err)
+		return
+	}
+	selfAddr := ca.GetDepositAddress()
+	elected := false
+	for _, v := range validators {
+		if v.Address == selfAddr {
+			elected = true
+			break
+		}
+	}
+
+	height := new(big.Int).SetUint64(bcInterval.GetNextBroadcastNumber(number))
+	for _, commit := range crState.Commits {
+		if commit.Account != selfAddr {
+			continue
+		}
+		secret, err := self.seedSecret(crState.Number, hash)
+		if err != nil {
+			log.Error(self.extraInfo, "随机种子提交-揭示", "生成种子失败", "err", err)
+			break
+		}
+		mc.PublishEvent(mc.SendBroadCastTx, mc.BroadCastEvent{Txtyps: mc.SeedReveal, Height: height, Data: secret})
+		log.Debug(self.extraInfo, "随机种子提交-揭示", "发送揭示交易", "高度", number, "广播高度", height)
+		break
+	}
+	if !elected {
+		return
+	}
+	secret, err := self.seedSecret(height.Uint64(), hash)
+	if err != nil {
+		log.Error(self.extraInfo, "随机种子提交-揭示", "生成种子失败", "err", err)
+		return
+	}
+	commitment := core.SeedCommitment(selfAddr, secret)
+	mc.PublishEvent(mc.SendBroadCastTx, mc.BroadCastEvent{Txtyps: mc.SeedCommit, Height: height, Data: commitment.Bytes()})
+	log.Debug(self.extraInfo, "随机种子提交-揭示", "发送承诺交易", "高度", number, "广播高度", height)
+}
+
+// seedSecret 由签名账户对广播区块高度的VRF输出生成该广播区块承诺的种子, 他人无法预测, 节点重启后可重新生成
+func (self *LeaderIdentity) seedSecret(broadcastNumber uint64, blkHash common.Hash) ([]byte, error) {
+	msg := crypto.Keccak256([]byte(mc.SeedCommit), new(big.Int).SetUint64(broadcastNumber).Bytes())
+	_, vrfValue, _, err := self.matrix.SignHelper().SignVrf(msg, blkHash)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(vrfValue), nil
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package leaderelect2
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// sendSeedThreshold 启用私钥交易门限加密后, 验证者在每个广播区块:
+// 1. 解密本区块提交的私钥交易中属于自己的份额, 通过份额交易释放
+// 2. 将新的私钥门限加密到本区块提交的公钥, 发送私钥交易
+// 3. 发送下一轮的公钥交易
+// 公钥交易的密钥由签名账户对提交公钥的广播区块高度的VRF输出生成, 节点重启后可重新生成
+func (self *LeaderIdentity) sendSeedThreshold(header *types.Header, st StateReader) {
+	number := header.Number.Uint64()
+	if !core.ForkActive(st, mc.ForkSeedThreshold, number+1) {
+		return
+	}
+	bcInterval, err := matrixstate.GetBroadcastInterval(st)
+	if err != nil {
+		log.Error(self.extraInfo, "私钥门限加密", "获取广播周期失败", "err", err)
+		return
+	}
+	if !bcInterval.IsBroadcastNumber(number) {
+		return
+	}
+
+	hash := header.Hash()
+	validators, err := ca.GetElectedByHeightAndRoleByHash(hash, common.RoleValidator)
+	if err != nil {
+		log.Error(self.extraInfo, "私钥门限加密", "获取验证者抵押列表失败", "err", err)
+		return
+	}
+	selfAddr := ca.GetDepositAddress()
+	elected := false
+	for _, v := range validators {
+		if v.Address == selfAddr {
+			elected = true
+			break
+		}
+	}
+	if !elected {
+		return
+	}
+	txs, err := matrixstate.GetBroadcastTxs(st)
+	if err != nil {
+		log.Error(self.extraInfo, "私钥门限加密", "获取广播交易失败", "err", err)
+		return
+	}
+	height := new(big.Int).SetUint64(bcInterval.GetNextBroadcastNumber(number))
+
+	// 本区块提交的私钥交易加密到上个广播区块提交的公钥
+	if parentInterval, err := self.matrix.BlockChain().GetBroadcastIntervalByHash(header.ParentHash); err == nil {
+		self.releaseSeedShares(txs.FindKey(mc.Privatekey), parentInterval.GetLastBroadcastNumber(), hash, height)
+	} else {
+		log.Error(self.extraInfo, "私钥门限加密", "获取父区块广播周期失败", "err", err)
+	}
+
+	seed, err := self.thresholdSecret(mc.Privatekey, number, hash)
+	if err != nil {
+		log.Error(self.extraInfo, "私钥门限加密", "生成种子失败", "err", err)
+		return
+	}
+	payload, err := core.EncryptPrivatekeyPayload(seed, txs.FindKey(mc.Publickey))
+	if err == nil {
+		mc.PublishEvent(mc.SendBroadCastTx, mc.BroadCastEvent{Txtyps: mc.Privatekey, Height: height, Data: payload})
+		log.Debug(self.extraInfo, "私钥门限加密", "发送私钥交易", "高度", number, "广播高度", height)
+	} else {
+		log.Warn(self.extraInfo, "私钥门限加密", "加密私钥失败", "err", err)
+	}
+
+	prv, err := self.seedRoundKey(bcInterval.GetNextBroadcastNumber(number+1), hash)
+	if err != nil {
+		log.Error(self.extraInfo, "私钥门限加密", "生成公钥失败", "err", err)
+		return
+	}
+	mc.PublishEvent(mc.SendBroadCastTx, mc.BroadCastEvent{Txtyps: mc.Publickey, Height: height, Data: crypto.FromECDSAPub(&prv.PublicKey)})
+	log.Debug(self.extraInfo, "私钥门限加密", "发送公钥交易", "高度", number, "广播高度", height)
+}
+
+// releaseSeedShares 用round高度提交的公钥对应的密钥解密私钥交易中自己的份额, 并发送份额交易
+func (self *LeaderIdentity) releaseSeedShares(payloads map[common.Address][]byte, round uint64, blkHash common.Hash, height *big.Int) {
+	if len(payloads) == 0 {
+		return
+	}
+	prv, err := self.seedRoundKey(round, blkHash)
+	if err != nil {
+		log.Error(self.extraInfo, "私钥门限加密", "生成密钥失败", "err", err)
+		return
+	}
+	shares := core.ReleasePrivatekeyShares(payloads, prv)
+	if len(shares) == 0 {
+		return
+	}
+	data, err := core.EncodePrivatekeyShares(shares)
+	if err != nil {
+		log.Error(self.extraInfo, "私钥门限加密", "编码份额失败", "err", err)
+		return
+	}
+	mc.PublishEvent(mc.SendBroadCastTx, mc.BroadCastEvent{Txtyps: mc.SeedShare, Height: height, Data: data})
+	log.Debug(self.extraInfo, "私钥门限加密", "发送份额交易", "份额数", len(shares), "广播高度", height)
+}
+
+// seedRoundKey 生成在round高度的广播区块提交公钥的密钥
+func (self *LeaderIdentity) seedRoundKey(round uint64, blkHash common.Hash) (*ecdsa.PrivateKey, error) {
+	secret, err := self.thresholdSecret(mc.Publickey, round, blkHash)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ToECDSA(secret)
+}
+
+// thresholdSecret 由签名账户对交易类型及高度的VRF输出生成秘密, 他人无法预测
+func (self *LeaderIdentity) thresholdSecret(typ string, number uint64, blkHash common.Hash) ([]byte, error) {
+	msg := crypto.Keccak256([]byte(typ), new(big.Int).SetUint64(number).Bytes())
+	_, vrfValue, _, err := self.matrix.SignHelper().SignVrf(msg, blkHash)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(vrfValue), nil
+}
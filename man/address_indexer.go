@@ -0,0 +1,347 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+)
+
+// Flags of an address index entry, telling how the address takes part in the
+// transaction.
+const (
+	addressTxSent     byte = 1 << iota // The address sent the transaction
+	addressTxReceived                  // The address is a recipient of the transaction
+)
+
+var (
+	// errAddressIndexDisabled is returned by the address queries if the node
+	// runs without the address indexer.
+	errAddressIndexDisabled = errors.New("address indexer disabled, enable it with --addressindex")
+
+	addressIndexHeadKey = []byte("atIndexHead") // Highest block up to which the index has no gap
+	addressIndexTailKey = []byte("atIndexTail") // Lowest block indexed by the backfill
+	addressTxPrefix     = []byte("ata")         // addressTxPrefix + address + number (uint64 big endian) + position (uint32 big endian) -> flags + tx hash
+	addressBlockPrefix  = []byte("atb")         // addressBlockPrefix + number (uint64 big endian) -> hash of the indexed block
+)
+
+// addressIndexDB is the database of the address index, which must support
+// iterating over the entries of an address.
+type addressIndexDB interface {
+	mandb.Database
+	mandb.Iteratee
+}
+
+// addressIndexer keeps an index of the transactions of the canonical chain by
+// sender and recipient address. The new blocks are indexed as they are
+// imported, the blocks imported before the index was enabled are backfilled in
+// the background, from the most recent one to the genesis.
+type addressIndexer struct {
+	db    addressIndexDB
+	chain *core.BlockChain
+
+	lock   sync.Mutex // Serializes the writes of the head
+	synced bool       // Whether the imported blocks extend the index head
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newAddressIndexer(db addressIndexDB, chain *core.BlockChain) *addressIndexer {
+	return &addressIndexer{
+		db:    db,
+		chain: chain,
+		quit:  make(chan struct{}),
+	}
+}
+
+// Start indexes the imported blocks, and catches up with the blocks imported
+// since the last run and backfills the older ones in the background.
+func (idx *addressIndexer) Start() {
+	events := make(chan core.ChainEvent, chainEventChanSize)
+	sub := idx.chain.SubscribeChainEvent(events)
+
+	idx.wg.Add(2)
+	go func() {
+		defer idx.wg.Done()
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				if err := idx.indexImported(ev.Block); err != nil {
+					log.Warn("Failed to index block addresses", "number", ev.Block.Number(), "err", err)
+				}
+			case <-sub.Err():
+				return
+			case <-idx.quit:
+				return
+			}
+		}
+	}()
+	go func() {
+		defer idx.wg.Done()
+
+		if idx.catchUp() {
+			idx.backfill()
+		}
+	}()
+}
+
+// Stop terminates the indexing and closes the index database.
+func (idx *addressIndexer) Stop() {
+	close(idx.quit)
+	idx.wg.Wait()
+	idx.db.Close()
+}
+
+// indexImported indexes an imported block, advancing the head once the index
+// caught up with the chain.
+func (idx *addressIndexer) indexImported(block *types.Block) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if err := idx.indexBlock(block); err != nil {
+		return err
+	}
+	if idx.synced {
+		return idx.db.Put(addressIndexHeadKey, encodeBlockNumber(block.NumberU64()))
+	}
+	return nil
+}
+
+// catchUp indexes the blocks imported after the index head, up to the current
+// block. A new index starts at the current block. It returns false if the
+// indexer was stopped.
+func (idx *addressIndexer) catchUp() bool {
+	if _, ok := idx.number(addressIndexHeadKey); !ok {
+		current := idx.chain.CurrentBlock().NumberU64()
+		batch := idx.db.NewBatch()
+		batch.Put(addressIndexHeadKey, encodeBlockNumber(current))
+		batch.Put(addressIndexTailKey, encodeBlockNumber(current+1))
+		if err := batch.Write(); err != nil {
+			log.Error("Failed to initialize address index", "err", err)
+			return false
+		}
+	}
+	for {
+		select {
+		case <-idx.quit:
+			return false
+		default:
+		}
+		idx.lock.Lock()
+		head, _ := idx.number(addressIndexHeadKey)
+		if head >= idx.chain.CurrentBlock().NumberU64() {
+			idx.synced = true
+			idx.lock.Unlock()
+			return true
+		}
+		err := errors.New("block not found")
+		if block := idx.chain.GetBlockByNumber(head + 1); block != nil {
+			if err = idx.indexBlock(block); err == nil {
+				err = idx.db.Put(addressIndexHeadKey, encodeBlockNumber(head+1))
+			}
+		}
+		idx.lock.Unlock()
+		if err != nil {
+			log.Error("Failed to catch up address index", "number", head+1, "err", err)
+			return false
+		}
+	}
+}
+
+// backfill indexes the blocks below the tail, down to the genesis.
+func (idx *addressIndexer) backfill() {
+	tail, _ := idx.number(addressIndexTailKey)
+	if tail > 1 {
+		log.Info("Backfilling address index", "from", tail-1)
+	}
+	for ; tail > 1; tail-- {
+		select {
+		case <-idx.quit:
+			return
+		default:
+		}
+		block := idx.chain.GetBlockByNumber(tail - 1)
+		if block == nil {
+			log.Error("Failed to backfill address index, block not found", "number", tail-1)
+			return
+		}
+		if err := idx.indexBlock(block); err != nil {
+			log.Error("Failed to backfill address index", "number", tail-1, "err", err)
+			return
+		}
+		if err := idx.db.Put(addressIndexTailKey, encodeBlockNumber(tail-1)); err != nil {
+			log.Error("Failed to write address index tail", "err", err)
+			return
+		}
+	}
+	log.Info("Address index backfilled")
+}
+
+// indexBlock writes the entries of the transactions of a block. The entries
+// of a block previously indexed at the same height are removed first.
+func (idx *addressIndexer) indexBlock(block *types.Block) error {
+	number := block.NumberU64()
+	if data, err := idx.db.Get(addressBlockKey(number)); err == nil && len(data) == common.HashLength {
+		old := common.BytesToHash(data)
+		if old == block.Hash() {
+			return nil
+		}
+		if oldBlock := idx.chain.GetBlock(old, number); oldBlock != nil {
+			for _, entry := range addressBlockEntries(oldBlock) {
+				if err := idx.db.Delete(entry.key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	batch := idx.db.NewBatch()
+	for _, entry := range addressBlockEntries(block) {
+		if err := batch.Put(entry.key, entry.value); err != nil {
+			return err
+		}
+	}
+	if err := batch.Put(addressBlockKey(number), block.Hash().Bytes()); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// transactions retrieves the hashes of the canonical transactions of an
+// address, most recent first, skipping the first offset ones. It reports
+// whether there are more transactions after the limit.
+func (idx *addressIndexer) transactions(addr common.Address, flags byte, offset, limit int) ([]common.Hash, bool, error) {
+	it := idx.db.NewIteratorWithPrefix(append(append([]byte{}, addressTxPrefix...), addr.Bytes()...))
+	defer it.Release()
+
+	var (
+		hashes    = make([]common.Hash, 0, limit)
+		canonical = make(map[uint64]bool)
+		numberPos = len(addressTxPrefix) + common.AddressLength
+	)
+	for ok := it.Last(); ok; ok = it.Prev() {
+		key, value := it.Key(), it.Value()
+		if len(key) != numberPos+12 || len(value) != 1+common.HashLength || value[0]&flags == 0 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[numberPos:])
+		isCanonical, ok := canonical[number]
+		if !ok {
+			isCanonical = idx.isCanonical(number)
+			canonical[number] = isCanonical
+		}
+		if !isCanonical {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if len(hashes) == limit {
+			return hashes, true, it.Error()
+		}
+		hashes = append(hashes, common.BytesToHash(value[1:]))
+	}
+	return hashes, false, it.Error()
+}
+
+// isCanonical checks whether the block indexed at the given height is still
+// part of the canonical chain.
+func (idx *addressIndexer) isCanonical(number uint64) bool {
+	data, err := idx.db.Get(addressBlockKey(number))
+	if err != nil || len(data) != common.HashLength {
+		return false
+	}
+	header := idx.chain.GetHeaderByNumber(number)
+	return header != nil && header.Hash() == common.BytesToHash(data)
+}
+
+// number reads a block number stored under the given key.
+func (idx *addressIndexer) number(key []byte) (uint64, bool) {
+	data, err := idx.db.Get(key)
+	if err != nil || len(data) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data), true
+}
+
+type addressIndexEntry struct {
+	key   []byte
+	value []byte
+}
+
+// addressBlockEntries returns the index entries of the transactions of a block,
+// one per transaction and address taking part in it.
+func addressBlockEntries(block *types.Block) []addressIndexEntry {
+	var (
+		entries []addressIndexEntry
+		pos     uint32
+	)
+	for _, currency := range block.Currencies() {
+		for _, tx := range currency.Transactions.GetTransactions() {
+			hash := tx.Hash()
+			for addr, flags := range transactionAddresses(tx) {
+				entries = append(entries, addressIndexEntry{
+					key:   addressTxKey(addr, block.NumberU64(), pos),
+					value: append([]byte{flags}, hash.Bytes()...),
+				})
+			}
+			pos++
+		}
+	}
+	return entries
+}
+
+// transactionAddresses returns the sender and the recipients of a transaction,
+// including the recipients of the extra transfers.
+func transactionAddresses(tx types.SelfTransaction) map[common.Address]byte {
+	addrs := make(map[common.Address]byte)
+	switch tx.GetMatrixType() {
+	case common.ExtraUnGasMinerTxType, common.ExtraUnGasValidatorTxType, common.ExtraUnGasInterestTxType, common.ExtraUnGasTxsType, common.ExtraUnGasLotteryTxType:
+		addrs[tx.From()] |= addressTxSent
+	default:
+		if from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx); err == nil {
+			addrs[from] |= addressTxSent
+		}
+	}
+	if to := tx.To(); to != nil {
+		addrs[*to] |= addressTxReceived
+	}
+	for _, extra := range tx.GetMatrix_EX() {
+		for _, to := range extra.ExtraTo {
+			if to.Recipient != nil {
+				addrs[*to.Recipient] |= addressTxReceived
+			}
+		}
+	}
+	return addrs
+}
+
+func encodeBlockNumber(number uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	return enc
+}
+
+func addressBlockKey(number uint64) []byte {
+	return append(append([]byte{}, addressBlockPrefix...), encodeBlockNumber(number)...)
+}
+
+func addressTxKey(addr common.Address, number uint64, pos uint32) []byte {
+	key := append(append([]byte{}, addressTxPrefix...), addr.Bytes()...)
+	key = append(key, encodeBlockNumber(number)...)
+	var enc [4]byte
+	binary.BigEndian.PutUint32(enc[:], pos)
+	return append(key, enc[:]...)
+}
@@ -5,6 +5,7 @@
 package man
 
 import (
+	"bufio"
 	"compress/gzip"
 	"context"
 	"encoding/json"
@@ -31,6 +32,8 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/base58"
 	"github.com/MatrixAINetwork/go-matrix/man/wizard"
 	"github.com/MatrixAINetwork/go-matrix/miner"
+	"github.com/MatrixAINetwork/go-matrix/p2p"
+	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
 	"github.com/MatrixAINetwork/go-matrix/params"
 	"github.com/MatrixAINetwork/go-matrix/rlp"
 	"github.com/MatrixAINetwork/go-matrix/rpc"
@@ -65,6 +68,18 @@ func (api *PublicMatrixAPI) Hashrate() hexutil.Uint64 {
 	return hexutil.Uint64(api.e.Miner().HashRate())
 }
 
+// GetProvenMatrixData retrieves a matrix state entry (e.g. the broadcast
+// transactions or the elected nodes) in the state of a block from the peers,
+// verified by Merkle proof against the block's state roots. Only the header of
+// the block is needed locally, so light clients can use it as well.
+func (api *PublicMatrixAPI) GetProvenMatrixData(ctx context.Context, blockHash common.Hash, key string) (hexutil.Bytes, error) {
+	header := api.e.blockchain.GetHeaderByHash(blockHash)
+	if header == nil {
+		return nil, fmt.Errorf("block %x not found", blockHash)
+	}
+	return api.e.protocolManager.RetrieveMatrixData(ctx, header, key)
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -359,7 +374,8 @@ func (api *PrivateAdminAPI) ExportChain(file string) (bool, error) {
 	}
 
 	// Export the blockchain
-	if err := api.man.BlockChain().Export(writer); err != nil {
+	chain := api.man.BlockChain()
+	if err := chain.ExportVersioned(writer, 0, chain.CurrentBlock().NumberU64()); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -391,8 +407,15 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 		}
 	}
 
+	buffered := bufio.NewReader(reader)
+	if core.IsVersionedExport(buffered) {
+		if _, err := api.man.BlockChain().ImportVersioned(buffered, 2500, nil); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
 	// Run actual the import in pre-configured batches
-	stream := rlp.NewStream(reader, 0)
+	stream := rlp.NewStream(buffered, 0)
 
 	blocks, index := make([]*types.Block, 0, 2500), 0
 	for batch := 0; ; batch++ {
@@ -424,6 +447,41 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// AddTrustedPeer allows a remote node to always connect, even if slots are full,
+// and persists it in the trusted node list.
+func (api *PrivateAdminAPI) AddTrustedPeer(url string) (bool, error) {
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	if err := p2p.ServerP2p.AddTrustedPeer(node); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveTrustedPeer removes a remote node from the trusted peer set and from the
+// persisted trusted node list, but it does not disconnect it automatically.
+func (api *PrivateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	if err := p2p.ServerP2p.RemoveTrustedPeer(node); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReloadPeers re-reads the persisted static and trusted node lists, connecting
+// to the added nodes and releasing the removed ones.
+func (api *PrivateAdminAPI) ReloadPeers() (bool, error) {
+	if err := p2p.ServerP2p.ReloadNodeLists(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // PublicDebugAPI is the collection of Matrix full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
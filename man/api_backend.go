@@ -88,6 +88,13 @@ func (b *ManAPIBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	if blockNr == rpc.LatestBlockNumber {
 		return b.man.blockchain.CurrentBlock().Header(), nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		finalized, err := b.finalizedCheckpoint()
+		if err != nil {
+			return nil, err
+		}
+		return b.man.blockchain.GetHeaderByHash(finalized.Hash), nil
+	}
 	return b.man.blockchain.GetHeaderByNumber(uint64(blockNr)), nil
 }
 
@@ -105,9 +112,28 @@ func (b *ManAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 	if blockNr == rpc.LatestBlockNumber {
 		return b.man.blockchain.CurrentBlock(), nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		finalized, err := b.finalizedCheckpoint()
+		if err != nil {
+			return nil, err
+		}
+		return b.man.blockchain.GetBlockByHash(finalized.Hash), nil
+	}
 	return b.man.blockchain.GetBlockByNumber(uint64(blockNr)), nil
 }
 
+// finalizedCheckpoint returns the finalized checkpoint of the canonical chain.
+func (b *ManAPIBackend) finalizedCheckpoint() (*mc.FinalizedCheckpoint, error) {
+	finalized, err := b.man.blockchain.GetFinalizedCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	if finalized.Number == 0 {
+		return nil, errors.New("finalized block not found")
+	}
+	return finalized, nil
+}
+
 func (b *ManAPIBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDBManage, *types.Header, error) {
 	// Pending state is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
@@ -164,7 +190,8 @@ func (b *ManAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([]types.
 	for k, v := range mm {
 		logs = append(logs, types.CoinLogs{k, v})
 	}
-	return logs, nil
+	// Reward and slash logs follow the transaction logs of the block
+	return append(logs, b.man.blockchain.GetSystemLogs(hash, *number)...), nil
 }
 
 func (b *ManAPIBackend) GetTd(blockHash common.Hash) *big.Int {
@@ -232,7 +259,7 @@ func (b *ManAPIBackend) ImportSuperBlock(ctx context.Context, filePath string) (
 
 //TODO 调用该方法的时候应该返回错误的切片
 func (b *ManAPIBackend) SendTx(ctx context.Context, signedTx types.SelfTransaction) error {
-	return b.man.txPool.AddRemote(signedTx)
+	return b.man.txPool.AddLocal(signedTx)
 }
 
 func (b *ManAPIBackend) GetPoolTransactions() (types.SelfTransactions, error) {
@@ -280,22 +307,11 @@ func (b *ManAPIBackend) GetPoolNonce(cointyp string, ctx context.Context, addr c
 }
 
 func (b *ManAPIBackend) Stats() (pending int, queued int) {
-	bpooler, err := b.man.TxPool().GetTxPoolByType(types.BroadCastTxIndex)
-	if err == nil {
-		_, ok := bpooler.(*core.BroadCastTxPool)
-		if ok {
-			//_,btxs = bpool.Content()
-		} else {
-			queued = 0
-		}
-	}
 	npooler, nerr := b.man.TxPool().GetTxPoolByType(types.NormalTxIndex)
 	if nerr == nil {
 		npool, ok := npooler.(*core.NormalTxPool)
 		if ok {
-			pending, _ = npool.Stats()
-		} else {
-			pending = 0
+			pending, queued = npool.Stats()
 		}
 	}
 	return pending, queued
@@ -314,39 +330,60 @@ func (b *ManAPIBackend) GetTxNmap() map[uint32]*types.Transaction {
 	return retval
 }
 
-//TODO 应该将返回值加入切片中否则以后多一种交易就要添加一个返回值
-func (b *ManAPIBackend) TxPoolContent() (ntxs map[common.Address]types.SelfTransactions, btxs map[common.Address]types.SelfTransactions) {
-	ntxs = make(map[common.Address]types.SelfTransactions)
-	btxs = make(map[common.Address]types.SelfTransactions)
-	bpooler, err := b.man.TxPool().GetTxPoolByType(types.BroadCastTxIndex)
-	if err == nil {
-		_, ok := bpooler.(*core.BroadCastTxPool)
-		if ok {
-			//_,btxs = bpool.Content()
-		} else {
-			btxs = nil
+// TxPoolContent 返回普通交易池中可执行的交易和nonce缺口之后排队的交易, 广播交易见TxPoolBroadcastContent
+func (b *ManAPIBackend) TxPoolContent() (pending map[common.Address]types.SelfTransactions, queued map[common.Address]types.SelfTransactions) {
+	pending = make(map[common.Address]types.SelfTransactions)
+	queued = make(map[common.Address]types.SelfTransactions)
+	npooler, nerr := b.man.TxPool().GetTxPoolByType(types.NormalTxIndex)
+	if nerr != nil {
+		return pending, queued
+	}
+	npool, ok := npooler.(*core.NormalTxPool)
+	if !ok {
+		return pending, queued
+	}
+	ptxs, qtxs := npool.Content()
+	for account, txs := range ptxs {
+		for _, tx := range txs {
+			pending[account] = append(pending[account], tx)
 		}
 	}
-	npooler, nerr := b.man.TxPool().GetTxPoolByType(types.NormalTxIndex)
-	if nerr == nil {
-		npool, ok := npooler.(*core.NormalTxPool)
-		if ok {
-			txlist := npool.Content()
-			for k, vlist := range txlist {
-				txser := make([]types.SelfTransaction, 0)
-				for _, v := range vlist {
-					txser = append(txser, v)
-				}
-				if vs, ok := ntxs[k]; !ok {
-					txser = append(txser, vs...)
-				}
-				ntxs[k] = txser
-			}
-		} else {
-			ntxs = nil
+	for account, txs := range qtxs {
+		for _, tx := range txs {
+			queued[account] = append(queued[account], tx)
 		}
 	}
-	return ntxs, btxs
+	return pending, queued
+}
+
+func (b *ManAPIBackend) TxPoolScheduled() map[common.Address]types.SelfTransactions {
+	npooler, err := b.man.TxPool().GetTxPoolByType(types.NormalTxIndex)
+	if err != nil {
+		return nil
+	}
+	npool, ok := npooler.(*core.NormalTxPool)
+	if !ok {
+		return nil
+	}
+	scheduled := make(map[common.Address]types.SelfTransactions)
+	for account, txs := range npool.Scheduled() {
+		for _, tx := range txs {
+			scheduled[account] = append(scheduled[account], tx)
+		}
+	}
+	return scheduled
+}
+
+func (b *ManAPIBackend) TxPoolBroadcastContent() map[common.Address]map[string][]*core.BroadcastTxContent {
+	bpooler, err := b.man.TxPool().GetTxPoolByType(types.BroadCastTxIndex)
+	if err != nil {
+		return nil
+	}
+	bpool, ok := bpooler.(*core.BroadCastTxPool)
+	if !ok {
+		return nil
+	}
+	return bpool.Content()
 }
 
 func (b *ManAPIBackend) SubscribeNewTxsEvent(ch chan core.NewTxsEvent) event.Subscription {
@@ -398,6 +435,17 @@ func (b *ManAPIBackend) SendBroadTx(ctx context.Context, signedTx types.SelfTran
 	return b.man.txPool.AddBroadTx(signedTx, bType)
 }
 
+// EncodeBroadcastPayload encodes the data of a broadcast transaction in the
+// format enabled for the next block.
+func (b *ManAPIBackend) EncodeBroadcastPayload(data map[string][]byte) ([]byte, error) {
+	head := b.man.blockchain.CurrentBlock()
+	st, err := b.man.blockchain.StateAt(head.Root())
+	if err != nil {
+		return nil, err
+	}
+	return core.EncodeBroadcastTxPayload(st, head.NumberU64()+1, data)
+}
+
 //
 func (b *ManAPIBackend) FetcherNotify(hash common.Hash, number uint64) {
 
@@ -417,6 +465,23 @@ func (b *ManAPIBackend) GetDepositAccount(signAccount common.Address, blockHash
 	return depositAccount, err
 }
 
+// GetAddressTransactions returns the hashes of the transactions sent and/or
+// received by an address, most recent first, and whether there are more of
+// them after the limit.
+func (b *ManAPIBackend) GetAddressTransactions(addr common.Address, sent, received bool, offset, limit int) ([]common.Hash, bool, error) {
+	if b.man.addressIndexer == nil {
+		return nil, false, errAddressIndexDisabled
+	}
+	var flags byte
+	if sent {
+		flags |= addressTxSent
+	}
+	if received {
+		flags |= addressTxReceived
+	}
+	return b.man.addressIndexer.transactions(addr, flags, offset, limit)
+}
+
 type TimeZone struct {
 	Start uint64
 	Stop  uint64
@@ -0,0 +1,110 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core"
+)
+
+// PublicBridgeAPI provides access to the bridge event signs collected by the
+// node and to the attestations recorded on chain, for the external relayers.
+type PublicBridgeAPI struct {
+	man *Matrix
+}
+
+// NewPublicBridgeAPI creates a new bridge API.
+func NewPublicBridgeAPI(man *Matrix) *PublicBridgeAPI {
+	return &PublicBridgeAPI{man: man}
+}
+
+// BridgeAttestationResult is a bridge event along with the validator signs on
+// it. The payload is the data of the bridge attestation transaction recording
+// it, empty until a supermajority of the validators signed.
+type BridgeAttestationResult struct {
+	ID          common.Hash     `json:"id"`
+	Contract    common.Address  `json:"contract"`
+	Topics      []common.Hash   `json:"topics"`
+	Data        hexutil.Bytes   `json:"data"`
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	BlockHash   common.Hash     `json:"blockHash"`
+	TxHash      common.Hash     `json:"txHash"`
+	LogIndex    hexutil.Uint64  `json:"logIndex"`
+	Signs       []hexutil.Bytes `json:"signs"`
+	Attested    *hexutil.Uint64 `json:"attested,omitempty"`
+	Payload     hexutil.Bytes   `json:"payload,omitempty"`
+}
+
+func newBridgeAttestationResult(att *core.BridgeAttestation) *BridgeAttestationResult {
+	ev := &att.Event
+	result := &BridgeAttestationResult{
+		ID:          ev.Hash(),
+		Contract:    ev.Contract,
+		Topics:      ev.Topics,
+		Data:        ev.Data,
+		BlockNumber: hexutil.Uint64(ev.BlockNumber),
+		BlockHash:   ev.BlockHash,
+		TxHash:      ev.TxHash,
+		LogIndex:    hexutil.Uint64(ev.LogIndex),
+		Signs:       make([]hexutil.Bytes, 0, len(att.Signs)),
+	}
+	for _, sign := range att.Signs {
+		result.Signs = append(result.Signs, sign.Bytes())
+	}
+	return result
+}
+
+// PendingAttestations returns the bridge events the node collected signs on
+// which are not recorded on chain yet.
+func (api *PublicBridgeAPI) PendingAttestations() ([]*BridgeAttestationResult, error) {
+	bc := api.man.BlockChain()
+	st, err := bc.State()
+	if err != nil {
+		return nil, err
+	}
+	number := bc.CurrentBlock().NumberU64() + 1
+	getHash := func(n uint64) common.Hash {
+		if header := bc.GetHeaderByNumber(n); header != nil {
+			return header.Hash()
+		}
+		return common.Hash{}
+	}
+
+	pending := bc.BridgePool().Pending()
+	results := make([]*BridgeAttestationResult, 0, len(pending))
+	for _, att := range pending {
+		switch err := core.CheckBridgeAttestation(st, getHash, att, number); err {
+		case nil:
+			result := newBridgeAttestationResult(att)
+			if result.Payload, err = core.EncodeBridgeAttestation(att); err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		case core.ErrBridgeAttested:
+			bc.BridgePool().Remove(att.Event.Hash())
+		case core.ErrBridgeSupermajority:
+			results = append(results, newBridgeAttestationResult(att))
+		}
+	}
+	return results, nil
+}
+
+// GetAttestation returns the attestation of the bridge event recorded on the
+// canonical chain, or nil if the event is not attested yet.
+func (api *PublicBridgeAPI) GetAttestation(id common.Hash) (*BridgeAttestationResult, error) {
+	st, err := api.man.BlockChain().State()
+	if err != nil {
+		return nil, err
+	}
+	record, err := core.GetBridgeAttestation(st, id)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	result := newBridgeAttestationResult(&record.Attestation)
+	attested := hexutil.Uint64(record.Number)
+	result.Attested = &attested
+	return result, nil
+}
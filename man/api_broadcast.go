@@ -0,0 +1,273 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// chainEventChanSize is the size of channel listening to ChainEvent.
+const chainEventChanSize = 10
+
+// PublicBroadcastAPI provides notifications about the broadcast intervals of
+// the chain.
+type PublicBroadcastAPI struct {
+	man *Matrix
+}
+
+// NewPublicBroadcastAPI creates a new broadcast interval notification API.
+func NewPublicBroadcastAPI(man *Matrix) *PublicBroadcastAPI {
+	return &PublicBroadcastAPI{man: man}
+}
+
+// BroadcastIntervalResult is the broadcast data committed by the broadcast block
+// closing a broadcast interval.
+type BroadcastIntervalResult struct {
+	Interval    hexutil.Uint64           `json:"interval"`
+	Number      hexutil.Uint64           `json:"number"`
+	Hash        common.Hash              `json:"hash"`
+	Heartbeats  map[string]hexutil.Bytes `json:"heartbeats"`  // Heartbeat payloads by sender
+	CallTheRoll map[string]uint32        `json:"callTheRoll"` // Roll call results by node
+}
+
+// NewBroadcastInterval sends a notification each time a broadcast block is
+// added to the chain, delivering the heartbeats and roll call results recorded
+// for the interval it closes.
+func (api *PublicBroadcastAPI) NewBroadcastInterval(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan core.ChainEvent, chainEventChanSize)
+		sub := api.man.BlockChain().SubscribeChainEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				header := ev.Block.Header()
+				if !manparams.IsBroadcastNumberByHash(header.Number.Uint64(), header.ParentHash) {
+					continue
+				}
+				result, err := api.broadcastInterval(header)
+				if err != nil {
+					log.Warn("Failed to retrieve broadcast interval data", "number", header.Number, "err", err)
+					continue
+				}
+				notifier.Notify(rpcSub.ID, result)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// broadcastInterval assembles the notification of a broadcast block.
+func (api *PublicBroadcastAPI) broadcastInterval(header *types.Header) (*BroadcastIntervalResult, error) {
+	bcInterval, err := manparams.GetBCIntervalInfoByHash(header.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	interval := header.Number.Uint64() / bcInterval.GetBroadcastInterval()
+
+	snapshot, err := core.GetBroadcastDataByBlock(api.man.BlockChain(), interval, header)
+	if err != nil {
+		return nil, err
+	}
+	result := &BroadcastIntervalResult{
+		Interval:    hexutil.Uint64(snapshot.Interval),
+		Number:      hexutil.Uint64(snapshot.Number),
+		Hash:        snapshot.Hash,
+		Heartbeats:  make(map[string]hexutil.Bytes),
+		CallTheRoll: make(map[string]uint32),
+	}
+	for sender, payload := range snapshot.Heartbeats() {
+		result.Heartbeats[base58.Base58EncodeToString(params.MAN_COIN, sender)] = payload
+	}
+	// Roll calls are sent by the broadcast nodes as JSON maps of node to result
+	for sender, payload := range snapshot.CallTheRolls() {
+		rolls := make(map[string]uint32)
+		if err := json.Unmarshal(payload, &rolls); err != nil {
+			log.Warn("Invalid roll call payload", "sender", sender, "err", err)
+			continue
+		}
+		for node, roll := range rolls {
+			result.CallTheRoll[base58.Base58EncodeToString(params.MAN_COIN, common.HexToAddress(node))] = roll
+		}
+	}
+	return result, nil
+}
+
+// BroadcastHistoryResult is a broadcast payload sent by an address in an interval.
+type BroadcastHistoryResult struct {
+	Interval hexutil.Uint64 `json:"interval"`
+	Payload  hexutil.Bytes  `json:"payload"`
+}
+
+// IndexedBroadcastIntervalResult is the broadcast data of an interval read from
+// the broadcast index.
+type IndexedBroadcastIntervalResult struct {
+	Interval hexutil.Uint64                      `json:"interval"`
+	Number   hexutil.Uint64                      `json:"number"`
+	Hash     common.Hash                         `json:"hash"`
+	Data     map[string]map[string]hexutil.Bytes `json:"data"` // Payloads by broadcast type and sender
+}
+
+// GetBroadcastHistory returns the payloads of a broadcast type sent by an address
+// in the intervals fromInterval..toInterval. It requires the broadcast indexer.
+func (api *PublicBroadcastAPI) GetBroadcastHistory(typ string, manAddress string, fromInterval, toInterval hexutil.Uint64) ([]BroadcastHistoryResult, error) {
+	if api.man.broadcastIndexer == nil {
+		return nil, errBroadcastIndexDisabled
+	}
+	bt, ok := core.LookupBroadcastType(typ)
+	if !ok {
+		return nil, fmt.Errorf("unknown broadcast type %s", typ)
+	}
+	addr, err := base58.Base58DecodeToAddress(manAddress)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := api.man.broadcastIndexer.history(bt.StateKey, addr, uint64(fromInterval), uint64(toInterval))
+	if err != nil {
+		return nil, err
+	}
+	results := make([]BroadcastHistoryResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, BroadcastHistoryResult{Interval: hexutil.Uint64(entry.Interval), Payload: entry.Payload})
+	}
+	return results, nil
+}
+
+// GetHeartbeatHistory returns the heartbeats sent by an address in the intervals
+// fromInterval..toInterval.
+func (api *PublicBroadcastAPI) GetHeartbeatHistory(manAddress string, fromInterval, toInterval hexutil.Uint64) ([]BroadcastHistoryResult, error) {
+	return api.GetBroadcastHistory(mc.Heartbeat, manAddress, fromInterval, toInterval)
+}
+
+// GetPublicKeyHistory returns the seed proofs sent by an address in the intervals
+// fromInterval..toInterval.
+func (api *PublicBroadcastAPI) GetPublicKeyHistory(manAddress string, fromInterval, toInterval hexutil.Uint64) ([]BroadcastHistoryResult, error) {
+	return api.GetBroadcastHistory(mc.Publickey, manAddress, fromInterval, toInterval)
+}
+
+// GetSeeds returns the threshold encrypted seeds sent in a broadcast interval by
+// sender, decrypted with the shares their recipients released in the following
+// interval. Seeds without a quorum of released shares are left out.
+func (api *PublicBroadcastAPI) GetSeeds(interval hexutil.Uint64) (map[string]hexutil.Bytes, error) {
+	bc := api.man.BlockChain()
+	snapshot, err := core.GetBroadcastDataByInterval(bc, uint64(interval))
+	if err != nil {
+		return nil, err
+	}
+	next, err := core.GetBroadcastDataByInterval(bc, uint64(interval)+1)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]hexutil.Bytes)
+	for sender, seed := range snapshot.Seeds(next) {
+		result[base58.Base58EncodeToString(params.MAN_COIN, sender)] = seed
+	}
+	return result, nil
+}
+
+// GetCallTheRollHistory returns the roll calls sent by a broadcast node in the
+// intervals fromInterval..toInterval.
+func (api *PublicBroadcastAPI) GetCallTheRollHistory(manAddress string, fromInterval, toInterval hexutil.Uint64) ([]BroadcastHistoryResult, error) {
+	return api.GetBroadcastHistory(mc.CallTheRoll, manAddress, fromInterval, toInterval)
+}
+
+// GetIndexedBroadcastInterval returns all the broadcast data of an interval from
+// the broadcast index.
+func (api *PublicBroadcastAPI) GetIndexedBroadcastInterval(interval hexutil.Uint64) (*IndexedBroadcastIntervalResult, error) {
+	if api.man.broadcastIndexer == nil {
+		return nil, errBroadcastIndexDisabled
+	}
+	record, err := api.man.broadcastIndexer.interval(uint64(interval))
+	if err != nil {
+		return nil, err
+	}
+	result := &IndexedBroadcastIntervalResult{
+		Interval: interval,
+		Number:   hexutil.Uint64(record.Number),
+		Hash:     record.Hash,
+		Data:     make(map[string]map[string]hexutil.Bytes),
+	}
+	for _, item := range record.Txs {
+		senders, ok := result.Data[item.Key.Key]
+		if !ok {
+			senders = make(map[string]hexutil.Bytes)
+			result.Data[item.Key.Key] = senders
+		}
+		senders[base58.Base58EncodeToString(params.MAN_COIN, item.Key.Address)] = item.Value
+	}
+	return result, nil
+}
+
+// BroadcastIntervalInfoResult describes the broadcast and election periods a
+// block belongs to.
+type BroadcastIntervalInfoResult struct {
+	Number             hexutil.Uint64 `json:"number"`
+	Interval           hexutil.Uint64 `json:"interval"` // Broadcast interval the block belongs to
+	BroadcastInterval  hexutil.Uint64 `json:"broadcastInterval"`
+	ReElectionInterval hexutil.Uint64 `json:"reElectionInterval"`
+	LastBroadcast      hexutil.Uint64 `json:"lastBroadcast"`
+	NextBroadcast      hexutil.Uint64 `json:"nextBroadcast"`
+	LastReElection     hexutil.Uint64 `json:"lastReElection"`
+	NextReElection     hexutil.Uint64 `json:"nextReElection"`
+}
+
+// GetBroadcastIntervalInfo returns the broadcast and election periods of the
+// given block.
+func (api *PublicBroadcastAPI) GetBroadcastIntervalInfo(blockNr rpc.BlockNumber) (*BroadcastIntervalInfoResult, error) {
+	number := api.man.BlockChain().CurrentBlock().NumberU64()
+	if blockNr >= 0 && uint64(blockNr) < number {
+		number = uint64(blockNr)
+	}
+	bcInterval, err := manparams.GetBCIntervalInfoByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	nextBroadcast := bcInterval.GetNextBroadcastNumber(number)
+	nextReElection := bcInterval.GetNextReElectionNumber(number)
+	before := func(next, interval uint64) uint64 {
+		if next < interval {
+			return 0
+		}
+		return next - interval
+	}
+	return &BroadcastIntervalInfoResult{
+		Number:             hexutil.Uint64(number),
+		Interval:           hexutil.Uint64(number / bcInterval.GetBroadcastInterval()),
+		BroadcastInterval:  hexutil.Uint64(bcInterval.GetBroadcastInterval()),
+		ReElectionInterval: hexutil.Uint64(bcInterval.GetReElectionInterval()),
+		LastBroadcast:      hexutil.Uint64(before(nextBroadcast, bcInterval.GetBroadcastInterval())),
+		NextBroadcast:      hexutil.Uint64(nextBroadcast),
+		LastReElection:     hexutil.Uint64(before(nextReElection, bcInterval.GetReElectionInterval())),
+		NextReElection:     hexutil.Uint64(nextReElection),
+	}, nil
+}
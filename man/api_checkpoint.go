@@ -0,0 +1,73 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+// PublicCheckpointAPI provides access to the checkpoint votes collected by the
+// node and to the finalized checkpoint of the chain.
+type PublicCheckpointAPI struct {
+	man *Matrix
+}
+
+// NewPublicCheckpointAPI creates a new checkpoint API.
+func NewPublicCheckpointAPI(man *Matrix) *PublicCheckpointAPI {
+	return &PublicCheckpointAPI{man: man}
+}
+
+// CheckpointResult is a checkpoint voted on by the validators. The aggregated
+// vote is the one a following header carries to finalize the checkpoint,
+// empty until a supermajority of the validators voted.
+type CheckpointResult struct {
+	Number     hexutil.Uint64       `json:"number"`
+	Hash       common.Hash          `json:"hash"`
+	Votes      int                  `json:"votes"`
+	Aggregated *types.AggregateVote `json:"aggregated,omitempty"`
+}
+
+// FinalizedCheckpoint returns the latest checkpoint finalized on the canonical
+// chain, the chain never reorgs behind it.
+func (api *PublicCheckpointAPI) FinalizedCheckpoint() (*CheckpointResult, error) {
+	finalized, err := api.man.BlockChain().GetFinalizedCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointResult{Number: hexutil.Uint64(finalized.Number), Hash: finalized.Hash}, nil
+}
+
+// PendingCheckpoints returns the checkpoints above the finalized one which the
+// node collected votes on, the most recent first.
+func (api *PublicCheckpointAPI) PendingCheckpoints() ([]*CheckpointResult, error) {
+	bc := api.man.BlockChain()
+	finalized, err := bc.GetFinalizedCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	st, err := bc.State()
+	if err != nil {
+		return nil, err
+	}
+	number := bc.CurrentBlock().NumberU64() + 1
+
+	pool := bc.CheckpointPool()
+	numbers, hashes, counts := pool.Pending(finalized.Number)
+	results := make([]*CheckpointResult, 0, len(numbers))
+	for i := range numbers {
+		result := &CheckpointResult{Number: hexutil.Uint64(numbers[i]), Hash: hashes[i], Votes: counts[i]}
+		if header := bc.GetHeaderByNumber(numbers[i]); header == nil || header.Hash() != hashes[i] {
+			continue
+		}
+		if vote, err := core.BuildCheckpointVote(st, numbers[i], hashes[i], pool.Votes(numbers[i], hashes[i]), number); err == nil {
+			result.Aggregated = vote
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// PublicElectionAPI provides a dry run of the election, so that the node
+// operators can predict the result of the next election.
+type PublicElectionAPI struct {
+	man *Matrix
+}
+
+// NewPublicElectionAPI creates a new election simulation API.
+func NewPublicElectionAPI(man *Matrix) *PublicElectionAPI {
+	return &PublicElectionAPI{man: man}
+}
+
+// SimulatedNode is a node the simulated election would elect. The stock is
+// the weight of the node in its role.
+type SimulatedNode struct {
+	Account  string         `json:"account"`
+	Position hexutil.Uint64 `json:"position"`
+	Stock    hexutil.Uint64 `json:"stock"`
+	VIPLevel hexutil.Uint64 `json:"vipLevel"`
+	Role     string         `json:"role"`
+}
+
+// SimulateElectionResult is the result of a simulated election.
+type SimulateElectionResult struct {
+	Number              hexutil.Uint64  `json:"number"`      // block of the deposits and the seed
+	ElectNumber         hexutil.Uint64  `json:"electNumber"` // next re-election block
+	Seed                *hexutil.Big    `json:"seed"`
+	Miners              []SimulatedNode `json:"miners"`
+	Validators          []SimulatedNode `json:"validators"`
+	BackupValidators    []SimulatedNode `json:"backupValidators"`
+	CandidateValidators []SimulatedNode `json:"candidateValidators"`
+}
+
+func newSimulatedNodes(nodes []mc.ElectNodeInfo) []SimulatedNode {
+	result := make([]SimulatedNode, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, SimulatedNode{
+			Account:  base58.Base58EncodeToString(params.MAN_COIN, node.Account),
+			Position: hexutil.Uint64(node.Position),
+			Stock:    hexutil.Uint64(node.Stock),
+			VIPLevel: hexutil.Uint64(node.VIPLevel),
+			Role:     node.Type.String(),
+		})
+	}
+	return result
+}
+
+// SimulateElection runs the election algorithm against the deposits at the
+// given block and returns the validators and miners it would elect. The seed
+// of the block is used unless a seed is given. Nothing is written to the
+// chain.
+func (api *PublicElectionAPI) SimulateElection(blockNr rpc.BlockNumber, seed *hexutil.Big) (*SimulateElectionResult, error) {
+	header := api.man.BlockChain().CurrentHeader()
+	if blockNr >= 0 && uint64(blockNr) < header.Number.Uint64() {
+		header = api.man.BlockChain().GetHeaderByNumber(uint64(blockNr))
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	var randSeed *big.Int
+	if seed != nil {
+		randSeed = seed.ToInt()
+	}
+	result, err := api.man.ReElection().SimulateElection(header.Hash(), randSeed)
+	if err != nil {
+		return nil, err
+	}
+	return &SimulateElectionResult{
+		Number:              hexutil.Uint64(header.Number.Uint64()),
+		ElectNumber:         hexutil.Uint64(result.ElectNumber),
+		Seed:                (*hexutil.Big)(result.Seed),
+		Miners:              newSimulatedNodes(result.MasterMiner),
+		Validators:          newSimulatedNodes(result.MasterValidator),
+		BackupValidators:    newSimulatedNodes(result.BackUpValidator),
+		CandidateValidators: newSimulatedNodes(result.CandidateValidator),
+	}, nil
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+// PublicEvidenceAPI provides access to the double sign evidence collected by
+// the node.
+type PublicEvidenceAPI struct {
+	man *Matrix
+}
+
+// NewPublicEvidenceAPI creates a new double sign evidence API.
+func NewPublicEvidenceAPI(man *Matrix) *PublicEvidenceAPI {
+	return &PublicEvidenceAPI{man: man}
+}
+
+// EvidenceResult is a pending double sign evidence. The payload is the data of
+// the evidence transaction including it into a block.
+type EvidenceResult struct {
+	Hash    common.Hash    `json:"hash"`
+	Number  hexutil.Uint64 `json:"number"`
+	Leader  common.Address `json:"leader"`
+	Signer  common.Address `json:"signer"`
+	Payload hexutil.Bytes  `json:"payload"`
+}
+
+// PendingEvidence returns the double sign evidence which can still be included
+// into the next block. Evidence which became too old or was already punished
+// is dropped from the pool.
+func (api *PublicEvidenceAPI) PendingEvidence() ([]*EvidenceResult, error) {
+	bc := api.man.BlockChain()
+	st, err := bc.State()
+	if err != nil {
+		return nil, err
+	}
+	number := bc.CurrentBlock().NumberU64() + 1
+
+	pool := bc.EvidencePool()
+	results := make([]*EvidenceResult, 0)
+	for _, ev := range pool.Pending() {
+		if err := core.CheckDoubleSignEvidence(st, ev, number); err != nil {
+			if err == core.ErrEvidenceTooOld || err == core.ErrEvidenceDuplicate {
+				pool.Remove(ev.Hash())
+			}
+			continue
+		}
+		signer, err := ev.Verify()
+		if err != nil {
+			continue
+		}
+		payload, err := types.EncodeDoubleSignEvidence(ev)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &EvidenceResult{
+			Hash:    ev.Hash(),
+			Number:  hexutil.Uint64(ev.Number()),
+			Leader:  ev.HeaderA.Leader,
+			Signer:  signer,
+			Payload: payload,
+		})
+	}
+	return results, nil
+}
+
+// SubmitEvidence adds a double sign evidence received out of band to the
+// evidence pool.
+func (api *PublicEvidenceAPI) SubmitEvidence(payload hexutil.Bytes) (common.Hash, error) {
+	ev, err := types.DecodeDoubleSignEvidence(payload)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := api.man.BlockChain().EvidencePool().AddEvidence(ev); err != nil {
+		return common.Hash{}, err
+	}
+	return ev.Hash(), nil
+}
@@ -0,0 +1,22 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+// PublicForkAPI provides access to the competing branches and the reorgs seen
+// by the node.
+type PublicForkAPI struct {
+	man *Matrix
+}
+
+// NewPublicForkAPI creates a new fork monitoring API.
+func NewPublicForkAPI(man *Matrix) *PublicForkAPI {
+	return &PublicForkAPI{man: man}
+}
+
+// ForkStatus returns the competing branches currently tracked and the depth and
+// frequency of the recent reorgs of the canonical chain.
+func (api *PublicForkAPI) ForkStatus() *ForkStatus {
+	return api.man.forkMonitor.status()
+}
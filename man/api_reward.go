@@ -0,0 +1,106 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// PublicRewardAPI provides access to the rewards paid by the recent blocks.
+type PublicRewardAPI struct {
+	man *Matrix
+}
+
+// NewPublicRewardAPI creates a new reward audit API.
+func NewPublicRewardAPI(man *Matrix) *PublicRewardAPI {
+	return &PublicRewardAPI{man: man}
+}
+
+// RewardBreakdownResult is the rewards paid to an account by a block.
+type RewardBreakdownResult struct {
+	Mining     *hexutil.Big            `json:"mining"`
+	Validation *hexutil.Big            `json:"validation"`
+	Interest   *hexutil.Big            `json:"interest"`
+	Lottery    *hexutil.Big            `json:"lottery"`
+	TxFees     map[string]*hexutil.Big `json:"txFees"` // Transaction fee rewards by coin
+}
+
+// GetRewardBreakdown returns the rewards paid by a block, by account. Only the
+// blocks executed by the node are known, synced state has no reward logs.
+func (api *PublicRewardAPI) GetRewardBreakdown(blockNr rpc.BlockNumber) (map[string]*RewardBreakdownResult, error) {
+	bc := api.man.BlockChain()
+	var block *types.Block
+	switch blockNr {
+	case rpc.PendingBlockNumber:
+		return nil, errors.New("unsupport argument 1: pending")
+	case rpc.LatestBlockNumber:
+		block = bc.CurrentBlock()
+	default:
+		block = bc.GetBlockByNumber(uint64(blockNr))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	record := bc.GetRewardRecord(block.Hash(), block.NumberU64())
+	if record == nil {
+		return nil, fmt.Errorf("reward breakdown of block #%d not found", block.NumberU64())
+	}
+
+	results := make(map[string]*RewardBreakdownResult, len(record.Rewards))
+	for addr, breakdown := range record.Rewards {
+		result := &RewardBreakdownResult{
+			Mining:     (*hexutil.Big)(breakdown.Mining),
+			Validation: (*hexutil.Big)(breakdown.Validation),
+			Interest:   (*hexutil.Big)(breakdown.Interest),
+			Lottery:    (*hexutil.Big)(breakdown.Lottery),
+			TxFees:     make(map[string]*hexutil.Big, len(breakdown.TxFees)),
+		}
+		for coin, fee := range breakdown.TxFees {
+			result.TxFees[coin] = (*hexutil.Big)(fee)
+		}
+		results[base58.Base58EncodeToString(params.MAN_COIN, addr)] = result
+	}
+	return results, nil
+}
+
+// RewardScheduleResult is the reward schedule in use, along with the schedule
+// voted by the validators which takes effect at the next broadcast block.
+type RewardScheduleResult struct {
+	Active         *mc.RewardSchedule `json:"active"`
+	Pending        *mc.RewardSchedule `json:"pending"`
+	ProposalNumber hexutil.Uint64     `json:"proposalNumber"` // Height of the last accepted proposal
+}
+
+// GetRewardSchedule returns the reward schedule at the given block.
+func (api *PublicRewardAPI) GetRewardSchedule(ctx context.Context, blockNr rpc.BlockNumber) (*RewardScheduleResult, error) {
+	st, _, err := api.man.APIBackend.StateAndHeaderByNumber(ctx, blockNr)
+	if st == nil || err != nil {
+		return nil, err
+	}
+	active, err := core.GetRewardSchedule(st)
+	if err != nil {
+		return nil, err
+	}
+	status, err := matrixstate.GetRewardGovernance(st)
+	if err != nil {
+		return nil, err
+	}
+	result := &RewardScheduleResult{Active: active, ProposalNumber: hexutil.Uint64(status.ProposalNumber)}
+	if status.Pending {
+		result.Pending = &status.Schedule
+	}
+	return result, nil
+}
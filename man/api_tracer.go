@@ -44,7 +44,7 @@ const (
 // TraceConfig holds extra parameters to trace functions.
 type TraceConfig struct {
 	*vm.LogConfig
-	Tracer  *string
+	Tracer  *string // callTracer, prestateTracer or a JavaScript tracer expression
 	Timeout *string
 	Reexec  *uint64
 }
@@ -389,6 +389,19 @@ func (api *PrivateDebugAPI) TraceBlockFromFile(ctx context.Context, file string,
 // executes all the transactions contained within. The return value will be one item
 // per transaction, dependent on the requestd tracer.
 func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
+	result, err := api.traceBlockState(ctx, block, config, false)
+	if err != nil {
+		return nil, err
+	}
+	return result.Txs, nil
+}
+
+// traceBlockState traces all the transactions of a block in the order the state
+// processor executes them. Broadcast transactions do not run in the EVM, their
+// decoded payload is returned instead. If withMatrixState is set, the matrix
+// state producers are run on the resulting state and the broadcast transaction
+// delta is returned as well.
+func (api *PrivateDebugAPI) traceBlockState(ctx context.Context, block *types.Block, config *TraceConfig, withMatrixState bool) (*blockStateTraceResult, error) {
 	// Create the parent state database
 	if item, ok := api.man.engine[string(block.Version())]; ok {
 		if err := item.VerifyHeader(api.man.blockchain, block.Header(), true, false); err != nil {
@@ -413,13 +426,18 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 	if err != nil {
 		return nil, err
 	}
-	// Execute all the transaction contained within the block concurrently
-	var txs []types.SelfTransaction
-	for _, currencie := range block.Currencies() {
-		for _, tx := range currencie.Transactions.Transactions {
-			txs = append(txs, tx)
-		}
+	var prestate *state.StateDBManage
+	if withMatrixState {
+		prestate = statedb.Copy()
 	}
+	// Prepare the state the same way the state processor does before the transactions
+	statedb.UpdateTxForBtree(uint32(block.Time().Uint64()))
+	statedb.UpdateTxForBtreeBytime(uint32(block.Time().Uint64()))
+	if err := core.ReleaseRevocableTxs(statedb, block.NumberU64()); err != nil {
+		return nil, err
+	}
+	// Execute all the transaction contained within the block concurrently
+	txs := core.ExecutionOrderTxs(block)
 	var (
 		//signer = types.MakeSigner(api.config, block.Number())
 
@@ -453,17 +471,23 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 		}()
 	}
 	// Feed the transactions into the tracers and return
-	var failed error
+	var (
+		failed  error
+		gp      = new(core.GasPool).AddGas(block.GasLimit())
+		usedGas = new(uint64)
+	)
 	for i, tx := range txs {
-		// Send the trace task over for execution
-		jobs <- &txTraceTask{statedb: statedb.Copy(), index: i}
-
-		// Generate the next state snapshot fast without tracing
-		//msg, _ := tx.AsMessage(signer)
-		vmctx := core.NewEVMContext(tx.From(), tx.GasPrice(), block.Header(), api.man.blockchain, nil)
-
-		vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{}, tx.GetTxCurrency())
-		if _, _, _, _, err := core.ApplyMessage(vmenv, tx, new(core.GasPool).AddGas(tx.Gas())); err != nil {
+		if isBroadcastTx(tx) {
+			// Broadcast transactions don't run in the EVM, only carry data for the matrix state
+			results[i] = &txTraceResult{Result: newBroadcastTxTrace(tx)}
+		} else {
+			// Send the trace task over for execution
+			jobs <- &txTraceTask{statedb: statedb.Copy(), index: i}
+		}
+		// Generate the next state snapshot fast without tracing, applying the
+		// transaction exactly as the state processor does
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		if _, _, _, err := core.ApplyTransaction(api.config, api.man.blockchain, nil, gp, statedb, block.Header(), tx, usedGas, vm.Config{}); err != nil {
 			failed = err
 			break
 		}
@@ -477,7 +501,13 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 	if failed != nil {
 		return nil, failed
 	}
-	return results, nil
+	result := &blockStateTraceResult{Txs: results}
+	if withMatrixState {
+		if result.MatrixState, err = api.traceBroadcastTxState(block, parent, prestate, statedb); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
 // computeStateDB retrieves the state database associated with a certain block.
@@ -578,6 +608,11 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message txinterface.Mes
 	)
 	switch {
 	case config != nil && config.Tracer != nil:
+		// Built-in tracers selected by name read the original state of touched accounts
+		if native, ok := vm.LookupNativeTracer(*config.Tracer, message.GetTxCurrency(), statedb.Copy()); ok {
+			tracer = native
+			break
+		}
 		// Define a meaningful timeout of a single transaction trace
 		timeout := defaultTraceTimeout
 		if config.Timeout != nil {
@@ -623,6 +658,9 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message txinterface.Mes
 	case *tracers.Tracer:
 		return tracer.GetResult()
 
+	case vm.NativeTracer:
+		return tracer.GetResult()
+
 	default:
 		panic(fmt.Sprintf("bad tracer type %T", tracer))
 	}
@@ -0,0 +1,138 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// blockStateTraceResult is the result of tracing a whole block, optionally
+// together with the matrix state delta the block produced.
+type blockStateTraceResult struct {
+	Txs         []*txTraceResult  `json:"txs"`
+	MatrixState *matrixStateDelta `json:"matrixState,omitempty"`
+}
+
+// matrixStateDelta describes how a block changed a matrix state key.
+type matrixStateDelta struct {
+	Key     string                `json:"key"`
+	Before  common.BroadTxSlice   `json:"before"`
+	After   common.BroadTxSlice   `json:"after"`
+	Changed []common.BroadTxValue `json:"changed"` // entries added or modified by the block
+	Removed []common.BroadTxkey   `json:"removed"` // entries deleted by the block
+}
+
+// broadcastTxTrace is the trace result of a broadcast transaction, which is not
+// executed in the EVM but only feeds the MSKeyBroadcastTx matrix state.
+type broadcastTxTrace struct {
+	Type    string                   `json:"type"`
+	From    common.Address           `json:"from"`
+	Payload map[string]hexutil.Bytes `json:"payload,omitempty"`
+	Error   string                   `json:"error,omitempty"`
+}
+
+// TraceBlockMatrixState traces all the transactions of a block, including the
+// matrix special transactions, and returns the MSKeyBroadcastTx matrix state
+// delta produced by the block.
+func (api *PrivateDebugAPI) TraceBlockMatrixState(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) (*blockStateTraceResult, error) {
+	var block *types.Block
+	switch number {
+	case rpc.PendingBlockNumber:
+		block = api.man.miner.PendingBlock()
+	case rpc.LatestBlockNumber:
+		block = api.man.blockchain.CurrentBlock()
+	default:
+		block = api.man.blockchain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return api.traceBlockState(ctx, block, config, true)
+}
+
+// traceBroadcastTxState runs the matrix state producers of the block on the
+// traced state and diffs the broadcast transaction state against the parent.
+func (api *PrivateDebugAPI) traceBroadcastTxState(block *types.Block, parent *types.Block, prestate *state.StateDBManage, statedb *state.StateDBManage) (*matrixStateDelta, error) {
+	before, err := matrixstate.GetBroadcastTxs(prestate)
+	if err != nil {
+		return nil, fmt.Errorf("read parent broadcast txs failed: %v", err)
+	}
+	if err := api.man.blockchain.ProcessMatrixState(block, string(parent.Version()), statedb); err != nil {
+		return nil, err
+	}
+	after, err := matrixstate.GetBroadcastTxs(statedb)
+	if err != nil {
+		return nil, fmt.Errorf("read broadcast txs failed: %v", err)
+	}
+	delta := diffBroadcastTxs(before, after)
+	delta.Key = mc.MSKeyBroadcastTx
+	return delta, nil
+}
+
+// diffBroadcastTxs compares two broadcast transaction states entry by entry.
+func diffBroadcastTxs(before, after common.BroadTxSlice) *matrixStateDelta {
+	delta := &matrixStateDelta{
+		Before:  before,
+		After:   after,
+		Changed: make([]common.BroadTxValue, 0),
+		Removed: make([]common.BroadTxkey, 0),
+	}
+	old := make(map[common.BroadTxkey][]byte, len(before))
+	for _, item := range before {
+		old[item.Key] = item.Value
+	}
+	for _, item := range after {
+		if value, ok := old[item.Key]; !ok || !bytes.Equal(value, item.Value) {
+			delta.Changed = append(delta.Changed, item)
+		}
+		delete(old, item.Key)
+	}
+	for key := range old {
+		delta.Removed = append(delta.Removed, key)
+	}
+	sort.Slice(delta.Removed, func(i, j int) bool {
+		if delta.Removed[i].Key != delta.Removed[j].Key {
+			return delta.Removed[i].Key < delta.Removed[j].Key
+		}
+		return bytes.Compare(delta.Removed[i].Address[:], delta.Removed[j].Address[:]) < 0
+	})
+	return delta
+}
+
+// isBroadcastTx reports whether the state processor skips the EVM for tx.
+func isBroadcastTx(tx types.SelfTransaction) bool {
+	return tx.TxType() == types.BroadCastTxIndex
+}
+
+func newBroadcastTxTrace(tx types.SelfTransaction) *broadcastTxTrace {
+	trace := &broadcastTxTrace{Type: "broadcast"}
+	from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+	if err != nil {
+		trace.Error = err.Error()
+		return trace
+	}
+	trace.From = from
+	payload, err := types.DecodeBroadcastPayload(tx.Data())
+	if err != nil {
+		trace.Error = err.Error()
+		return trace
+	}
+	trace.Payload = make(map[string]hexutil.Bytes, len(payload))
+	for key, value := range payload {
+		trace.Payload[key] = value
+	}
+	return trace
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func TestDiffBroadcastTxs(t *testing.T) {
+	var (
+		addrA = common.HexToAddress("0x01")
+		addrB = common.HexToAddress("0x02")
+		addrC = common.HexToAddress("0x03")
+
+		before common.BroadTxSlice
+		after  common.BroadTxSlice
+	)
+	before.Insert(mc.Heartbeat, addrA, []byte{1})
+	before.Insert(mc.Heartbeat, addrB, []byte{2})
+	before.Insert(mc.Heartbeat, addrC, []byte{3})
+
+	after.Insert(mc.Heartbeat, addrA, []byte{1})
+	after.Insert(mc.Heartbeat, addrB, []byte{4})
+
+	delta := diffBroadcastTxs(before, after)
+	if len(delta.Changed) != 1 || delta.Changed[0].Key.Address != addrB {
+		t.Errorf("changed entries mismatch: %v", delta.Changed)
+	}
+	if len(delta.Removed) != 1 || delta.Removed[0].Address != addrC {
+		t.Errorf("removed entries mismatch: %v", delta.Removed)
+	}
+}
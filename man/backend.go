@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/MatrixAINetwork/go-matrix/accounts"
+	"github.com/MatrixAINetwork/go-matrix/accounts/external"
 	"github.com/MatrixAINetwork/go-matrix/accounts/signhelper"
 	"github.com/MatrixAINetwork/go-matrix/baseinterface"
 	"github.com/MatrixAINetwork/go-matrix/blkgenor"
@@ -36,6 +37,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/crypto/bls"
 	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
 	"github.com/MatrixAINetwork/go-matrix/event"
 	"github.com/MatrixAINetwork/go-matrix/internal/manapi"
@@ -96,6 +98,14 @@ type Matrix struct {
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
 
+	broadcastIndexer *broadcastIndexer // Index of the broadcast transactions, nil if disabled
+	addressIndexer   *addressIndexer   // Index of the transactions by address, nil if disabled
+	rollCallChecker  *rollCallChecker  // Consistency checker of the roll calls committed by broadcast blocks
+	forkMonitor      *forkMonitor      // Tracker of the competing branches and reorgs
+	checkpointVoter  *checkpointVoter  // Voter and collector of the checkpoint votes
+	bridgeWatcher    *bridgeWatcher    // Signer and collector of the bridge event attestations
+	evidenceRelay    *evidenceRelay    // Relayer of the double sign evidence between validators
+
 	APIBackend *ManAPIBackend
 
 	miner    *miner.Miner
@@ -122,6 +132,7 @@ type Matrix struct {
 	blockVerify    *blkverify.BlockVerify
 	leaderServer   *leaderelect.LeaderIdentity
 	leaderServerV2 *leaderelect2.LeaderIdentity
+	devSealer      *devSealer // Block sealer of the developer chain, nil if not in developer mode
 	lessDiskSvr    *lessdisk.Server
 
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and manbase)
@@ -145,6 +156,16 @@ func New(ctx *pod.ServiceContext, config *Config) (*Matrix, error) {
 	if err != nil {
 		return nil, err
 	}
+	// All the services read the frozen chain segments through the ancient database
+	ancientDir := config.DatabaseAncient
+	if ancientDir == "" {
+		ancientDir = ctx.ResolvePath("chaindata/ancient")
+	}
+	if config.AncientThreshold > 0 {
+		if chainDb, err = core.OpenAncientDatabase(chainDb, ancientDir); err != nil {
+			return nil, err
+		}
+	}
 
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb, config.Genesis)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
@@ -180,10 +201,17 @@ func New(ctx *pod.ServiceContext, config *Config) (*Matrix, error) {
 		}
 		rawdb.WriteDatabaseVersion(chainDb, core.BlockChainVersion)
 	}
+	if err := core.CheckSchemaVersion(chainDb); err != nil {
+		return nil, err
+	}
 	var (
-		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording, ParallelExec: config.ParallelExec}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieCleanLimit: config.TrieCleanCache, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, StateRetention: config.StateRetention}
 	)
+	if config.AncientThreshold > 0 {
+		cacheConfig.AncientThreshold = config.AncientThreshold
+		cacheConfig.AncientDir = ancientDir
+	}
 	man.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, man.chainConfig, vmConfig, man.engine, man.dposEngine)
 	if err != nil {
 		return nil, err
@@ -197,14 +225,53 @@ func New(ctx *pod.ServiceContext, config *Config) (*Matrix, error) {
 	}
 	man.bloomIndexer.Start(man.blockchain)
 
+	if config.BroadcastIndex {
+		indexDb, err := CreateDB(ctx, config, "broadcastindex")
+		if err != nil {
+			return nil, err
+		}
+		man.broadcastIndexer = newBroadcastIndexer(indexDb, man.blockchain)
+	}
+	if config.AddressIndex {
+		indexDb, err := CreateDB(ctx, config, "addressindex")
+		if err != nil {
+			return nil, err
+		}
+		if db, ok := indexDb.(addressIndexDB); ok {
+			man.addressIndexer = newAddressIndexer(db, man.blockchain)
+		} else {
+			log.Warn("Address index disabled, the database does not support iteration")
+			indexDb.Close()
+		}
+	}
+
 	man.signHelper.SetAuthReader(man.blockchain)
+	if config.ExternalSigner != "" {
+		signer, err := external.NewSigner(config.ExternalSigner)
+		if err != nil {
+			return nil, err
+		}
+		man.signHelper.SetExternalSigner(signer)
+	}
+	if config.BLSKeyFile != "" {
+		key, err := bls.LoadKey(config.BLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BLS key file: %v", err)
+		}
+		man.signHelper.SetBLSKey(key)
+	}
 
 	ca.SetTopologyReader(man.blockchain.GetTopologyStore())
 
-	//if config.TxPool.Journal != "" {
-	//	config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
-	//}
+	if config.TxPool.Journal != "" {
+		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
+	}
 	man.txPool = core.NewTxPoolManager(config.TxPool, man.chainConfig, man.blockchain, ctx.GetConfig().DataDir)
+	man.rollCallChecker = newRollCallChecker(man.blockchain, man.txPool)
+	man.forkMonitor = newForkMonitor(man.blockchain, config.ForkAlertDepth, config.ForkAlertWebhook)
+	man.checkpointVoter = newCheckpointVoter(man.blockchain, man.hd, man.signHelper)
+	man.bridgeWatcher = newBridgeWatcher(man.blockchain, man.hd, man.signHelper, config.BridgeContracts, config.BridgeEvents, config.BridgeConfirmations)
+	man.evidenceRelay = newEvidenceRelay(man.blockchain, man.hd)
 
 	if man.protocolManager, err = NewProtocolManager(man.chainConfig, config.SyncMode, config.NetworkId, man.eventMux, man.txPool, man.engine, man.blockchain, chainDb, ctx.MsgCenter); err != nil {
 		return nil, err
@@ -258,29 +325,37 @@ func New(ctx *pod.ServiceContext, config *Config) (*Matrix, error) {
 	depoistInfo.NewDepositInfo(man.APIBackend)
 	man.broadTx = broadcastTx.NewBroadCast(man.APIBackend) //
 
-	man.leaderServer, err = leaderelect.NewLeaderIdentityService(man, "leader服务")
-	if err != nil {
-		return nil, err
-	}
-	man.leaderServerV2, err = leaderelect2.NewLeaderIdentityService(man, "leader服务V2")
-	if err != nil {
-		return nil, err
-	}
 	man.manBlkManage, err = blkmanage.New(man)
 	if err != nil {
 		return nil, err
 	}
-	man.blockGen, err = blkgenor.New(man)
-	if err != nil {
-		return nil, err
-	}
-	man.blockGenV2, err = blkgenorV2.New(man)
-	if err != nil {
-		return nil, err
+	for _, hook := range config.BlockBuilderHooks {
+		man.manBlkManage.RegisterBuilderHook(hook)
 	}
-	man.blockVerify, err = blkverify.NewBlockVerify(man)
-	if err != nil {
-		return nil, err
+	if config.DevMode {
+		// The single node of a developer chain seals its blocks by itself
+		man.devSealer = newDevSealer(man, config.DevPeriod)
+	} else {
+		man.leaderServer, err = leaderelect.NewLeaderIdentityService(man, "leader服务")
+		if err != nil {
+			return nil, err
+		}
+		man.leaderServerV2, err = leaderelect2.NewLeaderIdentityService(man, "leader服务V2")
+		if err != nil {
+			return nil, err
+		}
+		man.blockGen, err = blkgenor.New(man)
+		if err != nil {
+			return nil, err
+		}
+		man.blockGenV2, err = blkgenorV2.New(man)
+		if err != nil {
+			return nil, err
+		}
+		man.blockVerify, err = blkverify.NewBlockVerify(man)
+		if err != nil {
+			return nil, err
+		}
 	}
 	man.lessDiskSvr = lessdisk.NewLessDiskSvr(params.DefLessDiskConfig, chainDb, man.blockchain)
 	man.lessDiskSvr.FuncSwitch(ctx.GetConfig().LessDisk)
@@ -307,7 +382,21 @@ func makeExtraData(extra []byte) []byte {
 
 // CreateDB creates the chain database.
 func CreateDB(ctx *pod.ServiceContext, config *Config, name string) (mandb.Database, error) {
-	db, err := ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles, config.DatabaseTableSize)
+	var (
+		db  mandb.Database
+		err error
+	)
+	// Ephemeral nodes always use the memory database
+	if path := ctx.ResolvePath(name); path != "" {
+		engine, err := mandb.ResolveEngine(config.DatabaseEngine, path)
+		if err != nil {
+			return nil, err
+		}
+		if engine != mandb.EngineLevelDB {
+			return mandb.Open(engine, path, config.DatabaseCache, config.DatabaseHandles, config.DatabaseTableSize)
+		}
+	}
+	db, err = ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles, config.DatabaseTableSize)
 	if err != nil {
 		return nil, err
 	}
@@ -429,6 +518,41 @@ func (s *Matrix) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   filters.NewPublicFilterAPI(s.APIBackend, false),
 			Public:    true,
+		}, {
+			Namespace: "matrix",
+			Version:   "1.0",
+			Service:   NewPublicBroadcastAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "matrix",
+			Version:   "1.0",
+			Service:   NewPublicEvidenceAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "matrix",
+			Version:   "1.0",
+			Service:   NewPublicCheckpointAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "matrix",
+			Version:   "1.0",
+			Service:   NewPublicBridgeAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "matrix",
+			Version:   "1.0",
+			Service:   NewPublicRewardAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "matrix",
+			Version:   "1.0",
+			Service:   NewPublicForkAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "matrix",
+			Version:   "1.0",
+			Service:   NewPublicElectionAPI(s),
+			Public:    true,
 		}, {
 			Namespace: "admin",
 			Version:   "1.0",
@@ -534,6 +658,26 @@ func (s *Matrix) Start(srvr *p2p.Server) error {
 	srvr.NetWorkId = s.config.NetworkId
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers()
+	if s.broadcastIndexer != nil {
+		s.broadcastIndexer.Start()
+	}
+	if s.addressIndexer != nil {
+		s.addressIndexer.Start()
+	}
+	s.rollCallChecker.Start()
+	s.forkMonitor.Start(s.blockchain)
+	if err := s.checkpointVoter.Start(); err != nil {
+		return err
+	}
+	if err := s.bridgeWatcher.Start(); err != nil {
+		return err
+	}
+	if err := s.evidenceRelay.Start(); err != nil {
+		return err
+	}
+	if s.devSealer != nil {
+		s.devSealer.Start()
+	}
 
 	// Start the RPC service
 	s.netRPCService = manapi.NewPublicNetAPI(srvr, s.NetVersion())
@@ -609,10 +753,25 @@ func (s *Matrix) FetcherNotify(hash common.Hash, number uint64, addr common.Addr
 // Stop implements node.Service, terminating all internal goroutines used by the
 // Matrix protocol.
 func (s *Matrix) Stop() error {
-	s.blockGen.Close()
-	s.blockVerify.Close()
+	if s.devSealer != nil {
+		s.devSealer.Stop()
+	} else {
+		s.blockGen.Close()
+		s.blockVerify.Close()
+	}
 	s.olConsensus.Close()
 	s.bloomIndexer.Close()
+	if s.broadcastIndexer != nil {
+		s.broadcastIndexer.Stop()
+	}
+	if s.addressIndexer != nil {
+		s.addressIndexer.Stop()
+	}
+	s.rollCallChecker.Stop()
+	s.forkMonitor.Stop()
+	s.checkpointVoter.Stop()
+	s.bridgeWatcher.Stop()
+	s.evidenceRelay.Stop()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
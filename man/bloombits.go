@@ -110,9 +110,16 @@ func (b *BloomIndexer) Reset(section uint64, lastSectionHead common.Hash) error
 }
 
 // Process implements core.ChainIndexerBackend, adding a new header's bloom into
-// the index.
+// the index. The reward and slash logs of the block are not part of the header
+// blooms, their bloom is merged in as an extra entry so range queries find them.
 func (b *BloomIndexer) Process(header *types.Header) {
-	b.gen.AddBloom(uint(header.Number.Uint64()-b.section*b.size), header.Roots)
+	roots := header.Roots
+	if logs := core.ReadSystemLogs(b.db, header.Hash(), header.Number.Uint64()); len(logs) > 0 {
+		roots = make([]common.CoinRoot, len(header.Roots), len(header.Roots)+1)
+		copy(roots, header.Roots)
+		roots = append(roots, common.CoinRoot{Bloom: core.SystemLogsBloom(logs)})
+	}
+	b.gen.AddBloom(uint(header.Number.Uint64()-b.section*b.size), roots)
 	b.head = header.Hash()
 }
 
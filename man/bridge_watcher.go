@@ -0,0 +1,193 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts/signhelper"
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/msgsend"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// bridgeAttestChanSize is the size of channel listening to the bridge event signs.
+const bridgeAttestChanSize = 64
+
+// defaultBridgeEvents are the event signatures watched when none is configured.
+var defaultBridgeEvents = []string{
+	"Lock(address,uint256,uint256,bytes)",
+	"Burn(address,uint256,uint256,bytes)",
+}
+
+// bridgeWatcher watches the lock and burn events of the bridge contracts once
+// they are buried under enough confirmations. The elected validators sign the
+// events and collect the signs of the other validators into the bridge pool.
+// Once a supermajority signed, anyone can record the attestation on chain by a
+// bridge attestation transaction, from where the relayers fetch it.
+type bridgeWatcher struct {
+	chain         *core.BlockChain
+	hd            *msgsend.HD
+	signHelper    *signhelper.SignHelper
+	contracts     map[common.Address]bool
+	topics        map[common.Hash]bool
+	confirmations uint64
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newBridgeWatcher(chain *core.BlockChain, hd *msgsend.HD, signHelper *signhelper.SignHelper, contracts []common.Address, events []string, confirmations uint64) *bridgeWatcher {
+	if len(events) == 0 {
+		events = defaultBridgeEvents
+	}
+	w := &bridgeWatcher{
+		chain:         chain,
+		hd:            hd,
+		signHelper:    signHelper,
+		contracts:     make(map[common.Address]bool),
+		topics:        make(map[common.Hash]bool),
+		confirmations: confirmations,
+		quit:          make(chan struct{}),
+	}
+	for _, contract := range contracts {
+		w.contracts[contract] = true
+	}
+	for _, event := range events {
+		w.topics[crypto.Keccak256Hash([]byte(event))] = true
+	}
+	return w
+}
+
+// Start begins watching the bridge events and collecting the received signs.
+// The watcher stays idle if no bridge contract is configured.
+func (w *bridgeWatcher) Start() error {
+	if len(w.contracts) == 0 {
+		return nil
+	}
+	signs := make(chan *mc.HD_BridgeAttestMsg, bridgeAttestChanSize)
+	signSub, err := mc.SubscribeEvent(mc.HD_BridgeAttest, signs)
+	if err != nil {
+		return err
+	}
+	events := make(chan core.ChainEvent, chainEventChanSize)
+	chainSub := w.chain.SubscribeChainEvent(events)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer signSub.Unsubscribe()
+		defer chainSub.Unsubscribe()
+
+		for {
+			select {
+			case msg := <-signs:
+				w.addSign(msg)
+			case ev := <-events:
+				w.chain.BridgePool().Prune(ev.Block.NumberU64())
+				w.watch(ev.Block.NumberU64())
+			case <-chainSub.Err():
+				return
+			case <-w.quit:
+				return
+			}
+		}
+	}()
+	log.Info("Bridge watcher started", "contracts", len(w.contracts), "events", len(w.topics), "confirmations", w.confirmations)
+	return nil
+}
+
+// Stop terminates the watcher.
+func (w *bridgeWatcher) Stop() {
+	close(w.quit)
+	w.wg.Wait()
+}
+
+// addSign collects a sign received from another validator.
+func (w *bridgeWatcher) addSign(msg *mc.HD_BridgeAttestMsg) {
+	ev := new(core.BridgeEvent)
+	if err := rlp.DecodeBytes(msg.Event, ev); err != nil {
+		log.Debug("Invalid bridge event", "from", msg.From, "err", err)
+		return
+	}
+	if !w.matches(ev.Contract, ev.Topics) {
+		return
+	}
+	if _, err := w.addPoolSign(ev, msg.Sign); err != nil {
+		log.Debug("Invalid bridge event sign", "from", msg.From, "event", ev.Hash(), "err", err)
+	}
+}
+
+// addPoolSign adds a sign to the bridge pool, checked against the validators of
+// the current head.
+func (w *bridgeWatcher) addPoolSign(ev *core.BridgeEvent, sign common.Signature) (common.Address, error) {
+	head := w.chain.CurrentBlock()
+	st, err := w.chain.StateAt(head.Root())
+	if err != nil {
+		return common.Address{}, err
+	}
+	return w.chain.BridgePool().AddSign(st, head.NumberU64(), ev, sign)
+}
+
+// watch signs the bridge events of the canonical block reaching the required
+// confirmations with the head, if the node is an elected validator.
+func (w *bridgeWatcher) watch(head uint64) {
+	if ca.GetRole() != common.RoleValidator || head < w.confirmations {
+		return
+	}
+	block := w.chain.GetBlockByNumber(head - w.confirmations)
+	if block == nil {
+		return
+	}
+	for _, ev := range bridgeEvents(block, w.chain.GetReceiptsByHash(block.Hash()), w.matches) {
+		w.sign(ev)
+	}
+}
+
+// sign signs the bridge event and sends the sign to the other validators.
+func (w *bridgeWatcher) sign(ev *core.BridgeEvent) {
+	id := ev.Hash()
+	sign, err := w.signHelper.SignHashWithValidate(id.Bytes(), true, ev.BlockHash)
+	if err != nil {
+		log.Error("Failed to sign bridge event", "event", id, "number", ev.BlockNumber, "err", err)
+		return
+	}
+	if _, err := w.addPoolSign(ev, sign); err != nil {
+		log.Error("Failed to add bridge event sign", "event", id, "err", err)
+		return
+	}
+	data, err := rlp.EncodeToBytes(ev)
+	if err != nil {
+		return
+	}
+	w.hd.SendNodeMsg(mc.HD_BridgeAttest, &mc.HD_BridgeAttestMsg{Event: data, Sign: sign}, common.RoleValidator, nil)
+	log.Debug("Signed bridge event", "event", id, "number", ev.BlockNumber, "tx", ev.TxHash)
+}
+
+// matches reports whether the log is a watched event of a bridge contract.
+func (w *bridgeWatcher) matches(contract common.Address, topics []common.Hash) bool {
+	return w.contracts[contract] && len(topics) > 0 && w.topics[topics[0]]
+}
+
+// bridgeEvents returns the logs of the block which are watched bridge events.
+func bridgeEvents(block *types.Block, receipts []types.CoinReceipts, match func(common.Address, []common.Hash) bool) []*core.BridgeEvent {
+	var events []*core.BridgeEvent
+	for _, coin := range receipts {
+		for _, receipt := range coin.Receiptlist {
+			for _, l := range receipt.Logs {
+				if match(l.Address, l.Topics) {
+					events = append(events, core.NewBridgeEvent(block, l))
+				}
+			}
+		}
+	}
+	return events
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// maxBroadcastHistoryRange is the maximum number of intervals a single history
+// query may span.
+const maxBroadcastHistoryRange = 1024
+
+var (
+	// errBroadcastIndexDisabled is returned by the history queries if the node
+	// runs without the broadcast indexer.
+	errBroadcastIndexDisabled = errors.New("broadcast indexer disabled, enable it with --broadcastindex")
+
+	broadcastIndexHeadKey      = []byte("bcIndexHead") // Last indexed broadcast interval
+	broadcastIntervalPrefix    = []byte("bci")         // broadcastIntervalPrefix + interval (uint64 big endian) -> indexedBroadcastInterval
+	broadcastAddressDataPrefix = []byte("bca")         // broadcastAddressDataPrefix + address + interval (uint64 big endian) + state key -> payload
+)
+
+// BroadcastIndexKeyCategories are the key categories of the broadcast index
+// database, reported by gman db inspect.
+var BroadcastIndexKeyCategories = []core.KeyCategory{
+	{Name: "Broadcast intervals", Match: func(key, value []byte) bool { return bytes.HasPrefix(key, broadcastIntervalPrefix) }},
+	{Name: "Broadcast data by address", Match: func(key, value []byte) bool { return bytes.HasPrefix(key, broadcastAddressDataPrefix) }},
+	{Name: "Index head", Match: func(key, value []byte) bool { return bytes.Equal(key, broadcastIndexHeadKey) }},
+}
+
+// indexedBroadcastInterval is the broadcast data of an interval stored in the
+// index, as committed by its broadcast block.
+type indexedBroadcastInterval struct {
+	Number uint64
+	Hash   common.Hash
+	Txs    common.BroadTxSlice
+}
+
+// broadcastIndexer keeps an index of the broadcast transactions (heartbeats,
+// public keys, roll calls, ...) committed by every broadcast block, by interval
+// and by sender address. The data is read from the matrix state of the broadcast
+// blocks, so catching up with past intervals requires an archive node.
+type broadcastIndexer struct {
+	db    mandb.Database
+	chain *core.BlockChain
+	lock  sync.Mutex // Serializes the index writes of the catch-up and the live indexing
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newBroadcastIndexer(db mandb.Database, chain *core.BlockChain) *broadcastIndexer {
+	return &broadcastIndexer{
+		db:    db,
+		chain: chain,
+		quit:  make(chan struct{}),
+	}
+}
+
+// Start catches up with the intervals produced since the last run and keeps
+// indexing the new broadcast blocks. The catch-up may take long on an archive
+// node, it runs concurrently with the live indexing so that the chain events
+// are drained all along.
+func (idx *broadcastIndexer) Start() {
+	events := make(chan core.ChainEvent, chainEventChanSize)
+	sub := idx.chain.SubscribeChainEvent(events)
+
+	idx.wg.Add(2)
+	go func() {
+		defer idx.wg.Done()
+		idx.catchUp()
+	}()
+	go func() {
+		defer idx.wg.Done()
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				header := ev.Block.Header()
+				if !manparams.IsBroadcastNumberByHash(header.Number.Uint64(), header.ParentHash) {
+					continue
+				}
+				if err := idx.indexBlock(header); err != nil {
+					log.Warn("Failed to index broadcast block", "number", header.Number, "err", err)
+				}
+			case <-sub.Err():
+				return
+			case <-idx.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the indexing and closes the index database.
+func (idx *broadcastIndexer) Stop() {
+	close(idx.quit)
+	idx.wg.Wait()
+	idx.db.Close()
+}
+
+// catchUp indexes the finished intervals following the last indexed one.
+func (idx *broadcastIndexer) catchUp() {
+	for interval := idx.head() + 1; ; interval++ {
+		select {
+		case <-idx.quit:
+			return
+		default:
+		}
+		snapshot, err := core.GetBroadcastDataByInterval(idx.chain, interval)
+		if err == core.ErrFutureBroadcastInterval {
+			return
+		}
+		if err != nil {
+			log.Debug("Skipping broadcast interval in index", "interval", interval, "err", err)
+			continue
+		}
+		if err := idx.write(snapshot); err != nil {
+			log.Error("Failed to write broadcast index", "interval", interval, "err", err)
+			return
+		}
+	}
+}
+
+// indexBlock indexes the broadcast data committed by a broadcast block.
+func (idx *broadcastIndexer) indexBlock(header *types.Header) error {
+	bcInterval, err := manparams.GetBCIntervalInfoByHash(header.ParentHash)
+	if err != nil {
+		return err
+	}
+	interval := header.Number.Uint64() / bcInterval.GetBroadcastInterval()
+	snapshot, err := core.GetBroadcastDataByBlock(idx.chain, interval, header)
+	if err != nil {
+		return err
+	}
+	return idx.write(snapshot)
+}
+
+// write stores the snapshot of an interval and advances the index head.
+func (idx *broadcastIndexer) write(snapshot core.BroadcastSnapshot) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	batch := idx.db.NewBatch()
+	record := indexedBroadcastInterval{Number: snapshot.Number, Hash: snapshot.Hash}
+	for stateKey, senders := range snapshot.Data {
+		for from, payload := range senders {
+			record.Txs.Insert(stateKey, from, payload)
+			if err := batch.Put(broadcastAddressDataKey(from, snapshot.Interval, stateKey), payload); err != nil {
+				return err
+			}
+		}
+	}
+	enc, err := rlp.EncodeToBytes(record)
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(broadcastIntervalKey(snapshot.Interval), enc); err != nil {
+		return err
+	}
+	if snapshot.Interval > idx.head() {
+		if err := batch.Put(broadcastIndexHeadKey, encodeIntervalNumber(snapshot.Interval)); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// head returns the last indexed interval, 0 if nothing is indexed yet.
+func (idx *broadcastIndexer) head() uint64 {
+	data, err := idx.db.Get(broadcastIndexHeadKey)
+	if err != nil || len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// interval retrieves the indexed broadcast data of an interval.
+func (idx *broadcastIndexer) interval(interval uint64) (*indexedBroadcastInterval, error) {
+	data, err := idx.db.Get(broadcastIntervalKey(interval))
+	if err != nil {
+		return nil, fmt.Errorf("broadcast interval %d not indexed", interval)
+	}
+	record := new(indexedBroadcastInterval)
+	if err := rlp.DecodeBytes(data, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// broadcastHistoryEntry is the payload sent by an address in an interval.
+type broadcastHistoryEntry struct {
+	Interval uint64
+	Payload  []byte
+}
+
+// history retrieves the payloads of a broadcast type sent by an address in the
+// intervals from..to, both included. Intervals without payload are skipped.
+func (idx *broadcastIndexer) history(stateKey string, addr common.Address, from, to uint64) ([]broadcastHistoryEntry, error) {
+	if to < from {
+		return nil, fmt.Errorf("invalid interval range %d-%d", from, to)
+	}
+	if to-from >= maxBroadcastHistoryRange {
+		return nil, fmt.Errorf("interval range too large, at most %d intervals", maxBroadcastHistoryRange)
+	}
+	entries := make([]broadcastHistoryEntry, 0)
+	for interval := from; interval <= to; interval++ {
+		payload, err := idx.db.Get(broadcastAddressDataKey(addr, interval, stateKey))
+		if err != nil || payload == nil {
+			continue
+		}
+		entries = append(entries, broadcastHistoryEntry{Interval: interval, Payload: payload})
+	}
+	return entries, nil
+}
+
+func encodeIntervalNumber(interval uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, interval)
+	return enc
+}
+
+func broadcastIntervalKey(interval uint64) []byte {
+	return append(append([]byte{}, broadcastIntervalPrefix...), encodeIntervalNumber(interval)...)
+}
+
+func broadcastAddressDataKey(addr common.Address, interval uint64, stateKey string) []byte {
+	key := append(append([]byte{}, broadcastAddressDataPrefix...), addr.Bytes()...)
+	key = append(key, encodeIntervalNumber(interval)...)
+	return append(key, stateKey...)
+}
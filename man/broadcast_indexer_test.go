@@ -0,0 +1,61 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+func TestBroadcastIndexerHistory(t *testing.T) {
+	idx := newBroadcastIndexer(mandb.NewMemDatabase(), nil)
+	addr := common.HexToAddress("0x01")
+
+	for _, interval := range []uint64{3, 5} {
+		snapshot := core.BroadcastSnapshot{
+			Interval: interval,
+			Number:   interval * 100,
+			Data: map[string]map[common.Address][]byte{
+				mc.Heartbeat: {addr: {byte(interval)}},
+			},
+		}
+		if err := idx.write(snapshot); err != nil {
+			t.Fatalf("failed to index interval %d: %v", interval, err)
+		}
+	}
+	if head := idx.head(); head != 5 {
+		t.Errorf("index head mismatch: have %d, want 5", head)
+	}
+
+	entries, err := idx.history(mc.Heartbeat, addr, 1, 10)
+	if err != nil {
+		t.Fatalf("history query failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Interval != 3 || entries[1].Interval != 5 {
+		t.Fatalf("history mismatch: %v", entries)
+	}
+	if !bytes.Equal(entries[1].Payload, []byte{5}) {
+		t.Errorf("payload mismatch: have %x, want 05", entries[1].Payload)
+	}
+	if entries, _ := idx.history(mc.Publickey, addr, 1, 10); len(entries) != 0 {
+		t.Errorf("unexpected public key history: %v", entries)
+	}
+	if _, err := idx.history(mc.Heartbeat, addr, 0, maxBroadcastHistoryRange); err == nil {
+		t.Errorf("oversized range accepted")
+	}
+
+	record, err := idx.interval(5)
+	if err != nil {
+		t.Fatalf("interval query failed: %v", err)
+	}
+	if record.Number != 500 || len(record.Txs) != 1 {
+		t.Errorf("interval record mismatch: %+v", record)
+	}
+}
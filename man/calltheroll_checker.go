@@ -0,0 +1,217 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+const (
+	// broadcastTxChanSize is the size of channel listening to BroadcastTxEvent.
+	broadcastTxChanSize = 256
+
+	// maxObservedRollCallIntervals is the number of intervals the checker keeps
+	// the observed roll calls of, in case broadcast blocks go missing.
+	maxObservedRollCallIntervals = 4
+)
+
+var (
+	rollCallReportCounter    = metrics.NewRegisteredCounter("man/calltheroll/reports", nil)
+	rollCallObservedCounter  = metrics.NewRegisteredCounter("man/calltheroll/observed", nil)
+	rollCallMissingMeter     = metrics.NewRegisteredMeter("man/calltheroll/missing", nil)
+	rollCallUnobservedMeter  = metrics.NewRegisteredMeter("man/calltheroll/unobserved", nil)
+	rollCallMismatchedMeter  = metrics.NewRegisteredMeter("man/calltheroll/mismatched", nil)
+	rollCallDiscrepancyGauge = metrics.NewRegisteredGauge("man/calltheroll/discrepancies", nil)
+)
+
+// rollCallChecker cross-checks the roll calls committed by every broadcast block
+// against the roll call transactions the local broadcast pool accepted during
+// the interval. Roll calls seen locally but dropped from the block, roll calls
+// committed without ever being propagated and roll calls whose content changed
+// on the way hint at a misbehaving broadcast node, and are reported through a
+// mc.CallTheRoll_Report event on validator nodes.
+type rollCallChecker struct {
+	chain  *core.BlockChain
+	txPool *core.TxPoolManager
+
+	since    uint64                               // First interval observed from its start
+	observed map[uint64]map[common.Address][]byte // Roll calls accepted locally, by interval and sender
+	lock     sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newRollCallChecker(chain *core.BlockChain, txPool *core.TxPoolManager) *rollCallChecker {
+	return &rollCallChecker{
+		chain:    chain,
+		txPool:   txPool,
+		observed: make(map[uint64]map[common.Address][]byte),
+		quit:     make(chan struct{}),
+	}
+}
+
+// Start begins recording the roll calls accepted by the broadcast pool and
+// checking them against the broadcast blocks.
+func (c *rollCallChecker) Start() {
+	// The interval in progress is only partially observed, start with the next one
+	head := c.chain.CurrentBlock().NumberU64()
+	bcInterval, err := c.chain.GetBroadcastIntervalAt(head + 1)
+	if err != nil {
+		bcInterval = manparams.GetBCIntervalInfo()
+	}
+	c.since = head/bcInterval.GetBroadcastInterval() + 2
+
+	txs := make(chan core.BroadcastTxEvent, broadcastTxChanSize)
+	txSub := c.txPool.SubscribeBroadcastTxEvent(txs)
+	events := make(chan core.ChainEvent, chainEventChanSize)
+	chainSub := c.chain.SubscribeChainEvent(events)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer txSub.Unsubscribe()
+		defer chainSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-txs:
+				if ev.Type == mc.CallTheRoll {
+					c.observe(ev)
+				}
+			case ev := <-events:
+				header := ev.Block.Header()
+				if !c.chain.IsBroadcastBlock(header.Number.Uint64(), header.ParentHash) {
+					continue
+				}
+				if err := c.checkBlock(header); err != nil {
+					log.Debug("Failed to check roll calls of broadcast block", "number", header.Number, "err", err)
+				}
+			case <-txSub.Err():
+				return
+			case <-chainSub.Err():
+				return
+			case <-c.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the checker.
+func (c *rollCallChecker) Stop() {
+	close(c.quit)
+	c.wg.Wait()
+}
+
+// observe records the payload of a roll call accepted by the broadcast pool.
+func (c *rollCallChecker) observe(ev core.BroadcastTxEvent) {
+	payload, err := types.DecodeBroadcastPayload(ev.Tx.Data())
+	if err != nil {
+		return
+	}
+	data, ok := payload[fmt.Sprintf("%s%d", ev.Type, ev.Interval)]
+	if !ok {
+		return
+	}
+	rollCallObservedCounter.Inc(1)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.observed[ev.Interval]; !ok {
+		c.observed[ev.Interval] = make(map[common.Address][]byte)
+	}
+	c.observed[ev.Interval][ev.From] = data
+
+	// Drop the oldest intervals if their broadcast blocks never showed up
+	for interval := range c.observed {
+		if interval+maxObservedRollCallIntervals <= ev.Interval {
+			delete(c.observed, interval)
+		}
+	}
+}
+
+// checkBlock compares the roll calls committed by a broadcast block with the
+// locally observed ones and reports the differences.
+func (c *rollCallChecker) checkBlock(header *types.Header) error {
+	bcInterval, err := c.chain.GetBroadcastIntervalAt(header.Number.Uint64())
+	if err != nil {
+		return err
+	}
+	interval := header.Number.Uint64() / bcInterval.GetBroadcastInterval()
+
+	c.lock.Lock()
+	observed := c.observed[interval]
+	for i := range c.observed {
+		if i <= interval {
+			delete(c.observed, i)
+		}
+	}
+	c.lock.Unlock()
+
+	if interval < c.since || ca.GetRole() != common.RoleValidator {
+		return nil
+	}
+	snapshot, err := core.GetBroadcastDataByBlock(c.chain, interval, header)
+	if err != nil {
+		return err
+	}
+	report := diffRollCalls(observed, snapshot.CallTheRolls())
+	report.Interval, report.Number, report.Hash = interval, snapshot.Number, snapshot.Hash
+
+	rollCallReportCounter.Inc(1)
+	rollCallMissingMeter.Mark(int64(len(report.Missing)))
+	rollCallUnobservedMeter.Mark(int64(len(report.Unobserved)))
+	rollCallMismatchedMeter.Mark(int64(len(report.Mismatched)))
+	rollCallDiscrepancyGauge.Update(int64(len(report.Missing) + len(report.Unobserved) + len(report.Mismatched)))
+
+	if len(report.Missing)+len(report.Unobserved)+len(report.Mismatched) > 0 {
+		log.Warn("Roll calls of broadcast block differ from the observed ones", "interval", interval, "number", report.Number,
+			"missing", len(report.Missing), "unobserved", len(report.Unobserved), "mismatched", len(report.Mismatched))
+	}
+	mc.PublishEvent(mc.CallTheRoll_Report, report)
+	return nil
+}
+
+// diffRollCalls compares the locally observed roll calls of an interval with the
+// ones committed on chain, both indexed by sender.
+func diffRollCalls(observed, committed map[common.Address][]byte) *mc.CallTheRollReport {
+	report := &mc.CallTheRollReport{Observed: len(observed), Committed: len(committed)}
+	for from, payload := range observed {
+		onchain, ok := committed[from]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, from)
+		case !bytes.Equal(onchain, payload):
+			report.Mismatched = append(report.Mismatched, from)
+		}
+	}
+	for from := range committed {
+		if _, ok := observed[from]; !ok {
+			report.Unobserved = append(report.Unobserved, from)
+		}
+	}
+	sortAddresses(report.Missing)
+	sortAddresses(report.Unobserved)
+	sortAddresses(report.Mismatched)
+	return report
+}
+
+func sortAddresses(addrs []common.Address) {
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+}
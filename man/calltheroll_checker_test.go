@@ -0,0 +1,50 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+func TestDiffRollCalls(t *testing.T) {
+	var (
+		agreed     = common.HexToAddress("0x01")
+		missing    = common.HexToAddress("0x02")
+		unobserved = common.HexToAddress("0x03")
+		mismatched = common.HexToAddress("0x04")
+	)
+	observed := map[common.Address][]byte{
+		agreed:     []byte(`{"0x05":1}`),
+		missing:    []byte(`{"0x05":1}`),
+		mismatched: []byte(`{"0x05":1}`),
+	}
+	committed := map[common.Address][]byte{
+		agreed:     []byte(`{"0x05":1}`),
+		unobserved: []byte(`{"0x05":0}`),
+		mismatched: []byte(`{"0x05":0}`),
+	}
+	report := diffRollCalls(observed, committed)
+	if report.Observed != 3 || report.Committed != 3 {
+		t.Errorf("counts mismatch: have %d/%d, want 3/3", report.Observed, report.Committed)
+	}
+	if want := []common.Address{missing}; !reflect.DeepEqual(report.Missing, want) {
+		t.Errorf("missing mismatch: have %v, want %v", report.Missing, want)
+	}
+	if want := []common.Address{unobserved}; !reflect.DeepEqual(report.Unobserved, want) {
+		t.Errorf("unobserved mismatch: have %v, want %v", report.Unobserved, want)
+	}
+	if want := []common.Address{mismatched}; !reflect.DeepEqual(report.Mismatched, want) {
+		t.Errorf("mismatched mismatch: have %v, want %v", report.Mismatched, want)
+	}
+
+	// Nothing observed locally, e.g. a freshly started node, only unobserved entries
+	report = diffRollCalls(nil, committed)
+	if len(report.Missing) != 0 || len(report.Mismatched) != 0 || len(report.Unobserved) != 3 {
+		t.Errorf("unexpected report without observations: %+v", report)
+	}
+}
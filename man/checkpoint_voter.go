@@ -0,0 +1,125 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts/signhelper"
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/msgsend"
+)
+
+// checkpointVoteChanSize is the size of channel listening to the checkpoint votes.
+const checkpointVoteChanSize = 64
+
+// checkpointVoter makes the elected validators vote on the checkpoint blocks of
+// the canonical chain with their BLS key, and collects the votes of the other
+// validators into the checkpoint pool. Once a supermajority voted, the leader
+// of a following block aggregates the votes into its header.
+type checkpointVoter struct {
+	chain      *core.BlockChain
+	hd         *msgsend.HD
+	signHelper *signhelper.SignHelper
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newCheckpointVoter(chain *core.BlockChain, hd *msgsend.HD, signHelper *signhelper.SignHelper) *checkpointVoter {
+	return &checkpointVoter{
+		chain:      chain,
+		hd:         hd,
+		signHelper: signHelper,
+		quit:       make(chan struct{}),
+	}
+}
+
+// Start begins voting on the checkpoints and collecting the received votes.
+func (v *checkpointVoter) Start() error {
+	votes := make(chan *mc.HD_CheckpointVoteMsg, checkpointVoteChanSize)
+	voteSub, err := mc.SubscribeEvent(mc.HD_CheckpointVote, votes)
+	if err != nil {
+		return err
+	}
+	events := make(chan core.ChainEvent, chainEventChanSize)
+	chainSub := v.chain.SubscribeChainEvent(events)
+
+	v.wg.Add(1)
+	go func() {
+		defer v.wg.Done()
+		defer voteSub.Unsubscribe()
+		defer chainSub.Unsubscribe()
+
+		for {
+			select {
+			case msg := <-votes:
+				if err := v.addVote(msg.Number, msg.Hash, msg.Account, msg.BLSSign); err != nil {
+					log.Debug("Invalid checkpoint vote", "number", msg.Number, "account", msg.Account, "err", err)
+				}
+			case ev := <-events:
+				v.chain.CheckpointPool().Prune(ev.Block.NumberU64())
+				v.vote(ev.Block)
+			case <-chainSub.Err():
+				return
+			case <-v.quit:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the voter.
+func (v *checkpointVoter) Stop() {
+	close(v.quit)
+	v.wg.Wait()
+}
+
+// addVote adds a vote to the checkpoint pool, checked against the validators
+// of the current head.
+func (v *checkpointVoter) addVote(number uint64, hash common.Hash, account common.Address, sign []byte) error {
+	head := v.chain.CurrentBlock()
+	st, err := v.chain.StateAt(head.Root())
+	if err != nil {
+		return err
+	}
+	return v.chain.CheckpointPool().AddVote(st, head.NumberU64(), number, hash, account, sign)
+}
+
+// vote signs the block if it is a checkpoint and the node is an elected
+// validator with a BLS key, and sends the vote to the other validators.
+func (v *checkpointVoter) vote(block *types.Block) {
+	if ca.GetRole() != common.RoleValidator || !v.signHelper.HasBLSKey() {
+		return
+	}
+	st, err := v.chain.StateAt(block.Root())
+	if err != nil {
+		return
+	}
+	cfg, err := matrixstate.GetFinalityCfg(st)
+	if err != nil || !cfg.IsCheckpoint(block.NumberU64()) {
+		return
+	}
+	number, hash := block.NumberU64(), block.Hash()
+	sign, err := v.signHelper.SignBLSHash(core.CheckpointSignHash(number, hash).Bytes())
+	if err != nil {
+		log.Error("Failed to sign checkpoint", "number", number, "hash", hash, "err", err)
+		return
+	}
+	account := ca.GetDepositAddress()
+	if err := v.addVote(number, hash, account, sign); err != nil {
+		log.Error("Failed to add checkpoint vote", "number", number, "hash", hash, "err", err)
+		return
+	}
+	v.hd.SendNodeMsg(mc.HD_CheckpointVote, &mc.HD_CheckpointVoteMsg{Number: number, Hash: hash, Account: account, BLSSign: sign}, common.RoleValidator, nil)
+	log.Debug("Voted on checkpoint", "number", number, "hash", hash)
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/consensus/blkmanage"
 	"github.com/MatrixAINetwork/go-matrix/consensus/manash"
 	"github.com/MatrixAINetwork/go-matrix/core"
 	"github.com/MatrixAINetwork/go-matrix/man/downloader"
@@ -33,11 +34,15 @@ var DefaultConfig = Config{
 	},
 	NetworkId:         1,
 	LightPeers:        100,
-	DatabaseCache:     768,
+	DatabaseCache:     512,
 	DatabaseTableSize: 2,
+	TrieCleanCache:    256,
 	TrieCache:         256,
 	TrieTimeout:       5 * time.Minute,
 	GasPrice:          big.NewInt(18 * params.Shannon),
+	ForkAlertDepth:    6,
+
+	BridgeConfirmations: 12,
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
@@ -80,9 +85,14 @@ type Config struct {
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	DatabaseEngine     string `toml:",omitempty"` // Key-value backend, empty selects the one of the existing database
+	TrieCleanCache     int    // Memory allowance (MB) for caching clean trie nodes read from disk
 	TrieCache          int
 	DatabaseTableSize  int
 	TrieTimeout        time.Duration
+	StateRetention     uint64 // Number of recent block states kept on disk by the state pruner, 0 disables pruning
+	AncientThreshold   uint64 // Number of recent blocks kept in LevelDB, older ones are moved to the freezer, 0 disables freezing
+	DatabaseAncient    string `toml:",omitempty"` // Freezer directory, defaults to chaindata/ancient
 
 	// Mining-related options
 	Manerbase    common.Address `toml:",omitempty"`
@@ -102,6 +112,44 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables concurrent execution of conflict free transactions
+	ParallelExec bool
+
+	// Enables the index of the broadcast transactions committed by the broadcast blocks
+	BroadcastIndex bool
+
+	// Enables the index of the transactions by sender and recipient address
+	AddressIndex bool
+
+	// Reorgs dropping at least this many blocks raise an alert (0 = no alerts)
+	ForkAlertDepth uint64
+
+	// URL the deep reorg alerts are posted to, if any
+	ForkAlertWebhook string `toml:",omitempty"`
+
+	// Bridge contracts whose lock and burn events are attested (none = bridge disabled)
+	BridgeContracts []common.Address `toml:",omitempty"`
+
+	// Signatures of the watched bridge events (empty = Lock and Burn events)
+	BridgeEvents []string `toml:",omitempty"`
+
+	// Number of confirmations a bridge event needs before it is attested
+	BridgeConfirmations uint64
+
+	// Endpoint of the external signer taking over all the signatures, if any
+	ExternalSigner string `toml:",omitempty"`
+
+	// File holding the BLS secret key the block votes are signed with, if any
+	BLSKeyFile string `toml:",omitempty"`
+
+	// Hooks invoked by the block producer before sealing a block
+	BlockBuilderHooks []blkmanage.BlockBuilderHook `toml:"-"`
+
+	// Developer mode: the node seals the blocks of its single-node chain by itself,
+	// as soon as transactions are pending or at a fixed period if set
+	DevMode   bool          `toml:"-"`
+	DevPeriod time.Duration `toml:"-"`
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 }
@@ -0,0 +1,187 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"sync"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/consensus/blkmanage"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// devMaxBlocksPerSeal is the maximum number of blocks sealed in a row while
+// transactions are pending, for the transactions that never get included.
+const devMaxBlocksPerSeal = 16
+
+// devSealer produces the blocks of a developer chain. The local node holds all
+// the roles of the single-node chain, so instead of running the leader election,
+// the block verification and the mining, the sealer builds, signs and inserts
+// the blocks by itself, the broadcast blocks included.
+type devSealer struct {
+	man    *Matrix
+	period time.Duration // Block period, 0 to seal only when transactions are pending
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newDevSealer(man *Matrix, period time.Duration) *devSealer {
+	return &devSealer{
+		man:    man,
+		period: period,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start begins sealing the blocks, at every period or on every new transaction.
+func (s *devSealer) Start() {
+	txs := make(chan core.NewTxsEvent, txChanSize)
+	txSub := s.man.txPool.SubscribeNewTxsEvent(txs)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer txSub.Unsubscribe()
+
+		var tick <-chan time.Time
+		if s.period > 0 {
+			ticker := time.NewTicker(s.period)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-txs:
+				if s.period == 0 {
+					s.sealPending()
+				}
+			case <-tick:
+				s.seal()
+			case <-txSub.Err():
+				return
+			case <-s.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the sealer.
+func (s *devSealer) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// sealPending seals blocks until the pending transactions are all included.
+func (s *devSealer) sealPending() {
+	for i := 0; i < devMaxBlocksPerSeal && s.hasPending(); i++ {
+		if included, err := s.seal(); err != nil || included == 0 {
+			return
+		}
+	}
+}
+
+// hasPending checks whether the transaction pool holds executable transactions.
+func (s *devSealer) hasPending() bool {
+	pending, err := s.man.txPool.Pending()
+	if err != nil {
+		return false
+	}
+	for _, accounts := range pending {
+		for _, txs := range accounts {
+			if len(txs) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// seal produces the next common block, preceded by the broadcast block if the
+// next block is on the broadcast interval. It returns the number of
+// transactions included.
+func (s *devSealer) seal() (int, error) {
+	for {
+		broadcast, included, err := s.sealBlock()
+		if err != nil {
+			log.Error("Failed to seal developer block", "err", err)
+			return 0, err
+		}
+		if !broadcast {
+			return included, nil
+		}
+	}
+}
+
+// sealBlock builds the block on top of the current head, signs it with the
+// developer account and inserts it into the chain. It returns whether it was a
+// broadcast block and the number of transactions included.
+func (s *devSealer) sealBlock() (bool, int, error) {
+	var (
+		chain  = s.man.blockchain
+		manblk = s.man.manBlkManage
+		parent = chain.CurrentBlock()
+		number = parent.NumberU64() + 1
+	)
+	interval, err := chain.GetBroadcastIntervalByHash(parent.Hash())
+	if err != nil {
+		return false, 0, err
+	}
+	kind := blkmanage.CommonBlk
+	if interval.IsBroadcastNumber(number) {
+		kind = blkmanage.BroadcastBlk
+	}
+	version := manblk.ProduceBlockVersion(number, string(parent.Version()))
+
+	header, _, err := manblk.Prepare(kind, version, number, interval, parent.Hash())
+	if err != nil {
+		return false, 0, err
+	}
+	// Take the mining reward as the only miner. The coinbase must be set before
+	// the state is processed, since the rewards and the EVM read it.
+	if kind == blkmanage.CommonBlk {
+		header.Coinbase = ca.GetDepositAddress()
+	}
+	_, stateDB, receipts, originalTxs, finalTxs, _, err := manblk.ProcessState(kind, version, header, nil)
+	if err != nil {
+		return false, 0, err
+	}
+	block, _, err := manblk.Finalize(kind, version, header, stateDB, finalTxs, nil, receipts, nil)
+	if err != nil {
+		return false, 0, err
+	}
+
+	// Vote for the block as the only validator
+	header = block.Header()
+	sign, err := s.man.signHelper.SignHashWithValidateByAccount(header.HashNoSignsAndNonce().Bytes(), true, ca.GetDepositAddress())
+	if err != nil {
+		return false, 0, err
+	}
+	header.Signatures = []common.Signature{sign}
+
+	block = types.NewBlockWithTxs(header, types.MakeCurencyBlock(finalTxs, receipts, nil))
+	status, err := chain.WriteBlockWithState(block, stateDB)
+	if err != nil {
+		return false, 0, err
+	}
+	mc.PublishEvent(mc.BlockInserted, &mc.BlockInsertedMsg{Block: mc.BlockInfo{Hash: block.Hash(), Number: number}, InsertTime: uint64(time.Now().Unix()), CanonState: status == core.CanonStatTy})
+
+	logs := stateDB.Logs()
+	events := []interface{}{core.ChainEvent{Block: block, Hash: block.Hash(), Logs: logs}}
+	if status == core.CanonStatTy {
+		events = append(events, core.ChainHeadEvent{Block: block})
+	}
+	chain.PostChainEvents(events, logs)
+
+	included := len(types.GetTX(originalTxs))
+	log.Info("Sealed developer block", "number", number, "hash", block.Hash(), "broadcast", kind == blkmanage.BroadcastBlk, "txs", included)
+	return kind == blkmanage.BroadcastBlk, included, nil
+}
@@ -39,6 +39,8 @@ var (
 	MaxReceiptFetch = 64  //256 // Amount of transaction receipts to allow fetching per request
 	MaxStateFetch   = 384 // Amount of node state values to allow fetching per request
 
+	MaxStateChunkNodes = 4096 // Amount of trie nodes to allow fetching per state chunk request
+
 	MaxForkAncestry  = 3 * params.EpochDuration // Maximum chain reorganisation
 	rttMinEstimate   = 2 * time.Second          // Minimum round-trip time to target for download requests
 	rttMaxEstimate   = 20 * time.Second         // Maximum round-trip time to target for download requests
@@ -323,7 +325,7 @@ func (d *Downloader) Progress() matrix.SyncProgress {
 	switch d.mode {
 	case FullSync:
 		current = d.blockchain.CurrentBlock().NumberU64()
-	case FastSync:
+	case FastSync, SnapSync:
 		current = d.blockchain.CurrentFastBlock().NumberU64()
 	case LightSync:
 		current = d.lightchain.CurrentHeader().Number.Uint64()
@@ -580,7 +582,7 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	d.curRemote = height
 	// Ensure our origin point is below any fast sync pivot point
 	pivot := uint64(0)
-	if d.mode == FastSync {
+	if d.mode.pivoted() {
 		if height <= uint64(fsMinFullBlocks) {
 			origin = 0
 		} else {
@@ -591,7 +593,7 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		}
 	}
 	d.committed = 1
-	if d.mode == FastSync && pivot != 0 {
+	if d.mode.pivoted() && pivot != 0 {
 		d.committed = 0
 	}
 	// Initiate the sync using a concurrent header and content retrieval algorithm
@@ -606,7 +608,7 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		func() error { return d.fetchReceipts(origin + 1) },        // Receipts are retrieved during fast sync
 		func() error { return d.processHeaders(origin+1, pivot, td, pSbs) },
 	}
-	if d.mode == FastSync {
+	if d.mode.pivoted() {
 		fetchers = append(fetchers, func() error { return d.processFastSyncContent(latest) })
 	} else if d.mode == FullSync {
 		fetchers = append(fetchers, d.processFullSyncContent)
@@ -807,7 +809,7 @@ func (d *Downloader) findAncestor(p *peerConnection, height uint64) (uint64, err
 
 	if d.mode == FullSync {
 		ceil = d.blockchain.CurrentBlock().NumberU64()
-	} else if d.mode == FastSync {
+	} else if d.mode.pivoted() {
 		ceil = d.blockchain.CurrentFastBlock().NumberU64()
 	}
 	if ceil >= MaxForkAncestry {
@@ -1623,7 +1625,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int, pS
 				// This check cannot be executed "as is" for full imports, since blocks may still be
 				// queued for processing when the header download completes. However, as long as the
 				// peer gave us something useful, we're already happy/progressed (above check).
-				if d.mode == FastSync || d.mode == LightSync {
+				if d.mode.pivoted() || d.mode == LightSync {
 					head := d.lightchain.CurrentHeader()
 					sbs, err := d.blockchain.GetSuperBlockSeq()
 					if nil != err {
@@ -1662,7 +1664,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int, pS
 				chunk := headers[:limit]
 				log.Debug("download  processHeaders  recv header then deal ", "begin head number", chunk[0].Number.Uint64())
 				// In case of header only syncing, validate the chunk immediately
-				if d.mode == FastSync || d.mode == LightSync {
+				if d.mode.pivoted() || d.mode == LightSync {
 					// Collect the yet unknown headers to mark them as uncertain
 					unknown := make([]*types.Header, 0, len(headers))
 					for _, header := range chunk {
@@ -1690,7 +1692,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int, pS
 					}
 				}
 				// Unless we're doing light chains, schedule the headers for associated content retrieval
-				if d.mode == FullSync || d.mode == FastSync {
+				if d.mode == FullSync || d.mode.pivoted() {
 					// If we've reached the allowed number of pending headers, stall a bit
 					for d.queue.PendingBlocks() >= maxQueuedHeaders || d.queue.PendingReceipts() >= maxQueuedHeaders {
 						select {
@@ -1708,7 +1710,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int, pS
 				}
 				//liubo ipfs
 				//if d.IpfsMode == true && (d.mode == FullSync || d.mode == FastSync) { //len(chunk) > 0 {
-				if d.bIpfsDownload == 2 && (d.mode == FullSync || d.mode == FastSync) {
+				if d.bIpfsDownload == 2 && (d.mode == FullSync || d.mode.pivoted()) {
 					//ChIpfs <- headers://processHeaders
 					//
 
@@ -1870,6 +1872,14 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 				if stateSync.err != nil {
 					return stateSync.err
 				}
+				if d.mode == SnapSync {
+					if err := d.healState(P.Header); err != nil {
+						return err
+					}
+					if err := d.syncConsensusStates(P.Header); err != nil {
+						return err
+					}
+				}
 				if err := d.commitPivotBlock(P); err != nil {
 					return err
 				}
@@ -81,6 +81,12 @@ type Peer interface {
 	RequestNodeData([]common.Hash) error
 }
 
+// stateChunkPeer is implemented by the peers able to serve breadth first chunks
+// of the state tries [man/65+].
+type stateChunkPeer interface {
+	RequestStateChunks([]common.Hash) error
+}
+
 // lightPeerWrapper wraps a LightPeer struct, stubbing out the Peer-only methods.
 type lightPeerWrapper struct {
 	peer LightPeer
@@ -220,6 +226,24 @@ func (p *peerConnection) FetchNodeData(hashes []common.Hash) error {
 	return nil
 }
 
+// FetchStateChunks sends a state chunk retrieval request to the remote peer,
+// falling back to a node state data request if the peer can't serve chunks.
+func (p *peerConnection) FetchStateChunks(roots []common.Hash) error {
+	peer, ok := p.peer.(stateChunkPeer)
+	if !ok || p.version < 65 {
+		return p.FetchNodeData(roots)
+	}
+	// Short circuit if the peer is already fetching
+	if !atomic.CompareAndSwapInt32(&p.stateIdle, 0, 1) {
+		return errAlreadyFetching
+	}
+	p.stateStarted = time.Now()
+
+	go peer.RequestStateChunks(roots)
+
+	return nil
+}
+
 // SetHeadersIdle sets the peer to idle, allowing it to execute new header retrieval
 // requests. Its estimated header retrieval throughput is updated with that measured
 // just now.
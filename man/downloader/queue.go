@@ -369,7 +369,7 @@ func (q *queue) Schedule(headers []*types.Header, from uint64, bodymode int) []*
 		} else {
 			q.blockTaskPool[hash] = header
 			q.blockTaskQueue.Push(header, -float32(header.Number.Uint64()))
-			if q.mode == FastSync {
+			if q.mode.pivoted() {
 				q.receiptTaskPool[hash] = header
 				q.receiptTaskQueue.Push(header, -float32(header.Number.Uint64()))
 			}
@@ -641,7 +641,7 @@ func (q *queue) Reserveipfs(recvheader []*types.Header, origin, remote uint64) (
 		return isOK
 	}
 	components := 1
-	if q.mode == FastSync { // fast
+	if q.mode.pivoted() { // fast
 		components = 2
 	}
 	log.Trace("download queue  Reserveipfs begin ", "lenHeader", len(recvheader), "origin", origin, "remote", remote)
@@ -947,7 +947,7 @@ func (q *queue) reserveHeaders(p *peerConnection, count int, taskPool map[common
 		}
 		if q.resultCache[index] == nil {
 			components := 1
-			if q.mode == FastSync {
+			if q.mode.pivoted() {
 				components = 2
 			}
 			q.resultCache[index] = &fetchResult{
@@ -1401,7 +1401,7 @@ func (q *queue) recvIpfsBody(bodyBlock *BlockIpfs) {
 		header := bodyBlock.Headeripfs
 		hash := header.Hash()
 		components := 1
-		if q.mode == FastSync {
+		if q.mode.pivoted() {
 			components = 2
 		}
 		//log.Warn("download  syn recv a block insert reserveHeaders new", "index", index, " header number", header.Number.Uint64())
@@ -0,0 +1,167 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package downloader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+	"github.com/MatrixAINetwork/go-matrix/trie"
+)
+
+// maxStateHealRounds is the number of gaps healed per shard state root before
+// giving up on the pivot block.
+const maxStateHealRounds = 64
+
+var errStateHealFailed = errors.New("state trie could not be healed")
+
+// Snap sync differs from the pivot fast sync in two ways. The state tries are
+// downloaded in breadth first chunks over the man/65 GetStateChunksMsg, every
+// chunk covering the whole subtrie below a missing node instead of single nodes,
+// with each node verified against the hash its parent (and ultimately the pivot
+// header) committed to. And since the blocks following the pivot can't be run
+// on the pivot state alone - the election and the seed commit-reveal read the
+// state of the previous broadcast blocks, which is why the plain fast sync is
+// disabled - the states of those blocks are synced along with the pivot state.
+
+// healState verifies that the state tries of every coin root in the pivot header
+// are complete in the local database. A coin root commits to the trie roots of
+// its address range shards, each shard trie is checked and missing trie nodes
+// are downloaded from the peers as separate subtrie syncs, until no gap remains.
+func (d *Downloader) healState(header *types.Header) error {
+	for _, coinRoot := range header.Roots {
+		shards, err := state.GetShardRoots(d.stateDB, coinRoot.Root)
+		if err != nil {
+			return fmt.Errorf("%v: coin %s shard roots %x: %v", errStateHealFailed, coinRoot.Cointyp, coinRoot.Root, err)
+		}
+		for _, root := range shards {
+			if err := d.healTrie(coinRoot.Cointyp, root); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncConsensusStates downloads the states of the broadcast and reelection
+// blocks preceding the pivot, which the consensus reads when running the blocks
+// after it.
+func (d *Downloader) syncConsensusStates(pivot *types.Header) error {
+	st, err := state.NewStateDBManage(pivot.Roots, d.stateDB, state.NewDatabase(d.stateDB))
+	if err != nil {
+		return err
+	}
+	bcInterval, err := matrixstate.GetBroadcastInterval(st)
+	if err != nil {
+		return err
+	}
+	numbers := []uint64{bcInterval.LastBCNumber, bcInterval.LastReelectNumber}
+	if bcInterval.LastBCNumber >= bcInterval.BCInterval {
+		numbers = append(numbers, bcInterval.LastBCNumber-bcInterval.BCInterval)
+	}
+	synced := make(map[uint64]bool)
+	for _, number := range numbers {
+		if number == 0 || number >= pivot.Number.Uint64() || synced[number] {
+			continue
+		}
+		synced[number] = true
+
+		header := rawdb.ReadHeader(d.stateDB, rawdb.ReadCanonicalHash(d.stateDB, number), number)
+		if header == nil {
+			return fmt.Errorf("%v: missing header #%d", errStateHealFailed, number)
+		}
+		log.Info("Syncing consensus state", "number", number, "hash", header.Hash())
+		if err := d.syncState(types.RlpHash(header.Roots)).Wait(); err != nil {
+			return err
+		}
+		if err := d.healState(header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// healTrie downloads the missing nodes of a single shard state trie.
+func (d *Downloader) healTrie(coin string, root common.Hash) error {
+	for round := 0; round < maxStateHealRounds; round++ {
+		gap, err := d.findStateGap(root)
+		if err != nil {
+			return err
+		}
+		if gap == (common.Hash{}) {
+			return nil
+		}
+		log.Debug("Healing state trie gap", "coin", coin, "root", root, "gap", gap, "round", round)
+		stateHealMeter.Mark(1)
+
+		if err := d.syncState(gap).Wait(); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%v: coin %s root %x", errStateHealFailed, coin, root)
+}
+
+// findStateGap iterates the account trie and all the storage tries of a state
+// root, returning the hash of the first missing trie node, or an empty hash if
+// the state is complete.
+func (d *Downloader) findStateGap(root common.Hash) (common.Hash, error) {
+	if root == (common.Hash{}) || root == types.EmptyRootHash {
+		return common.Hash{}, nil
+	}
+	db := trie.NewDatabase(d.stateDB)
+
+	var storages []common.Hash
+	gap, err := findTrieGap(db, root, func(leaf []byte) {
+		var obj state.Account
+		if err := rlp.Decode(bytes.NewReader(leaf), &obj); err != nil {
+			return
+		}
+		if obj.Root != (common.Hash{}) && obj.Root != types.EmptyRootHash {
+			storages = append(storages, obj.Root)
+		}
+	})
+	if err != nil || gap != (common.Hash{}) {
+		return gap, err
+	}
+	for _, storage := range storages {
+		if gap, err := findTrieGap(db, storage, nil); err != nil || gap != (common.Hash{}) {
+			return gap, err
+		}
+	}
+	return common.Hash{}, nil
+}
+
+// findTrieGap iterates all the nodes of a single trie, feeding the leaves to the
+// given callback, and returns the hash of the first missing node.
+func findTrieGap(db *trie.Database, root common.Hash, onLeaf func([]byte)) (common.Hash, error) {
+	t, err := trie.New(root, db)
+	if err != nil {
+		if missing, ok := err.(*trie.MissingNodeError); ok {
+			return missing.NodeHash, nil
+		}
+		return common.Hash{}, err
+	}
+	it := t.NodeIterator(nil)
+	for it.Next(true) {
+		if onLeaf != nil && it.Leaf() {
+			onLeaf(it.LeafBlob())
+		}
+	}
+	if err := it.Error(); err != nil {
+		if missing, ok := err.(*trie.MissingNodeError); ok {
+			return missing.NodeHash, nil
+		}
+		return common.Hash{}, err
+	}
+	return common.Hash{}, nil
+}
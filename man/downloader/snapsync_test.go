@@ -0,0 +1,57 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/trie"
+)
+
+// Tests that missing trie nodes are detected as gaps to heal, and that a
+// complete trie is reported as such.
+func TestFindTrieGap(t *testing.T) {
+	diskdb := mandb.NewMemDatabase()
+	triedb := trie.NewDatabase(diskdb)
+
+	tr, _ := trie.New(common.Hash{}, triedb)
+	for i := 0; i < 256; i++ {
+		tr.Update([]byte(fmt.Sprintf("key-%03d", i)), bytes.Repeat([]byte{byte(i)}, 40))
+	}
+	root, err := tr.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to flush trie: %v", err)
+	}
+	leaves := 0
+	gap, err := findTrieGap(trie.NewDatabase(diskdb), root, func([]byte) { leaves++ })
+	if err != nil || gap != (common.Hash{}) {
+		t.Fatalf("complete trie: gap %x, err %v", gap, err)
+	}
+	if leaves != 256 {
+		t.Fatalf("leaf count mismatch: have %d, want %d", leaves, 256)
+	}
+	// Drop a non-root node and ensure it's found
+	for _, key := range diskdb.Keys() {
+		if !bytes.Equal(key, root[:]) && len(key) == common.HashLength {
+			diskdb.Delete(key)
+
+			gap, err := findTrieGap(trie.NewDatabase(diskdb), root, nil)
+			if err != nil {
+				t.Fatalf("failed to iterate trie: %v", err)
+			}
+			if gap != common.BytesToHash(key) {
+				t.Fatalf("gap mismatch: have %x, want %x", gap, key)
+			}
+			break
+		}
+	}
+}
@@ -340,12 +340,17 @@ func (s *stateSync) assignTasks() {
 		req := &stateReq{peer: p, timeout: s.d.requestTTL()}
 		s.fillTasks(cap, req)
 
-		// If the peer was assigned tasks to fetch, send the network request
+		// If the peer was assigned tasks to fetch, send the network request. Snap
+		// sync asks for the whole subtries below the missing nodes in chunks.
 		if len(req.items) > 0 {
 			req.peer.log.Trace("Requesting new batch of data", "type", "state", "count", len(req.items))
 			select {
 			case s.d.trackStateReq <- req:
-				req.peer.FetchNodeData(req.items)
+				if s.d.mode == SnapSync {
+					req.peer.FetchStateChunks(req.items)
+				} else {
+					req.peer.FetchNodeData(req.items)
+				}
 			case <-s.cancel:
 			case <-s.d.cancelCh:
 			}
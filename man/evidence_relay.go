@@ -0,0 +1,99 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/msgsend"
+)
+
+// evidenceChanSize is the size of channels listening to the double sign evidence.
+const evidenceChanSize = 16
+
+// evidenceRelay sends the double sign evidence found by the local evidence
+// pool to the validators, and adds the evidence received from them into the
+// pool. The pool only reports evidence it did not know yet, so every piece of
+// evidence is relayed once by each node.
+type evidenceRelay struct {
+	chain *core.BlockChain
+	hd    *msgsend.HD
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newEvidenceRelay(chain *core.BlockChain, hd *msgsend.HD) *evidenceRelay {
+	return &evidenceRelay{
+		chain: chain,
+		hd:    hd,
+		quit:  make(chan struct{}),
+	}
+}
+
+// Start begins relaying the evidence.
+func (r *evidenceRelay) Start() error {
+	received := make(chan *mc.HD_DoubleSignEvidenceMsg, evidenceChanSize)
+	msgSub, err := mc.SubscribeEvent(mc.HD_DoubleSignEvidence, received)
+	if err != nil {
+		return err
+	}
+	found := make(chan core.NewEvidenceEvent, evidenceChanSize)
+	poolSub := r.chain.EvidencePool().SubscribeNewEvidence(found)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer msgSub.Unsubscribe()
+		defer poolSub.Unsubscribe()
+
+		for {
+			select {
+			case msg := <-received:
+				r.addEvidence(msg)
+			case ev := <-found:
+				r.send(ev.Evidence)
+			case <-poolSub.Err():
+				return
+			case <-r.quit:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the relay.
+func (r *evidenceRelay) Stop() {
+	close(r.quit)
+	r.wg.Wait()
+}
+
+// addEvidence adds an evidence received from a validator into the pool.
+func (r *evidenceRelay) addEvidence(msg *mc.HD_DoubleSignEvidenceMsg) {
+	ev, err := types.DecodeDoubleSignEvidence(msg.Evidence)
+	if err != nil {
+		log.Debug("Invalid double sign evidence", "from", msg.From.Hex(), "err", err)
+		return
+	}
+	if err := r.chain.EvidencePool().AddEvidence(ev); err != nil {
+		log.Debug("Rejected double sign evidence", "from", msg.From.Hex(), "hash", ev.Hash(), "err", err)
+	}
+}
+
+// send relays an evidence to the validators.
+func (r *evidenceRelay) send(ev *types.DoubleSignEvidence) {
+	data, err := types.EncodeDoubleSignEvidence(ev)
+	if err != nil {
+		log.Error("Failed to encode double sign evidence", "hash", ev.Hash(), "err", err)
+		return
+	}
+	r.hd.SendNodeMsg(mc.HD_DoubleSignEvidence, &mc.HD_DoubleSignEvidenceMsg{Evidence: data}, common.RoleValidator, nil)
+}
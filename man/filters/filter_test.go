@@ -0,0 +1,113 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package filters
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+func TestFilterLogs(t *testing.T) {
+	var (
+		addr1  = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		addr2  = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		topic1 = common.HexToHash("0x01")
+		topic2 = common.HexToHash("0x02")
+	)
+	logs := []types.CoinLogs{
+		{CoinType: params.MAN_COIN, Logs: []*types.Log{
+			{Address: addr1, Topics: []common.Hash{topic1}, BlockNumber: 1},
+			{Address: addr2, Topics: []common.Hash{topic1, topic2}, BlockNumber: 2},
+		}},
+		{CoinType: "BTC", Logs: []*types.Log{
+			{Address: addr1, Topics: []common.Hash{topic2}, BlockNumber: 3},
+		}},
+	}
+	tests := []struct {
+		from, to  *big.Int
+		addresses []common.Address
+		topics    [][]common.Hash
+		want      []string
+	}{
+		{want: []string{
+			base58.Base58EncodeToString(params.MAN_COIN, addr1),
+			base58.Base58EncodeToString(params.MAN_COIN, addr2),
+			base58.Base58EncodeToString("BTC", addr1),
+		}},
+		{addresses: []common.Address{addr1}, want: []string{
+			base58.Base58EncodeToString(params.MAN_COIN, addr1),
+			base58.Base58EncodeToString("BTC", addr1),
+		}},
+		{topics: [][]common.Hash{nil, {topic2}}, want: []string{
+			base58.Base58EncodeToString(params.MAN_COIN, addr2),
+		}},
+		{topics: [][]common.Hash{{topic2}}, want: []string{
+			base58.Base58EncodeToString("BTC", addr1),
+		}},
+		{from: big.NewInt(2), to: big.NewInt(2), want: []string{
+			base58.Base58EncodeToString(params.MAN_COIN, addr2),
+		}},
+	}
+	for i, test := range tests {
+		have := filterLogs(logs, test.from, test.to, test.addresses, test.topics)
+		if len(have) != len(test.want) {
+			t.Errorf("test %d: log count mismatch: have %d, want %d", i, len(have), len(test.want))
+			continue
+		}
+		for j, log := range have {
+			if log.Address != test.want[j] {
+				t.Errorf("test %d, log %d: address mismatch: have %s, want %s", i, j, log.Address, test.want[j])
+			}
+		}
+	}
+}
+
+func TestUnmarshalFilterCriteria(t *testing.T) {
+	addr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	topic := common.HexToHash("0x01")
+
+	input := `{"fromBlock": "0x1", "toBlock": "latest", "address": ["` + base58.Base58EncodeToString(params.MAN_COIN, addr) + `", "` + addr.Hex() + `"], "topics": [null, ["` + topic.Hex() + `"]]}`
+
+	var crit FilterCriteria
+	if err := json.Unmarshal([]byte(input), &crit); err != nil {
+		t.Fatalf("failed to unmarshal criteria: %v", err)
+	}
+	if crit.FromBlock.Int64() != 1 || crit.ToBlock.Int64() != -1 {
+		t.Errorf("block range mismatch: have %v-%v", crit.FromBlock, crit.ToBlock)
+	}
+	if len(crit.Addresses) != 2 || crit.Addresses[0] != addr || crit.Addresses[1] != addr {
+		t.Errorf("addresses mismatch: have %v", crit.Addresses)
+	}
+	if len(crit.Topics) != 2 || crit.Topics[0] != nil || len(crit.Topics[1]) != 1 || crit.Topics[1][0] != topic {
+		t.Errorf("topics mismatch: have %v", crit.Topics)
+	}
+	if err := json.Unmarshal([]byte(`{"address": "MAN.invalid"}`), &crit); err == nil {
+		t.Errorf("invalid address accepted")
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	addr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	topic := common.HexToHash("0x01")
+
+	bloom := types.BytesToBloom(types.LogsBloom([]*types.Log{{Address: addr, Topics: []common.Hash{topic}}}).Bytes())
+	blooms := []types.Bloom{{}, bloom}
+
+	if !bloomFilter(blooms, []common.Address{addr}, [][]common.Hash{{topic}}) {
+		t.Errorf("matching bloom rejected")
+	}
+	if bloomFilter(blooms, []common.Address{common.HexToAddress("0x02")}, nil) {
+		t.Errorf("non-matching address accepted")
+	}
+	if bloomFilter(blooms[:1], nil, [][]common.Hash{{topic}}) {
+		t.Errorf("empty bloom matched")
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
+	"github.com/hashicorp/golang-lru"
+)
+
+const (
+	// chainSideChanSize is the size of channel listening to ChainSideEvent.
+	chainSideChanSize = 64
+
+	// forkBranchRetention is the number of blocks a competing branch is tracked
+	// for after its head fell behind the canonical head.
+	forkBranchRetention = 256
+
+	// maxForkBranches is the maximum number of competing branches tracked.
+	maxForkBranches = 64
+
+	// maxRecentReorgs is the number of most recent reorgs kept for the status.
+	maxRecentReorgs = 64
+
+	// forkWebhookTimeout is the timeout of the alert webhook requests.
+	forkWebhookTimeout = 5 * time.Second
+)
+
+var (
+	forkReorgMeter       = metrics.NewRegisteredMeter("man/fork/reorgs", nil)
+	forkReorgDepthHist   = metrics.NewRegisteredHistogram("man/fork/reorgdepth", nil, metrics.NewExpDecaySample(1028, 0.015))
+	forkMaxReorgGauge    = metrics.NewRegisteredGauge("man/fork/maxdepth", nil)
+	forkBranchGauge      = metrics.NewRegisteredGauge("man/fork/branches", nil)
+	forkSideBlockCounter = metrics.NewRegisteredCounter("man/fork/sideblocks", nil)
+	forkAlertCounter     = metrics.NewRegisteredCounter("man/fork/alerts", nil)
+)
+
+// forkChain is the part of the block chain the fork monitor reads.
+type forkChain interface {
+	CurrentHeader() *types.Header
+	GetHeader(hash common.Hash, number uint64) *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+// ForkBranch is a competing branch seen from the peers or left behind by a reorg.
+type ForkBranch struct {
+	ForkNumber uint64      `json:"forkNumber"` // Last block shared with the canonical chain
+	Head       common.Hash `json:"head"`
+	HeadNumber uint64      `json:"headNumber"`
+	Length     uint64      `json:"length"` // Number of blocks after the fork point
+	FirstSeen  time.Time   `json:"firstSeen"`
+	LastSeen   time.Time   `json:"lastSeen"`
+}
+
+// ReorgRecord describes a switch of the canonical chain to another branch.
+type ReorgRecord struct {
+	Time         time.Time   `json:"time"`
+	OldHead      common.Hash `json:"oldHead"`
+	NewHead      common.Hash `json:"newHead"`
+	CommonNumber uint64      `json:"commonNumber"`
+	Depth        uint64      `json:"depth"` // Number of canonical blocks dropped
+	Added        uint64      `json:"added"`
+}
+
+// ForkStatus is the fork monitoring summary returned by matrix_forkStatus.
+type ForkStatus struct {
+	Head           uint64        `json:"head"`
+	Branches       []ForkBranch  `json:"branches"`
+	Reorgs         uint64        `json:"reorgs"`         // Reorgs since the node started
+	ReorgsLastHour int           `json:"reorgsLastHour"` // Counted among the recent reorgs
+	MaxDepth       uint64        `json:"maxDepth"`
+	AlertDepth     uint64        `json:"alertDepth"`
+	RecentReorgs   []ReorgRecord `json:"recentReorgs"` // Most recent first
+}
+
+// forkAlert is the payload posted to the alert webhook.
+type forkAlert struct {
+	Event string      `json:"event"`
+	Reorg ReorgRecord `json:"reorg"`
+}
+
+// forkMonitor tracks the competing branches of the chain and the reorgs of the
+// canonical chain. Reorgs dropping at least alertDepth blocks raise an alert,
+// logged and posted to the webhook if configured.
+type forkMonitor struct {
+	chain      forkChain
+	alertDepth uint64
+	webhook    string
+	client     *http.Client
+
+	branches map[common.Hash]*ForkBranch // Tracked branches by head hash
+	known    *lru.Cache                  // Side blocks already accounted to a branch
+	reorgs   uint64
+	maxDepth uint64
+	recent   []ReorgRecord
+	lock     sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newForkMonitor(chain forkChain, alertDepth uint64, webhook string) *forkMonitor {
+	known, _ := lru.New(forkBranchRetention * 4)
+	return &forkMonitor{
+		chain:      chain,
+		alertDepth: alertDepth,
+		webhook:    webhook,
+		client:     &http.Client{Timeout: forkWebhookTimeout},
+		branches:   make(map[common.Hash]*ForkBranch),
+		known:      known,
+		quit:       make(chan struct{}),
+	}
+}
+
+// Start begins tracking the side blocks and the reorgs of the chain.
+func (m *forkMonitor) Start(chain *core.BlockChain) {
+	sides := make(chan core.ChainSideEvent, chainSideChanSize)
+	sideSub := chain.SubscribeChainSideEvent(sides)
+	reorgs := make(chan core.ChainReorgEvent, chainEventChanSize)
+	reorgSub := chain.SubscribeChainReorgEvent(reorgs)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer sideSub.Unsubscribe()
+		defer reorgSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-sides:
+				m.trackSideBlock(ev.Block.Header())
+			case ev := <-reorgs:
+				m.trackReorg(ev)
+			case <-sideSub.Err():
+				return
+			case <-reorgSub.Err():
+				return
+			case <-m.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the monitor and waits for the pending alerts.
+func (m *forkMonitor) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// trackSideBlock accounts a block not on the canonical chain to its branch.
+func (m *forkMonitor) trackSideBlock(header *types.Header) {
+	hash, number := header.Hash(), header.Number.Uint64()
+	if m.known.Contains(hash) {
+		return
+	}
+	forkSideBlockCounter.Inc(1)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	m.known.Add(hash, struct{}{})
+	if parent, ok := m.branches[header.ParentHash]; ok {
+		// The branch grows by one block
+		delete(m.branches, header.ParentHash)
+		parent.Head, parent.HeadNumber, parent.LastSeen = hash, number, now
+		parent.Length++
+		m.branches[hash] = parent
+	} else {
+		// Walk back to the canonical chain, the ancestors belong to this branch
+		forkNumber, ok := m.forkPoint(header)
+		if !ok {
+			log.Debug("Fork point of side block not found", "number", number, "hash", hash)
+			return
+		}
+		m.branches[hash] = &ForkBranch{
+			ForkNumber: forkNumber,
+			Head:       hash,
+			HeadNumber: number,
+			Length:     number - forkNumber,
+			FirstSeen:  now,
+			LastSeen:   now,
+		}
+	}
+	m.pruneBranches()
+}
+
+// forkPoint returns the number of the last canonical ancestor of a side block,
+// marking the side ancestors as known.
+func (m *forkMonitor) forkPoint(header *types.Header) (uint64, bool) {
+	for i := 0; i < forkBranchRetention && header.Number.Uint64() > 0; i++ {
+		parent := m.chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		if parent == nil {
+			return 0, false
+		}
+		if canonical := m.chain.GetHeaderByNumber(parent.Number.Uint64()); canonical != nil && canonical.Hash() == parent.Hash() {
+			return parent.Number.Uint64(), true
+		}
+		m.known.Add(parent.Hash(), struct{}{})
+		header = parent
+	}
+	return 0, false
+}
+
+// pruneBranches drops the branches fallen far behind the canonical head and
+// the least recently seen ones above the limit.
+func (m *forkMonitor) pruneBranches() {
+	if current := m.chain.CurrentHeader(); current != nil {
+		head := current.Number.Uint64()
+		for hash, branch := range m.branches {
+			if branch.HeadNumber+forkBranchRetention < head {
+				delete(m.branches, hash)
+			}
+		}
+	}
+	for len(m.branches) > maxForkBranches {
+		var oldest common.Hash
+		for hash, branch := range m.branches {
+			if oldest == (common.Hash{}) || branch.LastSeen.Before(m.branches[oldest].LastSeen) {
+				oldest = hash
+			}
+		}
+		delete(m.branches, oldest)
+	}
+	forkBranchGauge.Update(int64(len(m.branches)))
+}
+
+// trackReorg records a reorg and raises an alert if it is too deep.
+func (m *forkMonitor) trackReorg(ev core.ChainReorgEvent) {
+	record := ReorgRecord{
+		Time:         time.Now(),
+		OldHead:      ev.OldHead.Hash(),
+		NewHead:      ev.NewHead.Hash(),
+		CommonNumber: ev.Common.NumberU64(),
+		Depth:        uint64(ev.Dropped),
+		Added:        uint64(ev.Added),
+	}
+	forkReorgMeter.Mark(1)
+	forkReorgDepthHist.Update(int64(record.Depth))
+
+	m.lock.Lock()
+	m.reorgs++
+	if record.Depth > m.maxDepth {
+		m.maxDepth = record.Depth
+		forkMaxReorgGauge.Update(int64(m.maxDepth))
+	}
+	m.recent = append(m.recent, record)
+	if len(m.recent) > maxRecentReorgs {
+		m.recent = m.recent[len(m.recent)-maxRecentReorgs:]
+	}
+	m.lock.Unlock()
+
+	if m.alertDepth == 0 || record.Depth < m.alertDepth {
+		log.Info("Chain reorg", "common", record.CommonNumber, "drop", record.Depth, "add", record.Added, "newhead", record.NewHead)
+		return
+	}
+	forkAlertCounter.Inc(1)
+	log.Error("CRITICAL: deep chain reorg", "common", record.CommonNumber, "drop", record.Depth, "add", record.Added,
+		"oldhead", record.OldHead, "newhead", record.NewHead, "threshold", m.alertDepth)
+	if m.webhook != "" {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			if err := m.postAlert(forkAlert{Event: "reorg", Reorg: record}); err != nil {
+				log.Warn("Failed to post reorg alert", "url", m.webhook, "err", err)
+			}
+		}()
+	}
+}
+
+// postAlert posts the alert to the webhook as JSON.
+func (m *forkMonitor) postAlert(alert forkAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Post(m.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// status returns the fork monitoring summary.
+func (m *forkMonitor) status() *ForkStatus {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.pruneBranches()
+	status := &ForkStatus{
+		Branches:     make([]ForkBranch, 0, len(m.branches)),
+		Reorgs:       m.reorgs,
+		MaxDepth:     m.maxDepth,
+		AlertDepth:   m.alertDepth,
+		RecentReorgs: make([]ReorgRecord, 0, len(m.recent)),
+	}
+	if current := m.chain.CurrentHeader(); current != nil {
+		status.Head = current.Number.Uint64()
+	}
+	for _, branch := range m.branches {
+		status.Branches = append(status.Branches, *branch)
+	}
+	sort.Slice(status.Branches, func(i, j int) bool { return status.Branches[i].HeadNumber > status.Branches[j].HeadNumber })
+
+	hourAgo := time.Now().Add(-time.Hour)
+	for i := len(m.recent) - 1; i >= 0; i-- {
+		status.RecentReorgs = append(status.RecentReorgs, m.recent[i])
+		if m.recent[i].Time.After(hourAgo) {
+			status.ReorgsLastHour++
+		}
+	}
+	return status
+}
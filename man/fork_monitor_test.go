@@ -0,0 +1,131 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+// testForkChain is a canonical chain of headers with side headers on top.
+type testForkChain struct {
+	canonical []*types.Header
+	headers   map[common.Hash]*types.Header
+}
+
+func newTestForkChain(length int) *testForkChain {
+	chain := &testForkChain{headers: make(map[common.Hash]*types.Header)}
+	parent := common.Hash{}
+	for i := 0; i < length; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent}
+		chain.canonical = append(chain.canonical, header)
+		chain.headers[header.Hash()] = header
+		parent = header.Hash()
+	}
+	return chain
+}
+
+func (c *testForkChain) side(parent *types.Header, extra byte) *types.Header {
+	header := &types.Header{Number: new(big.Int).Add(parent.Number, big.NewInt(1)), ParentHash: parent.Hash(), Extra: []byte{extra}}
+	c.headers[header.Hash()] = header
+	return header
+}
+
+func (c *testForkChain) CurrentHeader() *types.Header { return c.canonical[len(c.canonical)-1] }
+
+func (c *testForkChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.headers[hash]
+}
+
+func (c *testForkChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c.canonical)) {
+		return nil
+	}
+	return c.canonical[number]
+}
+
+func TestForkMonitorBranches(t *testing.T) {
+	chain := newTestForkChain(10)
+	monitor := newForkMonitor(chain, 0, "")
+
+	// A two block branch forking off block 5, announced in order
+	first := chain.side(chain.canonical[5], 1)
+	second := chain.side(first, 1)
+	monitor.trackSideBlock(first)
+	monitor.trackSideBlock(second)
+
+	// A three block branch forking off block 7, announced head first
+	a := chain.side(chain.canonical[7], 2)
+	b := chain.side(a, 2)
+	c := chain.side(b, 2)
+	monitor.trackSideBlock(c)
+	monitor.trackSideBlock(b)
+	monitor.trackSideBlock(a)
+
+	status := monitor.status()
+	if len(status.Branches) != 2 {
+		t.Fatalf("branch count mismatch: have %d, want 2", len(status.Branches))
+	}
+	if branch := status.Branches[0]; branch.Head != c.Hash() || branch.ForkNumber != 7 || branch.Length != 3 {
+		t.Errorf("head first branch mismatch: %+v", branch)
+	}
+	if branch := status.Branches[1]; branch.Head != second.Hash() || branch.ForkNumber != 5 || branch.Length != 2 {
+		t.Errorf("in order branch mismatch: %+v", branch)
+	}
+}
+
+func TestForkMonitorAlert(t *testing.T) {
+	alerts := make(chan forkAlert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert forkAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("invalid alert payload: %v", err)
+		}
+		alerts <- alert
+	}))
+	defer server.Close()
+
+	chain := newTestForkChain(10)
+	monitor := newForkMonitor(chain, 3, server.URL)
+	reorg := func(forkNumber uint64, dropped int) {
+		monitor.trackReorg(core.ChainReorgEvent{
+			OldHead: types.NewBlockWithHeader(chain.side(chain.canonical[forkNumber], 1)),
+			NewHead: types.NewBlockWithHeader(chain.canonical[9]),
+			Common:  types.NewBlockWithHeader(chain.canonical[forkNumber]),
+			Dropped: dropped,
+			Added:   9 - int(forkNumber),
+		})
+	}
+	reorg(8, 1)
+	reorg(5, 4)
+	monitor.Stop()
+
+	select {
+	case alert := <-alerts:
+		if alert.Reorg.Depth != 4 || alert.Reorg.CommonNumber != 5 {
+			t.Errorf("alert mismatch: %+v", alert.Reorg)
+		}
+	default:
+		t.Fatal("no alert posted for the deep reorg")
+	}
+	if len(alerts) != 0 {
+		t.Error("alert posted for the shallow reorg")
+	}
+
+	status := monitor.status()
+	if status.Reorgs != 2 || status.MaxDepth != 4 || status.ReorgsLastHour != 2 {
+		t.Errorf("reorg statistics mismatch: %+v", status)
+	}
+	if len(status.RecentReorgs) != 2 || status.RecentReorgs[0].Depth != 4 {
+		t.Errorf("recent reorgs mismatch: %+v", status.RecentReorgs)
+	}
+}
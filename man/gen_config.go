@@ -7,9 +7,11 @@ package man
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/consensus/blkmanage"
 	"github.com/MatrixAINetwork/go-matrix/consensus/manash"
 	"github.com/MatrixAINetwork/go-matrix/core"
 	"github.com/MatrixAINetwork/go-matrix/man/downloader"
@@ -28,6 +30,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SkipBcVersionCheck      bool `toml:"-"`
 		DatabaseHandles         int  `toml:"-"`
 		DatabaseCache           int
+		DatabaseEngine          string `toml:",omitempty"`
+		TrieCleanCache          int
+		StateRetention          uint64
+		AncientThreshold        uint64
+		DatabaseAncient         string         `toml:",omitempty"`
 		Manerbase               common.Address `toml:",omitempty"`
 		MinerThreads            int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
@@ -36,7 +43,20 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
-		DocRoot                 string `toml:"-"`
+		ParallelExec            bool
+		BroadcastIndex          bool
+		AddressIndex            bool
+		ForkAlertDepth          uint64
+		ForkAlertWebhook        string           `toml:",omitempty"`
+		BridgeContracts         []common.Address `toml:",omitempty"`
+		BridgeEvents            []string         `toml:",omitempty"`
+		BridgeConfirmations     uint64
+		ExternalSigner          string                       `toml:",omitempty"`
+		BLSKeyFile              string                       `toml:",omitempty"`
+		BlockBuilderHooks       []blkmanage.BlockBuilderHook `toml:"-"`
+		DevMode                 bool                         `toml:"-"`
+		DevPeriod               time.Duration                `toml:"-"`
+		DocRoot                 string                       `toml:"-"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -47,6 +67,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.DatabaseEngine = c.DatabaseEngine
+	enc.TrieCleanCache = c.TrieCleanCache
+	enc.StateRetention = c.StateRetention
+	enc.AncientThreshold = c.AncientThreshold
+	enc.DatabaseAncient = c.DatabaseAncient
 	enc.Manerbase = c.Manerbase
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
@@ -55,6 +80,19 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.ParallelExec = c.ParallelExec
+	enc.BroadcastIndex = c.BroadcastIndex
+	enc.AddressIndex = c.AddressIndex
+	enc.ForkAlertDepth = c.ForkAlertDepth
+	enc.ForkAlertWebhook = c.ForkAlertWebhook
+	enc.BridgeContracts = c.BridgeContracts
+	enc.BridgeEvents = c.BridgeEvents
+	enc.BridgeConfirmations = c.BridgeConfirmations
+	enc.ExternalSigner = c.ExternalSigner
+	enc.BLSKeyFile = c.BLSKeyFile
+	enc.BlockBuilderHooks = c.BlockBuilderHooks
+	enc.DevMode = c.DevMode
+	enc.DevPeriod = c.DevPeriod
 	enc.DocRoot = c.DocRoot
 	return &enc, nil
 }
@@ -69,6 +107,11 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SkipBcVersionCheck      *bool `toml:"-"`
 		DatabaseHandles         *int  `toml:"-"`
 		DatabaseCache           *int
+		DatabaseEngine          *string `toml:",omitempty"`
+		TrieCleanCache          *int
+		StateRetention          *uint64
+		AncientThreshold        *uint64
+		DatabaseAncient         *string         `toml:",omitempty"`
 		Manerbase               *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
@@ -77,7 +120,20 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
-		DocRoot                 *string `toml:"-"`
+		ParallelExec            *bool
+		BroadcastIndex          *bool
+		AddressIndex            *bool
+		ForkAlertDepth          *uint64
+		ForkAlertWebhook        *string          `toml:",omitempty"`
+		BridgeContracts         []common.Address `toml:",omitempty"`
+		BridgeEvents            []string         `toml:",omitempty"`
+		BridgeConfirmations     *uint64
+		ExternalSigner          *string                      `toml:",omitempty"`
+		BLSKeyFile              *string                      `toml:",omitempty"`
+		BlockBuilderHooks       []blkmanage.BlockBuilderHook `toml:"-"`
+		DevMode                 *bool                        `toml:"-"`
+		DevPeriod               *time.Duration               `toml:"-"`
+		DocRoot                 *string                      `toml:"-"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -107,6 +163,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.DatabaseEngine != nil {
+		c.DatabaseEngine = *dec.DatabaseEngine
+	}
+	if dec.TrieCleanCache != nil {
+		c.TrieCleanCache = *dec.TrieCleanCache
+	}
+	if dec.StateRetention != nil {
+		c.StateRetention = *dec.StateRetention
+	}
+	if dec.AncientThreshold != nil {
+		c.AncientThreshold = *dec.AncientThreshold
+	}
+	if dec.DatabaseAncient != nil {
+		c.DatabaseAncient = *dec.DatabaseAncient
+	}
 	if dec.Manerbase != nil {
 		c.Manerbase = *dec.Manerbase
 	}
@@ -131,6 +202,45 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.ParallelExec != nil {
+		c.ParallelExec = *dec.ParallelExec
+	}
+	if dec.BroadcastIndex != nil {
+		c.BroadcastIndex = *dec.BroadcastIndex
+	}
+	if dec.AddressIndex != nil {
+		c.AddressIndex = *dec.AddressIndex
+	}
+	if dec.ForkAlertDepth != nil {
+		c.ForkAlertDepth = *dec.ForkAlertDepth
+	}
+	if dec.ForkAlertWebhook != nil {
+		c.ForkAlertWebhook = *dec.ForkAlertWebhook
+	}
+	if dec.BridgeContracts != nil {
+		c.BridgeContracts = dec.BridgeContracts
+	}
+	if dec.BridgeEvents != nil {
+		c.BridgeEvents = dec.BridgeEvents
+	}
+	if dec.BridgeConfirmations != nil {
+		c.BridgeConfirmations = *dec.BridgeConfirmations
+	}
+	if dec.ExternalSigner != nil {
+		c.ExternalSigner = *dec.ExternalSigner
+	}
+	if dec.BLSKeyFile != nil {
+		c.BLSKeyFile = *dec.BLSKeyFile
+	}
+	if dec.BlockBuilderHooks != nil {
+		c.BlockBuilderHooks = dec.BlockBuilderHooks
+	}
+	if dec.DevMode != nil {
+		c.DevMode = *dec.DevMode
+	}
+	if dec.DevPeriod != nil {
+		c.DevPeriod = *dec.DevPeriod
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
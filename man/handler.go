@@ -41,6 +41,10 @@ const (
 	// txChanSize is the size of channel listening to NewTxsEvent.
 	// The number is referenced from the size of tx pool.
 	txChanSize = 4096
+
+	// networkMsgWorkers is the number of network messages delivered to the
+	// transaction pools concurrently.
+	networkMsgWorkers = 16
 )
 
 var (
@@ -65,6 +69,7 @@ type ProtocolManager struct {
 	networkId uint64
 
 	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	snapSync  uint32 // Flag whether snap sync is enabled (gets disabled if we already have blocks)
 	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
 
 	txpool      txPool
@@ -89,6 +94,12 @@ type ProtocolManager struct {
 	quitSync    chan struct{}
 	noMorePeers chan struct{}
 
+	penalties *penaltyTracker      // Scores of peers sending invalid network messages
+	limits    *rateLimiter         // Network and algorithm message rate limits of the peers
+	proofs    *stateProofRetriever // Matrix state proof requests waiting for delivery
+
+	networkMsgSlots chan struct{} // Slots of the network messages being delivered to the transaction pools
+
 	CheckDownloadNum int
 	LastCheckTime    int64
 	LastCheckBlkNum  uint64
@@ -109,26 +120,37 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		blockchain:  blockchain,
 		chainconfig: config,
 		//		peers:       newPeerSet(),
-		Peers:       newPeerSet(),
-		newPeerCh:   make(chan *peer),
-		noMorePeers: make(chan struct{}),
-		txsyncCh:    make(chan *txsync),
-		quitSync:    make(chan struct{}),
-		Msgcenter:   MsgCenter,
+		Peers:           newPeerSet(),
+		newPeerCh:       make(chan *peer),
+		noMorePeers:     make(chan struct{}),
+		txsyncCh:        make(chan *txsync),
+		quitSync:        make(chan struct{}),
+		penalties:       newPenaltyTracker(),
+		limits:          newRateLimiter(),
+		proofs:          newStateProofRetriever(),
+		networkMsgSlots: make(chan struct{}, networkMsgWorkers),
+		Msgcenter:       MsgCenter,
 	}
 	// Figure out whether to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
 		log.Warn("Blockchain not empty, fast sync disabled")
 		mode = downloader.FullSync
 	}
+	if mode == downloader.SnapSync && blockchain.CurrentBlock().NumberU64() > 0 {
+		log.Warn("Blockchain not empty, snap sync disabled")
+		mode = downloader.FullSync
+	}
 	if mode == downloader.FastSync {
 		manager.fastSync = uint32(1)
 	}
+	if mode == downloader.SnapSync {
+		manager.snapSync = uint32(1)
+	}
 	// Initiate a sub-protocol for every implemented version we can handle
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
 		// Skip protocol version if incompatible with the mode of operation
-		if mode == downloader.FastSync && version < man63 {
+		if (mode == downloader.FastSync || mode == downloader.SnapSync) && version < man63 {
 			continue
 		}
 		// Compatible; initialise the sub-protocol
@@ -139,6 +161,11 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 			Length:  ProtocolLengths[i],
 			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
 				peer := manager.newPeer(int(version), p, rw)
+				// Peers speaking man/64 get the network messages batched and compressed.
+				// The batcher is installed before the peer becomes visible to the senders.
+				if peer.netMsgs != nil {
+					p.SetMsgReadWriter(peer.netMsgs)
+				}
 				select {
 				case manager.newPeerCh <- peer:
 					manager.wg.Add(1)
@@ -178,8 +205,8 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		return blockchain.CurrentBlock().NumberU64()
 	}
 	inserter := func(blocks types.Blocks) (int, error) {
-		// If fast sync is running, deny importing weird blocks
-		if atomic.LoadUint32(&manager.fastSync) == 1 {
+		// If fast or snap sync is running, deny importing weird blocks
+		if atomic.LoadUint32(&manager.fastSync) == 1 || atomic.LoadUint32(&manager.snapSync) == 1 {
 			log.Warn("Discarded bad propagated block", "number", blocks[0].Number(), "hash", blocks[0].Hash())
 			return 0, nil
 		}
@@ -199,6 +226,8 @@ func (pm *ProtocolManager) removePeer(id string, flg int) {
 		return
 	}
 	log.Debug("Removing Matrix peer", "peer", id)
+	pm.penalties.expire()
+	pm.limits.forget(id)
 
 	// Unregister the peer from the downloader and Matrix peer set
 	pm.downloader.UnregisterPeer(id, flg)
@@ -465,6 +494,77 @@ func (pm *ProtocolManager) ParseNewBlockData(msg *p2p.Msg) (newBlockData, error)
 
 }
 
+// handleNetworkMsgs delivers the network messages of a peer to the transaction
+// pools, penalizing the peer if they are rejected.
+func (pm *ProtocolManager) handleNetworkMsgs(p *peer, m []*core.MsgStruct) {
+	if pm.penalties.throttled(p.id) {
+		log.Debug("handler", "NetworkMsg throttled", "peer", p.id)
+		networkMsgThrottleMeter.Mark(1)
+		return
+	}
+	if err := checkNetworkMsgs(m); err != nil {
+		networkMsgOversizeMeter.Mark(1)
+		pm.penalizeNetworkMsg(p, err)
+		return
+	}
+	if m = pm.limitNetworkMsgs(p, m); len(m) == 0 {
+		return
+	}
+	addr := p2p.ServerP2p.ConvertIdToAddress(p.ID())
+
+	// Wait for a free slot, stalling the peer's message loop if the pools lag behind
+	select {
+	case pm.networkMsgSlots <- struct{}{}:
+	case <-pm.quitSync:
+		return
+	}
+	go func() {
+		defer func() { <-pm.networkMsgSlots }()
+
+		if err := pm.txpool.ProcessMsg(core.NetworkMsgData{SendAddress: addr, PeerID: p.ID(), Data: m}); err != nil {
+			log.Debug("handler", "NetworkMsg rejected", err, "peer", p.id)
+			markNetworkMsgReject(err)
+			pm.penalizeNetworkMsg(p, err)
+		}
+	}()
+}
+
+// limitNetworkMsgs drops the network messages of a peer exceeding the rate
+// limit of their class.
+func (pm *ProtocolManager) limitNetworkMsgs(p *peer, m []*core.MsgStruct) []*core.MsgStruct {
+	counts := make(map[msgClass]int)
+	for _, msg := range m {
+		if msg != nil {
+			counts[networkMsgClass(msg)]++
+		}
+	}
+	allowed := make(map[msgClass]bool, len(counts))
+	for class, n := range counts {
+		allowed[class] = pm.limits.allow(p.id, class, n)
+	}
+	kept := m[:0]
+	for _, msg := range m {
+		if msg != nil && allowed[networkMsgClass(msg)] {
+			kept = append(kept, msg)
+		}
+	}
+	if dropped := len(m) - len(kept); dropped > 0 {
+		log.Debug("handler", "NetworkMsg rate limited", dropped, "peer", p.id)
+		networkMsgRateLimitMeter.Mark(int64(dropped))
+		pm.penalizeNetworkMsg(p, errNetworkMsgRateLimited)
+	}
+	return kept
+}
+
+// penalizeNetworkMsg charges a peer for a rejected network message, dropping it
+// once it crossed the disconnect threshold.
+func (pm *ProtocolManager) penalizeNetworkMsg(p *peer, err error) {
+	if pm.penalties.penalize(p.id, err) {
+		log.Warn("Dropping peer sending invalid network messages", "peer", p.id, "err", err)
+		pm.removePeer(p.id, 0)
+	}
+}
+
 // handleMsg is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
 func (pm *ProtocolManager) handleMsg(p *peer) error {
@@ -602,6 +702,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			err := pm.downloader.DeliverHeaders(p.id, headers)
 			if err != nil {
 				log.Debug("Failed to deliver headers", "err", err)
+				p.MarkInvalid()
+			} else {
+				p.MarkBlocks(len(headers))
 			}
 		}
 
@@ -668,6 +771,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			err := pm.downloader.DeliverBodies(p.id, transCrBlock, uncles)
 			if err != nil {
 				log.Debug("Failed to deliver bodies", "err", err)
+				p.MarkInvalid()
+			} else {
+				p.MarkBlocks(len(transCrBlock))
 			}
 		}
 
@@ -709,6 +815,41 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			p.Log().Debug("Failed to deliver node state data", "err", err)
 		}
 
+	case p.version >= man65 && msg.Code == GetStateChunksMsg:
+		// Decode the state chunk retrieval message
+		var roots []common.Hash
+		if err := msg.Decode(&roots); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p.SendStateChunks(pm.serveStateChunks(roots))
+
+	case p.version >= man65 && msg.Code == StateChunksMsg:
+		// A batch of state chunks arrived to one of our previous requests. The
+		// nodes are hash verified one by one by the state sync like node data.
+		var data [][]byte
+		if err := msg.Decode(&data); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverNodeData(p.id, data); err != nil {
+			p.Log().Debug("Failed to deliver state chunks", "err", err)
+		}
+
+	case p.version >= man64 && msg.Code == GetMatrixStateProofsMsg:
+		// Decode the matrix state proof retrieval message
+		var reqs []*matrixStateProofReq
+		if err := msg.Decode(&reqs); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p.SendMatrixStateProofs(pm.serveMatrixStateProofs(reqs))
+
+	case p.version >= man64 && msg.Code == MatrixStateProofsMsg:
+		// A batch of matrix state proofs arrived to one of our previous requests
+		var proofs []*matrixStateProofRes
+		if err := msg.Decode(&proofs); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		pm.proofs.deliver(proofs)
+
 	case p.version >= man63 && msg.Code == GetReceiptsMsg:
 		// Decode the retrieval message
 		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
@@ -807,6 +948,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		//if(pm.fetcher.MaxChainHeight-currentBlock.NumberU64() < 32) && (request.Block.NumberU64() > currentBlock.NumberU64()) {//去掉重复高度插入判断
 		if dist := int64(recvBlockNum) - int64(curBlockNum); dist > -7 && dist < 64 {
 			pm.fetcher.Enqueue(p.id, request.Block)
+			p.MarkBlocks(1)
 			flg = 1
 		}
 		p.Log().Trace("download fetch handleMsg receive NewBlockMsg", "Recvnumber", recvBlockNum, "curBlockNum", curBlockNum, "request.TD", request.TD, "MaxChainHeight", pm.fetcher.MaxChainHeight, "flg", flg)
@@ -921,19 +1063,36 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			log.Info("==tcp tx hash", "from", tx.From().String(), "tx.Nonce", tx.Nonce(), "hash", hash.String(), "sender addr", p2p.ServerP2p.ConvertIdToAddress(p.ID()).String(),
 				"node id", p.ID().String())
 		}
+		p.MarkTxs(len(txs))
 		pm.txpool.AddRemotes(txs)
 	case msg.Code == common.NetworkMsg:
+		if msg.Size > maxNetworkMsgSize {
+			return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, maxNetworkMsgSize)
+		}
 		var m []*core.MsgStruct
 		if err := msg.Decode(&m); err != nil {
 			log.Info("handler", "mag NetworkMsg err", err)
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		log.Info("handler", "msg NetworkMsg ", "ProcessMsg")
+		pm.handleNetworkMsgs(p, m)
 
-		addr := p2p.ServerP2p.ConvertIdToAddress(p.ID())
-		go pm.txpool.ProcessMsg(core.NetworkMsgData{SendAddress: addr, Data: m})
+	case p.version >= man64 && msg.Code == NetworkBatchMsg:
+		m, err := decodeNetworkBatch(msg)
+		if err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		pm.handleNetworkMsgs(p, m)
 
 	case msg.Code == common.AlgorithmMsg:
+		if msg.Size > maxNetworkMsgSize {
+			return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, maxNetworkMsgSize)
+		}
+		if !pm.limits.allow(p.id, classConsensus, 1) {
+			networkMsgRateLimitMeter.Mark(1)
+			pm.penalizeNetworkMsg(p, errNetworkMsgRateLimited)
+			return nil
+		}
 		var m msgsend.NetData
 		if err := msg.Decode(&m); err != nil {
 			log.Error("algorithm message", "error", err)
@@ -5,6 +5,7 @@
 package man
 
 import (
+	"github.com/MatrixAINetwork/go-matrix/core"
 	"github.com/MatrixAINetwork/go-matrix/metrics"
 	"github.com/MatrixAINetwork/go-matrix/p2p"
 )
@@ -42,8 +43,35 @@ var (
 	miscInTrafficMeter        = metrics.NewRegisteredMeter("man/misc/in/traffic", nil)
 	miscOutPacketsMeter       = metrics.NewRegisteredMeter("man/misc/out/packets", nil)
 	miscOutTrafficMeter       = metrics.NewRegisteredMeter("man/misc/out/traffic", nil)
+
+	// Network messages rejected by the transaction pools, by reason
+	rejectBroadcastDuplicateMeter    = metrics.NewRegisteredMeter("man/reject/broadcast/duplicate", nil)
+	rejectBroadcastIntervalMeter     = metrics.NewRegisteredMeter("man/reject/broadcast/interval", nil)
+	rejectBroadcastUnauthorizedMeter = metrics.NewRegisteredMeter("man/reject/broadcast/unauthorized", nil)
+	rejectBroadcastUnknownTypeMeter  = metrics.NewRegisteredMeter("man/reject/broadcast/unknowntype", nil)
+	rejectNetworkMsgOtherMeter       = metrics.NewRegisteredMeter("man/reject/other", nil)
+	networkMsgThrottleMeter          = metrics.NewRegisteredMeter("man/reject/throttled", nil)
+	networkMsgRateLimitMeter         = metrics.NewRegisteredMeter("man/reject/ratelimited", nil)
+	networkMsgOversizeMeter          = metrics.NewRegisteredMeter("man/reject/oversized", nil)
 )
 
+// markNetworkMsgReject accounts a network message rejected by the transaction
+// pools to the meter of its reason.
+func markNetworkMsgReject(err error) {
+	switch err {
+	case core.ErrDuplicateBroadcastTx:
+		rejectBroadcastDuplicateMeter.Mark(1)
+	case core.ErrWrongBroadcastInterval:
+		rejectBroadcastIntervalMeter.Mark(1)
+	case core.ErrUnauthorizedBroadcaster:
+		rejectBroadcastUnauthorizedMeter.Mark(1)
+	case core.ErrUnknownBroadcastType:
+		rejectBroadcastUnknownTypeMeter.Mark(1)
+	default:
+		rejectNetworkMsgOtherMeter.Mark(1)
+	}
+}
+
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
 // accumulating the above defined metrics based on the data stream contents.
 type meteredMsgReadWriter struct {
@@ -0,0 +1,174 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
+	"github.com/MatrixAINetwork/go-matrix/p2p"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+	"github.com/golang/snappy"
+)
+
+const (
+	// networkMsgBatchDelay is the time network messages are held back to be
+	// batched with the ones following them.
+	networkMsgBatchDelay = 50 * time.Millisecond
+
+	// networkMsgBatchSize is the number of network messages flushing a batch
+	// without waiting for the delay.
+	networkMsgBatchSize = 256
+
+	// maxQueuedNetworkMsgs is the number of network messages queued for a peer
+	// before the new ones are dropped.
+	maxQueuedNetworkMsgs = 4096
+)
+
+var (
+	errNetworkBatchTooLarge = errors.New("network message batch too large")
+	errBatcherClosed        = errors.New("network message batcher closed")
+	errNetworkQueueFull     = errors.New("network message queue full")
+
+	networkBatchOutMeter        = metrics.NewRegisteredMeter("man/netmsg/batch/out", nil)
+	networkBatchRawMeter        = metrics.NewRegisteredMeter("man/netmsg/batch/raw", nil)
+	networkBatchCompressedMeter = metrics.NewRegisteredMeter("man/netmsg/batch/compressed", nil)
+	networkBatchDropMeter       = metrics.NewRegisteredMeter("man/netmsg/batch/drop", nil)
+)
+
+// networkMsgBatcher takes over the delivery of the common.NetworkMsg messages
+// sent through the p2p level senders to a man/64 peer: instead of writing every
+// message on its own, they are queued for a short while and written as a single
+// snappy compressed NetworkBatchMsg. All the other messages pass through.
+type networkMsgBatcher struct {
+	p2p.MsgReadWriter
+
+	queue chan *core.MsgStruct
+	term  <-chan struct{}
+}
+
+func newNetworkMsgBatcher(rw p2p.MsgReadWriter, term <-chan struct{}) *networkMsgBatcher {
+	return &networkMsgBatcher{
+		MsgReadWriter: rw,
+		queue:         make(chan *core.MsgStruct, maxQueuedNetworkMsgs),
+		term:          term,
+	}
+}
+
+// WriteMsg queues the network messages for batching and writes the others.
+// Messages not fitting into the queue are dropped and reported to the sender.
+func (b *networkMsgBatcher) WriteMsg(msg p2p.Msg) error {
+	if msg.Code != common.NetworkMsg {
+		return b.MsgReadWriter.WriteMsg(msg)
+	}
+	var msgs []*core.MsgStruct
+	if err := msg.Decode(&msgs); err != nil {
+		return err
+	}
+	dropped := 0
+	for _, m := range msgs {
+		select {
+		case b.queue <- m:
+		case <-b.term:
+			return errBatcherClosed
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		networkBatchDropMeter.Mark(int64(dropped))
+		log.Warn("Dropped network messages, peer queue full", "dropped", dropped, "total", len(msgs))
+		return errNetworkQueueFull
+	}
+	return nil
+}
+
+// loop writes the queued network messages in batches until the peer terminates.
+func (b *networkMsgBatcher) loop() {
+	var (
+		batch []*core.MsgStruct
+		timer = time.NewTimer(0)
+	)
+	<-timer.C
+	defer timer.Stop()
+
+	for {
+		select {
+		case m := <-b.queue:
+			if len(batch) == 0 {
+				timer.Reset(networkMsgBatchDelay)
+			}
+			batch = append(batch, m)
+			if len(batch) < networkMsgBatchSize {
+				continue
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+		case <-timer.C:
+		case <-b.term:
+			return
+		}
+		if err := b.flush(batch); err != nil {
+			log.Debug("Failed to send network message batch", "count", len(batch), "err", err)
+			return
+		}
+		batch = batch[:0]
+	}
+}
+
+// flush writes a batch of network messages as a compressed NetworkBatchMsg.
+func (b *networkMsgBatcher) flush(batch []*core.MsgStruct) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	data, err := encodeNetworkBatch(batch)
+	if err != nil {
+		return err
+	}
+	networkBatchOutMeter.Mark(1)
+	return p2p.Send(b.MsgReadWriter, NetworkBatchMsg, data)
+}
+
+// encodeNetworkBatch compresses the RLP encoding of a batch of network messages.
+func encodeNetworkBatch(batch []*core.MsgStruct) ([]byte, error) {
+	raw, err := rlp.EncodeToBytes(batch)
+	if err != nil {
+		return nil, err
+	}
+	data := snappy.Encode(nil, raw)
+
+	networkBatchRawMeter.Mark(int64(len(raw)))
+	networkBatchCompressedMeter.Mark(int64(len(data)))
+	return data, nil
+}
+
+// decodeNetworkBatch reads the network messages of a NetworkBatchMsg.
+func decodeNetworkBatch(msg p2p.Msg) ([]*core.MsgStruct, error) {
+	var data []byte
+	if err := msg.Decode(&data); err != nil {
+		return nil, err
+	}
+	size, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxNetworkMsgSize {
+		return nil, errNetworkBatchTooLarge
+	}
+	raw, err := snappy.Decode(nil, data)
+	if err != nil {
+		return nil, err
+	}
+	var batch []*core.MsgStruct
+	if err := rlp.DecodeBytes(raw, &batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/p2p"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+func TestNetworkBatchRoundTrip(t *testing.T) {
+	var batch []*core.MsgStruct
+	for i := 0; i < 100; i++ {
+		batch = append(batch, &core.MsgStruct{
+			Msgtype:    uint32(i % 3),
+			SendAddr:   common.HexToAddress("0x01"),
+			MsgData:    bytes.Repeat([]byte(`{"heartbeat":"0x00"}`), 4),
+			TxpoolType: 1,
+		})
+	}
+	data, err := encodeNetworkBatch(batch)
+	if err != nil {
+		t.Fatalf("failed to encode batch: %v", err)
+	}
+	raw, _ := rlp.EncodeToBytes(batch)
+	if len(data) >= len(raw) {
+		t.Errorf("batch not compressed: %d >= %d bytes", len(data), len(raw))
+	}
+	size, r, _ := rlp.EncodeToReader(data)
+	decoded, err := decodeNetworkBatch(p2p.Msg{Code: NetworkBatchMsg, Size: uint32(size), Payload: r})
+	if err != nil {
+		t.Fatalf("failed to decode batch: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, batch) {
+		t.Errorf("decoded batch mismatch")
+	}
+}
+
+func TestNetworkMsgBatcher(t *testing.T) {
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	defer net.Close()
+
+	term := make(chan struct{})
+	defer close(term)
+
+	batcher := newNetworkMsgBatcher(app, term)
+	go batcher.loop()
+
+	// Network messages sent separately arrive as a single batch
+	for i := 0; i < 3; i++ {
+		msg := &core.MsgStruct{Msgtype: uint32(i), MsgData: []byte("{}")}
+		if err := p2p.Send(batcher, common.NetworkMsg, []interface{}{msg}); err != nil {
+			t.Fatalf("failed to send network message: %v", err)
+		}
+	}
+	done := make(chan []*core.MsgStruct)
+	go func() {
+		msg, err := net.ReadMsg()
+		if err != nil || msg.Code != NetworkBatchMsg {
+			t.Errorf("unexpected message: code %d, err %v", msg.Code, err)
+			close(done)
+			return
+		}
+		batch, err := decodeNetworkBatch(msg)
+		if err != nil {
+			t.Errorf("failed to decode batch: %v", err)
+		}
+		done <- batch
+	}()
+	select {
+	case batch := <-done:
+		if len(batch) != 3 {
+			t.Errorf("batch size mismatch: have %d, want 3", len(batch))
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("batch not flushed")
+	}
+}
+
+func TestNetworkMsgBatcherQueueFull(t *testing.T) {
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	defer net.Close()
+
+	term := make(chan struct{})
+	defer close(term)
+
+	// Without a running loop the queue fills up and the overflow is reported
+	batcher := newNetworkMsgBatcher(app, term)
+	msgs := make([]interface{}, maxQueuedNetworkMsgs+1)
+	for i := range msgs {
+		msgs[i] = &core.MsgStruct{Msgtype: uint32(i), MsgData: []byte("{}")}
+	}
+	if err := p2p.Send(batcher, common.NetworkMsg, msgs); err != errNetworkQueueFull {
+		t.Fatalf("error mismatch: have %v, want %v", err, errNetworkQueueFull)
+	}
+	if len(batcher.queue) != maxQueuedNetworkMsgs {
+		t.Errorf("queued messages mismatch: have %d, want %d", len(batcher.queue), maxQueuedNetworkMsgs)
+	}
+}
@@ -93,11 +93,12 @@ type peer struct {
 	queuedProps chan *propEvent              // Queue of blocks to broadcast to the peer
 	queuedAnns  chan *types.Block            // Queue of blocks to announce to the peer
 	term        chan struct{}                // Termination channel to stop the broadcaster
+	netMsgs     *networkMsgBatcher           // Batcher of the network messages, nil before man/64
 	Msgcenter   *mc.Center
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
-	return &peer{
+	mp := &peer{
 		Peer: p,
 		//		rw:          rw,
 		rw:          rw,
@@ -110,6 +111,10 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 		queuedAnns:  make(chan *types.Block, maxQueuedAnns),
 		term:        make(chan struct{}),
 	}
+	if version >= man64 {
+		mp.netMsgs = newNetworkMsgBatcher(rw, mp.term)
+	}
+	return mp
 }
 
 // broadcast is a write loop that multiplexes block propagations, announcements
@@ -348,6 +353,18 @@ func (p *peer) SendNodeData(data [][]byte) error {
 	return p2p.Send(p.rw, NodeDataMsg, data)
 }
 
+// SendStateChunks sends the nodes of a batch of state chunks, corresponding to
+// the roots requested.
+func (p *peer) SendStateChunks(data [][]byte) error {
+	return p2p.Send(p.rw, StateChunksMsg, data)
+}
+
+// SendMatrixStateProofs sends a batch of matrix state proofs, corresponding to
+// the entries requested.
+func (p *peer) SendMatrixStateProofs(proofs []*matrixStateProofRes) error {
+	return p2p.Send(p.rw, MatrixStateProofsMsg, proofs)
+}
+
 // SendReceiptsRLP sends a batch of transaction receipts, corresponding to the
 // ones requested from an already RLP encoded format.
 func (p *peer) SendReceiptsRLP(receipts []rlp.RawValue) error {
@@ -394,6 +411,20 @@ func (p *peer) RequestNodeData(hashes []common.Hash) error {
 	return p2p.Send(p.rw, GetNodeDataMsg, hashes)
 }
 
+// RequestStateChunks fetches the state trie nodes below the specified roots in
+// breadth first chunks.
+func (p *peer) RequestStateChunks(roots []common.Hash) error {
+	p.Log().Debug("peer Fetching batch of state chunks", "len count", len(roots))
+	return p2p.Send(p.rw, GetStateChunksMsg, roots)
+}
+
+// RequestMatrixStateProofs fetches a batch of matrix state proofs from a remote
+// node, corresponding to the specified blocks and entries.
+func (p *peer) RequestMatrixStateProofs(reqs []*matrixStateProofReq) error {
+	p.Log().Debug("peer Fetching batch of matrix state proofs", "len count", len(reqs))
+	return p2p.Send(p.rw, GetMatrixStateProofsMsg, reqs)
+}
+
 // RequestReceipts fetches a batch of transaction receipts from a remote node.
 func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	p.Log().Debug("peer Fetching batch of receipts[request receipts]", "len count", len(hashes))
@@ -566,6 +597,9 @@ func (ps *peerSet) Register(p *peer) error {
 	}
 	ps.peers[p.id] = p
 	go p.broadcast()
+	if p.netMsgs != nil {
+		go p.netMsgs.loop()
+	}
 
 	return nil
 }
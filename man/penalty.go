@@ -0,0 +1,128 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/core"
+)
+
+const (
+	penaltyDecayInterval   = 10 * time.Second // Time needed for a peer's penalty score to decay by one point
+	penaltyThrottleScore   = 20               // Score from which network messages of a peer are dropped
+	penaltyDisconnectScore = 60               // Score from which a peer is disconnected
+)
+
+// penaltyWeight returns the score a peer is charged for a network message the
+// transaction pools rejected with the given error. Only messages which could
+// not have been produced by an honest node are charged heavily, rejections
+// depending on local pool policy or chain state are charged leniently.
+func penaltyWeight(err error) int {
+	switch err.(type) {
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		// Undecodable payloads
+		return 10
+	}
+	switch err {
+	case core.ErrInvalidSender:
+		// Unsigned or forged transactions
+		return 10
+	case core.ErrUnauthorizedBroadcaster, core.ErrUnknownBroadcastType, errNetworkMsgTooLarge,
+		core.ErrIntrinsicGas, core.ErrGasLimit, core.ErrNegativeValue, core.ErrTXWrongful:
+		// Well formed but invalid, the peer should have checked before relaying
+		return 5
+	case errNetworkMsgRateLimited:
+		// Bursts may be legit, only sustained floods should get the peer dropped
+		return 2
+	default:
+		// Could be caused by resends, full pools, a slightly lagging chain or the
+		// local policy (address filters, replacement and size limits), be lenient
+		return 1
+	}
+}
+
+// peerPenalty is the decaying misbehaviour score of a single peer.
+type peerPenalty struct {
+	score   int
+	updated time.Time
+}
+
+// decay lowers the score by the time passed since the last update.
+func (p *peerPenalty) decay(now time.Time) {
+	if points := int(now.Sub(p.updated) / penaltyDecayInterval); points > 0 {
+		p.score -= points
+		if p.score < 0 {
+			p.score = 0
+		}
+		p.updated = p.updated.Add(time.Duration(points) * penaltyDecayInterval)
+	}
+}
+
+// penaltyTracker accounts the network messages of every peer which got rejected
+// by the transaction pools, so that peers repeatedly sending malformed or invalid
+// broadcast transactions get throttled and eventually disconnected.
+type penaltyTracker struct {
+	peers map[string]*peerPenalty
+	lock  sync.Mutex
+}
+
+// newPenaltyTracker creates an empty peer penalty tracker.
+func newPenaltyTracker() *penaltyTracker {
+	return &penaltyTracker{
+		peers: make(map[string]*peerPenalty),
+	}
+}
+
+// penalize charges the peer for a rejected network message and reports whether
+// it crossed the disconnect threshold.
+func (t *penaltyTracker) penalize(id string, err error) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := time.Now()
+	penalty, ok := t.peers[id]
+	if !ok {
+		penalty = &peerPenalty{updated: now}
+		t.peers[id] = penalty
+	}
+	penalty.decay(now)
+	penalty.score += penaltyWeight(err)
+	return penalty.score >= penaltyDisconnectScore
+}
+
+// throttled reports whether network messages of the peer should be dropped.
+func (t *penaltyTracker) throttled(id string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	penalty, ok := t.peers[id]
+	if !ok {
+		return false
+	}
+	penalty.decay(time.Now())
+	if penalty.score == 0 {
+		delete(t.peers, id)
+		return false
+	}
+	return penalty.score >= penaltyThrottleScore
+}
+
+// expire drops the scores which fully decayed, called when a peer disconnects.
+// Scores of disconnected peers are otherwise kept, so that reconnecting does not
+// reset them.
+func (t *penaltyTracker) expire() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := time.Now()
+	for id, penalty := range t.peers {
+		if penalty.decay(now); penalty.score == 0 {
+			delete(t.peers, id)
+		}
+	}
+}
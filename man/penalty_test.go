@@ -0,0 +1,81 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/core"
+)
+
+// Tests that peers sending invalid network messages get throttled first, then
+// disconnected, and that their score decays over time.
+func TestPenaltyTracker(t *testing.T) {
+	tracker := newPenaltyTracker()
+	malformed := core.ErrInvalidSender
+
+	for i := 0; i < penaltyThrottleScore/penaltyWeight(malformed); i++ {
+		if tracker.throttled("peer") {
+			t.Fatalf("peer throttled after %d malformed messages", i)
+		}
+		if tracker.penalize("peer", malformed) {
+			t.Fatalf("peer dropped after %d malformed messages", i+1)
+		}
+	}
+	if !tracker.throttled("peer") {
+		t.Fatalf("peer not throttled after crossing the threshold")
+	}
+	// Lenient rejections should not affect other peers nor drop them quickly
+	if tracker.penalize("other", core.ErrDuplicateBroadcastTx) || tracker.throttled("other") {
+		t.Fatalf("duplicate broadcast penalized too harshly")
+	}
+	// Decay the score below the throttle threshold
+	tracker.peers["peer"].updated = time.Now().Add(-penaltyDecayInterval * penaltyThrottleScore)
+	if tracker.throttled("peer") {
+		t.Fatalf("peer still throttled after score decay")
+	}
+	for !tracker.penalize("peer", malformed) {
+	}
+	// Disconnecting and reconnecting must not reset the score
+	tracker.expire()
+	if !tracker.throttled("peer") {
+		t.Fatalf("reconnected peer not throttled")
+	}
+	tracker.peers["peer"].updated = time.Now().Add(-penaltyDecayInterval * penaltyDisconnectScore * 2)
+	tracker.expire()
+	if _, ok := tracker.peers["peer"]; ok {
+		t.Fatalf("decayed score not expired")
+	}
+}
+
+// Tests that rejections are charged by severity, and that only undecodable or
+// unsigned messages get the heavy weight.
+func TestPenaltyWeight(t *testing.T) {
+	syntaxErr := json.Unmarshal([]byte("{invalid"), new(map[string]interface{}))
+	typeErr := json.Unmarshal([]byte(`"string"`), new(uint64))
+	lenient := []error{core.ErrDuplicateBroadcastTx, core.ErrKnownTransaction, core.ErrNonceTooLow, core.ErrTXPoolFull,
+		core.ErrAddressFiltered, core.ErrReplaceNumbered, core.ErrReplaceRateLimited, core.ErrOversizedData, errors.New("unknown")}
+	invalid := []error{core.ErrUnauthorizedBroadcaster, core.ErrIntrinsicGas}
+	malformed := []error{core.ErrInvalidSender, syntaxErr, typeErr}
+
+	for _, err := range lenient {
+		if weight := penaltyWeight(err); weight != 1 {
+			t.Errorf("lenient rejection %q weighs %d, want 1", err, weight)
+		}
+	}
+	for _, err := range invalid {
+		if weight := penaltyWeight(err); weight <= 1 || weight >= 10 {
+			t.Errorf("invalid transaction %q weighs %d", err, weight)
+		}
+	}
+	for _, err := range malformed {
+		if weight := penaltyWeight(err); weight != 10 {
+			t.Errorf("malformed message %q weighs %d, want 10", err, weight)
+		}
+	}
+}
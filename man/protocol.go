@@ -11,6 +11,7 @@ import (
 
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/event"
 	"github.com/MatrixAINetwork/go-matrix/rlp"
@@ -20,16 +21,18 @@ import (
 const (
 	man62 = 62
 	man63 = 63
+	man64 = 64
+	man65 = 65
 )
 
 // ProtocolName is the official short name of the protocol used during capability negotiation.
 var ProtocolName = "man"
 
 // ProtocolVersions are the upported versions of the man protocol (first is primary).
-var ProtocolVersions = []uint{man63, man62}
+var ProtocolVersions = []uint{man65, man64, man63, man62}
 
 // ProtocolLengths are the number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{21, 8}
+var ProtocolLengths = []uint64{26, 24, 21, 8}
 
 const ProtocolMaxMsgSize = 20 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -50,6 +53,15 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+
+	// Protocol messages belonging to man/64, following the common message codes
+	GetMatrixStateProofsMsg = 0x15 // Matrix state proofs for light clients
+	MatrixStateProofsMsg    = 0x16
+	NetworkBatchMsg         = 0x17 // Snappy compressed batch of common.NetworkMsg messages
+
+	// Protocol messages belonging to man/65
+	GetStateChunksMsg = 0x18 // Breadth first chunks of the state tries below the requested nodes
+	StateChunksMsg    = 0x19
 )
 
 type errCode int
@@ -95,8 +107,9 @@ type txPool interface {
 	// NewTxsEvent and send events to the given channel.
 	SubscribeNewTxsEvent(chan core.NewTxsEvent) event.Subscription
 
-	//
-	ProcessMsg(m core.NetworkMsgData)
+	// ProcessMsg should handle a network message addressed to the pools,
+	// returning the reason if its content was rejected.
+	ProcessMsg(m core.NetworkMsgData) error
 }
 
 // statusData is the network packet for the status message.
@@ -196,3 +209,17 @@ type blockBody struct {
 
 // blockBodiesData is the network packet for block content distribution.
 type blockBodiesData []*blockBody
+
+// matrixStateProofReq is a request for the proof of a matrix state entry in the
+// state of a given block.
+type matrixStateProofReq struct {
+	Block common.Hash // Hash of the block whose state to prove against
+	Key   common.Hash // Hash of the matrix state entry
+}
+
+// matrixStateProofRes is the network packet answering a matrix state proof request.
+type matrixStateProofRes struct {
+	Block common.Hash
+	Key   common.Hash
+	Proof *state.MatrixDataProof
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+const (
+	// maxNetworkMsgSize is the maximum encoded size of a network or algorithm
+	// message, well below the protocol limit as their payloads are JSON decoded.
+	maxNetworkMsgSize = 8 * 1024 * 1024
+
+	// maxNetworkMsgEntries is the maximum number of entries of a network message.
+	maxNetworkMsgEntries = 1024
+
+	// maxNetworkMsgDataSize is the maximum size of the JSON payload of a single
+	// network message entry.
+	maxNetworkMsgDataSize = 4 * 1024 * 1024
+)
+
+var (
+	errNetworkMsgTooLarge    = errors.New("network message too large")
+	errNetworkMsgRateLimited = errors.New("network message rate limit exceeded")
+)
+
+// msgClass groups the messages sharing a rate limit.
+type msgClass int
+
+const (
+	classBroadcast msgClass = iota // Broadcast transactions and their recovery requests
+	classTxPool                    // Transaction pool network messages
+	classConsensus                 // Leader and consensus algorithm messages
+)
+
+// msgRate is the sustained rate and burst of messages allowed per peer.
+type msgRate struct {
+	perSecond float64
+	burst     float64
+}
+
+var msgRates = map[msgClass]msgRate{
+	classBroadcast: {perSecond: 10, burst: 50},
+	classTxPool:    {perSecond: 200, burst: 1000},
+	classConsensus: {perSecond: 100, burst: 500},
+}
+
+// networkMsgClass returns the rate limit class of a network message entry.
+func networkMsgClass(m *core.MsgStruct) msgClass {
+	if m.TxpoolType == types.BroadCastTxIndex {
+		return classBroadcast
+	}
+	return classTxPool
+}
+
+// checkNetworkMsgs enforces the size limits of a network message before its
+// entries get decoded.
+func checkNetworkMsgs(msgs []*core.MsgStruct) error {
+	if len(msgs) > maxNetworkMsgEntries {
+		return errNetworkMsgTooLarge
+	}
+	for _, m := range msgs {
+		if m != nil && len(m.MsgData) > maxNetworkMsgDataSize {
+			return errNetworkMsgTooLarge
+		}
+	}
+	return nil
+}
+
+// tokenBucket refills at the rate of its class up to the burst size.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take refills the bucket by the time passed and takes n tokens if available.
+func (b *tokenBucket) take(rate msgRate, n float64, now time.Time) bool {
+	b.tokens += now.Sub(b.updated).Seconds() * rate.perSecond
+	if b.tokens > rate.burst {
+		b.tokens = rate.burst
+	}
+	b.updated = now
+	if b.tokens < n {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+// rateLimiter keeps the token buckets of every peer, so that a single peer
+// can't saturate the message decoding nor keep the pools locked.
+type rateLimiter struct {
+	peers map[string]map[msgClass]*tokenBucket
+	lock  sync.Mutex
+}
+
+// newRateLimiter creates an empty per peer rate limiter.
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		peers: make(map[string]map[msgClass]*tokenBucket),
+	}
+}
+
+// allow reports whether the peer may send n more messages of the class.
+func (l *rateLimiter) allow(id string, class msgClass, n int) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	buckets, ok := l.peers[id]
+	if !ok {
+		buckets = make(map[msgClass]*tokenBucket)
+		l.peers[id] = buckets
+	}
+	bucket, ok := buckets[class]
+	if !ok {
+		bucket = &tokenBucket{tokens: msgRates[class].burst, updated: now}
+		buckets[class] = bucket
+	}
+	return bucket.take(msgRates[class], float64(n), now)
+}
+
+// forget drops the buckets of a peer, called when the peer disconnects.
+func (l *rateLimiter) forget(id string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	delete(l.peers, id)
+}
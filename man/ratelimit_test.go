@@ -0,0 +1,67 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/core"
+)
+
+// Tests that the token buckets allow bursts up to their size and then refill at
+// the sustained rate.
+func TestTokenBucket(t *testing.T) {
+	var (
+		rate   = msgRate{perSecond: 10, burst: 50}
+		now    = time.Now()
+		bucket = &tokenBucket{tokens: rate.burst, updated: now}
+	)
+	if !bucket.take(rate, 50, now) {
+		t.Fatalf("burst refused")
+	}
+	if bucket.take(rate, 1, now) {
+		t.Fatalf("message allowed over the burst")
+	}
+	if !bucket.take(rate, 5, now.Add(500*time.Millisecond)) {
+		t.Fatalf("message refused after refill")
+	}
+	// The bucket never holds more than the burst size
+	if bucket.take(rate, 51, now.Add(time.Hour)) {
+		t.Fatalf("message allowed over the burst after a long idle time")
+	}
+}
+
+// Tests that the rate limits are tracked per peer and per message class.
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter()
+	burst := int(msgRates[classBroadcast].burst)
+
+	if !limiter.allow("peer", classBroadcast, burst) {
+		t.Fatalf("burst refused")
+	}
+	if limiter.allow("peer", classBroadcast, burst) {
+		t.Fatalf("flood allowed")
+	}
+	if !limiter.allow("peer", classTxPool, 1) || !limiter.allow("other", classBroadcast, 1) {
+		t.Fatalf("unrelated limit exhausted")
+	}
+	limiter.forget("peer")
+	if !limiter.allow("peer", classBroadcast, burst) {
+		t.Fatalf("limit kept after the peer was forgotten")
+	}
+}
+
+func TestCheckNetworkMsgs(t *testing.T) {
+	if err := checkNetworkMsgs([]*core.MsgStruct{{MsgData: make([]byte, maxNetworkMsgDataSize)}, nil}); err != nil {
+		t.Errorf("valid message rejected: %v", err)
+	}
+	if err := checkNetworkMsgs([]*core.MsgStruct{{MsgData: make([]byte, maxNetworkMsgDataSize+1)}}); err != errNetworkMsgTooLarge {
+		t.Errorf("oversized entry error mismatch: have %v, want %v", err, errNetworkMsgTooLarge)
+	}
+	if err := checkNetworkMsgs(make([]*core.MsgStruct, maxNetworkMsgEntries+1)); err != errNetworkMsgTooLarge {
+		t.Errorf("too many entries error mismatch: have %v, want %v", err, errNetworkMsgTooLarge)
+	}
+}
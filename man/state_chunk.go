@@ -0,0 +1,32 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/man/downloader"
+	"github.com/MatrixAINetwork/go-matrix/trie"
+)
+
+// serveStateChunks assembles the breadth first chunks of the state tries below
+// the requested roots, within the node and size limits of a single response.
+// Roots not available locally are skipped.
+func (pm *ProtocolManager) serveStateChunks(roots []common.Hash) [][]byte {
+	var (
+		data  [][]byte
+		bytes int
+	)
+	for i, root := range roots {
+		if i >= downloader.MaxStateFetch || len(data) >= downloader.MaxStateChunkNodes || bytes >= softResponseLimit {
+			break
+		}
+		chunk := trie.Chunk(root, pm.blockchain.TrieNode, downloader.MaxStateChunkNodes-len(data), softResponseLimit-bytes)
+		for _, blob := range chunk {
+			bytes += len(blob)
+		}
+		data = append(data, chunk...)
+	}
+	return data
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+)
+
+const (
+	maxMatrixStateProofFetch = 64              // Amount of matrix state proofs to serve per request
+	stateProofTimeout        = 5 * time.Second // Time allowance for a peer to answer a proof request
+	stateProofRetries        = 3               // Number of peers to ask before giving up
+)
+
+var errNoStateProof = errors.New("no peer delivered a valid matrix state proof")
+
+// stateProofRetriever matches the matrix state proofs delivered by peers with
+// the on-demand requests waiting for them.
+type stateProofRetriever struct {
+	pending map[matrixStateProofReq][]chan *state.MatrixDataProof
+	lock    sync.Mutex
+}
+
+// newStateProofRetriever creates an idle matrix state proof retriever.
+func newStateProofRetriever() *stateProofRetriever {
+	return &stateProofRetriever{
+		pending: make(map[matrixStateProofReq][]chan *state.MatrixDataProof),
+	}
+}
+
+// register starts waiting for the proof of a request.
+func (r *stateProofRetriever) register(req matrixStateProofReq) chan *state.MatrixDataProof {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ch := make(chan *state.MatrixDataProof, stateProofRetries)
+	r.pending[req] = append(r.pending[req], ch)
+	return ch
+}
+
+// unregister stops waiting for the proof of a request.
+func (r *stateProofRetriever) unregister(req matrixStateProofReq, ch chan *state.MatrixDataProof) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	waiting := r.pending[req]
+	for i, c := range waiting {
+		if c == ch {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(waiting) == 0 {
+		delete(r.pending, req)
+	} else {
+		r.pending[req] = waiting
+	}
+}
+
+// deliver hands the proofs sent by a peer to the requests waiting for them,
+// unsolicited proofs are dropped.
+func (r *stateProofRetriever) deliver(proofs []*matrixStateProofRes) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, res := range proofs {
+		if res == nil || res.Proof == nil {
+			continue
+		}
+		for _, ch := range r.pending[matrixStateProofReq{Block: res.Block, Key: res.Key}] {
+			select {
+			case ch <- res.Proof:
+			default:
+			}
+		}
+	}
+}
+
+// serveMatrixStateProofs assembles the proofs of the requested matrix state
+// entries, skipping the blocks whose state is not available locally.
+func (pm *ProtocolManager) serveMatrixStateProofs(reqs []*matrixStateProofReq) []*matrixStateProofRes {
+	var (
+		proofs = make([]*matrixStateProofRes, 0, len(reqs))
+		states = make(map[common.Hash]*state.StateDBManage)
+	)
+	for _, req := range reqs {
+		if len(proofs) >= maxMatrixStateProofFetch {
+			break
+		}
+		st, ok := states[req.Block]
+		if !ok {
+			header := pm.blockchain.GetHeaderByHash(req.Block)
+			if header == nil {
+				continue
+			}
+			var err error
+			if st, err = pm.blockchain.StateAt(header.Roots); err != nil {
+				continue
+			}
+			states[req.Block] = st
+		}
+		proof, err := st.ProveMatrixData(req.Key)
+		if err != nil {
+			log.Debug("Failed to prove matrix state entry", "block", req.Block, "key", req.Key, "err", err)
+			continue
+		}
+		proofs = append(proofs, &matrixStateProofRes{Block: req.Block, Key: req.Key, Proof: proof})
+	}
+	return proofs
+}
+
+// RetrieveMatrixData fetches a matrix state entry in the state of the given
+// header from the connected peers, verifying the proof against the header's
+// state roots. This allows light clients, holding nothing but the header chain,
+// to read the elected nodes, heartbeats and other broadcast data trustlessly.
+func (pm *ProtocolManager) RetrieveMatrixData(ctx context.Context, header *types.Header, key string) ([]byte, error) {
+	req := matrixStateProofReq{Block: header.Hash(), Key: matrixstate.KeyHash(key)}
+	ch := pm.proofs.register(req)
+	defer pm.proofs.unregister(req, ch)
+
+	// Ask the best peer first, falling back to the others
+	peers := pm.Peers.PeersAll()
+	if best := pm.Peers.BestPeer(); best != nil {
+		for i, p := range peers {
+			if p == best {
+				peers[0], peers[i] = peers[i], peers[0]
+				break
+			}
+		}
+	}
+	for i, p := range peers {
+		if i >= stateProofRetries {
+			break
+		}
+		if p.version < man64 {
+			continue
+		}
+		if err := p.RequestMatrixStateProofs([]*matrixStateProofReq{&req}); err != nil {
+			continue
+		}
+		timeout := time.NewTimer(stateProofTimeout)
+		select {
+		case proof := <-ch:
+			timeout.Stop()
+			val, err := state.VerifyMatrixDataProof(header.Roots, req.Key, proof)
+			if err == nil {
+				return val, nil
+			}
+			p.Log().Debug("Invalid matrix state proof", "key", key, "err", err)
+
+		case <-timeout.C:
+			p.Log().Debug("Matrix state proof timed out", "key", key)
+
+		case <-ctx.Done():
+			timeout.Stop()
+			return nil, ctx.Err()
+		}
+	}
+	return nil, errNoStateProof
+}
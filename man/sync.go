@@ -385,6 +385,10 @@ func (pm *ProtocolManager) synchronise(peer *peer, flg int) {
 		mode = downloader.FastSync
 		log.Trace("download sync.go enter Synchronise set fastSync", "currentBlock", currentBlock.NumberU64())
 	}*/
+	if atomic.LoadUint32(&pm.snapSync) == 1 {
+		// Snap sync was explicitly requested on an empty chain
+		mode = downloader.SnapSync
+	}
 
 	//log.Trace("download sync.go enter Synchronise downloader", "currentBlock", currentBlock.NumberU64())
 	// Run the sync cycle, and disable fast sync if we've went past the pivot block
@@ -395,6 +399,12 @@ func (pm *ProtocolManager) synchronise(peer *peer, flg int) {
 		log.Info("Fast sync complete, auto disabling")
 		atomic.StoreUint32(&pm.fastSync, 0)
 	}
+	// Snap sync stays enabled until a sync cycle actually committed the pivot
+	// block, a cycle with a peer not ahead of us leaves the chain empty
+	if atomic.LoadUint32(&pm.snapSync) == 1 && pm.blockchain.CurrentBlock().NumberU64() > 0 {
+		log.Info("Snap sync complete, auto disabling")
+		atomic.StoreUint32(&pm.snapSync, 0)
+	}
 	atomic.StoreUint32(&pm.acceptTxs, 1) // Mark initial sync done
 
 	if pSbs < sbs {
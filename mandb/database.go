@@ -119,6 +119,19 @@ func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
 	return db.db.NewIterator(util.BytesPrefix(prefix), nil)
 }
 
+// Stat returns the compaction and io statistics of LevelDB.
+func (db *LDBDatabase) Stat() (string, error) {
+	stats, err := db.db.GetProperty("leveldb.stats")
+	if err != nil {
+		return "", err
+	}
+	iostats, err := db.db.GetProperty("leveldb.iostats")
+	if err != nil {
+		return "", err
+	}
+	return stats + "\n" + iostats + "\n", nil
+}
+
 func (db *LDBDatabase) Close() {
 	// Stop the metrics collection to avoid internal database races
 	db.quitLock.Lock()
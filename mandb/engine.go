@@ -0,0 +1,89 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package mandb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// EngineLevelDB is the name of the default LevelDB backend.
+	EngineLevelDB = "leveldb"
+
+	// EnginePebble is the name of the Pebble backend.
+	EnginePebble = "pebble"
+)
+
+// Opener opens a persistent key-value database in the given directory with the
+// cache (MB), file handles and table size (MB) allowances.
+type Opener func(file string, cache int, handles int, dbSize int) (Database, error)
+
+// engines is the registry of the available database backends.
+var engines = map[string]Opener{
+	EngineLevelDB: func(file string, cache int, handles int, dbSize int) (Database, error) {
+		return NewLDBDatabase(file, cache, handles, dbSize)
+	},
+	EnginePebble: func(file string, cache int, handles int, dbSize int) (Database, error) {
+		return NewPebbleDatabase(file, cache, handles, dbSize)
+	},
+}
+
+// Engines returns the names of the available database backends.
+func Engines() []string {
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DetectEngine returns the backend an existing database directory was created
+// with, or an empty string if the directory holds no database.
+func DetectEngine(file string) string {
+	// Pebble keeps its options in OPTIONS-<n> files, LevelDB never writes them
+	if matches, _ := filepath.Glob(filepath.Join(file, "OPTIONS-*")); len(matches) > 0 {
+		return EnginePebble
+	}
+	if _, err := os.Stat(filepath.Join(file, "CURRENT")); err == nil {
+		return EngineLevelDB
+	}
+	return ""
+}
+
+// ResolveEngine returns the backend to open the database directory with. An
+// empty engine selects the backend of the existing database, LevelDB for a new
+// one. Requesting another backend than the one of an existing database fails, as
+// the on-disk formats are incompatible.
+func ResolveEngine(engine string, file string) (string, error) {
+	if engine != "" {
+		if _, ok := engines[engine]; !ok {
+			return "", fmt.Errorf("unknown database engine %q, available: %v", engine, Engines())
+		}
+	}
+	existing := DetectEngine(file)
+	switch {
+	case existing == "" && engine == "":
+		return EngineLevelDB, nil
+	case existing == "":
+		return engine, nil
+	case engine == "" || engine == existing:
+		return existing, nil
+	default:
+		return "", fmt.Errorf("database %s was created with %s, cannot open it with %s", file, existing, engine)
+	}
+}
+
+// Open opens the database directory with the given backend, see ResolveEngine.
+func Open(engine string, file string, cache int, handles int, dbSize int) (Database, error) {
+	engine, err := ResolveEngine(engine, file)
+	if err != nil {
+		return nil, err
+	}
+	return engines[engine](file, cache, handles, dbSize)
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package mandb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveEngine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbengine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A new database defaults to LevelDB and accepts any known engine
+	if engine, err := ResolveEngine("", dir); err != nil || engine != EngineLevelDB {
+		t.Errorf("new database: have %q %v, want %q", engine, err, EngineLevelDB)
+	}
+	if engine, err := ResolveEngine(EnginePebble, dir); err != nil || engine != EnginePebble {
+		t.Errorf("new pebble database: have %q %v, want %q", engine, err, EnginePebble)
+	}
+	if _, err := ResolveEngine("rocksdb", dir); err == nil {
+		t.Errorf("unknown engine accepted")
+	}
+
+	// An existing database keeps its engine
+	ioutil.WriteFile(filepath.Join(dir, "CURRENT"), []byte("MANIFEST-000001\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "OPTIONS-000002"), nil, 0644)
+	if engine, err := ResolveEngine("", dir); err != nil || engine != EnginePebble {
+		t.Errorf("existing pebble database: have %q %v, want %q", engine, err, EnginePebble)
+	}
+	if _, err := ResolveEngine(EngineLevelDB, dir); err == nil {
+		t.Errorf("pebble database opened with leveldb")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package mandb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/MatrixAINetwork/go-matrix/log"
+)
+
+var (
+	// errUnknownTable is returned if a freezer table is accessed which was not
+	// configured when opening the freezer.
+	errUnknownTable = errors.New("unknown freezer table")
+
+	// errMissingTableItem is returned if an append does not provide an item for
+	// every table of the freezer.
+	errMissingTableItem = errors.New("missing freezer table item")
+)
+
+// Freezer is an append-only store of immutable chain data, made of one flat file
+// table per kind of data. All tables hold the same number of items, item n of
+// each table belonging to block n, and are only extended in lockstep by
+// AppendAncient, so that LevelDB does not need to carry (and compact) the data
+// of blocks which will never change again.
+type Freezer struct {
+	frozen uint64 // Number of blocks already frozen, must be accessed atomically
+
+	tables map[string]*freezerTable
+	lock   sync.Mutex // Serialises appends and truncations
+}
+
+// NewFreezer opens the freezer tables in datadir, creating the directory if it
+// does not exist yet. The map associates every table name with whether its items
+// are snappy compressed. Tables left uneven by an interrupted append are
+// truncated to the shortest one.
+func NewFreezer(datadir string, tables map[string]bool) (*Freezer, error) {
+	if err := os.MkdirAll(datadir, 0755); err != nil {
+		return nil, err
+	}
+	freezer := &Freezer{tables: make(map[string]*freezerTable)}
+	for name, compress := range tables {
+		table, err := newFreezerTable(datadir, name, compress)
+		if err != nil {
+			freezer.Close()
+			return nil, err
+		}
+		freezer.tables[name] = table
+	}
+	frozen := uint64(0)
+	first := true
+	for _, table := range freezer.tables {
+		if items := table.Items(); first || items < frozen {
+			frozen, first = items, false
+		}
+	}
+	for name, table := range freezer.tables {
+		if table.Items() != frozen {
+			log.Warn("Truncating uneven freezer table", "table", name, "items", table.Items(), "frozen", frozen)
+			if err := table.truncate(frozen); err != nil {
+				freezer.Close()
+				return nil, err
+			}
+		}
+	}
+	atomic.StoreUint64(&freezer.frozen, frozen)
+	log.Info("Opened ancient database", "path", datadir, "frozen", frozen)
+	return freezer, nil
+}
+
+// Ancients returns the number of blocks stored in the freezer.
+func (f *Freezer) Ancients() uint64 {
+	return atomic.LoadUint64(&f.frozen)
+}
+
+// HasAncient returns whether the freezer holds the item of block number in the
+// table kind.
+func (f *Freezer) HasAncient(kind string, number uint64) bool {
+	_, ok := f.tables[kind]
+	return ok && number < f.Ancients()
+}
+
+// Ancient retrieves the item of block number from the table kind.
+func (f *Freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	table, ok := f.tables[kind]
+	if !ok {
+		return nil, errUnknownTable
+	}
+	if number >= f.Ancients() {
+		return nil, errOutOfBounds
+	}
+	return table.Retrieve(number)
+}
+
+// AppendAncient appends the items of block number, which must directly follow
+// the last frozen block, to every table. If any table fails, all of them are
+// truncated back to the previous block.
+func (f *Freezer) AppendAncient(number uint64, items map[string][]byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	frozen := f.Ancients()
+	if number != frozen {
+		return fmt.Errorf("appending unexpected ancient block: want %d, have %d", frozen, number)
+	}
+	for name := range f.tables {
+		if _, ok := items[name]; !ok {
+			return fmt.Errorf("%v: %s", errMissingTableItem, name)
+		}
+	}
+	for name, table := range f.tables {
+		if err := table.Append(number, items[name]); err != nil {
+			f.truncate(frozen)
+			return fmt.Errorf("freezer table %s: %v", name, err)
+		}
+	}
+	atomic.StoreUint64(&f.frozen, frozen+1)
+	return nil
+}
+
+// TruncateAncients discards all the blocks from items onwards.
+func (f *Freezer) TruncateAncients(items uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if items >= f.Ancients() {
+		return nil
+	}
+	if err := f.truncate(items); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	return nil
+}
+
+func (f *Freezer) truncate(items uint64) error {
+	for name, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return fmt.Errorf("freezer table %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Sync flushes all the tables to disk.
+func (f *Freezer) Sync() error {
+	for name, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			return fmt.Errorf("freezer table %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Close closes all the tables.
+func (f *Freezer) Close() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
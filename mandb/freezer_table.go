@@ -0,0 +1,241 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package mandb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang/snappy"
+)
+
+const indexEntrySize = 8
+
+var (
+	// errOutOfBounds is returned if the item requested is not contained within
+	// the freezer table.
+	errOutOfBounds = errors.New("out of bounds")
+
+	// errClosed is returned if an operation attempts to read from or write to
+	// the freezer table after it has already been closed.
+	errClosed = errors.New("closed")
+)
+
+// freezerTable is an append-only flat file table. Items are appended to a data
+// file, the end offset of every item is recorded in an index file of fixed size
+// entries, so item n spans the data between the end offsets of items n-1 and n.
+type freezerTable struct {
+	name     string
+	compress bool // Whether the items are snappy compressed
+
+	index *os.File // Index file, big endian uint64 end offset of every item
+	data  *os.File // Data file holding the concatenated items
+	items uint64   // Number of items stored in the table
+	size  uint64   // Size of the data file
+
+	lock sync.RWMutex
+}
+
+// newFreezerTable opens the freezer table name in dir, repairing the files if
+// the last append was interrupted.
+func newFreezerTable(dir string, name string, compress bool) (*freezerTable, error) {
+	ext := ".rdat"
+	if compress {
+		ext = ".cdat"
+	}
+	index, err := os.OpenFile(filepath.Join(dir, name+".ridx"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.OpenFile(filepath.Join(dir, name+ext), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		index.Close()
+		return nil, err
+	}
+	t := &freezerTable{name: name, compress: compress, index: index, data: data}
+	if err := t.repair(); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// repair drops a partially written index entry and makes the index and the data
+// file agree on the last item, truncating whichever is ahead.
+func (t *freezerTable) repair() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	indexSize := uint64(stat.Size()) / indexEntrySize * indexEntrySize
+	if stat, err = t.data.Stat(); err != nil {
+		return err
+	}
+	dataSize := uint64(stat.Size())
+
+	items := indexSize / indexEntrySize
+	for ; items > 0; items-- {
+		end, err := t.readOffset(items - 1)
+		if err != nil {
+			return err
+		}
+		if end <= dataSize {
+			dataSize = end
+			break
+		}
+	}
+	if items == 0 {
+		dataSize = 0
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(dataSize)); err != nil {
+		return err
+	}
+	t.items, t.size = items, dataSize
+	return nil
+}
+
+// readOffset reads the end offset of an item from the index file.
+func (t *freezerTable) readOffset(item uint64) (uint64, error) {
+	buf := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buf, int64(item*indexEntrySize)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+// Items returns the number of items stored in the table.
+func (t *freezerTable) Items() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.items
+}
+
+// Append stores item as the next item of the table, which must be number item.
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil || t.data == nil {
+		return errClosed
+	}
+	if item != t.items {
+		return fmt.Errorf("appending unexpected item: want %d, have %d", t.items, item)
+	}
+	if t.compress {
+		blob = snappy.Encode(nil, blob)
+	}
+	if _, err := t.data.WriteAt(blob, int64(t.size)); err != nil {
+		return err
+	}
+	end := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint64(end, t.size+uint64(len(blob)))
+	if _, err := t.index.WriteAt(end, int64(t.items*indexEntrySize)); err != nil {
+		return err
+	}
+	t.size += uint64(len(blob))
+	t.items++
+	return nil
+}
+
+// Retrieve returns the item number item of the table.
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil || t.data == nil {
+		return nil, errClosed
+	}
+	if item >= t.items {
+		return nil, errOutOfBounds
+	}
+	var start uint64
+	if item > 0 {
+		var err error
+		if start, err = t.readOffset(item - 1); err != nil {
+			return nil, err
+		}
+	}
+	end, err := t.readOffset(item)
+	if err != nil {
+		return nil, err
+	}
+	blob := make([]byte, end-start)
+	if _, err := t.data.ReadAt(blob, int64(start)); err != nil {
+		return nil, err
+	}
+	if t.compress {
+		return snappy.Decode(nil, blob)
+	}
+	return blob, nil
+}
+
+// truncate discards the items after the first items ones.
+func (t *freezerTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if items >= t.items {
+		return nil
+	}
+	var size uint64
+	if items > 0 {
+		var err error
+		if size, err = t.readOffset(items - 1); err != nil {
+			return err
+		}
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(size)); err != nil {
+		return err
+	}
+	t.items, t.size = items, size
+	return nil
+}
+
+// Sync flushes the table files to disk, the data file first so that the index
+// never refers to data not yet persisted.
+func (t *freezerTable) Sync() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.data.Sync(); err != nil {
+		return err
+	}
+	return t.index.Sync()
+}
+
+// Close closes the table files.
+func (t *freezerTable) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []error
+	if t.index != nil {
+		if err := t.index.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		t.index = nil
+	}
+	if t.data != nil {
+		if err := t.data.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		t.data = nil
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package mandb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testFreezerTables = map[string]bool{"hashes": false, "bodies": true}
+
+func testFreezerItems(number uint64) map[string][]byte {
+	return map[string][]byte{
+		"hashes": []byte(fmt.Sprintf("hash-%d", number)),
+		"bodies": bytes.Repeat([]byte{byte(number)}, int(number)*10),
+	}
+}
+
+func TestFreezerAppendRetrieve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := NewFreezer(dir, testFreezerTables)
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	for number := uint64(0); number < 10; number++ {
+		if err := f.AppendAncient(number, testFreezerItems(number)); err != nil {
+			t.Fatalf("failed to append block %d: %v", number, err)
+		}
+	}
+	if err := f.AppendAncient(12, testFreezerItems(12)); err == nil {
+		t.Errorf("non-contiguous append accepted")
+	}
+	if err := f.AppendAncient(10, map[string][]byte{"hashes": nil}); err == nil {
+		t.Errorf("append with missing table item accepted")
+	}
+	f.Close()
+
+	// Reopen and check the persisted items
+	if f, err = NewFreezer(dir, testFreezerTables); err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	defer f.Close()
+	if frozen := f.Ancients(); frozen != 10 {
+		t.Fatalf("frozen count mismatch: have %d, want 10", frozen)
+	}
+	for number := uint64(0); number < 10; number++ {
+		for kind, want := range testFreezerItems(number) {
+			have, err := f.Ancient(kind, number)
+			if err != nil {
+				t.Fatalf("failed to retrieve %s %d: %v", kind, number, err)
+			}
+			if !bytes.Equal(have, want) {
+				t.Errorf("%s %d mismatch: have %x, want %x", kind, number, have, want)
+			}
+		}
+	}
+	if _, err := f.Ancient("bodies", 10); err == nil {
+		t.Errorf("retrieved item beyond the frozen blocks")
+	}
+	if _, err := f.Ancient("receipts", 0); err == nil {
+		t.Errorf("retrieved item of unknown table")
+	}
+
+	// Truncate and append again
+	if err := f.TruncateAncients(5); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if f.HasAncient("hashes", 5) || !f.HasAncient("hashes", 4) {
+		t.Errorf("truncation boundary mismatch")
+	}
+	if err := f.AppendAncient(5, testFreezerItems(5)); err != nil {
+		t.Fatalf("failed to append after truncation: %v", err)
+	}
+}
+
+func TestFreezerRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := NewFreezer(dir, testFreezerTables)
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	for number := uint64(0); number < 5; number++ {
+		if err := f.AppendAncient(number, testFreezerItems(number)); err != nil {
+			t.Fatalf("failed to append block %d: %v", number, err)
+		}
+	}
+	f.Close()
+
+	// Simulate a crash in the middle of an append: the hashes table holds an
+	// extra item and the bodies index a partial entry.
+	table, err := newFreezerTable(dir, "hashes", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.Append(5, []byte("hash-5"))
+	table.Close()
+
+	index, err := os.OpenFile(filepath.Join(dir, "bodies.ridx"), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Write([]byte{0, 0, 1})
+	index.Close()
+
+	if f, err = NewFreezer(dir, testFreezerTables); err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	defer f.Close()
+	if frozen := f.Ancients(); frozen != 5 {
+		t.Fatalf("frozen count mismatch after repair: have %d, want 5", frozen)
+	}
+	if err := f.AppendAncient(5, testFreezerItems(5)); err != nil {
+		t.Fatalf("failed to append after repair: %v", err)
+	}
+	if have, _ := f.Ancient("bodies", 5); !bytes.Equal(have, testFreezerItems(5)["bodies"]) {
+		t.Errorf("body mismatch after repair: have %x", have)
+	}
+}
@@ -4,6 +4,8 @@
 
 package mandb
 
+import "github.com/syndtr/goleveldb/leveldb/iterator"
+
 // Code using batches should try to add this much data to the batch.
 // The value was determined empirically.
 const IdealBatchSize = 100 * 1024
@@ -23,6 +25,18 @@ type Database interface {
 	NewBatch() Batch
 }
 
+// Iteratee wraps the NewIteratorWithPrefix method of the databases supporting
+// iteration over their content in key order.
+type Iteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// Stater wraps the Stat method of the databases reporting the internal statistics
+// of their storage engine.
+type Stater interface {
+	Stat() (string, error)
+}
+
 // Batch is a write-only database that commits changes to its host database
 // when Write is called. Batch cannot be used concurrently.
 type Batch interface {
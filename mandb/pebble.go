@@ -0,0 +1,248 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package mandb
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// PebbleDatabase is a persistent key-value store backed by Pebble. Pebble splits
+// compactions into small concurrent units and throttles them against the write
+// load instead of stalling writers, which keeps block import latency stable
+// while syncing.
+type PebbleDatabase struct {
+	fn string     // filename for reporting
+	db *pebble.DB // Pebble instance
+
+	log log.Logger // Contextual logger tracking the database path
+}
+
+// NewPebbleDatabase returns a Pebble wrapped object.
+func NewPebbleDatabase(file string, cache int, handles int, dbSize int) (*PebbleDatabase, error) {
+	logger := log.New("database", file)
+
+	// Ensure we have some minimal caching and file guarantees
+	if cache < 16 {
+		cache = 16
+	}
+	if handles < 16 {
+		handles = 16
+	}
+	if dbSize < 2 {
+		dbSize = 2
+	}
+	logger.Info("Allocated cache and file handles", "engine", EnginePebble, "cache", cache, "handles", handles, "dbSize", dbSize)
+
+	// Target table sizes double on every level, starting from dbSize
+	levels := make([]pebble.LevelOptions, 7)
+	for i := range levels {
+		levels[i] = pebble.LevelOptions{
+			TargetFileSize: int64(dbSize) * 1024 * 1024 << uint(i),
+			FilterPolicy:   bloom.FilterPolicy(10),
+		}
+	}
+	opts := &pebble.Options{
+		// Pebble's block cache plays the role of LevelDB's, sharing the allowance
+		// with the memory tables
+		Cache:                       pebble.NewCache(int64(cache / 2 * 1024 * 1024)),
+		MaxOpenFiles:                handles,
+		MemTableSize:                cache / 4 * 1024 * 1024,
+		MemTableStopWritesThreshold: 2,
+		MaxConcurrentCompactions:    3,
+		L0CompactionThreshold:       4,
+		L0StopWritesThreshold:       24,
+		Levels:                      levels,
+	}
+	defer opts.Cache.Unref()
+
+	db, err := pebble.Open(file, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PebbleDatabase{
+		fn:  file,
+		db:  db,
+		log: logger,
+	}, nil
+}
+
+// Path returns the path to the database directory.
+func (db *PebbleDatabase) Path() string {
+	return db.fn
+}
+
+// Put puts the given key / value to the queue
+func (db *PebbleDatabase) Put(key []byte, value []byte) error {
+	return db.db.Set(key, value, pebble.NoSync)
+}
+
+func (db *PebbleDatabase) Has(key []byte) (bool, error) {
+	_, closer, err := db.db.Get(key)
+	if err == pebble.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	closer.Close()
+	return true, nil
+}
+
+// Get returns the given key if it's present. Missing keys are reported with the
+// LevelDB not found error, which the callers of the Database interface check for.
+func (db *PebbleDatabase) Get(key []byte) ([]byte, error) {
+	dat, closer, err := db.db.Get(key)
+	if err == pebble.ErrNotFound {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	ret := make([]byte, len(dat))
+	copy(ret, dat)
+	return ret, nil
+}
+
+// Delete deletes the key from the queue and database
+func (db *PebbleDatabase) Delete(key []byte) error {
+	return db.db.Delete(key, pebble.NoSync)
+}
+
+// NewIteratorWithPrefix returns a iterator to iterate over subset of database content with a particular prefix.
+func (db *PebbleDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	opts := &pebble.IterOptions{LowerBound: prefix, UpperBound: upperBound(prefix)}
+	return &pebbleIterator{iter: db.db.NewIter(opts)}
+}
+
+// Stat returns the level, compaction and cache metrics of Pebble.
+func (db *PebbleDatabase) Stat() (string, error) {
+	return db.db.Metrics().String(), nil
+}
+
+func (db *PebbleDatabase) Close() {
+	if err := db.db.Close(); err == nil {
+		db.log.Info("Database closed")
+	} else {
+		db.log.Error("Failed to close database", "err", err)
+	}
+}
+
+func (db *PebbleDatabase) NewBatch() Batch {
+	return &pebbleBatch{db: db.db, b: db.db.NewBatch()}
+}
+
+type pebbleBatch struct {
+	db   *pebble.DB
+	b    *pebble.Batch
+	size int
+}
+
+func (b *pebbleBatch) Put(key, value []byte) error {
+	b.size += len(value)
+	return b.b.Set(key, value, nil)
+}
+
+func (b *pebbleBatch) Write() error {
+	return b.db.Apply(b.b, pebble.NoSync)
+}
+
+func (b *pebbleBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *pebbleBatch) Reset() {
+	b.b.Reset()
+	b.size = 0
+}
+
+// upperBound returns the smallest key greater than all the keys with the given
+// prefix, nil if there is none.
+func upperBound(prefix []byte) []byte {
+	limit := make([]byte, len(prefix))
+	copy(limit, prefix)
+	for i := len(limit) - 1; i >= 0; i-- {
+		if limit[i] < 0xff {
+			limit[i]++
+			return limit[:i+1]
+		}
+	}
+	return nil
+}
+
+// pebbleIterator adapts a Pebble iterator to the LevelDB iterator interface,
+// which positions a fresh iterator on the first (or last) key on the first call
+// to Next (or Prev).
+type pebbleIterator struct {
+	iter     *pebble.Iterator
+	started  bool
+	released bool
+}
+
+func (it *pebbleIterator) First() bool {
+	it.started = true
+	return it.iter.First()
+}
+
+func (it *pebbleIterator) Last() bool {
+	it.started = true
+	return it.iter.Last()
+}
+
+func (it *pebbleIterator) Seek(key []byte) bool {
+	it.started = true
+	return it.iter.SeekGE(key)
+}
+
+func (it *pebbleIterator) Next() bool {
+	if !it.started {
+		return it.First()
+	}
+	return it.iter.Next()
+}
+
+func (it *pebbleIterator) Prev() bool {
+	if !it.started {
+		return it.Last()
+	}
+	return it.iter.Prev()
+}
+
+func (it *pebbleIterator) Valid() bool {
+	return !it.released && it.started && it.iter.Valid()
+}
+
+func (it *pebbleIterator) Key() []byte {
+	if !it.Valid() {
+		return nil
+	}
+	return it.iter.Key()
+}
+
+func (it *pebbleIterator) Value() []byte {
+	if !it.Valid() {
+		return nil
+	}
+	return it.iter.Value()
+}
+
+func (it *pebbleIterator) Error() error {
+	return it.iter.Error()
+}
+
+func (it *pebbleIterator) Release() {
+	if !it.released {
+		it.released = true
+		it.iter.Close()
+	}
+}
+
+func (it *pebbleIterator) SetReleaser(releaser util.Releaser) {}
@@ -28,6 +28,7 @@ const (
 	MSKeyElectWhiteList          = "elect_white_list"           // 选举白名单
 	MSKeyElectWhiteListSwitcher  = "elect_white_list_switcher"  // 选举白名单生效开关
 	MSKeyElectDynamicPollingInfo = "elect_dynamic_polling_info" // 选举动态轮询信息
+	MSKeyElectorForks            = "elector_forks"              // 选举算法切换高度
 	MSKeyAccountBroadcasts       = "account_broadcasts"         // 广播账户 []common.Address
 	MSKeyAccountInnerMiners      = "account_inner_miners"       // 基金会矿工 []common.Address
 	MSKeyAccountFoundation       = "account_foundation"         // 基金会账户 common.Address
@@ -38,6 +39,7 @@ const (
 	MSKeyVIPConfig               = "vip_config"                 // VIP配置信息
 	MSKeyPreBroadcastRoot        = "pre_broadcast_Root"         // 前广播区块root信息
 	MSKeyLeaderConfig            = "leader_config"              // leader服务配置信息
+	MSKeyLeaderSelection         = "leader_selection"           // leader选取方式
 	MSKeyMinHash                 = "pre_100_min_hash"           // 最小hash
 	MSKeySuperBlockCfg           = "super_block_config"         // 超级区块配置
 	MSKeyMinimumDifficulty       = "min_difficulty"             // 最小挖矿难度
@@ -84,6 +86,50 @@ const (
 	MSKeyBasePowerSlashCfg    = "base_power_slash_cfg"    //
 	MSKeyBasePowerStats       = "base_power_stats"        //
 	MSKeyBasePowerBlackList   = "base_power_blacklist"    //
+
+	//心跳惩罚配置相关
+	MSKeyHeartbeatSlashCfg       = "heartbeat_slash_cfg"       // 心跳惩罚配置
+	MSKeyHeartbeatSlashNum       = "heartbeat_slash_num"       // 心跳惩罚状态
+	MSKeyHeartbeatSlashBlackList = "heartbeat_slash_blacklist" // 心跳惩罚黑名单
+
+	//双签惩罚配置相关
+	MSKeyDoubleSignSlashCfg = "double_sign_slash_cfg" // 双签惩罚配置
+	MSKeyDoubleSignRecords  = "double_sign_records"   // 已惩罚的双签记录
+
+	//抵押解绑配置相关
+	MSKeyUnbondingCfg   = "unbonding_cfg"   // 抵押解绑配置
+	MSKeyUnbondingQueue = "unbonding_queue" // 抵押解绑队列
+
+	//奖励配置治理
+	MSKeyRewardGovernance = "reward_governance" // 奖励分配比例治理状态
+
+	//可撤销交易
+	MSKeyRevocableQueue = "revocable_queue" // 按区块高度撤销期的可撤销交易队列
+
+	//共识密钥
+	MSKeyConsensusKeyCfg = "consensus_key_cfg" // 共识密钥配置
+	MSKeyConsensusKeys   = "consensus_keys"    // 抵押账户注册的共识密钥
+	MSKeyBLSVoteCfg      = "bls_vote_cfg"      // BLS聚合投票配置
+
+	//检查点终局
+	MSKeyFinalityCfg         = "finality_cfg"         // 检查点投票配置
+	MSKeyFinalizedCheckpoint = "finalized_checkpoint" // 最新的终局检查点
+
+	//WASM合约
+	MSKeyWasmCfg = "wasm_cfg" // WASM合约执行配置
+
+	//随机种子提交-揭示
+	MSKeySeedCommitRevealCfg   = "seed_commit_reveal_cfg"   // 随机种子提交-揭示配置
+	MSKeySeedCommitRevealState = "seed_commit_reveal_state" // 随机种子提交-揭示状态
+
+	//收据扩展信息
+	MSKeyReceiptMetaCfg = "receipt_meta_cfg" // 收据记录Matrix交易信息的配置
+
+	//链参数更新
+	MSKeyParamUpdates = "param_updates" // 待生效的链参数更新
+
+	//硬分叉
+	MSKeyForkSchedule = "fork_schedule" // 硬分叉生效高度
 	//交易配置
 	MSTxpoolGasLimitCfg = "man_TxpoolGasLimitCfg" //入池gas配置
 	MSCurrencyConfig    = "man_CurrencyConfig"    //币种配置
@@ -126,6 +172,12 @@ type ElectConfigInfo struct {
 	ElectPlug     string
 }
 
+// 自该选举高度起使用的选举算法
+type ElectorFork struct {
+	Number  uint64
+	Elector string
+}
+
 type ElectDynamicPollingInfo struct {
 	Number        uint64           //高度
 	Seq           uint64           //轮次序列号
@@ -161,6 +213,16 @@ type LeaderConfig struct {
 	ReelectHandleInterval uint64 // 重选处理间隔时间
 }
 
+// leader选取方式
+const (
+	LeaderSelectRotation uint8 = iota // 按前一个leader顺序轮换
+	LeaderSelectVRF                   // 以前一区块的VRF值为种子排序
+)
+
+type LeaderSelectionCfg struct {
+	Mode uint8
+}
+
 type PreBroadStateRoot struct {
 	LastStateRoot       []common.CoinRoot
 	BeforeLastStateRoot []common.CoinRoot
@@ -300,6 +362,227 @@ type BlockProduceSlashStatsStatus struct {
 	Number uint64
 }
 
+type HeartbeatSlashCfg struct {
+	Switcher         bool
+	SlashRate        uint64 // 每次未发送心跳惩罚的抵押比例(万分比)
+	ProhibitCycleNum uint16 // 禁止参选的广播周期数
+}
+
+type HeartbeatSlash struct {
+	Address              common.Address
+	ProhibitCycleCounter uint16
+}
+
+type HeartbeatSlashBlackList struct {
+	BlackList []HeartbeatSlash
+}
+
+type DoubleSignSlashCfg struct {
+	Switcher       bool
+	SlashRate      uint64 // 双签惩罚的抵押比例(万分比)
+	EvidenceMaxAge uint64 // 证据的有效区块数
+}
+
+type DoubleSignRecord struct {
+	Account common.Address // 作恶者抵押账户
+	Number  uint64         // 双签高度
+}
+
+type DoubleSignRecords struct {
+	Records []DoubleSignRecord
+}
+
+type UnbondingCfg struct {
+	Intervals uint64 // 退出抵押后锁定的广播周期数, 0表示不锁定
+}
+
+type UnbondingEntry struct {
+	Account       common.Address // 抵押账户(A0)
+	Position      uint64         // 抵押仓位
+	Amount        *big.Int       // 退出金额
+	RequestNumber uint64         // 退出请求高度
+	ReleaseNumber uint64         // 解绑完成高度
+}
+
+type UnbondingQueue struct {
+	Entries []UnbondingEntry
+}
+
+type ConsensusKeyCfg struct {
+	ActivateDelay uint64 // 共识密钥注册后生效前等待的区块数
+}
+
+type ConsensusKey struct {
+	Account        common.Address // 抵押账户(A0)
+	Signer         common.Address // 共识签名账户
+	BLSPubKey      []byte         // BLS共识公钥, 可为空
+	ActivateNumber uint64         // 生效高度
+	PrevSigner     common.Address // 生效前的共识签名账户, 为空表示使用A1账户
+	PrevBLSPubKey  []byte         // 生效前的BLS共识公钥
+}
+
+type ConsensusKeys struct {
+	Keys []ConsensusKey
+}
+
+type BLSVoteCfg struct {
+	Switcher       bool   // BLS聚合投票开关
+	ActivateNumber uint64 // 生效高度
+}
+
+// 区块高度number是否启用BLS聚合投票
+func (cfg *BLSVoteCfg) Active(number uint64) bool {
+	return cfg != nil && cfg.Switcher && number >= cfg.ActivateNumber
+}
+
+type FinalityCfg struct {
+	Switcher       bool   // 检查点投票开关
+	Interval       uint64 // 检查点间隔, 每Interval个区块为一个检查点
+	ActivateNumber uint64 // 生效高度
+}
+
+// 区块高度number是否启用检查点投票
+func (cfg *FinalityCfg) Active(number uint64) bool {
+	return cfg != nil && cfg.Switcher && cfg.Interval != 0 && number >= cfg.ActivateNumber
+}
+
+// 区块高度number是否为检查点
+func (cfg *FinalityCfg) IsCheckpoint(number uint64) bool {
+	return cfg.Active(number) && number%cfg.Interval == 0
+}
+
+type WasmCfg struct {
+	Switcher       bool   // WASM合约执行开关
+	ActivateNumber uint64 // 生效高度
+}
+
+// 区块高度number是否启用WASM合约执行
+func (cfg *WasmCfg) Active(number uint64) bool {
+	return cfg != nil && cfg.Switcher && number >= cfg.ActivateNumber
+}
+
+// 硬分叉名称
+const (
+	ForkElectedSet      = "elected_set"      // 查询当选节点的预编译合约
+	ForkMatrixSchedule  = "matrix_schedule"  // 查询广播周期及账户角色的预编译合约
+	ForkSeedThreshold   = "seed_threshold"   // 私钥交易门限加密
+	ForkRevocableHeight = "revocable_height" // 按区块高度设置撤销期的可撤销交易
+	ForkBridgeAttest    = "bridge_attest"    // 跨链证明交易
+	ForkConsensusKey    = "consensus_key"    // 共识密钥注册交易
+	ForkParamUpdate     = "param_update"     // 链参数更新治理交易
+	ForkBroadcastCodec  = "broadcast_codec"  // 广播交易内容及状态数据的版本化编码
+	ForkDoubleSign      = "double_sign"      // 按共识轮次校验的双签证据交易
+	ForkBroadcastTypes  = "broadcast_types"  // 广播交易内容的键按注册的类型精确匹配
+)
+
+type ForkActivation struct {
+	Name           string // 分叉名称
+	ActivateNumber uint64 // 生效高度
+}
+
+type ForkSchedule struct {
+	Forks []ForkActivation
+}
+
+// 名为name的硬分叉的生效高度, 未配置时返回false
+func (s *ForkSchedule) Activation(name string) (uint64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	for _, fork := range s.Forks {
+		if fork.Name == name {
+			return fork.ActivateNumber, true
+		}
+	}
+	return 0, false
+}
+
+// 区块高度number是否启用名为name的硬分叉
+func (s *ForkSchedule) Active(name string, number uint64) bool {
+	activate, ok := s.Activation(name)
+	return ok && number >= activate
+}
+
+type ReceiptMetaCfg struct {
+	Switcher       bool   // 收据记录Matrix交易信息开关
+	ActivateNumber uint64 // 生效高度
+}
+
+// 区块高度number的收据是否记录Matrix交易信息
+func (cfg *ReceiptMetaCfg) Active(number uint64) bool {
+	return cfg != nil && cfg.Switcher && number >= cfg.ActivateNumber
+}
+
+type SeedCommitRevealCfg struct {
+	Switcher  bool
+	SlashRate uint64 // 提交后未揭示惩罚的抵押比例(万分比)
+}
+
+// 验证者在广播周期内提交的随机种子承诺, 须在下个广播周期揭示
+type SeedCommitment struct {
+	Account    common.Address // 抵押账户
+	Commitment common.Hash
+}
+
+type SeedCommitRevealState struct {
+	Number  uint64           // 最近处理的广播区块高度
+	Commits []SeedCommitment // 待揭示的承诺, 按账户排序
+	Seed    common.Hash      // 最近揭示的种子累计值
+}
+
+// 经超过2/3的验证者签名的检查点, 主链不会回滚到检查点之前
+type FinalizedCheckpoint struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// 链参数更新, 为0的参数不修改
+type ParamUpdate struct {
+	ActivateNumber uint64 // 生效高度, 须为选举区块
+	BCInterval     uint64 // 广播周期
+	ValidatorNum   uint16 // 验证者数量
+	BackValidator  uint16 // 备份验证者数量
+	MinerNum       uint16 // 矿工数量
+}
+
+// 是否有参数需要修改
+func (update *ParamUpdate) Empty() bool {
+	return update.BCInterval == 0 && update.ValidatorNum == 0 && update.BackValidator == 0 && update.MinerNum == 0
+}
+
+type ParamUpdateQueue struct {
+	Updates        []ParamUpdate // 按生效高度排序
+	ProposalNumber uint64        // 最后通过的提案高度
+}
+
+// 广播交易状态数据编码版本
+const (
+	BroadcastTxCodecRLP       uint64 = 0 // rlp编码(旧版本)
+	BroadcastTxCodecCanonical uint64 = 1 // 排序后的规范JSON编码
+)
+
+type RevocableEntry struct {
+	Hash          common.Hash // 可撤销交易hash
+	ReleaseNumber uint64      // 撤销期结束, 转账到账的高度
+}
+
+type RevocableQueue struct {
+	Entries []RevocableEntry
+}
+
+type RewardSchedule struct {
+	BlkRewardRate     AIRewardRateCfg // 固定区块奖励分配比例
+	TxsMinersRate     uint64          // 交易费矿工网络奖励比例
+	TxsValidatorsRate uint64          // 交易费验证者网络奖励比例
+	TxsRewardRate     RewardRateCfg   // 交易费奖励分配比例
+}
+
+type RewardGovernanceStatus struct {
+	Pending        bool           // 是否有待生效的奖励分配比例
+	Schedule       RewardSchedule // 下个广播区块生效的奖励分配比例
+	ProposalNumber uint64         // 最近通过的提案高度
+}
+
 type BasePowerSlashCfg struct {
 	Switcher         bool
 	LowTHR           uint16
@@ -91,5 +91,20 @@ const (
 	HD_V2_FullBlockReq
 	HD_V2_FullBlockRsp
 
+	//slash
+	Slash_Notify // SlashEvent
+
+	//calltheroll
+	CallTheRoll_Report // CallTheRollReport
+
+	//finality
+	HD_CheckpointVote // HD_CheckpointVoteMsg
+
+	//bridge
+	HD_BridgeAttest // HD_BridgeAttestMsg
+
+	//evidence
+	HD_DoubleSignEvidence // HD_DoubleSignEvidenceMsg
+
 	LastEventCode
 )
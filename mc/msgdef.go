@@ -246,6 +246,7 @@ type HD_ConsensusVote struct {
 	Number   uint64
 	Sign     common.Signature
 	From     common.Address
+	BLSSign  []byte `json:",omitempty"` // BLS投票签名, 启用BLS聚合投票后用于聚合
 }
 
 type HD_OnlineConsensusVotes struct {
@@ -385,3 +386,51 @@ type BlockInsertedMsg struct {
 	InsertTime uint64
 	CanonState bool
 }
+
+// 惩罚原因
+const (
+	SlashReasonHeartbeat  = "heartbeat"  // 未发送心跳
+	SlashReasonSeedReveal = "seedReveal" // 提交随机种子承诺后未揭示
+)
+
+type SlashEvent struct {
+	Number         uint64         // 执行惩罚的区块高度
+	Account        common.Address // 被惩罚的抵押账户
+	Reason         string
+	Penalty        *big.Int // 惩罚的抵押金额
+	ProhibitCycles uint16   // 禁止参选的广播周期数
+}
+
+// CallTheRollReport 点名一致性检查报告, 广播区块上链的点名数据与本地交易池收到的点名交易的差异
+type CallTheRollReport struct {
+	Interval   uint64           // 广播周期
+	Number     uint64           // 广播区块高度
+	Hash       common.Hash      // 广播区块哈希
+	Observed   int              // 本地收到的点名交易数
+	Committed  int              // 上链的点名数据数
+	Missing    []common.Address // 本地收到但未上链的发送者
+	Unobserved []common.Address // 已上链但本地未收到的发送者
+	Mismatched []common.Address // 上链内容与本地收到的不一致的发送者
+}
+
+// HD_CheckpointVoteMsg 验证者对检查点区块的BLS投票
+type HD_CheckpointVoteMsg struct {
+	Number  uint64
+	Hash    common.Hash
+	Account common.Address // 投票验证者的抵押账户
+	BLSSign []byte
+	From    common.Address
+}
+
+// HD_BridgeAttestMsg 验证者对跨链事件的签名
+type HD_BridgeAttestMsg struct {
+	Event []byte // rlp编码的跨链事件
+	Sign  common.Signature
+	From  common.Address
+}
+
+// HD_DoubleSignEvidenceMsg 验证者之间转发的双签证据
+type HD_DoubleSignEvidenceMsg struct {
+	Evidence []byte // rlp编码的双签证据
+	From     common.Address
+}
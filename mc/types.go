@@ -11,21 +11,16 @@ import (
 
 //by   // 2018-08-18由tx_pool.go转移到此
 const (
-	Heartbeat   = "Heartbeat"   // 心跳交易（广播区块Hash对99取余）
-	Publickey   = "SeedProof"   // 公钥交易
-	Privatekey  = "Seed"        // 私钥交易
-	CallTheRoll = "CallTheRoll" //点名交易  （广播节点随机连接1000个点）
+	Heartbeat    = "Heartbeat"    // 心跳交易（广播区块Hash对99取余）
+	Publickey    = "SeedProof"    // 公钥交易
+	VrfPublicKey = "VrfPublicKey" // VRF公钥交易
+	Privatekey   = "Seed"         // 私钥交易
+	CallTheRoll  = "CallTheRoll"  //点名交易  （广播节点随机连接1000个点）
+	SeedCommit   = "SeedCommit"   // 随机种子承诺交易
+	SeedReveal   = "SeedReveal"   // 随机种子揭示交易
+	SeedShare    = "SeedShare"    // 私钥交易解密份额
 )
 
-func ReturnBroadCastType() (bType map[string]bool) {
-	bType = make(map[string]bool)
-	bType[Heartbeat] = true
-	bType[Publickey] = true
-	bType[Privatekey] = true
-	bType[CallTheRoll] = true
-	return bType
-}
-
 type BlockToBucket struct {
 	Ms     []common.Address
 	Height *big.Int
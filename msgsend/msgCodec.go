@@ -43,6 +43,9 @@ func (self *HD) initCodec() {
 	self.registerCodec(mc.HD_V2_PowMiningRsp, new(powMiningRspMsgcV2))
 	self.registerCodec(mc.HD_V2_AIMiningRsp, new(aiMiningRspMsgcV2))
 	self.registerCodec(mc.HD_BasePowerResult, new(basePowerDifficultyMsgcV2))
+	self.registerCodec(mc.HD_CheckpointVote, new(checkpointVoteCodec))
+	self.registerCodec(mc.HD_BridgeAttest, new(bridgeAttestCodec))
+	self.registerCodec(mc.HD_DoubleSignEvidence, new(doubleSignEvidenceCodec))
 }
 
 //每个模块需要自己实现这两个接口
@@ -529,3 +532,75 @@ func (*fullBlockRspCodec) DecodeFn(data []byte, from common.Address) (interface{
 
 	return nil, errors.Errorf("rlp decode failed: %v", err)
 }
+
+////////////////////////////////////////////////////////////////////////
+// 检查点投票消息
+// msg code = mc.HD_CheckpointVote
+type checkpointVoteCodec struct {
+}
+
+func (*checkpointVoteCodec) EncodeFn(msg interface{}) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, errors.Errorf("json.Marshal failed: %s", err)
+	}
+	return data, nil
+}
+
+func (*checkpointVoteCodec) DecodeFn(data []byte, from common.Address) (interface{}, error) {
+	msg := new(mc.HD_CheckpointVoteMsg)
+	err := json.Unmarshal([]byte(data), msg)
+	if err != nil {
+		return nil, errors.Errorf("json.Unmarshal failed: %s", err)
+	}
+	msg.From.Set(from)
+	return msg, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// 跨链事件签名消息
+// msg code = mc.HD_BridgeAttest
+type bridgeAttestCodec struct {
+}
+
+func (*bridgeAttestCodec) EncodeFn(msg interface{}) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, errors.Errorf("json.Marshal failed: %s", err)
+	}
+	return data, nil
+}
+
+func (*bridgeAttestCodec) DecodeFn(data []byte, from common.Address) (interface{}, error) {
+	msg := new(mc.HD_BridgeAttestMsg)
+	err := json.Unmarshal([]byte(data), msg)
+	if err != nil {
+		return nil, errors.Errorf("json.Unmarshal failed: %s", err)
+	}
+	msg.From.Set(from)
+	return msg, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// 双签证据消息
+// msg code = mc.HD_DoubleSignEvidence
+type doubleSignEvidenceCodec struct {
+}
+
+func (*doubleSignEvidenceCodec) EncodeFn(msg interface{}) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, errors.Errorf("json.Marshal failed: %s", err)
+	}
+	return data, nil
+}
+
+func (*doubleSignEvidenceCodec) DecodeFn(data []byte, from common.Address) (interface{}, error) {
+	msg := new(mc.HD_DoubleSignEvidenceMsg)
+	err := json.Unmarshal([]byte(data), msg)
+	if err != nil {
+		return nil, errors.Errorf("json.Unmarshal failed: %s", err)
+	}
+	msg.From.Set(from)
+	return msg, nil
+}
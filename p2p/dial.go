@@ -11,9 +11,11 @@ import (
 	"fmt"
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"net"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/MatrixAINetwork/go-matrix/ca"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
 	"github.com/MatrixAINetwork/go-matrix/p2p/netutil"
@@ -84,6 +86,9 @@ type discoverTable interface {
 	GetNodeByAddress(target common.Address) *discover.Node
 	GetAllAddress() map[common.Address]*discover.Node
 	ResolveNode(addr common.Address, id discover.NodeID) *discover.Node
+	NodeRole(id discover.NodeID) common.RoleType
+	SetRole(role common.RoleType)
+	SetIP(ip net.IP)
 }
 
 // the dial history remembers recent dials.
@@ -225,6 +230,7 @@ func (s *dialstate) newDyncTasks(nRunning int, peers map[discover.NodeID]*Peer,
 		return newtasks
 	} else {
 		n := s.ntab.ReadRandomNodes(s.randomNodes)
+		s.sortByRole(s.randomNodes[:n])
 		for i := 0; i < needDynDials && i < n; i++ {
 			if s.isbootnode(s.randomNodes[i]) {
 				continue
@@ -269,6 +275,30 @@ func (s *dialstate) newDyncTasks(nRunning int, peers map[discover.NodeID]*Peer,
 	return newtasks
 }
 
+// sortByRole moves the nodes advertising a role relevant to the local one to
+// the front, so that they are dialed first.
+func (s *dialstate) sortByRole(nodes []*discover.Node) {
+	self := ca.GetRole()
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return roleRelevant(self, s.ntab.NodeRole(nodes[i].ID)) && !roleRelevant(self, s.ntab.NodeRole(nodes[j].ID))
+	})
+}
+
+// roleRelevant reports whether a peer with the given role is worth connecting
+// to for the local role: consensus nodes look for each other, miners for the
+// validators they submit to, and the other nodes for any elected node to sync from.
+func roleRelevant(self, peer common.RoleType) bool {
+	const consensus = common.RoleValidator | common.RoleBackupValidator | common.RoleBroadcast
+	switch {
+	case self&consensus != 0:
+		return peer&consensus != 0
+	case self&(common.RoleMiner|common.RoleBackupMiner|common.RoleInnerMiner) != 0:
+		return peer&(common.RoleValidator|common.RoleBackupValidator) != 0
+	default:
+		return peer&(consensus|common.RoleMiner|common.RoleBackupMiner|common.RoleInnerMiner) != 0
+	}
+}
+
 func (s *dialstate) newStaticTasks(nRunning int, peers map[discover.NodeID]*Peer, now time.Time) []task {
 	if s.start.IsZero() {
 		s.start = now
@@ -0,0 +1,136 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package discover
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/p2p/enr"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// Nodes advertise their Matrix role in a signed node record, attached to the
+// ping and pong packets after the regular fields. Old nodes ignore it as one of
+// the additional fields kept for forward compatibility.
+
+var errRecordOwner = errors.New("node record not signed by sender")
+
+// nodeRole is the role a node advertised, with the sequence number of the
+// record it was advertised in.
+type nodeRole struct {
+	role common.RoleType
+	seq  uint64
+}
+
+// makeRoleRecord creates the signed node record advertising role.
+func makeRoleRecord(priv *ecdsa.PrivateKey, role common.RoleType, seq uint64) (rlp.RawValue, error) {
+	var r enr.Record
+	r.Set(enr.Role(role))
+	r.SetSeq(seq)
+	if err := enr.SignV4(&r, priv); err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(r)
+}
+
+// decodeRoleRecord verifies the node record sent by id and returns the role it
+// advertises. Decoding the record checks its signature.
+func decodeRoleRecord(raw rlp.RawValue, id NodeID) (nodeRole, error) {
+	var r enr.Record
+	if err := rlp.DecodeBytes(raw, &r); err != nil {
+		return nodeRole{}, err
+	}
+	if !bytes.Equal(r.NodeAddr(), id[:]) {
+		return nodeRole{}, errRecordOwner
+	}
+	var role enr.Role
+	if err := r.Load(&role); err != nil {
+		return nodeRole{}, err
+	}
+	return nodeRole{role: common.RoleType(role), seq: r.Seq()}, nil
+}
+
+// setRole re-signs the local node record if the role changed.
+func (t *udp) setRole(role common.RoleType) {
+	t.recordMu.Lock()
+	defer t.recordMu.Unlock()
+
+	if t.role == role && t.record != nil {
+		return
+	}
+	// Sequence numbers derive from the clock to stay increasing across restarts
+	seq := uint64(time.Now().Unix())
+	if seq <= t.recordSeq {
+		seq = t.recordSeq + 1
+	}
+	record, err := makeRoleRecord(t.priv, role, seq)
+	if err != nil {
+		log.Error("Failed to sign node record", "role", role, "err", err)
+		return
+	}
+	t.role, t.record, t.recordSeq = role, record, seq
+	log.Debug("Updated node record", "role", role, "seq", t.recordSeq)
+}
+
+// localRecord returns the fields to attach to ping and pong packets.
+func (t *udp) localRecord() []rlp.RawValue {
+	t.recordMu.Lock()
+	defer t.recordMu.Unlock()
+
+	if t.record == nil {
+		return nil
+	}
+	return []rlp.RawValue{t.record}
+}
+
+// handleRecord stores the role advertised in the additional fields of a ping or
+// pong packet, if any.
+func (t *udp) handleRecord(from NodeID, rest []rlp.RawValue) {
+	if len(rest) == 0 {
+		return
+	}
+	role, err := decodeRoleRecord(rest[0], from)
+	if err != nil {
+		log.Trace("Invalid node record", "id", from, "err", err)
+		return
+	}
+	t.Table.setNodeRole(from, role)
+}
+
+// setNodeRole records the role of a node, ignoring records older than the
+// known one.
+func (tab *Table) setNodeRole(id NodeID, role nodeRole) {
+	tab.mutex.Lock()
+	defer tab.mutex.Unlock()
+
+	if known, ok := tab.roles[id]; ok && known.seq >= role.seq {
+		return
+	}
+	tab.roles[id] = role
+}
+
+// NodeRole returns the role advertised by a node, common.RoleNil if unknown.
+func (tab *Table) NodeRole(id NodeID) common.RoleType {
+	tab.mutex.Lock()
+	defer tab.mutex.Unlock()
+
+	if known, ok := tab.roles[id]; ok {
+		return known.role
+	}
+	return common.RoleNil
+}
+
+// SetRole updates the role advertised to the other nodes, which learn about it
+// on the next ping or pong exchanged.
+func (tab *Table) SetRole(role common.RoleType) {
+	if t, ok := tab.net.(*udp); ok {
+		t.setRole(role)
+	}
+}
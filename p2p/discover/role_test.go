@@ -0,0 +1,52 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package discover
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+)
+
+func TestRoleRecord(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	raw, err := makeRoleRecord(key, common.RoleValidator, 7)
+	if err != nil {
+		t.Fatalf("failed to make record: %v", err)
+	}
+	role, err := decodeRoleRecord(raw, PubkeyID(&key.PublicKey))
+	if err != nil {
+		t.Fatalf("failed to decode record: %v", err)
+	}
+	if role.role != common.RoleValidator || role.seq != 7 {
+		t.Errorf("role mismatch: have %v/%d, want %v/%d", role.role, role.seq, common.RoleValidator, 7)
+	}
+	// A record relayed for another node must be rejected
+	if _, err := decodeRoleRecord(raw, PubkeyID(&other.PublicKey)); err != errRecordOwner {
+		t.Errorf("foreign record error mismatch: have %v, want %v", err, errRecordOwner)
+	}
+	// Tampering with the record breaks the signature
+	raw[len(raw)-1]++
+	if _, err := decodeRoleRecord(raw, PubkeyID(&key.PublicKey)); err == nil {
+		t.Errorf("tampered record accepted")
+	}
+}
+
+func TestSetNodeRoleIgnoresOldRecords(t *testing.T) {
+	tab := &Table{roles: make(map[NodeID]nodeRole)}
+	id := NodeID{1}
+
+	tab.setNodeRole(id, nodeRole{role: common.RoleMiner, seq: 2})
+	tab.setNodeRole(id, nodeRole{role: common.RoleDefault, seq: 1})
+	if role := tab.NodeRole(id); role != common.RoleMiner {
+		t.Errorf("role mismatch: have %v, want %v", role, common.RoleMiner)
+	}
+	if role := tab.NodeRole(NodeID{2}); role != common.RoleNil {
+		t.Errorf("unknown node role mismatch: have %v, want %v", role, common.RoleNil)
+	}
+}
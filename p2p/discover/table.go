@@ -73,9 +73,13 @@ type Table struct {
 
 	nodeAddedHook   func(*Node) // for testing
 	nodeBindAddress map[common.Address]*Node
+	roles           map[NodeID]nodeRole // Roles advertised in the node records
 
 	net  transport
 	self *Node // metadata of the local node
+
+	selfMu    sync.Mutex
+	announced *Node // local node with the external IP found after startup, if any
 }
 
 type bondproc struct {
@@ -115,6 +119,7 @@ func newTable(t transport, ourID NodeID, ourAddr *net.UDPAddr, nodeDBPath string
 		self:            NewNode(ourID, ourAddr.IP, uint16(ourAddr.Port), uint16(ourAddr.Port)),
 		bonding:         make(map[NodeID]*bondproc),
 		nodeBindAddress: make(map[common.Address]*Node),
+		roles:           make(map[NodeID]nodeRole),
 		bondslots:       make(chan struct{}, maxBondingPingPongs),
 		refreshReq:      make(chan chan struct{}),
 		initDone:        make(chan struct{}),
@@ -156,9 +161,29 @@ func (tab *Table) seedRand() {
 // Self returns the local node.
 // The returned node should not be modified by the caller.
 func (tab *Table) Self() *Node {
+	tab.selfMu.Lock()
+	defer tab.selfMu.Unlock()
+
+	if tab.announced != nil {
+		return tab.announced
+	}
 	return tab.self
 }
 
+// SetIP updates the IP address the local node is announced with, after its
+// external IP address changed. Other nodes learn about it on the next ping.
+func (tab *Table) SetIP(ip net.IP) {
+	tab.selfMu.Lock()
+	announced := *tab.self
+	announced.IP = ip
+	tab.announced = &announced
+	tab.selfMu.Unlock()
+
+	if t, ok := tab.net.(*udp); ok {
+		t.setEndpointIP(ip)
+	}
+}
+
 func (tab *Table) GetAllAddress() map[common.Address]*Node {
 	tab.mutex.Lock()
 	defer tab.mutex.Unlock()
@@ -819,6 +844,7 @@ func (tab *Table) delete(node *Node) {
 	defer tab.mutex.Unlock()
 
 	tab.deleteInBucket(tab.bucket(node.sha), node)
+	delete(tab.roles, node.ID)
 }
 
 func (tab *Table) addIP(b *bucket, ip net.IP) bool {
@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/MatrixAINetwork/go-matrix/base58"
@@ -176,6 +177,7 @@ type udp struct {
 	conn        conn
 	netrestrict *netutil.Netlist
 	priv        *ecdsa.PrivateKey
+	endpointMu  sync.Mutex // Protects ourEndpoint
 	ourEndpoint rpcEndpoint
 
 	addpending chan *pending
@@ -190,6 +192,11 @@ type udp struct {
 	signature common.Signature
 	signTime  uint64
 
+	recordMu  sync.Mutex
+	role      common.RoleType // Role advertised in the local node record
+	record    rlp.RawValue    // Signed local node record
+	recordSeq uint64          // Sequence number of the local node record
+
 	*Table
 }
 
@@ -287,6 +294,7 @@ func newUDP(c conn, cfg Config) (*Table, *udp, error) {
 		return nil, nil, err
 	}
 	udp.Table = tab
+	udp.setRole(common.RoleDefault)
 
 	go udp.loop()
 	go udp.readLoop(cfg.Unhandled)
@@ -299,17 +307,37 @@ func (t *udp) close() {
 	// TODO: wait for the loops to end.
 }
 
+// setEndpointIP updates the IP address the local node sends in its pings.
+func (t *udp) setEndpointIP(ip net.IP) {
+	t.endpointMu.Lock()
+	defer t.endpointMu.Unlock()
+
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	t.ourEndpoint.IP = ip
+}
+
+// endpoint returns the endpoint the local node sends in its pings.
+func (t *udp) endpoint() rpcEndpoint {
+	t.endpointMu.Lock()
+	defer t.endpointMu.Unlock()
+
+	return t.ourEndpoint
+}
+
 // ping sends a ping message to the given node and waits for a reply.
 func (t *udp) ping(toid NodeID, toaddr *net.UDPAddr) error {
 	req := &ping{
 		Version:    Version,
-		From:       t.ourEndpoint,
+		From:       t.endpoint(),
 		NetWorkId:  t.netWorkId,
 		Address:    t.address,
 		Signature:  t.signature,
 		SignTime:   t.signTime,
 		To:         makeEndpoint(toaddr, 0), // TODO: maybe use known TCP port from DB
 		Expiration: uint64(time.Now().Add(expiration).Unix()),
+		Rest:       t.localRecord(),
 	}
 	packet, hash, err := encodePacket(t.priv, pingPacket, req)
 	if err != nil {
@@ -659,10 +687,12 @@ func (req *ping) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) er
 		w.SignTime = req.SignTime
 		t.add(w)
 	}
+	t.handleRecord(fromID, req.Rest)
 	t.send(from, pongPacket, &pong{
 		To:         makeEndpoint(from, req.From.TCP),
 		ReplyTok:   mac,
 		Expiration: uint64(time.Now().Add(expiration).Unix()),
+		Rest:       t.localRecord(),
 	})
 	if !t.handleReply(fromID, pingPacket, req) {
 		// Note: we're ignoring the provided IP address right now
@@ -680,6 +710,7 @@ func (req *pong) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) er
 	if !t.handleReply(fromID, pongPacket, req) {
 		return errUnsolicitedReply
 	}
+	t.handleRecord(fromID, req.Rest)
 	return nil
 }
 
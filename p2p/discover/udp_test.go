@@ -130,6 +130,26 @@ func TestUDP_pingTimeout(t *testing.T) {
 	}
 }
 
+func TestUDP_setIP(t *testing.T) {
+	t.Parallel()
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	ip := net.IP{203, 0, 113, 7}
+	test.table.SetIP(ip)
+	if self := test.table.Self(); !self.IP.Equal(ip) || self.ID != test.table.self.ID {
+		t.Errorf("self not announced with the new IP: got %v", self)
+	}
+	toaddr := &net.UDPAddr{IP: net.ParseIP("1.2.3.4"), Port: 2222}
+	toid := NodeID{1, 2, 3, 4}
+	go test.udp.ping(toid, toaddr)
+	test.waitPacketOut(func(p *ping) {
+		if !p.From.IP.Equal(ip) {
+			t.Errorf("got ping.From.IP %v, want %v", p.From.IP, ip)
+		}
+	})
+}
+
 func TestUDP_responseTimeouts(t *testing.T) {
 	t.Parallel()
 	test := newUDPTest(t)
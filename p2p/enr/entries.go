@@ -55,6 +55,12 @@ type UDP uint16
 
 func (v UDP) ENRKey() string { return "udp" }
 
+// Role is the "role" key, which holds the Matrix role of the node (validator,
+// miner, broadcast, ...) as a common.RoleType.
+type Role uint32
+
+func (v Role) ENRKey() string { return "role" }
+
 // ID is the "id" key, which holds the name of the identity scheme.
 type ID string
 
@@ -86,6 +86,10 @@ func (l *Linker) Start() {
 					continue
 				}
 
+				// Advertise the current role in the discovery node record
+				if ServerP2p.ntab != nil {
+					ServerP2p.ntab.SetRole(r.Role)
+				}
 				if r.Role <= common.RoleBucket {
 					l.role = common.RoleNil
 					break
@@ -332,7 +336,7 @@ func Record(id discover.NodeID) error {
 func (l *Linker) sendToAllPeersPing() {
 	peers := ServerP2p.Peers()
 	for _, peer := range peers {
-		Send(peer.msgReadWriter, common.BroadcastReqMsg, []uint8{0})
+		Send(peer.MsgReadWriter(), common.BroadcastReqMsg, []uint8{0})
 	}
 }
 
@@ -0,0 +1,235 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package p2p
+
+import (
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/event"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
+	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
+)
+
+// meshRoles are the roles taking part in the consensus, connected directly to
+// each other through the validator mesh.
+const meshRoles = common.RoleValidator | common.RoleBackupValidator | common.RoleBroadcast
+
+var (
+	meshMembersGauge   = metrics.NewRegisteredGauge("p2p/mesh/members", nil)
+	meshConnectedGauge = metrics.NewRegisteredGauge("p2p/mesh/connected", nil)
+)
+
+// ValidatorMesh is an overlay keeping direct connections between the elected
+// validators and broadcast nodes while the local node is one of them. Members
+// are dialed as soon as they are elected (including the next election during
+// the election duration) and pruned at the election boundaries. Their
+// connections are authenticated by matching the node identity proven in the
+// encryption handshake against the one the account announced in discovery, and
+// are allowed even above the peer limit like the trusted ones.
+type ValidatorMesh struct {
+	lock    sync.RWMutex
+	members map[common.Address]discover.NodeID // Elected accounts in the mesh, empty id if not resolved yet
+	ids     map[discover.NodeID]common.Address // Resolved node ids of the members
+	peers   map[common.Address]*Peer           // Connected members
+
+	sub  event.Subscription
+	quit chan struct{}
+}
+
+var Mesh = &ValidatorMesh{
+	members: make(map[common.Address]discover.NodeID),
+	ids:     make(map[discover.NodeID]common.Address),
+	peers:   make(map[common.Address]*Peer),
+	quit:    make(chan struct{}),
+}
+
+func (m *ValidatorMesh) Start() {
+	roleChan := make(chan mc.BlockToLinker)
+	m.sub, _ = mc.SubscribeEvent(mc.BlockToLinkers, roleChan)
+	defer m.sub.Unsubscribe()
+
+	for {
+		select {
+		case r := <-roleChan:
+			if r.BroadCastInterval == nil || r.Height == nil {
+				continue
+			}
+			if r.Role&meshRoles == 0 {
+				// Not in the consensus anymore, leave the mesh
+				m.update(nil, true)
+				continue
+			}
+			height := r.Height.Uint64()
+			m.update(meshTargets(), r.BroadCastInterval.IsReElectionNumber(height))
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+func (m *ValidatorMesh) Stop() {
+	select {
+	case m.quit <- struct{}{}:
+	default:
+	}
+}
+
+// meshTargets returns the accounts the local node should be connected to,
+// including the next elected ones during the election duration.
+func meshTargets() map[common.Address]bool {
+	var accounts []common.Address
+	if ca.InDuration() {
+		accounts = ca.GetRolesByGroupWithNextElect(meshRoles)
+	} else {
+		accounts = ca.GetRolesByGroup(meshRoles)
+	}
+	targets := make(map[common.Address]bool, len(accounts))
+	for _, account := range accounts {
+		if account != ServerP2p.ManAddress {
+			targets[account] = true
+		}
+	}
+	return targets
+}
+
+// update dials the targets not in the mesh yet and, if prune is set, drops the
+// members that are not targets anymore.
+func (m *ValidatorMesh) update(targets map[common.Address]bool, prune bool) {
+	m.lock.Lock()
+	var dial, drop []common.Address
+	for account := range targets {
+		if id, ok := m.members[account]; ok && id != EmptyNodeId {
+			continue
+		}
+		m.members[account] = m.resolve(account)
+		dial = append(dial, account)
+	}
+	if prune {
+		for account, id := range m.members {
+			if targets[account] {
+				continue
+			}
+			delete(m.members, account)
+			delete(m.ids, id)
+			delete(m.peers, account)
+			drop = append(drop, account)
+		}
+	}
+	meshMembersGauge.Update(int64(len(m.members)))
+	meshConnectedGauge.Update(int64(len(m.peers)))
+	m.lock.Unlock()
+
+	for _, account := range dial {
+		ServerP2p.AddPeerTask(account)
+	}
+	if len(drop) == 0 {
+		return
+	}
+	// Members still part of the topology in another role stay connected
+	topology := make(map[common.Address]bool)
+	for _, account := range ca.GetRolesByGroupWithNextElect(common.RoleAll) {
+		topology[account] = true
+	}
+	for _, account := range drop {
+		log.Debug("Pruning validator mesh member", "account", account.Hex())
+		if !topology[account] {
+			ServerP2p.RemovePeerByAddress(account)
+		}
+	}
+}
+
+// resolve looks up the node id announced by a member, assuming the lock is held.
+func (m *ValidatorMesh) resolve(account common.Address) discover.NodeID {
+	if ServerP2p.ntab == nil {
+		return EmptyNodeId
+	}
+	id := ServerP2p.ConvertAddressToId(account)
+	if id != EmptyNodeId {
+		m.ids[id] = account
+	}
+	return id
+}
+
+// isMember reports whether the node is an authenticated mesh member.
+func (m *ValidatorMesh) isMember(id discover.NodeID) bool {
+	m.lock.RLock()
+	_, ok := m.ids[id]
+	m.lock.RUnlock()
+	if ok {
+		return true
+	}
+	// The member may have shown up in discovery after being elected
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for account, known := range m.members {
+		if known == EmptyNodeId {
+			if m.members[account] = m.resolve(account); m.members[account] == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addPeer registers a connected peer if it is a mesh member.
+func (m *ValidatorMesh) addPeer(p *Peer) {
+	if !m.isMember(p.ID()) {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if account, ok := m.ids[p.ID()]; ok {
+		m.peers[account] = p
+		meshConnectedGauge.Update(int64(len(m.peers)))
+	}
+}
+
+// removePeer unregisters a disconnected peer.
+func (m *ValidatorMesh) removePeer(p *Peer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if account, ok := m.ids[p.ID()]; ok && m.peers[account] == p {
+		delete(m.peers, account)
+		meshConnectedGauge.Update(int64(len(m.peers)))
+	}
+}
+
+// peer returns the direct connection to a mesh member, nil if not connected.
+func (m *ValidatorMesh) peer(account common.Address) *Peer {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.peers[account]
+}
+
+// MeshInfo describes a member of the validator mesh.
+type MeshInfo struct {
+	Account   common.Address `json:"account"`
+	ID        string         `json:"id"`
+	Connected bool           `json:"connected"`
+}
+
+// Members returns the current members of the validator mesh.
+func (m *ValidatorMesh) Members() []MeshInfo {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	infos := make([]MeshInfo, 0, len(m.members))
+	for account, id := range m.members {
+		info := MeshInfo{Account: account, Connected: m.peers[account] != nil}
+		if id != EmptyNodeId {
+			info.ID = id.String()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
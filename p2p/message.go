@@ -102,6 +102,9 @@ func SendToSingle(addr common.Address, msgCode uint64, data interface{}) error {
 	if addr == ServerP2p.ManAddress {
 		return nil
 	}
+	if peer := Mesh.peer(addr); peer != nil {
+		return Send(peer.MsgReadWriter(), msgCode, data)
+	}
 	id := ServerP2p.ConvertAddressToId(addr)
 	if id == EmptyNodeId {
 		log.Error("send to single peer failed, id convert failed", "peer addr", addr)
@@ -117,6 +120,23 @@ func SendToSingle(addr common.Address, msgCode uint64, data interface{}) error {
 	return ErrCanNotFindPeer
 }
 
+// IsConnected reports whether the node of the given account is a connected peer.
+func IsConnected(addr common.Address) bool {
+	if Mesh.peer(addr) != nil {
+		return true
+	}
+	id := ServerP2p.ConvertAddressToId(addr)
+	if id == EmptyNodeId {
+		return false
+	}
+	for _, peer := range ServerP2p.Peers() {
+		if id == peer.ID() {
+			return true
+		}
+	}
+	return false
+}
+
 // SendToGroup send message to a group.
 func SendToGroupWithBackup(to common.RoleType, msgCode uint64, data interface{}) error {
 	address := ca.GetRolesByGroupWithNextElect(to)
@@ -125,6 +145,12 @@ func SendToGroupWithBackup(to common.RoleType, msgCode uint64, data interface{})
 		if addr == ServerP2p.ManAddress {
 			continue
 		}
+		if peer := Mesh.peer(addr); peer != nil {
+			if err := Send(peer.MsgReadWriter(), msgCode, data); err != nil {
+				log.Error("send to group with backup", "error:", err)
+			}
+			continue
+		}
 		id := ServerP2p.ConvertAddressToId(addr)
 		if id == EmptyNodeId {
 			log.Error("send to single peer failed, id convert failed", "peer addr", addr)
@@ -154,6 +180,12 @@ func SendToGroup(to common.RoleType, msgCode uint64, data interface{}) error {
 		if addr == ServerP2p.ManAddress {
 			continue
 		}
+		// Consensus messages go straight over the validator mesh
+		if peer := Mesh.peer(addr); peer != nil {
+			if err := Send(peer.MsgReadWriter(), msgCode, data); err == nil {
+				continue
+			}
+		}
 		bSend := false
 
 		id := ServerP2p.ConvertAddressToId(addr)
@@ -0,0 +1,170 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package p2p
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/metrics"
+)
+
+const (
+	// Port mappings are renewed well before their lease expires, as consumer
+	// routers drop them on reboots or silently expire them early.
+	natMapLifetime  = 10 * time.Minute
+	natMapRenewal   = 3 * time.Minute
+	natMapRetry     = 30 * time.Second
+	extIPCheckCycle = 5 * time.Minute
+
+	// Sources of the external endpoint reported in the node infos.
+	extSourceNAT  = "nat"
+	extSourceSTUN = "stun"
+)
+
+var natMapFailMeter = metrics.NewRegisteredMeter("p2p/nat/MapFailures", nil)
+
+// ExternalInfo describes the endpoint the node is reachable at from the internet.
+type ExternalInfo struct {
+	IP     string         `json:"ip"`     // External IP address
+	Source string         `json:"source"` // How the address was found: "nat" or "stun"
+	Ports  map[string]int `json:"ports"`  // Mapped external ports by name
+}
+
+// natState tracks the external endpoint of the server.
+type natState struct {
+	lock   sync.Mutex
+	ip     net.IP
+	source string
+	ports  map[string]int
+}
+
+func (s *natState) setIP(ip net.IP, source string) (changed bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	changed = !s.ip.Equal(ip)
+	s.ip, s.source = ip, source
+	return changed
+}
+
+func (s *natState) setPort(name string, port int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.ports == nil {
+		s.ports = make(map[string]int)
+	}
+	if port == 0 {
+		delete(s.ports, name)
+	} else {
+		s.ports[name] = port
+	}
+}
+
+func (s *natState) info() *ExternalInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.ip == nil && len(s.ports) == 0 {
+		return nil
+	}
+	info := &ExternalInfo{Source: s.source, Ports: make(map[string]int, len(s.ports))}
+	if s.ip != nil {
+		info.IP = s.ip.String()
+	}
+	for name, port := range s.ports {
+		info.Ports[name] = port
+	}
+	return info
+}
+
+// mapPort keeps a port mapped through the NAT until the server stops, renewing
+// the lease periodically and retrying quickly after failures.
+func (srv *Server) mapPort(protocol string, port int, name string) {
+	defer srv.loopWG.Done()
+
+	log := srv.log.New("proto", protocol, "extport", port, "intport", port, "interface", srv.NAT)
+	refresh := time.NewTimer(0)
+	defer func() {
+		refresh.Stop()
+		log.Debug("Deleting port mapping")
+		srv.NAT.DeleteMapping(protocol, port, port)
+	}()
+	mapped := false
+	for {
+		select {
+		case <-srv.quit:
+			return
+		case <-refresh.C:
+		}
+		if err := srv.NAT.AddMapping(protocol, port, port, name, natMapLifetime); err != nil {
+			natMapFailMeter.Mark(1)
+			if mapped {
+				log.Warn("Port mapping lost", "err", err)
+			} else {
+				log.Debug("Couldn't add port mapping", "err", err)
+			}
+			mapped = false
+			srv.nat.setPort(name, 0)
+			refresh.Reset(natMapRetry)
+			continue
+		}
+		if !mapped {
+			log.Info("Mapped network port")
+		}
+		mapped = true
+		srv.nat.setPort(name, port)
+		refresh.Reset(natMapRenewal)
+	}
+}
+
+// externalIP finds the external IP address of the node, asking the NAT device
+// first and falling back to the STUN servers. Nothing is looked up if NAT
+// traversal is disabled.
+func (srv *Server) externalIP() (net.IP, string) {
+	if srv.NAT == nil {
+		return nil, ""
+	}
+	if ip, err := srv.NAT.ExternalIP(); err == nil {
+		return ip, extSourceNAT
+	}
+	for _, server := range srv.STUNServers {
+		addr, err := stunExternalAddr(server)
+		if err != nil {
+			srv.log.Debug("STUN request failed", "server", server, "err", err)
+			continue
+		}
+		return addr.IP, extSourceSTUN
+	}
+	return nil, ""
+}
+
+// natLoop periodically checks the external IP address, announcing the node
+// with the new address when it changes.
+func (srv *Server) natLoop() {
+	defer srv.loopWG.Done()
+
+	check := time.NewTicker(extIPCheckCycle)
+	defer check.Stop()
+	for {
+		select {
+		case <-check.C:
+			ip, source := srv.externalIP()
+			if ip == nil {
+				continue
+			}
+			if srv.nat.setIP(ip, source) {
+				srv.log.Warn("External IP address changed", "ip", ip, "source", source)
+				if srv.ntab != nil {
+					srv.ntab.SetIP(ip)
+				}
+			}
+		case <-srv.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package p2p
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
+)
+
+// nodeListPollInterval is the interval at which the node list files are
+// checked for modifications.
+const nodeListPollInterval = 10 * time.Second
+
+// nodeList is a set of nodes persisted as a JSON list of enode URLs, the
+// format of the static-nodes.json and trusted-nodes.json files.
+type nodeList struct {
+	path    string
+	modTime time.Time
+	nodes   map[discover.NodeID]*discover.Node
+}
+
+// load reads the list from its file. A missing file is an empty list.
+func (l *nodeList) load() (map[discover.NodeID]*discover.Node, error) {
+	nodes := make(map[discover.NodeID]*discover.Node)
+	info, err := os.Stat(l.path)
+	if os.IsNotExist(err) {
+		l.modTime = time.Time{}
+		return nodes, nil
+	} else if err != nil {
+		return nil, err
+	}
+	// Invalid files are reported once, not on every poll
+	l.modTime = info.ModTime()
+
+	var urls []string
+	if err := common.LoadJSON(l.path, &urls); err != nil {
+		return nil, err
+	}
+	for _, url := range urls {
+		if url == "" {
+			continue
+		}
+		node, err := discover.ParseNode(url)
+		if err != nil {
+			return nil, err
+		}
+		nodes[node.ID] = node
+	}
+	return nodes, nil
+}
+
+// save writes the list to its file.
+func (l *nodeList) save() error {
+	urls := make([]string, 0, len(l.nodes))
+	for _, node := range l.nodes {
+		urls = append(urls, node.String())
+	}
+	data, err := json.MarshalIndent(urls, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(l.path, data, 0600); err != nil {
+		return err
+	}
+	if info, err := os.Stat(l.path); err == nil {
+		l.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// modified reports whether the file changed since it was last loaded or saved.
+func (l *nodeList) modified() bool {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return !l.modTime.IsZero()
+	}
+	return !info.ModTime().Equal(l.modTime)
+}
+
+// nodeLists holds the persisted static and trusted node lists of the server.
+type nodeLists struct {
+	lock    sync.Mutex
+	static  *nodeList
+	trusted *nodeList
+}
+
+// loadNodeLists reads the persisted node lists on startup, adding their nodes
+// to the configured static and trusted ones.
+func (srv *Server) loadNodeLists() error {
+	srv.lists.lock.Lock()
+	defer srv.lists.lock.Unlock()
+
+	if srv.StaticNodesFile != "" {
+		srv.lists.static = &nodeList{path: srv.StaticNodesFile}
+		nodes, err := srv.lists.static.load()
+		if err != nil {
+			return err
+		}
+		srv.lists.static.nodes = nodes
+		for _, node := range nodes {
+			srv.StaticNodes = append(srv.StaticNodes, node)
+		}
+	}
+	if srv.TrustedNodesFile != "" {
+		srv.lists.trusted = &nodeList{path: srv.TrustedNodesFile}
+		nodes, err := srv.lists.trusted.load()
+		if err != nil {
+			return err
+		}
+		srv.lists.trusted.nodes = nodes
+		for _, node := range nodes {
+			srv.TrustedNodes = append(srv.TrustedNodes, node)
+		}
+	}
+	return nil
+}
+
+// ReloadNodeLists re-reads the persisted static and trusted node lists,
+// connecting to the nodes added and releasing the ones removed.
+func (srv *Server) ReloadNodeLists() error {
+	srv.lists.lock.Lock()
+	defer srv.lists.lock.Unlock()
+
+	if list := srv.lists.static; list != nil {
+		nodes, err := list.load()
+		if err != nil {
+			return err
+		}
+		added, removed := diffNodes(list.nodes, nodes)
+		for _, node := range added {
+			srv.AddPeer(node)
+		}
+		for _, node := range removed {
+			srv.RemovePeer(node)
+		}
+		list.nodes = nodes
+		srv.log.Info("Reloaded static nodes", "count", len(nodes), "added", len(added), "removed", len(removed))
+	}
+	if list := srv.lists.trusted; list != nil {
+		nodes, err := list.load()
+		if err != nil {
+			return err
+		}
+		added, removed := diffNodes(list.nodes, nodes)
+		for _, node := range added {
+			srv.setTrusted(node, true)
+		}
+		for _, node := range removed {
+			srv.setTrusted(node, false)
+		}
+		list.nodes = nodes
+		srv.log.Info("Reloaded trusted nodes", "count", len(nodes), "added", len(added), "removed", len(removed))
+	}
+	return nil
+}
+
+// AddTrustedPeer adds the given node to the trusted nodes, which are always
+// allowed to connect even above the peer limit and are never evicted. The
+// node is persisted in the trusted node list if one is configured.
+func (srv *Server) AddTrustedPeer(node *discover.Node) error {
+	srv.setTrusted(node, true)
+	return srv.persistTrusted(node, true)
+}
+
+// RemoveTrustedPeer removes the given node from the trusted nodes and from the
+// persisted trusted node list. The connection to the node, if any, is kept as
+// a regular one.
+func (srv *Server) RemoveTrustedPeer(node *discover.Node) error {
+	srv.setTrusted(node, false)
+	return srv.persistTrusted(node, false)
+}
+
+func (srv *Server) setTrusted(node *discover.Node, trusted bool) {
+	ch := srv.addtrusted
+	if !trusted {
+		ch = srv.removetrusted
+	}
+	select {
+	case ch <- node:
+	case <-srv.quit:
+	}
+}
+
+func (srv *Server) persistTrusted(node *discover.Node, trusted bool) error {
+	srv.lists.lock.Lock()
+	defer srv.lists.lock.Unlock()
+
+	list := srv.lists.trusted
+	if list == nil {
+		return nil
+	}
+	if _, ok := list.nodes[node.ID]; ok == trusted {
+		return nil
+	}
+	if trusted {
+		list.nodes[node.ID] = node
+	} else {
+		delete(list.nodes, node.ID)
+	}
+	return list.save()
+}
+
+// watchNodeLists reloads the node lists whenever their files are modified.
+func (srv *Server) watchNodeLists() {
+	defer srv.loopWG.Done()
+
+	poll := time.NewTicker(nodeListPollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case <-poll.C:
+			srv.lists.lock.Lock()
+			modified := (srv.lists.static != nil && srv.lists.static.modified()) ||
+				(srv.lists.trusted != nil && srv.lists.trusted.modified())
+			srv.lists.lock.Unlock()
+
+			if modified {
+				if err := srv.ReloadNodeLists(); err != nil {
+					srv.log.Warn("Failed to reload node lists", "err", err)
+				}
+			}
+		case <-srv.quit:
+			return
+		}
+	}
+}
+
+// diffNodes returns the nodes of next not in prev and the ones of prev not in next.
+func diffNodes(prev, next map[discover.NodeID]*discover.Node) (added, removed []*discover.Node) {
+	for id, node := range next {
+		if _, ok := prev[id]; !ok {
+			added = append(added, node)
+		}
+	}
+	for id, node := range prev {
+		if _, ok := next[id]; !ok {
+			removed = append(removed, node)
+		}
+	}
+	return added, removed
+}
@@ -84,6 +84,7 @@ type PeerEvent struct {
 // Peer represents a connected remote node.
 type Peer struct {
 	msgReadWriter MsgReadWriter
+	rwLock        sync.RWMutex // Protects msgReadWriter, read by the senders of other goroutines
 
 	rw      *conn
 	running map[string]*protoRW
@@ -97,6 +98,8 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	score peerScore // Usefulness of the peer, used to evict useless peers
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -155,9 +158,20 @@ func (p *Peer) Inbound() bool {
 
 // MsgReadWriter return ReadWriter between peers.
 func (p *Peer) MsgReadWriter() MsgReadWriter {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
 	return p.msgReadWriter
 }
 
+// SetMsgReadWriter replaces the writer used by the p2p level senders (Send,
+// SendToSingle, SendToGroup, ...), allowing the protocol to post-process the
+// messages written to the peer.
+func (p *Peer) SetMsgReadWriter(rw MsgReadWriter) {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	p.msgReadWriter = rw
+}
+
 func newPeer(conn *conn, protocols []Protocol) *Peer {
 	protomap := matchProtocols(protocols, conn.caps, conn)
 	p := &Peer{
@@ -237,6 +251,7 @@ func (p *Peer) pingLoop() {
 				p.protoErr <- err
 				return
 			}
+			p.score.ping(time.Now())
 			ping.Reset(pingInterval)
 		case <-p.closed:
 			return
@@ -265,6 +280,9 @@ func (p *Peer) handle(msg Msg) error {
 	case msg.Code == pingMsg:
 		msg.Discard()
 		go SendItems(p.rw, pongMsg)
+	case msg.Code == pongMsg:
+		msg.Discard()
+		p.score.pong(msg.ReceivedAt)
 	case msg.Code == discMsg:
 		var reason [1]DiscReason
 		// This is the last message. We don't need to discard or
@@ -338,7 +356,7 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 		if p.events != nil {
 			rw = newMsgEventer(rw, p.events, p.ID(), proto.Name)
 		}
-		p.msgReadWriter = rw
+		p.SetMsgReadWriter(rw)
 		p.log.Trace(fmt.Sprintf("Starting protocol %s/%d", proto.Name, proto.Version))
 		go func() {
 			err := proto.Run(p, rw)
@@ -408,9 +426,10 @@ func (rw *protoRW) ReadMsg() (Msg, error) {
 // peer. Sub-protocol independent fields are contained and initialized here, with
 // protocol specifics delegated to all connected sub-protocols.
 type PeerInfo struct {
-	ID      string   `json:"id"`   // Unique node identifier (also the encryption key)
-	Name    string   `json:"name"` // Name of the node, including client type, version, OS, custom data
-	Caps    []string `json:"caps"` // Sum-protocols advertised by this particular peer
+	ID      string   `json:"id"`    // Unique node identifier (also the encryption key)
+	Name    string   `json:"name"`  // Name of the node, including client type, version, OS, custom data
+	Caps    []string `json:"caps"`  // Sum-protocols advertised by this particular peer
+	Score   float64  `json:"score"` // Usefulness score of the peer, the lowest ones are evicted first
 	Network struct {
 		LocalAddress  string `json:"localAddress"`  // Local endpoint of the TCP data connection
 		RemoteAddress string `json:"remoteAddress"` // Remote endpoint of the TCP data connection
@@ -440,6 +459,7 @@ func (p *Peer) Info() *PeerInfo {
 	info.Network.Inbound = p.rw.is(inboundConn)
 	info.Network.Trusted = p.rw.is(trustedConn)
 	info.Network.Static = p.rw.is(staticDialedConn)
+	info.Score = p.Score()
 
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
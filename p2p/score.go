@@ -0,0 +1,189 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package p2p
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/mclock"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
+	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
+)
+
+const (
+	// scoreEvictInterval is the interval at which the peer scores are decayed
+	// and, when the peer slots are exhausted, the least useful peer is dropped.
+	scoreEvictInterval = time.Minute
+
+	// scoreGracePeriod is the time a new peer gets to prove its usefulness
+	// before it may be evicted.
+	scoreGracePeriod = 3 * time.Minute
+
+	// scoreDecay is the factor the scores are multiplied with on every round,
+	// so that the recent behaviour of a peer outweighs its history.
+	scoreDecay = 0.5
+
+	blockScore   = 10.0  // Score of every valid block (or header, body) delivered
+	txScore      = 0.1   // Score of every transaction delivered
+	invalidScore = -20.0 // Score of every invalid or unrequested message
+
+	// latencyScore is the score lost per latencyUnit of ping round trip time.
+	latencyScore = -1.0
+	latencyUnit  = 100 * time.Millisecond
+)
+
+var scoreEvictMeter = metrics.NewRegisteredMeter("p2p/ScoreEvictions", nil)
+
+// peerScore tracks how useful the data delivered by a peer is.
+type peerScore struct {
+	blocks  float64       // Decayed number of valid blocks delivered
+	txs     float64       // Decayed number of transactions delivered
+	invalid float64       // Decayed number of invalid messages
+	latency time.Duration // Moving average of the ping round trip time
+	pinged  time.Time     // Time of the last ping awaiting its pong, zero if none
+
+	lock sync.Mutex
+}
+
+// value calculates the current score.
+func (s *peerScore) value() float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.blocks*blockScore + s.txs*txScore + s.invalid*invalidScore +
+		float64(s.latency)/float64(latencyUnit)*latencyScore
+}
+
+// decay ages the delivery counters.
+func (s *peerScore) decay() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.blocks *= scoreDecay
+	s.txs *= scoreDecay
+	s.invalid *= scoreDecay
+}
+
+// ping records the time a ping was sent to the peer.
+func (s *peerScore) ping(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.pinged = now
+}
+
+// pong updates the latency with the round trip of the pending ping.
+func (s *peerScore) pong(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.pinged.IsZero() {
+		return
+	}
+	rtt := now.Sub(s.pinged)
+	s.pinged = time.Time{}
+
+	if s.latency == 0 {
+		s.latency = rtt
+	} else {
+		s.latency = (s.latency*3 + rtt) / 4
+	}
+}
+
+// MarkBlocks records that the peer delivered n valid blocks, headers or bodies.
+func (p *Peer) MarkBlocks(n int) {
+	p.score.lock.Lock()
+	p.score.blocks += float64(n)
+	p.score.lock.Unlock()
+}
+
+// MarkTxs records that the peer delivered n transactions.
+func (p *Peer) MarkTxs(n int) {
+	p.score.lock.Lock()
+	p.score.txs += float64(n)
+	p.score.lock.Unlock()
+}
+
+// MarkInvalid records that the peer sent an invalid or unrequested message.
+func (p *Peer) MarkInvalid() {
+	p.score.lock.Lock()
+	p.score.invalid++
+	p.score.lock.Unlock()
+}
+
+// Score returns the usefulness score of the peer, higher is better.
+func (p *Peer) Score() float64 {
+	return p.score.value()
+}
+
+// Latency returns the average ping round trip time of the peer.
+func (p *Peer) Latency() time.Duration {
+	p.score.lock.Lock()
+	defer p.score.lock.Unlock()
+
+	return p.score.latency
+}
+
+// consensusPeers returns the accounts of the elected validators, whose peers
+// are kept connected while the local node takes part in the consensus.
+var consensusPeers = func() map[common.Address]bool {
+	switch ca.GetRole() {
+	case common.RoleValidator, common.RoleBackupValidator, common.RoleBroadcast:
+	default:
+		return nil
+	}
+	var validators []common.Address
+	if ca.InDuration() {
+		validators = ca.GetRolesByGroupWithNextElect(common.RoleValidator | common.RoleBackupValidator)
+	} else {
+		validators = ca.GetRolesByGroup(common.RoleValidator | common.RoleBackupValidator)
+	}
+	accounts := make(map[common.Address]bool, len(validators))
+	for _, account := range validators {
+		accounts[account] = true
+	}
+	return accounts
+}
+
+// evictUselessPeers decays the peer scores and, if the peer slots are exhausted,
+// drops the lowest scoring peer to make room for a more useful one. Trusted and
+// static peers, peers still in their grace period and elected validator peers
+// during consensus are never evicted.
+func (srv *Server) evictUselessPeers(peers map[discover.NodeID]*Peer) {
+	for _, p := range peers {
+		p.score.decay()
+	}
+	if len(peers) < srv.MaxPeers {
+		return
+	}
+	validators := consensusPeers()
+
+	candidates := make([]*Peer, 0, len(peers))
+	for _, p := range peers {
+		if p.rw.is(trustedConn | staticDialedConn) {
+			continue
+		}
+		if time.Duration(mclock.Now()-p.created) < scoreGracePeriod {
+			continue
+		}
+		if len(validators) > 0 && srv.ntab != nil && validators[srv.ConvertIdToAddress(p.ID())] {
+			continue
+		}
+		candidates = append(candidates, p)
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Score() < candidates[j].Score() })
+
+	worst := candidates[0]
+	worst.log.Debug("Dropping least useful peer", "score", worst.Score(), "latency", worst.Latency(), "peers", len(peers))
+	scoreEvictMeter.Mark(1)
+	worst.Disconnect(DiscUselessPeer)
+}
@@ -90,6 +90,12 @@ type Config struct {
 	// allowed to connect, even above the peer limit.
 	TrustedNodes []*discover.Node
 
+	// StaticNodesFile and TrustedNodesFile are the JSON lists of enode URLs
+	// persisting the static and trusted nodes. They are loaded on startup
+	// and reloaded whenever modified.
+	StaticNodesFile  string `toml:",omitempty"`
+	TrustedNodesFile string `toml:",omitempty"`
+
 	// Connectivity can be restricted to certain IP networks.
 	// If this option is set to a non-nil value, only hosts which match one of the
 	// IP networks contained in the list are considered.
@@ -117,6 +123,10 @@ type Config struct {
 	// Internet.
 	NAT nat.Interface `toml:",omitempty"`
 
+	// STUNServers are queried for the external IP address when the NAT
+	// port mapper can't tell it.
+	STUNServers []string `toml:",omitempty"`
+
 	// If Dialer is set to a non-nil value, the given Dialer
 	// is used to dial outbound peer connections.
 	Dialer NodeDialer `toml:"-"`
@@ -124,6 +134,12 @@ type Config struct {
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 
+	// Transport selects the transport carrying the RLPx connections, either
+	// TransportTCP (the default) or TransportQUIC. With QUIC the server also
+	// keeps accepting TCP connections and dials over TCP the nodes not
+	// reachable over QUIC.
+	Transport string `toml:",omitempty"`
+
 	// If EnableMsgEvents is set then the server will emit PeerEvents
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
@@ -155,6 +171,9 @@ type Server struct {
 
 	ntab         discoverTable
 	listener     net.Listener
+	quicListener net.Listener
+	nat          natState
+	lists        nodeLists
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
 	//DiscV5       *discv5.Network
@@ -166,6 +185,8 @@ type Server struct {
 	quit          chan struct{}
 	addstatic     chan *discover.Node
 	removestatic  chan *discover.Node
+	addtrusted    chan *discover.Node
+	removetrusted chan *discover.Node
 	posthandshake chan *conn
 	addpeer       chan *conn
 	delpeer       chan peerDrop
@@ -259,6 +280,14 @@ func (c *conn) is(f connFlag) bool {
 	return c.flags&f != 0
 }
 
+func (c *conn) set(f connFlag, val bool) {
+	if val {
+		c.flags |= f
+	} else {
+		c.flags &^= f
+	}
+}
+
 // Peers returns all connected peers.
 func (srv *Server) Peers() []*Peer {
 	var ps []*Peer
@@ -410,6 +439,9 @@ func (srv *Server) Stop() {
 		// this unblocks listener Accept
 		srv.listener.Close()
 	}
+	if srv.quicListener != nil {
+		srv.quicListener.Close()
+	}
 	close(srv.quit)
 	srv.loopWG.Wait()
 }
@@ -463,8 +495,20 @@ func (srv *Server) Start() (err error) {
 	if srv.newTransport == nil {
 		srv.newTransport = newRLPX
 	}
+	switch srv.Transport {
+	case "", TransportTCP:
+	case TransportQUIC:
+		if quicListen == nil {
+			return errNoQUIC
+		}
+	default:
+		return fmt.Errorf("unknown p2p transport %q", srv.Transport)
+	}
 	if srv.Dialer == nil {
 		srv.Dialer = TCPDialer{&net.Dialer{Timeout: defaultDialTimeout}}
+		if srv.Transport == TransportQUIC {
+			srv.Dialer = QUICDialer{Timeout: defaultDialTimeout, Fallback: srv.Dialer}
+		}
 	}
 	srv.quit = make(chan struct{})
 	srv.addpeer = make(chan *conn)
@@ -472,6 +516,8 @@ func (srv *Server) Start() (err error) {
 	srv.posthandshake = make(chan *conn)
 	srv.addstatic = make(chan *discover.Node)
 	srv.removestatic = make(chan *discover.Node)
+	srv.addtrusted = make(chan *discover.Node)
+	srv.removetrusted = make(chan *discover.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 	srv.tasks = make(map[common.Address]*taskManager)
@@ -495,10 +541,11 @@ func (srv *Server) Start() (err error) {
 		realaddr = conn.LocalAddr().(*net.UDPAddr)
 		if srv.NAT != nil {
 			if !realaddr.IP.IsLoopback() {
-				go nat.Map(srv.NAT, srv.quit, "udp", realaddr.Port, realaddr.Port, "matrix discovery")
+				srv.loopWG.Add(1)
+				go srv.mapPort("udp", realaddr.Port, "matrix discovery")
 			}
-			// TODO: react to external IP changes over time.
-			if ext, err := srv.NAT.ExternalIP(); err == nil {
+			if ext, source := srv.externalIP(); ext != nil {
+				srv.nat.setIP(ext, source)
 				realaddr = &net.UDPAddr{IP: ext, Port: realaddr.Port}
 			}
 		}
@@ -551,6 +598,9 @@ func (srv *Server) Start() (err error) {
 	//	srv.DiscV5 = ntab
 	//}
 
+	if err := srv.loadNodeLists(); err != nil {
+		return err
+	}
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
 
@@ -569,6 +619,15 @@ func (srv *Server) Start() (err error) {
 		srv.log.Warn("P2P server will be useless, neither dialing nor listening")
 	}
 
+	if srv.NAT != nil {
+		srv.loopWG.Add(1)
+		go srv.natLoop()
+	}
+	if srv.StaticNodesFile != "" || srv.TrustedNodesFile != "" {
+		srv.loopWG.Add(1)
+		go srv.watchNodeLists()
+	}
+
 	srv.loopWG.Add(1)
 	//add by zw
 	go Receiveudp()
@@ -582,6 +641,7 @@ func (srv *Server) Start() (err error) {
 
 	go Buckets.Start()
 	go Link.Start()
+	go Mesh.Start()
 	go UdpStart()
 
 	return nil
@@ -597,14 +657,33 @@ func (srv *Server) startListening() error {
 	srv.ListenAddr = laddr.String()
 	srv.listener = listener
 	srv.loopWG.Add(1)
-	go srv.listenLoop()
+	go srv.listenLoop(listener)
 	// Map the TCP listening port if NAT is configured.
 	if !laddr.IP.IsLoopback() && srv.NAT != nil {
 		srv.loopWG.Add(1)
-		go func() {
-			nat.Map(srv.NAT, srv.quit, "tcp", laddr.Port, laddr.Port, "matrix p2p")
-			srv.loopWG.Done()
-		}()
+		go srv.mapPort("tcp", laddr.Port, "matrix p2p")
+	}
+	if srv.Transport == TransportQUIC {
+		return srv.startQUICListening(laddr)
+	}
+	return nil
+}
+
+// startQUICListening launches the QUIC listener next to the TCP one. As the
+// discovery owns the UDP port equal to the TCP one, QUIC listens on the port
+// following it.
+func (srv *Server) startQUICListening(laddr *net.TCPAddr) error {
+	qaddr := &net.UDPAddr{IP: laddr.IP, Port: laddr.Port + quicPortOffset}
+	listener, err := quicListen(qaddr.String())
+	if err != nil {
+		return err
+	}
+	srv.quicListener = listener
+	srv.loopWG.Add(1)
+	go srv.listenLoop(listener)
+	if !laddr.IP.IsLoopback() && srv.NAT != nil {
+		srv.loopWG.Add(1)
+		go srv.mapPort("udp", qaddr.Port, "matrix quic")
 	}
 	return nil
 }
@@ -630,10 +709,13 @@ func (srv *Server) run(dialstate dialer) {
 		queuedDyncTasks        []task
 		timeoutTimerdyncmanger = time.NewTimer(time.Second * dyncmangerTimertime)
 		timeoutTimerdyncdial   = time.NewTimer(time.Second * dyncdialTimertime)
+		scoreTicker            = time.NewTicker(scoreEvictInterval)
 	)
+	defer scoreTicker.Stop()
+
 	// Put trusted nodes into a map to speed up checks.
-	// Trusted peers are loaded on startup and cannot be
-	// modified while the server is running.
+	// Trusted peers are loaded on startup and can be
+	// added or removed through AddTrustedPeer/RemoveTrustedPeer.
 	for _, n := range srv.TrustedNodes {
 		trusted[n.ID] = true
 	}
@@ -700,6 +782,9 @@ running:
 				<-timeoutTimerdyncmanger.C
 			}
 			timeoutTimerdyncmanger.Reset(time.Second * dyncmangerTimertime)
+		case <-scoreTicker.C:
+			// Make room for more useful peers if the slots are exhausted
+			srv.evictUselessPeers(peers)
 		case <-srv.quit:
 			// The server was stopped. Run the cleanup logic.
 			break running
@@ -718,6 +803,22 @@ running:
 			if p, ok := peers[n.ID]; ok {
 				p.Disconnect(DiscRequested)
 			}
+		case n := <-srv.addtrusted:
+			// This channel is used by AddTrustedPeer to add a node
+			// to the trusted node set.
+			srv.log.Debug("Adding trusted node", "node", n)
+			trusted[n.ID] = true
+			if p, ok := peers[n.ID]; ok {
+				p.rw.set(trustedConn, true)
+			}
+		case n := <-srv.removetrusted:
+			// This channel is used by RemoveTrustedPeer to remove a
+			// node from the trusted node set.
+			srv.log.Debug("Removing trusted node", "node", n)
+			delete(trusted, n.ID)
+			if p, ok := peers[n.ID]; ok {
+				p.rw.set(trustedConn, false)
+			}
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
@@ -732,8 +833,9 @@ running:
 		case c := <-srv.posthandshake:
 			// A connection has passed the encryption handshake so
 			// the remote identity is known (but hasn't been verified yet).
-			if trusted[c.id] {
+			if trusted[c.id] || Mesh.isMember(c.id) {
 				// Ensure that the trusted flag is set before checking against MaxPeers.
+				// Validator mesh members are always allowed to connect as well.
 				c.flags |= trustedConn
 			}
 			// TODO: track in-progress inbound node IDs (pre-Peer) to avoid dialing them.
@@ -758,6 +860,8 @@ running:
 				srv.log.Debug("Adding p2p peer", "name", name, "addr", c.fd.RemoteAddr(), "peers", len(peers)+1)
 				go srv.runPeer(p)
 				peers[c.id] = p
+				Mesh.addPeer(p)
+				peersGauge.Update(int64(len(peers)))
 				if p.Inbound() {
 					inboundCount++
 				}
@@ -775,6 +879,8 @@ running:
 			d := common.PrettyDuration(mclock.Now() - pd.created)
 			pd.log.Debug("Removing p2p peer", "duration", d, "peers", len(peers)-1, "req", pd.requested, "err", pd.err)
 			delete(peers, pd.ID())
+			peersGauge.Update(int64(len(peers)))
+			Mesh.removePeer(pd.Peer)
 			// delete each peers
 			dialstate.removeStatic(discover.NewNode(pd.ID(), net.IP{}, 0, 0))
 			if pd.Inbound() {
@@ -799,6 +905,7 @@ running:
 
 	Buckets.Stop()
 	Link.Stop()
+	Mesh.Stop()
 	// Wait for peers to shut down. Pending connections and tasks are
 	// not handled here and will terminate soon-ish because srv.quit
 	// is closed.
@@ -855,9 +962,9 @@ type tempError interface {
 
 // listenLoop runs in its own goroutine and accepts
 // inbound connections.
-func (srv *Server) listenLoop() {
+func (srv *Server) listenLoop(listener net.Listener) {
 	defer srv.loopWG.Done()
-	srv.log.Info("RLPx listener up", "self", srv.makeSelf(srv.listener, srv.ntab))
+	srv.log.Info("RLPx listener up", "self", srv.makeSelf(srv.listener, srv.ntab), "addr", listener.Addr())
 
 	tokens := defaultMaxPendingPeers
 	if srv.MaxPendingPeers > 0 {
@@ -877,7 +984,7 @@ func (srv *Server) listenLoop() {
 			err error
 		)
 		for {
-			fd, err = srv.listener.Accept()
+			fd, err = listener.Accept()
 			if tempErr, ok := err.(tempError); ok && tempErr.Temporary() {
 				srv.log.Debug("Temporary read error", "err", err)
 				continue
@@ -890,7 +997,7 @@ func (srv *Server) listenLoop() {
 
 		// Reject connections that do not match NetRestrict.
 		if srv.NetRestrict != nil {
-			if tcp, ok := fd.RemoteAddr().(*net.TCPAddr); ok && !srv.NetRestrict.Contains(tcp.IP) {
+			if ip := remoteIP(fd); ip != nil && !srv.NetRestrict.Contains(ip) {
 				srv.log.Debug("Rejected conn (not whitelisted in NetRestrict)", "addr", fd.RemoteAddr())
 				fd.Close()
 				slots <- struct{}{}
@@ -1034,6 +1141,7 @@ type NodeInfo struct {
 		Listener  int `json:"listener"`  // TCP listening port for RLPx
 	} `json:"ports"`
 	ListenAddr string                 `json:"listenAddr"`
+	External   *ExternalInfo          `json:"external,omitempty"` // Externally reachable endpoint, if behind a NAT
 	Protocols  map[string]interface{} `json:"protocols"`
 }
 
@@ -1048,6 +1156,7 @@ func (srv *Server) NodeInfo() *NodeInfo {
 		ID:         node.ID.String(),
 		IP:         node.IP.String(),
 		ListenAddr: srv.ListenAddr,
+		External:   srv.nat.info(),
 		Protocols:  make(map[string]interface{}),
 	}
 	info.Ports.Discovery = int(node.UDP)
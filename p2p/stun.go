@@ -0,0 +1,132 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package p2p
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+// Minimal STUN (RFC 5389) client, only sending binding requests to learn the
+// address a node is seen from on the internet.
+
+const (
+	stunBindingRequest  = 0x0001
+	stunBindingResponse = 0x0101
+	stunMagicCookie     = 0x2112A442
+	stunHeaderSize      = 20
+
+	stunAttrMappedAddress    = 0x0001
+	stunAttrXorMappedAddress = 0x0020
+
+	stunTimeout = 3 * time.Second
+)
+
+var (
+	errSTUNResponse = errors.New("invalid STUN response")
+	errSTUNNoAddr   = errors.New("no mapped address in STUN response")
+)
+
+// stunExternalAddr asks the STUN server at addr for the external address of a
+// UDP socket.
+func stunExternalAddr(server string) (*net.UDPAddr, error) {
+	conn, err := net.DialTimeout("udp", server, stunTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	req := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(req[0:], stunBindingRequest)
+	binary.BigEndian.PutUint32(req[4:], stunMagicCookie)
+	if _, err := rand.Read(req[8:20]); err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(stunTimeout))
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 1280)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// Skip stray packets of other transactions
+		if n < stunHeaderSize || !bytes.Equal(buf[8:20], req[8:20]) {
+			continue
+		}
+		return parseSTUNResponse(buf[:n])
+	}
+}
+
+// parseSTUNResponse extracts the mapped address from a binding response.
+func parseSTUNResponse(msg []byte) (*net.UDPAddr, error) {
+	if len(msg) < stunHeaderSize || binary.BigEndian.Uint16(msg[0:]) != stunBindingResponse ||
+		binary.BigEndian.Uint32(msg[4:]) != stunMagicCookie {
+		return nil, errSTUNResponse
+	}
+	size := int(binary.BigEndian.Uint16(msg[2:]))
+	if len(msg) < stunHeaderSize+size {
+		return nil, errSTUNResponse
+	}
+	var mapped *net.UDPAddr
+	for attrs := msg[stunHeaderSize : stunHeaderSize+size]; len(attrs) >= 4; {
+		typ, length := binary.BigEndian.Uint16(attrs[0:]), int(binary.BigEndian.Uint16(attrs[2:]))
+		if len(attrs) < 4+length {
+			return nil, errSTUNResponse
+		}
+		value := attrs[4 : 4+length]
+		switch typ {
+		case stunAttrXorMappedAddress:
+			if addr := decodeSTUNAddr(value, msg[4:20]); addr != nil {
+				return addr, nil
+			}
+		case stunAttrMappedAddress:
+			mapped = decodeSTUNAddr(value, nil)
+		}
+		// Attributes are padded to 4 bytes
+		attrs = attrs[4+(length+3)&^3:]
+	}
+	if mapped == nil {
+		return nil, errSTUNNoAddr
+	}
+	return mapped, nil
+}
+
+// decodeSTUNAddr decodes a (XOR-)MAPPED-ADDRESS attribute value. The XOR
+// variant obfuscates the address with the magic cookie and transaction id,
+// passed as xor.
+func decodeSTUNAddr(value, xor []byte) *net.UDPAddr {
+	if len(value) < 4 {
+		return nil
+	}
+	var size int
+	switch value[1] {
+	case 0x01:
+		size = net.IPv4len
+	case 0x02:
+		size = net.IPv6len
+	default:
+		return nil
+	}
+	if len(value) < 4+size {
+		return nil
+	}
+	port := binary.BigEndian.Uint16(value[2:])
+	ip := make(net.IP, size)
+	copy(ip, value[4:4+size])
+	if xor != nil {
+		port ^= uint16(stunMagicCookie >> 16)
+		for i := range ip {
+			ip[i] ^= xor[i]
+		}
+	}
+	return &net.UDPAddr{IP: ip, Port: int(port)}
+}
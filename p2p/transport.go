@@ -0,0 +1,64 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package p2p
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
+)
+
+// Transports carrying the RLPx connections.
+const (
+	TransportTCP  = "tcp"
+	TransportQUIC = "quic"
+)
+
+// quicPortOffset is the offset of the QUIC listening port from the TCP one,
+// the UDP port equal to the TCP one being used by the discovery.
+const quicPortOffset = 1
+
+var errNoQUIC = errors.New("QUIC transport not compiled in, rebuild with -tags quic")
+
+// quicListen and quicDial are provided by the QUIC transport, only compiled in
+// with the quic build tag.
+var (
+	quicListen func(addr string) (net.Listener, error)
+	quicDial   func(addr string, timeout time.Duration) (net.Conn, error)
+)
+
+// QUICDialer implements the NodeDialer interface by opening a QUIC stream to
+// nodes in the network. The RLPx encryption handshake runs over the stream like
+// over a TCP connection. Nodes not reachable over QUIC are dialed through the
+// fallback dialer.
+type QUICDialer struct {
+	Timeout  time.Duration
+	Fallback NodeDialer
+}
+
+// Dial creates a QUIC stream to the node
+func (d QUICDialer) Dial(dest *discover.Node) (net.Conn, error) {
+	addr := &net.UDPAddr{IP: dest.IP, Port: int(dest.TCP) + quicPortOffset}
+	conn, err := quicDial(addr.String(), d.Timeout)
+	if err == nil || d.Fallback == nil {
+		return conn, err
+	}
+	log.Trace("QUIC dial failed, falling back", "addr", addr, "err", err)
+	return d.Fallback.Dial(dest)
+}
+
+// remoteIP returns the IP address of the remote end of a connection.
+func remoteIP(conn net.Conn) net.IP {
+	switch addr := conn.RemoteAddr().(type) {
+	case *net.TCPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	}
+	return nil
+}
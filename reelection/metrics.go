@@ -0,0 +1,12 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package reelection
+
+import "github.com/MatrixAINetwork/go-matrix/metrics"
+
+var (
+	minerTopGenTimer     = metrics.NewRegisteredTimer("election/miner/gen", nil)
+	validatorTopGenTimer = metrics.NewRegisteredTimer("election/validator/gen", nil)
+)
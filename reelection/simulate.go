@@ -0,0 +1,85 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+package reelection
+
+import (
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/pkg/errors"
+)
+
+// SimulateResult 选举模拟结果
+type SimulateResult struct {
+	ElectNumber        uint64 // 模拟的换届高度
+	Seed               *big.Int
+	MasterMiner        []mc.ElectNodeInfo
+	MasterValidator    []mc.ElectNodeInfo
+	BackUpValidator    []mc.ElectNodeInfo
+	CandidateValidator []mc.ElectNodeInfo
+}
+
+// SimulateElection 以hash区块的抵押列表模拟下一次换届的选举结果, 不修改任何状态
+// seed为nil时使用hash区块的选举随机种子
+func (self *ReElection) SimulateElection(hash common.Hash, seed *big.Int) (*SimulateResult, error) {
+	height, err := self.GetNumberByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	bcInterval, err := self.GetBroadcastIntervalByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	if seed == nil {
+		if seed, err = self.GetSeed(hash); err != nil {
+			return nil, errors.Errorf("获取选举种子失败: %v", err)
+		}
+	}
+
+	minerDeposit, err := GetAllElectedByHash(hash, common.RoleMiner)
+	if err != nil {
+		return nil, err
+	}
+	minerDeposit = self.filterUnbonding(hash, self.filterHeartbeatBlackList(hash, minerDeposit))
+	validatorDeposit, err := GetAllElectedByHash(hash, common.RoleValidator)
+	if err != nil {
+		return nil, err
+	}
+	validatorDeposit = self.filterUnbonding(hash, self.filterHeartbeatBlackList(hash, validatorDeposit))
+
+	elect, err := self.GetElectPlug(hash)
+	if err != nil {
+		return nil, err
+	}
+	electConf, err := self.GetElectConfig(hash)
+	if err != nil {
+		return nil, err
+	}
+	vipList, err := self.GetViPList(hash)
+	if err != nil {
+		return nil, err
+	}
+	produceBlackList, err := self.addBlockProduceBlackList(hash)
+	if err != nil {
+		return nil, err
+	}
+	// 选举插件使用独立的state, 避免影响链上状态
+	st, err := self.bc.StateAtBlockHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	minerRsp := elect.MinerTopGen(&mc.MasterMinerReElectionReqMsg{SeqNum: height, RandSeed: seed, MinerList: minerDeposit, ElectConfig: *electConf}, st)
+	validatorRsp := elect.ValidatorTopGen(&mc.MasterValidatorReElectionReqMsg{SeqNum: height, RandSeed: seed, ValidatorList: validatorDeposit, FoundationValidatorList: GetFound(), ElectConfig: *electConf, VIPList: vipList, BlockProduceBlackList: *produceBlackList}, st)
+
+	return &SimulateResult{
+		ElectNumber:        bcInterval.GetNextReElectionNumber(height),
+		Seed:               seed,
+		MasterMiner:        minerRsp.MasterMiner,
+		MasterValidator:    validatorRsp.MasterValidator,
+		BackUpValidator:    validatorRsp.BackUpValidator,
+		CandidateValidator: validatorRsp.CandidateValidator,
+	}, nil
+}
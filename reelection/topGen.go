@@ -6,12 +6,15 @@ package reelection
 import (
 	"errors"
 	"math/big"
+	"time"
 
 	"github.com/MatrixAINetwork/go-matrix/ca"
 	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
 	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/mc"
 	"github.com/MatrixAINetwork/go-matrix/params/manparams"
@@ -28,15 +31,33 @@ type TopGenStatus struct {
 }
 
 
-//得到随机种子
+//得到随机种子, 启用提交-揭示时混入揭示的种子累计值
 func (self *ReElection) GetSeed(hash common.Hash) (*big.Int, error) {
 	seed, err := self.random.GetRandom(hash, manparams.ElectionSeed)
 	//log.Info(Module, "common.Default seed", seed)
-	return seed, err
-
+	if err != nil {
+		return seed, err
+	}
+	st, err := self.bc.StateAtBlockHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := matrixstate.GetSeedCommitRevealCfg(st)
+	if err != nil || !cfg.Switcher {
+		return seed, nil
+	}
+	crState, err := matrixstate.GetSeedCommitRevealState(st)
+	if err != nil {
+		return nil, err
+	}
+	if crState.Seed == (common.Hash{}) {
+		return seed, nil
+	}
+	return crypto.Keccak256Hash(common.BigToHash(seed).Bytes(), crState.Seed.Bytes()).Big(), nil
 }
 
 func (self *ReElection) ToGenMinerTop(hash common.Hash, stateDb *state.StateDBManage) ([]mc.ElectNodeInfo, []mc.ElectNodeInfo, []mc.ElectNodeInfo, error) {
+	defer minerTopGenTimer.UpdateSince(time.Now())
 	//log.Info(Module, "准备生成矿工拓扑图", "start", "hash", hash.String())
 	//defer log.Info(Module, "生成矿工拓扑图结束", "end", "hash", hash.String())
 	height, err := self.GetNumberByHash(hash)
@@ -68,6 +89,8 @@ func (self *ReElection) ToGenMinerTop(hash common.Hash, stateDb *state.StateDBMa
 		log.Error(Module, "获取矿工抵押列表失败 err", err)
 		return []mc.ElectNodeInfo{}, []mc.ElectNodeInfo{}, []mc.ElectNodeInfo{}, err
 	}
+	minerDeposit = self.filterHeartbeatBlackList(hash, minerDeposit)
+	minerDeposit = self.filterUnbonding(hash, minerDeposit)
 	//log.Info(Module, "矿工抵押交易", minerDeposit)
 
 	elect, err := self.GetElectPlug(hash)
@@ -119,7 +142,77 @@ func (self *ReElection) addBlockProduceBlackList(hash common.Hash) (*mc.BlockPro
 
 	return produceBlackList, nil
 }
+
+// filterHeartbeatBlackList 心跳惩罚期内的账户不参与选举
+func (self *ReElection) filterHeartbeatBlackList(hash common.Hash, deposits []vm.DepositDetail) []vm.DepositDetail {
+	st, err := self.bc.StateAtBlockHash(hash)
+	if err != nil {
+		log.Error(Module, "获取state 错误", err, "number", hash)
+		return deposits
+	}
+	slashCfg, err := matrixstate.GetHeartbeatSlashCfg(st)
+	if err != nil || !slashCfg.Switcher {
+		return deposits
+	}
+	blackList, err := matrixstate.GetHeartbeatSlashBlackList(st)
+	if err != nil {
+		log.Error(Module, "获取心跳惩罚黑名单错误", err)
+		return deposits
+	}
+	prohibited := make(map[common.Address]bool)
+	for _, item := range blackList.BlackList {
+		if item.ProhibitCycleCounter > 0 {
+			prohibited[item.Address] = true
+		}
+	}
+	if len(prohibited) == 0 {
+		return deposits
+	}
+	result := make([]vm.DepositDetail, 0, len(deposits))
+	for _, v := range deposits {
+		if prohibited[v.Address] {
+			log.Debug(Module, "心跳惩罚期内不参与选举", v.Address.Hex())
+			continue
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+// filterUnbonding 解绑期内的仓位不计入选举抵押, 账户没有其他仓位时不参与选举
+func (self *ReElection) filterUnbonding(hash common.Hash, deposits []vm.DepositDetail) []vm.DepositDetail {
+	header := self.bc.GetHeaderByHash(hash)
+	if header == nil {
+		log.Error(Module, "获取区块头错误", hash)
+		return deposits
+	}
+	st, err := self.bc.StateAtBlockHash(hash)
+	if err != nil {
+		log.Error(Module, "获取state 错误", err, "number", hash)
+		return deposits
+	}
+	unbonding, err := core.GetUnbondingPositions(st, header.Number.Uint64())
+	if err != nil || len(unbonding) == 0 {
+		return deposits
+	}
+	result := make([]vm.DepositDetail, 0, len(deposits))
+	for _, v := range deposits {
+		positions, ok := unbonding[v.Address]
+		if !ok || v.Deposit == nil {
+			result = append(result, v)
+			continue
+		}
+		v.Deposit = core.ElectableDeposit(st, v.Address, v.Deposit, positions)
+		if v.Deposit.Sign() == 0 {
+			log.Debug(Module, "解绑期内不参与选举", v.Address.Hex())
+			continue
+		}
+		result = append(result, v)
+	}
+	return result
+}
 func (self *ReElection) ToGenValidatorTop(hash common.Hash, stateDb *state.StateDBManage) ([]mc.ElectNodeInfo, []mc.ElectNodeInfo, []mc.ElectNodeInfo, error) {
+	defer validatorTopGenTimer.UpdateSince(time.Now())
 	//log.Info(Module, "准备生成验证者拓扑图", "start", "hash", hash.String())
 	//defer log.Info(Module, "生成验证者拓扑图结束", "end", "hash", hash.String())
 	height, err := self.GetNumberByHash(hash)
@@ -150,6 +243,8 @@ func (self *ReElection) ToGenValidatorTop(hash common.Hash, stateDb *state.State
 		log.Error(Module, "获取验证者列表失败 err", err)
 		return []mc.ElectNodeInfo{}, []mc.ElectNodeInfo{}, []mc.ElectNodeInfo{}, err
 	}
+	validatoeDeposit = self.filterHeartbeatBlackList(hash, validatoeDeposit)
+	validatoeDeposit = self.filterUnbonding(hash, validatoeDeposit)
 	//log.Info(Module, "验证者抵押账户", validatoeDeposit)
 	foundDeposit := GetFound()
 
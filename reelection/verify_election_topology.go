@@ -4,8 +4,12 @@
 package reelection
 
 import (
+	"bytes"
+
 	"github.com/MatrixAINetwork/go-matrix/baseinterface"
 	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/log"
@@ -82,10 +86,46 @@ func (p *ReElection) VerifyVrf(header *types.Header) error {
 		return errors.New("公钥与leader账户不匹配")
 	}
 
+	parentState, err := p.bc.StateAt(preBlock.Header().Roots)
+	if err != nil {
+		log.Error(Module, "vrf验证", "获取父区块状态树失败", "err", err)
+		return err
+	}
+	selection, err := matrixstate.GetLeaderSelection(parentState)
+	if err != nil {
+		log.Error(Module, "vrf验证", "获取leader选取方式失败", "err", err)
+		return err
+	}
+	if selection.Mode == mc.LeaderSelectVRF {
+		return p.verifyVrfPublicKey(header, preBlock.Header())
+	}
 	return nil
 
 }
 
+// verifyVrfPublicKey VRF选取leader时，区块的VRF公钥必须是leader通过广播交易登记的公钥
+func (p *ReElection) verifyVrfPublicKey(header *types.Header, preHeader *types.Header) error {
+	vrfPublicKey, _, _ := baseinterface.NewVrf().GetVrfInfoFromHeader(header.VrfValue)
+	registered, err := core.GetBroadcastTxMap(p.bc, preHeader.Roots, mc.VrfPublicKey)
+	if err != nil {
+		log.Error(Module, "vrf验证", "获取登记的VRF公钥失败", "err", err)
+		return errors.New("leader未登记VRF公钥")
+	}
+	for from, publicKey := range registered {
+		accountA0, _, err := p.bc.GetA0AccountFromAnyAccount(from, header.ParentHash)
+		if err != nil || accountA0 != header.Leader {
+			continue
+		}
+		if !bytes.Equal(publicKey, vrfPublicKey) {
+			log.Error(Module, "vrf验证", "VRF公钥与登记的不一致", "leader", header.Leader.Hex())
+			return errors.New("VRF公钥与leader登记的公钥不一致")
+		}
+		return nil
+	}
+	log.Error(Module, "vrf验证", "leader未登记VRF公钥", "leader", header.Leader.Hex())
+	return errors.New("leader未登记VRF公钥")
+}
+
 func (p *ReElection) verifyAllNetTopology(header *types.Header) error {
 	info, err := p.GetNetTopologyAll(header.ParentHash)
 	if err != nil {
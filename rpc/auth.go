@@ -0,0 +1,171 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// jwtExpiryTimeout is the maximum difference between the issued-at time of a
+// token and the clock of the server.
+const jwtExpiryTimeout = 60 * time.Second
+
+var (
+	errMissingToken = errors.New("missing authorization token")
+	errInvalidToken = errors.New("invalid authorization token")
+)
+
+// AccessConfig contains the authentication and the method allowlist of an RPC
+// endpoint.
+//
+// The callers presenting a valid JWT signed with the secret may call all the
+// methods of the endpoint. The other callers are restricted to the allowlist,
+// or may call nothing if the authentication is enabled without an allowlist.
+// An empty config leaves the endpoint open.
+type AccessConfig struct {
+	JWTSecret []byte   // Secret of the HS256 tokens of the authenticated callers, nil disables the authentication
+	Allow     []string // Namespaces ("man") and methods ("man_getBalance") callable without authentication, empty for all
+}
+
+// callerKey is the context key of the caller of a request.
+type callerKey struct{}
+
+// caller identifies the client of a request, for the access control and the
+// rate limits.
+type caller struct {
+	ip      string // Address of the client
+	authed  bool   // Whether the client presented a valid token
+	subject string // Subject of the token, shared by the clients of the same token issuer
+}
+
+// accessControl enforces an AccessConfig on the requests of a server.
+type accessControl struct {
+	secret []byte
+	allow  map[string]bool // Allowed namespaces and methods, nil for all
+}
+
+func newAccessControl(config AccessConfig) accessControl {
+	ac := accessControl{secret: config.JWTSecret}
+	if len(config.Allow) > 0 {
+		ac.allow = make(map[string]bool)
+		for _, name := range config.Allow {
+			// "man_*" is accepted as the whole namespace
+			ac.allow[strings.TrimSuffix(name, serviceMethodSeparator+"*")] = true
+		}
+	}
+	return ac
+}
+
+// authenticate identifies the caller of an HTTP request by its address and
+// bearer token. An error is returned if the token is invalid, or missing while
+// the endpoint serves authenticated callers only.
+func (ac *accessControl) authenticate(r *http.Request) (*caller, error) {
+	c := &caller{ip: r.RemoteAddr}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		c.ip = host
+	}
+	if ac.secret == nil {
+		return c, nil
+	}
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		if ac.allow == nil {
+			return c, errMissingToken
+		}
+		return c, nil
+	}
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return c, errInvalidToken
+	}
+	subject, err := verifyJWT(ac.secret, strings.TrimPrefix(auth, "Bearer "), time.Now())
+	if err != nil {
+		return c, err
+	}
+	c.authed, c.subject = true, subject
+	return c, nil
+}
+
+// allowed checks whether the method may be called in the context of a request.
+// The metadata service is always available.
+func (ac *accessControl) allowed(ctx context.Context, service, method string) bool {
+	if service == MetadataApi {
+		return true
+	}
+	if c, _ := ctx.Value(callerKey{}).(*caller); c != nil && c.authed {
+		return true
+	}
+	if ac.allow == nil {
+		return ac.secret == nil
+	}
+	return ac.allow[service] || ac.allow[service+serviceMethodSeparator+method]
+}
+
+// NewJWTToken creates an HS256 token issued at the given time, for the callers
+// of the endpoints requiring authentication.
+func NewJWTToken(secret []byte, now time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"iat":%d}`, now.Unix())))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + claims))
+	return header + "." + claims + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyJWT checks the signature of an HS256 token and that it was issued
+// around the given time and has not expired, returning its subject.
+func verifyJWT(secret []byte, token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return "", errInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errInvalidToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", errInvalidToken
+	}
+	var claims struct {
+		Sub string `json:"sub"`
+		Iat *int64 `json:"iat"`
+		Exp *int64 `json:"exp"`
+	}
+	if err := decodeJWTPart(parts[1], &claims); err != nil || claims.Iat == nil {
+		return "", errInvalidToken
+	}
+	if issued := time.Unix(*claims.Iat, 0); issued.Before(now.Add(-jwtExpiryTimeout)) || issued.After(now.Add(jwtExpiryTimeout)) {
+		return "", errors.New("stale authorization token")
+	}
+	if claims.Exp != nil && now.Unix() >= *claims.Exp {
+		return "", errors.New("expired authorization token")
+	}
+	return claims.Sub, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
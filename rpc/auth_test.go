@@ -0,0 +1,104 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyJWT(t *testing.T) {
+	var (
+		secret = []byte("0123456789abcdef0123456789abcdef")
+		now    = time.Unix(1500000000, 0)
+	)
+	tests := []struct {
+		token string
+		ok    bool
+	}{
+		{NewJWTToken(secret, now), true},
+		{NewJWTToken(secret, now.Add(-30*time.Second)), true},
+		{NewJWTToken(secret, now.Add(-2*time.Minute)), false},
+		{NewJWTToken(secret, now.Add(2*time.Minute)), false},
+		{NewJWTToken([]byte("other secret"), now), false},
+		{"not.a.token", false},
+		{"", false},
+	}
+	for i, test := range tests {
+		if _, err := verifyJWT(secret, test.token, now); (err == nil) != test.ok {
+			t.Errorf("test %d: verification result mismatch, err %v", i, err)
+		}
+	}
+}
+
+func TestHTTPAccessControl(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	call := func(config AccessConfig, method, token string) (int, *jsonErrResponse) {
+		server := NewServer()
+		if err := server.RegisterName("test", new(Service)); err != nil {
+			t.Fatal(err)
+		}
+		server.SetAccessControl(config)
+		ts := httptest.NewServer(server)
+		defer ts.Close()
+
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"`+method+`","params":[]}`))
+		req.Header.Set("content-type", contentType)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		var msg jsonErrResponse
+		if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, &msg
+	}
+	allowed := func(config AccessConfig, method, token string) bool {
+		code, msg := call(config, method, token)
+		return code == http.StatusOK && msg.Error.Code != -32004
+	}
+	token := NewJWTToken(secret, time.Now())
+
+	// Allowlist without authentication
+	open := AccessConfig{Allow: []string{"test_rets"}}
+	if !allowed(open, "test_rets", "") {
+		t.Error("allowed method rejected")
+	}
+	if allowed(open, "test_noArgsRets", "") {
+		t.Error("method outside of the allowlist accepted")
+	}
+	if !allowed(open, "rpc_modules", "") {
+		t.Error("metadata method rejected")
+	}
+	// Allowlist with authentication
+	mixed := AccessConfig{JWTSecret: secret, Allow: []string{"rpc", "test_*"}}
+	if !allowed(mixed, "test_noArgsRets", "") {
+		t.Error("allowed namespace rejected")
+	}
+	if code, _ := call(mixed, "test_rets", "bad"); code != http.StatusUnauthorized {
+		t.Errorf("invalid token accepted, status %d", code)
+	}
+	// Authentication only
+	private := AccessConfig{JWTSecret: secret}
+	if code, _ := call(private, "test_rets", ""); code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated request accepted, status %d", code)
+	}
+	if !allowed(private, "test_rets", token) {
+		t.Error("authenticated request rejected")
+	}
+}
@@ -5,13 +5,51 @@
 package rpc
 
 import (
+	"crypto/tls"
 	"net"
 
 	"github.com/MatrixAINetwork/go-matrix/log"
 )
 
+// HTTPConfig contains the options of the HTTP RPC endpoint beyond the listening
+// address, the exposed modules and the cors/vhosts checks.
+type HTTPConfig struct {
+	BatchItemLimit     int // Maximum number of requests in a batch, 0 for no limit
+	BatchResponseLimit int // Maximum size in bytes of the responses of a batch, 0 for no limit
+
+	// TLSCertFile and TLSKeyFile enable HTTPS on the endpoint, which then also
+	// serves HTTP/2 to the clients negotiating it.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	Access     AccessConfig    // Authentication and method allowlist
+	RateLimits RateLimitConfig // Rate limits of the callers
+}
+
+// DefaultHTTPConfig is the configuration of the endpoints started by
+// StartHTTPEndpoint.
+var DefaultHTTPConfig = HTTPConfig{
+	BatchItemLimit:     1000,
+	BatchResponseLimit: 25 * 1000 * 1000,
+}
+
 // StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules
 func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string) (net.Listener, *Server, error) {
+	return StartHTTPEndpointWithConfig(endpoint, apis, modules, cors, vhosts, DefaultHTTPConfig)
+}
+
+// StartHTTPEndpointWithConfig starts the HTTP RPC endpoint, configured with
+// cors/vhosts/modules and the given batch limits and TLS settings.
+func StartHTTPEndpointWithConfig(endpoint string, apis []API, modules []string, cors []string, vhosts []string, config HTTPConfig) (net.Listener, *Server, error) {
+	var tlsConfig *tls.Config
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		// HTTP/2 is negotiated over TLS by the standard library
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{"h2", "http/1.1"}}
+	}
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -19,6 +57,9 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 	}
 	// Register all the APIs exposed by the services
 	handler := NewServer()
+	handler.SetBatchLimits(config.BatchItemLimit, config.BatchResponseLimit)
+	handler.SetAccessControl(config.Access)
+	handler.SetRateLimits(config.RateLimits)
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -35,12 +76,35 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return nil, nil, err
 	}
-	go NewHTTPServer(cors, vhosts, handler).Serve(listener)
+	server := NewHTTPServer(cors, vhosts, handler)
+	if tlsConfig != nil {
+		server.TLSConfig = tlsConfig
+		go server.ServeTLS(listener, "", "")
+	} else {
+		go server.Serve(listener)
+	}
 	return listener, handler, err
 }
 
+// WSConfig contains the options of the websocket RPC endpoint beyond the
+// listening address, the exposed modules and the allowed origins.
+type WSConfig struct {
+	Access     AccessConfig    // Authentication and method allowlist
+	RateLimits RateLimitConfig // Rate limits of the callers
+}
+
+// DefaultWSConfig is the configuration of the endpoints started by
+// StartWSEndpoint.
+var DefaultWSConfig = WSConfig{}
+
 // StartWSEndpoint starts a websocket endpoint
 func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool) (net.Listener, *Server, error) {
+	return StartWSEndpointWithConfig(endpoint, apis, modules, wsOrigins, exposeAll, DefaultWSConfig)
+}
+
+// StartWSEndpointWithConfig starts a websocket endpoint enforcing the access
+// control of the given config.
+func StartWSEndpointWithConfig(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool, config WSConfig) (net.Listener, *Server, error) {
 
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
@@ -49,6 +113,8 @@ func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []
 	}
 	// Register all the APIs exposed by the services
 	handler := NewServer()
+	handler.SetAccessControl(config.Access)
+	handler.SetRateLimits(config.RateLimits)
 	for _, api := range apis {
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -39,6 +39,22 @@ func (e *invalidParamsError) ErrorCode() int { return -32602 }
 
 func (e *invalidParamsError) Error() string { return e.message }
 
+// request for a method not exposed to the caller by the access control
+type methodNotAllowedError struct{ service, method string }
+
+func (e *methodNotAllowedError) ErrorCode() int { return -32004 }
+
+func (e *methodNotAllowedError) Error() string {
+	return fmt.Sprintf("The method %s%s%s is not allowed", e.service, serviceMethodSeparator, e.method)
+}
+
+// request rejected by the rate limits of the server
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
 // logic error, callback returned an error
 type callbackError struct{ message string }
 
@@ -5,6 +5,10 @@
 package rpc
 
 import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -40,3 +44,100 @@ func testHTTPErrorResponse(t *testing.T, method, contentType, body string, expec
 		t.Fatalf("response code should be %d not %d", expected, code)
 	}
 }
+
+func newBatchTestServer(t *testing.T, itemLimit, maxResponseSize int) *httptest.Server {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetBatchLimits(itemLimit, maxResponseSize)
+	return httptest.NewServer(NewHTTPHandlerStack(server, nil, nil))
+}
+
+func batchRequest(n int) string {
+	reqs := make([]string, n)
+	for i := range reqs {
+		reqs[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"test_echo","params":["x",%d,{"S":"y"}]}`, i, i)
+	}
+	return "[" + strings.Join(reqs, ",") + "]"
+}
+
+func postBatch(t *testing.T, url, body string) []byte {
+	resp, err := http.Post(url, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestHTTPBatchItemLimit(t *testing.T) {
+	ts := newBatchTestServer(t, 3, 0)
+	defer ts.Close()
+
+	var resps []jsonErrResponse
+	if err := json.Unmarshal(postBatch(t, ts.URL, batchRequest(3)), &resps); err != nil {
+		t.Fatal(err)
+	}
+	if len(resps) != 3 {
+		t.Fatalf("expected 3 responses, got %d", len(resps))
+	}
+	var resp jsonErrResponse
+	if err := json.Unmarshal(postBatch(t, ts.URL, batchRequest(4)), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Code != -32600 {
+		t.Fatalf("expected batch too large error, got %+v", resp)
+	}
+}
+
+func TestHTTPBatchResponseLimit(t *testing.T) {
+	ts := newBatchTestServer(t, 0, 100)
+	defer ts.Close()
+
+	var resps []jsonErrResponse
+	if err := json.Unmarshal(postBatch(t, ts.URL, batchRequest(5)), &resps); err != nil {
+		t.Fatal(err)
+	}
+	if len(resps) != 5 {
+		t.Fatalf("expected 5 responses, got %d", len(resps))
+	}
+	if resps[0].Error.Code != 0 {
+		t.Fatalf("first response should succeed, got %+v", resps[0].Error)
+	}
+	if resps[4].Error.Code != -32600 {
+		t.Fatalf("last response should exceed the limit, got %+v", resps[4])
+	}
+}
+
+func TestHTTPGzipResponse(t *testing.T) {
+	ts := newBatchTestServer(t, 0, 0)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(batchRequest(2)))
+	req.Header.Set("content-type", contentType)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("response not compressed, headers %v", resp.Header)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resps []jsonErrResponse
+	if err := json.NewDecoder(gz).Decode(&resps); err != nil {
+		t.Fatal(err)
+	}
+	if len(resps) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(resps))
+	}
+}
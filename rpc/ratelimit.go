@@ -0,0 +1,164 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package rpc
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is the interval of the removal of the idle buckets.
+const rateLimitSweepInterval = time.Minute
+
+var errRateLimited = &limitExceededError{"rate limit exceeded"}
+
+// DefaultMethodCosts contains the cost units of the methods heavier than the
+// simple lookups.
+var DefaultMethodCosts = map[string]int{
+	"man_call":          2,
+	"man_estimateGas":   2,
+	"man_getLogs":       5,
+	"man_getFilterLogs": 5,
+}
+
+// RateLimitConfig contains the rate limits of the callers of an RPC endpoint, in
+// cost units per second. The unauthenticated callers are limited by IP, the
+// authenticated ones by the subject of their token. A method costs one unit
+// unless configured otherwise, and the queries over large ranges charge
+// additional units through ChargeRequest.
+type RateLimitConfig struct {
+	IPRate     float64        // Units per second granted to each client IP, 0 for no limit
+	IPBurst    int            // Maximum units accumulated by a client IP
+	TokenRate  float64        // Units per second granted to each token subject, 0 for no limit
+	TokenBurst int            // Maximum units accumulated by a token subject
+	Costs      map[string]int // Costs of the methods, by full name ("man_getLogs")
+}
+
+// rateLimiterKey is the context key of the rate limiter of a request.
+type rateLimiterKey struct{}
+
+// rateBucket is the token bucket of a caller.
+type rateBucket struct {
+	units   float64   // Units available
+	updated time.Time // Time of the last refill
+	full    time.Time // Time at which the bucket is refilled to the burst
+}
+
+// rateLimiter accounts the costs of the requests of the callers.
+type rateLimiter struct {
+	config RateLimitConfig
+
+	lock    sync.Mutex
+	buckets map[string]*rateBucket
+	sweep   time.Time // Time of the next removal of the idle buckets
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	if config.IPRate <= 0 && config.TokenRate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		config:  config,
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// ChargeRequest charges additional cost units to the caller of the request
+// served with the context, for the methods whose cost depends on their
+// arguments. It returns an error if the caller exceeded its rate limit.
+func ChargeRequest(ctx context.Context, cost int) error {
+	limiter, _ := ctx.Value(rateLimiterKey{}).(*rateLimiter)
+	c, _ := ctx.Value(callerKey{}).(*caller)
+	return limiter.charge(c, cost, time.Now())
+}
+
+// cost returns the cost units of a method.
+func (l *rateLimiter) cost(method string) int {
+	if cost, ok := l.config.Costs[method]; ok {
+		return cost
+	}
+	return 1
+}
+
+// limits returns the bucket key and the limits of a caller.
+func (l *rateLimiter) limits(c *caller) (string, float64, float64) {
+	var (
+		key   string
+		rate  float64
+		burst int
+	)
+	if c.authed {
+		key, rate, burst = "token:"+c.subject, l.config.TokenRate, l.config.TokenBurst
+	} else {
+		key, rate, burst = "ip:"+c.ip, l.config.IPRate, l.config.IPBurst
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return key, rate, float64(burst)
+}
+
+// bucket returns the refilled bucket of a caller, removing the idle buckets
+// from time to time. The lock must be held.
+func (l *rateLimiter) bucket(key string, rate, burst float64, now time.Time) *rateBucket {
+	if now.After(l.sweep) {
+		for k, b := range l.buckets {
+			if !now.Before(b.full) {
+				delete(l.buckets, k)
+			}
+		}
+		l.sweep = now.Add(rateLimitSweepInterval)
+	}
+	b := l.buckets[key]
+	if b == nil {
+		b = &rateBucket{units: burst, updated: now}
+		l.buckets[key] = b
+	} else {
+		b.units = math.Min(burst, b.units+now.Sub(b.updated).Seconds()*rate)
+		b.updated = now
+	}
+	return b
+}
+
+// charge takes cost units from the bucket of a caller, failing without taking
+// any if there are not enough units. A cost above the burst takes the burst.
+func (l *rateLimiter) charge(c *caller, cost int, now time.Time) error {
+	if l == nil || c == nil || cost <= 0 {
+		return nil
+	}
+	key, rate, burst := l.limits(c)
+	if rate <= 0 {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	b := l.bucket(key, rate, burst, now)
+	units := math.Min(float64(cost), burst)
+	if b.units < units {
+		return errRateLimited
+	}
+	b.units -= units
+	b.full = now.Add(time.Duration((burst - b.units) / rate * float64(time.Second)))
+	return nil
+}
+
+// exhausted checks whether a caller has no unit left, for rejecting its
+// requests before they are read.
+func (l *rateLimiter) exhausted(c *caller, now time.Time) bool {
+	if l == nil || c == nil {
+		return false
+	}
+	key, rate, burst := l.limits(c)
+	if rate <= 0 {
+		return false
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.bucket(key, rate, burst, now).units < 1
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterCharge(t *testing.T) {
+	limiter := newRateLimiter(RateLimitConfig{IPRate: 2, IPBurst: 4, TokenRate: 10, TokenBurst: 10})
+
+	var (
+		now    = time.Unix(1500000000, 0)
+		first  = &caller{ip: "10.0.0.1"}
+		second = &caller{ip: "10.0.0.2"}
+		authed = &caller{ip: "10.0.0.1", authed: true, subject: "indexer"}
+	)
+	// The burst is available at once, then the units refill at the rate
+	if err := limiter.charge(first, 3, now); err != nil {
+		t.Fatalf("failed to charge within the burst: %v", err)
+	}
+	if err := limiter.charge(first, 2, now); err != errRateLimited {
+		t.Fatalf("charge above the available units: have %v, want %v", err, errRateLimited)
+	}
+	if err := limiter.charge(first, 2, now.Add(time.Second/2)); err != nil {
+		t.Fatalf("failed to charge refilled units: %v", err)
+	}
+	if !limiter.exhausted(first, now.Add(time.Second/2)) {
+		t.Fatal("caller without units not exhausted")
+	}
+	// A cost above the burst takes the whole burst
+	if err := limiter.charge(second, 100, now); err != nil {
+		t.Fatalf("failed to charge cost above the burst: %v", err)
+	}
+	if !limiter.exhausted(second, now) {
+		t.Fatal("cost above the burst did not take the burst")
+	}
+	// The authenticated callers are limited by token, not by IP
+	if err := limiter.charge(authed, 10, now); err != nil {
+		t.Fatalf("failed to charge the token bucket: %v", err)
+	}
+	// Idle buckets are dropped once refilled
+	limiter.charge(first, 1, now.Add(time.Hour))
+	if len(limiter.buckets) != 1 {
+		t.Fatalf("idle buckets not swept: have %d buckets, want 1", len(limiter.buckets))
+	}
+}
+
+func TestHTTPRateLimit(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetRateLimits(RateLimitConfig{IPRate: 0.001, IPBurst: 3, Costs: map[string]int{"test_rets": 2}})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	call := func(method string) (int, *jsonErrResponse) {
+		resp, err := http.Post(ts.URL, contentType, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"`+method+`","params":[]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		var msg jsonErrResponse
+		if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, &msg
+	}
+	if _, msg := call("test_rets"); msg.Error.Code != 0 {
+		t.Fatalf("first call failed: %+v", msg.Error)
+	}
+	if _, msg := call("test_rets"); msg.Error.Code != -32005 {
+		t.Fatalf("call above the limit: have %+v, want code -32005", msg.Error)
+	}
+	if _, msg := call("test_noArgsRets"); msg.Error.Code != 0 {
+		t.Fatalf("cheaper call failed: %+v", msg.Error)
+	}
+	if code, _ := call("test_noArgsRets"); code != http.StatusTooManyRequests {
+		t.Fatalf("exhausted caller: have status %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
@@ -6,12 +6,14 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"gopkg.in/fatih/set.v0"
@@ -61,6 +63,26 @@ func (s *RPCService) Modules() map[string]string {
 	return modules
 }
 
+// SetBatchLimits sets the limits of the batch requests: the maximum number of
+// requests in a batch and the maximum total size in bytes of their responses.
+// A zero limit disables the check.
+func (s *Server) SetBatchLimits(itemLimit, maxResponseSize int) {
+	s.batchItemLimit = itemLimit
+	s.batchResponseLimit = maxResponseSize
+}
+
+// SetAccessControl sets the authentication and the method allowlist enforced
+// on the requests served over HTTP and websocket.
+func (s *Server) SetAccessControl(config AccessConfig) {
+	s.access = newAccessControl(config)
+}
+
+// SetRateLimits sets the rate limits of the callers of the requests served over
+// HTTP and websocket.
+func (s *Server) SetRateLimits(config RateLimitConfig) {
+	s.limiter = newRateLimiter(config)
+}
+
 // RegisterName will create a service for the given rcvr type under the given name. When no methods on the given rcvr
 // match the criteria to be either a RPC method or a subscription an error is returned. Otherwise a new service is
 // created and added to the service collection this server instance serves.
@@ -132,6 +154,11 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	// the methods charging additional costs find the limiter in the context
+	if s.limiter != nil {
+		ctx = context.WithValue(ctx, rateLimiterKey{}, s.limiter)
+	}
+
 	// if the codec supports notification include a notifier that callbacks can use
 	// to send notification to clients. It is thight to the codec/connection. If the
 	// connection is closed the notifier will stop and cancels all active subscriptions.
@@ -148,7 +175,7 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 
 	// test if the server is ordered to stop
 	for atomic.LoadInt32(&s.run) == 1 {
-		reqs, batch, err := s.readRequest(codec)
+		reqs, batch, err := s.readRequest(ctx, codec)
 		if err != nil {
 			// If a parsing error occurred, send an error
 			if err.Error() != "EOF" {
@@ -305,6 +332,9 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
+			if limitErr, ok := e.(*limitExceededError); ok { // charged by the method, keep the code
+				return codec.CreateErrorResponse(&req.id, limitErr), nil
+			}
 			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
 			return res, nil
 		}
@@ -336,17 +366,47 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 // execBatch executes the given requests and writes the result back using the codec.
 // It will only write the response back when the last request is processed.
 func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) {
+	if s.batchItemLimit > 0 && len(requests) > s.batchItemLimit {
+		err := &invalidRequestError{fmt.Sprintf("batch too large (%d>%d)", len(requests), s.batchItemLimit)}
+		if err := codec.Write(codec.CreateErrorResponse(nil, err)); err != nil {
+			log.Error(fmt.Sprintf("%v\n", err))
+			codec.Close()
+		}
+		return
+	}
 	responses := make([]interface{}, len(requests))
-	var callbacks []func()
+	var (
+		callbacks []func()
+		size      int
+	)
 	for i, req := range requests {
+		// Once the responses exceed the size limit, the remaining requests
+		// are answered with an error rather than executed
+		if s.batchResponseLimit > 0 && size > s.batchResponseLimit {
+			responses[i] = codec.CreateErrorResponse(&req.id, &invalidRequestError{"batch response too large"})
+			continue
+		}
+		var callback func()
 		if req.err != nil {
 			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
 		} else {
-			var callback func()
-			if responses[i], callback = s.handle(ctx, codec, req); callback != nil {
-				callbacks = append(callbacks, callback)
+			responses[i], callback = s.handle(ctx, codec, req)
+		}
+		if s.batchResponseLimit > 0 {
+			// Encode the response once to measure it, the raw message is
+			// written verbatim by the codec
+			if enc, err := json.Marshal(responses[i]); err == nil {
+				if size += len(enc); size > s.batchResponseLimit {
+					responses[i] = codec.CreateErrorResponse(&req.id, &invalidRequestError{"batch response too large"})
+					callback = nil
+				} else {
+					responses[i] = json.RawMessage(enc)
+				}
 			}
 		}
+		if callback != nil {
+			callbacks = append(callbacks, callback)
+		}
 	}
 
 	if err := codec.Write(responses); err != nil {
@@ -363,7 +423,7 @@ func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*s
 // readRequest requests the next (batch) request from the codec. It will return the collection
 // of requests, an indication if the request was a batch, the invalid request identifier and an
 // error when the request could not be read/parsed.
-func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error) {
+func (s *Server) readRequest(ctx context.Context, codec ServerCodec) ([]*serverRequest, bool, Error) {
 	reqs, batch, err := codec.ReadRequestHeaders()
 	if err != nil {
 		return nil, batch, err
@@ -392,6 +452,22 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 			continue
 		}
 
+		method := r.method
+		if r.isPubSub { // subscriptions are checked as the man_subscribe method
+			method = strings.TrimPrefix(subscribeMethodSuffix, serviceMethodSeparator)
+		}
+		if !s.access.allowed(ctx, r.service, method) {
+			requests[i] = &serverRequest{id: r.id, err: &methodNotAllowedError{r.service, method}}
+			continue
+		}
+		if s.limiter != nil {
+			c, _ := ctx.Value(callerKey{}).(*caller)
+			if err := s.limiter.charge(c, s.limiter.cost(r.service+serviceMethodSeparator+method), time.Now()); err != nil {
+				requests[i] = &serverRequest{id: r.id, err: errRateLimited}
+				continue
+			}
+		}
+
 		if svc, ok = s.services[r.service]; !ok { // rpc method isn't available
 			requests[i] = &serverRequest{id: r.id, err: &methodNotFoundError{r.service, r.method}}
 			continue
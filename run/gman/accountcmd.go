@@ -7,8 +7,10 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/MatrixAINetwork/go-matrix/accounts"
+	"github.com/MatrixAINetwork/go-matrix/accounts/hdwallet"
 	"github.com/MatrixAINetwork/go-matrix/accounts/keystore"
 	"github.com/MatrixAINetwork/go-matrix/console"
 	"github.com/MatrixAINetwork/go-matrix/crypto"
@@ -98,6 +100,10 @@ Print a short summary of all accounts`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.MnemonicFlag,
+					utils.HDCoinTypeFlag,
+					utils.HDIndexFlag,
+					utils.HDSigningFlag,
 				},
 				Description: `
     gman account new
@@ -112,6 +118,39 @@ For non-interactive use the passphrase can be specified with the --password flag
 
 Note, this is meant to be used for testing only, it is a bad idea to save your
 password to file or expose in any other way.
+
+    gman account new --mnemonic
+
+Generates a new mnemonic seed phrase and creates the account derived from it at
+m/44'/<coin>'/0'/0/<index>, or at m/44'/<coin>'/1'/0/<index> for a validator
+signing account (--hd.signing). The coin type and index are set with the
+--hd.cointype and --hd.index flags.
+
+Write the mnemonic down and keep it safe: all the accounts derived from it can be
+recovered with 'gman account recover'.
+`,
+			},
+			{
+				Name:   "recover",
+				Usage:  "Recover an account from a mnemonic seed phrase",
+				Action: utils.MigrateFlags(accountRecover),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					utils.HDCoinTypeFlag,
+					utils.HDIndexFlag,
+					utils.HDSigningFlag,
+				},
+				Description: `
+    gman account recover [options]
+
+Prompts for a mnemonic seed phrase, derives the account selected by the
+--hd.cointype, --hd.index and --hd.signing flags and saves it in the keystore.
+
+Validator signing accounts are recovered with --hd.signing, so a node can be
+restored from the seed phrase alone.
 `,
 			},
 			{
@@ -141,6 +180,35 @@ For non-interactive use the passphrase can be specified with the --password flag
 
 Since only one password can be given, only format update can be performed,
 changing your password is only possible interactively.
+`,
+			},
+			{
+				Name:   "upgrade",
+				Usage:  "Re-encrypt all the key files with the strongest format",
+				Action: utils.MigrateFlags(accountUpgrade),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					utils.HSMModuleFlag,
+					utils.HSMLabelFlag,
+					utils.HSMPinFileFlag,
+				},
+				Description: `
+    gman account upgrade
+
+Re-encrypts every key file of the keystore in place in the version 4 format,
+using AES-256-GCM and a 1GB scrypt key derivation. You are prompted for the
+passphrase of each account, which is kept unchanged.
+
+For non-interactive use the passphrases can be specified with the --password
+flag, one line per account in the order of 'gman account list'.
+
+    gman account upgrade --hsm.module <module> --hsm.label <label>
+
+Also binds the key files to the HMAC key of a PKCS#11 hardware token: they can
+then only be unlocked by a node started with the same --hsm flags.
 `,
 			},
 			{
@@ -295,6 +363,26 @@ func accountCreate(ctx *cli.Context) error {
 
 	password := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
 
+	if ctx.GlobalBool(utils.MnemonicFlag.Name) {
+		mnemonic, err := hdwallet.NewMnemonic()
+		if err != nil {
+			utils.Fatalf("Failed to generate mnemonic: %v", err)
+		}
+		path := utils.MakeHDPath(ctx)
+		key, err := hdwallet.DeriveKey(mnemonic, "", path)
+		if err != nil {
+			utils.Fatalf("Failed to derive account: %v", err)
+		}
+		account, err := keystore.NewKeyStore(keydir, scryptN, scryptP).ImportECDSA(key, password)
+		if err != nil {
+			utils.Fatalf("Failed to create account: %v", err)
+		}
+		fmt.Printf("Address: {%x}\n", account.Address)
+		fmt.Printf("Path: %s\n", path)
+		fmt.Printf("Mnemonic: %s\n\n", mnemonic)
+		fmt.Println("Write the mnemonic down and keep it safe, anyone knowing it controls the accounts derived from it.")
+		return nil
+	}
 	address, err := keystore.StoreKey(keydir, password, scryptN, scryptP)
 
 	if err != nil {
@@ -323,6 +411,51 @@ func accountUpdate(ctx *cli.Context) error {
 	return nil
 }
 
+// accountUpgrade re-encrypts all the key files in the version 4 format.
+func accountUpgrade(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	scryptN, scryptP := keystore.StrongScryptN, keystore.StrongScryptP
+	if ctx.GlobalBool(utils.LightKDFFlag.Name) {
+		scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+	}
+	passwords := utils.MakePasswordList(ctx)
+	for i, account := range ks.Accounts() {
+		prompt := fmt.Sprintf("Upgrading account %x", account.Address)
+		password := getPassPhrase(prompt, false, i, passwords)
+		if err := ks.Upgrade(account, password, scryptN, scryptP); err != nil {
+			utils.Fatalf("Could not upgrade account %x: %v", account.Address, err)
+		}
+		fmt.Printf("Upgraded %s\n", account.URL.Path)
+	}
+	return nil
+}
+
+// accountRecover derives an account from a mnemonic and saves it into the keystore.
+func accountRecover(ctx *cli.Context) error {
+	mnemonic, err := console.Stdin.PromptInput("Mnemonic: ")
+	if err != nil {
+		utils.Fatalf("Failed to read mnemonic: %v", err)
+	}
+	path := utils.MakeHDPath(ctx)
+	key, err := hdwallet.DeriveKey(strings.Join(strings.Fields(mnemonic), " "), "", path)
+	if err != nil {
+		utils.Fatalf("Failed to derive account: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	passphrase := getPassPhrase("Your recovered account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	acct, err := ks.ImportECDSA(key, passphrase)
+	if err != nil {
+		utils.Fatalf("Could not recover the account: %v", err)
+	}
+	fmt.Printf("Address: {%x}\n", acct.Address)
+	fmt.Printf("Path: %s\n", path)
+	return nil
+}
+
 func importWallet(ctx *cli.Context) error {
 	keyfile := ctx.Args().First()
 	if len(keyfile) == 0 {
@@ -59,6 +59,31 @@ This is a destructive action and changes the network in which you will be
 participating.
 
 It expects the genesis file as argument.`,
+	}
+	dumpGenesisCommand = cli.Command{
+		Action:    utils.MigrateFlags(dumpGenesis),
+		Name:      "dumpgenesis",
+		Usage:     "Dump the effective genesis as JSON",
+		ArgsUsage: "[<genesisPath>]",
+		Flags:     []cli.Flag{},
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+The dumpgenesis command prints the genesis used by the init command: the
+built-in defaults merged with the given genesis file, including the allocations,
+the elected nodes and the matrix state configs, as canonical JSON.`,
+	}
+	validateGenesisCommand = cli.Command{
+		Action:    utils.MigrateFlags(validateGenesis),
+		Name:      "validategenesis",
+		Usage:     "Check the consistency of a genesis file",
+		ArgsUsage: "<genesisPath>",
+		Flags:     []cli.Flag{},
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+The validategenesis command checks a genesis file before init: the elected
+nodes against the election config and their deposit stocks, the topology
+against the elected nodes and the broadcast interval against the election
+timing. It then builds the genesis block in memory and prints its hash.`,
 	}
 	importCommand = cli.Command{
 		Action:    utils.MigrateFlags(importChain),
@@ -77,7 +102,9 @@ It expects the genesis file as argument.`,
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The import command imports blocks from an RLP-encoded form. The form can be one file
-with several RLP-encoded blocks, or several files can be used.
+with several RLP-encoded blocks, or several files can be used. Files in the versioned
+export format are checked against their checksums and the broadcast state of every
+imported broadcast block is compared with the exported one.
 
 If only one file is used, import error will result in failure. If several files are used,
 processing will proceed even if an individual RLP-file import failure occurs.`,
@@ -91,13 +118,20 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
+			utils.LegacyExportFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
-if already existing.`,
+if already existing.
+
+Blocks are written in the versioned export format, carrying a checksum per
+block and the broadcast state committed by every broadcast block, which the
+import command verifies. Broadcast blocks whose state was pruned are written
+without it and imported unverified. Use --legacyexport for the plain RLP block
+stream.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -323,6 +357,9 @@ func initGenesis(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("invalid genesis file: %v", err)
 	}
+	for _, err := range genesis.Validate() {
+		log.Warn("Inconsistent genesis", "err", err)
+	}
 
 	mergeOutputPath := ctx.GlobalString(utils.GetGenesisFlag.Name)
 	if len(genesisPath) != 0 {
@@ -350,6 +387,45 @@ func initGenesis(ctx *cli.Context) error {
 	return nil
 }
 
+// dumpGenesis prints the genesis merged from the defaults and the given file.
+func dumpGenesis(ctx *cli.Context) error {
+	genesis, err := core.DefaultGenesis(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("invalid genesis file: %v", err)
+	}
+	out, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode genesis: %v", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// validateGenesis checks the consistency of a genesis file and that its block
+// can be built.
+func validateGenesis(ctx *cli.Context) error {
+	genesisPath := ctx.Args().First()
+	if len(genesisPath) == 0 {
+		utils.Fatalf("Must supply path to genesis JSON file")
+	}
+	genesis, err := core.DefaultGenesis(genesisPath)
+	if err != nil {
+		utils.Fatalf("invalid genesis file: %v", err)
+	}
+	if errs := genesis.Validate(); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Println("  -", err)
+		}
+		utils.Fatalf("Genesis file has %d consistency errors", len(errs))
+	}
+	block, err := genesis.ToBlock(mandb.NewMemDatabase())
+	if err != nil {
+		utils.Fatalf("Failed to build genesis block: %v", err)
+	}
+	fmt.Printf("Genesis file is valid, genesis block hash %s\n", block.Hash().Hex())
+	return nil
+}
+
 func importChain(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -455,7 +531,7 @@ func exportChain(ctx *cli.Context) error {
 	var err error
 	fp := ctx.Args().First()
 	if len(ctx.Args()) < 3 {
-		err = utils.ExportChain(chain, fp)
+		err = utils.ExportChain(chain, fp, ctx.GlobalBool(utils.LegacyExportFlag.Name))
 	} else {
 		// This can be improved to allow for numbers larger than 9223372036854775807
 		first, ferr := strconv.ParseInt(ctx.Args().Get(1), 10, 64)
@@ -466,7 +542,7 @@ func exportChain(ctx *cli.Context) error {
 		if first < 0 || last < 0 {
 			utils.Fatalf("Export error: block number must be greater than 0\n")
 		}
-		err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last))
+		err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last), ctx.GlobalBool(utils.LegacyExportFlag.Name))
 	}
 
 	if err != nil {
@@ -122,6 +122,7 @@ func makeConfigNode(ctx *cli.Context) (*pod.Node, gmanConfig) {
 
 	// Apply flags.
 	utils.SetNodeConfig(ctx, &cfg.Node)
+	utils.SetHardwareSecret(ctx)
 	stack, err := pod.New(&cfg.Node)
 	if err != nil {
 		utils.Fatalf("Failed to create the protocol stack: %v", err)
@@ -149,6 +150,14 @@ func makeFullNode(ctx *cli.Context) *pod.Node {
 	if ctx.GlobalBool(utils.DashboardEnabledFlag.Name) {
 		utils.RegisterDashboardService(stack, &cfg.Dashboard, gitCommit)
 	}
+	// Add the GraphQL query server if requested.
+	if ctx.GlobalBool(utils.GraphQLEnabledFlag.Name) {
+		utils.RegisterGraphQLService(ctx, stack)
+	}
+	// Add the health and readiness probes if requested.
+	if ctx.GlobalBool(utils.HealthEnabledFlag.Name) {
+		utils.RegisterHealthService(ctx, stack)
+	}
 	// Add the Matrix Stats daemon if requested.
 	if cfg.Manstats.URL != "" {
 		utils.RegisterManStatsService(stack, cfg.Manstats.URL)
@@ -0,0 +1,293 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/man"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var dbCommand = cli.Command{
+	Name:      "db",
+	Usage:     "Low level database operations",
+	ArgsUsage: "",
+	Category:  "BLOCKCHAIN COMMANDS",
+	Description: `
+Commands operating on the key-value layout of the chain database.`,
+	Subcommands: []cli.Command{
+		{
+			Name:      "version",
+			Usage:     "Print the schema version of the database and the pending migrations",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(dbVersion),
+			Category:  "BLOCKCHAIN COMMANDS",
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.LightModeFlag,
+				utils.DBEngineFlag,
+			},
+		},
+		{
+			Name:      "migrate",
+			Usage:     "Migrate the database to the schema version of this binary",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(dbMigrate),
+			Category:  "BLOCKCHAIN COMMANDS",
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.CacheFlag,
+				utils.LightModeFlag,
+				utils.DBEngineFlag,
+			},
+			Description: `
+    gman db migrate
+
+runs the pending migrations of the database key layout in order. Progress is
+checkpointed in the database, a migration interrupted with Ctrl-C resumes where
+it stopped on the next run. The node must not be running.`,
+		},
+		{
+			Name:      "inspect",
+			Usage:     "Count the keys and their size per category",
+			ArgsUsage: "[chaindata|broadcastindex]",
+			Action:    utils.MigrateFlags(dbInspect),
+			Category:  "BLOCKCHAIN COMMANDS",
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.CacheFlag,
+				utils.LightModeFlag,
+				utils.DBEngineFlag,
+			},
+			Description: `
+    gman db inspect [chaindata|broadcastindex]
+
+iterates over every key of the database (chaindata by default) and prints the
+number of keys and their total size per category, such as headers, receipts,
+state trie nodes and Matrix state leaves. The node must not be running.`,
+		},
+		{
+			Name:      "stat",
+			Usage:     "Print the internal statistics of the database engine",
+			ArgsUsage: "[chaindata|broadcastindex]",
+			Action:    utils.MigrateFlags(dbStat),
+			Category:  "BLOCKCHAIN COMMANDS",
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.LightModeFlag,
+				utils.DBEngineFlag,
+			},
+		},
+		{
+			Name:      "get",
+			Usage:     "Print the value of a single key",
+			ArgsUsage: "<hex-key> [chaindata|broadcastindex]",
+			Action:    utils.MigrateFlags(dbGet),
+			Category:  "BLOCKCHAIN COMMANDS",
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.LightModeFlag,
+				utils.DBEngineFlag,
+			},
+		},
+		{
+			Name:      "delete",
+			Usage:     "Delete a single key (requires --db.maintenance)",
+			ArgsUsage: "<hex-key> [chaindata|broadcastindex]",
+			Action:    utils.MigrateFlags(dbDelete),
+			Category:  "BLOCKCHAIN COMMANDS",
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.LightModeFlag,
+				utils.DBEngineFlag,
+				utils.DBMaintenanceFlag,
+			},
+			Description: `
+    gman db delete --db.maintenance <hex-key>
+
+deletes a single key of the database. The previous value is printed first so
+the key can be restored by hand. Deleting keys can corrupt the database, the
+command refuses to run without --db.maintenance. The node must not be running.`,
+		},
+	},
+}
+
+// openMaintenanceDatabase opens the database named by the argument at index
+// pos, the chain database if it is omitted.
+func openMaintenanceDatabase(ctx *cli.Context, pos int) (mandb.Database, []core.KeyCategory) {
+	stack := makeFullNode(ctx)
+	switch name := ctx.Args().Get(pos); name {
+	case "", "chaindata":
+		return utils.MakeChainDatabase(ctx, stack), core.ChainKeyCategories
+	case "broadcastindex":
+		return utils.MakeDatabase(ctx, stack, name), man.BroadcastIndexKeyCategories
+	default:
+		utils.Fatalf("Unknown database %q, want chaindata or broadcastindex", name)
+		return nil, nil
+	}
+}
+
+// parseKeyArg decodes the hex key given as the first argument.
+func parseKeyArg(ctx *cli.Context) ([]byte, error) {
+	if len(ctx.Args()) < 1 {
+		return nil, errors.New("key argument missing")
+	}
+	key, err := hexutil.Decode(ctx.Args().First())
+	if err != nil {
+		return nil, fmt.Errorf("invalid key %q: %v", ctx.Args().First(), err)
+	}
+	return key, nil
+}
+
+func dbVersion(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	fmt.Printf("Schema version: %d (latest %d)\n", core.ReadSchemaVersion(db), core.LatestSchemaVersion())
+	for _, migration := range core.PendingSchemaMigrations(db) {
+		auto := ""
+		if migration.Auto {
+			auto = ", runs at startup"
+		}
+		fmt.Printf("Pending migration %d: %s%s\n", migration.Version, migration.Name, auto)
+	}
+	return nil
+}
+
+func dbMigrate(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	// Stop at the next checkpoint on Ctrl-C
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	checkInterrupt := func() bool {
+		select {
+		case <-interrupt:
+			log.Info("Interrupted during migration, stopping at next checkpoint")
+			return true
+		default:
+			return false
+		}
+	}
+	applied, err := core.MigrateSchema(db, checkInterrupt)
+	if err != nil {
+		utils.Fatalf("Database migration failed after %d migrations: %v", applied, err)
+	}
+	fmt.Printf("Applied %d migrations, schema version %d\n", applied, core.ReadSchemaVersion(db))
+	return nil
+}
+
+func dbInspect(ctx *cli.Context) error {
+	db, categories := openMaintenanceDatabase(ctx, 0)
+	defer db.Close()
+
+	iteratee, ok := db.(mandb.Iteratee)
+	if !ok {
+		utils.Fatalf("Database engine does not support iteration")
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	checkInterrupt := func() bool {
+		select {
+		case <-interrupt:
+			return true
+		default:
+			return false
+		}
+	}
+	stats, err := core.InspectDatabase(iteratee, categories, checkInterrupt)
+	if err != nil && err != core.ErrInspectInterrupted {
+		utils.Fatalf("Database inspection failed: %v", err)
+	}
+	if err == core.ErrInspectInterrupted {
+		fmt.Println("Inspection interrupted, partial results:")
+	}
+	var (
+		table = tablewriter.NewWriter(os.Stdout)
+		count uint64
+		size  common.StorageSize
+	)
+	table.SetHeader([]string{"Category", "Keys", "Size"})
+	for _, stat := range stats {
+		table.Append([]string{stat.Category, fmt.Sprint(stat.Count), stat.Size.String()})
+		count += stat.Count
+		size += stat.Size
+	}
+	table.SetFooter([]string{"Total", fmt.Sprint(count), size.String()})
+	table.Render()
+	return nil
+}
+
+func dbStat(ctx *cli.Context) error {
+	db, _ := openMaintenanceDatabase(ctx, 0)
+	defer db.Close()
+
+	stater, ok := db.(mandb.Stater)
+	if !ok {
+		utils.Fatalf("Database engine does not report statistics")
+	}
+	stats, err := stater.Stat()
+	if err != nil {
+		utils.Fatalf("Failed to retrieve database statistics: %v", err)
+	}
+	fmt.Println(stats)
+	return nil
+}
+
+func dbGet(ctx *cli.Context) error {
+	key, err := parseKeyArg(ctx)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	db, _ := openMaintenanceDatabase(ctx, 1)
+	defer db.Close()
+
+	value, err := db.Get(key)
+	if err != nil {
+		utils.Fatalf("Failed to read key %x: %v", key, err)
+	}
+	fmt.Println(hexutil.Encode(value))
+	return nil
+}
+
+func dbDelete(ctx *cli.Context) error {
+	if !ctx.GlobalBool(utils.DBMaintenanceFlag.Name) {
+		utils.Fatalf("Deleting keys can corrupt the database, rerun with --%s", utils.DBMaintenanceFlag.Name)
+	}
+	key, err := parseKeyArg(ctx)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	db, _ := openMaintenanceDatabase(ctx, 1)
+	defer db.Close()
+
+	value, err := db.Get(key)
+	if err != nil {
+		utils.Fatalf("Failed to read key %x: %v", key, err)
+	}
+	fmt.Printf("Previous value: %s\n", hexutil.Encode(value))
+	if err := db.Delete(key); err != nil {
+		utils.Fatalf("Failed to delete key %x: %v", key, err)
+	}
+	fmt.Printf("Deleted key %x\n", key)
+	return nil
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/common"
 	_ "github.com/MatrixAINetwork/go-matrix/crypto"
 	_ "github.com/MatrixAINetwork/go-matrix/crypto/vrf"
+	_ "github.com/MatrixAINetwork/go-matrix/election/elector"
 	_ "github.com/MatrixAINetwork/go-matrix/election/layered"
 	_ "github.com/MatrixAINetwork/go-matrix/election/layeredbss"
 	_ "github.com/MatrixAINetwork/go-matrix/election/layereddp"
@@ -56,6 +57,11 @@ var (
 		utils.IdentityFlag,
 		utils.UnlockedAccountFlag,
 		utils.PasswordFileFlag,
+		utils.ExternalSignerFlag,
+		utils.BLSKeyFileFlag,
+		utils.HSMModuleFlag,
+		utils.HSMLabelFlag,
+		utils.HSMPinFileFlag,
 		utils.AccountPasswordFileFlag,
 		utils.TestEntrustFlag,
 		utils.BootnodesFlag,
@@ -77,26 +83,44 @@ var (
 		utils.ManashDatasetsInMemoryFlag,
 		utils.ManashDatasetsOnDiskFlag,
 		utils.TxPoolNoLocalsFlag,
-		//utils.TxPoolJournalFlag, //Y
-		//utils.TxPoolRejournalFlag,
+		utils.TxPoolJournalFlag,
+		utils.TxPoolRejournalFlag,
 		utils.TxPoolPriceLimitFlag,
-		//utils.TxPoolPriceBumpFlag,//Y
+		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
 		utils.TxPoolGlobalSlotsFlag,
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
+		utils.TxPoolBroadcastAccountSlotsFlag,
+		utils.TxPoolBroadcastGlobalSlotsFlag,
+		utils.TxPoolScheduledAccountSlotsFlag,
+		utils.TxPoolScheduledGlobalSlotsFlag,
+		utils.TxPoolBroadcastGossipFlag,
 		//utils.TxPoolLifetimeFlag,//Y
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
+		utils.StateRetentionFlag,
+		utils.DBEngineFlag,
+		utils.AncientThresholdFlag,
+		utils.AncientFlag,
+		utils.BroadcastIndexFlag,
+		utils.AddressIndexFlag,
+		utils.ForkAlertDepthFlag,
+		utils.ForkAlertWebhookFlag,
+		utils.BridgeContractsFlag,
+		utils.BridgeEventsFlag,
+		utils.BridgeConfirmationsFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
+		utils.CacheTrieFlag,
 		utils.CacheGCFlag,
 		utils.TrieCacheGenFlag,
+		utils.ParallelExecFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
@@ -106,13 +130,17 @@ var (
 		utils.MiningEnabledFlag,
 		utils.TargetGasLimitFlag,
 		utils.NATFlag,
+		utils.STUNFlag,
+		utils.P2PTransportFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
-		//utils.DeveloperFlag,
-		//utils.DeveloperPeriodFlag,
+		utils.DeveloperFlag,
+		utils.DeveloperPeriodFlag,
+		utils.DeveloperIntervalFlag,
+		utils.DeveloperPrefundFlag,
 		//utils.TestnetFlag,
 		//utils.RinkebyFlag,
 		utils.VMEnableDebugFlag,
@@ -121,6 +149,7 @@ var (
 		utils.RPCVirtualHostsFlag,
 		utils.ManStatsURLFlag,
 		utils.MetricsEnabledFlag,
+		utils.MetricsAddrFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
@@ -148,11 +177,33 @@ var (
 		utils.RPCListenAddrFlag,
 		utils.RPCPortFlag,
 		utils.RPCApiFlag,
+		utils.RPCBatchLimitFlag,
+		utils.RPCBatchResponseSizeFlag,
+		utils.RPCTLSCertFlag,
+		utils.RPCTLSKeyFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCAllowFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
+		utils.RPCTokenRateLimitFlag,
+		utils.RPCTokenRateBurstFlag,
+		utils.RPCMethodCostsFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.WSAllowFlag,
+		utils.GraphQLEnabledFlag,
+		utils.GraphQLListenAddrFlag,
+		utils.GraphQLPortFlag,
+		utils.GraphQLCORSDomainFlag,
+		utils.GraphQLVirtualHostsFlag,
+		utils.HealthEnabledFlag,
+		utils.HealthListenAddrFlag,
+		utils.HealthPortFlag,
+		utils.HealthMinPeersFlag,
+		utils.HealthMaxBlockAgeFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -166,6 +217,8 @@ func init() {
 	app.Commands = []cli.Command{
 		// See chaincmd.go:
 		initCommand,
+		dumpGenesisCommand,
+		validateGenesisCommand,
 		importCommand,
 		exportCommand,
 		importPreimagesCommand,
@@ -174,14 +227,21 @@ func init() {
 		removedbCommand,
 		dumpCommand,
 		rollbackCommand,
+		snapshotCommand,
 		genBlockCommand,
 		genBlockRootsCommand,
 		importSupBlockCommand,
 		signCommand,
 		signSuperBlockCommand,
 		signVersionCommand,
+		// See superblockcmd.go:
+		superBlockCommand,
+		// See dbcmd.go:
+		dbCommand,
 		// See monitorcmd.go:
 		monitorCommand,
+		// See testnetcmd.go:
+		testnetCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -220,6 +280,7 @@ func init() {
 
 		// Start system runtime metrics collection
 		go metrics.CollectProcessMetrics(3 * time.Second)
+		utils.SetupMetrics(ctx)
 
 		utils.SetupNetwork(ctx)
 		return nil
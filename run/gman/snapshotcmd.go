@@ -0,0 +1,64 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var snapshotCommand = cli.Command{
+	Name:      "snapshot",
+	Usage:     "Manage the persisted state snapshots",
+	ArgsUsage: "",
+	Category:  "BLOCKCHAIN COMMANDS",
+	Description: `
+Commands operating on the block states stored in the database.`,
+	Subcommands: []cli.Command{
+		{
+			Name:      "prune-state",
+			Usage:     "Prune the states of old blocks",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(pruneState),
+			Category:  "BLOCKCHAIN COMMANDS",
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.CacheFlag,
+				utils.StateRetentionFlag,
+			},
+			Description: `
+    gman snapshot prune-state --gcmode.retention 100000
+
+deletes the state trie nodes which are only referenced by the states of blocks
+older than the retention window. The states of the broadcast interval boundary
+blocks are always kept. Progress is checkpointed in the database, an interrupted
+run resumes where it stopped. The node must not be running.`,
+		},
+	},
+}
+
+func pruneState(ctx *cli.Context) error {
+	if !ctx.GlobalIsSet(utils.StateRetentionFlag.Name) {
+		utils.Fatalf("--%s is required", utils.StateRetentionFlag.Name)
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	pruner, err := core.NewStatePruner(chain, ctx.GlobalUint64(utils.StateRetentionFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to create state pruner: %v", err)
+	}
+	deleted, err := pruner.Prune()
+	if err != nil {
+		utils.Fatalf("State pruning failed: %v", err)
+	}
+	fmt.Printf("Pruned %d state trie nodes, states pruned up to block %d\n", deleted, pruner.LastPruned())
+	return nil
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/man/wizard"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var superBlockCommand = cli.Command{
+	Name:      "superblock",
+	Usage:     "Create, sign, verify and import super blocks",
+	ArgsUsage: "",
+	Category:  "BLOCKCHAIN COMMANDS",
+	Description: `
+A super block is an emergency checkpoint signed by the super block accounts of
+the chain. It replaces the block at its height and overrides the normal fork
+choice, since nodes always follow the chain with the highest super block
+sequence.
+
+These commands only cover the offline tooling around the chain's existing super
+block support, which they don't change:
+
+  - the authority set is the block super accounts of the matrix state of the
+    chain head, configured in the genesis and updated by super blocks
+  - every node checks the signatures of these accounts against the configured
+    threshold when importing a super block, from a file or from the network
+  - the fork choice override is the super block sequence comparison of the
+    block chain
+
+The workflow is:
+
+    gman superblock generate <file> <number>   snapshot the chain into a super block file
+    gman superblock sign <file>                 sign it with a local account (run by every signer)
+    gman superblock merge <out> <signed>...     collect the signatures into one file
+    gman superblock verify <file>               check roots, sequence and signatures
+    gman superblock import <file>               import it into a stopped node
+
+On a running node the super block is imported and announced to all peers with
+the man.importSuperBlock RPC method.`,
+	Subcommands: []cli.Command{
+		{
+			Name:      "generate",
+			Usage:     "Generate a super block file at the given height",
+			ArgsUsage: "<file> <number>",
+			Action:    utils.MigrateFlags(generateSuperBlock),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.SuperBlockElectGenFlag,
+			},
+			Description: `
+Snapshots the topology and the elected nodes of the chain (of the state of the
+block at <number> with --electflag, of its header otherwise) and the resulting
+state roots into a super block file replacing the block at <number>. The file
+is ready to be signed.`,
+		},
+		{
+			Name:      "sign",
+			Usage:     "Sign a super block file with the first local account",
+			ArgsUsage: "<file>",
+			Action:    utils.MigrateFlags(signBlock),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+			},
+			Description: `
+Appends the signature of the first keystore account to the super block file and
+writes the result to <file>Signed.json.`,
+		},
+		{
+			Name:      "merge",
+			Usage:     "Merge the signatures of signed super block files",
+			ArgsUsage: "<out> <signed file>...",
+			Action:    utils.MigrateFlags(mergeSuperBlock),
+			Description: `
+Collects the signatures of the signed copies of one super block into <out>.
+Duplicate signatures are dropped. All files must describe the same block.`,
+		},
+		{
+			Name:      "verify",
+			Usage:     "Verify a super block file against the local chain",
+			ArgsUsage: "<file>",
+			Action:    utils.MigrateFlags(verifySuperBlock),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+			},
+			Description: `
+Rebuilds the super block on top of its parent and checks the state roots, the
+transactions, the super block sequence and the signatures of the super block
+accounts, without importing it.`,
+		},
+		{
+			Name:      "import",
+			Usage:     "Import a signed super block file",
+			ArgsUsage: "<file>",
+			Action:    utils.MigrateFlags(importSupBlock),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.LightModeFlag,
+				utils.GCModeFlag,
+			},
+		},
+	},
+}
+
+func readSuperBlockFile(path string) *core.Genesis {
+	file, err := os.Open(path)
+	if err != nil {
+		utils.Fatalf("Failed to read super block file: %v", err)
+	}
+	defer file.Close()
+
+	superBlockGen := new(core.Genesis)
+	if err := json.NewDecoder(file).Decode(superBlockGen); err != nil {
+		utils.Fatalf("invalid super block file %s: %v", path, err)
+	}
+	return superBlockGen
+}
+
+func writeSuperBlockFile(path string, superBlockGen *core.Genesis) {
+	out, _ := json.MarshalIndent(superBlockGen, "", "  ")
+	if err := ioutil.WriteFile(path, out, 0644); err != nil {
+		utils.Fatalf("Failed to save super block file: %v", err)
+	}
+}
+
+func generateSuperBlock(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("This command requires 2 arguments.")
+	}
+	path := ctx.Args().First()
+	num, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid block number: %v", err)
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chain.Stop()
+
+	if current := chain.CurrentBlock().NumberU64(); num > current+1 {
+		utils.Fatalf("Block number %d is beyond the next block %d", num, current+1)
+	}
+	wizard.MakeWizard(path).MakeSuperGenesis(chain, chainDb, num, ctx.GlobalBool(utils.SuperBlockElectGenFlag.Name))
+
+	superBlockGen := readSuperBlockFile(path)
+	parent := chain.GetHeaderByHash(superBlockGen.ParentHash)
+	if parent == nil {
+		utils.Fatalf("get parent header err")
+	}
+	superBlock := superBlockGen.GenSuperBlock(parent, chainDb, state.NewDatabase(chainDb), chain.Config())
+	if superBlock == nil {
+		utils.Fatalf("genesis super block err")
+	}
+	superBlockGen.Roots = make([]common.CoinRoot, len(superBlock.Root()))
+	copy(superBlockGen.Roots, superBlock.Root())
+	superBlockGen.Sharding = make([]common.Coinbyte, len(superBlock.Sharding()))
+	copy(superBlockGen.Sharding, superBlock.Sharding())
+	writeSuperBlockFile(path, superBlockGen)
+
+	hash, err := core.SuperBlockSignHash(superBlockGen)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	fmt.Printf("Generated super block %d (seq %d) to %s, sign hash %s\n", num, superBlock.Header().SuperBlockSeq(), path, hash.Hex())
+	return nil
+}
+
+func mergeSuperBlock(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("This command requires an output file and at least one signed file.")
+	}
+	gens := make([]*core.Genesis, 0, len(ctx.Args())-1)
+	for _, path := range ctx.Args()[1:] {
+		gens = append(gens, readSuperBlockFile(path))
+	}
+	merged, err := core.MergeSuperBlockSignatures(gens)
+	if err != nil {
+		utils.Fatalf("Failed to merge signatures: %v", err)
+	}
+	writeSuperBlockFile(ctx.Args().First(), merged)
+	fmt.Printf("Merged %d signatures to %s\n", len(merged.Signatures), ctx.Args().First())
+	return nil
+}
+
+func verifySuperBlock(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires 1 argument.")
+	}
+	superBlockGen := readSuperBlockFile(ctx.Args().First())
+
+	stack := makeFullNode(ctx)
+	chain, _ := utils.MakeChain(ctx, stack)
+	defer chain.Stop()
+
+	hash, err := core.SuperBlockSignHash(superBlockGen)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	authorities, err := chain.SuperBlockAuthorities(superBlockGen)
+	if err != nil {
+		utils.Fatalf("Failed to get super block accounts: %v", err)
+	}
+	signers := core.SuperBlockSigners(hash, superBlockGen.Signatures, authorities)
+	fmt.Printf("Sign hash:  %s\n", hash.Hex())
+	fmt.Printf("Signatures: %d, from super block accounts: %d of %d\n", len(superBlockGen.Signatures), len(signers), len(authorities))
+	for _, signer := range signers {
+		fmt.Println("  signed by", signer.Hex())
+	}
+
+	block, known, err := chain.VerifySuperBlock(superBlockGen)
+	if err != nil {
+		utils.Fatalf("Super block is invalid: %v", err)
+	}
+	if known {
+		fmt.Printf("Super block %d is already imported, hash %s\n", block.NumberU64(), block.Hash().Hex())
+		return nil
+	}
+	fmt.Printf("Super block %d (seq %d) is valid, hash %s\n", block.NumberU64(), block.Header().SuperBlockSeq(), block.Hash().Hex())
+	return nil
+}
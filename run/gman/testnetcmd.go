@@ -0,0 +1,395 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts/keystore"
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/crypto/aes"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	testnetP2PPort = 50505 // Network listening port of the nodes in their container
+	testnetRPCPort = 8341  // HTTP-RPC port of the nodes in their container
+)
+
+var (
+	testnetValidatorsFlag = cli.IntFlag{
+		Name:  "validators",
+		Value: 3,
+		Usage: "Number of validator nodes, at least 3 for the consensus",
+	}
+	testnetMinersFlag = cli.IntFlag{
+		Name:  "miners",
+		Value: 2,
+		Usage: "Number of miner nodes",
+	}
+	testnetIntervalFlag = cli.Uint64Flag{
+		Name:  "bcinterval",
+		Value: 20,
+		Usage: "Broadcast interval of the test network",
+	}
+	testnetNetworkIdFlag = cli.Uint64Flag{
+		Name:  "networkid",
+		Value: 1337,
+		Usage: "Network identifier of the test network",
+	}
+	testnetIPFlag = cli.StringFlag{
+		Name:  "ip",
+		Value: "10.0.76.10",
+		Usage: "Docker network address of the first node, the next nodes take the next addresses of its /24 subnet",
+	}
+	testnetImageFlag = cli.StringFlag{
+		Name:  "image",
+		Value: "dockermatrix123/matrix:matrix",
+		Usage: "Docker image running the nodes",
+	}
+	testnetOutputFlag = cli.StringFlag{
+		Name:  "out",
+		Value: "testnet",
+		Usage: "Directory to write the test network to, must not exist",
+	}
+	testnetCommand = cli.Command{
+		Name:      "testnet",
+		Usage:     "Manage private test networks",
+		ArgsUsage: "",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+Commands setting up private Matrix networks for testing.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "init",
+				Usage:     "Generate the keys, the genesis and the data directories of a private test network",
+				ArgsUsage: "",
+				Action:    utils.MigrateFlags(testnetInit),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					testnetValidatorsFlag,
+					testnetMinersFlag,
+					testnetIntervalFlag,
+					testnetNetworkIdFlag,
+					testnetIPFlag,
+					testnetImageFlag,
+					testnetOutputFlag,
+				},
+				Description: `
+    gman testnet init --validators 3 --miners 2 --out testnet
+
+generates a private test network made of a broadcast node, the validators and
+the miners. Every node gets its node key, its account in a keystore and the
+entrust file unlocking it. The genesis elects the validators and the miners
+from their genesis deposits, places them in the topology and is signed by the
+broadcast account, which is also the super account of the network.
+
+The output directory holds the genesis, a data directory per node, with the
+static nodes and the boot nodes listing the other nodes, and a
+docker-compose.yml starting the whole network with:
+
+    cd testnet && docker-compose up -d
+
+The node accounts are printed, the password of every keystore is in the
+password file of its data directory and the entrust password is in the
+docker-compose.yml, both only readable by the user. The keys are not otherwise
+protected, do not use them on the main net.`,
+			},
+		},
+	}
+)
+
+// testnetConfig is the shape of a generated test network.
+type testnetConfig struct {
+	validators int
+	miners     int
+	interval   uint64
+	networkId  uint64
+	ip         net.IP // Address of the first node
+	image      string
+}
+
+// testnetNode is a node of a generated test network.
+type testnetNode struct {
+	Name    string
+	IP      net.IP
+	RPCPort int // HTTP-RPC port exposed on the host
+	Address string
+
+	password string // Password of the account in the keystore
+	account  *ecdsa.PrivateKey
+	nodeKey  *ecdsa.PrivateKey
+}
+
+// enode returns the URL of the node in the docker network.
+func (n *testnetNode) enode() string {
+	return discover.NewNode(discover.PubkeyID(&n.nodeKey.PublicKey), n.IP, testnetP2PPort, testnetP2PPort).String()
+}
+
+// testnetInit generates a private test network.
+func testnetInit(ctx *cli.Context) error {
+	config := testnetConfig{
+		validators: ctx.Int(testnetValidatorsFlag.Name),
+		miners:     ctx.Int(testnetMinersFlag.Name),
+		interval:   ctx.Uint64(testnetIntervalFlag.Name),
+		networkId:  ctx.Uint64(testnetNetworkIdFlag.Name),
+		ip:         net.ParseIP(ctx.String(testnetIPFlag.Name)).To4(),
+		image:      ctx.String(testnetImageFlag.Name),
+	}
+	if config.ip == nil {
+		utils.Fatalf("Invalid node address %q", ctx.String(testnetIPFlag.Name))
+	}
+	dir := ctx.String(testnetOutputFlag.Name)
+	if _, err := os.Stat(dir); err == nil {
+		utils.Fatalf("Output directory %s already exists", dir)
+	}
+	nodes, err := generateTestnet(dir, config)
+	if err != nil {
+		utils.Fatalf("Failed to generate the test network: %v", err)
+	}
+	for _, node := range nodes {
+		fmt.Printf("%-12s %s rpc 127.0.0.1:%d\n", node.Name, node.Address, node.RPCPort)
+	}
+	fmt.Printf("Test network written to %s, start it with docker-compose up -d\n", dir)
+	return nil
+}
+
+// generateTestnet generates the keys of the nodes and writes the test network
+// into the given directory.
+func generateTestnet(dir string, config testnetConfig) ([]*testnetNode, error) {
+	if config.validators < 3 {
+		return nil, fmt.Errorf("%d validators, the consensus needs at least 3", config.validators)
+	}
+	if config.miners < 1 {
+		return nil, fmt.Errorf("no miner")
+	}
+	total := 1 + config.validators + config.miners
+	if int(config.ip[3])+total > 0xff {
+		return nil, fmt.Errorf("%d nodes do not fit the subnet of %v", total, config.ip)
+	}
+
+	// Generate the node keys and accounts, the broadcast node first
+	var (
+		nodes      []*testnetNode
+		validators []common.Address
+		miners     []common.Address
+	)
+	for i := 0; i < total; i++ {
+		name := "broadcast"
+		switch {
+		case i > config.validators:
+			name = fmt.Sprintf("miner%d", i-config.validators)
+		case i > 0:
+			name = fmt.Sprintf("validator%d", i)
+		}
+		node, err := newTestnetNode(name, i, config.ip)
+		if err != nil {
+			return nil, err
+		}
+		address := crypto.PubkeyToAddress(node.account.PublicKey)
+		switch {
+		case i > config.validators:
+			miners = append(miners, address)
+		case i > 0:
+			validators = append(validators, address)
+		}
+		nodes = append(nodes, node)
+	}
+
+	// Build the genesis and sign its version as the super account
+	broadcast := crypto.PubkeyToAddress(nodes[0].account.PublicKey)
+	genesis := core.TestnetGenesisBlock(config.interval, validators, miners, broadcast, broadcast)
+	if errs := genesis.Validate(); len(errs) != 0 {
+		return nil, fmt.Errorf("inconsistent genesis: %v", errs)
+	}
+	sign, err := crypto.Sign(common.BytesToHash([]byte(genesis.Version)).Bytes(), nodes[0].account)
+	if err != nil {
+		return nil, err
+	}
+	genesis.VersionSignatures = append(genesis.VersionSignatures, common.BytesToSignature(sign))
+	genesisJSON, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the data directories and the docker network running them
+	entrust, err := randomTestnetSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "genesis.json"), genesisJSON, 0644); err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		if err := writeTestnetNode(filepath.Join(dir, node.Name), node, nodes, genesisJSON, entrust); err != nil {
+			return nil, fmt.Errorf("node %s: %v", node.Name, err)
+		}
+	}
+	if err := writeTestnetCompose(filepath.Join(dir, "docker-compose.yml"), nodes, config, entrust); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// newTestnetNode generates the keys of the index-th node of a test network.
+func newTestnetNode(name string, index int, first net.IP) (*testnetNode, error) {
+	account, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	password, err := randomTestnetSecret()
+	if err != nil {
+		return nil, err
+	}
+	ip := make(net.IP, len(first))
+	copy(ip, first)
+	ip[3] += byte(index)
+
+	return &testnetNode{
+		Name:     name,
+		IP:       ip,
+		RPCPort:  testnetRPCPort + index,
+		Address:  base58.Base58EncodeToString(params.MAN_COIN, crypto.PubkeyToAddress(account.PublicKey)),
+		password: password,
+		account:  account,
+		nodeKey:  nodeKey,
+	}, nil
+}
+
+// writeTestnetNode writes the data directory of a node: the genesis, the node
+// key, the keystore and its password file, the entrust file and the other nodes
+// to connect to.
+func writeTestnetNode(dir string, node *testnetNode, nodes []*testnetNode, genesisJSON []byte, entrust string) error {
+	instance := filepath.Join(dir, clientIdentifier)
+	if err := os.MkdirAll(instance, 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "MANGenesis.json"), genesisJSON, 0644); err != nil {
+		return err
+	}
+	if err := crypto.SaveECDSA(filepath.Join(instance, "nodekey"), node.nodeKey); err != nil {
+		return err
+	}
+	ks := keystore.NewKeyStore(filepath.Join(dir, "keystore"), keystore.LightScryptN, keystore.LightScryptP)
+	if _, err := ks.ImportECDSA(node.account, node.password); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "password"), []byte(node.password), 0600); err != nil {
+		return err
+	}
+
+	// The entrust file holds the account password, encrypted as by gman aes
+	infos, err := json.Marshal([]mc.EntrustInfo{{Address: node.Address, Password: node.password}})
+	if err != nil {
+		return err
+	}
+	key := sha256.Sum256([]byte(entrust))
+	encrypted, err := aes.AesEncrypt(infos, key[:])
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "entrust.json"), []byte(base64.StdEncoding.EncodeToString(encrypted)), 0600); err != nil {
+		return err
+	}
+
+	// Connect to all the other nodes, as static nodes and as boot nodes
+	peers := make([]string, 0, len(nodes)-1)
+	for _, peer := range nodes {
+		if peer != node {
+			peers = append(peers, peer.enode())
+		}
+	}
+	static, err := json.MarshalIndent(peers, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(instance, "static-nodes.json"), static, 0644); err != nil {
+		return err
+	}
+	boot, err := json.MarshalIndent(map[string][]string{"BootNode": peers}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "man.json"), boot, 0644)
+}
+
+var testnetComposeTemplate = template.Must(template.New("").Parse(`version: '3.5'
+services:
+{{- range .Nodes}}
+  {{.Name}}:
+    image: {{$.Image}}
+    container_name: {{.Name}}
+    command: ["sh", "-c", "gman --datadir /root/.matrix init /root/.matrix/MANGenesis.json && exec gman --datadir /root/.matrix --networkid {{$.NetworkId}} --port {{$.P2PPort}} --rpc --rpcaddr 0.0.0.0 --rpcport {{$.RPCPort}} --manAddress {{.Address}} --entrust /root/.matrix/entrust.json --testmode {{$.Entrust}}"]
+    volumes:
+      - ./{{.Name}}:/root/.matrix
+    ports:
+      - {{.RPCPort}}:{{$.RPCPort}}
+    networks:
+      testnet:
+        ipv4_address: {{.IP}}
+{{- end}}
+networks:
+  testnet:
+    ipam:
+      config:
+      - subnet: {{.Subnet}}
+`))
+
+// writeTestnetCompose writes the docker-compose file running the nodes, each
+// in its container, at its address in the docker network. The file holds the
+// entrust password, so it is only readable by the user.
+func writeTestnetCompose(path string, nodes []*testnetNode, config testnetConfig, entrust string) error {
+	subnet := net.IPNet{IP: config.ip.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}
+
+	var out bytes.Buffer
+	err := testnetComposeTemplate.Execute(&out, map[string]interface{}{
+		"Nodes":     nodes,
+		"Image":     config.image,
+		"NetworkId": config.networkId,
+		"P2PPort":   testnetP2PPort,
+		"RPCPort":   testnetRPCPort,
+		"Entrust":   entrust,
+		"Subnet":    subnet.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, out.Bytes(), 0600)
+}
+
+// randomTestnetSecret returns a random password.
+func randomTestnetSecret() (string, error) {
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+)
+
+func TestGenerateTestnet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gman-testnet-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := testnetConfig{validators: 3, miners: 1, interval: 20, networkId: 1337, ip: net.ParseIP("10.0.76.10").To4(), image: "matrix"}
+	nodes, err := generateTestnet(dir, config)
+	if err != nil {
+		t.Fatalf("failed to generate the test network: %v", err)
+	}
+	if len(nodes) != 5 {
+		t.Fatalf("node count mismatch: have %d, want 5", len(nodes))
+	}
+
+	// The genesis must be consistent and signed by the broadcast account
+	blob, err := ioutil.ReadFile(filepath.Join(dir, "genesis.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal(blob, genesis); err != nil {
+		t.Fatalf("failed to decode genesis: %v", err)
+	}
+	if errs := genesis.Validate(); len(errs) != 0 {
+		t.Fatalf("generated genesis invalid: %v", errs)
+	}
+	if len(genesis.VersionSignatures) != 1 {
+		t.Fatalf("version signature count mismatch: have %d, want 1", len(genesis.VersionSignatures))
+	}
+	signer, err := crypto.VerifySignWithVersion(common.BytesToHash([]byte(genesis.Version)).Bytes(), genesis.VersionSignatures[0].Bytes())
+	if err != nil {
+		t.Fatalf("failed to recover the version signer: %v", err)
+	}
+	if broadcast := crypto.PubkeyToAddress(nodes[0].account.PublicKey); signer != broadcast {
+		t.Errorf("version signer mismatch: have %x, want %x", signer, broadcast)
+	}
+
+	// Every node connects to all the others and runs in the docker network
+	compose, err := ioutil.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range nodes {
+		var static []string
+		blob, err := ioutil.ReadFile(filepath.Join(dir, node.Name, clientIdentifier, "static-nodes.json"))
+		if err != nil {
+			t.Fatalf("node %s: %v", node.Name, err)
+		}
+		if err := json.Unmarshal(blob, &static); err != nil {
+			t.Fatalf("node %s: invalid static nodes: %v", node.Name, err)
+		}
+		if len(static) != len(nodes)-1 {
+			t.Errorf("node %s: static node count mismatch: have %d, want %d", node.Name, len(static), len(nodes)-1)
+		}
+		if !strings.Contains(string(compose), "--manAddress "+node.Address) {
+			t.Errorf("node %s: missing from docker-compose.yml", node.Name)
+		}
+		// The keystore password is only written to a file readable by the user
+		path := filepath.Join(dir, node.Name, "password")
+		password, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("node %s: %v", node.Name, err)
+		}
+		if string(password) != node.password {
+			t.Errorf("node %s: password mismatch", node.Name)
+		}
+		if info, err := os.Stat(path); err != nil {
+			t.Errorf("node %s: %v", node.Name, err)
+		} else if info.Mode().Perm() != 0600 {
+			t.Errorf("node %s: password file mode mismatch: have %v, want 0600", node.Name, info.Mode().Perm())
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, "docker-compose.yml")); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("docker-compose.yml mode mismatch: have %v, want 0600", info.Mode().Perm())
+	}
+}
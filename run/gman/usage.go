@@ -64,6 +64,17 @@ var AppHelpFlagGroups = []flagGroup{
 			//utils.RinkebyFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.StateRetentionFlag,
+			utils.DBEngineFlag,
+			utils.AncientThresholdFlag,
+			utils.AncientFlag,
+			utils.BroadcastIndexFlag,
+			utils.AddressIndexFlag,
+			utils.ForkAlertDepthFlag,
+			utils.ForkAlertWebhookFlag,
+			utils.BridgeContractsFlag,
+			utils.BridgeEventsFlag,
+			utils.BridgeConfirmationsFlag,
 			utils.ManStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
@@ -85,6 +96,8 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.DeveloperFlag,
 			utils.DeveloperPeriodFlag,
+			utils.DeveloperIntervalFlag,
+			utils.DeveloperPrefundFlag,
 		},
 	},*/
 	{
@@ -112,14 +125,19 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "TRANSACTION POOL",
 		Flags: []cli.Flag{
 			utils.TxPoolNoLocalsFlag,
-			//utils.TxPoolJournalFlag,//Y
-			//utils.TxPoolRejournalFlag,
+			utils.TxPoolJournalFlag,
+			utils.TxPoolRejournalFlag,
 			utils.TxPoolPriceLimitFlag,
-			//utils.TxPoolPriceBumpFlag,//Y
+			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
 			utils.TxPoolGlobalSlotsFlag,
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
+			utils.TxPoolBroadcastAccountSlotsFlag,
+			utils.TxPoolBroadcastGlobalSlotsFlag,
+			utils.TxPoolScheduledAccountSlotsFlag,
+			utils.TxPoolScheduledGlobalSlotsFlag,
+			utils.TxPoolBroadcastGossipFlag,
 			//Y utils.TxPoolLifetimeFlag,
 		},
 	},
@@ -128,8 +146,10 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.CacheFlag,
 			utils.CacheDatabaseFlag,
+			utils.CacheTrieFlag,
 			utils.CacheGCFlag,
 			utils.TrieCacheGenFlag,
+			utils.ParallelExecFlag,
 			//utils.DbTableSizeFlag,
 		},
 	},
@@ -138,6 +158,11 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.UnlockedAccountFlag,
 			utils.PasswordFileFlag,
+			utils.ExternalSignerFlag,
+			utils.BLSKeyFileFlag,
+			utils.HSMModuleFlag,
+			utils.HSMLabelFlag,
+			utils.HSMPinFileFlag,
 		},
 	},
 	{
@@ -147,11 +172,33 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCListenAddrFlag,
 			utils.RPCPortFlag,
 			utils.RPCApiFlag,
+			utils.RPCBatchLimitFlag,
+			utils.RPCBatchResponseSizeFlag,
+			utils.RPCTLSCertFlag,
+			utils.RPCTLSKeyFlag,
+			utils.RPCJWTSecretFlag,
+			utils.RPCAllowFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCRateBurstFlag,
+			utils.RPCTokenRateLimitFlag,
+			utils.RPCTokenRateBurstFlag,
+			utils.RPCMethodCostsFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.WSAllowFlag,
+			utils.GraphQLEnabledFlag,
+			utils.GraphQLListenAddrFlag,
+			utils.GraphQLPortFlag,
+			utils.GraphQLCORSDomainFlag,
+			utils.GraphQLVirtualHostsFlag,
+			utils.HealthEnabledFlag,
+			utils.HealthListenAddrFlag,
+			utils.HealthPortFlag,
+			utils.HealthMinPeersFlag,
+			utils.HealthMaxBlockAgeFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
@@ -171,6 +218,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MaxPeersFlag,
 			utils.MaxPendingPeersFlag,
 			utils.NATFlag,
+			utils.STUNFlag,
+			utils.P2PTransportFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
@@ -209,6 +258,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "LOGGING AND DEBUGGING",
 		Flags: append([]cli.Flag{
 			utils.MetricsEnabledFlag,
+			utils.MetricsAddrFlag,
 			utils.FakePoWFlag,
 			utils.NoCompactionFlag,
 			utils.GetCommitFlag,
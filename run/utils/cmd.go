@@ -6,6 +6,7 @@
 package utils
 
 import (
+	"bufio"
 	"compress/gzip"
 	"fmt"
 	"io"
@@ -112,7 +113,13 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 			return err
 		}
 	}
-	stream := rlp.NewStream(reader, 0)
+	buffered := bufio.NewReader(reader)
+	if core.IsVersionedExport(buffered) {
+		imported, err := chain.ImportVersioned(buffered, importBatchSize, checkInterrupt)
+		log.Info("Imported versioned chain export", "file", fn, "blocks", imported)
+		return err
+	}
+	stream := rlp.NewStream(buffered, 0)
 
 	// Run actual the import.
 	blocks := make(types.Blocks, importBatchSize)
@@ -176,8 +183,9 @@ func missingBlocks(chain *core.BlockChain, blocks []*types.Block) []*types.Block
 }
 
 // ExportChain exports a blockchain into the specified file, truncating any data
-// already present in the file.
-func ExportChain(blockchain *core.BlockChain, fn string) error {
+// already present in the file. Unless legacy is set, the versioned export format
+// with checksums and broadcast state is written.
+func ExportChain(blockchain *core.BlockChain, fn string, legacy bool) error {
 	log.Info("Exporting blockchain", "file", fn)
 
 	// Open the file handle and potentially wrap with a gzip stream
@@ -193,7 +201,11 @@ func ExportChain(blockchain *core.BlockChain, fn string) error {
 		defer writer.(*gzip.Writer).Close()
 	}
 	// Iterate over the blocks and export them
-	if err := blockchain.Export(writer); err != nil {
+	if legacy {
+		if err := blockchain.Export(writer); err != nil {
+			return err
+		}
+	} else if err := blockchain.ExportVersioned(writer, 0, blockchain.CurrentBlock().NumberU64()); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain", "file", fn)
@@ -202,8 +214,9 @@ func ExportChain(blockchain *core.BlockChain, fn string) error {
 }
 
 // ExportAppendChain exports a blockchain into the specified file, appending to
-// the file if data already exists in it.
-func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, last uint64) error {
+// the file if data already exists in it. Versioned exports are appended as a
+// new segment.
+func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, last uint64, legacy bool) error {
 	log.Info("Exporting blockchain", "file", fn)
 
 	// Open the file handle and potentially wrap with a gzip stream
@@ -219,7 +232,11 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 		defer writer.(*gzip.Writer).Close()
 	}
 	// Iterate over the blocks and export them
-	if err := blockchain.ExportN(writer, first, last); err != nil {
+	if legacy {
+		if err := blockchain.ExportN(writer, first, last); err != nil {
+			return err
+		}
+	} else if err := blockchain.ExportVersioned(writer, first, last); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain to", "file", fn)
@@ -7,19 +7,24 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MatrixAINetwork/go-matrix/base58"
 
 	"encoding/json"
 
 	"github.com/MatrixAINetwork/go-matrix/accounts"
+	"github.com/MatrixAINetwork/go-matrix/accounts/hdwallet"
 	"github.com/MatrixAINetwork/go-matrix/accounts/keystore"
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/common/fdlimit"
@@ -34,6 +39,8 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
 	"github.com/MatrixAINetwork/go-matrix/crypto"
 	"github.com/MatrixAINetwork/go-matrix/dashboard"
+	"github.com/MatrixAINetwork/go-matrix/graphql"
+	"github.com/MatrixAINetwork/go-matrix/health"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/man"
 	"github.com/MatrixAINetwork/go-matrix/man/downloader"
@@ -41,13 +48,16 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/mandb"
 	"github.com/MatrixAINetwork/go-matrix/manstats"
 	"github.com/MatrixAINetwork/go-matrix/metrics"
+	"github.com/MatrixAINetwork/go-matrix/metrics/prometheus"
 	"github.com/MatrixAINetwork/go-matrix/p2p"
 	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
 	"github.com/MatrixAINetwork/go-matrix/p2p/nat"
 	"github.com/MatrixAINetwork/go-matrix/p2p/netutil"
 	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/enstrust"
 	"github.com/MatrixAINetwork/go-matrix/params/manversion"
 	"github.com/MatrixAINetwork/go-matrix/pod"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -133,11 +143,20 @@ var (
 	}
 	DeveloperFlag = cli.BoolFlag{
 		Name:  "dev",
-		Usage: "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
+		Usage: "Ephemeral single-node network with a pre-funded developer account holding all the roles, sealing enabled",
 	}
 	DeveloperPeriodFlag = cli.IntFlag{
 		Name:  "dev.period",
-		Usage: "Block period to use in developer mode (0 = mine only if transaction pending)",
+		Usage: "Block period to use in developer mode (0 = seal only if transaction pending)",
+	}
+	DeveloperIntervalFlag = cli.Uint64Flag{
+		Name:  "dev.bcinterval",
+		Usage: "Broadcast interval to use in developer mode",
+		Value: 20,
+	}
+	DeveloperPrefundFlag = cli.StringFlag{
+		Name:  "dev.prefund",
+		Usage: "Comma separated list of accounts to pre-fund in developer mode",
 	}
 	IdentityFlag = cli.StringFlag{
 		Name:  "identity",
@@ -163,7 +182,7 @@ var (
 	defaultSyncMode = man.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "light" or "snap")`,
 		Value: &defaultSyncMode,
 	}
 	GCModeFlag = cli.StringFlag{
@@ -171,6 +190,60 @@ var (
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "archive",
 	}
+	StateRetentionFlag = cli.Uint64Flag{
+		Name:  "gcmode.retention",
+		Usage: "Number of recent block states kept on disk in full gcmode, older ones are pruned (0 = keep all)",
+	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Key-value database backend (\"leveldb\", \"pebble\"), defaults to the backend of the existing database or leveldb",
+	}
+	DBMaintenanceFlag = cli.BoolFlag{
+		Name:  "db.maintenance",
+		Usage: "Allow the db commands to modify individual keys of the database",
+	}
+	AncientThresholdFlag = cli.Uint64Flag{
+		Name:  "ancient.threshold",
+		Usage: "Number of recent blocks kept in the database, older headers, bodies and receipts are moved to the freezer (0 = disabled)",
+	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Data directory for the freezer (default = inside chaindata)",
+	}
+	LegacyExportFlag = cli.BoolFlag{
+		Name:  "legacyexport",
+		Usage: "Export blocks as a plain RLP stream instead of the versioned export format",
+	}
+	BroadcastIndexFlag = cli.BoolFlag{
+		Name:  "broadcastindex",
+		Usage: "Index the heartbeats, public keys and roll calls of every broadcast interval (requires --gcmode=archive to index past intervals)",
+	}
+	AddressIndexFlag = cli.BoolFlag{
+		Name:  "addressindex",
+		Usage: "Index the transactions by sender and recipient address, backfilling the past blocks in the background",
+	}
+	ForkAlertDepthFlag = cli.Uint64Flag{
+		Name:  "forkalert.depth",
+		Usage: "Raise a critical alert on reorgs dropping at least this many blocks (0 = disabled)",
+		Value: man.DefaultConfig.ForkAlertDepth,
+	}
+	ForkAlertWebhookFlag = cli.StringFlag{
+		Name:  "forkalert.webhook",
+		Usage: "URL the deep reorg alerts are posted to as JSON",
+	}
+	BridgeContractsFlag = cli.StringFlag{
+		Name:  "bridge.contracts",
+		Usage: "Comma separated MAN addresses of the bridge contracts whose events are attested (empty = bridge disabled)",
+	}
+	BridgeEventsFlag = cli.StringFlag{
+		Name:  "bridge.events",
+		Usage: "Semicolon separated signatures of the attested bridge events (default = Lock and Burn events)",
+	}
+	BridgeConfirmationsFlag = cli.Uint64Flag{
+		Name:  "bridge.confirmations",
+		Usage: "Number of confirmations a bridge event needs before it is attested",
+		Value: man.DefaultConfig.BridgeConfirmations,
+	}
 	DbTableSizeFlag = cli.IntFlag{
 		Name:  "dbsize",
 		Usage: "db store size ",
@@ -253,26 +326,26 @@ var (
 		Name:  "txpool.nolocals",
 		Usage: "Disables price exemptions for locally submitted transactions",
 	}
-	//TxPoolJournalFlag = cli.StringFlag{ //Y
-	//	Name:  "txpool.journal",
-	//	Usage: "Disk journal for local transaction to survive node restarts",
-	//	Value: core.DefaultTxPoolConfig.Journal,
-	//}
-	//TxPoolRejournalFlag = cli.DurationFlag{
-	//	Name:  "txpool.rejournal",
-	//	Usage: "Time interval to regenerate the local transaction journal",
-	//	Value: core.DefaultTxPoolConfig.Rejournal,
-	//}
+	TxPoolJournalFlag = cli.StringFlag{
+		Name:  "txpool.journal",
+		Usage: "Disk journal for local transaction to survive node restarts",
+		Value: core.DefaultTxPoolConfig.Journal,
+	}
+	TxPoolRejournalFlag = cli.DurationFlag{
+		Name:  "txpool.rejournal",
+		Usage: "Time interval to regenerate the local transaction journal",
+		Value: core.DefaultTxPoolConfig.Rejournal,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
 		Value: man.DefaultConfig.TxPool.PriceLimit,
 	}
-	//TxPoolPriceBumpFlag = cli.Uint64Flag{ //Y
-	//	Name:  "txpool.pricebump",
-	//	Usage: "Price bump percentage to replace an already existing transaction",
-	//	Value: eth.DefaultConfig.TxPool.PriceBump,
-	//}
+	TxPoolPriceBumpFlag = cli.Uint64Flag{
+		Name:  "txpool.pricebump",
+		Usage: "Price bump percentage to replace an already existing transaction",
+		Value: man.DefaultConfig.TxPool.PriceBump,
+	}
 	TxPoolAccountSlotsFlag = cli.Uint64Flag{
 		Name:  "txpool.accountslots",
 		Usage: "Minimum number of executable transaction slots guaranteed per account",
@@ -293,6 +366,30 @@ var (
 		Usage: "Maximum number of non-executable transaction slots for all accounts",
 		Value: man.DefaultConfig.TxPool.GlobalQueue,
 	}
+	TxPoolBroadcastAccountSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.broadcastaccountslots",
+		Usage: "Maximum number of broadcast transactions permitted per sender",
+		Value: man.DefaultConfig.TxPool.BroadcastAccountSlots,
+	}
+	TxPoolBroadcastGlobalSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.broadcastglobalslots",
+		Usage: "Maximum number of broadcast transactions held by the broadcast pool",
+		Value: man.DefaultConfig.TxPool.BroadcastGlobalSlots,
+	}
+	TxPoolScheduledAccountSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.scheduledaccountslots",
+		Usage: "Maximum number of immature scheduled transactions permitted per account",
+		Value: man.DefaultConfig.TxPool.ScheduledAccountSlots,
+	}
+	TxPoolScheduledGlobalSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.scheduledglobalslots",
+		Usage: "Maximum number of immature scheduled transactions for all accounts",
+		Value: man.DefaultConfig.TxPool.ScheduledGlobalSlots,
+	}
+	TxPoolBroadcastGossipFlag = cli.BoolFlag{
+		Name:  "txpool.broadcastgossip",
+		Usage: "Flood broadcast transactions through the broadcast nodes instead of sending them to each directly",
+	}
 	//TxPoolLifetimeFlag = cli.DurationFlag{ //Y
 	//	Name:  "txpool.lifetime",
 	//	Usage: "Maximum amount of time non-executable transaction are queued",
@@ -307,7 +404,12 @@ var (
 	CacheDatabaseFlag = cli.IntFlag{
 		Name:  "cache.database",
 		Usage: "Percentage of cache memory allowance to use for database io",
-		Value: 75,
+		Value: 50,
+	}
+	CacheTrieFlag = cli.IntFlag{
+		Name:  "cache.trie",
+		Usage: "Percentage of cache memory allowance to use for caching trie nodes read from disk",
+		Value: 25,
 	}
 	CacheGCFlag = cli.IntFlag{
 		Name:  "cache.gc",
@@ -319,6 +421,10 @@ var (
 		Usage: "Number of trie node generations to keep in memory",
 		Value: int(state.MaxTrieCacheGen),
 	}
+	ParallelExecFlag = cli.BoolFlag{
+		Name:  "parallel-exec",
+		Usage: "Execute conflict free transactions of a block concurrently",
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -375,6 +481,48 @@ var (
 		Usage: "Password file to use for non-interactive password input",
 		Value: "",
 	}
+	MnemonicFlag = cli.BoolFlag{
+		Name:  "mnemonic",
+		Usage: "Derive the new account from a newly generated mnemonic seed phrase",
+	}
+	HDCoinTypeFlag = cli.Uint64Flag{
+		Name:  "hd.cointype",
+		Usage: "BIP-44 coin type of the accounts derived from mnemonics",
+		Value: uint64(hdwallet.DefaultCoinType),
+	}
+	HDIndexFlag = cli.Uint64Flag{
+		Name:  "hd.index",
+		Usage: "Index of the account derived from the mnemonic",
+	}
+	HDSigningFlag = cli.BoolFlag{
+		Name:  "hd.signing",
+		Usage: "Derive the validator signing account instead of the wallet account",
+	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer endpoint (IPC path or URL) taking over all the signatures",
+		Value: "",
+	}
+	BLSKeyFileFlag = cli.StringFlag{
+		Name:  "bls.keyfile",
+		Usage: "File holding the hex encoded BLS secret key the block votes are signed with",
+		Value: "",
+	}
+	HSMModuleFlag = cli.StringFlag{
+		Name:  "hsm.module",
+		Usage: "PKCS#11 module of the hardware token the key files are bound to",
+		Value: "",
+	}
+	HSMLabelFlag = cli.StringFlag{
+		Name:  "hsm.label",
+		Usage: "Label of the HMAC key on the hardware token",
+		Value: "",
+	}
+	HSMPinFileFlag = cli.StringFlag{
+		Name:  "hsm.pinfile",
+		Usage: "File holding the user PIN of the hardware token",
+		Value: "",
+	}
 	ManAddressFlag = cli.StringFlag{
 		Name:  "manAddress",
 		Usage: "deposit user signature account.",
@@ -403,6 +551,10 @@ var (
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsAddrFlag = cli.StringFlag{
+		Name:  "metrics.addr",
+		Usage: "Serve the collected metrics in Prometheus format on this HTTP address (requires --metrics)",
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -441,6 +593,59 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpcbatchlimit",
+		Usage: "Maximum number of requests in a HTTP-RPC batch (0 = unlimited)",
+		Value: rpc.DefaultHTTPConfig.BatchItemLimit,
+	}
+	RPCBatchResponseSizeFlag = cli.IntFlag{
+		Name:  "rpcbatchsize",
+		Usage: "Maximum size in bytes of the responses of a HTTP-RPC batch (0 = unlimited)",
+		Value: rpc.DefaultHTTPConfig.BatchResponseLimit,
+	}
+	RPCTLSCertFlag = cli.StringFlag{
+		Name:  "rpctlscert",
+		Usage: "TLS certificate file of the HTTP-RPC server, enables HTTPS and HTTP/2",
+		Value: "",
+	}
+	RPCTLSKeyFlag = cli.StringFlag{
+		Name:  "rpctlskey",
+		Usage: "TLS private key file of the HTTP-RPC server",
+		Value: "",
+	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpcjwtsecret",
+		Usage: "File holding the hex encoded JWT secret of the authenticated HTTP-RPC and WS-RPC callers (generated if missing)",
+		Value: "",
+	}
+	RPCAllowFlag = cli.StringFlag{
+		Name:  "rpcallow",
+		Usage: "Comma separated list of namespaces (man) and methods (man_getBalance) callable over HTTP-RPC without authentication",
+		Value: "",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpcratelimit",
+		Usage: "Request cost units per second granted to each client IP of the HTTP-RPC and WS-RPC servers (0 = unlimited)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpcrateburst",
+		Usage: "Maximum request cost units accumulated by a client IP",
+		Value: 100,
+	}
+	RPCTokenRateLimitFlag = cli.Float64Flag{
+		Name:  "rpctokenratelimit",
+		Usage: "Request cost units per second granted to each JWT subject of the authenticated callers (0 = unlimited)",
+	}
+	RPCTokenRateBurstFlag = cli.IntFlag{
+		Name:  "rpctokenrateburst",
+		Usage: "Maximum request cost units accumulated by a JWT subject",
+		Value: 1000,
+	}
+	RPCMethodCostsFlag = cli.StringFlag{
+		Name:  "rpcmethodcosts",
+		Usage: "Comma separated list of method=cost overriding the request cost units of the methods (e.g. man_getLogs=10)",
+		Value: "",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -473,6 +678,59 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	WSAllowFlag = cli.StringFlag{
+		Name:  "wsallow",
+		Usage: "Comma separated list of namespaces (man) and methods (man_getBalance) callable over WS-RPC without authentication",
+		Value: "",
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL query server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: pod.DefaultHTTPHost,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: 8547,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "",
+	}
+	GraphQLVirtualHostsFlag = cli.StringFlag{
+		Name:  "graphql.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(pod.DefaultConfig.HTTPVirtualHosts, ","),
+	}
+	HealthEnabledFlag = cli.BoolFlag{
+		Name:  "health",
+		Usage: "Enable the /health and /ready HTTP probes",
+	}
+	HealthListenAddrFlag = cli.StringFlag{
+		Name:  "health.addr",
+		Usage: "Health probes listening interface",
+		Value: pod.DefaultHTTPHost,
+	}
+	HealthPortFlag = cli.IntFlag{
+		Name:  "health.port",
+		Usage: "Health probes listening port",
+		Value: 8548,
+	}
+	HealthMinPeersFlag = cli.IntFlag{
+		Name:  "health.minpeers",
+		Usage: "Minimum number of peers of a ready node",
+		Value: health.DefaultConfig.MinPeers,
+	}
+	HealthMaxBlockAgeFlag = cli.DurationFlag{
+		Name:  "health.maxage",
+		Usage: "Maximum age of the head block of a ready node (0 = disabled)",
+		Value: health.DefaultConfig.MaxBlockAge,
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -526,6 +784,16 @@ var (
 		Usage: "NAT port mapping mechanism (any|none|upnp|pmp|extip:<IP>)",
 		Value: "any",
 	}
+	STUNFlag = cli.StringFlag{
+		Name:  "nat.stun",
+		Usage: "Comma separated STUN servers asked for the external IP when the NAT device can't tell it (empty to disable)",
+		Value: "stun.l.google.com:19302,stun1.l.google.com:19302",
+	}
+	P2PTransportFlag = cli.StringFlag{
+		Name:  "p2p.transport",
+		Usage: "Transport carrying the peer connections (tcp|quic), QUIC listens on the UDP port after the listening port",
+		Value: p2p.TransportTCP,
+	}
 	NoDiscoverFlag = cli.BoolFlag{
 		Name:  "nodiscover",
 		Usage: "Disables the peer discovery mechanism (manual peer addition)",
@@ -727,6 +995,9 @@ func setNAT(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NAT = natif
 	}
+	if servers := ctx.GlobalString(STUNFlag.Name); servers != "" {
+		cfg.STUNServers = splitAndTrim(servers)
+	}
 }
 
 // splitAndTrim splits input separated by a comma
@@ -761,6 +1032,27 @@ func setHTTP(ctx *cli.Context, cfg *pod.Config) {
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
+	// The batch limits and TLS settings are picked up by the HTTP endpoint
+	// started by the node
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		rpc.DefaultHTTPConfig.BatchItemLimit = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchResponseSizeFlag.Name) {
+		rpc.DefaultHTTPConfig.BatchResponseLimit = ctx.GlobalInt(RPCBatchResponseSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSCertFlag.Name) {
+		rpc.DefaultHTTPConfig.TLSCertFile = ctx.GlobalString(RPCTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSKeyFlag.Name) {
+		rpc.DefaultHTTPConfig.TLSKeyFile = ctx.GlobalString(RPCTLSKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		rpc.DefaultHTTPConfig.Access.JWTSecret = obtainJWTSecret(ctx.GlobalString(RPCJWTSecretFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCAllowFlag.Name) {
+		rpc.DefaultHTTPConfig.Access.Allow = splitAndTrim(ctx.GlobalString(RPCAllowFlag.Name))
+	}
+	setRPCRateLimits(ctx, &rpc.DefaultHTTPConfig.RateLimits)
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -782,6 +1074,62 @@ func setWS(ctx *cli.Context, cfg *pod.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		rpc.DefaultWSConfig.Access.JWTSecret = obtainJWTSecret(ctx.GlobalString(RPCJWTSecretFlag.Name))
+	}
+	if ctx.GlobalIsSet(WSAllowFlag.Name) {
+		rpc.DefaultWSConfig.Access.Allow = splitAndTrim(ctx.GlobalString(WSAllowFlag.Name))
+	}
+	setRPCRateLimits(ctx, &rpc.DefaultWSConfig.RateLimits)
+}
+
+// setRPCRateLimits applies the rate limit flags to the config of an RPC endpoint.
+func setRPCRateLimits(ctx *cli.Context, cfg *rpc.RateLimitConfig) {
+	cfg.IPRate = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	cfg.IPBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	cfg.TokenRate = ctx.GlobalFloat64(RPCTokenRateLimitFlag.Name)
+	cfg.TokenBurst = ctx.GlobalInt(RPCTokenRateBurstFlag.Name)
+
+	cfg.Costs = make(map[string]int)
+	for method, cost := range rpc.DefaultMethodCosts {
+		cfg.Costs[method] = cost
+	}
+	if ctx.GlobalIsSet(RPCMethodCostsFlag.Name) {
+		for _, entry := range splitAndTrim(ctx.GlobalString(RPCMethodCostsFlag.Name)) {
+			parts := strings.Split(entry, "=")
+			if len(parts) != 2 {
+				Fatalf("Invalid method cost %q, expected method=cost", entry)
+			}
+			cost, err := strconv.Atoi(parts[1])
+			if err != nil || cost < 0 {
+				Fatalf("Invalid method cost %q, expected method=cost", entry)
+			}
+			cfg.Costs[parts[0]] = cost
+		}
+	}
+}
+
+// obtainJWTSecret loads the hex encoded JWT secret of the RPC endpoints from a
+// file, generating a new random secret if the file does not exist.
+func obtainJWTSecret(path string) []byte {
+	if data, err := ioutil.ReadFile(path); err == nil {
+		secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+		if err != nil || len(secret) != 32 {
+			Fatalf("Invalid JWT secret in %s, expected 32 hex encoded bytes", path)
+		}
+		return secret
+	} else if !os.IsNotExist(err) {
+		Fatalf("Failed to read JWT secret: %v", err)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		Fatalf("Failed to generate JWT secret: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		Fatalf("Failed to write JWT secret: %v", err)
+	}
+	log.Info("Generated JWT secret", "path", path)
+	return secret
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -876,6 +1224,16 @@ type EntrustPassword struct {
 	Password map[common.Address]string
 }
 
+// MakeHDPath returns the derivation path of the account derived from a mnemonic
+// selected by the HD wallet flags.
+func MakeHDPath(ctx *cli.Context) accounts.DerivationPath {
+	coinType, index := uint32(ctx.GlobalUint64(HDCoinTypeFlag.Name)), uint32(ctx.GlobalUint64(HDIndexFlag.Name))
+	if ctx.GlobalBool(HDSigningFlag.Name) {
+		return hdwallet.SigningPath(coinType, index)
+	}
+	return hdwallet.AccountPath(coinType, index)
+}
+
 // MakePasswordList reads password lines from the file specified by the global --password flag.
 func MakePasswordList(ctx *cli.Context) []string {
 	path := ctx.GlobalString(PasswordFileFlag.Name)
@@ -894,6 +1252,28 @@ func MakePasswordList(ctx *cli.Context) []string {
 	return lines
 }
 
+// SetHardwareSecret binds the keystore to the PKCS#11 token selected by the
+// --hsm flags, if any.
+func SetHardwareSecret(ctx *cli.Context) {
+	module := ctx.GlobalString(HSMModuleFlag.Name)
+	if module == "" {
+		return
+	}
+	var pin string
+	if path := ctx.GlobalString(HSMPinFileFlag.Name); path != "" {
+		text, err := ioutil.ReadFile(path)
+		if err != nil {
+			Fatalf("Failed to read PIN file: %v", err)
+		}
+		pin = strings.TrimRight(string(text), "\r\n")
+	}
+	secret, err := keystore.NewPKCS11Secret(module, ctx.GlobalString(HSMLabelFlag.Name), pin)
+	if err != nil {
+		Fatalf("Failed to open hardware token: %v", err)
+	}
+	keystore.SetHardwareSecret(secret)
+}
+
 // GetSignPassword returns password specified by the global --testmode flag.
 func GetSignPassword(ctx *cli.Context) ([]string, error) {
 	var password string
@@ -1027,6 +1407,9 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetWorkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
+	if ctx.GlobalIsSet(P2PTransportFlag.Name) {
+		cfg.Transport = ctx.GlobalString(P2PTransportFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || lightClient {
 		cfg.NoDiscovery = true
 	}
@@ -1082,6 +1465,10 @@ func SetNodeConfig(ctx *cli.Context, cfg *pod.Config) {
 	case ctx.GlobalBool(RinkebyFlag.Name):
 		cfg.DataDir = filepath.Join(pod.DefaultDataDir(), "rinkeby")
 	}
+	if cfg.DataDir != "" {
+		cfg.P2P.StaticNodesFile = cfg.ResolvePath("static-nodes.json")
+		cfg.P2P.TrustedNodesFile = cfg.ResolvePath("trusted-nodes.json")
+	}
 
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
@@ -1092,6 +1479,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *pod.Config) {
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
+	if ctx.GlobalBool(DeveloperFlag.Name) {
+		setDeveloperAccount(cfg)
+	}
 	if ctx.GlobalIsSet(LessDiskEnabledFlag.Name) {
 		cfg.LessDisk = ctx.GlobalBool(LessDiskEnabledFlag.Name)
 	} else {
@@ -1121,21 +1511,21 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
-	//if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) { //Y
-	//	cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
-	//}
-	//if ctx.GlobalIsSet(TxPoolJournalFlag.Name) {
-	//	cfg.Journal = ctx.GlobalString(TxPoolJournalFlag.Name)
-	//}
-	//if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
-	//	cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
-	//}
+	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
+		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolJournalFlag.Name) {
+		cfg.Journal = ctx.GlobalString(TxPoolJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
+		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
-	//if ctx.GlobalIsSet(TxPoolPriceBumpFlag.Name) {//Y
-	//	cfg.PriceBump = ctx.GlobalUint64(TxPoolPriceBumpFlag.Name)
-	//}
+	if ctx.GlobalIsSet(TxPoolPriceBumpFlag.Name) {
+		cfg.PriceBump = ctx.GlobalUint64(TxPoolPriceBumpFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolAccountSlotsFlag.Name) {
 		cfg.AccountSlots = ctx.GlobalUint64(TxPoolAccountSlotsFlag.Name)
 	}
@@ -1148,6 +1538,21 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolGlobalQueueFlag.Name) {
 		cfg.GlobalQueue = ctx.GlobalUint64(TxPoolGlobalQueueFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolBroadcastAccountSlotsFlag.Name) {
+		cfg.BroadcastAccountSlots = ctx.GlobalUint64(TxPoolBroadcastAccountSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolBroadcastGlobalSlotsFlag.Name) {
+		cfg.BroadcastGlobalSlots = ctx.GlobalUint64(TxPoolBroadcastGlobalSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolScheduledAccountSlotsFlag.Name) {
+		cfg.ScheduledAccountSlots = ctx.GlobalUint64(TxPoolScheduledAccountSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolScheduledGlobalSlotsFlag.Name) {
+		cfg.ScheduledGlobalSlots = ctx.GlobalUint64(TxPoolScheduledGlobalSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolBroadcastGossipFlag.Name) {
+		cfg.BroadcastGossip = ctx.GlobalBool(TxPoolBroadcastGossipFlag.Name)
+	}
 	//if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {//Y
 	//	cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	//}
@@ -1256,7 +1661,57 @@ func SetManConfig(ctx *cli.Context, stack *pod.Node, cfg *man.Config) {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	if ctx.GlobalIsSet(StateRetentionFlag.Name) {
+		cfg.StateRetention = ctx.GlobalUint64(StateRetentionFlag.Name)
+	}
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		cfg.DatabaseEngine = ctx.GlobalString(DBEngineFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientThresholdFlag.Name) {
+		cfg.AncientThreshold = ctx.GlobalUint64(AncientThresholdFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseAncient = ctx.GlobalString(AncientFlag.Name)
+	}
+	if ctx.GlobalIsSet(BroadcastIndexFlag.Name) {
+		cfg.BroadcastIndex = ctx.GlobalBool(BroadcastIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(AddressIndexFlag.Name) {
+		cfg.AddressIndex = ctx.GlobalBool(AddressIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(ForkAlertDepthFlag.Name) {
+		cfg.ForkAlertDepth = ctx.GlobalUint64(ForkAlertDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(ForkAlertWebhookFlag.Name) {
+		cfg.ForkAlertWebhook = ctx.GlobalString(ForkAlertWebhookFlag.Name)
+	}
+	if ctx.GlobalIsSet(BridgeContractsFlag.Name) {
+		for _, manAddr := range strings.Split(ctx.GlobalString(BridgeContractsFlag.Name), ",") {
+			addr, err := base58.Base58DecodeToAddress(strings.TrimSpace(manAddr))
+			if err != nil {
+				Fatalf("Invalid bridge contract %q: %v", manAddr, err)
+			}
+			cfg.BridgeContracts = append(cfg.BridgeContracts, addr)
+		}
+	}
+	if ctx.GlobalIsSet(BridgeEventsFlag.Name) {
+		for _, event := range strings.Split(ctx.GlobalString(BridgeEventsFlag.Name), ";") {
+			cfg.BridgeEvents = append(cfg.BridgeEvents, strings.TrimSpace(event))
+		}
+	}
+	if ctx.GlobalIsSet(BridgeConfirmationsFlag.Name) {
+		cfg.BridgeConfirmations = ctx.GlobalUint64(BridgeConfirmationsFlag.Name)
+	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
+	if ctx.GlobalIsSet(BLSKeyFileFlag.Name) {
+		cfg.BLSKeyFile = ctx.GlobalString(BLSKeyFileFlag.Name)
+	}
 
+	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
+		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
@@ -1276,6 +1731,9 @@ func SetManConfig(ctx *cli.Context, stack *pod.Node, cfg *man.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(ParallelExecFlag.Name) {
+		cfg.ParallelExec = ctx.GlobalBool(ParallelExecFlag.Name)
+	}
 
 	// Override any default configs for hard coded networks.
 	/*switch {
@@ -1289,33 +1747,88 @@ func SetManConfig(ctx *cli.Context, stack *pod.Node, cfg *man.Config) {
 			cfg.NetworkId = 4
 		}
 		cfg.Genesis = core.DefaultRinkebyGenesisBlock()
-	case ctx.GlobalBool(DeveloperFlag.Name):
-		// Create new developer account or reuse existing one
-		var (
-			developer accounts.Account
-			err       error
-		)
-		if accs := ks.Accounts(); len(accs) > 0 {
-			developer = ks.Accounts()[0]
-		} else {
-			developer, err = ks.NewAccount("")
+	}*/
+	if ctx.GlobalBool(DeveloperFlag.Name) {
+		setDeveloperGenesis(ctx, ks, cfg)
+	}
+	// TODO(fjl): move trie cache generations into config
+	if gen := ctx.GlobalInt(TrieCacheGenFlag.Name); gen > 0 {
+		state.MaxTrieCacheGen = uint16(gen)
+	}
+}
+
+// setDeveloperAccount creates the developer account or reuses the existing one,
+// and runs the node as the developer. The account has to be known before the
+// node is created, as the node identifies itself by its account.
+func setDeveloperAccount(cfg *pod.Config) {
+	scryptN, scryptP, keydir, err := cfg.AccountConfig()
+	if err != nil {
+		Fatalf("Failed to read the keystore configuration: %v", err)
+	}
+	if keydir == "" {
+		// Ephemeral node, keep the developer account for the node to find it
+		if keydir, err = ioutil.TempDir("", "gman-dev-keystore"); err != nil {
+			Fatalf("Failed to create the developer keystore: %v", err)
+		}
+		cfg.KeyStoreDir = keydir
+	}
+	ks := keystore.NewKeyStore(keydir, scryptN, scryptP)
+
+	var developer accounts.Account
+	if accs := ks.Accounts(); len(accs) > 0 {
+		developer = accs[0]
+	} else if developer, err = ks.NewAccount(""); err != nil {
+		Fatalf("Failed to create developer account: %v", err)
+	}
+	cfg.P2P.ManAddress = developer.Address
+}
+
+// setDeveloperGenesis sets up the single-node chain of the developer mode: a
+// genesis giving all the roles to the developer account, signed by it, and the
+// local sealing of the blocks.
+func setDeveloperGenesis(ctx *cli.Context, ks *keystore.KeyStore, cfg *man.Config) {
+	accs := ks.Accounts()
+	if len(accs) == 0 {
+		Fatalf("No developer account")
+	}
+	developer := accs[0]
+	if err := ks.Unlock(developer, ""); err != nil {
+		Fatalf("Failed to unlock developer account: %v", err)
+	}
+	if err := entrust.EntrustAccountValue.SetEntrustValue(map[common.Address]string{developer.Address: ""}); err != nil {
+		Fatalf("Failed to entrust developer account: %v", err)
+	}
+	log.Info("Using developer account", "address", base58.Base58EncodeToString(params.MAN_COIN, developer.Address))
+
+	var prefund []common.Address
+	if ctx.GlobalIsSet(DeveloperPrefundFlag.Name) {
+		for _, manAddr := range strings.Split(ctx.GlobalString(DeveloperPrefundFlag.Name), ",") {
+			addr, err := base58.Base58DecodeToAddress(strings.TrimSpace(manAddr))
 			if err != nil {
-				Fatalf("Failed to create developer account: %v", err)
+				Fatalf("Invalid pre-funded account %q: %v", manAddr, err)
 			}
+			prefund = append(prefund, addr)
 		}
-		if err := ks.Unlock(developer, ""); err != nil {
-			Fatalf("Failed to unlock developer account: %v", err)
+	}
+	genesis := core.DeveloperGenesisBlock(ctx.GlobalUint64(DeveloperIntervalFlag.Name), developer.Address, prefund)
+	if errs := genesis.Validate(); len(errs) != 0 {
+		for _, err := range errs {
+			log.Error("Invalid developer genesis", "err", err)
 		}
-		log.Info("Using developer account", "address", developer.Address)
+		Fatalf("Invalid developer genesis, check the --%s flag", DeveloperIntervalFlag.Name)
+	}
+	sign, err := ks.SignHashVersionWithPass(developer, "", common.BytesToHash([]byte(genesis.Version)).Bytes())
+	if err != nil {
+		Fatalf("Failed to sign developer genesis version: %v", err)
+	}
+	genesis.VersionSignatures = append(genesis.VersionSignatures, common.BytesToSignature(sign))
+	cfg.Genesis = genesis
 
-		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
-		if !ctx.GlobalIsSet(GasPriceFlag.Name) {
-			cfg.GasPrice = big.NewInt(1)
-		}
-	}*/
-	// TODO(fjl): move trie cache generations into config
-	if gen := ctx.GlobalInt(TrieCacheGenFlag.Name); gen > 0 {
-		state.MaxTrieCacheGen = uint16(gen)
+	cfg.DevMode = true
+	cfg.DevPeriod = time.Duration(ctx.GlobalInt(DeveloperPeriodFlag.Name)) * time.Second
+	cfg.Manash.PowMode = manash.ModeFake
+	if !ctx.GlobalIsSet(GasPriceFlag.Name) {
+		cfg.GasPrice = big.NewInt(1)
 	}
 }
 
@@ -1338,6 +1851,46 @@ func RegisterManService(stack *pod.Node, cfg *man.Config) {
 	}
 }
 
+// RegisterGraphQLService adds the GraphQL query server configured by the flags
+// to the stack.
+func RegisterGraphQLService(ctx *cli.Context, stack *pod.Node) {
+	var (
+		endpoint = fmt.Sprintf("%s:%d", ctx.GlobalString(GraphQLListenAddrFlag.Name), ctx.GlobalInt(GraphQLPortFlag.Name))
+		cors     = splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+		vhosts   = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
+	)
+	if err := stack.Register(func(ctx *pod.ServiceContext) (pod.Service, error) {
+		var manServ *man.Matrix
+		if err := ctx.Service(&manServ); err != nil {
+			return nil, err
+		}
+		return graphql.New(manServ, endpoint, cors, vhosts)
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
+// RegisterHealthService adds the health and readiness probes configured by the
+// flags to the stack.
+func RegisterHealthService(ctx *cli.Context, stack *pod.Node) {
+	var (
+		endpoint = fmt.Sprintf("%s:%d", ctx.GlobalString(HealthListenAddrFlag.Name), ctx.GlobalInt(HealthPortFlag.Name))
+		config   = health.Config{
+			MinPeers:    ctx.GlobalInt(HealthMinPeersFlag.Name),
+			MaxBlockAge: ctx.GlobalDuration(HealthMaxBlockAgeFlag.Name),
+		}
+	)
+	if err := stack.Register(func(ctx *pod.ServiceContext) (pod.Service, error) {
+		var manServ *man.Matrix
+		if err := ctx.Service(&manServ); err != nil {
+			return nil, err
+		}
+		return health.New(manServ, endpoint, config)
+	}); err != nil {
+		Fatalf("Failed to register the health service: %v", err)
+	}
+}
+
 // RegisterDashboardService adds a dashboard to the stack.
 func RegisterDashboardService(stack *pod.Node, cfg *dashboard.Config, commit string) {
 	stack.Register(func(ctx *pod.ServiceContext) (pod.Service, error) {
@@ -1365,15 +1918,46 @@ func SetupNetwork(ctx *cli.Context) {
 	params.TargetGasLimit = ctx.GlobalUint64(TargetGasLimitFlag.Name)
 }
 
+// SetupMetrics starts the Prometheus metrics server if requested.
+func SetupMetrics(ctx *cli.Context) {
+	addr := ctx.GlobalString(MetricsAddrFlag.Name)
+	if addr == "" {
+		return
+	}
+	if !metrics.Enabled {
+		log.Warn("Metrics collection disabled, serving empty metrics", "flag", MetricsEnabledFlag.Name)
+	}
+	prometheus.StartServer(addr)
+}
+
 // MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *pod.Node) mandb.Database {
+	name := "chaindata"
+	if ctx.GlobalBool(LightModeFlag.Name) {
+		name = "lightchaindata"
+	}
+	return MakeDatabase(ctx, stack, name)
+}
+
+// MakeDatabase opens the named database of the node with the database engine
+// passed to the client and will hard crash if it fails.
+func MakeDatabase(ctx *cli.Context, stack *pod.Node, name string) mandb.Database {
 	var (
 		cache   = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 		handles = makeDatabaseHandles()
 	)
-	name := "chaindata"
-	if ctx.GlobalBool(LightModeFlag.Name) {
-		name = "lightchaindata"
+	if path := stack.ResolvePath(name); path != "" {
+		engine, err := mandb.ResolveEngine(ctx.GlobalString(DBEngineFlag.Name), path)
+		if err != nil {
+			Fatalf("Could not open database: %v", err)
+		}
+		if engine != mandb.EngineLevelDB {
+			chainDb, err := mandb.Open(engine, path, cache, handles, ctx.GlobalInt(DbTableSizeFlag.Name))
+			if err != nil {
+				Fatalf("Could not open database: %v", err)
+			}
+			return chainDb
+		}
 	}
 	chainDb, err := stack.OpenDatabase(name, cache, handles)
 	if err != nil {
@@ -1411,14 +1995,28 @@ func MakeChain(ctx *cli.Context, stack *pod.Node) (chain *core.BlockChain, chain
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
 	cache := &core.CacheConfig{
-		Disabled:      ctx.GlobalString(GCModeFlag.Name) == "archive",
-		TrieNodeLimit: man.DefaultConfig.TrieCache,
-		TrieTimeLimit: man.DefaultConfig.TrieTimeout,
+		Disabled:       ctx.GlobalString(GCModeFlag.Name) == "archive",
+		TrieCleanLimit: man.DefaultConfig.TrieCleanCache,
+		TrieNodeLimit:  man.DefaultConfig.TrieCache,
+		TrieTimeLimit:  man.DefaultConfig.TrieTimeout,
+	}
+	if ctx.GlobalIsSet(StateRetentionFlag.Name) {
+		cache.StateRetention = ctx.GlobalUint64(StateRetentionFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientThresholdFlag.Name) {
+		cache.AncientThreshold = ctx.GlobalUint64(AncientThresholdFlag.Name)
+		cache.AncientDir = stack.ResolvePath("chaindata/ancient")
+		if ctx.GlobalIsSet(AncientFlag.Name) {
+			cache.AncientDir = ctx.GlobalString(AncientFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
+		cache.TrieCleanLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
-	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
+	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name), ParallelExec: ctx.GlobalBool(ParallelExecFlag.Name)}
 
 	chain, err = core.NewBlockChain(chainDb, cache, config, vmcfg, engine, dposEngine)
 	if err != nil {
@@ -0,0 +1,64 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package trie
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+// NodeReader retrieves the data blob of a trie node by its hash.
+type NodeReader func(hash common.Hash) ([]byte, error)
+
+// Chunk collects the data blobs of the nodes below root in breadth first order,
+// starting with the root itself, until maxNodes nodes or maxBytes bytes are
+// gathered. Every node in the chunk is referenced by a node preceding it, so a
+// TrieSync scheduled for root can process the chunk one node after another,
+// verifying each against the hash its parent committed to.
+//
+// A root which is not a trie node (e.g. contract code) is returned on its own.
+// Nodes missing from the reader are skipped along with their subtries.
+func Chunk(root common.Hash, read NodeReader, maxNodes int, maxBytes int) [][]byte {
+	var (
+		chunk [][]byte
+		size  int
+		queue = []common.Hash{root}
+	)
+	for len(queue) > 0 && len(chunk) < maxNodes && size < maxBytes {
+		hash := queue[0]
+		queue = queue[1:]
+
+		blob, err := read(hash)
+		if err != nil || len(blob) == 0 {
+			continue
+		}
+		chunk = append(chunk, blob)
+		size += len(blob)
+
+		n, err := decodeNode(hash[:], blob, 0)
+		if err != nil {
+			continue
+		}
+		queue = append(queue, childHashes(n)...)
+	}
+	return chunk
+}
+
+// childHashes returns the hashes of the nodes referenced by a decoded node.
+func childHashes(n node) []common.Hash {
+	var children []node
+	switch n := n.(type) {
+	case *shortNode:
+		children = []node{n.Val}
+	case *fullNode:
+		children = n.Children[:]
+	}
+	hashes := make([]common.Hash, 0, len(children))
+	for _, child := range children {
+		if hash, ok := child.(hashNode); ok {
+			hashes = append(hashes, common.BytesToHash(hash))
+		}
+	}
+	return hashes
+}
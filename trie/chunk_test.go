@@ -0,0 +1,60 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+)
+
+// Tests that a trie can be reconstructed from chunks, processing the nodes of
+// every chunk one after another, and that the scheduler only reports the nodes
+// not yet delivered as missing.
+func TestChunkedTrieSync(t *testing.T) {
+	srcDb, srcTrie, srcData := makeTestTrie()
+
+	diskdb := mandb.NewMemDatabase()
+	triedb := NewDatabase(diskdb)
+	sched := NewTrieSync(srcTrie.Hash(), diskdb, nil)
+
+	requests := 0
+	for queue := sched.Missing(4); len(queue) > 0; queue = sched.Missing(4) {
+		for _, hash := range queue {
+			requests++
+			chunk := Chunk(hash, srcDb.Node, 64, 1024*1024)
+			if len(chunk) == 0 || crypto.Keccak256Hash(chunk[0]) != hash {
+				t.Fatalf("chunk of %x does not start with the requested node", hash)
+			}
+			for i, blob := range chunk {
+				res := SyncResult{Hash: crypto.Keccak256Hash(blob), Data: blob}
+				if _, _, err := sched.Process([]SyncResult{res}); err != nil && err != ErrAlreadyProcessed {
+					t.Fatalf("failed to process node #%d of chunk %x: %v", i, hash, err)
+				}
+			}
+		}
+		if index, err := sched.Commit(diskdb); err != nil {
+			t.Fatalf("failed to commit data #%d: %v", index, err)
+		}
+	}
+	checkTrieContents(t, triedb, srcTrie.Root(), srcData)
+
+	if nodes := len(srcDb.Nodes()); requests >= nodes {
+		t.Errorf("chunked sync not batched: %d requests for %d nodes", requests, nodes)
+	}
+}
+
+// Tests that a chunk stops at the requested limits.
+func TestChunkLimits(t *testing.T) {
+	srcDb, srcTrie, _ := makeTestTrie()
+
+	if chunk := Chunk(srcTrie.Hash(), srcDb.Node, 10, 1024*1024); len(chunk) != 10 {
+		t.Errorf("node limit not honoured: have %d nodes, want 10", len(chunk))
+	}
+	if chunk := Chunk(srcTrie.Hash(), srcDb.Node, 1000, 1); len(chunk) != 1 {
+		t.Errorf("byte limit not honoured: have %d nodes, want 1", len(chunk))
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package trie
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+// cleanCache is a least recently used cache of clean trie nodes, i.e. nodes that
+// are already persisted to disk, bounded by the total size of the cached data.
+type cleanCache struct {
+	limit common.StorageSize // Maximum storage size of the cached nodes
+	size  common.StorageSize // Current storage size of the cached nodes
+
+	items map[common.Hash]*list.Element // Cached nodes, indexed by hash
+	order *list.List                    // Recency order of the nodes, most recent first
+
+	lock sync.Mutex
+}
+
+// cleanEntry is a single node in the clean cache.
+type cleanEntry struct {
+	hash common.Hash
+	blob []byte
+}
+
+// newCleanCache creates a clean node cache holding at most limit bytes.
+func newCleanCache(limit common.StorageSize) *cleanCache {
+	return &cleanCache{
+		limit: limit,
+		items: make(map[common.Hash]*list.Element),
+		order: list.New(),
+	}
+}
+
+// get retrieves a node from the cache, marking it as recently used.
+func (c *cleanCache) get(hash common.Hash) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.items[hash]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cleanEntry).blob, true
+}
+
+// set inserts a node into the cache, evicting the least recently used nodes if
+// the size limit is exceeded. Nodes larger than the whole cache are not cached.
+func (c *cleanCache) set(hash common.Hash, blob []byte) {
+	size := common.StorageSize(common.HashLength + len(blob))
+	if size > c.limit {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.items[hash]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[hash] = c.order.PushFront(&cleanEntry{hash: hash, blob: blob})
+	c.size += size
+
+	for c.size > c.limit {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*cleanEntry)
+
+		c.order.Remove(oldest)
+		delete(c.items, entry.hash)
+		c.size -= common.StorageSize(common.HashLength + len(entry.blob))
+	}
+}
+
+// len returns the number of cached nodes.
+func (c *cleanCache) len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.items)
+}
@@ -11,9 +11,19 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
 	"github.com/MatrixAINetwork/go-matrix/rlp"
 )
 
+var (
+	memcacheCleanHitCounter  = metrics.NewRegisteredCounter("trie/memcache/clean/hit", nil)
+	memcacheCleanMissCounter = metrics.NewRegisteredCounter("trie/memcache/clean/miss", nil)
+
+	memcacheFlushTimeTimer  = metrics.NewRegisteredTimer("trie/memcache/flush/time", nil)
+	memcacheFlushNodesMeter = metrics.NewRegisteredMeter("trie/memcache/flush/nodes", nil)
+	memcacheFlushSizeMeter  = metrics.NewRegisteredMeter("trie/memcache/flush/size", nil)
+)
+
 // secureKeyPrefix is the database key prefix used to store trie node preimages.
 var secureKeyPrefix = []byte("secure-key-")
 
@@ -35,7 +45,10 @@ type DatabaseReader interface {
 type Database struct {
 	diskdb mandb.Database // Persistent storage for matured trie nodes
 
+	cleans    *cleanCache                 // Cache of clean nodes read from or flushed to disk, nil if disabled
 	nodes     map[common.Hash]*cachedNode // Data and references relationships of a node
+	oldest    common.Hash                 // Oldest tracked node, flush-list head
+	newest    common.Hash                 // Newest tracked node, flush-list tail
 	preimages map[common.Hash][]byte      // Preimages of nodes from the secure trie
 	seckeybuf [secureKeyLength]byte       // Ephemeral buffer for calculating preimage keys
 
@@ -43,6 +56,10 @@ type Database struct {
 	gcnodes uint64             // Nodes garbage collected since last commit
 	gcsize  common.StorageSize // Data storage garbage collected since last commit
 
+	flushtime  time.Duration      // Time spent on data flushing since last commit
+	flushnodes uint64             // Nodes flushed since last commit
+	flushsize  common.StorageSize // Data storage flushed since last commit
+
 	nodesSize     common.StorageSize // Storage size of the nodes cache
 	preimagesSize common.StorageSize // Storage size of the preimages cache
 
@@ -55,13 +72,29 @@ type cachedNode struct {
 	blob     []byte              // Cached data block of the trie node
 	parents  int                 // Number of live nodes referencing this one
 	children map[common.Hash]int // Children referenced by this nodes
+
+	flushPrev common.Hash // Previous node in the flush-list
+	flushNext common.Hash // Next node in the flush-list
 }
 
 // NewDatabase creates a new trie database to store ephemeral trie content before
-// its written out to disk or garbage collected.
+// its written out to disk or garbage collected. No read cache is created, so all
+// data retrievals will hit the underlying disk database.
 func NewDatabase(diskdb mandb.Database) *Database {
+	return NewDatabaseWithCache(diskdb, 0)
+}
+
+// NewDatabaseWithCache creates a new trie database to store ephemeral trie content
+// before its written out to disk or garbage collected. It also acts as a read cache
+// for nodes loaded from disk, holding at most cache megabytes of them.
+func NewDatabaseWithCache(diskdb mandb.Database, cache int) *Database {
+	var cleans *cleanCache
+	if cache > 0 {
+		cleans = newCleanCache(common.StorageSize(cache) * 1024 * 1024)
+	}
 	return &Database{
 		diskdb: diskdb,
+		cleans: cleans,
 		nodes: map[common.Hash]*cachedNode{
 			{}: {children: make(map[common.Hash]int)},
 		},
@@ -88,13 +121,39 @@ func (db *Database) insert(hash common.Hash, blob []byte) {
 	if _, ok := db.nodes[hash]; ok {
 		return
 	}
+	// Track the node in the flush-list. The hasher inserts the children of a node
+	// before the node itself, so flushing from the oldest end never leaves a node
+	// on disk whose children are only in memory.
 	db.nodes[hash] = &cachedNode{
-		blob:     common.CopyBytes(blob),
-		children: make(map[common.Hash]int),
+		blob:      common.CopyBytes(blob),
+		children:  make(map[common.Hash]int),
+		flushPrev: db.newest,
+	}
+	if db.oldest == (common.Hash{}) {
+		db.oldest, db.newest = hash, hash
+	} else {
+		db.nodes[db.newest].flushNext, db.newest = hash, hash
 	}
 	db.nodesSize += common.StorageSize(common.HashLength + len(blob))
 }
 
+// unlink removes a node from the flush-list.
+//
+// Note, this method assumes that the database's lock is held!
+func (db *Database) unlink(hash common.Hash, node *cachedNode) {
+	switch hash {
+	case db.oldest:
+		db.oldest = node.flushNext
+		db.nodes[node.flushNext].flushPrev = common.Hash{}
+	case db.newest:
+		db.newest = node.flushPrev
+		db.nodes[node.flushPrev].flushNext = common.Hash{}
+	default:
+		db.nodes[node.flushPrev].flushNext = node.flushNext
+		db.nodes[node.flushNext].flushPrev = node.flushPrev
+	}
+}
+
 // insertPreimage writes a new trie node pre-image to the memory database if it's
 // yet unknown. The method will make a copy of the slice.
 //
@@ -118,8 +177,20 @@ func (db *Database) Node(hash common.Hash) ([]byte, error) {
 	if node != nil {
 		return node.blob, nil
 	}
+	// Retrieve the node from the clean cache if available
+	if db.cleans != nil {
+		if blob, ok := db.cleans.get(hash); ok {
+			memcacheCleanHitCounter.Inc(1)
+			return blob, nil
+		}
+		memcacheCleanMissCounter.Inc(1)
+	}
 	// Content unavailable in memory, attempt to retrieve from disk
-	return db.diskdb.Get(hash[:])
+	blob, err := db.diskdb.Get(hash[:])
+	if err == nil && db.cleans != nil && len(blob) > 0 {
+		db.cleans.set(hash, blob)
+	}
+	return blob, err
 }
 
 // preimage retrieves a cached trie node pre-image from memory. If it cannot be
@@ -177,8 +248,8 @@ func (db *Database) ReferenceRoot(childs []common.CoinRoot, parent common.Hash)
 
 // Reference adds a new reference from a parent node to a child node.
 func (db *Database) Reference(child common.Hash, parent common.Hash) {
-	db.lock.RLock()
-	defer db.lock.RUnlock()
+	db.lock.Lock()
+	defer db.lock.Unlock()
 
 	db.reference(child, parent)
 }
@@ -190,12 +261,17 @@ func (db *Database) reference(child common.Hash, parent common.Hash) {
 	if !ok {
 		return
 	}
+	// If the parent was flushed to disk, there is no reference to track
+	pnode, ok := db.nodes[parent]
+	if !ok {
+		return
+	}
 	// If the reference already exists, only duplicate for roots
-	if _, ok = db.nodes[parent].children[child]; ok && parent != (common.Hash{}) {
+	if _, ok = pnode.children[child]; ok && parent != (common.Hash{}) {
 		return
 	}
 	node.parents++
-	db.nodes[parent].children[child]++
+	pnode.children[child]++
 }
 
 // Dereference removes an existing reference from a parent node to a child node.
@@ -216,15 +292,18 @@ func (db *Database) Dereference(child common.Hash, parent common.Hash) {
 
 // dereference is the private locked version of Dereference.
 func (db *Database) dereference(child common.Hash, parent common.Hash) {
-	// Dereference the parent-child
-	node := db.nodes[parent]
-
+	// Dereference the parent-child. The parent may already have been flushed
+	// to disk by Cap, in which case it holds no references any more.
+	node, ok := db.nodes[parent]
+	if !ok || node.children[child] == 0 {
+		return
+	}
 	node.children[child]--
 	if node.children[child] == 0 {
 		delete(node.children, child)
 	}
 	// If the node does not exist, it's a previously committed node.
-	node, ok := db.nodes[child]
+	node, ok = db.nodes[child]
 	if !ok {
 		return
 	}
@@ -234,6 +313,7 @@ func (db *Database) dereference(child common.Hash, parent common.Hash) {
 		for hash := range node.children {
 			db.dereference(hash, child)
 		}
+		db.unlink(child, node)
 		delete(db.nodes, child)
 		db.nodesSize -= common.StorageSize(common.HashLength + len(node.blob))
 	}
@@ -259,6 +339,102 @@ func (db *Database) CommitRoots(nodes []common.CoinRoot, report bool) error {
 	return nil
 }
 
+// Cap iteratively flushes old but still referenced trie nodes until the total
+// memory usage goes below the given threshold. The nodes stay referenced, only
+// their data moves from the memory write layer to disk (and the clean cache).
+//
+// As a side effect, all pre-images accumulated up to this point are also written.
+func (db *Database) Cap(limit common.StorageSize) error {
+	// Create a database batch to flush persistent data out. It is important that
+	// outside code doesn't see an inconsistent state (referenced data removed from
+	// memory cache during flush but not yet in persistent storage). This is ensured
+	// by only uncaching existing data when the database write finalizes.
+	db.lock.RLock()
+
+	nodes, storage, start := len(db.nodes), db.nodesSize, time.Now()
+	batch := db.diskdb.NewBatch()
+
+	// Move all of the accumulated preimages into a write batch
+	size := db.nodesSize + db.preimagesSize
+	for hash, preimage := range db.preimages {
+		if err := batch.Put(db.secureKey(hash[:]), preimage); err != nil {
+			log.Error("Failed to commit preimage from trie database", "err", err)
+			db.lock.RUnlock()
+			return err
+		}
+		if batch.ValueSize() > mandb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				db.lock.RUnlock()
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	size -= db.preimagesSize
+
+	// Keep committing nodes from the flush-list until we're below allowance
+	oldest := db.oldest
+	for size > limit && oldest != (common.Hash{}) {
+		node := db.nodes[oldest]
+		if err := batch.Put(oldest[:], node.blob); err != nil {
+			db.lock.RUnlock()
+			return err
+		}
+		// If we exceeded the ideal batch size, commit and reset
+		if batch.ValueSize() >= mandb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Error("Failed to write flush list to disk", "err", err)
+				db.lock.RUnlock()
+				return err
+			}
+			batch.Reset()
+		}
+		size -= common.StorageSize(common.HashLength + len(node.blob))
+		oldest = node.flushNext
+	}
+	// Flush out any remainder data from the last batch
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write flush list to disk", "err", err)
+		db.lock.RUnlock()
+		return err
+	}
+	db.lock.RUnlock()
+
+	// Write successful, clear out the flushed data
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.preimages = make(map[common.Hash][]byte)
+	db.preimagesSize = 0
+
+	for db.oldest != oldest {
+		node := db.nodes[db.oldest]
+		delete(db.nodes, db.oldest)
+		if db.cleans != nil {
+			db.cleans.set(db.oldest, node.blob)
+		}
+		db.oldest = node.flushNext
+		db.nodesSize -= common.StorageSize(common.HashLength + len(node.blob))
+	}
+	if db.oldest != (common.Hash{}) {
+		db.nodes[db.oldest].flushPrev = common.Hash{}
+	} else {
+		db.newest = common.Hash{}
+	}
+	db.flushnodes += uint64(nodes - len(db.nodes))
+	db.flushsize += storage - db.nodesSize
+	db.flushtime += time.Since(start)
+
+	memcacheFlushTimeTimer.Update(time.Since(start))
+	memcacheFlushNodesMeter.Mark(int64(nodes - len(db.nodes)))
+	memcacheFlushSizeMeter.Mark(int64(storage - db.nodesSize))
+
+	log.Debug("Persisted nodes from memory database", "nodes", nodes-len(db.nodes), "size", storage-db.nodesSize, "time", time.Since(start),
+		"flushnodes", db.flushnodes, "flushsize", db.flushsize, "flushtime", db.flushtime, "livenodes", len(db.nodes), "livesize", db.nodesSize)
+
+	return nil
+}
+
 // Commit iterates over all the children of a particular node, writes them out
 // to disk, forcefully tearing down all references in both directions.
 //
@@ -320,8 +496,9 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 	//logger("Persisted trie from memory database", "nodes", nodes-len(db.nodes), "size", storage-db.nodesSize, "time", time.Since(start),
 	//	"gcnodes", db.gcnodes, "gcsize", db.gcsize, "gctime", db.gctime, "livenodes", len(db.nodes), "livesize", db.nodesSize)
 
-	// Reset the garbage collection statistics
+	// Reset the garbage collection and flush statistics
 	db.gcnodes, db.gcsize, db.gctime = 0, 0, 0
+	db.flushnodes, db.flushsize, db.flushtime = 0, 0, 0
 
 	return nil
 }
@@ -365,8 +542,13 @@ func (db *Database) uncache(hash common.Hash) {
 	for child := range node.children {
 		db.uncache(child)
 	}
+	db.unlink(hash, node)
 	delete(db.nodes, hash)
 	db.nodesSize -= common.StorageSize(common.HashLength + len(node.blob))
+
+	if db.cleans != nil {
+		db.cleans.set(hash, node.blob)
+	}
 }
 
 // Size returns the current storage size of the memory cache in front of the
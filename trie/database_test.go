@@ -0,0 +1,112 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+)
+
+// Tests that capping the memory database flushes the nodes to disk while keeping
+// the trie readable, and that the flushed nodes are served from the clean cache.
+func TestDatabaseCap(t *testing.T) {
+	diskdb := mandb.NewMemDatabase()
+	triedb := NewDatabaseWithCache(diskdb, 1)
+
+	trie, _ := New(common.Hash{}, triedb)
+	for i := 0; i < 100; i++ {
+		trie.Update([]byte(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	root, err := trie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	triedb.Reference(root, common.Hash{})
+	if diskdb.Len() != 0 {
+		t.Fatalf("nodes written to disk before capping: %d", diskdb.Len())
+	}
+	if err := triedb.Cap(0); err != nil {
+		t.Fatalf("failed to cap database: %v", err)
+	}
+	if size := triedb.Size(); size != 0 {
+		t.Errorf("memory database not empty after capping: %v", size)
+	}
+	if diskdb.Len() == 0 {
+		t.Fatalf("no nodes flushed to disk")
+	}
+	if triedb.cleans.len() != diskdb.Len() {
+		t.Errorf("clean cache size mismatch: have %d, want %d", triedb.cleans.len(), diskdb.Len())
+	}
+	// Dereferencing flushed nodes must be a noop
+	triedb.Dereference(root, common.Hash{})
+
+	trie, err = New(root, NewDatabase(diskdb))
+	if err != nil {
+		t.Fatalf("failed to reopen trie: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if have, want := trie.Get([]byte(fmt.Sprintf("key-%d", i))), []byte(fmt.Sprintf("value-%d", i)); !bytes.Equal(have, want) {
+			t.Errorf("value %d mismatch: have %s, want %s", i, have, want)
+		}
+	}
+}
+
+// Tests that referencing from or dereferencing a parent already flushed to disk
+// by Cap is a noop for the parent instead of a crash.
+func TestDatabaseFlushedParent(t *testing.T) {
+	diskdb := mandb.NewMemDatabase()
+	triedb := NewDatabase(diskdb)
+
+	trie, _ := New(common.Hash{}, triedb)
+	trie.Update([]byte("key"), []byte("value"))
+	parent, _ := trie.Commit(nil)
+	triedb.Reference(parent, common.Hash{})
+	if err := triedb.Cap(0); err != nil {
+		t.Fatalf("failed to cap database: %v", err)
+	}
+	trie, _ = New(common.Hash{}, triedb)
+	trie.Update([]byte("other"), []byte("value"))
+	child, _ := trie.Commit(nil)
+	triedb.Reference(child, common.Hash{})
+
+	triedb.Reference(child, parent)
+	triedb.Dereference(child, parent)
+	if _, err := triedb.Node(child); err != nil {
+		t.Errorf("node still referenced by the root dropped: %v", err)
+	}
+	triedb.Dereference(child, common.Hash{})
+	if size := triedb.Size(); size != 0 {
+		t.Errorf("memory database not empty after dereferencing: %v", size)
+	}
+}
+
+// Tests that the clean cache evicts the least recently used nodes when full.
+func TestCleanCacheEviction(t *testing.T) {
+	entry := common.StorageSize(common.HashLength + 10)
+	cache := newCleanCache(3 * entry)
+
+	for i := byte(1); i <= 3; i++ {
+		cache.set(common.Hash{i}, make([]byte, 10))
+	}
+	cache.get(common.Hash{1})
+	cache.set(common.Hash{4}, make([]byte, 10))
+
+	if _, ok := cache.get(common.Hash{2}); ok {
+		t.Errorf("least recently used node not evicted")
+	}
+	for _, i := range []byte{1, 3, 4} {
+		if _, ok := cache.get(common.Hash{i}); !ok {
+			t.Errorf("node %d evicted", i)
+		}
+	}
+	cache.set(common.Hash{5}, make([]byte, 4*int(entry)))
+	if _, ok := cache.get(common.Hash{5}); ok {
+		t.Errorf("node larger than the cache was cached")
+	}
+}
@@ -143,11 +143,16 @@ func (s *TrieSync) AddRawEntry(hash common.Hash, depth int, parent common.Hash)
 	s.schedule(req)
 }
 
-// Missing retrieves the known missing nodes from the trie for retrieval.
+// Missing retrieves the known missing nodes from the trie for retrieval. Nodes
+// delivered ahead of their turn (e.g. as part of a chunk) are skipped.
 func (s *TrieSync) Missing(max int) []common.Hash {
 	requests := []common.Hash{}
 	for !s.queue.Empty() && (max == 0 || len(requests) < max) {
-		requests = append(requests, s.queue.PopItem().(common.Hash))
+		hash := s.queue.PopItem().(common.Hash)
+		if req := s.requests[hash]; req == nil || req.data != nil {
+			continue
+		}
+		requests = append(requests, hash)
 	}
 	return requests
 }